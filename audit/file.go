@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	server.RegisterAuditSinkFactory(config.AuditSinkFile, NewFile)
+}
+
+// maxLineSize is the maximum size of a record line when reading the file.
+const maxLineSize = 1024 * 1024
+
+// NewFile returns the file sink which writes the records as JSON lines into a rotating file.
+// When the file exceeds max_size, it is renamed to path.1, the existing path.1 is renamed to path.2 and so on,
+// the files beyond max_backups are removed.
+func NewFile(config config.Config) (server.AuditSink, error) {
+	cfg := config.Audit.File
+	p := cfg.Path
+	if !filepath.IsAbs(p) && config.ConfigDir != "" {
+		p = filepath.Join(config.ConfigDir, p)
+	}
+	return newFile(p, int64(cfg.MaxSize)*1024*1024, cfg.MaxBackups)
+}
+
+func newFile(path string, maxSize int64, maxBackups int) (*file, error) {
+	f := &file{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	return f, f.open()
+}
+
+type file struct {
+	// mu guards the file against the concurrent writing, rotating and querying.
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func (f *file) open() error {
+	fd, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return err
+	}
+	f.f = fd
+	f.size = info.Size()
+	return nil
+}
+
+func (f *file) backupPath(n int) string {
+	return f.path + "." + strconv.Itoa(n)
+}
+
+func (f *file) rotate() error {
+	err := f.f.Close()
+	if err != nil {
+		return err
+	}
+	if f.maxBackups == 0 {
+		err = os.Remove(f.path)
+	} else {
+		_ = os.Remove(f.backupPath(f.maxBackups))
+		for i := f.maxBackups - 1; i >= 1; i-- {
+			err = os.Rename(f.backupPath(i), f.backupPath(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err = os.Rename(f.path, f.backupPath(1))
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return f.open()
+}
+
+func (f *file) Write(record *server.AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		err = f.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	n, err := f.f.Write(b)
+	f.size += int64(n)
+	return err
+}
+
+// Query reads the current file and the backups from the latest to the oldest.
+// The files are opened under the lock and scanned without it, so that a query does not block the writing.
+func (f *file) Query(query server.AuditQuery) (records []*server.AuditRecord, total int, err error) {
+	files, err := f.openFiles()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		for _, v := range files {
+			v.Close()
+		}
+	}()
+	p := newPager(query)
+	for _, v := range files {
+		if err = queryFile(v, p); err != nil {
+			return nil, 0, err
+		}
+	}
+	return p.records, p.total, nil
+}
+
+// openFiles opens the current file and the backups from the latest to the oldest.
+// The opened files are not affected by the later rotation.
+func (f *file) openFiles() (files []*os.File, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := []string{f.path}
+	for i := 1; i <= f.maxBackups; i++ {
+		names = append(names, f.backupPath(i))
+	}
+	for _, v := range names {
+		fd, err := os.Open(v)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			for _, v := range files {
+				v.Close()
+			}
+			return nil, err
+		}
+		files = append(files, fd)
+	}
+	return files, nil
+}
+
+// queryFile adds the records that match the query in the file to the pager.
+// It counts the matched records in the first pass, and decodes the records in the page in the second pass,
+// so that the memory usage is bounded by the page size instead of the file size.
+func queryFile(fd *os.File, p *pager) error {
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	// ignore the records written after the first pass
+	size := info.Size()
+	var n int
+	err = scanRecords(io.NewSectionReader(fd, 0, size), &p.query, func(r *server.AuditRecord) {
+		n++
+	})
+	if err != nil {
+		return err
+	}
+	// The records are added to the pager in descending order of time,
+	// so the i-th matched record in the file is the (p.total+n-1-i)-th matched record of the query.
+	lo, hi := p.query.Offset-p.total, n
+	if p.query.Limit > 0 && p.query.Offset+p.query.Limit-p.total < hi {
+		hi = p.query.Offset + p.query.Limit - p.total
+	}
+	if lo < 0 {
+		lo = 0
+	}
+	if lo < hi {
+		var i int
+		rs := make([]*server.AuditRecord, 0, hi-lo)
+		err = scanRecords(io.NewSectionReader(fd, 0, size), &p.query, func(r *server.AuditRecord) {
+			if j := n - 1 - i; j >= lo && j < hi {
+				rs = append(rs, r)
+			}
+			i++
+		})
+		if err != nil {
+			return err
+		}
+		for i := len(rs) - 1; i >= 0; i-- {
+			p.records = append(p.records, rs[i])
+		}
+	}
+	p.total += n
+	return nil
+}
+
+// scanRecords calls fn for each record that matches the query in the reader.
+// The malformed lines, e.g. the last line which is partially written when crashed, are skipped.
+func scanRecords(r io.Reader, query *server.AuditQuery, fn func(r *server.AuditRecord)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		r := &server.AuditRecord{}
+		if json.Unmarshal(scanner.Bytes(), r) != nil {
+			continue
+		}
+		if query.Match(r) {
+			fn(r)
+		}
+	}
+	return scanner.Err()
+}
+
+func (f *file) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.f.Sync()
+	if err != nil {
+		f.f.Close()
+		return err
+	}
+	return f.f.Close()
+}
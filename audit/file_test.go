@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestFile(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_audit")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	p := path.Join(dir, "audit.log")
+
+	rs := newTestRecords(6)
+	f, err := newFile(p, 1024*1024, 2)
+	a.NoError(err)
+	for _, v := range rs {
+		a.NoError(f.Write(v))
+	}
+	testQuery(t, f, rs)
+	a.NoError(f.Close())
+
+	// reopen and append
+	f, err = newFile(p, 1024*1024, 2)
+	a.NoError(err)
+	a.NotZero(f.size)
+	testQuery(t, f, rs)
+	a.NoError(f.Close())
+}
+
+func TestFile_Rotate(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_audit")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	p := path.Join(dir, "audit.log")
+
+	rs := newTestRecords(8)
+	// each file can hold one record
+	f, err := newFile(p, 10, 5)
+	a.NoError(err)
+	for _, v := range rs[:6] {
+		a.NoError(f.Write(v))
+	}
+	for i := 1; i <= 5; i++ {
+		_, err := os.Stat(f.backupPath(i))
+		a.NoError(err)
+	}
+	testQuery(t, f, rs[:6])
+
+	// the oldest records are removed
+	a.NoError(f.Write(rs[6]))
+	a.NoError(f.Write(rs[7]))
+	_, err = os.Stat(f.backupPath(6))
+	a.True(os.IsNotExist(err))
+	testQuery(t, f, rs[2:])
+
+	// a partially written line is skipped
+	_, err = f.f.WriteString(`{"time":`)
+	a.NoError(err)
+	testQuery(t, f, rs[2:])
+	a.NoError(f.Close())
+
+	// no backups
+	f, err = newFile(p, 10, 0)
+	a.NoError(err)
+	a.NoError(f.Write(rs[0]))
+	_, total, err := f.Query(server.AuditQuery{})
+	a.NoError(err)
+	a.Equal(1, total)
+	a.NoError(f.Close())
+}
+
+func TestFile_QueryAcrossBackups(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_audit")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	p := path.Join(dir, "audit.log")
+
+	rs := newTestRecords(6)
+	f, err := newFile(p, 1024*1024, 5)
+	a.NoError(err)
+	// each file holds two records
+	for i, v := range rs {
+		if i != 0 && i%2 == 0 {
+			a.NoError(f.rotate())
+		}
+		a.NoError(f.Write(v))
+	}
+	testQuery(t, f, rs)
+
+	records, total, err := f.Query(server.AuditQuery{Offset: 1, Limit: 3})
+	a.NoError(err)
+	a.Equal(6, total)
+	a.Len(records, 3)
+	for i, v := range []*server.AuditRecord{rs[4], rs[3], rs[2]} {
+		a.Equal(v.Target, records[i].Target)
+	}
+	a.NoError(f.Close())
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"sync"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	server.RegisterAuditSinkFactory(config.AuditSinkMemory, NewMemory)
+}
+
+// NewMemory returns the memory sink which keeps the latest records in memory.
+func NewMemory(config config.Config) (server.AuditSink, error) {
+	return newMemory(config.Audit.Memory.MaxRecords), nil
+}
+
+func newMemory(max int) *memory {
+	return &memory{
+		max:     max,
+		records: make([]*server.AuditRecord, 0, max),
+	}
+}
+
+type memory struct {
+	mu  sync.RWMutex
+	max int
+	// records is a ring buffer, next is the position of the next record.
+	records []*server.AuditRecord
+	next    int
+}
+
+func (m *memory) Write(record *server.AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.records) < m.max {
+		m.records = append(m.records, record)
+	} else {
+		m.records[m.next] = record
+	}
+	m.next = (m.next + 1) % m.max
+	return nil
+}
+
+func (m *memory) Query(query server.AuditQuery) (records []*server.AuditRecord, total int, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p := newPager(query)
+	// iterate from the latest record
+	for i := 0; i < len(m.records); i++ {
+		r := m.records[(m.next-1-i+len(m.records))%len(m.records)]
+		p.add(r)
+	}
+	return p.records, p.total, nil
+}
+
+func (m *memory) Close() error {
+	return nil
+}
+
+// pager collects the matched records in the page, the records must be added in descending order of time.
+type pager struct {
+	query   server.AuditQuery
+	records []*server.AuditRecord
+	total   int
+}
+
+func newPager(query server.AuditQuery) *pager {
+	return &pager{
+		query:   query,
+		records: []*server.AuditRecord{},
+	}
+}
+
+func (p *pager) add(r *server.AuditRecord) {
+	if !p.query.Match(r) {
+		return
+	}
+	if p.total >= p.query.Offset && (p.query.Limit <= 0 || len(p.records) < p.query.Limit) {
+		p.records = append(p.records, r)
+	}
+	p.total++
+}
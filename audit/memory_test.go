@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newTestRecords(n int) []*server.AuditRecord {
+	now := time.Unix(1000, 0)
+	var rs []*server.AuditRecord
+	for i := 0; i < n; i++ {
+		r := &server.AuditRecord{
+			Time:      now.Add(time.Duration(i) * time.Second),
+			Actor:     "admin",
+			Operation: "admin.client.delete",
+			Target:    "c" + string(rune('0'+i)),
+			Result:    server.AuditResultSuccess,
+		}
+		if i%2 == 1 {
+			r.Actor = "ops"
+			r.Operation = "auth.account.update"
+			r.Result = server.AuditResultFailure
+			r.Error = "error"
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+func testQuery(t *testing.T, q server.AuditQuerier, rs []*server.AuditRecord) {
+	a := assert.New(t)
+	var tt = []struct {
+		name     string
+		query    server.AuditQuery
+		expected []*server.AuditRecord
+		total    int
+	}{
+		{name: "all", query: server.AuditQuery{}, expected: []*server.AuditRecord{rs[5], rs[4], rs[3], rs[2], rs[1], rs[0]}, total: 6},
+		{name: "page", query: server.AuditQuery{Offset: 1, Limit: 2}, expected: []*server.AuditRecord{rs[4], rs[3]}, total: 6},
+		{name: "actor", query: server.AuditQuery{Actor: "ops"}, expected: []*server.AuditRecord{rs[5], rs[3], rs[1]}, total: 3},
+		{name: "operation_prefix", query: server.AuditQuery{Operation: "admin"}, expected: []*server.AuditRecord{rs[4], rs[2], rs[0]}, total: 3},
+		{name: "operation_not_prefix", query: server.AuditQuery{Operation: "adm"}, expected: []*server.AuditRecord{}, total: 0},
+		{name: "target", query: server.AuditQuery{Target: rs[2].Target}, expected: []*server.AuditRecord{rs[2]}, total: 1},
+		{name: "result", query: server.AuditQuery{Result: server.AuditResultFailure, Limit: 1}, expected: []*server.AuditRecord{rs[5]}, total: 3},
+		{name: "time_range", query: server.AuditQuery{Since: rs[1].Time, Until: rs[2].Time}, expected: []*server.AuditRecord{rs[2], rs[1]}, total: 2},
+	}
+	for _, v := range tt {
+		records, total, err := q.Query(v.query)
+		a.NoError(err, v.name)
+		a.Equal(v.total, total, v.name)
+		a.Equal(len(v.expected), len(records), v.name)
+		for i := range v.expected {
+			if i < len(records) {
+				a.True(v.expected[i].Time.Equal(records[i].Time), v.name)
+				a.Equal(v.expected[i].Target, records[i].Target, v.name)
+			}
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	a := assert.New(t)
+	m := newMemory(6)
+	rs := newTestRecords(8)
+	for _, v := range rs {
+		a.NoError(m.Write(v))
+	}
+	// the oldest 2 records are dropped
+	testQuery(t, m, rs[2:])
+	a.NoError(m.Close())
+}
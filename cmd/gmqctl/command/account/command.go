@@ -0,0 +1,136 @@
+package account
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+)
+
+var (
+	page     uint32
+	pageSize uint32
+	password string
+)
+
+// Command is the command for the account management of the auth plugin.
+var Command = &cobra.Command{
+	Use:   "account",
+	Short: "Manage the accounts of the auth plugin",
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the accounts",
+	Args:  cobra.NoArgs,
+	RunE:  ctl.Run(list),
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get <username>",
+	Short: "Get the account",
+	Args:  cobra.ExactArgs(1),
+	RunE:  ctl.Run(get),
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set <username>",
+	Short: "Create the account or update the password",
+	Long:  "Create the account or update the password. The password is read from the standard input if --password is not set.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  ctl.Run(set),
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <username>",
+	Short: "Delete the account",
+	Args:  cobra.ExactArgs(1),
+	RunE:  ctl.Run(rm),
+}
+
+func init() {
+	ctl.AddFlags(Command)
+	listCmd.Flags().Uint32Var(&page, "page", 1, "The page number.")
+	listCmd.Flags().Uint32Var(&pageSize, "page-size", 20, "The page size.")
+	setCmd.Flags().StringVarP(&password, "password", "p", "", "The password of the account.")
+	Command.AddCommand(listCmd, getCmd, setCmd, rmCmd)
+}
+
+// accountsTable prints the accounts, the hashed passwords are omitted.
+func accountsTable(accounts ...*auth.Account) *ctl.Table {
+	t := &ctl.Table{
+		Header: []string{"USERNAME"},
+	}
+	for _, v := range accounts {
+		t.Rows = append(t.Rows, []string{v.Username})
+	}
+	return t
+}
+
+func list(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := auth.NewAccountServiceClient(conn).List(ctx, &auth.ListAccountsRequest{
+		PageSize: pageSize,
+		Page:     page,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.Print(resp, accountsTable(resp.Accounts...))
+}
+
+func get(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := auth.NewAccountServiceClient(conn).Get(ctx, &auth.GetAccountRequest{
+		Username: args[0],
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.Print(resp, accountsTable(resp.Account))
+}
+
+func set(conn *grpc.ClientConn, args []string) error {
+	if password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read the password: %s", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := auth.NewAccountServiceClient(conn).Update(ctx, &auth.UpdateAccountRequest{
+		Username: args[0],
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "account "+args[0]+" updated")
+}
+
+func rm(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := auth.NewAccountServiceClient(conn).Delete(ctx, &auth.DeleteAccountRequest{
+		Username: args[0],
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "account "+args[0]+" deleted")
+}
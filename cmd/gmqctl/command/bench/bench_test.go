@@ -0,0 +1,267 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// testBroker is a minimal broker which delivers the messages to the subscribers of the exact topic.
+type testBroker struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	subs map[string][]*testConn
+	// refuse refuses the connections of the username.
+	refuse string
+}
+
+type testConn struct {
+	mu sync.Mutex
+	w  *packets.Writer
+}
+
+func (c *testConn) write(p packets.Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.w.WriteAndFlush(p)
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{ln: ln, subs: make(map[string][]*testConn)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := packets.NewReader(conn)
+	c := &testConn{w: packets.NewWriter(conn)}
+	for {
+		p, err := r.ReadPacket()
+		if err != nil {
+			return
+		}
+		switch p := p.(type) {
+		case *packets.Connect:
+			code := codes.Success
+			if b.refuse != "" && string(p.Username) == b.refuse {
+				code = codes.NotAuthorized
+				if p.Version != packets.Version5 {
+					code = codes.V3NotAuthorized
+				}
+			}
+			c.write(&packets.Connack{Version: p.Version, Code: code})
+		case *packets.Subscribe:
+			b.mu.Lock()
+			for _, v := range p.Topics {
+				b.subs[v.Name] = append(b.subs[v.Name], c)
+			}
+			b.mu.Unlock()
+			c.write(p.NewSuback())
+		case *packets.Publish:
+			switch p.Qos {
+			case packets.Qos1:
+				c.write(p.NewPuback(codes.Success, nil))
+			case packets.Qos2:
+				c.write(p.NewPubrec(codes.Success, nil))
+			}
+			b.mu.Lock()
+			subs := b.subs[string(p.TopicName)]
+			b.mu.Unlock()
+			for _, v := range subs {
+				v.write(&packets.Publish{
+					Version:    p.Version,
+					TopicName:  p.TopicName,
+					Payload:    p.Payload,
+					Properties: p.Properties,
+				})
+			}
+		case *packets.Pubrel:
+			c.write(p.NewPubcomp())
+		case *packets.Pingreq:
+			c.write(p.NewPingresp())
+		case *packets.Disconnect:
+			return
+		}
+	}
+}
+
+func TestReadVouchers(t *testing.T) {
+	a := assert.New(t)
+	vs, err := readVouchers(strings.NewReader("device_number,voucher\n" +
+		"d1,\"{\"\"username\"\":\"\"u1\"\",\"\"password\"\":\"\"p1\"\"}\"\n" +
+		"d2,\"{\"\"username\"\":\"\"u2\"\"}\"\n"))
+	a.NoError(err)
+	a.Equal([]*voucher{
+		{deviceNumber: "d1", username: "u1", password: "p1"},
+		{deviceNumber: "d2", username: "u2"},
+	}, vs)
+
+	vs, err = readVouchers(strings.NewReader("username,password\nu1,p1\n"))
+	a.NoError(err)
+	a.Equal([]*voucher{{username: "u1", password: "p1"}}, vs)
+
+	_, err = readVouchers(strings.NewReader("device_number\nd1\n"))
+	a.Error(err)
+	_, err = readVouchers(strings.NewReader("device_number,voucher\nd1,invalid\n"))
+	a.Error(err)
+	_, err = readVouchers(strings.NewReader("device_number,voucher\n"))
+	a.Error(err)
+}
+
+func TestTemplate(t *testing.T) {
+	a := assert.New(t)
+	tmpl := newTemplate(3, "dev-3")
+	a.Equal("devices/dev-3/3", tmpl.render("devices/{device_number}/{seq}"))
+	a.Equal("static", tmpl.render("static"))
+}
+
+func TestHistogram(t *testing.T) {
+	a := assert.New(t)
+	h := newHistogram()
+	a.Equal("no samples", h.summary())
+	for i := 100; i >= 1; i-- {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	a.Equal([]time.Duration{50 * time.Millisecond, 99 * time.Millisecond, 100 * time.Millisecond, time.Millisecond},
+		h.percentiles(50, 99, 100, 0))
+	a.Contains(h.summary(), "max: 100ms")
+}
+
+func TestPublishTime(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(0, time.Now().UnixNano())
+	ts, ok := publishTime(&packets.Publish{Payload: newPayload(100, now)})
+	a.True(ok)
+	a.True(now.Equal(ts))
+
+	p := newPayload(4, now)
+	a.Equal([]byte("xxxx"), p)
+	_, ok = publishTime(&packets.Publish{Payload: p})
+	a.False(ok)
+
+	ts, ok = publishTime(&packets.Publish{
+		Payload: []byte("{}"),
+		Properties: &packets.Properties{
+			User: []packets.UserProperty{{K: []byte(timestampProperty), V: []byte("1000")}},
+		},
+	})
+	a.True(ok)
+	a.Equal(int64(1000), ts.UnixNano())
+}
+
+func TestClient(t *testing.T) {
+	b := newTestBroker(t)
+	defer b.ln.Close()
+	b.refuse = "refused"
+	for _, version := range []packets.Version{packets.Version311, packets.Version5} {
+		a := assert.New(t)
+		received := make(chan *packets.Publish, 10)
+		opts := &clientOptions{
+			addr:       b.ln.Addr().String(),
+			version:    version,
+			clientID:   "c1",
+			keepAlive:  1,
+			cleanStart: true,
+			timeout:    time.Second,
+			onMessage: func(p *packets.Publish) {
+				received <- p
+			},
+		}
+		c, err := connect(opts)
+		a.NoError(err)
+		a.NoError(c.subscribe(packets.Qos1, "t"))
+		for qos := packets.Qos0; qos <= packets.Qos2; qos++ {
+			a.NoError(c.publish("t", qos, false, []byte{qos}, nil))
+			select {
+			case p := <-received:
+				a.Equal([]byte{qos}, p.Payload)
+			case <-time.After(time.Second):
+				t.Fatal("message not received")
+			}
+		}
+		c.disconnect()
+		<-c.done()
+
+		opts.username = "refused"
+		_, err = connect(opts)
+		a.Error(err)
+	}
+}
+
+func TestRunPub(t *testing.T) {
+	a := assert.New(t)
+	b := newTestBroker(t)
+	defer b.ln.Close()
+	defer func(o options) { opts = o }(opts)
+	buf := &bytes.Buffer{}
+	opts = options{
+		broker:         b.ln.Addr().String(),
+		version:        5,
+		count:          3,
+		start:          1,
+		rate:           0,
+		clientID:       "pub-{seq}",
+		timeout:        time.Second,
+		reportInterval: time.Hour,
+	}
+	pubOpts.topic = "t/{seq}"
+	pubOpts.qos = packets.Qos1
+	pubOpts.size = 16
+	pubOpts.interval = 0
+	pubOpts.limit = 5
+	r, err := newRunner("pub")
+	a.NoError(err)
+	r.out = buf
+	runPub(r)
+	a.Contains(buf.String(), "connected: 3\n")
+	a.Contains(buf.String(), "published: 15, throughput:")
+	a.Contains(buf.String(), "publish_failed: 0, throughput:")
+}
+
+func TestClient_LateAck(t *testing.T) {
+	a := assert.New(t)
+	c := &client{
+		opts:    &clientOptions{timeout: 50 * time.Millisecond},
+		pending: make(map[packets.PacketID]chan error),
+		close:   make(chan struct{}),
+	}
+	ch := c.register(1)
+	// the acknowledgement of another packet does not satisfy the wait
+	c.acked(2, nil)
+	a.Error(c.waitAck(1, ch))
+
+	// the late acknowledgement of packet 1 is discarded
+	ch = c.register(2)
+	c.acked(1, nil)
+	select {
+	case <-ch:
+		t.Fatal("unexpected acknowledgement")
+	default:
+	}
+	ackErr := errors.New("publish failed")
+	c.acked(2, ackErr)
+	a.Equal(ackErr, c.waitAck(2, ch))
+	a.Empty(c.pending)
+}
@@ -0,0 +1,311 @@
+package bench
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+var errClosed = errors.New("connection closed")
+
+// clientOptions is the options to connect to the broker.
+type clientOptions struct {
+	addr       string
+	tlsConfig  *tls.Config
+	version    packets.Version
+	clientID   string
+	username   string
+	password   string
+	keepAlive  uint16
+	cleanStart bool
+	timeout    time.Duration
+	// onMessage is called in the read goroutine when a PUBLISH packet is received.
+	onMessage func(p *packets.Publish)
+}
+
+// client is a minimal MQTT client for benchmarking, which supports MQTT v3.1.1 and v5.
+// Publish and Subscribe are synchronous, each client has at most one outgoing inflight packet at a time.
+type client struct {
+	opts *clientOptions
+	conn net.Conn
+	r    *packets.Reader
+	w    *packets.Writer
+	// wmu guards the writer.
+	wmu sync.Mutex
+	pid packets.PacketID
+	// pmu guards pending.
+	pmu sync.Mutex
+	// pending is keyed by the packet id of the outgoing inflight packet, the channel receives the acknowledgement
+	// which is PUBACK, PUBCOMP or SUBACK. A failed acknowledgement is delivered as an error.
+	// The acknowledgement that arrives after the waiting timed out is discarded.
+	pending map[packets.PacketID]chan error
+	close   chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// connect connects to the broker and waits for the CONNACK packet.
+func connect(opts *clientOptions) (*client, error) {
+	dialer := &net.Dialer{Timeout: opts.timeout}
+	var conn net.Conn
+	var err error
+	if opts.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", opts.addr, opts.tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", opts.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		opts:    opts,
+		conn:    conn,
+		r:       packets.NewReader(conn),
+		w:       packets.NewWriter(conn),
+		pending: make(map[packets.PacketID]chan error),
+		close:   make(chan struct{}),
+	}
+	c.r.SetVersion(opts.version)
+	connect := &packets.Connect{
+		Version:       opts.version,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: opts.version,
+		CleanStart:    opts.cleanStart,
+		KeepAlive:     opts.keepAlive,
+		ClientID:      []byte(opts.clientID),
+	}
+	if opts.username != "" {
+		connect.UsernameFlag = true
+		connect.Username = []byte(opts.username)
+	}
+	if opts.password != "" {
+		connect.PasswordFlag = true
+		connect.Password = []byte(opts.password)
+	}
+	_ = conn.SetDeadline(time.Now().Add(opts.timeout))
+	err = c.w.WriteAndFlush(connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p, err := c.r.ReadPacket()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	connack, ok := p.(*packets.Connack)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected packet: %s", p)
+	}
+	if connack.Code != codes.Success {
+		conn.Close()
+		return nil, fmt.Errorf("connection refused, code: %d", connack.Code)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	go c.readLoop()
+	if opts.keepAlive != 0 {
+		go c.keepAliveLoop()
+	}
+	return c, nil
+}
+
+func (c *client) setError(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.close)
+		c.conn.Close()
+	})
+}
+
+func (c *client) write(p packets.Packet) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	err := c.w.WriteAndFlush(p)
+	if err != nil {
+		c.setError(err)
+	}
+	return err
+}
+
+func (c *client) readLoop() {
+	for {
+		p, err := c.r.ReadPacket()
+		if err != nil {
+			c.setError(err)
+			return
+		}
+		switch p := p.(type) {
+		case *packets.Publish:
+			if c.opts.onMessage != nil {
+				c.opts.onMessage(p)
+			}
+			switch p.Qos {
+			case packets.Qos1:
+				err = c.write(p.NewPuback(codes.Success, nil))
+			case packets.Qos2:
+				err = c.write(p.NewPubrec(codes.Success, nil))
+			}
+		case *packets.Pubrel:
+			err = c.write(p.NewPubcomp())
+		case *packets.Pubrec:
+			if p.Code >= codes.UnspecifiedError {
+				c.acked(p.PacketID, fmt.Errorf("publish failed, code: %d", p.Code))
+				continue
+			}
+			err = c.write(p.NewPubrel())
+		case *packets.Puback:
+			c.acked(p.PacketID, codeError("publish", p.Code))
+		case *packets.Pubcomp:
+			c.acked(p.PacketID, codeError("publish", p.Code))
+		case *packets.Suback:
+			var ackErr error
+			for _, v := range p.Payload {
+				if v >= codes.UnspecifiedError {
+					ackErr = fmt.Errorf("subscribe failed, code: %d", v)
+				}
+			}
+			c.acked(p.PacketID, ackErr)
+		case *packets.Disconnect:
+			c.setError(fmt.Errorf("disconnected by the server, code: %d", p.Code))
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func codeError(op string, code codes.Code) error {
+	if code >= codes.UnspecifiedError {
+		return fmt.Errorf("%s failed, code: %d", op, code)
+	}
+	return nil
+}
+
+// register registers the packet id of the outgoing inflight packet before sending it,
+// the returned channel receives the acknowledgement.
+func (c *client) register(pid packets.PacketID) chan error {
+	ch := make(chan error, 1)
+	c.pmu.Lock()
+	c.pending[pid] = ch
+	c.pmu.Unlock()
+	return ch
+}
+
+func (c *client) unregister(pid packets.PacketID) {
+	c.pmu.Lock()
+	delete(c.pending, pid)
+	c.pmu.Unlock()
+}
+
+// acked delivers the acknowledgement to the waiting packet, it is discarded if no packet is waiting for it.
+func (c *client) acked(pid packets.PacketID, err error) {
+	c.pmu.Lock()
+	ch, ok := c.pending[pid]
+	delete(c.pending, pid)
+	c.pmu.Unlock()
+	if ok {
+		ch <- err
+	}
+}
+
+func (c *client) keepAliveLoop() {
+	t := time.NewTicker(time.Duration(c.opts.keepAlive) * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.close:
+			return
+		case <-t.C:
+			if c.write(&packets.Pingreq{}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *client) nextPacketID() packets.PacketID {
+	c.pid++
+	if c.pid == 0 {
+		c.pid = packets.MinPacketID
+	}
+	return c.pid
+}
+
+// waitAck waits for the acknowledgement of the inflight packet.
+func (c *client) waitAck(pid packets.PacketID, ch chan error) error {
+	t := time.NewTimer(c.opts.timeout)
+	defer t.Stop()
+	// the late acknowledgement is discarded after unregistering.
+	defer c.unregister(pid)
+	select {
+	case err := <-ch:
+		return err
+	case <-c.close:
+		return c.err
+	case <-t.C:
+		return errors.New("wait for acknowledgement timeout")
+	}
+}
+
+// publish publishes the message and waits for the acknowledgement if the qos > 0.
+func (c *client) publish(topic string, qos uint8, retain bool, payload []byte, properties *packets.Properties) error {
+	p := &packets.Publish{
+		Version:    c.opts.version,
+		Qos:        qos,
+		Retain:     retain,
+		TopicName:  []byte(topic),
+		Payload:    payload,
+		Properties: properties,
+	}
+	if qos == packets.Qos0 {
+		return c.write(p)
+	}
+	p.PacketID = c.nextPacketID()
+	ch := c.register(p.PacketID)
+	if err := c.write(p); err != nil {
+		c.unregister(p.PacketID)
+		return err
+	}
+	return c.waitAck(p.PacketID, ch)
+}
+
+// subscribe subscribes the topics and waits for the SUBACK packet.
+func (c *client) subscribe(qos uint8, topics ...string) error {
+	p := &packets.Subscribe{
+		Version:  c.opts.version,
+		PacketID: c.nextPacketID(),
+	}
+	for _, v := range topics {
+		p.Topics = append(p.Topics, packets.Topic{
+			Name: v,
+			SubOptions: packets.SubOptions{
+				Qos: qos,
+			},
+		})
+	}
+	ch := c.register(p.PacketID)
+	if err := c.write(p); err != nil {
+		c.unregister(p.PacketID)
+		return err
+	}
+	return c.waitAck(p.PacketID, ch)
+}
+
+// disconnect sends the DISCONNECT packet and closes the connection.
+func (c *client) disconnect() {
+	_ = c.write(&packets.Disconnect{Version: c.opts.version})
+	c.setError(errClosed)
+}
+
+// done returns a channel that is closed when the connection is closed.
+func (c *client) done() <-chan struct{} {
+	return c.close
+}
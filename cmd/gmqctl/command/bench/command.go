@@ -0,0 +1,363 @@
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// maxErrors is the maximum number of the errors printed for each kind of error, the others are only counted.
+const maxErrors = 10
+
+// options is the options shared by the bench commands.
+type options struct {
+	broker         string
+	version        int
+	count          int
+	start          int
+	rate           float64
+	clientID       string
+	username       string
+	password       string
+	voucherFile    string
+	keepAlive      uint16
+	cleanStart     bool
+	timeout        time.Duration
+	duration       time.Duration
+	reportInterval time.Duration
+	tls            ctl.TLSOptions
+}
+
+var opts options
+
+// Command is the command for benchmarking.
+var Command = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the broker",
+	Long: "Benchmark the broker with simulated clients.\n" +
+		"\n" +
+		"The client id, username, password, topic and payload are templates, the following variables are supported:\n" +
+		"  {seq}            the sequence number of the client, starts from --start.\n" +
+		"  {device_number}  the device number in the voucher file, or the sequence number if no voucher file is set.\n" +
+		"\n" +
+		"The voucher file is a CSV file with a header line, which contains the device_number column and either\n" +
+		"the voucher column (the ThingsPanel voucher, e.g. {\"username\":\"xxx\",\"password\":\"xxx\"}) or the username and password columns.\n" +
+		"The n-th client uses the n-th voucher, the username and password in the voucher override --username and --password.",
+}
+
+func init() {
+	f := Command.PersistentFlags()
+	f.StringVarP(&opts.broker, "broker", "b", "127.0.0.1:1883", "The broker address.")
+	f.IntVarP(&opts.version, "mqtt-version", "V", 3, "The MQTT version: 3 (3.1.1) | 5.")
+	f.IntVarP(&opts.count, "count", "c", 100, "The number of the clients, defaults to the number of vouchers if --voucher is set.")
+	f.IntVar(&opts.start, "start", 1, "The first sequence number.")
+	f.Float64VarP(&opts.rate, "rate", "R", 100, "The connect rate (connections per second), 0 means no limit.")
+	f.StringVarP(&opts.clientID, "client-id", "i", "", "The client id template. (default \"bench-<command>-{seq}\")")
+	f.StringVarP(&opts.username, "username", "u", "", "The username template.")
+	f.StringVarP(&opts.password, "password", "P", "", "The password template.")
+	f.StringVar(&opts.voucherFile, "voucher", "", "The voucher CSV file.")
+	f.Uint16VarP(&opts.keepAlive, "keepalive", "k", 60, "The keep alive in seconds.")
+	f.BoolVar(&opts.cleanStart, "clean-start", true, "The clean start (v5) or clean session (v3) flag.")
+	f.DurationVar(&opts.timeout, "timeout", 10*time.Second, "The timeout of connecting and waiting for acknowledgements.")
+	f.DurationVarP(&opts.duration, "duration", "d", 0, "The duration of the benchmark, 0 means running until interrupted.")
+	f.DurationVar(&opts.reportInterval, "report-interval", time.Second, "The interval to report the statistics.")
+	opts.tls.AddFlags(f)
+	Command.AddCommand(connCmd, pubCmd, subCmd)
+}
+
+// voucher is the credential of a device.
+type voucher struct {
+	deviceNumber string
+	username     string
+	password     string
+}
+
+// readVouchers reads the vouchers from the CSV file.
+func readVouchers(r io.Reader) ([]*voucher, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("no vouchers")
+	}
+	col := make(map[string]int)
+	for k, v := range records[0] {
+		col[strings.ToLower(strings.TrimSpace(v))] = k
+	}
+	get := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	_, hasVoucher := col["voucher"]
+	_, hasUsername := col["username"]
+	if !hasVoucher && !hasUsername {
+		return nil, fmt.Errorf("either the voucher or the username column is required")
+	}
+	var vs []*voucher
+	for k, record := range records[1:] {
+		v := &voucher{
+			deviceNumber: get(record, "device_number"),
+			username:     get(record, "username"),
+			password:     get(record, "password"),
+		}
+		if s := get(record, "voucher"); s != "" {
+			var credential struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+			}
+			if err := json.Unmarshal([]byte(s), &credential); err != nil {
+				return nil, fmt.Errorf("invalid voucher at line %d: %s", k+2, err)
+			}
+			v.username, v.password = credential.Username, credential.Password
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// template renders the templates for the client.
+type template struct {
+	r *strings.Replacer
+}
+
+func newTemplate(seq int, deviceNumber string) *template {
+	return &template{
+		r: strings.NewReplacer("{seq}", strconv.Itoa(seq), "{device_number}", deviceNumber),
+	}
+}
+
+func (t *template) render(s string) string {
+	return t.r.Replace(s)
+}
+
+// session is a connected client.
+type session struct {
+	seq int
+	t   *template
+	c   *client
+}
+
+// runner connects the clients and runs the benchmark.
+type runner struct {
+	name      string
+	version   packets.Version
+	tlsConfig *tls.Config
+	vouchers  []*voucher
+	stats     *stats
+	connected *counter
+	failed    *counter
+	closed    *counter
+	connect   *histogram
+	errs      sync.Map
+	out       io.Writer
+}
+
+func newRunner(name string) (*runner, error) {
+	r := &runner{
+		name:  name,
+		stats: newStats(),
+		out:   ctl.Out,
+	}
+	switch opts.version {
+	case 3:
+		r.version = packets.Version311
+	case 5:
+		r.version = packets.Version5
+	default:
+		return nil, fmt.Errorf("invalid mqtt version: %d", opts.version)
+	}
+	var err error
+	r.tlsConfig, err = opts.tls.Config()
+	if err != nil {
+		return nil, err
+	}
+	if opts.voucherFile != "" {
+		f, err := os.Open(opts.voucherFile)
+		if err != nil {
+			return nil, err
+		}
+		r.vouchers, err = readVouchers(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !Command.PersistentFlags().Changed("count") {
+			opts.count = len(r.vouchers)
+		}
+		if opts.count > len(r.vouchers) {
+			return nil, fmt.Errorf("not enough vouchers, want %d, got %d", opts.count, len(r.vouchers))
+		}
+	}
+	if opts.count <= 0 {
+		return nil, fmt.Errorf("invalid count: %d", opts.count)
+	}
+	if opts.clientID == "" {
+		opts.clientID = "bench-" + name + "-{seq}"
+	}
+	r.connected = r.stats.counter("connected", true)
+	r.failed = r.stats.counter("connect_failed", true)
+	r.closed = r.stats.counter("disconnected", true)
+	r.connect = r.stats.histogram("connect")
+	return r, nil
+}
+
+// error prints the error, at most maxErrors times for each kind of error.
+func (r *runner) error(kind string, err error) {
+	v, _ := r.errs.LoadOrStore(kind, new(int64))
+	if atomic.AddInt64(v.(*int64), 1) <= maxErrors {
+		fmt.Fprintf(r.out, "%s: %s\n", kind, err)
+	}
+}
+
+func (r *runner) clientOptions(t *template, v *voucher) *clientOptions {
+	o := &clientOptions{
+		addr:       opts.broker,
+		tlsConfig:  r.tlsConfig,
+		version:    r.version,
+		clientID:   t.render(opts.clientID),
+		username:   t.render(opts.username),
+		password:   t.render(opts.password),
+		keepAlive:  opts.keepAlive,
+		cleanStart: opts.cleanStart,
+		timeout:    opts.timeout,
+	}
+	if v != nil {
+		o.username, o.password = v.username, v.password
+	}
+	return o
+}
+
+// run connects the clients with the rate limit, and calls fn for each connected client in a new goroutine.
+// setup is called before connecting to set up the client options, e.g. the message handler.
+// It blocks until the duration is reached or interrupted, or all fn calls return if stopWhenDone is true.
+func (r *runner) run(setup func(s *session, o *clientOptions), fn func(ctx context.Context, s *session), stopWhenDone bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if opts.duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	interval := opts.reportInterval
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				r.stats.report(r.out, interval)
+			}
+		}
+	}()
+
+	var limiter <-chan time.Time
+	if opts.rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer t.Stop()
+		limiter = t.C
+	}
+	var mu sync.Mutex
+	var sessions []*session
+	var wg sync.WaitGroup
+	for i := 0; i < opts.count; i++ {
+		if limiter != nil {
+			select {
+			case <-ctx.Done():
+			case <-limiter:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		seq := opts.start + i
+		var v *voucher
+		deviceNumber := strconv.Itoa(seq)
+		if r.vouchers != nil {
+			v = r.vouchers[i]
+			if v.deviceNumber != "" {
+				deviceNumber = v.deviceNumber
+			}
+		}
+		s := &session{seq: seq, t: newTemplate(seq, deviceNumber)}
+		o := r.clientOptions(s.t, v)
+		if setup != nil {
+			setup(s, o)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			c, err := connect(o)
+			if err != nil {
+				r.failed.add(1)
+				r.error("connect error", fmt.Errorf("%s: %s", o.clientID, err))
+				return
+			}
+			r.connect.record(time.Since(start))
+			r.connected.add(1)
+			s.c = c
+			mu.Lock()
+			sessions = append(sessions, s)
+			mu.Unlock()
+			go func() {
+				select {
+				case <-c.done():
+					if c.err != errClosed {
+						r.connected.add(-1)
+						r.closed.add(1)
+						r.error("connection lost", fmt.Errorf("%s: %s", o.clientID, c.err))
+					}
+				case <-ctx.Done():
+				}
+			}()
+			if fn != nil {
+				fn(ctx, s)
+			}
+		}()
+	}
+	if stopWhenDone {
+		go func() {
+			wg.Wait()
+			cancel()
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+	mu.Lock()
+	for _, s := range sessions {
+		s.c.disconnect()
+	}
+	mu.Unlock()
+	r.stats.summary(r.out)
+}
@@ -0,0 +1,23 @@
+package bench
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var connCmd = &cobra.Command{
+	Use:   "conn",
+	Short: "Benchmark the connections",
+	Long:  "Connect the clients with the given rate and keep them connected, reports the connect latency.",
+	Example: "gmqctl bench conn -c 10000 -R 500 -d 5m\n" +
+		"gmqctl bench conn --voucher ./vouchers.csv -R 200",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := newRunner("conn")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		r.run(nil, nil, false)
+		return nil
+	},
+}
@@ -0,0 +1,157 @@
+package bench
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// The publish timestamp is carried in the user property (v5) or the header of the generated payload (v3),
+// which is used by the bench sub command to calculate the publish-to-receive latency.
+const (
+	timestampProperty = "gmqctl-bench-ts"
+	payloadMagic      = "GMQB"
+	payloadHeaderSize = len(payloadMagic) + 8
+)
+
+var pubOpts struct {
+	topic    string
+	qos      uint8
+	retain   bool
+	size     int
+	payload  string
+	interval time.Duration
+	limit    int
+}
+
+var pubCmd = &cobra.Command{
+	Use:   "pub",
+	Short: "Benchmark the publishing",
+	Long: "Each client publishes messages in the given interval, reports the publish throughput and the latency of the acknowledgements.\n" +
+		"The publish timestamp is carried in the messages, use the bench sub command to measure the publish-to-receive latency.\n" +
+		"For MQTT v3, the timestamp is only carried in the generated payload (without --payload).",
+	Example: "gmqctl bench pub -c 1000 -t 'bench/{seq}' -q 1 -s 512 -I 100ms\n" +
+		"gmqctl bench pub --voucher ./vouchers.csv -t devices/telemetry -m '{\"temperature\":{seq}}'",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pubOpts.topic == "" {
+			return fmt.Errorf("--topic is required")
+		}
+		if pubOpts.qos > packets.Qos2 {
+			return fmt.Errorf("invalid qos: %d", pubOpts.qos)
+		}
+		r, err := newRunner("pub")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		runPub(r)
+		return nil
+	},
+}
+
+func init() {
+	f := pubCmd.Flags()
+	f.StringVarP(&pubOpts.topic, "topic", "t", "", "The topic template.")
+	f.Uint8VarP(&pubOpts.qos, "qos", "q", 0, "The QoS of the messages.")
+	f.BoolVarP(&pubOpts.retain, "retain", "r", false, "Whether the messages are retained.")
+	f.IntVarP(&pubOpts.size, "size", "s", 256, "The size of the generated payload.")
+	f.StringVarP(&pubOpts.payload, "payload", "m", "", "The payload template, overrides --size.")
+	f.DurationVarP(&pubOpts.interval, "interval", "I", time.Second, "The publish interval of each client, 0 means publishing as fast as possible.")
+	f.IntVarP(&pubOpts.limit, "limit", "L", 0, "The number of messages published by each client, 0 means no limit. The benchmark stops when all clients finish.")
+}
+
+// newPayload returns the generated payload with the timestamp header if the size is enough.
+func newPayload(size int, now time.Time) []byte {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = 'x'
+	}
+	if size >= payloadHeaderSize {
+		copy(b, payloadMagic)
+		binary.BigEndian.PutUint64(b[len(payloadMagic):], uint64(now.UnixNano()))
+	}
+	return b
+}
+
+// publishTime returns the publish timestamp carried in the message.
+func publishTime(p *packets.Publish) (time.Time, bool) {
+	if p.Properties != nil {
+		for _, v := range p.Properties.User {
+			if string(v.K) == timestampProperty {
+				ns, err := strconv.ParseInt(string(v.V), 10, 64)
+				if err != nil {
+					return time.Time{}, false
+				}
+				return time.Unix(0, ns), true
+			}
+		}
+	}
+	if len(p.Payload) >= payloadHeaderSize && string(p.Payload[:len(payloadMagic)]) == payloadMagic {
+		return time.Unix(0, int64(binary.BigEndian.Uint64(p.Payload[len(payloadMagic):]))), true
+	}
+	return time.Time{}, false
+}
+
+func runPub(r *runner) {
+	published := r.stats.counter("published", false)
+	failed := r.stats.counter("publish_failed", false)
+	ack := r.stats.histogram("publish")
+	r.run(nil, func(ctx context.Context, s *session) {
+		topic := s.t.render(pubOpts.topic)
+		var payload []byte
+		if pubOpts.payload != "" {
+			payload = []byte(s.t.render(pubOpts.payload))
+		}
+		var ticker *time.Ticker
+		if pubOpts.interval > 0 {
+			ticker = time.NewTicker(pubOpts.interval)
+			defer ticker.Stop()
+		}
+		for n := 0; pubOpts.limit == 0 || n < pubOpts.limit; n++ {
+			if ticker != nil && n != 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.c.done():
+					return
+				case <-ticker.C:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.c.done():
+				return
+			default:
+			}
+			now := time.Now()
+			var properties *packets.Properties
+			p := payload
+			if r.version == packets.Version5 {
+				properties = &packets.Properties{
+					User: []packets.UserProperty{
+						{K: []byte(timestampProperty), V: []byte(strconv.FormatInt(now.UnixNano(), 10))},
+					},
+				}
+			}
+			if p == nil {
+				p = newPayload(pubOpts.size, now)
+			}
+			err := s.c.publish(topic, pubOpts.qos, pubOpts.retain, p, properties)
+			if err != nil {
+				failed.add(1)
+				r.error("publish error", fmt.Errorf("%s: %s", s.c.opts.clientID, err))
+				continue
+			}
+			ack.record(time.Since(now))
+			published.add(1)
+		}
+	}, pubOpts.limit > 0)
+}
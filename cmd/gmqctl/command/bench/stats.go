@@ -0,0 +1,175 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSamples is the maximum number of the latency samples kept by the histogram.
+// When exceeded, the samples are replaced randomly (reservoir sampling), so the percentiles are still representative.
+const maxSamples = 1000000
+
+// histogram records the latencies and calculates the percentiles.
+type histogram struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	count   int64
+	sum     time.Duration
+	max     time.Duration
+	rand    *rand.Rand
+}
+
+func newHistogram() *histogram {
+	return &histogram{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.count++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+	if len(h.samples) < maxSamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	if i := h.rand.Int63n(h.count); i < maxSamples {
+		h.samples[i] = d
+	}
+}
+
+// percentiles returns the latencies at the given percentiles, e.g. 50, 99, 99.9.
+func (h *histogram) percentiles(ps ...float64) []time.Duration {
+	h.mu.Lock()
+	s := make([]time.Duration, len(h.samples))
+	copy(s, h.samples)
+	h.mu.Unlock()
+	rs := make([]time.Duration, len(ps))
+	if len(s) == 0 {
+		return rs
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	for k, p := range ps {
+		i := int(float64(len(s))*p/100+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(s) {
+			i = len(s) - 1
+		}
+		rs[k] = s[i]
+	}
+	return rs
+}
+
+// summary returns the summary of the latencies.
+func (h *histogram) summary() string {
+	h.mu.Lock()
+	count, sum, max := h.count, h.sum, h.max
+	h.mu.Unlock()
+	if count == 0 {
+		return "no samples"
+	}
+	p := h.percentiles(50, 90, 99, 99.9)
+	return fmt.Sprintf("avg: %s, p50: %s, p90: %s, p99: %s, p99.9: %s, max: %s",
+		round(sum/time.Duration(count)), round(p[0]), round(p[1]), round(p[2]), round(p[3]), round(max))
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d > time.Second:
+		return d.Round(time.Millisecond)
+	case d > time.Millisecond:
+		return d.Round(time.Microsecond)
+	}
+	return d
+}
+
+// counter is a counter that reports the rate between two reports.
+type counter struct {
+	name  string
+	n     int64
+	last  int64
+	total bool
+}
+
+func (c *counter) add(n int64) {
+	atomic.AddInt64(&c.n, n)
+}
+
+func (c *counter) load() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// stats is the statistics of a benchmark.
+type stats struct {
+	start     time.Time
+	counters  []*counter
+	latencies []*histogramStat
+}
+
+type histogramStat struct {
+	name string
+	h    *histogram
+}
+
+func newStats() *stats {
+	return &stats{start: time.Now()}
+}
+
+// counter returns a new counter, if total is true, the report prints the current value instead of the rate.
+func (s *stats) counter(name string, total bool) *counter {
+	c := &counter{name: name, total: total}
+	s.counters = append(s.counters, c)
+	return c
+}
+
+func (s *stats) histogram(name string) *histogram {
+	h := newHistogram()
+	s.latencies = append(s.latencies, &histogramStat{name: name, h: h})
+	return h
+}
+
+// report prints the rates of the counters since the last report.
+func (s *stats) report(w io.Writer, interval time.Duration) {
+	line := fmt.Sprintf("[%s]", time.Since(s.start).Round(time.Second))
+	for _, c := range s.counters {
+		n := c.load()
+		if c.total {
+			line += fmt.Sprintf(" %s: %d", c.name, n)
+			continue
+		}
+		line += fmt.Sprintf(" %s: %d (%.1f/s)", c.name, n, float64(n-c.last)/interval.Seconds())
+		c.last = n
+	}
+	fmt.Fprintln(w, line)
+}
+
+// summary prints the totals, the average rates and the latency percentiles.
+func (s *stats) summary(w io.Writer) {
+	elapsed := time.Since(s.start)
+	fmt.Fprintf(w, "\n===== summary (%s) =====\n", elapsed.Round(time.Millisecond))
+	for _, c := range s.counters {
+		n := c.load()
+		if c.total {
+			fmt.Fprintf(w, "%s: %d\n", c.name, n)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d, throughput: %.1f/s\n", c.name, n, float64(n)/elapsed.Seconds())
+	}
+	for _, v := range s.latencies {
+		fmt.Fprintf(w, "%s latency: %s\n", v.name, v.h.summary())
+	}
+}
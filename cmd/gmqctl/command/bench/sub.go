@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+var subOpts struct {
+	topics []string
+	qos    uint8
+}
+
+var subCmd = &cobra.Command{
+	Use:   "sub",
+	Short: "Benchmark the subscribing",
+	Long: "Each client subscribes the topics, reports the receive throughput and the publish-to-receive latency of the messages\n" +
+		"published by the bench pub command. The latency is accurate only if the clocks of the publisher and the subscriber are synchronized.",
+	Example: "gmqctl bench sub -c 100 -t 'bench/{seq}' -q 1\n" +
+		"gmqctl bench sub -c 10 -t '$share/bench/devices/telemetry'",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(subOpts.topics) == 0 {
+			return fmt.Errorf("--topic is required")
+		}
+		if subOpts.qos > packets.Qos2 {
+			return fmt.Errorf("invalid qos: %d", subOpts.qos)
+		}
+		r, err := newRunner("sub")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		runSub(r)
+		return nil
+	},
+}
+
+func init() {
+	f := subCmd.Flags()
+	f.StringArrayVarP(&subOpts.topics, "topic", "t", nil, "The topic filter template, can be set multiple times.")
+	f.Uint8VarP(&subOpts.qos, "qos", "q", 0, "The QoS of the subscriptions.")
+}
+
+func runSub(r *runner) {
+	received := r.stats.counter("received", false)
+	subscribed := r.stats.counter("subscribed", true)
+	latency := r.stats.histogram("publish-to-receive")
+	r.run(func(s *session, o *clientOptions) {
+		o.onMessage = func(p *packets.Publish) {
+			received.add(1)
+			if t, ok := publishTime(p); ok {
+				latency.record(time.Since(t))
+			}
+		}
+	}, func(ctx context.Context, s *session) {
+		var topics []string
+		for _, v := range subOpts.topics {
+			topics = append(topics, s.t.render(v))
+		}
+		err := s.c.subscribe(subOpts.qos, topics...)
+		if err != nil {
+			r.error("subscribe error", fmt.Errorf("%s: %s", s.c.opts.clientID, err))
+			return
+		}
+		subscribed.add(1)
+	}, false)
+}
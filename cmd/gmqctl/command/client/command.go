@@ -0,0 +1,129 @@
+package client
+
+import (
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+var (
+	page         uint32
+	pageSize     uint32
+	cleanSession bool
+	cluster      bool
+)
+
+// Command is the command for client management.
+var Command = &cobra.Command{
+	Use:   "client",
+	Short: "Manage the clients",
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clients",
+	Args:  cobra.NoArgs,
+	RunE:  ctl.Run(list),
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get <client_id>",
+	Short: "Get the client",
+	Args:  cobra.ExactArgs(1),
+	RunE:  ctl.Run(get),
+}
+
+var kickCmd = &cobra.Command{
+	Use:   "kick <client_id>",
+	Short: "Disconnect the client",
+	Args:  cobra.ExactArgs(1),
+	RunE:  ctl.Run(kick),
+}
+
+func init() {
+	ctl.AddFlags(Command)
+	listCmd.Flags().Uint32Var(&page, "page", 1, "The page number.")
+	listCmd.Flags().Uint32Var(&pageSize, "page-size", 20, "The page size.")
+	kickCmd.Flags().BoolVar(&cleanSession, "clean-session", false, "Whether to remove the session of the client.")
+	for _, v := range []*cobra.Command{listCmd, getCmd, kickCmd} {
+		v.Flags().BoolVar(&cluster, "cluster", false, "Operate on all nodes in the cluster, requires the federation plugin.")
+	}
+	Command.AddCommand(listCmd, getCmd, kickCmd)
+}
+
+func clientsTable(clients ...*admin.Client) *ctl.Table {
+	t := &ctl.Table{
+		Header: []string{"CLIENT ID", "USERNAME", "VERSION", "REMOTE ADDR", "CONNECTED AT", "DISCONNECTED AT", "SUBSCRIPTIONS", "INFLIGHT", "QUEUE", "DROPPED"},
+	}
+	if cluster {
+		t.Header = append([]string{"NODE"}, t.Header...)
+	}
+	for _, v := range clients {
+		var row []string
+		if cluster {
+			row = append(row, v.NodeName)
+		}
+		t.Rows = append(t.Rows, append(row,
+			v.ClientId,
+			v.Username,
+			strconv.Itoa(int(v.Version)),
+			v.RemoteAddr,
+			ctl.FormatTime(v.ConnectedAt),
+			ctl.FormatTime(v.DisconnectedAt),
+			strconv.Itoa(int(v.SubscriptionsCurrent)),
+			strconv.Itoa(int(v.InflightLen))+"/"+strconv.Itoa(int(v.MaxInflight)),
+			strconv.Itoa(int(v.QueueLen))+"/"+strconv.Itoa(int(v.MaxQueue)),
+			strconv.FormatUint(v.MessageDropped, 10),
+		))
+	}
+	return t
+}
+
+func list(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := admin.NewClientServiceClient(conn).List(ctx, &admin.ListClientRequest{
+		PageSize: pageSize,
+		Page:     page,
+		Cluster:  cluster,
+	})
+	if err != nil {
+		return err
+	}
+	if err = ctl.Print(resp, clientsTable(resp.Clients...)); err != nil {
+		return err
+	}
+	return ctl.PrintNodeErrors(resp.NodeErrors)
+}
+
+func get(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := admin.NewClientServiceClient(conn).Get(ctx, &admin.GetClientRequest{
+		ClientId: args[0],
+		Cluster:  cluster,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.Print(resp, clientsTable(resp.Client))
+}
+
+func kick(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := admin.NewClientServiceClient(conn).Delete(ctx, &admin.DeleteClientRequest{
+		ClientId:     args[0],
+		CleanSession: cleanSession,
+		Cluster:      cluster,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "client "+args[0]+" kicked")
+}
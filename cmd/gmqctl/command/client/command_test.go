@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+type testClientService struct {
+	admin.UnimplementedClientServiceServer
+	authorization []string
+	deleteReq     *admin.DeleteClientRequest
+}
+
+func (t *testClientService) List(ctx context.Context, req *admin.ListClientRequest) (*admin.ListClientResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	t.authorization = md.Get("authorization")
+	if req.Cluster {
+		return &admin.ListClientResponse{
+			Clients: []*admin.Client{
+				{ClientId: "c1", Username: "u1", Version: 5, NodeName: "node1"},
+			},
+			TotalCount: 1,
+			NodeErrors: map[string]string{"node2": "unavailable"},
+		}, nil
+	}
+	return &admin.ListClientResponse{
+		Clients: []*admin.Client{
+			{ClientId: "c1", Username: "u1", Version: 5, MaxInflight: 10, MaxQueue: 100},
+		},
+		TotalCount: 1,
+	}, nil
+}
+
+func (t *testClientService) Delete(ctx context.Context, req *admin.DeleteClientRequest) (*empty.Empty, error) {
+	t.deleteReq = req
+	return &empty.Empty{}, nil
+}
+
+func TestCommand(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqctl")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	sock := path.Join(dir, "gmqttd.sock")
+	l, err := net.Listen("unix", sock)
+	a.NoError(err)
+	srv := grpc.NewServer()
+	svc := &testClientService{}
+	admin.RegisterClientServiceServer(srv, svc)
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	buf := &bytes.Buffer{}
+	ctl.Out = buf
+	defer func() { ctl.Out = os.Stdout }()
+
+	Command.SetArgs([]string{"list", "--addr", "unix://" + sock, "--token", "abc"})
+	a.NoError(Command.Execute())
+	a.Equal([]string{"Bearer abc"}, svc.authorization)
+	a.Contains(buf.String(), "c1         u1        5")
+
+	buf.Reset()
+	Command.SetArgs([]string{"kick", "c1", "--clean-session", "--addr", "unix://" + sock, "-o", "json"})
+	a.NoError(Command.Execute())
+	a.Equal("c1", svc.deleteReq.ClientId)
+	a.True(svc.deleteReq.CleanSession)
+	a.Equal("{}\n", buf.String())
+
+	buf.Reset()
+	Command.SetArgs([]string{"list", "--cluster", "--addr", "unix://" + sock, "-o", "table"})
+	a.NoError(Command.Execute())
+	a.Contains(buf.String(), "node1  c1")
+	a.Contains(buf.String(), "node node2 failed: unavailable")
+}
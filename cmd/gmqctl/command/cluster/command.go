@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/federation"
+)
+
+var force string
+
+// Command is the command for the cluster management of the federation plugin.
+var Command = &cobra.Command{
+	Use:   "cluster",
+	Short: "Manage the cluster of the federation plugin",
+}
+
+var membersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "List the members of the cluster",
+	Args:  cobra.NoArgs,
+	RunE:  ctl.Run(members),
+}
+
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "List the state of the event streams to the peers",
+	Args:  cobra.NoArgs,
+	RunE:  ctl.Run(peers),
+}
+
+var joinCmd = &cobra.Command{
+	Use:   "join <host>...",
+	Short: "Join the local node to an existing cluster",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  ctl.Run(join),
+}
+
+var leaveCmd = &cobra.Command{
+	Use:   "leave",
+	Short: "Leave the cluster gracefully",
+	Long: "Leave the cluster gracefully. The leaved node cannot re-join the cluster unless it is restarted.\n" +
+		"With --force, force the member to enter the \"left\" state, which is used to remove the failed node.",
+	Args: cobra.NoArgs,
+	RunE: ctl.Run(leave),
+}
+
+func init() {
+	ctl.AddFlags(Command)
+	leaveCmd.Flags().StringVar(&force, "force", "", "The name of the member to force leave.")
+	Command.AddCommand(membersCmd, peersCmd, joinCmd, leaveCmd)
+}
+
+func members(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := federation.NewMembershipClient(conn).ListMembers(ctx, &empty.Empty{})
+	if err != nil {
+		return err
+	}
+	t := &ctl.Table{
+		Header: []string{"NAME", "ADDR", "STATUS", "TAGS"},
+	}
+	for _, v := range resp.Members {
+		var tags []string
+		for k, v := range v.Tags {
+			tags = append(tags, k+"="+v)
+		}
+		sort.Strings(tags)
+		t.Rows = append(t.Rows, []string{
+			v.Name,
+			v.Addr,
+			strings.ToLower(strings.TrimPrefix(v.Status.String(), "STATUS_")),
+			strings.Join(tags, ","),
+		})
+	}
+	return ctl.Print(resp, t)
+}
+
+func peers(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	resp, err := federation.NewMembershipClient(conn).ListPeers(ctx, &empty.Empty{})
+	if err != nil {
+		return err
+	}
+	t := &ctl.Table{
+		Header: []string{"NAME", "ADDR", "CONNECTED", "BATCH", "QUEUE", "NEXT_ID", "ACKED_ID", "RTT", "RECONNECTS", "DROPPED", "OVERFLOWS"},
+	}
+	for _, v := range resp.Peers {
+		t.Rows = append(t.Rows, []string{
+			v.Name,
+			v.Addr,
+			strconv.FormatBool(v.Connected),
+			strconv.FormatBool(v.Batch),
+			strconv.FormatUint(v.QueueLength, 10),
+			strconv.FormatUint(v.NextEventId, 10),
+			strconv.FormatUint(v.AckedEventId, 10),
+			time.Duration(v.RttMs * float64(time.Millisecond)).String(),
+			strconv.FormatUint(v.Reconnects, 10),
+			strconv.FormatUint(v.DroppedEvents, 10),
+			strconv.FormatUint(v.OverflowDisconnects, 10),
+		})
+	}
+	return ctl.Print(resp, t)
+}
+
+func join(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := federation.NewMembershipClient(conn).Join(ctx, &federation.JoinRequest{
+		Hosts: args,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "joined")
+}
+
+func leave(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	cli := federation.NewMembershipClient(conn)
+	if force != "" {
+		_, err := cli.ForceLeave(ctx, &federation.ForceLeaveRequest{
+			NodeName: force,
+		})
+		if err != nil {
+			return err
+		}
+		return ctl.PrintDone(&empty.Empty{}, "member "+force+" left")
+	}
+	_, err := cli.Leave(ctx, &empty.Empty{})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "left")
+}
@@ -0,0 +1,278 @@
+// Package ctl provides the common connection and output options for the gmqctl management commands.
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// DefaultAddr is the default gRPC endpoint of gmqttd.
+	DefaultAddr = "unix://./gmqttd.sock"
+
+	// OutputTable prints the result as a table.
+	OutputTable = "table"
+	// OutputJSON prints the result as JSON.
+	OutputJSON = "json"
+)
+
+// Environment variables that provide the default values of the flags.
+const (
+	EnvAddr  = "GMQCTL_ADDR"
+	EnvToken = "GMQCTL_TOKEN"
+)
+
+// Options is the connection and output options shared by the management commands.
+type Options struct {
+	// Addr is the gRPC endpoint, format: [tcp|unix://][<host>]:<port>, e.g. unix://./gmqttd.sock, tcp://127.0.0.1:8084.
+	Addr string
+	// Token is the API token of the admin APIs, sent as "authorization: Bearer <token>".
+	Token   string
+	Timeout time.Duration
+	Output  string
+	TLS     TLSOptions
+}
+
+// TLSOptions is the client side TLS options.
+type TLSOptions struct {
+	Enabled            bool
+	CACert             string
+	Cert               string
+	Key                string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// AddFlags adds the TLS flags to the flag set.
+func (o *TLSOptions) AddFlags(f *pflag.FlagSet) {
+	f.BoolVar(&o.Enabled, "tls", false, "Whether to connect with TLS, implied by the other tls flags.")
+	f.StringVar(&o.CACert, "tls-cacert", "", "The CA certificate file to verify the server.")
+	f.StringVar(&o.Cert, "tls-cert", "", "The client certificate file.")
+	f.StringVar(&o.Key, "tls-key", "", "The client key file.")
+	f.StringVar(&o.ServerName, "tls-server-name", "", "The server name to verify the server certificate.")
+	f.BoolVar(&o.InsecureSkipVerify, "tls-insecure-skip-verify", false, "Whether to skip the verification of the server certificate.")
+}
+
+// Config returns the TLS config, or nil if TLS is not enabled.
+func (o *TLSOptions) Config() (*tls.Config, error) {
+	if !o.Enabled && o.CACert == "" && o.Cert == "" && o.Key == "" && o.ServerName == "" && !o.InsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if o.CACert != "" {
+		b, err := ioutil.ReadFile(o.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("invalid ca certificate: %s", o.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.Cert != "" || o.Key != "" {
+		c, err := tls.LoadX509KeyPair(o.Cert, o.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{c}
+	}
+	return cfg, nil
+}
+
+var (
+	options Options
+	// Out is the writer of the command output.
+	Out io.Writer = os.Stdout
+)
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// AddFlags adds the persistent connection and output flags to the command.
+func AddFlags(cmd *cobra.Command) {
+	f := cmd.PersistentFlags()
+	f.StringVarP(&options.Addr, "addr", "a", envOr(EnvAddr, DefaultAddr), "The gRPC endpoint of gmqttd, e.g. unix://./gmqttd.sock, tcp://127.0.0.1:8084. Env: "+EnvAddr)
+	f.StringVar(&options.Token, "token", os.Getenv(EnvToken), "The API token of the admin APIs. Env: "+EnvToken)
+	f.DurationVar(&options.Timeout, "timeout", 10*time.Second, "The timeout of the request.")
+	f.StringVarP(&options.Output, "output", "o", OutputTable, "The output format: table | json.")
+	options.TLS.AddFlags(f)
+}
+
+// target returns the gRPC dial target of the endpoint.
+func target(addr string) (string, error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) == 1 {
+		return addr, nil
+	}
+	switch parts[0] {
+	case "tcp":
+		return parts[1], nil
+	case "unix":
+		if parts[1] == "" {
+			return "", errors.New("empty unix socket path")
+		}
+		// unix:relative/path or unix:/absolute/path
+		return "unix:" + parts[1], nil
+	default:
+		return "", fmt.Errorf("invalid endpoint schema: %s", parts[0])
+	}
+}
+
+// Dial connects to the gRPC endpoint.
+func Dial() (*grpc.ClientConn, error) {
+	t, err := target(options.Addr)
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithBlock(), grpc.FailOnNonTempDialError(true)}
+	cfg, err := options.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, t, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %s", options.Addr, err)
+	}
+	return conn, nil
+}
+
+// Context returns the context of the request, which carries the token and the timeout.
+func Context() (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if options.Token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+options.Token)
+	}
+	return context.WithTimeout(ctx, options.Timeout)
+}
+
+// Run returns the cobra run function which dials the endpoint and calls fn.
+func Run(fn func(conn *grpc.ClientConn, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		// the arguments are valid, do not print the usage on request errors.
+		cmd.SilenceUsage = true
+		switch options.Output {
+		case OutputTable, OutputJSON:
+		default:
+			return fmt.Errorf("invalid output format: %s", options.Output)
+		}
+		conn, err := Dial()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		err = fn(conn, args)
+		if s, ok := status.FromError(err); ok && err != nil {
+			return fmt.Errorf("%s: %s", s.Code(), s.Message())
+		}
+		return err
+	}
+}
+
+// Table is the table presentation of the result.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Print prints the response message as JSON or the table, according to the output flag.
+// A nil table prints nothing in table format.
+func Print(m proto.Message, table *Table) error {
+	if options.Output == OutputJSON {
+		b, err := protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		}.Marshal(proto.MessageV2(m))
+		if err != nil {
+			return err
+		}
+		// the whitespaces of protojson output are unstable on purpose, reformat it.
+		buf := &bytes.Buffer{}
+		if err = json.Indent(buf, b, "", "  "); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(Out, buf.String())
+		return err
+	}
+	if table == nil {
+		return nil
+	}
+	w := tabwriter.NewWriter(Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(table.Header, "\t"))
+	for _, v := range table.Rows {
+		fmt.Fprintln(w, strings.Join(v, "\t"))
+	}
+	return w.Flush()
+}
+
+// PrintDone prints the result of the operations that have no response.
+func PrintDone(m proto.Message, msg string) error {
+	if options.Output == OutputJSON {
+		return Print(m, nil)
+	}
+	_, err := fmt.Fprintln(Out, msg)
+	return err
+}
+
+// PrintNodeErrors prints the errors of the nodes which failed to respond in the cluster-wide queries.
+// It prints nothing in JSON format, in which the errors are the part of the response.
+func PrintNodeErrors(errs map[string]string) error {
+	if options.Output == OutputJSON || len(errs) == 0 {
+		return nil
+	}
+	var nodes []string
+	for k := range errs {
+		nodes = append(nodes, k)
+	}
+	sort.Strings(nodes)
+	for _, v := range nodes {
+		if _, err := fmt.Fprintf(Out, "node %s failed: %s\n", v, errs[v]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatTime formats the timestamp, returns empty string if it is not set.
+func FormatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil || (ts.Seconds == 0 && ts.Nanos == 0) {
+		return ""
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
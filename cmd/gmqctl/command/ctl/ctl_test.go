@@ -0,0 +1,83 @@
+package ctl
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+func TestTarget(t *testing.T) {
+	a := assert.New(t)
+	var tt = []struct {
+		addr   string
+		target string
+		err    bool
+	}{
+		{addr: "unix://./gmqttd.sock", target: "unix:./gmqttd.sock"},
+		{addr: "unix:///var/run/gmqttd.sock", target: "unix:/var/run/gmqttd.sock"},
+		{addr: "tcp://127.0.0.1:8084", target: "127.0.0.1:8084"},
+		{addr: "127.0.0.1:8084", target: "127.0.0.1:8084"},
+		{addr: "unix://", err: true},
+		{addr: "http://127.0.0.1:8083", err: true},
+	}
+	for _, v := range tt {
+		target, err := target(v.addr)
+		if v.err {
+			a.Error(err, v.addr)
+			continue
+		}
+		a.NoError(err, v.addr)
+		a.Equal(v.target, target)
+	}
+}
+
+func TestContext(t *testing.T) {
+	a := assert.New(t)
+	defer func(o Options) { options = o }(options)
+	options.Token = "abc"
+	options.Timeout = time.Second
+	ctx, cancel := Context()
+	defer cancel()
+	md, _ := metadata.FromOutgoingContext(ctx)
+	a.Equal([]string{"Bearer abc"}, md.Get("authorization"))
+
+	options.Token = ""
+	ctx, cancel = Context()
+	defer cancel()
+	md, _ = metadata.FromOutgoingContext(ctx)
+	a.Len(md.Get("authorization"), 0)
+	_, ok := ctx.Deadline()
+	a.True(ok)
+}
+
+func TestPrint(t *testing.T) {
+	a := assert.New(t)
+	defer func(o Options, out io.Writer) {
+		options = o
+		Out = out
+	}(options, Out)
+	buf := &bytes.Buffer{}
+	Out = buf
+	resp := &admin.GetClientResponse{
+		Client: &admin.Client{ClientId: "cid", Username: "u"},
+	}
+	table := &Table{
+		Header: []string{"CLIENT ID", "USERNAME"},
+		Rows:   [][]string{{"cid", "u"}},
+	}
+
+	options.Output = OutputTable
+	a.NoError(Print(resp, table))
+	a.Equal("CLIENT ID  USERNAME\ncid        u\n", buf.String())
+
+	buf.Reset()
+	options.Output = OutputJSON
+	a.NoError(Print(resp, table))
+	a.Contains(buf.String(), "{\n  \"client\": {\n    \"client_id\": \"cid\",\n    \"username\": \"u\",")
+}
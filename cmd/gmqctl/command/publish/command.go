@@ -0,0 +1,93 @@
+package publish
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+var (
+	payload         string
+	payloadFile     string
+	qos             uint32
+	retained        bool
+	contentType     string
+	correlationData string
+	messageExpiry   uint32
+	payloadFormat   uint32
+	responseTopic   string
+	userProperties  []string
+)
+
+// Command is the command to publish a message to the broker.
+var Command = &cobra.Command{
+	Use:   "publish <topic_name>",
+	Short: "Publish a message to the broker",
+	Example: "gmqctl publish devices/dev1/control -m '{\"switch\":1}' -q 1\n" +
+		"gmqctl publish devices/dev1/control -f ./payload.json -u k1=v1 -u k2=v2",
+	Args: cobra.ExactArgs(1),
+	RunE: ctl.Run(publish),
+}
+
+func init() {
+	ctl.AddFlags(Command)
+	f := Command.Flags()
+	f.StringVarP(&payload, "message", "m", "", "The payload of the message.")
+	f.StringVarP(&payloadFile, "file", "f", "", "Read the payload from the file.")
+	f.Uint32VarP(&qos, "qos", "q", 0, "The QoS of the message.")
+	f.BoolVarP(&retained, "retain", "r", false, "Whether the message is retained.")
+	f.StringVar(&contentType, "content-type", "", "The content type of the message (v5).")
+	f.StringVar(&correlationData, "correlation-data", "", "The correlation data of the message (v5).")
+	f.Uint32Var(&messageExpiry, "message-expiry", 0, "The message expiry interval in seconds (v5).")
+	f.Uint32Var(&payloadFormat, "payload-format", 0, "The payload format indicator (v5).")
+	f.StringVar(&responseTopic, "response-topic", "", "The response topic of the message (v5).")
+	f.StringArrayVarP(&userProperties, "user-property", "u", nil, "The user property in key=value format, can be set multiple times (v5).")
+}
+
+func publish(conn *grpc.ClientConn, args []string) error {
+	if payloadFile != "" {
+		if payload != "" {
+			return fmt.Errorf("--message and --file cannot be set together")
+		}
+		b, err := ioutil.ReadFile(payloadFile)
+		if err != nil {
+			return err
+		}
+		payload = string(b)
+	}
+	req := &admin.PublishRequest{
+		TopicName:       args[0],
+		Payload:         payload,
+		Qos:             qos,
+		Retained:        retained,
+		ContentType:     contentType,
+		CorrelationData: correlationData,
+		MessageExpiry:   messageExpiry,
+		PayloadFormat:   payloadFormat,
+		ResponseTopic:   responseTopic,
+	}
+	for _, v := range userProperties {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid user property: %s", v)
+		}
+		req.UserProperties = append(req.UserProperties, &admin.UserProperties{
+			K: []byte(kv[0]),
+			V: []byte(kv[1]),
+		})
+	}
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := admin.NewPublishServiceClient(conn).Publish(ctx, req)
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "published")
+}
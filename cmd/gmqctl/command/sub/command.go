@@ -0,0 +1,231 @@
+package sub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/ctl"
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+var (
+	page     uint32
+	pageSize uint32
+
+	clientID   string
+	topic      string
+	match      string
+	filterType string
+	limit      int32
+	cluster    bool
+
+	qos               uint32
+	id                uint32
+	noLocal           bool
+	retainAsPublished bool
+	retainHandling    uint32
+)
+
+// Command is the command for subscription management.
+var Command = &cobra.Command{
+	Use:   "sub",
+	Short: "Manage the subscriptions",
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the subscriptions",
+	Example: "List the subscriptions page by page:\n" +
+		"gmqctl sub list --page 2\n" +
+		"\n" +
+		"List the subscriptions of the client that match the topic:\n" +
+		"gmqctl sub list --client-id dev1 --topic devices/dev1/attributes --match filter",
+	Args: cobra.NoArgs,
+	RunE: ctl.Run(list),
+}
+
+var addCmd = &cobra.Command{
+	Use:   "add <client_id> <topic_filter>...",
+	Short: "Subscribe the topics for the client",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  ctl.Run(add),
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <client_id> <topic_filter>...",
+	Short: "Unsubscribe the topics for the client",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  ctl.Run(rm),
+}
+
+func init() {
+	ctl.AddFlags(Command)
+	f := listCmd.Flags()
+	f.Uint32Var(&page, "page", 1, "The page number, only used when no filter is set.")
+	f.Uint32Var(&pageSize, "page-size", 20, "The page size, only used when no filter is set.")
+	f.StringVar(&clientID, "client-id", "", "Only list the subscriptions of the client.")
+	f.StringVar(&topic, "topic", "", "The topic to match, requires --match.")
+	f.StringVar(&match, "match", "", "How to match the topic: name | filter.\n"+
+		"name: the topic filter of the subscription equals to the topic.\n"+
+		"filter: the topic filter of the subscription matches the topic.")
+	f.StringVar(&filterType, "type", "", "The types of the topics to list, separated by ',': sys | shared | non_shared.")
+	f.Int32Var(&limit, "limit", 0, "The maximum number of the subscriptions when any filter is set.")
+	f.BoolVar(&cluster, "cluster", false, "List the subscriptions on all nodes in the cluster, requires the federation plugin.\n"+
+		"Filters are not supported in the cluster-wide query.")
+	rmCmd.Flags().BoolVar(&cluster, "cluster", false, "Unsubscribe the topics on all nodes in the cluster, requires the federation plugin.")
+
+	f = addCmd.Flags()
+	f.Uint32VarP(&qos, "qos", "q", 0, "The QoS of the subscriptions.")
+	f.Uint32Var(&id, "id", 0, "The subscription identifier.")
+	f.BoolVar(&noLocal, "no-local", false, "The No Local option.")
+	f.BoolVar(&retainAsPublished, "retain-as-published", false, "The Retain As Published option.")
+	f.Uint32Var(&retainHandling, "retain-handling", 0, "The Retain Handling option.")
+
+	Command.AddCommand(listCmd, addCmd, rmCmd)
+}
+
+func subscriptionsTable(subs []*admin.Subscription) *ctl.Table {
+	t := &ctl.Table{
+		Header: []string{"CLIENT ID", "TOPIC", "QOS", "ID", "NO LOCAL", "RETAIN AS PUBLISHED", "RETAIN HANDLING"},
+	}
+	if cluster {
+		t.Header = append([]string{"NODE"}, t.Header...)
+	}
+	for _, v := range subs {
+		var row []string
+		if cluster {
+			row = append(row, v.NodeName)
+		}
+		t.Rows = append(t.Rows, append(row,
+			v.ClientId,
+			v.TopicName,
+			strconv.Itoa(int(v.Qos)),
+			strconv.Itoa(int(v.Id)),
+			strconv.FormatBool(v.NoLocal),
+			strconv.FormatBool(v.RetainAsPublished),
+			strconv.Itoa(int(v.RetainHandling)),
+		))
+	}
+	return t
+}
+
+// filterRequest converts the flags into the FilterSubscriptionRequest.
+// Returns nil if no filter is set.
+func filterRequest() (*admin.FilterSubscriptionRequest, error) {
+	if clientID == "" && topic == "" && match == "" && filterType == "" {
+		return nil, nil
+	}
+	req := &admin.FilterSubscriptionRequest{
+		ClientId:  clientID,
+		TopicName: topic,
+		Limit:     limit,
+	}
+	switch match {
+	case "":
+	case "name":
+		req.MatchType = admin.SubMatchType_SUB_MATCH_TYPE_MATCH_NAME
+	case "filter":
+		req.MatchType = admin.SubMatchType_SUB_MATCH_TYPE_MATCH_FILTER
+	default:
+		return nil, fmt.Errorf("invalid match type: %s", match)
+	}
+	if (topic == "") != (match == "") {
+		return nil, fmt.Errorf("--topic and --match must be set together")
+	}
+	var types []string
+	for _, v := range strings.Split(filterType, ",") {
+		switch strings.TrimSpace(v) {
+		case "":
+		case "sys":
+			types = append(types, strconv.Itoa(int(admin.SubFilterType_SUB_FILTER_TYPE_SYS)))
+		case "shared":
+			types = append(types, strconv.Itoa(int(admin.SubFilterType_SUB_FILTER_TYPE_SHARED)))
+		case "non_shared":
+			types = append(types, strconv.Itoa(int(admin.SubFilterType_SUB_FILTER_TYPE_NON_SHARED)))
+		default:
+			return nil, fmt.Errorf("invalid topic type: %s", v)
+		}
+	}
+	req.FilterType = strings.Join(types, ",")
+	return req, nil
+}
+
+func list(conn *grpc.ClientConn, args []string) error {
+	req, err := filterRequest()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	cli := admin.NewSubscriptionServiceClient(conn)
+	if req != nil && cluster {
+		return fmt.Errorf("filters are not supported with --cluster")
+	}
+	if req != nil {
+		resp, err := cli.Filter(ctx, req)
+		if err != nil {
+			return err
+		}
+		return ctl.Print(resp, subscriptionsTable(resp.Subscriptions))
+	}
+	resp, err := cli.List(ctx, &admin.ListSubscriptionRequest{
+		PageSize: pageSize,
+		Page:     page,
+		Cluster:  cluster,
+	})
+	if err != nil {
+		return err
+	}
+	if err = ctl.Print(resp, subscriptionsTable(resp.Subscriptions)); err != nil {
+		return err
+	}
+	return ctl.PrintNodeErrors(resp.NodeErrors)
+}
+
+func add(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	req := &admin.SubscribeRequest{
+		ClientId: args[0],
+	}
+	for _, v := range args[1:] {
+		req.Subscriptions = append(req.Subscriptions, &admin.Subscription{
+			TopicName:         v,
+			Id:                id,
+			Qos:               qos,
+			NoLocal:           noLocal,
+			RetainAsPublished: retainAsPublished,
+			RetainHandling:    retainHandling,
+		})
+	}
+	resp, err := admin.NewSubscriptionServiceClient(conn).Subscribe(ctx, req)
+	if err != nil {
+		return err
+	}
+	t := &ctl.Table{
+		Header: []string{"TOPIC", "NEW"},
+	}
+	for k, v := range resp.New {
+		t.Rows = append(t.Rows, []string{args[k+1], strconv.FormatBool(v)})
+	}
+	return ctl.Print(resp, t)
+}
+
+func rm(conn *grpc.ClientConn, args []string) error {
+	ctx, cancel := ctl.Context()
+	defer cancel()
+	_, err := admin.NewSubscriptionServiceClient(conn).Unsubscribe(ctx, &admin.UnsubscribeRequest{
+		ClientId: args[0],
+		Topics:   args[1:],
+		Cluster:  cluster,
+	})
+	if err != nil {
+		return err
+	}
+	return ctl.PrintDone(&empty.Empty{}, "unsubscribed")
+}
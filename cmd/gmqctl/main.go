@@ -7,6 +7,12 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/account"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/bench"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/client"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/cluster"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/publish"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/sub"
 )
 
 var (
@@ -14,11 +20,15 @@ var (
 		Use:     "gmqctl",
 		Long:    "gmqctl is a command line tool for gmqtt",
 		Version: Version,
+		// errors are printed by must
+		SilenceErrors: true,
 	}
 )
 
 func init() {
 	rootCmd.AddCommand(command.Gen)
+	rootCmd.AddCommand(client.Command, sub.Command, publish.Command, account.Command, cluster.Command)
+	rootCmd.AddCommand(bench.Command)
 }
 
 func must(err error) {
@@ -2,16 +2,15 @@ package command
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/DrmagicE/gmqtt/config"
 	"github.com/DrmagicE/gmqtt/pkg/pidfile"
@@ -42,14 +41,27 @@ func installSignal(srv server.Server) {
 	for {
 		select {
 		case <-reloadSignalCh:
-			var c config.Config
-			var err error
-			c, err = config.ParseConfig(ConfigFile)
+			// keep listening for the later reloads on error.
+			c, err := config.ParseConfig(ConfigFile)
 			if err != nil {
 				logger.Error("reload error", zap.Error(err))
-				return
+				continue
+			}
+			rs, err := srv.ReloadConfig(c)
+			if err != nil {
+				logger.Error("reload error", zap.Error(err))
+				continue
+			}
+			for _, r := range rs {
+				if !r.Changed {
+					continue
+				}
+				if r.Err != nil {
+					logger.Error("reload section failed", zap.String("section", r.Section), zap.Error(r.Err))
+					continue
+				}
+				logger.Info("reload section succeeded", zap.String("section", r.Section))
 			}
-			srv.ApplyConfig(c)
 			logger.Info("gmqtt reloaded")
 		case <-stopSignalCh:
 			err := srv.Stop(context.Background())
@@ -61,34 +73,18 @@ func installSignal(srv server.Server) {
 
 }
 
-func GetListeners(c config.Config) (tcpListeners []net.Listener, websockets []*server.WsServer, err error) {
+// GetListeners binds the listeners of the config.
+func GetListeners(c config.Config) (listeners []*server.Listener, err error) {
 	for _, v := range c.Listeners {
-		var ln net.Listener
-		if v.Websocket != nil {
-			ws := &server.WsServer{
-				Server: &http.Server{Addr: v.Address},
-				Path:   v.Websocket.Path,
-			}
-			if v.TLSOptions != nil {
-				ws.KeyFile = v.Key
-				ws.CertFile = v.Cert
-			}
-			websockets = append(websockets, ws)
-			continue
-		}
-		if v.TLSOptions != nil {
-			var cert tls.Certificate
-			cert, err = tls.LoadX509KeyPair(v.Cert, v.Key)
-			if err != nil {
-				return
+		var ln *server.Listener
+		ln, err = server.NewListener(*v)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
 			}
-			ln, err = tls.Listen("tcp", v.Address, &tls.Config{
-				Certificates: []tls.Certificate{cert},
-			})
-		} else {
-			ln, err = net.Listen("tcp", v.Address)
+			return nil, err
 		}
-		tcpListeners = append(tcpListeners, ln)
+		listeners = append(listeners, ln)
 	}
 	return
 }
@@ -115,11 +111,11 @@ func NewStartCmd() *cobra.Command {
 				defer pid.Remove()
 			}
 
-			tcpListeners, websockets, err := GetListeners(c)
+			listeners, err := GetListeners(c)
 			must(err)
-			l, err := c.GetLogger(c.Log)
+			// the levels are applied by the server, which can be changed at runtime.
+			l, err := config.NewLogger(c.Log, zapcore.DebugLevel)
 			must(err)
-			logger = l
 
 			// 添加 OnAccept Hook 来禁用 TCP Keep-Alive
 			hooks := server.Hooks{
@@ -135,11 +131,12 @@ func NewStartCmd() *cobra.Command {
 
 			s := server.New(
 				server.WithConfig(c),
-				server.WithTCPListener(tcpListeners...),
-				server.WithWebsocketServer(websockets...),
+				server.WithListeners(listeners...),
 				server.WithLogger(l),
 				server.WithHook(hooks),
 			)
+			// the logger derived from the server logger follows the log config changes on reload.
+			logger = server.LoggerWithField()
 
 			err = s.Init()
 			if err != nil {
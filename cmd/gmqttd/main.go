@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	_ "github.com/DrmagicE/gmqtt/audit"
 	"github.com/DrmagicE/gmqtt/cmd/gmqttd/command"
 	_ "github.com/DrmagicE/gmqtt/persistence"
 	_ "github.com/DrmagicE/gmqtt/plugin/prometheus"
@@ -8,5 +8,6 @@ import (
 	_ "github.com/DrmagicE/gmqtt/plugin/auth"
 	_ "github.com/DrmagicE/gmqtt/plugin/federation"
 	_ "github.com/DrmagicE/gmqtt/plugin/prometheus"
+	_ "github.com/DrmagicE/gmqtt/plugin/sys"
 	_ "github.com/DrmagicE/gmqtt/plugin/thingspanel"
 )
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+)
+
+type AuditSinkType = string
+
+const (
+	AuditSinkMemory AuditSinkType = "memory"
+	AuditSinkFile   AuditSinkType = "file"
+)
+
+var (
+	// DefaultAuditConfig is the default value of Audit
+	DefaultAuditConfig = Audit{
+		Sink: AuditSinkMemory,
+		Memory: MemoryAuditSink{
+			MaxRecords: 10000,
+		},
+		File: FileAuditSink{
+			Path:       "./gmqttd-audit.log",
+			MaxSize:    100,
+			MaxBackups: 10,
+		},
+	}
+)
+
+// Audit is the config of the audit log,
+// which records the administrative and privileged operations, e.g. kicking a client through the admin API.
+type Audit struct {
+	// Sink is the type of the sink that the audit records are written to.
+	// The built-in sinks are "memory" and "file", other sinks can be registered by server.RegisterAuditSinkFactory.
+	// If empty, use "memory" as default.
+	Sink AuditSinkType `yaml:"sink"`
+	// Memory is the configuration of the memory sink.
+	Memory MemoryAuditSink `yaml:"memory"`
+	// File is the configuration of the file sink.
+	File FileAuditSink `yaml:"file"`
+}
+
+// MemoryAuditSink is the configuration of the memory sink, which keeps the latest records in memory.
+type MemoryAuditSink struct {
+	// MaxRecords is the maximum number of records to keep, the oldest records are dropped when exceeded.
+	// Default to 10000.
+	MaxRecords int `yaml:"max_records"`
+}
+
+// FileAuditSink is the configuration of the file sink, which writes the records as JSON lines into a rotating file.
+type FileAuditSink struct {
+	// Path is the path of the audit log file. A relative path is relative to the directory of the config file.
+	// Default to "./gmqttd-audit.log".
+	Path string `yaml:"path"`
+	// MaxSize is the maximum size in megabytes of the file before it is rotated.
+	// Default to 100.
+	MaxSize int `yaml:"max_size"`
+	// MaxBackups is the maximum number of rotated files to retain, the rotated files are named as path.1, path.2...
+	// Default to 10.
+	MaxBackups int `yaml:"max_backups"`
+}
+
+func (a *Audit) Validate() error {
+	switch a.Sink {
+	case "":
+		return errors.New("empty audit sink")
+	case AuditSinkMemory:
+		if a.Memory.MaxRecords <= 0 {
+			return errors.New("audit memory max_records must be greater than 0")
+		}
+	case AuditSinkFile:
+		if a.File.Path == "" {
+			return errors.New("empty audit file path")
+		}
+		if a.File.MaxSize <= 0 {
+			return errors.New("audit file max_size must be greater than 0")
+		}
+		if a.File.MaxBackups < 0 {
+			return errors.New("audit file max_backups must not be negative")
+		}
+	}
+	return nil
+}
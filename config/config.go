@@ -3,11 +3,9 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
 	"path"
+	"reflect"
 
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v2"
 )
 
@@ -38,24 +36,36 @@ func RegisterDefaultPluginConfig(name string, config Configuration) {
 // If config file is not provided, gmqttd will start with DefaultConfig.
 func DefaultConfig() Config {
 	c := Config{
-		Listeners: DefaultListeners,
-		MQTT:      DefaultMQTTConfig,
-		API:       DefaultAPI,
-		Log: LogConfig{
-			Level:  "info",
-			Format: "text",
-		},
-		Plugins:           make(pluginConfig),
-		Persistence:       DefaultPersistenceConfig,
-		TopicAliasManager: DefaultTopicAliasManager,
+		Listeners:          DefaultListeners,
+		MQTT:               DefaultMQTTConfig,
+		API:                DefaultAPI,
+		Log:                DefaultLogConfig,
+		Plugins:            make(pluginConfig),
+		Persistence:        DefaultPersistenceConfig,
+		TopicAliasManager:  DefaultTopicAliasManager,
+		SharedSubscription: DefaultSharedSubscription,
+		Audit:              DefaultAuditConfig,
+		Tracing:            DefaultTracingConfig,
 	}
 
 	for name, v := range defaultPluginConfig {
-		c.Plugins[name] = v
+		c.Plugins[name] = copyConfiguration(v)
 	}
 	return c
 }
 
+// copyConfiguration returns a copy of the registered default plugin configuration,
+// so that parsing a config file does not modify the default configuration and the configuration in use.
+func copyConfiguration(c Configuration) Configuration {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return c
+	}
+	n := reflect.New(v.Elem().Type())
+	n.Elem().Set(v.Elem())
+	return n.Interface().(Configuration)
+}
+
 var DefaultListeners = []*ListenerConfig{
 	{
 		Address:    "0.0.0.0:1883",
@@ -70,26 +80,6 @@ var DefaultListeners = []*ListenerConfig{
 	},
 }
 
-// LogConfig is use to configure the log behaviors.
-type LogConfig struct {
-	// Level is the log level. Possible values: debug, info, warn, error
-	Level string `yaml:"level"`
-	// Format is the log format. Possible values: json, text
-	Format string `yaml:"format"`
-	// DumpPacket indicates whether to dump MQTT packet in debug level.
-	DumpPacket bool `yaml:"dump_packet"`
-}
-
-func (l LogConfig) Validate() error {
-	if l.Level != "debug" && l.Level != "info" && l.Level != "warn" && l.Level != "error" {
-		return fmt.Errorf("invalid log level: %s", l.Level)
-	}
-	if l.Format != "json" && l.Format != "text" {
-		return fmt.Errorf("invalid log format: %s", l.Format)
-	}
-	return nil
-}
-
 // pluginConfig stores the plugin default configuration, key by the plugin name.
 // If the plugin has default configuration, it should call RegisterDefaultPluginConfig in it's init function to register.
 type pluginConfig map[string]Configuration
@@ -120,6 +110,12 @@ type Config struct {
 	PluginOrder       []string          `yaml:"plugin_order"`
 	Persistence       Persistence       `yaml:"persistence"`
 	TopicAliasManager TopicAliasManager `yaml:"topic_alias_manager"`
+	// SharedSubscription is the load balancing setting of shared subscriptions.
+	SharedSubscription SharedSubscription `yaml:"shared_subscription"`
+	// Audit is the audit log setting.
+	Audit Audit `yaml:"audit"`
+	// Tracing is the OpenTelemetry tracing setting.
+	Tracing Tracing `yaml:"tracing"`
 }
 
 type GRPC struct {
@@ -141,6 +137,18 @@ type ListenerConfig struct {
 	Address     string `yaml:"address"`
 	*TLSOptions `yaml:"tls"`
 	Websocket   *WebsocketOptions `yaml:"websocket"`
+	// MQTTSN makes the listener a MQTT-SN gateway listening on UDP.
+	MQTTSN *MQTTSNOptions `yaml:"mqttsn"`
+}
+
+func (l *ListenerConfig) Validate() error {
+	if l.MQTTSN == nil {
+		return nil
+	}
+	if l.TLSOptions != nil || l.Websocket != nil {
+		return fmt.Errorf("listener %s: mqttsn can not be used with tls or websocket", l.Address)
+	}
+	return l.MQTTSN.Validate()
 }
 
 type WebsocketOptions struct {
@@ -160,12 +168,12 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if len(raw.Plugins) == 0 {
 		raw.Plugins = make(pluginConfig)
 		for name, v := range defaultPluginConfig {
-			raw.Plugins[name] = v
+			raw.Plugins[name] = copyConfiguration(v)
 		}
 	} else {
 		for name, v := range raw.Plugins {
 			if v == nil {
-				raw.Plugins[name] = defaultPluginConfig[name]
+				raw.Plugins[name] = copyConfiguration(defaultPluginConfig[name])
 			}
 		}
 	}
@@ -174,6 +182,12 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 func (c Config) Validate() (err error) {
+	for _, l := range c.Listeners {
+		err = l.Validate()
+		if err != nil {
+			return err
+		}
+	}
 	err = c.Log.Validate()
 	if err != nil {
 		return err
@@ -190,6 +204,18 @@ func (c Config) Validate() (err error) {
 	if err != nil {
 		return err
 	}
+	err = c.SharedSubscription.Validate()
+	if err != nil {
+		return err
+	}
+	err = c.Audit.Validate()
+	if err != nil {
+		return err
+	}
+	err = c.Tracing.Validate()
+	if err != nil {
+		return err
+	}
 	for _, conf := range c.Plugins {
 		err := conf.Validate()
 		if err != nil {
@@ -219,21 +245,3 @@ func ParseConfig(filePath string) (c Config, err error) {
 	}
 	return c, err
 }
-
-func (c Config) GetLogger(config LogConfig) (l *zap.Logger, err error) {
-	var logLevel zapcore.Level
-	err = logLevel.UnmarshalText([]byte(config.Level))
-	if err != nil {
-		return
-	}
-	var core zapcore.Core
-	if config.Format == "json" {
-		core = zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), os.Stdout, logLevel)
-	}
-	if config.Format == "text" {
-		core = zapcore.NewCore(zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), os.Stdout, logLevel)
-	}
-
-	zaplog := zap.New(core, zap.AddStacktrace(zap.ErrorLevel), zap.AddCaller())
-	return zaplog, nil
-}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"io/ioutil"
+	"net"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +38,106 @@ func TestParseConfig(t *testing.T) {
 		})
 	}
 }
+
+type testPluginConfig struct {
+	Value string `yaml:"value"`
+}
+
+func (t *testPluginConfig) Validate() error {
+	return nil
+}
+
+var testPluginDefault = testPluginConfig{Value: "default"}
+
+func (t *testPluginConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg testPluginConfig
+	df := cfg(testPluginDefault)
+	var v = &struct {
+		TestPlugin *cfg `yaml:"test_plugin"`
+	}{
+		TestPlugin: &df,
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*t = testPluginConfig(*v.TestPlugin)
+	return nil
+}
+
+func TestParseConfig_PluginConfigNotShared(t *testing.T) {
+	a := assert.New(t)
+	RegisterDefaultPluginConfig("test_plugin", &testPluginDefault)
+	defer delete(defaultPluginConfig, "test_plugin")
+
+	parse := func(value string) Config {
+		f, err := ioutil.TempFile("", "config")
+		a.Nil(err)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString("plugins:\n  test_plugin:\n    value: " + value + "\n")
+		a.Nil(err)
+		a.Nil(f.Close())
+		c, err := ParseConfig(f.Name())
+		a.Nil(err)
+		return c
+	}
+	c1 := parse("v1")
+	c2 := parse("v2")
+	a.Equal("v1", c1.Plugins["test_plugin"].(*testPluginConfig).Value)
+	a.Equal("v2", c2.Plugins["test_plugin"].(*testPluginConfig).Value)
+	a.Equal("default", testPluginDefault.Value)
+	a.Equal("default", DefaultConfig().Plugins["test_plugin"].(*testPluginConfig).Value)
+}
+
+func TestListenerConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	l := &ListenerConfig{Address: ":1884", MQTTSN: &MQTTSNOptions{}}
+	c := DefaultConfig()
+	c.Listeners = []*ListenerConfig{l}
+	a.Nil(l.Validate())
+	a.Nil(c.Validate())
+
+	l.Websocket = &WebsocketOptions{Path: "/"}
+	a.Error(l.Validate())
+	a.Error(c.Validate())
+
+	l = &ListenerConfig{Address: ":1884", MQTTSN: &MQTTSNOptions{PredefinedTopics: map[uint16]string{1: "a/#"}}}
+	a.Error(l.Validate())
+	l.MQTTSN.PredefinedTopics = map[uint16]string{0: "a/b"}
+	a.Error(l.Validate())
+	l.MQTTSN.PredefinedTopics = map[uint16]string{1: "a/b"}
+	l.MQTTSN.AdvertiseInterval = 24 * time.Hour
+	a.Error(l.Validate())
+	l.MQTTSN.AdvertiseInterval = 0
+	a.Nil(l.Validate())
+	a.Equal(DefaultMQTTSNOptions.AdvertiseInterval, l.MQTTSN.GetAdvertiseInterval())
+	a.Equal("mqttsn-gateway-1", l.MQTTSN.GetQoSMinusOneClientID())
+
+	l.MQTTSN.Username, l.MQTTSN.Password = "", "shared"
+	username, password, ok := l.MQTTSN.GetCredential("cid")
+	a.True(ok)
+	a.Equal("cid", username)
+	a.Equal("shared", password)
+	l.MQTTSN.Credentials = map[string]MQTTSNCredential{"cid": {Username: "u1", Password: "p1"}}
+	a.Nil(l.Validate())
+	username, password, ok = l.MQTTSN.GetCredential("cid")
+	a.True(ok)
+	a.Equal("u1", username)
+	a.Equal("p1", password)
+	_, _, ok = l.MQTTSN.GetCredential("unknown")
+	a.False(ok)
+	l.MQTTSN.Credentials[""] = MQTTSNCredential{}
+	a.Error(l.Validate())
+	delete(l.MQTTSN.Credentials, "")
+
+	l.MQTTSN.QoSMinusOneAllowedNetworks = []string{"10.0.0.0/8", "192.168.1.1", "::1"}
+	a.Nil(l.Validate())
+	networks, err := l.MQTTSN.GetQoSMinusOneAllowedNetworks()
+	a.Nil(err)
+	a.Len(networks, 3)
+	a.True(networks[0].Contains(net.ParseIP("10.1.2.3")))
+	a.True(networks[1].Contains(net.ParseIP("192.168.1.1")))
+	a.False(networks[1].Contains(net.ParseIP("192.168.1.2")))
+	a.True(networks[2].Contains(net.ParseIP("::1")))
+	l.MQTTSN.QoSMinusOneAllowedNetworks = []string{"10.0.0.0/33"}
+	a.Error(l.Validate())
+}
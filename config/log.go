@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/DrmagicE/gmqtt/pkg/logfile"
+)
+
+// The log outputs.
+const (
+	LogOutputStdout = "stdout"
+	LogOutputStderr = "stderr"
+	LogOutputFile   = "file"
+)
+
+// DefaultLogConfig is the default log configuration.
+var DefaultLogConfig = LogConfig{
+	Level:  "info",
+	Format: "text",
+	Output: LogOutputStdout,
+	File: LogFile{
+		Filename:   "logs/gmqttd.log",
+		MaxSize:    100,
+		MaxAge:     7,
+		MaxBackups: 10,
+	},
+}
+
+// LogConfig is use to configure the log behaviors.
+type LogConfig struct {
+	// Level is the log level. Possible values: debug, info, warn, error
+	Level string `yaml:"level"`
+	// Format is the log format. Possible values: json, text
+	Format string `yaml:"format"`
+	// DumpPacket indicates whether to dump MQTT packet in debug level.
+	DumpPacket bool `yaml:"dump_packet"`
+	// Output is where the logs are written to. Possible values: stdout, stderr, file
+	Output string `yaml:"output"`
+	// File is the log file setting, used if the Output is file.
+	File LogFile `yaml:"file"`
+	// Sampling limits the number of the logs with the same level and message per Tick.
+	// Sampling is disabled if not set.
+	Sampling *LogSampling `yaml:"sampling"`
+	// Plugins is the log level overrides of the plugins, key by the plugin name.
+	// The plugins which are not listed use Level.
+	Plugins map[string]string `yaml:"plugins"`
+}
+
+// LogFile is the log file and rotation setting.
+type LogFile struct {
+	// Filename is the path of the log file, relative path is relative to the working directory.
+	Filename string `yaml:"filename"`
+	// MaxSize is the maximum size in megabytes of the log file before it gets rotated. 0 means no rotation.
+	MaxSize int `yaml:"max_size"`
+	// MaxAge is the maximum number of days to retain the rotated files. 0 means no limit.
+	MaxAge int `yaml:"max_age"`
+	// MaxBackups is the maximum number of the rotated files to retain. 0 means no limit.
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// LogSampling is the log sampling setting.
+// In each Tick, the first Initial logs with the same level and message are written,
+// after that, every Thereafter-th log is written and the others are dropped.
+type LogSampling struct {
+	Tick       time.Duration `yaml:"tick"`
+	Initial    int           `yaml:"initial"`
+	Thereafter int           `yaml:"thereafter"`
+}
+
+// ParseLogLevel parses the log level. Possible values: debug, info, warn, error
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %s", level)
+}
+
+func (l LogConfig) Validate() error {
+	if _, err := ParseLogLevel(l.Level); err != nil {
+		return err
+	}
+	if l.Format != "json" && l.Format != "text" {
+		return fmt.Errorf("invalid log format: %s", l.Format)
+	}
+	switch l.Output {
+	case "", LogOutputStdout, LogOutputStderr:
+	case LogOutputFile:
+		if l.File.Filename == "" {
+			return errors.New("empty log file name")
+		}
+		if l.File.MaxSize < 0 || l.File.MaxAge < 0 || l.File.MaxBackups < 0 {
+			return errors.New("invalid log file rotation setting")
+		}
+	default:
+		return fmt.Errorf("invalid log output: %s", l.Output)
+	}
+	if s := l.Sampling; s != nil {
+		if s.Tick <= 0 {
+			return errors.New("invalid log sampling tick")
+		}
+		if s.Initial < 0 || s.Thereafter < 0 {
+			return errors.New("invalid log sampling initial or thereafter")
+		}
+	}
+	for k, v := range l.Plugins {
+		if _, err := ParseLogLevel(v); err != nil {
+			return fmt.Errorf("invalid log level of plugin %s: %s", k, v)
+		}
+	}
+	return nil
+}
+
+var (
+	logFilesMu sync.Mutex
+	// logFiles caches the opened log files, key by the absolute path,
+	// so that the loggers rebuilt on reload share the same file and rotation.
+	logFiles = make(map[string]*logfile.Writer)
+)
+
+func openLogFile(f LogFile) (*logfile.Writer, error) {
+	path, err := filepath.Abs(f.Filename)
+	if err != nil {
+		return nil, err
+	}
+	opts := logfile.Options{
+		MaxSize:    f.MaxSize,
+		MaxAge:     f.MaxAge,
+		MaxBackups: f.MaxBackups,
+	}
+	logFilesMu.Lock()
+	defer logFilesMu.Unlock()
+	if w, ok := logFiles[path]; ok {
+		w.SetOptions(opts)
+		return w, nil
+	}
+	w, err := logfile.New(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	logFiles[path] = w
+	return w, nil
+}
+
+// NewLogger returns the logger of the config, the logs are filtered by the given level enabler instead of the Level.
+// The server uses it to build a logger with all levels enabled, and applies the levels at runtime.
+func NewLogger(config LogConfig, enab zapcore.LevelEnabler) (*zap.Logger, error) {
+	var ws zapcore.WriteSyncer
+	switch config.Output {
+	case "", LogOutputStdout:
+		ws = zapcore.Lock(os.Stdout)
+	case LogOutputStderr:
+		ws = zapcore.Lock(os.Stderr)
+	case LogOutputFile:
+		w, err := openLogFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		ws = w
+	default:
+		return nil, fmt.Errorf("invalid log output: %s", config.Output)
+	}
+	var enc zapcore.Encoder
+	switch config.Format {
+	case "json":
+		enc = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "text":
+		enc = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		return nil, fmt.Errorf("invalid log format: %s", config.Format)
+	}
+	core := zapcore.NewCore(enc, ws, enab)
+	if s := config.Sampling; s != nil {
+		core = zapcore.NewSampler(core, s.Tick, s.Initial, s.Thereafter)
+	}
+	return zap.New(core, zap.AddStacktrace(zap.ErrorLevel), zap.AddCaller()), nil
+}
+
+// GetLogger returns the logger of the config at the Level. The plugin levels are applied by the server.
+func (c Config) GetLogger(config LogConfig) (l *zap.Logger, err error) {
+	level, err := ParseLogLevel(config.Level)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogger(config, level)
+}
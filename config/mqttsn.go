@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultMQTTSNOptions is the default value of MQTTSNOptions.
+	// The zero value fields of MQTTSNOptions fall back to the values of DefaultMQTTSNOptions.
+	DefaultMQTTSNOptions = MQTTSNOptions{
+		GatewayID:           1,
+		AdvertiseInterval:   15 * time.Minute,
+		MaxBufferedMessages: 100,
+		RetryInterval:       10 * time.Second,
+		MaxRetries:          5,
+	}
+)
+
+// MQTTSNOptions is the setting of the MQTT-SN v1.2 gateway listener, which listens on UDP.
+// Each MQTT-SN client is translated into a MQTT 3.1.1 client of the broker,
+// so that it is authenticated and authorized by the hooks like any other clients.
+type MQTTSNOptions struct {
+	// GatewayID is the id of the gateway in ADVERTISE and GWINFO messages.
+	// Default to 1.
+	GatewayID uint8 `yaml:"gateway_id"`
+	// AdvertiseAddress is the broadcast or multicast UDP address to send the ADVERTISE messages to, e.g. 255.255.255.255:1884.
+	// If empty, the gateway does not advertise itself but still answers SEARCHGW.
+	AdvertiseAddress string `yaml:"advertise_address"`
+	// AdvertiseInterval is the interval of the ADVERTISE messages.
+	// Default to 15m.
+	AdvertiseInterval time.Duration `yaml:"advertise_interval"`
+	// PredefinedTopics is the predefined topic ids shared by the gateway and the clients, key by the topic id.
+	PredefinedTopics map[uint16]string `yaml:"predefined_topics"`
+	// Username is the username of the MQTT CONNECT packets sent on behalf of the MQTT-SN clients,
+	// since MQTT-SN v1.2 has no authentication.
+	// If empty, the client id is used as the username.
+	// It is shared by all clients which are not in Credentials, so it must not be a privileged (e.g. root) account,
+	// any MQTT-SN client can use it by choosing a client id.
+	Username string `yaml:"username"`
+	// Password is the password of the MQTT CONNECT packets sent on behalf of the MQTT-SN clients.
+	Password string `yaml:"password"`
+	// Credentials is the username and password of the MQTT CONNECT packets of each client, key by the client id.
+	// If it is not empty, the clients which are not in it are rejected, including QoSMinusOneClientID.
+	Credentials map[string]MQTTSNCredential `yaml:"credentials"`
+	// QoSMinusOneClientID is the client id of the connection which publishes the QoS -1 messages.
+	// Default to "mqttsn-gateway-{gateway_id}".
+	QoSMinusOneClientID string `yaml:"qos_minus_one_client_id"`
+	// QoSMinusOneAllowedNetworks is the IP addresses or CIDRs which are allowed to publish the QoS -1 messages,
+	// e.g. 10.0.0.0/8. The QoS -1 messages are not authenticated, so they are disabled if it is empty.
+	QoSMinusOneAllowedNetworks []string `yaml:"qos_minus_one_allowed_networks"`
+	// MaxBufferedMessages is the maximum number of messages buffered for a sleeping client,
+	// and the maximum number of messages published by a client before its connection is accepted by the broker.
+	// The oldest message is dropped when the buffer of the sleeping client is full.
+	// Default to 100.
+	MaxBufferedMessages int `yaml:"max_buffered_messages"`
+	// RetryInterval is the interval to retransmit the unacknowledged REGISTER, PUBLISH and PUBREL messages.
+	// Default to 10s.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	// MaxRetries is the maximum retransmissions of a message before the client is considered lost.
+	// Default to 5.
+	MaxRetries int `yaml:"max_retries"`
+}
+
+// MQTTSNCredential is the username and password of the MQTT CONNECT packet sent on behalf of a MQTT-SN client.
+type MQTTSNCredential struct {
+	// Username defaults to the client id if empty.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// GetCredential returns the username and password of the MQTT CONNECT packet sent on behalf of the client.
+// It returns false if Credentials is not empty and the client is not in it.
+func (o *MQTTSNOptions) GetCredential(clientID string) (username, password string, ok bool) {
+	username, password = o.Username, o.Password
+	if len(o.Credentials) != 0 {
+		c, ok := o.Credentials[clientID]
+		if !ok {
+			return "", "", false
+		}
+		username, password = c.Username, c.Password
+	}
+	if username == "" {
+		username = clientID
+	}
+	return username, password, true
+}
+
+// GetQoSMinusOneAllowedNetworks parses QoSMinusOneAllowedNetworks, an IP address is parsed as a single host network.
+func (o *MQTTSNOptions) GetQoSMinusOneAllowedNetworks() ([]*net.IPNet, error) {
+	var rs []*net.IPNet
+	for _, v := range o.QoSMinusOneAllowedNetworks {
+		if ip := net.ParseIP(v); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			rs = append(rs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mqttsn.qos_minus_one_allowed_networks: %s", v)
+		}
+		rs = append(rs, ipNet)
+	}
+	return rs, nil
+}
+
+// GetGatewayID returns the gateway id.
+func (o *MQTTSNOptions) GetGatewayID() uint8 {
+	if o.GatewayID == 0 {
+		return DefaultMQTTSNOptions.GatewayID
+	}
+	return o.GatewayID
+}
+
+// GetAdvertiseInterval returns the interval of the ADVERTISE messages.
+func (o *MQTTSNOptions) GetAdvertiseInterval() time.Duration {
+	if o.AdvertiseInterval == 0 {
+		return DefaultMQTTSNOptions.AdvertiseInterval
+	}
+	return o.AdvertiseInterval
+}
+
+// GetQoSMinusOneClientID returns the client id of the connection which publishes the QoS -1 messages.
+func (o *MQTTSNOptions) GetQoSMinusOneClientID() string {
+	if o.QoSMinusOneClientID == "" {
+		return "mqttsn-gateway-" + strconv.Itoa(int(o.GetGatewayID()))
+	}
+	return o.QoSMinusOneClientID
+}
+
+// GetMaxBufferedMessages returns the maximum number of messages buffered for a sleeping client.
+func (o *MQTTSNOptions) GetMaxBufferedMessages() int {
+	if o.MaxBufferedMessages == 0 {
+		return DefaultMQTTSNOptions.MaxBufferedMessages
+	}
+	return o.MaxBufferedMessages
+}
+
+// GetRetryInterval returns the retransmission interval.
+func (o *MQTTSNOptions) GetRetryInterval() time.Duration {
+	if o.RetryInterval == 0 {
+		return DefaultMQTTSNOptions.RetryInterval
+	}
+	return o.RetryInterval
+}
+
+// GetMaxRetries returns the maximum retransmissions of a message.
+func (o *MQTTSNOptions) GetMaxRetries() int {
+	if o.MaxRetries == 0 {
+		return DefaultMQTTSNOptions.MaxRetries
+	}
+	return o.MaxRetries
+}
+
+func (o *MQTTSNOptions) Validate() error {
+	if o.AdvertiseInterval < 0 || o.AdvertiseInterval > math.MaxUint16*time.Second {
+		return fmt.Errorf("invalid mqttsn.advertise_interval: %s", o.AdvertiseInterval)
+	}
+	if o.MaxBufferedMessages < 0 {
+		return errors.New("invalid mqttsn.max_buffered_messages")
+	}
+	if o.RetryInterval < 0 {
+		return errors.New("invalid mqttsn.retry_interval")
+	}
+	if o.MaxRetries < 0 {
+		return errors.New("invalid mqttsn.max_retries")
+	}
+	if _, err := o.GetQoSMinusOneAllowedNetworks(); err != nil {
+		return err
+	}
+	for clientID := range o.Credentials {
+		if clientID == "" {
+			return errors.New("invalid client id in mqttsn.credentials")
+		}
+	}
+	for id, name := range o.PredefinedTopics {
+		if id == 0 || id == math.MaxUint16 {
+			return fmt.Errorf("invalid topic id in mqttsn.predefined_topics: %d", id)
+		}
+		if name == "" || strings.ContainsAny(name, "+#") {
+			return fmt.Errorf("invalid topic name in mqttsn.predefined_topics: %s", name)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"net"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -9,9 +10,17 @@ import (
 
 type PersistenceType = string
 
+// RedisMode is the deployment mode of redis.
+type RedisMode = string
+
 const (
 	PersistenceTypeMemory PersistenceType = "memory"
 	PersistenceTypeRedis  PersistenceType = "redis"
+	PersistenceTypeFile   PersistenceType = "file"
+
+	RedisModeStandalone RedisMode = "standalone"
+	RedisModeSentinel   RedisMode = "sentinel"
+	RedisModeCluster    RedisMode = "cluster"
 )
 
 var (
@@ -21,6 +30,7 @@ var (
 	DefaultPersistenceConfig = Persistence{
 		Type: PersistenceTypeMemory,
 		Redis: RedisPersistence{
+			Mode:        RedisModeStandalone,
 			Addr:        "127.0.0.1:6379",
 			Password:    "",
 			Database:    0,
@@ -28,27 +38,73 @@ var (
 			MaxActive:   &defaultMaxActive,
 			IdleTimeout: 240 * time.Second,
 		},
+		File: FilePersistence{
+			Path:                 "./gmqttd.aof",
+			Fsync:                "everysec",
+			CompactionMinSize:    64 * 1024 * 1024,
+			CompactionPercentage: 100,
+		},
 	}
 )
 
 // Persistence is the config of backend persistence.
+// The persistence type also decides the backend of the retained message store.
 type Persistence struct {
 	// Type is the persistence type.
 	// If empty, use "memory" as default.
 	Type PersistenceType `yaml:"type"`
 	// Redis is the redis configuration and must be set when Type ==  "redis".
 	Redis RedisPersistence `yaml:"redis"`
+	// File is the embedded file persistence configuration and must be set when Type == "file".
+	File FilePersistence `yaml:"file"`
+}
+
+// FilePersistence is the configuration of the embedded file persistence.
+// All data are written into a single append-only file which will be replayed on startup.
+type FilePersistence struct {
+	// Path is the path of the data file. A relative path is relative to the directory of the config file.
+	// Default to "./gmqttd.aof".
+	Path string `yaml:"path"`
+	// Fsync decides when to flush the data to the disk, possible values: "always", "everysec", "no".
+	// "always" is the most durable but slowest option,
+	// "everysec" may lose the data written in the last second when the operating system crashes.
+	// Default to "everysec".
+	Fsync string `yaml:"fsync"`
+	// CompactionMinSize is the minimum file size in bytes to trigger an automatic compaction.
+	// Default to 64MB.
+	CompactionMinSize int64 `yaml:"compaction_min_size"`
+	// CompactionPercentage triggers an automatic compaction when the file size grows by the given percentage
+	// since the last compaction. Set to zero to disable automatic compaction.
+	// Default to 100.
+	CompactionPercentage int `yaml:"compaction_percentage"`
 }
 
 // RedisPersistence is the configuration of redis persistence.
 type RedisPersistence struct {
-	// Addr is the redis server address.
+	// Mode is the redis deployment mode, possible values: "standalone", "sentinel", "cluster".
+	// If empty, use "standalone" as default.
+	Mode RedisMode `yaml:"mode"`
+	// Addr is the redis server address, only used in standalone mode.
 	// If empty, use "127.0.0.1:6379" as default.
 	Addr string `yaml:"addr"`
+	// Addrs is the sentinel addresses in sentinel mode, or the seed node addresses in cluster mode.
+	Addrs []string `yaml:"addrs"`
+	// MasterName is the name of the master monitored by the sentinels, required in sentinel mode.
+	MasterName string `yaml:"master_name"`
+	// SentinelPassword is the password of the sentinels.
+	SentinelPassword string `yaml:"sentinel_password"`
+	// Username is the ACL username (Redis 6+). If empty, the password is used with the legacy AUTH command.
+	Username string `yaml:"username"`
 	// Password is the redis password.
 	Password string `yaml:"password"`
 	// Database is the number of the redis database to be connected.
+	// It is ignored in cluster mode because redis cluster only supports database 0.
 	Database uint `yaml:"database"`
+	// KeyPrefix is prepended to all keys written by the redis persistence,
+	// which allows multiple deployments to share one redis.
+	KeyPrefix string `yaml:"key_prefix"`
+	// TLS enables TLS connections to redis (and the sentinels) if it is not nil.
+	TLS *RedisTLSOptions `yaml:"tls"`
 	// MaxIdle is the maximum number of idle connections in the pool.
 	// If nil, use 1000 as default.
 	// This value will pass to redis.Pool.MaxIde.
@@ -66,16 +122,69 @@ type RedisPersistence struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout"`
 }
 
+// RedisTLSOptions is the TLS configuration of redis connections.
+type RedisTLSOptions struct {
+	// CACert is the trust CA certificate file. If empty, use the system CA pool.
+	CACert string `yaml:"cacert"`
+	// Cert is the path to the client certificate file, it is optional.
+	Cert string `yaml:"cert"`
+	// Key is the path to the client key file, it is optional.
+	Key string `yaml:"key"`
+	// ServerName is used to verify the hostname of the server certificate.
+	// If empty, the host of the connecting address is used.
+	ServerName string `yaml:"server_name"`
+	// InsecureSkipVerify disables the server certificate verification.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
+func (r *RedisPersistence) Validate() error {
+	switch r.Mode {
+	case RedisModeStandalone, "":
+		_, _, err := net.SplitHostPort(r.Addr)
+		if err != nil {
+			return err
+		}
+	case RedisModeSentinel, RedisModeCluster:
+		if len(r.Addrs) == 0 {
+			return errors.New("redis addrs must be set in " + r.Mode + " mode")
+		}
+		for _, v := range r.Addrs {
+			_, _, err := net.SplitHostPort(v)
+			if err != nil {
+				return err
+			}
+		}
+		if r.Mode == RedisModeSentinel && r.MasterName == "" {
+			return errors.New("redis master_name must be set in sentinel mode")
+		}
+	default:
+		return errors.New("invalid redis mode")
+	}
+	if strings.ContainsAny(r.KeyPrefix, "*?[]{}\\") {
+		return errors.New("redis key_prefix must not contain glob characters or braces")
+	}
+	if r.TLS != nil && (r.TLS.Cert == "") != (r.TLS.Key == "") {
+		return errors.New("redis tls cert and key must be set together")
+	}
+	return nil
+}
+
 func (p *Persistence) Validate() error {
-	if p.Type != PersistenceTypeMemory && p.Type != PersistenceTypeRedis {
+	if p.Type != PersistenceTypeMemory && p.Type != PersistenceTypeRedis && p.Type != PersistenceTypeFile {
 		return errors.New("invalid persistence type")
 	}
-	_, _, err := net.SplitHostPort(p.Redis.Addr)
-	if err != nil {
-		return err
+	if p.Type == PersistenceTypeFile {
+		if p.File.Path == "" {
+			return errors.New("empty file persistence path")
+		}
+		switch p.File.Fsync {
+		case "always", "everysec", "no":
+		default:
+			return errors.New("invalid fsync policy, possible values: always, everysec, no")
+		}
 	}
-	if p.Redis.Database < 0 {
-		return errors.New("invalid redis database number")
+	if p.Type == PersistenceTypeRedis {
+		return p.Redis.Validate()
 	}
 	return nil
 }
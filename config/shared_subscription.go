@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SharedStrategyType is the name of the load balancing strategy of shared subscriptions.
+type SharedStrategyType = string
+
+const (
+	// SharedStrategyRandom selects a random subscriber.
+	SharedStrategyRandom SharedStrategyType = "random"
+	// SharedStrategyRoundRobin selects the subscribers in turn.
+	SharedStrategyRoundRobin SharedStrategyType = "round_robin"
+	// SharedStrategyStickyPublisher always selects the same subscriber for the messages from the same publisher client.
+	SharedStrategyStickyPublisher SharedStrategyType = "sticky_publisher"
+	// SharedStrategyHashTopic always selects the same subscriber for the messages with the same topic name,
+	// which keeps the message order of a topic (e.g. per-device ordering).
+	SharedStrategyHashTopic SharedStrategyType = "hash_topic"
+	// SharedStrategyLeastQueueDepth selects the subscriber with the least queued messages.
+	SharedStrategyLeastQueueDepth SharedStrategyType = "least_queue_depth"
+)
+
+var (
+	// DefaultSharedSubscription is the default value of SharedSubscription
+	// The strategy is left empty, so that the federation can tell whether it is configured.
+	DefaultSharedSubscription = SharedSubscription{}
+)
+
+// SharedSubscription is the config of shared subscriptions.
+type SharedSubscription struct {
+	// Strategy is the default load balancing strategy for all share groups.
+	// If empty, use "random" as default.
+	Strategy SharedStrategyType `yaml:"strategy"`
+	// Groups overrides the strategy of specific share groups, key by the share name.
+	Groups map[string]SharedStrategyType `yaml:"groups"`
+}
+
+// StrategyOf returns the strategy of the given share group.
+func (s SharedSubscription) StrategyOf(shareName string) SharedStrategyType {
+	if v, ok := s.Groups[shareName]; ok && v != "" {
+		return v
+	}
+	if s.Strategy != "" {
+		return s.Strategy
+	}
+	return SharedStrategyRandom
+}
+
+func (s SharedSubscription) Validate() error {
+	for name := range s.Groups {
+		if name == "" || strings.ContainsAny(name, "/+#") {
+			return fmt.Errorf("invalid share name in shared_subscription.groups: %s", name)
+		}
+	}
+	return nil
+}
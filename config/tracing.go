@@ -0,0 +1,104 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type TracingExporterType = string
+
+const (
+	TracingExporterOTLP   TracingExporterType = "otlp"
+	TracingExporterFile   TracingExporterType = "file"
+	TracingExporterStdout TracingExporterType = "stdout"
+)
+
+var (
+	// DefaultTracingConfig is the default value of Tracing
+	DefaultTracingConfig = Tracing{
+		Enable:      false,
+		ServiceName: "gmqttd",
+		SampleRatio: 1,
+		Exporter:    TracingExporterOTLP,
+		OTLP: OTLPTracingExporter{
+			Endpoint: "localhost:4317",
+			Insecure: true,
+			Timeout:  10 * time.Second,
+		},
+		File: FileTracingExporter{
+			Path: "./gmqttd-traces.json",
+		},
+	}
+)
+
+// Tracing is the config of the OpenTelemetry tracing,
+// which records the spans of the connect, publish and delivery paths, including the hooks and persistence calls.
+// The W3C trace context is propagated through the "traceparent" user property of the MQTT 5 messages.
+type Tracing struct {
+	// Enable indicates whether to enable tracing.
+	Enable bool `yaml:"enable"`
+	// ServiceName is the service.name resource attribute of the spans.
+	// Default to "gmqttd".
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio is the ratio of the traces started by the broker to be sampled, in range [0,1].
+	// The traces propagated from the clients follow the sampling decision of the clients.
+	// Default to 1.
+	SampleRatio float64 `yaml:"sample_ratio"`
+	// Exporter is the type of the span exporter, possible values: otlp, file, stdout.
+	// Default to "otlp".
+	Exporter TracingExporterType `yaml:"exporter"`
+	// OTLP is the configuration of the OTLP exporter.
+	OTLP OTLPTracingExporter `yaml:"otlp"`
+	// File is the configuration of the file exporter.
+	File FileTracingExporter `yaml:"file"`
+}
+
+// OTLPTracingExporter is the configuration of the OTLP exporter, which exports the spans via gRPC.
+type OTLPTracingExporter struct {
+	// Endpoint is the host:port of the OTLP gRPC receiver.
+	// Default to "localhost:4317".
+	Endpoint string `yaml:"endpoint"`
+	// Insecure indicates whether to disable TLS.
+	// Default to true.
+	Insecure bool `yaml:"insecure"`
+	// Headers is the gRPC metadata sent with each export request, e.g. the authentication token.
+	Headers map[string]string `yaml:"headers"`
+	// Timeout is the timeout of each export request.
+	// Default to 10s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// FileTracingExporter is the configuration of the file exporter, which appends the spans as JSON to the file.
+// It is intended for local testing.
+type FileTracingExporter struct {
+	// Path is the path of the file. A relative path is relative to the directory of the config file.
+	// Default to "./gmqttd-traces.json".
+	Path string `yaml:"path"`
+}
+
+func (t *Tracing) Validate() error {
+	if !t.Enable {
+		return nil
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New("tracing sample_ratio must be in range [0,1]")
+	}
+	switch t.Exporter {
+	case TracingExporterOTLP:
+		if t.OTLP.Endpoint == "" {
+			return errors.New("empty tracing otlp endpoint")
+		}
+		if t.OTLP.Timeout < 0 {
+			return errors.New("tracing otlp timeout must not be negative")
+		}
+	case TracingExporterFile:
+		if t.File.Path == "" {
+			return errors.New("empty tracing file path")
+		}
+	case TracingExporterStdout:
+	default:
+		return fmt.Errorf("invalid tracing exporter: %s", t.Exporter)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package encoding
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"time"
 
@@ -150,13 +151,17 @@ func EncodeSession(sess *gmqtt.Session, b *bytes.Buffer) {
 	WriteString(b, []byte(sess.ClientID))
 	if sess.Will != nil {
 		b.WriteByte(1)
-		EncodeMessage(sess.Will, b)
+		// DecodeMessage reads until EOF, so the will message is length-prefixed.
+		will := &bytes.Buffer{}
+		EncodeMessage(sess.Will, will)
+		WriteString(b, will.Bytes())
 		WriteUint32(b, sess.WillDelayInterval)
 	} else {
 		b.WriteByte(0)
 	}
 	time := make([]byte, 8)
 	binary.BigEndian.PutUint64(time, uint64(sess.ConnectedAt.Unix()))
+	b.Write(time)
 	WriteUint32(b, sess.ExpiryInterval)
 }
 
@@ -172,7 +177,12 @@ func DecodeSession(b *bytes.Buffer) (sess *gmqtt.Session, err error) {
 		return
 	}
 	if willPresent == 1 {
-		sess.Will, err = DecodeMessage(b)
+		var will []byte
+		will, err = ReadString(b)
+		if err != nil {
+			return
+		}
+		sess.Will, err = DecodeMessageFromBytes(will)
 		if err != nil {
 			return
 		}
@@ -181,8 +191,61 @@ func DecodeSession(b *bytes.Buffer) (sess *gmqtt.Session, err error) {
 			return
 		}
 	}
+	if b.Len() < 8 {
+		return nil, errors.New("invalid length")
+	}
 	t := binary.BigEndian.Uint64(b.Next(8))
 	sess.ConnectedAt = time.Unix(int64(t), 0)
 	sess.ExpiryInterval, err = ReadUint32(b)
 	return
 }
+
+// EncodeSubscription encodes the subscription into bytes.
+func EncodeSubscription(sub *gmqtt.Subscription) []byte {
+	w := &bytes.Buffer{}
+	WriteString(w, []byte(sub.ShareName))
+	WriteString(w, []byte(sub.TopicFilter))
+	WriteUint32(w, sub.ID)
+	w.WriteByte(sub.QoS)
+	WriteBool(w, sub.NoLocal)
+	WriteBool(w, sub.RetainAsPublished)
+	w.WriteByte(sub.RetainHandling)
+	return w.Bytes()
+}
+
+// DecodeSubscription decodes the subscription from bytes.
+func DecodeSubscription(b []byte) (*gmqtt.Subscription, error) {
+	sub := &gmqtt.Subscription{}
+	r := bytes.NewBuffer(b)
+	share, err := ReadString(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.ShareName = string(share)
+	topic, err := ReadString(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.TopicFilter = string(topic)
+	sub.ID, err = ReadUint32(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.QoS, err = r.ReadByte()
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.NoLocal, err = ReadBool(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.RetainAsPublished, err = ReadBool(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.RetainHandling, err = r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"path/filepath"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	file_queue "github.com/DrmagicE/gmqtt/persistence/queue/file"
+	"github.com/DrmagicE/gmqtt/persistence/session"
+	file_sess "github.com/DrmagicE/gmqtt/persistence/session/file"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	file_sub "github.com/DrmagicE/gmqtt/persistence/subscription/file"
+	"github.com/DrmagicE/gmqtt/persistence/unack"
+	file_unack "github.com/DrmagicE/gmqtt/persistence/unack/file"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+	"github.com/DrmagicE/gmqtt/retained"
+	file_retained "github.com/DrmagicE/gmqtt/retained/file"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	server.RegisterPersistenceFactory("file", NewFile)
+}
+
+// NewFile returns the embedded file persistence which stores all data in a single append-only file.
+// It requires no external service.
+func NewFile(config config.Config) (server.Persistence, error) {
+	return &file{
+		config: config,
+	}, nil
+}
+
+type file struct {
+	db     *aof.DB
+	config config.Config
+}
+
+func (f *file) Open() error {
+	cfg := f.config.Persistence.File
+	p := cfg.Path
+	if !filepath.IsAbs(p) && f.config.ConfigDir != "" {
+		p = filepath.Join(f.config.ConfigDir, p)
+	}
+	db, err := aof.Open(p, aof.Options{
+		Fsync:                cfg.Fsync,
+		CompactionMinSize:    cfg.CompactionMinSize,
+		CompactionPercentage: cfg.CompactionPercentage,
+		OnCompactionError: func(err error) {
+			server.LoggerWithField(zap.String("persistence", "file")).Error("fail to compact the persistence file", zap.Error(err))
+		},
+	})
+	if err != nil {
+		return err
+	}
+	f.db = db
+	return nil
+}
+
+func (f *file) NewQueueStore(config config.Config, defaultNotifier queue.Notifier, clientID string) (queue.Store, error) {
+	return file_queue.New(file_queue.Options{
+		MaxQueuedMsg:    config.MQTT.MaxQueuedMsg,
+		InflightExpiry:  config.MQTT.InflightExpiry,
+		ClientID:        clientID,
+		DB:              f.db,
+		DefaultNotifier: defaultNotifier,
+	})
+}
+
+func (f *file) NewSubscriptionStore(config config.Config) (subscription.Store, error) {
+	return file_sub.New(f.db), nil
+}
+
+func (f *file) NewSessionStore(config config.Config) (session.Store, error) {
+	return file_sess.New(f.db), nil
+}
+
+func (f *file) NewUnackStore(config config.Config, clientID string) (unack.Store, error) {
+	return file_unack.New(file_unack.Options{
+		ClientID: clientID,
+		DB:       f.db,
+	}), nil
+}
+
+func (f *file) NewRetainedStore(config config.Config) (retained.Store, error) {
+	st := file_retained.New(f.db)
+	err := st.Init()
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (f *file) Close() error {
+	return f.db.Close()
+}
@@ -0,0 +1,191 @@
+package persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	queue_test "github.com/DrmagicE/gmqtt/persistence/queue/test"
+	sess_test "github.com/DrmagicE/gmqtt/persistence/session/test"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
+	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newFileConfig(t *testing.T) config.Config {
+	dir, err := ioutil.TempDir("", "gmqtt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	cfg := config.Config{
+		Persistence: config.DefaultPersistenceConfig,
+	}
+	cfg.Persistence.Type = config.PersistenceTypeFile
+	cfg.Persistence.File.Path = filepath.Join(dir, "gmqttd.aof")
+	return cfg
+}
+
+// nopNotifier is used by the tests which should not change the state of queue_test.TestNotifier.
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyDropped(elem *queue.Elem, err error) {}
+func (nopNotifier) NotifyInflightAdded(delta int)             {}
+func (nopNotifier) NotifyMsgQueueAdded(delta int)             {}
+
+type FileSuite struct {
+	suite.Suite
+	p server.Persistence
+}
+
+func (s *FileSuite) SetupTest() {
+	p, err := NewFile(newFileConfig(s.T()))
+	if err != nil {
+		s.T().Fatal(err.Error())
+	}
+	err = p.Open()
+	if err != nil {
+		s.T().Fatal(err.Error())
+	}
+	s.p = p
+}
+
+func (s *FileSuite) TearDownTest() {
+	s.p.Close()
+}
+
+func (s *FileSuite) TestQueue() {
+	a := assert.New(s.T())
+	qs, err := s.p.NewQueueStore(queue_test.TestServerConfig, queue_test.TestNotifier, queue_test.TestClientID)
+	a.Nil(err)
+	queue_test.TestQueue(s.T(), qs)
+}
+
+func (s *FileSuite) TestSubscription() {
+	newFn := func() subscription.Store {
+		st, err := s.p.NewSubscriptionStore(queue_test.TestServerConfig)
+		if err != nil {
+			panic(err)
+		}
+		return st
+	}
+	sub_test.TestSuite(s.T(), newFn)
+}
+
+func (s *FileSuite) TestSession() {
+	a := assert.New(s.T())
+	st, err := s.p.NewSessionStore(queue_test.TestServerConfig)
+	a.Nil(err)
+	sess_test.TestSuite(s.T(), st)
+}
+
+func (s *FileSuite) TestUnack() {
+	a := assert.New(s.T())
+	st, err := s.p.NewUnackStore(unack_test.TestServerConfig, unack_test.TestClientID)
+	a.Nil(err)
+	unack_test.TestSuite(s.T(), st)
+}
+
+func (s *FileSuite) TestRetained() {
+	retained_test.TestSuite(s.T(), func() retained.Store {
+		st, err := s.p.NewRetainedStore(queue_test.TestServerConfig)
+		if err != nil {
+			panic(err)
+		}
+		st.ClearAll()
+		return st
+	})
+}
+
+func TestFile(t *testing.T) {
+	suite.Run(t, &FileSuite{})
+}
+
+func TestFile_Restart(t *testing.T) {
+	a := assert.New(t)
+	cfg := newFileConfig(t)
+	p, err := NewFile(cfg)
+	a.Nil(err)
+	a.Nil(p.Open())
+
+	sess, err := p.NewSessionStore(cfg)
+	a.Nil(err)
+	a.Nil(sess.Set(&gmqtt.Session{ClientID: "cid", Will: &gmqtt.Message{Topic: "will"}}))
+	subs, err := p.NewSubscriptionStore(cfg)
+	a.Nil(err)
+	_, err = subs.Subscribe("cid", &gmqtt.Subscription{TopicFilter: "a/b", QoS: 1})
+	a.Nil(err)
+	ua, err := p.NewUnackStore(cfg, "cid")
+	a.Nil(err)
+	_, err = ua.Set(1)
+	a.Nil(err)
+	rt, err := p.NewRetainedStore(cfg)
+	a.Nil(err)
+	rt.AddOrReplace(&gmqtt.Message{Topic: "a/b", Retained: true, Payload: []byte{1}})
+	qs, err := p.NewQueueStore(queue_test.TestServerConfig, nopNotifier{}, "cid")
+	a.Nil(err)
+	a.Nil(qs.Add(&queue.Elem{
+		At: time.Now(),
+		MessageWithID: &queue.Publish{
+			Message: &gmqtt.Message{QoS: packets.Qos1, Topic: "a/b", Payload: []byte{1}},
+		},
+	}))
+	a.Nil(p.Close())
+
+	// reopen and check the data
+	p, err = NewFile(cfg)
+	a.Nil(err)
+	a.Nil(p.Open())
+	defer p.Close()
+
+	sess, err = p.NewSessionStore(cfg)
+	a.Nil(err)
+	s, err := sess.Get("cid")
+	a.Nil(err)
+	a.Equal("will", s.Will.Topic)
+
+	subs, err = p.NewSubscriptionStore(cfg)
+	a.Nil(err)
+	a.Nil(subs.Init([]string{"cid"}))
+	stats, err := subs.GetClientStats("cid")
+	a.Nil(err)
+	a.EqualValues(1, stats.SubscriptionsCurrent)
+
+	ua, err = p.NewUnackStore(cfg, "cid")
+	a.Nil(err)
+	a.Nil(ua.Init(false))
+	ok, err := ua.Set(1)
+	a.Nil(err)
+	a.True(ok)
+
+	rt, err = p.NewRetainedStore(cfg)
+	a.Nil(err)
+	a.NotNil(rt.GetRetainedMessage("a/b"))
+
+	qs, err = p.NewQueueStore(queue_test.TestServerConfig, nopNotifier{}, "cid")
+	a.Nil(err)
+	a.Nil(qs.Init(&queue.InitOptions{
+		Version:        packets.Version5,
+		ReadBytesLimit: 1024,
+		Notifier:       nopNotifier{},
+	}))
+	rs, err := qs.ReadInflight(10)
+	a.Nil(err)
+	a.Len(rs, 0)
+	rs, err = qs.Read([]packets.PacketID{1})
+	a.Nil(err)
+	a.Len(rs, 1)
+	a.Equal("a/b", rs[0].MessageWithID.(*queue.Publish).Topic)
+}
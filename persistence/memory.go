@@ -10,6 +10,8 @@ import (
 	mem_sub "github.com/DrmagicE/gmqtt/persistence/subscription/mem"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
 	mem_unack "github.com/DrmagicE/gmqtt/persistence/unack/mem"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -50,6 +52,10 @@ func (m *memory) NewSubscriptionStore(config config.Config) (subscription.Store,
 	return mem_sub.NewStore(), nil
 }
 
+func (m *memory) NewRetainedStore(config config.Config) (retained.Store, error) {
+	return trie.NewStore(), nil
+}
+
 func (m *memory) Close() error {
 	return nil
 }
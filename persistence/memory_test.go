@@ -12,6 +12,8 @@ import (
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
 	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -52,6 +54,17 @@ func (s *MemorySuite) TestUnack() {
 	unack_test.TestSuite(s.T(), st)
 }
 
+func (s *MemorySuite) TestRetained() {
+	retained_test.TestSuite(s.T(), func() retained.Store {
+		st, err := s.p.NewRetainedStore(queue_test.TestServerConfig)
+		if err != nil {
+			panic(err)
+		}
+		st.ClearAll()
+		return st
+	})
+}
+
 func TestMemory(t *testing.T) {
 	p, err := NewMemory(config.Config{})
 	if err != nil {
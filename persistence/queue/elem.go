@@ -48,6 +48,21 @@ type Elem struct {
 	MessageWithID
 }
 
+// Clone returns a deep copy of the elem.
+func (e *Elem) Clone() *Elem {
+	ne := &Elem{
+		At:     e.At,
+		Expiry: e.Expiry,
+	}
+	switch m := e.MessageWithID.(type) {
+	case *Publish:
+		ne.MessageWithID = &Publish{Message: m.Message.Copy()}
+	case *Pubrel:
+		ne.MessageWithID = &Pubrel{PacketID: m.PacketID}
+	}
+	return ne
+}
+
 // Encode encodes the publish structure into bytes and write it to the buffer
 func (p *Publish) Encode(b *bytes.Buffer) {
 	encoding.EncodeMessage(p.Message, b)
@@ -10,6 +10,7 @@ var (
 	ErrDropQueueFull            = errors.New("the message queue is full")
 	ErrDropExpired              = errors.New("the message is expired")
 	ErrDropExpiredInflight      = errors.New("the inflight message is expired")
+	ErrDropManually             = errors.New("the message is dropped manually")
 )
 
 // InternalError wraps the error of the backend storage.
@@ -0,0 +1,407 @@
+package file
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const (
+	queuePrefix = "queue:"
+)
+
+var _ queue.Store = (*Queue)(nil)
+
+func getBucket(clientID string) string {
+	return queuePrefix + clientID
+}
+
+// getKey returns the db key of the given sequence, the big-endian encoding keeps the keys in order.
+func getKey(seq uint64) string {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return string(b)
+}
+
+type Options struct {
+	MaxQueuedMsg    int
+	InflightExpiry  time.Duration
+	ClientID        string
+	DB              *aof.DB
+	DefaultNotifier queue.Notifier
+}
+
+// elem is the list element value which records the sequence of the queue.Elem in db.
+type elem struct {
+	seq uint64
+	*queue.Elem
+}
+
+// Queue is the queue store backed by aof.DB.
+// It keeps the same in-memory structure as the mem queue and writes every modification to the db.
+type Queue struct {
+	cond           *sync.Cond
+	clientID       string
+	version        packets.Version
+	readBytesLimit uint32
+	l              *list.List
+	// current is the next element to read.
+	current         *list.Element
+	inflightDrained bool
+	closed          bool
+	// max is the maximum queue length
+	max            int
+	log            *zap.Logger
+	inflightExpiry time.Duration
+	notifier       queue.Notifier
+	db             *aof.DB
+	// seq is the sequence for the next element.
+	seq uint64
+}
+
+// New creates a queue and loads the queued elements of the client from db.
+func New(opts Options) (*Queue, error) {
+	q := &Queue{
+		clientID:       opts.ClientID,
+		cond:           sync.NewCond(&sync.Mutex{}),
+		l:              list.New(),
+		max:            opts.MaxQueuedMsg,
+		inflightExpiry: opts.InflightExpiry,
+		notifier:       opts.DefaultNotifier,
+		db:             opts.DB,
+		log:            server.LoggerWithField(zap.String("queue", "file")),
+	}
+	var err error
+	q.db.ForEach(getBucket(q.clientID), func(key string, value []byte) bool {
+		e := &queue.Elem{}
+		err = e.Decode(value)
+		if err != nil {
+			return false
+		}
+		seq := binary.BigEndian.Uint64([]byte(key))
+		q.l.PushBack(&elem{seq: seq, Elem: e})
+		q.seq = seq + 1
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) put(e *elem) error {
+	return q.db.Put(getBucket(q.clientID), getKey(e.seq), e.Encode())
+}
+
+func (q *Queue) delete(e *elem) error {
+	return q.db.Delete(getBucket(q.clientID), getKey(e.seq))
+}
+
+func (q *Queue) Close() error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.closed = true
+	q.cond.Signal()
+	return nil
+}
+
+func (q *Queue) Init(opts *queue.InitOptions) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.closed = false
+	q.inflightDrained = false
+	if opts.CleanStart {
+		err := q.db.DeleteBucket(getBucket(q.clientID))
+		if err != nil {
+			return err
+		}
+		q.l = list.New()
+	}
+	q.readBytesLimit = opts.ReadBytesLimit
+	q.version = opts.Version
+	q.current = q.l.Front()
+	q.notifier = opts.Notifier
+	q.cond.Signal()
+	return nil
+}
+
+func (q *Queue) Clean() error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.db.DeleteBucket(getBucket(q.clientID))
+}
+
+func (q *Queue) Add(el *queue.Elem) (err error) {
+	now := time.Now()
+	var dropErr error
+	var dropElem *list.Element
+	var drop bool
+	q.cond.L.Lock()
+	defer func() {
+		q.cond.L.Unlock()
+		q.cond.Signal()
+	}()
+	defer func() {
+		if drop {
+			if dropErr == queue.ErrDropExpiredInflight {
+				q.notifier.NotifyInflightAdded(-1)
+			}
+			if dropElem == nil {
+				q.notifier.NotifyDropped(el, dropErr)
+				return
+			}
+			if dropElem == q.current {
+				q.current = q.current.Next()
+			}
+			q.l.Remove(dropElem)
+			if err = q.delete(dropElem.Value.(*elem)); err != nil {
+				return
+			}
+			q.notifier.NotifyDropped(dropElem.Value.(*elem).Elem, dropErr)
+		} else {
+			q.notifier.NotifyMsgQueueAdded(1)
+		}
+		ne := &elem{seq: q.seq, Elem: el}
+		q.seq++
+		if err = q.put(ne); err != nil {
+			return
+		}
+		e := q.l.PushBack(ne)
+		if q.current == nil {
+			q.current = e
+		}
+	}()
+	if q.l.Len() >= q.max {
+		// set default drop error
+		dropErr = queue.ErrDropQueueFull
+		drop = true
+
+		// drop expired inflight message
+		if v := q.l.Front(); v != q.current &&
+			v != nil &&
+			queue.ElemExpiry(now, v.Value.(*elem).Elem) {
+			dropElem = v
+			dropErr = queue.ErrDropExpiredInflight
+			return
+		}
+
+		// drop the current elem if there is no more non-inflight messages.
+		if q.inflightDrained && q.current == nil {
+			return
+		}
+		for e := q.current; e != nil; e = e.Next() {
+			pub := e.Value.(*elem).MessageWithID.(*queue.Publish)
+			// drop expired non-inflight message
+			if pub.ID() == 0 &&
+				queue.ElemExpiry(now, e.Value.(*elem).Elem) {
+				dropElem = e
+				dropErr = queue.ErrDropExpired
+				return
+			}
+			// drop qos0 message in the queue
+			if pub.ID() == 0 && pub.QoS == packets.Qos0 && dropElem == nil {
+				dropElem = e
+			}
+		}
+		if dropElem != nil {
+			return
+		}
+		if el.MessageWithID.(*queue.Publish).QoS == packets.Qos0 {
+			return
+		}
+
+		if q.inflightDrained {
+			// drop the front message
+			dropElem = q.current
+			return
+		}
+		// the messages in the queue are all inflight messages, drop the current elem
+		return
+	}
+	return nil
+}
+
+func (q *Queue) Replace(el *queue.Elem) (replaced bool, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	unread := q.current
+	for e := q.l.Front(); e != nil && e != unread; e = e.Next() {
+		if v := e.Value.(*elem); v.ID() == el.ID() {
+			ne := &elem{seq: v.seq, Elem: el}
+			if err = q.put(ne); err != nil {
+				return false, err
+			}
+			e.Value = ne
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (q *Queue) Read(pids []packets.PacketID) (rs []*queue.Elem, err error) {
+	now := time.Now()
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if !q.inflightDrained {
+		panic("must call ReadInflight to drain all inflight messages before Read")
+	}
+	for (q.l.Len() == 0 || q.current == nil) && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, queue.ErrClosed
+	}
+	length := q.l.Len()
+	if len(pids) < length {
+		length = len(pids)
+	}
+	var msgQueueDelta, inflightDelta int
+	var pflag int
+	defer func() {
+		q.notifier.NotifyMsgQueueAdded(msgQueueDelta)
+		q.notifier.NotifyInflightAdded(inflightDelta)
+	}()
+	for i := 0; i < length && q.current != nil; i++ {
+		v := q.current
+		e := v.Value.(*elem)
+		// remove expired message
+		if queue.ElemExpiry(now, e.Elem) {
+			q.current = q.current.Next()
+			q.notifier.NotifyDropped(e.Elem, queue.ErrDropExpired)
+			q.l.Remove(v)
+			msgQueueDelta--
+			if err = q.delete(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// remove message which exceeds maximum packet size
+		pub := e.MessageWithID.(*queue.Publish)
+		if size := pub.TotalBytes(q.version); size > q.readBytesLimit {
+			q.current = q.current.Next()
+			q.notifier.NotifyDropped(e.Elem, queue.ErrDropExceedsMaxPacketSize)
+			q.l.Remove(v)
+			msgQueueDelta--
+			if err = q.delete(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// remove qos 0 message after read
+		if pub.QoS == 0 {
+			q.current = q.current.Next()
+			q.l.Remove(v)
+			msgQueueDelta--
+			if err = q.delete(e); err != nil {
+				return nil, err
+			}
+		} else {
+			pub.SetID(pids[pflag])
+			// When the message becomes inflight message, update the expiry time.
+			if q.inflightExpiry != 0 {
+				e.Expiry = now.Add(q.inflightExpiry)
+			}
+			pflag++
+			inflightDelta++
+			q.current = q.current.Next()
+			if err = q.put(e); err != nil {
+				return nil, err
+			}
+		}
+		rs = append(rs, e.Elem)
+	}
+	return rs, nil
+}
+
+func (q *Queue) ReadInflight(maxSize uint) (rs []*queue.Elem, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	length := q.l.Len()
+	if length == 0 || q.current == nil {
+		q.inflightDrained = true
+		return nil, nil
+	}
+	if int(maxSize) < length {
+		length = int(maxSize)
+	}
+	for i := 0; i < length && q.current != nil; i++ {
+		if e := q.current.Value.(*elem); e.ID() != 0 {
+			if q.inflightExpiry != 0 {
+				e.Expiry = time.Now().Add(q.inflightExpiry)
+				if err = q.put(e); err != nil {
+					return nil, err
+				}
+			}
+			rs = append(rs, e.Elem)
+			q.current = q.current.Next()
+		} else {
+			q.inflightDrained = true
+			break
+		}
+	}
+	return rs, nil
+}
+
+func (q *Queue) Remove(pid packets.PacketID) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	// Must not remove unread messages.
+	unread := q.current
+	for e := q.l.Front(); e != nil && e != unread; e = e.Next() {
+		if v := e.Value.(*elem); v.ID() == pid {
+			if err := q.delete(v); err != nil {
+				return err
+			}
+			q.l.Remove(e)
+			q.notifier.NotifyMsgQueueAdded(-1)
+			q.notifier.NotifyInflightAdded(-1)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *Queue) Iterate(fn queue.IterateFn) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for e := q.l.Front(); e != nil; e = e.Next() {
+		if !fn(e.Value.(*elem).Elem.Clone()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *Queue) Drop(fn queue.DropFn) (n int, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	defer func() {
+		q.notifier.NotifyMsgQueueAdded(-n)
+	}()
+	var i int
+	for e := q.l.Front(); e != nil; i++ {
+		next := e.Next()
+		if v := e.Value.(*elem); v.ID() == 0 && fn(i, v.Elem.Clone()) {
+			if err = q.delete(v); err != nil {
+				return n, err
+			}
+			if e == q.current {
+				q.current = next
+			}
+			q.l.Remove(e)
+			q.notifier.NotifyDropped(v.Elem, queue.ErrDropManually)
+			n++
+		}
+		e = next
+	}
+	return n, nil
+}
@@ -276,3 +276,36 @@ func (q *Queue) Remove(pid packets.PacketID) error {
 	}
 	return nil
 }
+
+func (q *Queue) Iterate(fn queue.IterateFn) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for e := q.l.Front(); e != nil; e = e.Next() {
+		if !fn(e.Value.(*queue.Elem).Clone()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *Queue) Drop(fn queue.DropFn) (n int, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	defer func() {
+		q.notifier.NotifyMsgQueueAdded(-n)
+	}()
+	var i int
+	for e := q.l.Front(); e != nil; i++ {
+		next := e.Next()
+		if v := e.Value.(*queue.Elem); v.ID() == 0 && fn(i, v.Clone()) {
+			if e == q.current {
+				q.current = next
+			}
+			q.l.Remove(e)
+			q.notifier.NotifyDropped(v, queue.ErrDropManually)
+			n++
+		}
+		e = next
+	}
+	return n, nil
+}
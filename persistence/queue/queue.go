@@ -58,8 +58,26 @@ type Store interface {
 
 	// Remove removes the elem for a given id.
 	Remove(pid packets.PacketID) error
+
+	// Iterate iterates the elems in the queue in order without removing them.
+	// The inflight elems (the elems with a non-zero packet id) come first.
+	// The elems passed to fn must be copies, modifying them must not affect the queue.
+	Iterate(fn IterateFn) error
+	// Drop removes the non-inflight elems for which fn returns true and returns the number of removed elems.
+	// The inflight elems are managed by the protocol flow and must not be dropped.
+	// The implementation must call Notifier.NotifyDropped with ErrDropManually for each removed elem.
+	Drop(fn DropFn) (int, error)
 }
 
+// IterateFn is the callback function used by Store.Iterate.
+// Return false means to stop the iteration.
+type IterateFn func(elem *Elem) bool
+
+// DropFn is the callback function used by Store.Drop.
+// The index is the position of the elem in the queue, which is the same as the Iterate order.
+// Return true means to drop the elem.
+type DropFn func(index int, elem *Elem) bool
+
 type Notifier interface {
 	// NotifyDropped will be called when the element in the queue is dropped.
 	// The err indicates the reason of why it is dropped.
@@ -148,6 +148,35 @@ func (mr *MockStoreMockRecorder) Remove(pid interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockStore)(nil).Remove), pid)
 }
 
+// Iterate mocks base method
+func (m *MockStore) Iterate(fn IterateFn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Iterate", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Iterate indicates an expected call of Iterate
+func (mr *MockStoreMockRecorder) Iterate(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockStore)(nil).Iterate), fn)
+}
+
+// Drop mocks base method
+func (m *MockStore) Drop(fn DropFn) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drop", fn)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Drop indicates an expected call of Drop
+func (mr *MockStoreMockRecorder) Drop(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop", reflect.TypeOf((*MockStore)(nil).Drop), fn)
+}
+
 // MockNotifier is a mock of Notifier interface
 type MockNotifier struct {
 	ctrl     *gomock.Controller
@@ -20,16 +20,18 @@ const (
 
 var _ queue.Store = (*Queue)(nil)
 
-func getKey(clientID string) string {
-	return queuePrefix + clientID
-}
-
 type Options struct {
 	MaxQueuedMsg    int
 	ClientID        string
 	InflightExpiry  time.Duration
 	Pool            *redigo.Pool
 	DefaultNotifier queue.Notifier
+	// KeyPrefix is prepended to all keys.
+	KeyPrefix string
+	// HashTag wraps the client id in the key with braces (e.g: queue:{clientID}),
+	// which makes the slot of the key in redis cluster only depend on the client id,
+	// no matter the client id contains braces or not.
+	HashTag bool
 }
 
 type Queue struct {
@@ -51,6 +53,8 @@ type Queue struct {
 	log            *zap.Logger
 	inflightExpiry time.Duration
 	notifier       queue.Notifier
+	// key is the redis key of the queue.
+	key string
 }
 
 func New(opts Options) (*Queue, error) {
@@ -66,9 +70,17 @@ func New(opts Options) (*Queue, error) {
 		inflightExpiry:  opts.InflightExpiry,
 		notifier:        opts.DefaultNotifier,
 		log:             server.LoggerWithField(zap.String("queue", "redis")),
+		key:             getKey(opts.KeyPrefix, opts.ClientID, opts.HashTag),
 	}, nil
 }
 
+func getKey(keyPrefix, clientID string, hashTag bool) string {
+	if hashTag {
+		return keyPrefix + queuePrefix + "{" + clientID + "}"
+	}
+	return keyPrefix + queuePrefix + clientID
+}
+
 func wrapError(err error) *codes.Error {
 	return &codes.Error{
 		Code: codes.UnspecifiedError,
@@ -90,7 +102,7 @@ func (q *Queue) Close() error {
 }
 
 func (q *Queue) setLen(conn redigo.Conn) error {
-	l, err := conn.Do("llen", getKey(q.clientID))
+	l, err := conn.Do("llen", q.key)
 	if err != nil {
 		return err
 	}
@@ -105,7 +117,7 @@ func (q *Queue) Init(opts *queue.InitOptions) error {
 	defer conn.Close()
 
 	if opts.CleanStart {
-		_, err := conn.Do("del", getKey(q.clientID))
+		_, err := conn.Do("del", q.key)
 		if err != nil {
 			return wrapError(err)
 		}
@@ -128,7 +140,7 @@ func (q *Queue) Init(opts *queue.InitOptions) error {
 func (q *Queue) Clean() error {
 	conn := q.pool.Get()
 	defer conn.Close()
-	_, err := conn.Do("del", getKey(q.clientID))
+	_, err := conn.Do("del", q.key)
 	return err
 }
 
@@ -156,14 +168,14 @@ func (q *Queue) Add(elem *queue.Elem) (err error) {
 				q.notifier.NotifyDropped(elem, dropErr)
 				return
 			} else {
-				err = conn.Send("lrem", getKey(q.clientID), 1, dropBytes)
+				err = conn.Send("lrem", q.key, 1, dropBytes)
 			}
 			q.notifier.NotifyDropped(dropElem, dropErr)
 		} else {
 			q.notifier.NotifyMsgQueueAdded(1)
 			q.len++
 		}
-		_ = conn.Send("rpush", getKey(q.clientID), elem.Encode())
+		_ = conn.Send("rpush", q.key, elem.Encode())
 		err = conn.Flush()
 	}()
 	if q.len >= q.max {
@@ -172,7 +184,7 @@ func (q *Queue) Add(elem *queue.Elem) (err error) {
 		drop = true
 		var rs []interface{}
 		// drop expired inflight message
-		rs, err = redigo.Values(conn.Do("lrange", getKey(q.clientID), 0, q.len))
+		rs, err = redigo.Values(conn.Do("lrange", q.key, 0, q.len))
 		if err != nil {
 			return
 		}
@@ -217,7 +229,7 @@ func (q *Queue) Add(elem *queue.Elem) (err error) {
 		if q.inflightDrained && q.current >= q.len {
 			return
 		}
-		rs, err = redigo.Values(conn.Do("lrange", getKey(q.clientID), q.current, q.len))
+		rs, err = redigo.Values(conn.Do("lrange", q.key, q.current, q.len))
 		if err != nil {
 			return err
 		}
@@ -251,7 +263,7 @@ func (q *Queue) Replace(elem *queue.Elem) (replaced bool, err error) {
 	if stop < 0 {
 		stop = 0
 	}
-	rs, err := redigo.Values(conn.Do("lrange", getKey(q.clientID), 0, stop))
+	rs, err := redigo.Values(conn.Do("lrange", q.key, 0, stop))
 	if err != nil {
 		return false, err
 	}
@@ -263,7 +275,7 @@ func (q *Queue) Replace(elem *queue.Elem) (replaced bool, err error) {
 			return false, err
 		}
 		if e.ID() == elem.ID() {
-			_, err = conn.Do("lset", getKey(q.clientID), k, eb)
+			_, err = conn.Do("lset", q.key, k, eb)
 			if err != nil {
 				return false, err
 			}
@@ -290,7 +302,7 @@ func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
 	if q.closed {
 		return nil, queue.ErrClosed
 	}
-	rs, err := redigo.Values(conn.Do("lrange", getKey(q.clientID), q.current, q.current+len(pids)-1))
+	rs, err := redigo.Values(conn.Do("lrange", q.key, q.current, q.current+len(pids)-1))
 	if err != nil {
 		return nil, wrapError(err)
 	}
@@ -305,7 +317,7 @@ func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
 		}
 		// remove expired message
 		if queue.ElemExpiry(now, e) {
-			err = conn.Send("lrem", getKey(q.clientID), 1, b)
+			err = conn.Send("lrem", q.key, 1, b)
 			q.len--
 			if err != nil {
 				return nil, err
@@ -318,7 +330,7 @@ func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
 		// remove message which exceeds maximum packet size
 		pub := e.MessageWithID.(*queue.Publish)
 		if size := pub.TotalBytes(q.version); size > q.readBytesLimit {
-			err = conn.Send("lrem", getKey(q.clientID), 1, b)
+			err = conn.Send("lrem", q.key, 1, b)
 			q.len--
 			if err != nil {
 				return nil, err
@@ -329,7 +341,7 @@ func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
 		}
 
 		if e.MessageWithID.(*queue.Publish).QoS == 0 {
-			err = conn.Send("lrem", getKey(q.clientID), 1, b)
+			err = conn.Send("lrem", q.key, 1, b)
 			q.len--
 			msgQueueDelta--
 			if err != nil {
@@ -343,7 +355,7 @@ func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
 			pflag++
 			nb := e.Encode()
 
-			err = conn.Send("lset", getKey(q.clientID), q.current, nb)
+			err = conn.Send("lset", q.key, q.current, nb)
 			q.current++
 			inflightDelta++
 			q.readCache[e.MessageWithID.ID()] = nb
@@ -361,7 +373,7 @@ func (q *Queue) ReadInflight(maxSize uint) (elems []*queue.Elem, err error) {
 	defer q.cond.L.Unlock()
 	conn := q.pool.Get()
 	defer conn.Close()
-	rs, err := redigo.Values(conn.Do("lrange", getKey(q.clientID), q.current, q.current+int(maxSize)-1))
+	rs, err := redigo.Values(conn.Do("lrange", q.key, q.current, q.current+int(maxSize)-1))
 	if len(rs) == 0 {
 		q.inflightDrained = true
 		return
@@ -382,7 +394,7 @@ func (q *Queue) ReadInflight(maxSize uint) (elems []*queue.Elem, err error) {
 			if q.inflightExpiry != 0 {
 				e.Expiry = time.Now().Add(q.inflightExpiry)
 				b = e.Encode()
-				_, err = conn.Do("lset", getKey(q.clientID), beginIndex+index, b)
+				_, err = conn.Do("lset", q.key, beginIndex+index, b)
 				if err != nil {
 					return nil, err
 				}
@@ -404,7 +416,7 @@ func (q *Queue) Remove(pid packets.PacketID) error {
 	conn := q.pool.Get()
 	defer conn.Close()
 	if b, ok := q.readCache[pid]; ok {
-		_, err := conn.Do("lrem", getKey(q.clientID), 1, b)
+		_, err := conn.Do("lrem", q.key, 1, b)
 		if err != nil {
 			return err
 		}
@@ -416,3 +428,77 @@ func (q *Queue) Remove(pid packets.PacketID) error {
 	}
 	return nil
 }
+
+func (q *Queue) Iterate(fn queue.IterateFn) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	conn := q.pool.Get()
+	defer conn.Close()
+	rs, err := redigo.Values(conn.Do("lrange", q.key, 0, -1))
+	if err != nil {
+		return wrapError(err)
+	}
+	for _, v := range rs {
+		e := &queue.Elem{}
+		err := e.Decode(v.([]byte))
+		if err != nil {
+			return err
+		}
+		if !fn(e) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *Queue) Drop(fn queue.DropFn) (n int, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	conn := q.pool.Get()
+	defer conn.Close()
+	defer func() {
+		q.notifier.NotifyMsgQueueAdded(-n)
+	}()
+	rs, err := redigo.Values(conn.Do("lrange", q.key, 0, -1))
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	// kept is the arguments of rpush: the key followed by the elements which are not dropped.
+	kept := make([]interface{}, 1, len(rs)+1)
+	kept[0] = q.key
+	var dropped []*queue.Elem
+	for i, v := range rs {
+		b := v.([]byte)
+		e := &queue.Elem{}
+		err = e.Decode(b)
+		if err != nil {
+			return 0, err
+		}
+		if e.ID() != 0 || !fn(i, e) {
+			kept = append(kept, b)
+			continue
+		}
+		dropped = append(dropped, e)
+	}
+	if len(dropped) == 0 {
+		return 0, nil
+	}
+	// Rewrite the list in a batch rather than removing the elements one by one:
+	// append the kept elements, then trim the old elements off the head.
+	// A failure in between leaves duplicated elements rather than losing any of them.
+	if len(kept) > 1 {
+		_, err = conn.Do("rpush", kept...)
+		if err != nil {
+			return 0, wrapError(err)
+		}
+	}
+	_, err = conn.Do("ltrim", q.key, len(rs), -1)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	for _, e := range dropped {
+		q.notifier.NotifyDropped(e, queue.ErrDropManually)
+	}
+	n = len(dropped)
+	return n, q.setLen(conn)
+}
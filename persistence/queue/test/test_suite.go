@@ -217,6 +217,7 @@ func TestQueue(t *testing.T, store queue.Store) {
 	testCleanStart(a, store)
 	testReadExceedsDrop(a, store)
 	testClose(a, store)
+	testIterateAndDrop(a, store)
 }
 
 func testDrop(a *assert.Assertions, store queue.Store) {
@@ -670,3 +671,79 @@ func testClose(a *assert.Assertions, store queue.Store) {
 		a.Equal(queue.ErrClosed, r.err)
 	}
 }
+
+func testIterateAndDrop(a *assert.Assertions, store queue.Store) {
+	reconnect(a, true, store)
+	initDrop()
+	initNotifierLen()
+	a.NoError(add(store))
+	assertQueueLen(a, 2, 5)
+
+	var elems []*queue.Elem
+	a.NoError(store.Iterate(func(elem *queue.Elem) bool {
+		elems = append(elems, elem)
+		return true
+	}))
+	a.Len(elems, len(initElems))
+	for k, v := range elems {
+		assertMsgEqual(a, initElems[k], v)
+	}
+	// modifying the elem must not affect the queue
+	elems[0].MessageWithID.(*queue.Publish).Topic = "modified"
+
+	var i int
+	a.NoError(store.Iterate(func(elem *queue.Elem) bool {
+		i++
+		return false
+	}))
+	a.Equal(1, i)
+
+	// only the non-inflight messages can be dropped
+	var indexes []int
+	n, err := store.Drop(func(index int, elem *queue.Elem) bool {
+		indexes = append(indexes, index)
+		return elem.MessageWithID.(*queue.Publish).QoS == packets.Qos0
+	})
+	a.NoError(err)
+	a.Equal(1, n)
+	a.Equal([]int{2, 3, 4}, indexes)
+	assertDrop(a, initElems[3], queue.ErrDropManually)
+	assertQueueLen(a, 2, 4)
+
+	elems = nil
+	a.NoError(store.Iterate(func(elem *queue.Elem) bool {
+		elems = append(elems, elem)
+		return true
+	}))
+	a.Len(elems, 4)
+	assertMsgEqual(a, initElems[0], elems[0])
+	assertMsgEqual(a, initElems[1], elems[1])
+	assertMsgEqual(a, initElems[2], elems[2])
+	assertMsgEqual(a, initElems[4], elems[3])
+
+	// purge
+	n, err = store.Drop(func(index int, elem *queue.Elem) bool {
+		return true
+	})
+	a.NoError(err)
+	a.Equal(2, n)
+	a.Len(TestNotifier.dropElem, 2)
+	assertQueueLen(a, 2, 2)
+	initDrop()
+
+	// the remaining inflight messages are still readable
+	e, err := store.ReadInflight(10)
+	a.NoError(err)
+	a.Len(e, 2)
+	assertMsgEqual(a, initElems[0], e[0])
+	assertMsgEqual(a, initElems[1], e[1])
+	e, err = store.ReadInflight(10)
+	a.NoError(err)
+	a.Len(e, 0)
+	a.NoError(store.Remove(1))
+	a.NoError(store.Remove(2))
+	assertQueueLen(a, 0, 0)
+
+	reconnect(a, true, store)
+	initNotifierLen()
+}
@@ -1,17 +1,22 @@
 package persistence
 
 import (
+	"path/filepath"
+
 	redigo "github.com/gomodule/redigo/redis"
 
 	"github.com/DrmagicE/gmqtt/config"
 	"github.com/DrmagicE/gmqtt/persistence/queue"
 	redis_queue "github.com/DrmagicE/gmqtt/persistence/queue/redis"
+	"github.com/DrmagicE/gmqtt/persistence/redisconn"
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	redis_sess "github.com/DrmagicE/gmqtt/persistence/session/redis"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	redis_sub "github.com/DrmagicE/gmqtt/persistence/subscription/redis"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
 	redis_unack "github.com/DrmagicE/gmqtt/persistence/unack/redis"
+	"github.com/DrmagicE/gmqtt/retained"
+	redis_retained "github.com/DrmagicE/gmqtt/retained/redis"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -26,53 +31,56 @@ func NewRedis(config config.Config) (server.Persistence, error) {
 }
 
 type redis struct {
+	client       *redisconn.Client
 	pool         *redigo.Pool
 	config       config.Config
 	onMsgDropped server.OnMsgDropped
 }
 
+func (r *redis) keyPrefix() string {
+	return r.config.Persistence.Redis.KeyPrefix
+}
+
+// hashTag reports whether the keys should use hash tags, which is required by the redis cluster.
+func (r *redis) hashTag() bool {
+	return r.config.Persistence.Redis.Mode == config.RedisModeCluster
+}
+
 func (r *redis) NewUnackStore(config config.Config, clientID string) (unack.Store, error) {
 	return redis_unack.New(redis_unack.Options{
-		ClientID: clientID,
-		Pool:     r.pool,
+		ClientID:  clientID,
+		Pool:      r.pool,
+		KeyPrefix: r.keyPrefix(),
+		HashTag:   r.hashTag(),
 	}), nil
 }
 
 func (r *redis) NewSessionStore(config config.Config) (session.Store, error) {
-	return redis_sess.New(r.pool), nil
+	return redis_sess.New(r.pool, r.keyPrefix(), r.hashTag()), nil
 }
 
-func newPool(config config.Config) *redigo.Pool {
-	return &redigo.Pool{
-		// Dial or DialContext must be set. When both are set, DialContext takes precedence over Dial.
-		Dial: func() (redigo.Conn, error) {
-			c, err := redigo.Dial("tcp", config.Persistence.Redis.Addr)
-			if err != nil {
-				return nil, err
-			}
-			if pswd := config.Persistence.Redis.Password; pswd != "" {
-				if _, err := c.Do("AUTH", pswd); err != nil {
-					c.Close()
-					return nil, err
-				}
-			}
-			if _, err := c.Do("SELECT", config.Persistence.Redis.Database); err != nil {
-				c.Close()
-				return nil, err
+func (r *redis) Open() error {
+	cfg := r.config.Persistence.Redis
+	// relative tls file paths are relative to the directory of the config file.
+	if cfg.TLS != nil && r.config.ConfigDir != "" {
+		tlsCfg := *cfg.TLS
+		for _, v := range []*string{&tlsCfg.CACert, &tlsCfg.Cert, &tlsCfg.Key} {
+			if *v != "" && !filepath.IsAbs(*v) {
+				*v = filepath.Join(r.config.ConfigDir, *v)
 			}
-			return c, nil
-		},
+		}
+		cfg.TLS = &tlsCfg
 	}
-}
-func (r *redis) Open() error {
-	r.pool = newPool(r.config)
-	r.pool.MaxIdle = int(*r.config.Persistence.Redis.MaxIdle)
-	r.pool.MaxActive = int(*r.config.Persistence.Redis.MaxActive)
-	r.pool.IdleTimeout = r.config.Persistence.Redis.IdleTimeout
+	client, err := redisconn.New(cfg)
+	if err != nil {
+		return err
+	}
+	r.client = client
+	r.pool = client.Pool
 	conn := r.pool.Get()
 	defer conn.Close()
 	// Test the connection
-	_, err := conn.Do("PING")
+	_, err = conn.Do("PING")
 
 	return err
 }
@@ -84,13 +92,24 @@ func (r *redis) NewQueueStore(config config.Config, defaultNotifier queue.Notifi
 		ClientID:        clientID,
 		Pool:            r.pool,
 		DefaultNotifier: defaultNotifier,
+		KeyPrefix:       r.keyPrefix(),
+		HashTag:         r.hashTag(),
 	})
 }
 
 func (r *redis) NewSubscriptionStore(config config.Config) (subscription.Store, error) {
-	return redis_sub.New(r.pool), nil
+	return redis_sub.New(r.pool, r.keyPrefix(), r.hashTag()), nil
+}
+
+func (r *redis) NewRetainedStore(config config.Config) (retained.Store, error) {
+	st := redis_retained.New(r.pool, r.keyPrefix(), r.hashTag())
+	err := st.Init()
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
 }
 
 func (r *redis) Close() error {
-	return r.pool.Close()
+	return r.client.Close()
 }
@@ -0,0 +1,98 @@
+//go:build !windows
+// +build !windows
+
+package persistence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	queue_test "github.com/DrmagicE/gmqtt/persistence/queue/test"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// RedisClusterSuite runs the redis persistence in cluster mode with a key prefix.
+// miniredis serves as a single node cluster.
+type RedisClusterSuite struct {
+	RedisSuite
+	s *miniredis.Miniredis
+}
+
+func (s *RedisClusterSuite) SetupTest() {
+	m, err := miniredis.Run()
+	if err != nil {
+		s.T().Fatalf("miniredis: %v", err)
+	}
+	s.s = m
+	cfg := redisConfig
+	cfg.Mode = config.RedisModeCluster
+	cfg.Addrs = []string{m.Addr()}
+	cfg.KeyPrefix = "gmqtt:"
+	p, err := NewRedis(config.Config{
+		Persistence: config.Persistence{
+			Type:  config.PersistenceTypeRedis,
+			Redis: cfg,
+		},
+	})
+	if err != nil {
+		s.T().Fatal(err.Error())
+	}
+	err = p.Open()
+	if err != nil {
+		s.T().Fatal("fail to open redis", err)
+	}
+	s.p = p
+}
+
+func (s *RedisClusterSuite) TearDownTest() {
+	s.p.Close()
+	s.s.Close()
+}
+
+func (s *RedisClusterSuite) TearDownSuite() {}
+
+func (s *RedisClusterSuite) TestKeyPrefix() {
+	a := assert.New(s.T())
+	qs, err := s.p.NewQueueStore(queue_test.TestServerConfig, nopNotifier{}, "cid")
+	a.Nil(err)
+	a.Nil(qs.Clean())
+	sess, err := s.p.NewSessionStore(config.Config{})
+	a.Nil(err)
+	a.Nil(sess.Set(&gmqtt.Session{ClientID: "cid"}))
+	subs, err := s.p.NewSubscriptionStore(config.Config{})
+	a.Nil(err)
+	_, err = subs.Subscribe("cid", &gmqtt.Subscription{TopicFilter: "a"})
+	a.Nil(err)
+	rt, err := s.p.NewRetainedStore(config.Config{})
+	a.Nil(err)
+	rt.AddOrReplace(&gmqtt.Message{Topic: "a", Payload: []byte{1}})
+	ua, err := s.p.NewUnackStore(config.Config{}, "cid")
+	a.Nil(err)
+	_, err = ua.Set(1)
+	a.Nil(err)
+
+	keys := s.s.Keys()
+	a.NotEmpty(keys)
+	for _, v := range keys {
+		a.True(strings.HasPrefix(v, "gmqtt:"), v)
+	}
+	a.Nil(qs.Add(&queue.Elem{
+		At: time.Now(),
+		MessageWithID: &queue.Publish{
+			Message: &gmqtt.Message{QoS: packets.Qos1, Topic: "a", Payload: []byte{1}},
+		},
+	}))
+	a.True(s.s.Exists("gmqtt:queue:{cid}"))
+}
+
+func TestRedisCluster(t *testing.T) {
+	suite.Run(t, &RedisClusterSuite{})
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
 	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -100,6 +102,17 @@ func (s *RedisSuite) TestUnack() {
 	unack_test.TestSuite(s.T(), st)
 }
 
+func (s *RedisSuite) TestRetained() {
+	retained_test.TestSuite(s.T(), func() retained.Store {
+		st, err := s.p.NewRetainedStore(config.Config{})
+		if err != nil {
+			panic(err)
+		}
+		st.ClearAll()
+		return st
+	})
+}
+
 func TestRedis(t *testing.T) {
 	suite.Run(t, &RedisSuite{})
 }
@@ -0,0 +1,503 @@
+package redisconn
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	slotCount = 16384
+	// maxRedirects is the maximum number of MOVED/ASK redirections for one command.
+	maxRedirects = 5
+	// scanNodeBits is the number of the low bits of the cluster scan cursor which store the node index.
+	scanNodeBits = 8
+)
+
+var errClusterClosed = errors.New("redisconn: cluster has been closed")
+
+// Slot returns the cluster hash slot of the key.
+// If the key contains a hash tag (a non-empty substring between the first "{" and the following "}"),
+// only the hash tag is hashed, so that keys with the same hash tag are stored in the same slot.
+func Slot(key string) int {
+	if s := strings.IndexByte(key, '{'); s != -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// cluster maintains the slot mapping and the connection pools of the cluster nodes.
+type cluster struct {
+	seeds   []string
+	newPool func(addr string) *redigo.Pool
+
+	mu     sync.RWMutex
+	slots  []string
+	pools  map[string]*redigo.Pool
+	closed bool
+}
+
+func newCluster(seeds []string, newPool func(addr string) *redigo.Pool) *cluster {
+	return &cluster{
+		seeds:   seeds,
+		newPool: newPool,
+		pools:   make(map[string]*redigo.Pool),
+	}
+}
+
+func (c *cluster) getPool(addr string) (*redigo.Pool, error) {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	closed := c.closed
+	c.mu.RUnlock()
+	if closed {
+		return nil, errClusterClosed
+	}
+	if ok {
+		return p, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok = c.pools[addr]; !ok {
+		p = c.newPool(addr)
+		c.pools[addr] = p
+	}
+	return p, nil
+}
+
+// refresh reloads the slot mapping by CLUSTER SLOTS, the known nodes are tried before the seeds.
+func (c *cluster) refresh() error {
+	c.mu.RLock()
+	addrs := make([]string, 0, len(c.pools)+len(c.seeds))
+	for k := range c.pools {
+		addrs = append(addrs, k)
+	}
+	c.mu.RUnlock()
+	sort.Strings(addrs)
+	addrs = append(addrs, c.seeds...)
+	var lastErr error
+	for _, addr := range addrs {
+		slots, err := c.fetchSlots(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("redisconn: fail to load cluster slots, last error: %v", lastErr)
+}
+
+func (c *cluster) fetchSlots(addr string) ([]string, error) {
+	p, err := c.getPool(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := p.Get()
+	defer conn.Close()
+	rs, err := redigo.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+	slots := make([]string, slotCount)
+	for _, v := range rs {
+		// [start, end, [ip, port, id], replicas...]
+		r, err := redigo.Values(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(r) < 3 {
+			return nil, errors.New("redisconn: invalid CLUSTER SLOTS reply")
+		}
+		start, err := redigo.Int(r[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		end, err := redigo.Int(r[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		node, err := redigo.Values(r[2], nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(node) < 2 || start < 0 || end >= slotCount || start > end {
+			return nil, errors.New("redisconn: invalid CLUSTER SLOTS reply")
+		}
+		host, err := redigo.String(node[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		port, err := redigo.Int(node[1], nil)
+		if err != nil {
+			return nil, err
+		}
+		// an empty host means the same host as the one we queried.
+		if host == "" {
+			host, _, _ = net.SplitHostPort(addr)
+		}
+		master := net.JoinHostPort(host, strconv.Itoa(port))
+		for i := start; i <= end; i++ {
+			slots[i] = master
+		}
+	}
+	return slots, nil
+}
+
+func (c *cluster) addrBySlot(slot int) (string, error) {
+	c.mu.RLock()
+	loaded := c.slots != nil
+	var addr string
+	if loaded {
+		addr = c.slots[slot]
+	}
+	c.mu.RUnlock()
+	if loaded && addr != "" {
+		return addr, nil
+	}
+	if err := c.refresh(); err != nil {
+		return "", err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if addr = c.slots[slot]; addr == "" {
+		return "", fmt.Errorf("redisconn: slot %d is not served by any node", slot)
+	}
+	return addr, nil
+}
+
+// masters returns the sorted addresses of the master nodes.
+func (c *cluster) masters() ([]string, error) {
+	c.mu.RLock()
+	loaded := c.slots != nil
+	c.mu.RUnlock()
+	if !loaded {
+		if err := c.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	m := make(map[string]struct{})
+	for _, v := range c.slots {
+		if v != "" {
+			m[v] = struct{}{}
+		}
+	}
+	rs := make([]string, 0, len(m))
+	for k := range m {
+		rs = append(rs, k)
+	}
+	sort.Strings(rs)
+	return rs, nil
+}
+
+func (c *cluster) doNode(addr string, asking bool, cmd string, args []interface{}) (interface{}, error) {
+	p, err := c.getPool(addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := p.Get()
+	defer conn.Close()
+	if asking {
+		if _, err = conn.Do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+	return conn.Do(cmd, args...)
+}
+
+// parseRedirect parses the MOVED and ASK errors, e.g: "MOVED 3999 127.0.0.1:6381".
+func parseRedirect(err error) (addr string, ask bool, ok bool) {
+	e, isRedisErr := err.(redigo.Error)
+	if !isRedisErr {
+		return "", false, false
+	}
+	fields := strings.Fields(string(e))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", false, false
+	}
+	return fields[2], fields[0] == "ASK", true
+}
+
+func isRetryable(err error) bool {
+	e, ok := err.(redigo.Error)
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(string(e), "TRYAGAIN") || strings.HasPrefix(string(e), "CLUSTERDOWN")
+}
+
+// doKey executes the command on the node which serves the key, following the redirections.
+func (c *cluster) doKey(key string, cmd string, args []interface{}) (reply interface{}, err error) {
+	slot := Slot(key)
+	addr, err := c.addrBySlot(slot)
+	if err != nil {
+		return nil, err
+	}
+	var asking bool
+	for i := 0; i <= maxRedirects; i++ {
+		reply, err = c.doNode(addr, asking, cmd, args)
+		if err == nil {
+			return reply, nil
+		}
+		if redirect, ask, ok := parseRedirect(err); ok {
+			addr, asking = redirect, ask
+			if !ask {
+				// the slot has been migrated, update the mapping.
+				c.mu.Lock()
+				if c.slots != nil {
+					c.slots[slot] = addr
+				}
+				c.mu.Unlock()
+			}
+			continue
+		}
+		if isRetryable(err) {
+			time.Sleep(100 * time.Millisecond)
+			asking = false
+			continue
+		}
+		return reply, err
+	}
+	return reply, err
+}
+
+// scan implements the SCAN command across all master nodes.
+// The cursor returned to the caller is composed of the node cursor and the node index:
+// cursor = nodeCursor << scanNodeBits | nodeIndex.
+func (c *cluster) scan(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("redisconn: wrong number of arguments for SCAN")
+	}
+	cursor, err := redigo.Uint64(args[0], nil)
+	if err != nil {
+		cursor, err = strconv.ParseUint(fmt.Sprint(args[0]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	masters, err := c.masters()
+	if err != nil {
+		return nil, err
+	}
+	if len(masters) >= 1<<scanNodeBits {
+		return nil, errors.New("redisconn: too many master nodes to scan")
+	}
+	idx := int(cursor & (1<<scanNodeBits - 1))
+	nodeCursor := cursor >> scanNodeBits
+	if idx >= len(masters) {
+		return []interface{}{[]byte("0"), []interface{}{}}, nil
+	}
+	nodeArgs := append([]interface{}{nodeCursor}, args[1:]...)
+	rs, err := redigo.Values(c.doNode(masters[idx], false, "SCAN", nodeArgs))
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) != 2 {
+		return nil, errors.New("redisconn: invalid SCAN reply")
+	}
+	next, err := redigo.Uint64(rs[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	if next == 0 {
+		// move to the next node
+		idx++
+		if idx >= len(masters) {
+			return []interface{}{[]byte("0"), rs[1]}, nil
+		}
+	}
+	next = next<<scanNodeBits | uint64(idx)
+	return []interface{}{[]byte(strconv.FormatUint(next, 10)), rs[1]}, nil
+}
+
+// exec executes the command in the cluster.
+// Multi-key commands are split into single key commands, so the keys are not required to be in the same slot.
+func (c *cluster) exec(cmd string, args []interface{}) (interface{}, error) {
+	switch strings.ToUpper(cmd) {
+	case "SCAN":
+		return c.scan(args)
+	case "PING":
+		masters, err := c.masters()
+		if err != nil {
+			return nil, err
+		}
+		if len(masters) == 0 {
+			return nil, errors.New("redisconn: no master node available")
+		}
+		return c.doNode(masters[0], false, cmd, args)
+	case "MGET":
+		rs := make([]interface{}, len(args))
+		for i, v := range args {
+			r, err := c.doKey(keyString(v), "GET", []interface{}{v})
+			if err != nil {
+				return nil, err
+			}
+			rs[i] = r
+		}
+		return rs, nil
+	case "DEL", "UNLINK", "EXISTS":
+		if len(args) > 1 {
+			var n int64
+			for _, v := range args {
+				r, err := redigo.Int64(c.doKey(keyString(v), cmd, []interface{}{v}))
+				if err != nil {
+					return nil, err
+				}
+				n += r
+			}
+			return n, nil
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("redisconn: command %s is not supported in cluster mode", cmd)
+	}
+	return c.doKey(keyString(args[0]), cmd, args)
+}
+
+func (c *cluster) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var err error
+	for _, p := range c.pools {
+		if e := p.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func keyString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type command struct {
+	name string
+	args []interface{}
+}
+
+type result struct {
+	reply interface{}
+	err   error
+}
+
+// clusterConn implements redigo.Conn for redis cluster.
+// Each command is routed to the node which serves the key, pipelined commands are executed in order on Flush.
+type clusterConn struct {
+	cluster *cluster
+	pending []command
+	results []result
+}
+
+var _ redigo.Conn = (*clusterConn)(nil)
+
+func (c *clusterConn) Close() error {
+	// like redigo, the pending commands are executed before the connection is released.
+	_, err := c.Do("")
+	return err
+}
+
+func (c *clusterConn) Err() error {
+	return nil
+}
+
+func (c *clusterConn) Do(commandName string, args ...interface{}) (reply interface{}, err error) {
+	if err = c.Flush(); err != nil {
+		c.results = nil
+		return nil, err
+	}
+	results := c.results
+	c.results = nil
+	if commandName == "" {
+		if len(results) == 0 {
+			return nil, nil
+		}
+		rs := make([]interface{}, len(results))
+		for i, v := range results {
+			if v.err != nil {
+				rs[i] = v.err
+			} else {
+				rs[i] = v.reply
+			}
+		}
+		return rs, nil
+	}
+	for _, v := range results {
+		if v.err != nil && err == nil {
+			err = v.err
+		}
+	}
+	reply, e := c.cluster.exec(commandName, args)
+	if e != nil {
+		return reply, e
+	}
+	return reply, err
+}
+
+func (c *clusterConn) Send(commandName string, args ...interface{}) error {
+	c.pending = append(c.pending, command{name: commandName, args: args})
+	return nil
+}
+
+// Flush executes the pending commands.
+// Like redigo, redis error replies are returned by Receive, only the connection errors are returned by Flush.
+func (c *clusterConn) Flush() (err error) {
+	for _, v := range c.pending {
+		reply, e := c.cluster.exec(v.name, v.args)
+		c.results = append(c.results, result{reply: reply, err: e})
+		if _, ok := e.(redigo.Error); e != nil && !ok && err == nil {
+			err = e
+		}
+	}
+	c.pending = nil
+	return err
+}
+
+func (c *clusterConn) Receive() (reply interface{}, err error) {
+	if len(c.results) == 0 {
+		// the error of the flush is also stored in the results.
+		_ = c.Flush()
+		if len(c.results) == 0 {
+			return nil, errors.New("redisconn: no pending reply")
+		}
+	}
+	r := c.results[0]
+	c.results = c.results[1:]
+	return r.reply, r.err
+}
@@ -0,0 +1,176 @@
+// Package redisconn creates redis connection pools for the redis persistence.
+// It supports standalone, sentinel and cluster deployments, TLS and ACL authentication.
+package redisconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// roleCheckInterval is the minimum idle time before checking the role of a sentinel-resolved connection.
+const roleCheckInterval = time.Second
+
+// Client holds the connection pool.
+type Client struct {
+	// Pool is the connection pool used by the redis stores.
+	// In cluster mode, connections returned by the pool route commands to the corresponding cluster nodes.
+	Pool    *redigo.Pool
+	cluster *cluster
+}
+
+// Close closes the pool and all underlying connections.
+func (c *Client) Close() error {
+	err := c.Pool.Close()
+	if c.cluster != nil {
+		if e := c.cluster.close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// New returns the Client of the given configuration.
+func New(cfg config.RedisPersistence) (*Client, error) {
+	opts, err := dialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	newPool := func(dial func() (redigo.Conn, error)) *redigo.Pool {
+		p := &redigo.Pool{
+			Dial:        dial,
+			IdleTimeout: cfg.IdleTimeout,
+		}
+		if cfg.MaxIdle != nil {
+			p.MaxIdle = int(*cfg.MaxIdle)
+		}
+		if cfg.MaxActive != nil {
+			p.MaxActive = int(*cfg.MaxActive)
+		}
+		return p
+	}
+	switch cfg.Mode {
+	case config.RedisModeSentinel:
+		sentinelOpts, err := dialOptions(config.RedisPersistence{
+			Password: cfg.SentinelPassword,
+			TLS:      cfg.TLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s := &sentinel{
+			// masterAddr reorders the addresses, copy it to avoid modifying the config.
+			addrs:      append([]string(nil), cfg.Addrs...),
+			masterName: cfg.MasterName,
+			opts:       sentinelOpts,
+		}
+		dbOpts := withDatabase(opts, cfg.Database)
+		p := newPool(func() (redigo.Conn, error) {
+			return s.dialMaster(dbOpts...)
+		})
+		// detect failover for the idle connections
+		p.TestOnBorrow = func(c redigo.Conn, t time.Time) error {
+			if time.Since(t) < roleCheckInterval {
+				return nil
+			}
+			return checkRole(c)
+		}
+		return &Client{Pool: p}, nil
+	case config.RedisModeCluster:
+		cl := newCluster(cfg.Addrs, func(addr string) *redigo.Pool {
+			return newPool(func() (redigo.Conn, error) {
+				return redigo.Dial("tcp", addr, opts...)
+			})
+		})
+		p := &redigo.Pool{
+			Dial: func() (redigo.Conn, error) {
+				return &clusterConn{cluster: cl}, nil
+			},
+		}
+		return &Client{Pool: p, cluster: cl}, nil
+	default:
+		dbOpts := withDatabase(opts, cfg.Database)
+		p := newPool(func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", cfg.Addr, dbOpts...)
+		})
+		return &Client{Pool: p}, nil
+	}
+}
+
+func dialOptions(cfg config.RedisPersistence) ([]redigo.DialOption, error) {
+	var opts []redigo.DialOption
+	if cfg.Username != "" {
+		opts = append(opts, redigo.DialUsername(cfg.Username))
+	}
+	if cfg.Password != "" {
+		opts = append(opts, redigo.DialPassword(cfg.Password))
+	}
+	if cfg.TLS != nil {
+		tlsCfg, err := buildTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, redigo.DialUseTLS(true), redigo.DialTLSConfig(tlsCfg))
+	}
+	return opts, nil
+}
+
+// withDatabase returns a new slice of the dial options which selects the database.
+// The options must not be appended in the dial function, which is called concurrently.
+func withDatabase(opts []redigo.DialOption, db uint) []redigo.DialOption {
+	rs := make([]redigo.DialOption, 0, len(opts)+1)
+	rs = append(rs, opts...)
+	return append(rs, redigo.DialDatabase(int(db)))
+}
+
+func buildTLSConfig(cfg *config.RedisTLSOptions) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CACert != "" {
+		b, err := ioutil.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("invalid redis ca cert: %s", cfg.CACert)
+		}
+		tlsCfg.RootCAs = certPool
+	}
+	if cfg.Cert != "" {
+		c, err := tls.LoadX509KeyPair(cfg.Cert, cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{c}
+	}
+	return tlsCfg, nil
+}
+
+// checkRole returns an error if the connection is not connected to a master.
+func checkRole(c redigo.Conn) error {
+	rs, err := redigo.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(rs) == 0 {
+		return errors.New("redisconn: invalid ROLE reply")
+	}
+	role, err := redigo.String(rs[0], nil)
+	if err != nil {
+		return err
+	}
+	if role != "master" {
+		return fmt.Errorf("redisconn: unexpected role: %s", role)
+	}
+	return nil
+}
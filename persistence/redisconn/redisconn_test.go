@@ -0,0 +1,251 @@
+package redisconn
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func runMiniredis(t *testing.T) *miniredis.Miniredis {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSlot(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(12739, Slot("123456789"))
+	a.Equal(Slot("{user1000}.following"), Slot("{user1000}.followers"))
+	a.Equal(Slot("user1000"), Slot("queue:{user1000}"))
+	// empty hash tag, the whole key is hashed.
+	a.Equal(int(crc16("foo{}{bar}")%slotCount), Slot("foo{}{bar}"))
+	a.Equal(Slot("{bar"), Slot("foo{{bar}}zap"))
+}
+
+func TestNew_Standalone(t *testing.T) {
+	a := assert.New(t)
+	s := runMiniredis(t)
+	s.RequireUserAuth("gmqtt", "pass")
+
+	c, err := New(config.RedisPersistence{
+		Addr:     s.Addr(),
+		Username: "gmqtt",
+		Password: "pass",
+		Database: 1,
+	})
+	a.Nil(err)
+	defer c.Close()
+	conn := c.Pool.Get()
+	_, err = conn.Do("SET", "k", "v")
+	a.Nil(err)
+	conn.Close()
+	s.Select(1)
+	v, err := s.Get("k")
+	a.Nil(err)
+	a.Equal("v", v)
+
+	c, err = New(config.RedisPersistence{
+		Addr:     s.Addr(),
+		Username: "gmqtt",
+		Password: "wrong",
+	})
+	a.Nil(err)
+	defer c.Close()
+	conn = c.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PING")
+	a.NotNil(err)
+}
+
+func TestNew_Sentinel(t *testing.T) {
+	a := assert.New(t)
+	master := runMiniredis(t)
+	a.Nil(master.Server().Register("ROLE", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(1)
+		c.WriteBulk("master")
+	}))
+	st := runMiniredis(t)
+	host, port, _ := net.SplitHostPort(master.Addr())
+	a.Nil(st.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) != 2 || args[1] != "mymaster" {
+			c.WriteNull()
+			return
+		}
+		c.WriteStrings([]string{host, port})
+	}))
+	addrs := []string{"127.0.0.1:1", st.Addr()}
+	c, err := New(config.RedisPersistence{
+		Mode: config.RedisModeSentinel,
+		// the first sentinel is unavailable
+		Addrs:      addrs,
+		MasterName: "mymaster",
+	})
+	a.Nil(err)
+	defer c.Close()
+	conn := c.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("SET", "k", "v")
+	a.Nil(err)
+	v, err := master.Get("k")
+	a.Nil(err)
+	a.Equal("v", v)
+	// the addresses in the config are not reordered.
+	a.Equal([]string{"127.0.0.1:1", st.Addr()}, addrs)
+
+	// the master is not found
+	c, err = New(config.RedisPersistence{
+		Mode:       config.RedisModeSentinel,
+		Addrs:      []string{st.Addr()},
+		MasterName: "unknown",
+	})
+	a.Nil(err)
+	defer c.Close()
+	conn = c.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PING")
+	a.NotNil(err)
+}
+
+func newClusterClient(t *testing.T, seeds ...string) *Client {
+	c, err := New(config.RedisPersistence{
+		Mode:  config.RedisModeCluster,
+		Addrs: seeds,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCluster(t *testing.T) {
+	a := assert.New(t)
+	s := runMiniredis(t)
+	c := newClusterClient(t, s.Addr())
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PING")
+	a.Nil(err)
+	for i := 0; i < 10; i++ {
+		_, err = conn.Do("SET", "k"+strconv.Itoa(i), i)
+		a.Nil(err)
+	}
+	vs, err := redigo.Strings(conn.Do("MGET", "k1", "k2", "nil"))
+	a.Nil(err)
+	a.Equal([]string{"1", "2", ""}, vs)
+
+	// pipeline
+	a.Nil(conn.Send("RPUSH", "l", "a"))
+	a.Nil(conn.Send("LPOP", "not-a-list-k1"))
+	a.Nil(conn.Send("SET", "k1", "v1"))
+	a.Nil(conn.Send("GET", "k1"))
+	a.Nil(conn.Flush())
+	n, err := redigo.Int(conn.Receive())
+	a.Nil(err)
+	a.Equal(1, n)
+	_, err = conn.Receive()
+	a.Nil(err)
+	_, err = conn.Receive()
+	a.Nil(err)
+	v, err := redigo.String(conn.Receive())
+	a.Nil(err)
+	a.Equal("v1", v)
+
+	// scan
+	var keys []string
+	iter := 0
+	for {
+		arr, err := redigo.Values(conn.Do("SCAN", iter, "MATCH", "k*", "COUNT", 3))
+		a.Nil(err)
+		ks, err := redigo.Strings(arr[1], nil)
+		a.Nil(err)
+		keys = append(keys, ks...)
+		iter, _ = redigo.Int(arr[0], nil)
+		if iter == 0 {
+			break
+		}
+	}
+	a.Len(keys, 10)
+
+	n, err = redigo.Int(conn.Do("DEL", "k1", "k2", "nil"))
+	a.Nil(err)
+	a.Equal(2, n)
+}
+
+// fakeNode is a cluster node which owns all slots and redirects all GET commands.
+func fakeNode(t *testing.T, redirect func(key string) string) (*server.Server, *int32) {
+	srv, err := server.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	var hits int32
+	_ = srv.Register("CLUSTER", func(c *server.Peer, cmd string, args []string) {
+		c.WriteLen(1)
+		c.WriteLen(3)
+		c.WriteInt(0)
+		c.WriteInt(slotCount - 1)
+		c.WriteLen(2)
+		c.WriteBulk(srv.Addr().IP.String())
+		c.WriteInt(srv.Addr().Port)
+	})
+	_ = srv.Register("GET", func(c *server.Peer, cmd string, args []string) {
+		atomic.AddInt32(&hits, 1)
+		c.WriteError(redirect(args[0]))
+	})
+	return srv, &hits
+}
+
+func TestCluster_Redirect(t *testing.T) {
+	a := assert.New(t)
+	target := runMiniredis(t)
+	var asking int32
+	a.Nil(target.Server().Register("ASKING", func(c *server.Peer, cmd string, args []string) {
+		atomic.AddInt32(&asking, 1)
+		c.WriteOK()
+	}))
+	a.Nil(target.Set("moved", "1"))
+	a.Nil(target.Set("ask", "2"))
+
+	srv, hits := fakeNode(t, func(key string) string {
+		if key == "ask" {
+			return fmt.Sprintf("ASK %d %s", Slot(key), target.Addr())
+		}
+		return fmt.Sprintf("MOVED %d %s", Slot(key), target.Addr())
+	})
+	c := newClusterClient(t, srv.Addr().String())
+	conn := c.Pool.Get()
+	defer conn.Close()
+
+	v, err := redigo.String(conn.Do("GET", "moved"))
+	a.Nil(err)
+	a.Equal("1", v)
+	a.EqualValues(1, atomic.LoadInt32(hits))
+	// the slot mapping has been updated
+	v, err = redigo.String(conn.Do("GET", "moved"))
+	a.Nil(err)
+	a.Equal("1", v)
+	a.EqualValues(1, atomic.LoadInt32(hits))
+
+	// ASK does not update the slot mapping
+	for i := 0; i < 2; i++ {
+		v, err = redigo.String(conn.Do("GET", "ask"))
+		a.Nil(err)
+		a.Equal("2", v)
+	}
+	a.EqualValues(3, atomic.LoadInt32(hits))
+	a.EqualValues(2, atomic.LoadInt32(&asking))
+}
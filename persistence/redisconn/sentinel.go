@@ -0,0 +1,71 @@
+package redisconn
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// sentinel resolves the master address from the sentinels.
+type sentinel struct {
+	mu         sync.Mutex
+	addrs      []string
+	masterName string
+	// opts is the dial options of the sentinels.
+	opts []redigo.DialOption
+}
+
+// masterAddr asks the sentinels for the master address.
+// The sentinel that answered is moved to the front so that it will be asked first next time.
+func (s *sentinel) masterAddr() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var lastErr error
+	for i, addr := range s.addrs {
+		master, err := s.queryMaster(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if i != 0 {
+			s.addrs[0], s.addrs[i] = s.addrs[i], s.addrs[0]
+		}
+		return master, nil
+	}
+	return "", fmt.Errorf("redisconn: no sentinel available, last error: %v", lastErr)
+}
+
+func (s *sentinel) queryMaster(addr string) (string, error) {
+	c, err := redigo.Dial("tcp", addr, s.opts...)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+	rs, err := redigo.Strings(c.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+	if err != nil {
+		return "", err
+	}
+	if len(rs) != 2 {
+		return "", fmt.Errorf("redisconn: master %s not found in sentinel %s", s.masterName, addr)
+	}
+	return net.JoinHostPort(rs[0], rs[1]), nil
+}
+
+// dialMaster dials the current master and verifies its role.
+func (s *sentinel) dialMaster(opts ...redigo.DialOption) (redigo.Conn, error) {
+	addr, err := s.masterAddr()
+	if err != nil {
+		return nil, err
+	}
+	c, err := redigo.Dial("tcp", addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkRole(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
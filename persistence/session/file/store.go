@@ -0,0 +1,76 @@
+package file
+
+import (
+	"bytes"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/persistence/session"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+)
+
+const (
+	sessBucket = "session"
+)
+
+var _ session.Store = (*Store)(nil)
+
+type Store struct {
+	db *aof.DB
+}
+
+func New(db *aof.DB) *Store {
+	return &Store{
+		db: db,
+	}
+}
+
+func (s *Store) Set(session *gmqtt.Session) error {
+	b := &bytes.Buffer{}
+	encoding.EncodeSession(session, b)
+	return s.db.Put(sessBucket, session.ClientID, b.Bytes())
+}
+
+func (s *Store) Remove(clientID string) error {
+	return s.db.Delete(sessBucket, clientID)
+}
+
+func (s *Store) Get(clientID string) (*gmqtt.Session, error) {
+	b, ok := s.db.Get(sessBucket, clientID)
+	if !ok {
+		return nil, nil
+	}
+	return encoding.DecodeSession(bytes.NewBuffer(b))
+}
+
+func (s *Store) SetSessionExpiry(clientID string, expiry uint32) error {
+	sess, err := s.Get(clientID)
+	if err != nil || sess == nil {
+		return err
+	}
+	sess.ExpiryInterval = expiry
+	return s.Set(sess)
+}
+
+func (s *Store) Iterate(fn session.IterateFn) error {
+	var sess []*gmqtt.Session
+	var err error
+	s.db.ForEach(sessBucket, func(key string, value []byte) bool {
+		var v *gmqtt.Session
+		v, err = encoding.DecodeSession(bytes.NewBuffer(value))
+		if err != nil {
+			return false
+		}
+		sess = append(sess, v)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range sess {
+		if !fn(v) {
+			return nil
+		}
+	}
+	return nil
+}
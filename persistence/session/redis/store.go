@@ -21,17 +21,27 @@ var _ session.Store = (*Store)(nil)
 type Store struct {
 	mu   sync.Mutex
 	pool *redis.Pool
+	// keyPrefix is prepended to all keys.
+	keyPrefix string
+	// hashTag wraps the client id in the key with braces (e.g: session:{clientID}),
+	// so that all keys of a client are stored in the same slot of redis cluster.
+	hashTag bool
 }
 
-func New(pool *redis.Pool) *Store {
+func New(pool *redis.Pool, keyPrefix string, hashTag bool) *Store {
 	return &Store{
-		mu:   sync.Mutex{},
-		pool: pool,
+		mu:        sync.Mutex{},
+		pool:      pool,
+		keyPrefix: keyPrefix,
+		hashTag:   hashTag,
 	}
 }
 
-func getKey(clientID string) string {
-	return sessPrefix + clientID
+func (s *Store) getKey(clientID string) string {
+	if s.hashTag {
+		return s.keyPrefix + sessPrefix + "{" + clientID + "}"
+	}
+	return s.keyPrefix + sessPrefix + clientID
 }
 func (s *Store) Set(session *gmqtt.Session) error {
 	s.mu.Lock()
@@ -40,7 +50,7 @@ func (s *Store) Set(session *gmqtt.Session) error {
 	defer c.Close()
 	b := &bytes.Buffer{}
 	encoding.EncodeMessage(session.Will, b)
-	_, err := c.Do("hset", getKey(session.ClientID),
+	_, err := c.Do("hset", s.getKey(session.ClientID),
 		"client_id", session.ClientID,
 		"will", b.Bytes(),
 		"will_delay_interval", session.WillDelayInterval,
@@ -55,7 +65,7 @@ func (s *Store) Remove(clientID string) error {
 	defer s.mu.Unlock()
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("del", getKey(clientID))
+	_, err := c.Do("del", s.getKey(clientID))
 	return err
 }
 
@@ -64,7 +74,7 @@ func (s *Store) Get(clientID string) (*gmqtt.Session, error) {
 	defer s.mu.Unlock()
 	c := s.pool.Get()
 	defer c.Close()
-	return getSessionLocked(getKey(clientID), c)
+	return getSessionLocked(s.getKey(clientID), c)
 }
 
 func getSessionLocked(key string, c redis.Conn) (*gmqtt.Session, error) {
@@ -92,7 +102,7 @@ func (s *Store) SetSessionExpiry(clientID string, expiry uint32) error {
 	defer s.mu.Unlock()
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("hset", getKey(clientID),
+	_, err := c.Do("hset", s.getKey(clientID),
 		"expiry_interval", expiry,
 	)
 	return err
@@ -105,7 +115,7 @@ func (s *Store) Iterate(fn session.IterateFn) error {
 	defer c.Close()
 	iter := 0
 	for {
-		arr, err := redis.Values(c.Do("SCAN", iter, "MATCH", sessPrefix+"*"))
+		arr, err := redis.Values(c.Do("SCAN", iter, "MATCH", s.keyPrefix+sessPrefix+"*"))
 		if err != nil {
 			return err
 		}
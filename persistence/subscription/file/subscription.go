@@ -0,0 +1,115 @@
+package file
+
+import (
+	"sync"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+)
+
+const (
+	subPrefix = "sub:"
+)
+
+var _ subscription.Store = (*sub)(nil)
+
+func New(db *aof.DB) *sub {
+	return &sub{
+		mu:       &sync.Mutex{},
+		memStore: mem.NewStore(),
+		db:       db,
+	}
+}
+
+type sub struct {
+	mu       *sync.Mutex
+	memStore *mem.TrieDB
+	db       *aof.DB
+}
+
+// Init loads the subscriptions of given clientIDs from the db into memory.
+func (s *sub) Init(clientIDs []string) error {
+	if len(clientIDs) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for _, v := range clientIDs {
+		var subs []*gmqtt.Subscription
+		s.db.ForEach(subPrefix+v, func(key string, value []byte) bool {
+			var sub *gmqtt.Subscription
+			sub, err = encoding.DecodeSubscription(value)
+			if err != nil {
+				return false
+			}
+			subs = append(subs, sub)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		s.memStore.SubscribeLocked(v, subs...)
+	}
+	return nil
+}
+
+func (s *sub) Close() error {
+	return s.memStore.Close()
+}
+
+func (s *sub) Subscribe(clientID string, subscriptions ...*gmqtt.Subscription) (rs subscription.SubscribeResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range subscriptions {
+		err = s.db.Put(subPrefix+clientID, subscription.GetFullTopicName(v.ShareName, v.TopicFilter), encoding.EncodeSubscription(v))
+		if err != nil {
+			return nil, err
+		}
+	}
+	rs = s.memStore.SubscribeLocked(clientID, subscriptions...)
+	return rs, nil
+}
+
+func (s *sub) Unsubscribe(clientID string, topics ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Delete(subPrefix+clientID, topics...)
+	if err != nil {
+		return err
+	}
+	s.memStore.UnsubscribeLocked(clientID, topics...)
+	return nil
+}
+
+func (s *sub) UnsubscribeAll(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.DeleteBucket(subPrefix + clientID)
+	if err != nil {
+		return err
+	}
+	s.memStore.UnsubscribeAllLocked(clientID)
+	return nil
+}
+
+func (s *sub) Iterate(fn subscription.IterateFn, options subscription.IterationOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memStore.IterateLocked(fn, options)
+}
+
+func (s *sub) GetStats() subscription.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memStore.GetStatusLocked()
+}
+
+func (s *sub) GetClientStats(clientID string) (subscription.Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memStore.GetClientStatsLocked(clientID)
+}
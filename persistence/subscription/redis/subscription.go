@@ -1,8 +1,6 @@
 package redis
 
 import (
-	"bytes"
-	"strings"
 	"sync"
 
 	redigo "github.com/gomodule/redigo/redis"
@@ -20,58 +18,20 @@ const (
 var _ subscription.Store = (*sub)(nil)
 
 func EncodeSubscription(sub *gmqtt.Subscription) []byte {
-	w := &bytes.Buffer{}
-	encoding.WriteString(w, []byte(sub.ShareName))
-	encoding.WriteString(w, []byte(sub.TopicFilter))
-	encoding.WriteUint32(w, sub.ID)
-	w.WriteByte(sub.QoS)
-	encoding.WriteBool(w, sub.NoLocal)
-	encoding.WriteBool(w, sub.RetainAsPublished)
-	w.WriteByte(sub.RetainHandling)
-	return w.Bytes()
+	return encoding.EncodeSubscription(sub)
 }
 
 func DecodeSubscription(b []byte) (*gmqtt.Subscription, error) {
-	sub := &gmqtt.Subscription{}
-	r := bytes.NewBuffer(b)
-	share, err := encoding.ReadString(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.ShareName = string(share)
-	topic, err := encoding.ReadString(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.TopicFilter = string(topic)
-	sub.ID, err = encoding.ReadUint32(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.QoS, err = r.ReadByte()
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.NoLocal, err = encoding.ReadBool(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.RetainAsPublished, err = encoding.ReadBool(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.RetainHandling, err = r.ReadByte()
-	if err != nil {
-		return nil, err
-	}
-	return sub, nil
+	return encoding.DecodeSubscription(b)
 }
 
-func New(pool *redigo.Pool) *sub {
+func New(pool *redigo.Pool, keyPrefix string, hashTag bool) *sub {
 	return &sub{
-		mu:       &sync.Mutex{},
-		memStore: mem.NewStore(),
-		pool:     pool,
+		mu:        &sync.Mutex{},
+		memStore:  mem.NewStore(),
+		pool:      pool,
+		keyPrefix: keyPrefix,
+		hashTag:   hashTag,
 	}
 }
 
@@ -79,6 +39,18 @@ type sub struct {
 	mu       *sync.Mutex
 	memStore *mem.TrieDB
 	pool     *redigo.Pool
+	// keyPrefix is prepended to all keys.
+	keyPrefix string
+	// hashTag wraps the client id in the key with braces (e.g: sub:{clientID}),
+	// so that all keys of a client are stored in the same slot of redis cluster.
+	hashTag bool
+}
+
+func (s *sub) getKey(clientID string) string {
+	if s.hashTag {
+		return s.keyPrefix + subPrefix + "{" + clientID + "}"
+	}
+	return s.keyPrefix + subPrefix + clientID
 }
 
 // Init loads the subscriptions of given clientIDs from backend into memory.
@@ -91,7 +63,7 @@ func (s *sub) Init(clientIDs []string) error {
 	c := s.pool.Get()
 	defer c.Close()
 	for _, v := range clientIDs {
-		rs, err := redigo.Values(c.Do("hgetall", subPrefix+v))
+		rs, err := redigo.Values(c.Do("hgetall", s.getKey(v)))
 		if err != nil {
 			return err
 		}
@@ -100,7 +72,7 @@ func (s *sub) Init(clientIDs []string) error {
 			if err != nil {
 				return err
 			}
-			s.memStore.SubscribeLocked(strings.TrimLeft(v, subPrefix), sub)
+			s.memStore.SubscribeLocked(v, sub)
 		}
 	}
 	return nil
@@ -118,7 +90,7 @@ func (s *sub) Subscribe(clientID string, subscriptions ...*gmqtt.Subscription) (
 	defer c.Close()
 	// hset sub:clientID topicFilter xxx
 	for _, v := range subscriptions {
-		err = c.Send("hset", subPrefix+clientID, subscription.GetFullTopicName(v.ShareName, v.TopicFilter), EncodeSubscription(v))
+		err = c.Send("hset", s.getKey(clientID), subscription.GetFullTopicName(v.ShareName, v.TopicFilter), EncodeSubscription(v))
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +108,7 @@ func (s *sub) Unsubscribe(clientID string, topics ...string) error {
 	defer s.mu.Unlock()
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("hdel", subPrefix+clientID, topics)
+	_, err := c.Do("hdel", redigo.Args{}.Add(s.getKey(clientID)).AddFlat(topics)...)
 	if err != nil {
 		return err
 	}
@@ -149,7 +121,7 @@ func (s *sub) UnsubscribeAll(clientID string) error {
 	defer s.mu.Unlock()
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("del", subPrefix+clientID)
+	_, err := c.Do("del", s.getKey(clientID))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,87 @@
+package file
+
+import (
+	"encoding/binary"
+
+	"github.com/DrmagicE/gmqtt/persistence/unack"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+const (
+	unackPrefix = "unack:"
+)
+
+var _ unack.Store = (*Store)(nil)
+
+type Store struct {
+	clientID     string
+	db           *aof.DB
+	unackpublish map[packets.PacketID]struct{}
+}
+
+type Options struct {
+	ClientID string
+	DB       *aof.DB
+}
+
+func New(opts Options) *Store {
+	s := &Store{
+		clientID:     opts.ClientID,
+		db:           opts.DB,
+		unackpublish: make(map[packets.PacketID]struct{}),
+	}
+	s.load()
+	return s
+}
+
+func getBucket(clientID string) string {
+	return unackPrefix + clientID
+}
+
+func getKey(id packets.PacketID) string {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, id)
+	return string(b)
+}
+
+// load loads the packet ids from the db.
+func (s *Store) load() {
+	s.unackpublish = make(map[packets.PacketID]struct{})
+	s.db.ForEach(getBucket(s.clientID), func(key string, value []byte) bool {
+		if len(key) == 2 {
+			s.unackpublish[binary.BigEndian.Uint16([]byte(key))] = struct{}{}
+		}
+		return true
+	})
+}
+
+func (s *Store) Init(cleanStart bool) error {
+	if cleanStart {
+		s.unackpublish = make(map[packets.PacketID]struct{})
+		return s.db.DeleteBucket(getBucket(s.clientID))
+	}
+	s.load()
+	return nil
+}
+
+func (s *Store) Set(id packets.PacketID) (bool, error) {
+	if _, ok := s.unackpublish[id]; ok {
+		return true, nil
+	}
+	err := s.db.Put(getBucket(s.clientID), getKey(id), nil)
+	if err != nil {
+		return false, err
+	}
+	s.unackpublish[id] = struct{}{}
+	return false, nil
+}
+
+func (s *Store) Remove(id packets.PacketID) error {
+	err := s.db.Delete(getBucket(s.clientID), getKey(id))
+	if err != nil {
+		return err
+	}
+	delete(s.unackpublish, id)
+	return nil
+}
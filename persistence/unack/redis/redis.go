@@ -16,31 +16,43 @@ var _ unack.Store = (*Store)(nil)
 type Store struct {
 	clientID     string
 	pool         *redis.Pool
+	keyPrefix    string
+	hashTag      bool
 	unackpublish map[packets.PacketID]struct{}
 }
 
 type Options struct {
 	ClientID string
 	Pool     *redis.Pool
+	// KeyPrefix is prepended to all keys.
+	KeyPrefix string
+	// HashTag wraps the client id in the key with braces (e.g: unack:{clientID}),
+	// so that all keys of a client are stored in the same slot of redis cluster.
+	HashTag bool
 }
 
 func New(opts Options) *Store {
 	return &Store{
 		clientID:     opts.ClientID,
 		pool:         opts.Pool,
+		keyPrefix:    opts.KeyPrefix,
+		hashTag:      opts.HashTag,
 		unackpublish: make(map[packets.PacketID]struct{}),
 	}
 }
 
-func getKey(clientID string) string {
-	return unackPrefix + clientID
+func (s *Store) getKey() string {
+	if s.hashTag {
+		return s.keyPrefix + unackPrefix + "{" + s.clientID + "}"
+	}
+	return s.keyPrefix + unackPrefix + s.clientID
 }
 func (s *Store) Init(cleanStart bool) error {
 	if cleanStart {
 		c := s.pool.Get()
 		defer c.Close()
 		s.unackpublish = make(map[packets.PacketID]struct{})
-		_, err := c.Do("del", getKey(s.clientID))
+		_, err := c.Do("del", s.getKey())
 		if err != nil {
 			return err
 		}
@@ -55,7 +67,7 @@ func (s *Store) Set(id packets.PacketID) (bool, error) {
 	}
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("hset", getKey(s.clientID), id, 1)
+	_, err := c.Do("hset", s.getKey(), id, 1)
 	if err != nil {
 		return false, err
 	}
@@ -66,7 +78,7 @@ func (s *Store) Set(id packets.PacketID) (bool, error) {
 func (s *Store) Remove(id packets.PacketID) error {
 	c := s.pool.Get()
 	defer c.Close()
-	_, err := c.Do("hdel", getKey(s.clientID), id)
+	_, err := c.Do("hdel", s.getKey(), id)
 	if err != nil {
 		return err
 	}
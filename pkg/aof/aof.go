@@ -0,0 +1,590 @@
+// Package aof provides a simple embedded key-value store which is backed by an append-only file.
+// All data is kept in memory, every modification is appended to the file and will be replayed on Open.
+// The file is rewritten (compacted) in the background when it grows too large compared to the live data.
+package aof
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy decides when the data is flushed to the disk.
+type FsyncPolicy = string
+
+const (
+	// FsyncAlways calls fsync after every write.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec calls fsync every second in the background.
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo never calls fsync, the operating system decides when to flush the data.
+	FsyncNo FsyncPolicy = "no"
+)
+
+const (
+	opPut byte = iota
+	opDelete
+	opDeleteBucket
+)
+
+// recordHeaderSize is the size of the record header. Format: 4 byte crc32 | 4 byte body length
+const recordHeaderSize = 8
+
+var (
+	// ErrClosed is returned when operating a closed DB.
+	ErrClosed = errors.New("aof: db has been closed")
+	// ErrInvalidFsyncPolicy is returned by Open if the fsync policy is unknown.
+	ErrInvalidFsyncPolicy = errors.New("aof: invalid fsync policy")
+	errCorrupted          = errors.New("aof: corrupted record")
+)
+
+// Options is the options of the DB.
+type Options struct {
+	// Fsync is the fsync policy, default to FsyncEverySec.
+	Fsync FsyncPolicy
+	// CompactionMinSize is the minimum file size in bytes to trigger an automatic compaction.
+	CompactionMinSize int64
+	// CompactionPercentage triggers an automatic compaction when the file grows by the given percentage
+	// compared with the size after the last compaction.
+	// Zero disables automatic compaction.
+	CompactionPercentage int
+	// OnCompactionError is called if the compaction fails.
+	// The compaction runs in the background, so the failed compaction does not fail the write
+	// and will be retried on the next write.
+	OnCompactionError func(err error)
+}
+
+// DB is a key-value store that groups keys into buckets.
+// It is safe for concurrent use.
+type DB struct {
+	mu      sync.RWMutex
+	path    string
+	opts    Options
+	f       *os.File
+	buckets map[string]map[string][]byte
+	// size is the current size of the file.
+	size int64
+	// baseSize is the size of the file after the last compaction.
+	baseSize int64
+	dirty    bool
+	closed   bool
+	// compaction is the running compaction, nil if there is none.
+	compaction *compaction
+	exit       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// compaction is a background rewrite of the file.
+type compaction struct {
+	// buf holds the records written after the snapshot was taken,
+	// they are appended to the new file before it replaces the old one.
+	buf  bytes.Buffer
+	done chan struct{}
+	err  error
+}
+
+// Open opens the DB file with the given path, the file will be created if it does not exist.
+// If the tail of the file is corrupted (e.g. partially written due to a crash), it will be truncated.
+func Open(path string, opts Options) (*DB, error) {
+	if opts.Fsync == "" {
+		opts.Fsync = FsyncEverySec
+	}
+	if opts.Fsync != FsyncAlways && opts.Fsync != FsyncEverySec && opts.Fsync != FsyncNo {
+		return nil, ErrInvalidFsyncPolicy
+	}
+	db := &DB{
+		path:    path,
+		opts:    opts,
+		buckets: make(map[string]map[string][]byte),
+		exit:    make(chan struct{}),
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	size, err := db.replay(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err = f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	db.f = f
+	db.size = size
+	db.baseSize = size
+	if opts.Fsync == FsyncEverySec {
+		db.wg.Add(1)
+		go db.syncLoop()
+	}
+	return db, nil
+}
+
+// replay loads all valid records from the file and returns the offset of the last valid record.
+func (db *DB) replay(f *os.File) (offset int64, err error) {
+	r := bufio.NewReader(f)
+	header := make([]byte, recordHeaderSize)
+	for {
+		_, err = io.ReadFull(r, header)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		_, err = io.ReadFull(r, body)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[0:4]) {
+			return offset, nil
+		}
+		if err = db.apply(body); err != nil {
+			return offset, nil
+		}
+		offset += int64(recordHeaderSize + len(body))
+	}
+}
+
+func writeBytes(b *bytes.Buffer, p []byte) {
+	l := make([]byte, 4)
+	binary.BigEndian.PutUint32(l, uint32(len(p)))
+	b.Write(l)
+	b.Write(p)
+}
+
+func readBytes(b *bytes.Buffer) ([]byte, error) {
+	if b.Len() < 4 {
+		return nil, errCorrupted
+	}
+	l := int(binary.BigEndian.Uint32(b.Next(4)))
+	if b.Len() < l {
+		return nil, errCorrupted
+	}
+	p := make([]byte, l)
+	copy(p, b.Next(l))
+	return p, nil
+}
+
+// encodeRecord encodes the operation into a record.
+// Format: header | 1 byte op | bucket | key | value
+func encodeRecord(b *bytes.Buffer, op byte, bucket, key string, value []byte) {
+	start := b.Len()
+	b.Write(make([]byte, recordHeaderSize))
+	b.WriteByte(op)
+	writeBytes(b, []byte(bucket))
+	if op != opDeleteBucket {
+		writeBytes(b, []byte(key))
+	}
+	if op == opPut {
+		writeBytes(b, value)
+	}
+	rs := b.Bytes()[start:]
+	body := rs[recordHeaderSize:]
+	binary.BigEndian.PutUint32(rs[0:4], crc32.ChecksumIEEE(body))
+	binary.BigEndian.PutUint32(rs[4:8], uint32(len(body)))
+}
+
+// apply applies the record body to the memory.
+func (db *DB) apply(body []byte) error {
+	b := bytes.NewBuffer(body)
+	op, err := b.ReadByte()
+	if err != nil {
+		return errCorrupted
+	}
+	bucket, err := readBytes(b)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case opPut:
+		key, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		value, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		db.putLocked(string(bucket), string(key), value)
+	case opDelete:
+		key, err := readBytes(b)
+		if err != nil {
+			return err
+		}
+		db.deleteLocked(string(bucket), string(key))
+	case opDeleteBucket:
+		delete(db.buckets, string(bucket))
+	default:
+		return errCorrupted
+	}
+	return nil
+}
+
+func (db *DB) putLocked(bucket, key string, value []byte) {
+	bkt := db.buckets[bucket]
+	if bkt == nil {
+		bkt = make(map[string][]byte)
+		db.buckets[bucket] = bkt
+	}
+	bkt[key] = value
+}
+
+func (db *DB) deleteLocked(bucket, key string) {
+	if bkt := db.buckets[bucket]; bkt != nil {
+		delete(bkt, key)
+		if len(bkt) == 0 {
+			delete(db.buckets, bucket)
+		}
+	}
+}
+
+func (db *DB) syncLoop() {
+	defer db.wg.Done()
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-db.exit:
+			return
+		case <-t.C:
+			db.mu.Lock()
+			if db.dirty && !db.closed {
+				if db.f.Sync() == nil {
+					db.dirty = false
+				}
+			}
+			db.mu.Unlock()
+		}
+	}
+}
+
+// appendLocked writes the records to the file and applies the fsync policy.
+func (db *DB) appendLocked(b []byte) error {
+	if db.closed {
+		return ErrClosed
+	}
+	n, err := db.f.Write(b)
+	db.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if db.compaction != nil {
+		db.compaction.buf.Write(b)
+	}
+	if db.opts.Fsync == FsyncAlways {
+		if err = db.f.Sync(); err != nil {
+			return err
+		}
+	} else {
+		db.dirty = true
+	}
+	return nil
+}
+
+// maybeCompactLocked starts a background compaction if needed.
+// It must be called after the written records have been applied to the memory,
+// since the compaction rewrites the file with a snapshot of the data in memory.
+func (db *DB) maybeCompactLocked() {
+	if db.compaction != nil || !db.needCompactLocked() {
+		return
+	}
+	db.startCompactionLocked()
+}
+
+func (db *DB) needCompactLocked() bool {
+	if db.opts.CompactionPercentage <= 0 || db.size < db.opts.CompactionMinSize {
+		return false
+	}
+	return db.size-db.baseSize >= db.baseSize*int64(db.opts.CompactionPercentage)/100
+}
+
+// Put sets the value for the key in the bucket.
+func (db *DB) Put(bucket, key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	v := make([]byte, len(value))
+	copy(v, value)
+	b := &bytes.Buffer{}
+	encodeRecord(b, opPut, bucket, key, v)
+	if err := db.appendLocked(b.Bytes()); err != nil {
+		return err
+	}
+	db.putLocked(bucket, key, v)
+	db.maybeCompactLocked()
+	return nil
+}
+
+// Delete removes the key from the bucket.
+func (db *DB) Delete(bucket string, keys ...string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	bkt := db.buckets[bucket]
+	b := &bytes.Buffer{}
+	for _, k := range keys {
+		if _, ok := bkt[k]; ok {
+			encodeRecord(b, opDelete, bucket, k, nil)
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	if err := db.appendLocked(b.Bytes()); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		db.deleteLocked(bucket, k)
+	}
+	db.maybeCompactLocked()
+	return nil
+}
+
+// DeleteBucket removes the bucket and all keys in it.
+func (db *DB) DeleteBucket(bucket string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.buckets[bucket]; !ok {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	encodeRecord(b, opDeleteBucket, bucket, "", nil)
+	if err := db.appendLocked(b.Bytes()); err != nil {
+		return err
+	}
+	delete(db.buckets, bucket)
+	db.maybeCompactLocked()
+	return nil
+}
+
+// Get returns the value of the key in the bucket.
+// The returned value must not be modified.
+func (db *DB) Get(bucket, key string) (value []byte, ok bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	value, ok = db.buckets[bucket][key]
+	return
+}
+
+// ForEach calls fn for each key in the bucket in lexicographical order of keys.
+// If fn returns false, the iteration will be stopped.
+// The value passed to fn must not be modified, and fn must not modify the DB.
+func (db *DB) ForEach(bucket string, fn func(key string, value []byte) bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	bkt := db.buckets[bucket]
+	keys := make([]string, 0, len(bkt))
+	for k := range bkt {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k, bkt[k]) {
+			return
+		}
+	}
+}
+
+// Buckets returns the names of buckets which have the given prefix.
+func (db *DB) Buckets(prefix string) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	var rs []string
+	for k := range db.buckets {
+		if strings.HasPrefix(k, prefix) {
+			rs = append(rs, k)
+		}
+	}
+	sort.Strings(rs)
+	return rs
+}
+
+// Size returns the current size of the file.
+func (db *DB) Size() int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.size
+}
+
+// Compact rewrites the file with the live data only.
+// It blocks until the compaction finishes, the DB can still be used by other goroutines in the meantime.
+func (db *DB) Compact() error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrClosed
+	}
+	c := db.compaction
+	if c == nil {
+		c = db.startCompactionLocked()
+	}
+	db.mu.Unlock()
+	<-c.done
+	return c.err
+}
+
+// waitCompaction waits for the running compaction to finish.
+func (db *DB) waitCompaction() {
+	db.mu.RLock()
+	c := db.compaction
+	db.mu.RUnlock()
+	if c != nil {
+		<-c.done
+	}
+}
+
+// startCompactionLocked takes a snapshot of the data and rewrites the file with it in the background.
+func (db *DB) startCompactionLocked() *compaction {
+	c := &compaction{
+		done: make(chan struct{}),
+	}
+	// The values are never modified once stored, so copying the maps is enough.
+	snapshot := make(map[string]map[string][]byte, len(db.buckets))
+	for bucket, bkt := range db.buckets {
+		m := make(map[string][]byte, len(bkt))
+		for k, v := range bkt {
+			m[k] = v
+		}
+		snapshot[bucket] = m
+	}
+	db.compaction = c
+	db.wg.Add(1)
+	go func() {
+		defer db.wg.Done()
+		c.err = db.compact(c, snapshot)
+		// the compaction is abandoned silently if the DB is closed.
+		if c.err != nil && c.err != ErrClosed && db.opts.OnCompactionError != nil {
+			db.opts.OnCompactionError(c.err)
+		}
+		close(c.done)
+	}()
+	return c
+}
+
+// compact writes the snapshot into a temporary file without holding the lock,
+// then appends the records written in the meantime and replaces the file under the lock.
+func (db *DB) compact(c *compaction, snapshot map[string]map[string][]byte) error {
+	tmpPath := db.path + ".rewrite"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	var size int64
+	if err == nil {
+		size, err = writeSnapshot(tmp, snapshot)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.compaction = nil
+	if err == nil && db.closed {
+		err = ErrClosed
+	}
+	if err == nil {
+		var n int
+		n, err = tmp.Write(c.buf.Bytes())
+		size += int64(n)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, db.path)
+	}
+	if err != nil {
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+		return err
+	}
+	db.f.Close()
+	db.f = tmp
+	db.size = size
+	db.baseSize = size
+	db.dirty = false
+	// make the rename durable.
+	return syncDir(filepath.Dir(db.path))
+}
+
+// writeSnapshot writes the snapshot into the file and flushes it to the disk.
+func writeSnapshot(f *os.File, snapshot map[string]map[string][]byte) (size int64, err error) {
+	w := bufio.NewWriter(f)
+	b := &bytes.Buffer{}
+	for bucket, bkt := range snapshot {
+		for k, v := range bkt {
+			b.Reset()
+			encodeRecord(b, opPut, bucket, k, v)
+			n, err := w.Write(b.Bytes())
+			size += int64(n)
+			if err != nil {
+				return size, err
+			}
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return size, err
+	}
+	return size, f.Sync()
+}
+
+// syncDir flushes the directory entries to the disk.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories can not be synced on Windows.
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Sync flushes the file to the disk.
+func (db *DB) Sync() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	db.dirty = false
+	return db.f.Sync()
+}
+
+// Close flushes and closes the file.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil
+	}
+	db.closed = true
+	close(db.exit)
+	err := db.f.Sync()
+	if cerr := db.f.Close(); err == nil {
+		err = cerr
+	}
+	db.mu.Unlock()
+	db.wg.Wait()
+	return err
+}
@@ -0,0 +1,212 @@
+package aof
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "aof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "test.aof")
+}
+
+func TestDB(t *testing.T) {
+	a := assert.New(t)
+	path := tempPath(t)
+	db, err := Open(path, Options{Fsync: FsyncAlways})
+	a.Nil(err)
+	a.Nil(db.Put("b1", "k1", []byte("v1")))
+	a.Nil(db.Put("b1", "k2", []byte("v2")))
+	a.Nil(db.Put("b2", "k1", []byte("v3")))
+	a.Nil(db.Put("c1", "k1", []byte("v4")))
+	a.Nil(db.Delete("b1", "k1"))
+	a.Nil(db.DeleteBucket("c1"))
+
+	v, ok := db.Get("b1", "k2")
+	a.True(ok)
+	a.Equal([]byte("v2"), v)
+	_, ok = db.Get("b1", "k1")
+	a.False(ok)
+	a.Equal([]string{"b1", "b2"}, db.Buckets("b"))
+	a.Nil(db.Close())
+	a.Equal(ErrClosed, db.Put("b1", "k1", nil))
+
+	// replay
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	defer db.Close()
+	v, ok = db.Get("b1", "k2")
+	a.True(ok)
+	a.Equal([]byte("v2"), v)
+	_, ok = db.Get("b1", "k1")
+	a.False(ok)
+	v, ok = db.Get("b2", "k1")
+	a.True(ok)
+	a.Equal([]byte("v3"), v)
+	a.Len(db.Buckets("c"), 0)
+}
+
+func TestDB_ForEach(t *testing.T) {
+	a := assert.New(t)
+	db, err := Open(tempPath(t), Options{Fsync: FsyncNo})
+	a.Nil(err)
+	defer db.Close()
+	for _, k := range []string{"c", "a", "b"} {
+		a.Nil(db.Put("bucket", k, []byte(k)))
+	}
+	var keys []string
+	db.ForEach("bucket", func(key string, value []byte) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	a.Equal([]string{"a", "b"}, keys)
+}
+
+func TestDB_TruncateCorruptedTail(t *testing.T) {
+	a := assert.New(t)
+	path := tempPath(t)
+	db, err := Open(path, Options{})
+	a.Nil(err)
+	a.Nil(db.Put("b", "k1", []byte("v1")))
+	a.Nil(db.Put("b", "k2", []byte("v2")))
+	size := db.Size()
+	a.Nil(db.Close())
+
+	// simulate a partial write
+	a.Nil(os.Truncate(path, size-1))
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	_, ok := db.Get("b", "k1")
+	a.True(ok)
+	_, ok = db.Get("b", "k2")
+	a.False(ok)
+	a.Nil(db.Put("b", "k3", []byte("v3")))
+	a.Nil(db.Close())
+
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	defer db.Close()
+	_, ok = db.Get("b", "k3")
+	a.True(ok)
+}
+
+func TestDB_Compact(t *testing.T) {
+	a := assert.New(t)
+	path := tempPath(t)
+	db, err := Open(path, Options{
+		Fsync:                FsyncEverySec,
+		CompactionMinSize:    1024,
+		CompactionPercentage: 100,
+	})
+	a.Nil(err)
+	for i := 0; i < 1000; i++ {
+		a.Nil(db.Put("b", "k", make([]byte, 10)))
+		db.waitCompaction()
+	}
+	// the file has been compacted automatically
+	a.True(db.Size() < 2048)
+
+	a.Nil(db.Put("b", "k2", []byte("v2")))
+	a.Nil(db.Delete("b", "k"))
+	a.Nil(db.Compact())
+	a.Nil(db.Close())
+
+	_, err = os.Stat(path + ".rewrite")
+	a.True(os.IsNotExist(err))
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	defer db.Close()
+	_, ok := db.Get("b", "k")
+	a.False(ok)
+	v, ok := db.Get("b", "k2")
+	a.True(ok)
+	a.Equal([]byte("v2"), v)
+}
+
+func TestDB_CompactionError(t *testing.T) {
+	a := assert.New(t)
+	path := tempPath(t)
+	var compactErr error
+	db, err := Open(path, Options{
+		CompactionMinSize:    1,
+		CompactionPercentage: 1,
+		OnCompactionError: func(err error) {
+			compactErr = err
+		},
+	})
+	a.Nil(err)
+	// the rewrite file can not be created.
+	a.Nil(os.Mkdir(path+".rewrite", 0700))
+	a.Nil(db.Put("b", "k1", []byte("v1")))
+	db.waitCompaction()
+	a.NotNil(compactErr)
+	v, ok := db.Get("b", "k1")
+	a.True(ok)
+	a.Equal([]byte("v1"), v)
+
+	// the compaction is retried on the next write.
+	a.Nil(os.Remove(path + ".rewrite"))
+	compactErr = nil
+	a.Nil(db.Put("b", "k2", []byte("v2")))
+	db.waitCompaction()
+	a.Nil(compactErr)
+	a.Nil(db.Close())
+
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	defer db.Close()
+	for _, k := range []string{"k1", "k2"} {
+		_, ok = db.Get("b", k)
+		a.True(ok, k)
+	}
+}
+
+func TestDB_WriteDuringCompaction(t *testing.T) {
+	a := assert.New(t)
+	path := tempPath(t)
+	db, err := Open(path, Options{Fsync: FsyncNo})
+	a.Nil(err)
+	for i := 0; i < 1000; i++ {
+		a.Nil(db.Put("b", strconv.Itoa(i), make([]byte, 100)))
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.Nil(db.Compact())
+	}()
+	// the records written during the compaction must be kept in the new file.
+	for i := 0; i < 1000; i++ {
+		a.Nil(db.Put("b2", strconv.Itoa(i), []byte("v")))
+		a.Nil(db.Delete("b", strconv.Itoa(i)))
+	}
+	wg.Wait()
+	db.waitCompaction()
+	a.Nil(db.Close())
+
+	db, err = Open(path, Options{})
+	a.Nil(err)
+	defer db.Close()
+	a.Equal([]string{"b2"}, db.Buckets(""))
+	n := 0
+	db.ForEach("b2", func(key string, value []byte) bool {
+		n++
+		return true
+	})
+	a.Equal(1000, n)
+}
+
+func TestOpen_InvalidFsyncPolicy(t *testing.T) {
+	_, err := Open(tempPath(t), Options{Fsync: "sometimes"})
+	assert.Equal(t, ErrInvalidFsyncPolicy, err)
+}
@@ -0,0 +1,215 @@
+// Package logfile provides a log file writer which rotates the file by size,
+// and removes the rotated files by age and count.
+package logfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the time format in the name of the rotated files.
+// The rotated file of gmqttd.log is gmqttd-2006-01-02T15-04-05.000.log.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+const megabyte = 1024 * 1024
+
+// Options is the rotation options.
+type Options struct {
+	// MaxSize is the maximum size in megabytes of the log file before it gets rotated. 0 means no rotation.
+	MaxSize int
+	// MaxAge is the maximum number of days to retain the rotated files. 0 means no limit.
+	MaxAge int
+	// MaxBackups is the maximum number of the rotated files to retain. 0 means no limit.
+	MaxBackups int
+}
+
+// Writer is an io.WriteCloser that writes to the log file and rotates it when the size exceeds the MaxSize.
+// It is safe for concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	filename string
+	opts     Options
+	file     *os.File
+	size     int64
+	now      func() time.Time
+}
+
+// New opens or creates the log file for appending, the parent directories are created if not exist.
+func New(filename string, opts Options) (*Writer, error) {
+	w := &Writer{
+		filename: filename,
+		opts:     opts,
+		now:      time.Now,
+	}
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Filename returns the name of the log file.
+func (w *Writer) Filename() string {
+	return w.filename
+}
+
+// SetOptions changes the rotation options, which take effect on the next write.
+func (w *Writer) SetOptions(opts Options) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opts = opts
+}
+
+func (w *Writer) open() error {
+	err := os.MkdirAll(filepath.Dir(w.filename), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err = w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > int64(w.opts.MaxSize)*megabyte {
+		// failing to remove the rotated files does not stop writing.
+		if err = w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync commits the content of the log file to the disk.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the log file. The file will be opened again on the next write.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// Rotate rotates the log file immediately.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+func (w *Writer) prefixAndExt() (prefix, ext string) {
+	name := filepath.Base(w.filename)
+	ext = filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	now := w.now()
+	prefix, ext := w.prefixAndExt()
+	backup := filepath.Join(filepath.Dir(w.filename), prefix+now.UTC().Format(backupTimeFormat)+ext)
+	if err := os.Rename(w.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.removeBackups(now)
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (w *Writer) backups() ([]backupFile, error) {
+	dir := filepath.Dir(w.filename)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := w.prefixAndExt()
+	var files []backupFile
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].time.After(files[j].time)
+	})
+	return files, nil
+}
+
+// removeBackups removes the rotated files exceed the MaxBackups or older than the MaxAge.
+func (w *Writer) removeBackups(now time.Time) error {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return nil
+	}
+	files, err := w.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-time.Duration(w.opts.MaxAge) * 24 * time.Hour)
+	var errs []string
+	for k, f := range files {
+		if (w.opts.MaxBackups > 0 && k >= w.opts.MaxBackups) || (w.opts.MaxAge > 0 && f.time.Before(cutoff)) {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to remove the rotated files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
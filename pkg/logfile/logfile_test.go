@@ -0,0 +1,92 @@
+package logfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range infos {
+		names = append(names, v.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWriter_Rotate(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "logfile")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "logs", "gmqttd.log")
+
+	w, err := New(filename, Options{MaxSize: 1, MaxBackups: 2})
+	a.Nil(err)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	line := []byte(strings.Repeat("a", megabyte/2-1) + "\n")
+	for i := 0; i < 2; i++ {
+		_, err = w.Write(line)
+		a.Nil(err)
+	}
+	a.Equal([]string{"gmqttd.log"}, listDir(t, filepath.Dir(filename)))
+
+	// exceeds the max size, rotate.
+	_, err = w.Write(line)
+	a.Nil(err)
+	a.Equal([]string{"gmqttd-2020-01-01T00-00-01.000.log", "gmqttd.log"}, listDir(t, filepath.Dir(filename)))
+	b, err := ioutil.ReadFile(filename)
+	a.Nil(err)
+	a.Equal(line, b)
+
+	a.Nil(w.Rotate())
+	a.Nil(w.Rotate())
+	// only the 2 newest backups are retained.
+	a.Equal([]string{"gmqttd-2020-01-01T00-00-02.000.log", "gmqttd-2020-01-01T00-00-03.000.log", "gmqttd.log"}, listDir(t, filepath.Dir(filename)))
+	a.Nil(w.Close())
+
+	// reopen and append.
+	_, err = w.Write([]byte("b\n"))
+	a.Nil(err)
+	a.Nil(w.Sync())
+	a.Nil(w.Close())
+	b, err = ioutil.ReadFile(filename)
+	a.Nil(err)
+	a.Equal("b\n", string(b))
+}
+
+func TestWriter_MaxAge(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "logfile")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "gmqttd.log")
+	old := filepath.Join(dir, "gmqttd-2020-01-01T00-00-00.000.log")
+	a.Nil(ioutil.WriteFile(old, []byte("old"), 0644))
+	unrelated := filepath.Join(dir, "gmqttd-unrelated.log")
+	a.Nil(ioutil.WriteFile(unrelated, []byte("unrelated"), 0644))
+
+	w, err := New(filename, Options{MaxAge: 1})
+	a.Nil(err)
+	defer w.Close()
+	w.now = func() time.Time {
+		return time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)
+	}
+	a.Nil(w.Rotate())
+	a.Equal([]string{"gmqttd-2020-01-03T00-00-00.000.log", "gmqttd-unrelated.log", "gmqttd.log"}, listDir(t, dir))
+}
@@ -0,0 +1,727 @@
+// Package mqttsn implements the encoding and decoding of the MQTT-SN v1.2 messages.
+// For details: https://www.oasis-open.org/committees/download.php/66091/MQTT-SN_spec_v1.2.pdf
+package mqttsn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Error type
+var (
+	ErrMalformed          = errors.New("mqttsn: malformed message")
+	ErrUnsupportedMessage = errors.New("mqttsn: unsupported message type")
+)
+
+// ProtocolID is the only protocol id defined by MQTT-SN v1.2.
+const ProtocolID = 0x01
+
+// Message type
+const (
+	ADVERTISE     = 0x00
+	SEARCHGW      = 0x01
+	GWINFO        = 0x02
+	CONNECT       = 0x04
+	CONNACK       = 0x05
+	WILLTOPICREQ  = 0x06
+	WILLTOPIC     = 0x07
+	WILLMSGREQ    = 0x08
+	WILLMSG       = 0x09
+	REGISTER      = 0x0A
+	REGACK        = 0x0B
+	PUBLISH       = 0x0C
+	PUBACK        = 0x0D
+	PUBCOMP       = 0x0E
+	PUBREC        = 0x0F
+	PUBREL        = 0x10
+	SUBSCRIBE     = 0x12
+	SUBACK        = 0x13
+	UNSUBSCRIBE   = 0x14
+	UNSUBACK      = 0x15
+	PINGREQ       = 0x16
+	PINGRESP      = 0x17
+	DISCONNECT    = 0x18
+	WILLTOPICUPD  = 0x1A
+	WILLTOPICRESP = 0x1B
+	WILLMSGUPD    = 0x1C
+	WILLMSGRESP   = 0x1D
+)
+
+// Return code
+const (
+	Accepted               = 0x00
+	RejectedCongestion     = 0x01
+	RejectedInvalidTopicID = 0x02
+	RejectedNotSupported   = 0x03
+)
+
+// Topic id type
+const (
+	// TopicIDTypeNormal indicates the topic id is registered by REGISTER or SUBSCRIBE,
+	// or the SUBSCRIBE and UNSUBSCRIBE message contains the topic name.
+	TopicIDTypeNormal = 0x00
+	// TopicIDTypePredefined indicates the topic id is predefined in the gateway and the client.
+	TopicIDTypePredefined = 0x01
+	// TopicIDTypeShortName indicates the topic id field contains a two characters topic name.
+	TopicIDTypeShortName = 0x02
+)
+
+// QoSMinusOne is the QoS -1 level, which allows the client to publish without connecting to the gateway.
+// It is the value of the QoS bits in the flags.
+const QoSMinusOne = 0x03
+
+// Flags is the flags field of the MQTT-SN messages.
+type Flags struct {
+	DUP bool
+	// QoS is the QoS level, 0, 1, 2 or QoSMinusOne.
+	QoS          uint8
+	Retain       bool
+	Will         bool
+	CleanSession bool
+	TopicIDType  uint8
+}
+
+func (f Flags) pack() byte {
+	var b byte
+	if f.DUP {
+		b |= 0x80
+	}
+	b |= (f.QoS & 0x03) << 5
+	if f.Retain {
+		b |= 0x10
+	}
+	if f.Will {
+		b |= 0x08
+	}
+	if f.CleanSession {
+		b |= 0x04
+	}
+	b |= f.TopicIDType & 0x03
+	return b
+}
+
+func unpackFlags(b byte) Flags {
+	return Flags{
+		DUP:          b&0x80 != 0,
+		QoS:          (b >> 5) & 0x03,
+		Retain:       b&0x10 != 0,
+		Will:         b&0x08 != 0,
+		CleanSession: b&0x04 != 0,
+		TopicIDType:  b & 0x03,
+	}
+}
+
+func (f Flags) String() string {
+	return fmt.Sprintf("DUP: %v, QoS: %v, Retain: %v, Will: %v, CleanSession: %v, TopicIDType: %v",
+		f.DUP, f.QoS, f.Retain, f.Will, f.CleanSession, f.TopicIDType)
+}
+
+// Message is the MQTT-SN message.
+type Message interface {
+	// Type returns the message type.
+	Type() byte
+	// Pack encodes the message into bytes, including the length and the message type.
+	Pack() []byte
+	// String is mainly used in logging, debugging and testing.
+	String() string
+}
+
+// ShortTopicID returns the topic id of the given two characters topic name.
+func ShortTopicID(name string) uint16 {
+	return uint16(name[0])<<8 | uint16(name[1])
+}
+
+// ShortTopicName returns the two characters topic name of the given topic id.
+func ShortTopicName(id uint16) string {
+	return string([]byte{byte(id >> 8), byte(id)})
+}
+
+func pack(typ byte, body []byte) []byte {
+	l := len(body) + 2
+	var b []byte
+	if l < 256 {
+		b = make([]byte, 0, l)
+		b = append(b, byte(l))
+	} else {
+		l += 2
+		b = make([]byte, 0, l)
+		b = append(b, 0x01, byte(l>>8), byte(l))
+	}
+	b = append(b, typ)
+	return append(b, body...)
+}
+
+func appendUint16(b []byte, i uint16) []byte {
+	return append(b, byte(i>>8), byte(i))
+}
+
+// ReadMessage decodes the message from the datagram.
+func ReadMessage(b []byte) (Message, error) {
+	if len(b) < 2 {
+		return nil, ErrMalformed
+	}
+	var l int
+	if b[0] == 0x01 {
+		if len(b) < 4 {
+			return nil, ErrMalformed
+		}
+		l = int(binary.BigEndian.Uint16(b[1:3]))
+		b = b[3:]
+		l -= 3
+	} else {
+		l = int(b[0])
+		b = b[1:]
+		l--
+	}
+	if l < 1 || l > len(b) {
+		return nil, ErrMalformed
+	}
+	typ := b[0]
+	body := b[1:l]
+	r := &reader{b: body}
+	var msg Message
+	switch typ {
+	case ADVERTISE:
+		msg = &Advertise{GatewayID: r.byte(), Duration: r.uint16()}
+	case SEARCHGW:
+		msg = &SearchGW{Radius: r.byte()}
+	case GWINFO:
+		msg = &GWInfo{GatewayID: r.byte(), GatewayAddr: r.rest()}
+	case CONNECT:
+		msg = &Connect{Flags: r.flags(), ProtocolID: r.byte(), Duration: r.uint16(), ClientID: string(r.rest())}
+	case CONNACK:
+		msg = &Connack{ReturnCode: r.byte()}
+	case WILLTOPICREQ:
+		msg = &WillTopicReq{}
+	case WILLTOPIC:
+		// an empty WILLTOPIC message is used to delete the will topic and will message.
+		if len(body) == 0 {
+			msg = &WillTopic{}
+		} else {
+			msg = &WillTopic{Flags: r.flags(), WillTopic: string(r.rest())}
+		}
+	case WILLMSGREQ:
+		msg = &WillMsgReq{}
+	case WILLMSG:
+		msg = &WillMsg{WillMsg: r.rest()}
+	case REGISTER:
+		msg = &Register{TopicID: r.uint16(), MsgID: r.uint16(), TopicName: string(r.rest())}
+	case REGACK:
+		msg = &Regack{TopicID: r.uint16(), MsgID: r.uint16(), ReturnCode: r.byte()}
+	case PUBLISH:
+		msg = &Publish{Flags: r.flags(), TopicID: r.uint16(), MsgID: r.uint16(), Data: r.rest()}
+	case PUBACK:
+		msg = &Puback{TopicID: r.uint16(), MsgID: r.uint16(), ReturnCode: r.byte()}
+	case PUBREC:
+		msg = &Pubrec{MsgID: r.uint16()}
+	case PUBREL:
+		msg = &Pubrel{MsgID: r.uint16()}
+	case PUBCOMP:
+		msg = &Pubcomp{MsgID: r.uint16()}
+	case SUBSCRIBE:
+		s := &Subscribe{Flags: r.flags(), MsgID: r.uint16()}
+		s.TopicName, s.TopicID = r.topic(s.Flags.TopicIDType)
+		msg = s
+	case SUBACK:
+		msg = &Suback{Flags: r.flags(), TopicID: r.uint16(), MsgID: r.uint16(), ReturnCode: r.byte()}
+	case UNSUBSCRIBE:
+		u := &Unsubscribe{Flags: r.flags(), MsgID: r.uint16()}
+		u.TopicName, u.TopicID = r.topic(u.Flags.TopicIDType)
+		msg = u
+	case UNSUBACK:
+		msg = &Unsuback{MsgID: r.uint16()}
+	case PINGREQ:
+		msg = &Pingreq{ClientID: string(r.rest())}
+	case PINGRESP:
+		msg = &Pingresp{}
+	case DISCONNECT:
+		d := &Disconnect{}
+		if len(body) != 0 {
+			d.Duration = r.uint16()
+		}
+		msg = d
+	case WILLTOPICUPD:
+		if len(body) == 0 {
+			msg = &WillTopicUpd{}
+		} else {
+			msg = &WillTopicUpd{Flags: r.flags(), WillTopic: string(r.rest())}
+		}
+	case WILLTOPICRESP:
+		msg = &WillTopicResp{ReturnCode: r.byte()}
+	case WILLMSGUPD:
+		msg = &WillMsgUpd{WillMsg: r.rest()}
+	case WILLMSGRESP:
+		msg = &WillMsgResp{ReturnCode: r.byte()}
+	default:
+		return nil, ErrUnsupportedMessage
+	}
+	if r.err {
+		return nil, ErrMalformed
+	}
+	return msg, nil
+}
+
+// reader reads the fields of the message body, it records the error instead of returning it.
+type reader struct {
+	b   []byte
+	err bool
+}
+
+func (r *reader) byte() byte {
+	if len(r.b) < 1 {
+		r.err = true
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *reader) uint16() uint16 {
+	if len(r.b) < 2 {
+		r.err = true
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *reader) flags() Flags {
+	return unpackFlags(r.byte())
+}
+
+func (r *reader) rest() []byte {
+	v := make([]byte, len(r.b))
+	copy(v, r.b)
+	r.b = r.b[len(r.b):]
+	return v
+}
+
+func (r *reader) topic(idType uint8) (name string, id uint16) {
+	if idType == TopicIDTypeNormal {
+		name = string(r.rest())
+		if name == "" {
+			r.err = true
+		}
+		return
+	}
+	id = r.uint16()
+	return
+}
+
+// Advertise is broadcasted periodically by the gateway to advertise its presence.
+type Advertise struct {
+	GatewayID uint8
+	// Duration is the time interval in seconds until the next ADVERTISE is broadcasted.
+	Duration uint16
+}
+
+func (m *Advertise) Type() byte { return ADVERTISE }
+
+func (m *Advertise) Pack() []byte {
+	return pack(ADVERTISE, appendUint16([]byte{m.GatewayID}, m.Duration))
+}
+
+func (m *Advertise) String() string {
+	return fmt.Sprintf("Advertise, GatewayID: %v, Duration: %v", m.GatewayID, m.Duration)
+}
+
+// SearchGW is broadcasted by the client to search for a gateway.
+type SearchGW struct {
+	Radius uint8
+}
+
+func (m *SearchGW) Type() byte { return SEARCHGW }
+
+func (m *SearchGW) Pack() []byte {
+	return pack(SEARCHGW, []byte{m.Radius})
+}
+
+func (m *SearchGW) String() string {
+	return fmt.Sprintf("SearchGW, Radius: %v", m.Radius)
+}
+
+// GWInfo is the response of SEARCHGW.
+type GWInfo struct {
+	GatewayID uint8
+	// GatewayAddr is only present if the message is sent by a client.
+	GatewayAddr []byte
+}
+
+func (m *GWInfo) Type() byte { return GWINFO }
+
+func (m *GWInfo) Pack() []byte {
+	return pack(GWINFO, append([]byte{m.GatewayID}, m.GatewayAddr...))
+}
+
+func (m *GWInfo) String() string {
+	return fmt.Sprintf("GWInfo, GatewayID: %v, GatewayAddr: %v", m.GatewayID, m.GatewayAddr)
+}
+
+// Connect is sent by the client to setup a connection.
+type Connect struct {
+	Flags
+	ProtocolID uint8
+	// Duration is the keep alive timer in seconds.
+	Duration uint16
+	ClientID string
+}
+
+func (m *Connect) Type() byte { return CONNECT }
+
+func (m *Connect) Pack() []byte {
+	b := appendUint16([]byte{m.Flags.pack(), m.ProtocolID}, m.Duration)
+	return pack(CONNECT, append(b, m.ClientID...))
+}
+
+func (m *Connect) String() string {
+	return fmt.Sprintf("Connect, %s, ProtocolID: %v, Duration: %v, ClientID: %s", m.Flags, m.ProtocolID, m.Duration, m.ClientID)
+}
+
+// Connack is the response of CONNECT.
+type Connack struct {
+	ReturnCode uint8
+}
+
+func (m *Connack) Type() byte { return CONNACK }
+
+func (m *Connack) Pack() []byte {
+	return pack(CONNACK, []byte{m.ReturnCode})
+}
+
+func (m *Connack) String() string {
+	return fmt.Sprintf("Connack, ReturnCode: %v", m.ReturnCode)
+}
+
+// WillTopicReq is sent by the gateway to request the will topic.
+type WillTopicReq struct{}
+
+func (m *WillTopicReq) Type() byte { return WILLTOPICREQ }
+
+func (m *WillTopicReq) Pack() []byte { return pack(WILLTOPICREQ, nil) }
+
+func (m *WillTopicReq) String() string { return "WillTopicReq" }
+
+// WillTopic is the response of WILLTOPICREQ.
+// The QoS and Retain flags are the will QoS and will retain.
+type WillTopic struct {
+	Flags
+	WillTopic string
+}
+
+func (m *WillTopic) Type() byte { return WILLTOPIC }
+
+func (m *WillTopic) Pack() []byte {
+	if m.WillTopic == "" {
+		return pack(WILLTOPIC, nil)
+	}
+	return pack(WILLTOPIC, append([]byte{m.Flags.pack()}, m.WillTopic...))
+}
+
+func (m *WillTopic) String() string {
+	return fmt.Sprintf("WillTopic, %s, WillTopic: %s", m.Flags, m.WillTopic)
+}
+
+// WillMsgReq is sent by the gateway to request the will message.
+type WillMsgReq struct{}
+
+func (m *WillMsgReq) Type() byte { return WILLMSGREQ }
+
+func (m *WillMsgReq) Pack() []byte { return pack(WILLMSGREQ, nil) }
+
+func (m *WillMsgReq) String() string { return "WillMsgReq" }
+
+// WillMsg is the response of WILLMSGREQ.
+type WillMsg struct {
+	WillMsg []byte
+}
+
+func (m *WillMsg) Type() byte { return WILLMSG }
+
+func (m *WillMsg) Pack() []byte { return pack(WILLMSG, m.WillMsg) }
+
+func (m *WillMsg) String() string {
+	return fmt.Sprintf("WillMsg, WillMsg: %s", m.WillMsg)
+}
+
+// Register is sent by the client to request a topic id for the topic name,
+// or by the gateway to inform the client the topic id of the topic name it is going to publish.
+type Register struct {
+	TopicID   uint16
+	MsgID     uint16
+	TopicName string
+}
+
+func (m *Register) Type() byte { return REGISTER }
+
+func (m *Register) Pack() []byte {
+	b := appendUint16(appendUint16(make([]byte, 0, 4+len(m.TopicName)), m.TopicID), m.MsgID)
+	return pack(REGISTER, append(b, m.TopicName...))
+}
+
+func (m *Register) String() string {
+	return fmt.Sprintf("Register, TopicID: %v, MsgID: %v, TopicName: %s", m.TopicID, m.MsgID, m.TopicName)
+}
+
+// Regack is the response of REGISTER.
+type Regack struct {
+	TopicID    uint16
+	MsgID      uint16
+	ReturnCode uint8
+}
+
+func (m *Regack) Type() byte { return REGACK }
+
+func (m *Regack) Pack() []byte {
+	b := appendUint16(appendUint16(make([]byte, 0, 5), m.TopicID), m.MsgID)
+	return pack(REGACK, append(b, m.ReturnCode))
+}
+
+func (m *Regack) String() string {
+	return fmt.Sprintf("Regack, TopicID: %v, MsgID: %v, ReturnCode: %v", m.TopicID, m.MsgID, m.ReturnCode)
+}
+
+// Publish is used by both the client and the gateway to publish data for a certain topic.
+type Publish struct {
+	Flags
+	// TopicID is the topic id, or the two characters topic name if the topic id type is TopicIDTypeShortName.
+	TopicID uint16
+	MsgID   uint16
+	Data    []byte
+}
+
+func (m *Publish) Type() byte { return PUBLISH }
+
+func (m *Publish) Pack() []byte {
+	b := appendUint16(appendUint16([]byte{m.Flags.pack()}, m.TopicID), m.MsgID)
+	return pack(PUBLISH, append(b, m.Data...))
+}
+
+func (m *Publish) String() string {
+	return fmt.Sprintf("Publish, %s, TopicID: %v, MsgID: %v, Data: %s", m.Flags, m.TopicID, m.MsgID, m.Data)
+}
+
+// Puback is the response of a QoS 1 PUBLISH, or the rejection of a PUBLISH.
+type Puback struct {
+	TopicID    uint16
+	MsgID      uint16
+	ReturnCode uint8
+}
+
+func (m *Puback) Type() byte { return PUBACK }
+
+func (m *Puback) Pack() []byte {
+	b := appendUint16(appendUint16(make([]byte, 0, 5), m.TopicID), m.MsgID)
+	return pack(PUBACK, append(b, m.ReturnCode))
+}
+
+func (m *Puback) String() string {
+	return fmt.Sprintf("Puback, TopicID: %v, MsgID: %v, ReturnCode: %v", m.TopicID, m.MsgID, m.ReturnCode)
+}
+
+// Pubrec is the first response of a QoS 2 PUBLISH.
+type Pubrec struct {
+	MsgID uint16
+}
+
+func (m *Pubrec) Type() byte { return PUBREC }
+
+func (m *Pubrec) Pack() []byte { return pack(PUBREC, appendUint16(nil, m.MsgID)) }
+
+func (m *Pubrec) String() string { return fmt.Sprintf("Pubrec, MsgID: %v", m.MsgID) }
+
+// Pubrel is the response of PUBREC.
+type Pubrel struct {
+	MsgID uint16
+}
+
+func (m *Pubrel) Type() byte { return PUBREL }
+
+func (m *Pubrel) Pack() []byte { return pack(PUBREL, appendUint16(nil, m.MsgID)) }
+
+func (m *Pubrel) String() string { return fmt.Sprintf("Pubrel, MsgID: %v", m.MsgID) }
+
+// Pubcomp is the response of PUBREL.
+type Pubcomp struct {
+	MsgID uint16
+}
+
+func (m *Pubcomp) Type() byte { return PUBCOMP }
+
+func (m *Pubcomp) Pack() []byte { return pack(PUBCOMP, appendUint16(nil, m.MsgID)) }
+
+func (m *Pubcomp) String() string { return fmt.Sprintf("Pubcomp, MsgID: %v", m.MsgID) }
+
+// Subscribe is sent by the client to subscribe to a topic name, a topic filter or a topic id.
+type Subscribe struct {
+	Flags
+	MsgID uint16
+	// TopicName is the topic name or topic filter if the topic id type is TopicIDTypeNormal.
+	TopicName string
+	// TopicID is the predefined topic id or the short topic name.
+	TopicID uint16
+}
+
+func (m *Subscribe) Type() byte { return SUBSCRIBE }
+
+func (m *Subscribe) Pack() []byte {
+	return pack(SUBSCRIBE, packTopic(appendUint16([]byte{m.Flags.pack()}, m.MsgID), m.Flags.TopicIDType, m.TopicName, m.TopicID))
+}
+
+func (m *Subscribe) String() string {
+	return fmt.Sprintf("Subscribe, %s, MsgID: %v, TopicName: %s, TopicID: %v", m.Flags, m.MsgID, m.TopicName, m.TopicID)
+}
+
+func packTopic(b []byte, idType uint8, name string, id uint16) []byte {
+	if idType == TopicIDTypeNormal {
+		return append(b, name...)
+	}
+	return appendUint16(b, id)
+}
+
+// Suback is the response of SUBSCRIBE.
+type Suback struct {
+	Flags
+	TopicID    uint16
+	MsgID      uint16
+	ReturnCode uint8
+}
+
+func (m *Suback) Type() byte { return SUBACK }
+
+func (m *Suback) Pack() []byte {
+	b := appendUint16(appendUint16([]byte{m.Flags.pack()}, m.TopicID), m.MsgID)
+	return pack(SUBACK, append(b, m.ReturnCode))
+}
+
+func (m *Suback) String() string {
+	return fmt.Sprintf("Suback, %s, TopicID: %v, MsgID: %v, ReturnCode: %v", m.Flags, m.TopicID, m.MsgID, m.ReturnCode)
+}
+
+// Unsubscribe is sent by the client to unsubscribe from a topic.
+type Unsubscribe struct {
+	Flags
+	MsgID     uint16
+	TopicName string
+	TopicID   uint16
+}
+
+func (m *Unsubscribe) Type() byte { return UNSUBSCRIBE }
+
+func (m *Unsubscribe) Pack() []byte {
+	return pack(UNSUBSCRIBE, packTopic(appendUint16([]byte{m.Flags.pack()}, m.MsgID), m.Flags.TopicIDType, m.TopicName, m.TopicID))
+}
+
+func (m *Unsubscribe) String() string {
+	return fmt.Sprintf("Unsubscribe, %s, MsgID: %v, TopicName: %s, TopicID: %v", m.Flags, m.MsgID, m.TopicName, m.TopicID)
+}
+
+// Unsuback is the response of UNSUBSCRIBE.
+type Unsuback struct {
+	MsgID uint16
+}
+
+func (m *Unsuback) Type() byte { return UNSUBACK }
+
+func (m *Unsuback) Pack() []byte { return pack(UNSUBACK, appendUint16(nil, m.MsgID)) }
+
+func (m *Unsuback) String() string { return fmt.Sprintf("Unsuback, MsgID: %v", m.MsgID) }
+
+// Pingreq is the keep alive message.
+// A sleeping client sends PINGREQ with its client id to enter the awake state and receive the buffered messages.
+type Pingreq struct {
+	ClientID string
+}
+
+func (m *Pingreq) Type() byte { return PINGREQ }
+
+func (m *Pingreq) Pack() []byte { return pack(PINGREQ, []byte(m.ClientID)) }
+
+func (m *Pingreq) String() string { return fmt.Sprintf("Pingreq, ClientID: %s", m.ClientID) }
+
+// Pingresp is the response of PINGREQ.
+type Pingresp struct{}
+
+func (m *Pingresp) Type() byte { return PINGRESP }
+
+func (m *Pingresp) Pack() []byte { return pack(PINGRESP, nil) }
+
+func (m *Pingresp) String() string { return "Pingresp" }
+
+// Disconnect is sent by the client to close the connection or to enter the asleep state,
+// or by the gateway to close the connection.
+type Disconnect struct {
+	// Duration is the sleep duration in seconds. If zero, the field is not present and the connection is closed.
+	Duration uint16
+}
+
+func (m *Disconnect) Type() byte { return DISCONNECT }
+
+func (m *Disconnect) Pack() []byte {
+	if m.Duration == 0 {
+		return pack(DISCONNECT, nil)
+	}
+	return pack(DISCONNECT, appendUint16(nil, m.Duration))
+}
+
+func (m *Disconnect) String() string { return fmt.Sprintf("Disconnect, Duration: %v", m.Duration) }
+
+// WillTopicUpd is sent by the client to update its will topic.
+type WillTopicUpd struct {
+	Flags
+	WillTopic string
+}
+
+func (m *WillTopicUpd) Type() byte { return WILLTOPICUPD }
+
+func (m *WillTopicUpd) Pack() []byte {
+	if m.WillTopic == "" {
+		return pack(WILLTOPICUPD, nil)
+	}
+	return pack(WILLTOPICUPD, append([]byte{m.Flags.pack()}, m.WillTopic...))
+}
+
+func (m *WillTopicUpd) String() string {
+	return fmt.Sprintf("WillTopicUpd, %s, WillTopic: %s", m.Flags, m.WillTopic)
+}
+
+// WillTopicResp is the response of WILLTOPICUPD.
+type WillTopicResp struct {
+	ReturnCode uint8
+}
+
+func (m *WillTopicResp) Type() byte { return WILLTOPICRESP }
+
+func (m *WillTopicResp) Pack() []byte { return pack(WILLTOPICRESP, []byte{m.ReturnCode}) }
+
+func (m *WillTopicResp) String() string {
+	return fmt.Sprintf("WillTopicResp, ReturnCode: %v", m.ReturnCode)
+}
+
+// WillMsgUpd is sent by the client to update its will message.
+type WillMsgUpd struct {
+	WillMsg []byte
+}
+
+func (m *WillMsgUpd) Type() byte { return WILLMSGUPD }
+
+func (m *WillMsgUpd) Pack() []byte { return pack(WILLMSGUPD, m.WillMsg) }
+
+func (m *WillMsgUpd) String() string {
+	return fmt.Sprintf("WillMsgUpd, WillMsg: %s", m.WillMsg)
+}
+
+// WillMsgResp is the response of WILLMSGUPD.
+type WillMsgResp struct {
+	ReturnCode uint8
+}
+
+func (m *WillMsgResp) Type() byte { return WILLMSGRESP }
+
+func (m *WillMsgResp) Pack() []byte { return pack(WILLMSGRESP, []byte{m.ReturnCode}) }
+
+func (m *WillMsgResp) String() string {
+	return fmt.Sprintf("WillMsgResp, ReturnCode: %v", m.ReturnCode)
+}
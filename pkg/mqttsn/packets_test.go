@@ -0,0 +1,158 @@
+package mqttsn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMessage(t *testing.T) {
+	tt := []struct {
+		testname string
+		msg      Message
+		want     []byte
+	}{
+		{
+			testname: "advertise",
+			msg:      &Advertise{GatewayID: 1, Duration: 900},
+			want:     []byte{5, ADVERTISE, 1, 0x03, 0x84},
+		},
+		{
+			testname: "searchgw",
+			msg:      &SearchGW{Radius: 0},
+			want:     []byte{3, SEARCHGW, 0},
+		},
+		{
+			testname: "gwinfo",
+			msg:      &GWInfo{GatewayID: 1, GatewayAddr: []byte{}},
+			want:     []byte{3, GWINFO, 1},
+		},
+		{
+			testname: "connect",
+			msg: &Connect{
+				Flags:      Flags{Will: true, CleanSession: true},
+				ProtocolID: ProtocolID,
+				Duration:   60,
+				ClientID:   "cid",
+			},
+			want: []byte{9, CONNECT, 0x0C, 0x01, 0, 60, 'c', 'i', 'd'},
+		},
+		{
+			testname: "willtopic",
+			msg:      &WillTopic{Flags: Flags{QoS: 1, Retain: true}, WillTopic: "a/b"},
+			want:     []byte{6, WILLTOPIC, 0x30, 'a', '/', 'b'},
+		},
+		{
+			testname: "empty willtopic",
+			msg:      &WillTopic{},
+			want:     []byte{2, WILLTOPIC},
+		},
+		{
+			testname: "register",
+			msg:      &Register{TopicID: 1, MsgID: 2, TopicName: "a/b"},
+			want:     []byte{9, REGISTER, 0, 1, 0, 2, 'a', '/', 'b'},
+		},
+		{
+			testname: "publish qos -1 short topic",
+			msg: &Publish{
+				Flags:   Flags{QoS: QoSMinusOne, TopicIDType: TopicIDTypeShortName},
+				TopicID: ShortTopicID("ab"),
+				Data:    []byte("hi"),
+			},
+			want: []byte{9, PUBLISH, 0x62, 'a', 'b', 0, 0, 'h', 'i'},
+		},
+		{
+			testname: "publish qos 2 dup",
+			msg: &Publish{
+				Flags:   Flags{DUP: true, QoS: 2, Retain: true, TopicIDType: TopicIDTypePredefined},
+				TopicID: 10,
+				MsgID:   11,
+				Data:    []byte{},
+			},
+			want: []byte{7, PUBLISH, 0xD1, 0, 10, 0, 11},
+		},
+		{
+			testname: "puback",
+			msg:      &Puback{TopicID: 1, MsgID: 2, ReturnCode: RejectedInvalidTopicID},
+			want:     []byte{7, PUBACK, 0, 1, 0, 2, 2},
+		},
+		{
+			testname: "pubrel",
+			msg:      &Pubrel{MsgID: 3},
+			want:     []byte{4, PUBREL, 0, 3},
+		},
+		{
+			testname: "subscribe topic name",
+			msg:      &Subscribe{Flags: Flags{QoS: 1}, MsgID: 1, TopicName: "a/#"},
+			want:     []byte{8, SUBSCRIBE, 0x20, 0, 1, 'a', '/', '#'},
+		},
+		{
+			testname: "subscribe predefined",
+			msg:      &Subscribe{Flags: Flags{TopicIDType: TopicIDTypePredefined}, MsgID: 1, TopicID: 5},
+			want:     []byte{7, SUBSCRIBE, 0x01, 0, 1, 0, 5},
+		},
+		{
+			testname: "unsubscribe short name",
+			msg:      &Unsubscribe{Flags: Flags{TopicIDType: TopicIDTypeShortName}, MsgID: 1, TopicID: ShortTopicID("ab")},
+			want:     []byte{7, UNSUBSCRIBE, 0x02, 0, 1, 'a', 'b'},
+		},
+		{
+			testname: "pingreq with client id",
+			msg:      &Pingreq{ClientID: "cid"},
+			want:     []byte{5, PINGREQ, 'c', 'i', 'd'},
+		},
+		{
+			testname: "disconnect",
+			msg:      &Disconnect{},
+			want:     []byte{2, DISCONNECT},
+		},
+		{
+			testname: "disconnect with duration",
+			msg:      &Disconnect{Duration: 300},
+			want:     []byte{4, DISCONNECT, 0x01, 0x2C},
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.testname, func(t *testing.T) {
+			a := assert.New(t)
+			b := v.msg.Pack()
+			a.Equal(v.want, b)
+			msg, err := ReadMessage(b)
+			a.Nil(err)
+			a.Equal(v.msg, msg)
+		})
+	}
+}
+
+func TestReadMessage_LongLength(t *testing.T) {
+	a := assert.New(t)
+	data := bytes.Repeat([]byte{'a'}, 300)
+	pub := &Publish{Flags: Flags{QoS: 1}, TopicID: 1, MsgID: 1, Data: data}
+	b := pub.Pack()
+	a.Equal([]byte{0x01, 0x01, 0x35, PUBLISH}, b[:4])
+	a.Len(b, 309)
+	msg, err := ReadMessage(b)
+	a.Nil(err)
+	a.Equal(pub, msg)
+}
+
+func TestReadMessage_Malformed(t *testing.T) {
+	tt := []struct {
+		testname string
+		b        []byte
+		err      error
+	}{
+		{testname: "too short", b: []byte{1}, err: ErrMalformed},
+		{testname: "length exceeds datagram", b: []byte{10, PUBLISH, 0}, err: ErrMalformed},
+		{testname: "missing fields", b: []byte{4, PUBLISH, 0, 1}, err: ErrMalformed},
+		{testname: "empty subscribe topic", b: []byte{5, SUBSCRIBE, 0, 0, 1}, err: ErrMalformed},
+		{testname: "unsupported", b: []byte{2, 0xFE}, err: ErrUnsupportedMessage},
+	}
+	for _, v := range tt {
+		t.Run(v.testname, func(t *testing.T) {
+			_, err := ReadMessage(v.b)
+			assert.Equal(t, v.err, err)
+		})
+	}
+}
@@ -13,24 +13,43 @@ const Name = "admin"
 
 func init() {
 	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
 }
 
 func New(config config.Config) (server.Plugin, error) {
-	return &Admin{}, nil
+	a := &Admin{
+		config: config.Plugins[Name].(*Config),
+	}
+	a.deviceRPC = newDeviceRPC(a)
+	a.statsService = newStatsService(a)
+	return a, nil
 }
 
 var log *zap.Logger
 
 // Admin providers gRPC and HTTP API that enables the external system to interact with the broker.
 type Admin struct {
-	statsReader   server.StatsReader
-	publisher     server.Publisher
-	clientService server.ClientService
-	store         *store
+	config              *Config
+	auth                *authenticator
+	statsReader         server.StatsReader
+	publisher           server.Publisher
+	clientService       server.ClientService
+	subscriptionService server.SubscriptionService
+	retainedService     server.RetainedService
+	queueService        server.QueueService
+	traceService        server.TraceService
+	auditLogger         server.AuditLogger
+	logLevelManager     server.LogLevelManager
+	// cluster is used by the cluster-wide operations, nil if the federation plugin is not enabled.
+	cluster      server.Cluster
+	store        *store
+	deviceRPC    *deviceRPC
+	statsService *statsService
 }
 
 func (a *Admin) registerHTTP(g server.APIRegistrar) (err error) {
-	err = g.RegisterHTTPHandler(registerAdminUI)
+	ui := &webUI{auth: a.auth}
+	err = g.RegisterHTTPHandler(ui.register)
 	if err != nil {
 		return err
 	}
@@ -46,15 +65,74 @@ func (a *Admin) registerHTTP(g server.APIRegistrar) (err error) {
 	if err != nil {
 		return err
 	}
+	err = g.RegisterHTTPHandler(RegisterDeviceRPCServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterRetainedServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterQueueServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterStatsServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterTraceServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterAuditServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
+	err = g.RegisterHTTPHandler(RegisterLogServiceHandlerFromEndpoint)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
 func (a *Admin) Load(service server.Server) error {
 	log = server.LoggerWithField(zap.String("plugin", Name))
+	if err := checkPluginOrder(service.Plugins()); err != nil {
+		return err
+	}
 	apiRegistrar := service.APIRegistrar()
-	RegisterClientServiceServer(apiRegistrar, &clientService{a: a})
-	RegisterSubscriptionServiceServer(apiRegistrar, &subscriptionService{a: a})
+	a.auditLogger = service.AuditLogger()
+	if a.config.Auth.Enable {
+		auth, err := newAuthenticator(a.config.Auth)
+		if err != nil {
+			return err
+		}
+		auth.auditLogger = a.auditLogger
+		a.auth = auth
+		apiRegistrar.RegisterUnaryInterceptor(auth.unaryInterceptor)
+		apiRegistrar.RegisterStreamInterceptor(auth.streamInterceptor)
+		apiRegistrar.RegisterHTTPMiddleware(auth.httpMiddleware)
+	} else {
+		log.Warn("the authentication of the admin APIs is disabled, the web UI and the admin APIs which modify the broker are refused")
+		apiRegistrar.RegisterUnaryInterceptor(readOnlyUnaryInterceptor)
+		apiRegistrar.RegisterStreamInterceptor(readOnlyStreamInterceptor)
+	}
+	// the audit interceptors must be registered after the auth interceptors to get the actor.
+	apiRegistrar.RegisterUnaryInterceptor(a.auditUnaryInterceptor)
+	apiRegistrar.RegisterStreamInterceptor(a.auditStreamInterceptor)
+	cs := &clientService{a: a}
+	ss := &subscriptionService{a: a}
+	RegisterClientServiceServer(apiRegistrar, cs)
+	RegisterSubscriptionServiceServer(apiRegistrar, ss)
 	RegisterPublishServiceServer(apiRegistrar, &publisher{a: a})
+	RegisterDeviceRPCServiceServer(apiRegistrar, a.deviceRPC)
+	RegisterRetainedServiceServer(apiRegistrar, &retainedService{a: a})
+	RegisterQueueServiceServer(apiRegistrar, &queueService{a: a})
+	RegisterStatsServiceServer(apiRegistrar, a.statsService)
+	RegisterTraceServiceServer(apiRegistrar, &traceService{a: a})
+	RegisterAuditServiceServer(apiRegistrar, &auditService{a: a})
+	RegisterLogServiceServer(apiRegistrar, &logService{a: a})
 	err := a.registerHTTP(apiRegistrar)
 	if err != nil {
 		return err
@@ -64,10 +142,28 @@ func (a *Admin) Load(service server.Server) error {
 	a.store.subscriptionService = service.SubscriptionService()
 	a.publisher = service.Publisher()
 	a.clientService = service.ClientService()
+	a.subscriptionService = service.SubscriptionService()
+	a.retainedService = service.RetainedService()
+	a.queueService = service.QueueService()
+	a.traceService = service.TraceService()
+	a.logLevelManager = service.LogLevelManager()
+	a.cluster = findCluster(service.Plugins())
+	if a.cluster != nil {
+		a.registerClusterHandlers(cs, ss)
+	}
+	a.statsService.run()
 	return nil
 }
 
+// checkPluginOrder requires the admin plugin to be placed before the thingspanel plugin,
+// which does not pass the OnClosed and OnMsgArrived hooks on to the plugins after it,
+// otherwise the device RPC responses and the client disconnections are never seen by the admin plugin.
+func checkPluginOrder(plugins []server.Plugin) error {
+	return server.CheckPluginBefore(plugins, Name, "thingspanel")
+}
+
 func (a *Admin) Unload() error {
+	a.statsService.stop()
 	return nil
 }
 
@@ -0,0 +1,231 @@
+package admin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const adminAPIPrefix = "/gmqtt.admin.api."
+
+// auditOperation returns the operation name of the gRPC method,
+// e.g. /gmqtt.admin.api.DeviceRPCService/Call => admin.device_rpc.call.
+func auditOperation(fullMethod string) string {
+	s := strings.Split(strings.TrimPrefix(fullMethod, "/"), "/")
+	if len(s) != 2 {
+		return fullMethod
+	}
+	names := strings.Split(s[0], ".")
+	service := strings.TrimSuffix(names[len(names)-1], "Service")
+	pkg := names[:len(names)-1]
+	// gmqtt.admin.api => admin
+	if len(pkg) >= 2 && pkg[0] == "gmqtt" {
+		pkg = pkg[1:2]
+	}
+	return strings.Join(append(pkg, strcase.ToSnake(service), strcase.ToSnake(s[1])), ".")
+}
+
+// auditTargetGetters are the fields that identify the target of the operation, in the order of precedence.
+var auditTargetGetters = []struct {
+	name string
+	get  func(req interface{}) string
+}{
+	{name: "client_id", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetClientId() string }); ok {
+			return v.GetClientId()
+		}
+		return ""
+	}},
+	{name: "device_number", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetDeviceNumber() string }); ok {
+			return v.GetDeviceNumber()
+		}
+		return ""
+	}},
+	{name: "username", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetUsername() string }); ok {
+			return v.GetUsername()
+		}
+		return ""
+	}},
+	{name: "topic_name", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetTopicName() string }); ok {
+			return v.GetTopicName()
+		}
+		return ""
+	}},
+	{name: "topic_filter", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetTopicFilter() string }); ok {
+			return v.GetTopicFilter()
+		}
+		return ""
+	}},
+	{name: "plugin", get: func(req interface{}) string {
+		if v, ok := req.(interface{ GetPlugin() string }); ok {
+			return v.GetPlugin()
+		}
+		return ""
+	}},
+}
+
+// auditTarget returns the target and the details of the request.
+// The first non-empty field in auditTargetGetters is the target, the other non-empty fields are put in the details.
+func auditTarget(req interface{}) (target string, details map[string]string) {
+	for _, v := range auditTargetGetters {
+		s := v.get(req)
+		if s == "" {
+			continue
+		}
+		if target == "" {
+			target = s
+			continue
+		}
+		if details == nil {
+			details = make(map[string]string)
+		}
+		details[v.name] = s
+	}
+	if v, ok := req.(*SubscribeRequest); ok && len(v.Subscriptions) != 0 {
+		if details == nil {
+			details = make(map[string]string)
+		}
+		var topics []string
+		for _, t := range v.Subscriptions {
+			topics = append(topics, t.TopicName)
+		}
+		details["topics"] = strings.Join(topics, ",")
+	}
+	if v, ok := req.(*SetLogLevelRequest); ok {
+		if details == nil {
+			details = make(map[string]string)
+		}
+		details["level"] = v.Level
+	}
+	if v, ok := req.(interface{ GetTopics() []string }); ok && len(v.GetTopics()) != 0 {
+		if details == nil {
+			details = make(map[string]string)
+		}
+		details["topics"] = strings.Join(v.GetTopics(), ",")
+	}
+	return target, details
+}
+
+// audited returns whether the method is audited by the audit interceptors.
+// All admin API methods except the read-only ones (which require RoleViewer) are audited.
+func audited(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, adminAPIPrefix) && methodRoles[fullMethod] != RoleViewer
+}
+
+// auditRecord returns the audit record of the gRPC method.
+func auditRecord(ctx context.Context, fullMethod string, req interface{}, err error) *server.AuditRecord {
+	actor, sourceIP := server.AuditSourceFromContext(ctx)
+	r := &server.AuditRecord{
+		Actor:     actor,
+		SourceIP:  sourceIP,
+		Operation: auditOperation(fullMethod),
+	}
+	if req != nil {
+		r.Target, r.Details = auditTarget(req)
+	}
+	if err != nil {
+		r.Error = status.Convert(err).Message()
+	}
+	return r
+}
+
+func (a *Admin) auditUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !audited(info.FullMethod) {
+		return handler(ctx, req)
+	}
+	resp, err := handler(ctx, req)
+	a.auditLogger.Audit(auditRecord(ctx, info.FullMethod, req, err))
+	return resp, err
+}
+
+// auditServerStream records the first received message as the request.
+type auditServerStream struct {
+	grpc.ServerStream
+	req interface{}
+}
+
+func (s *auditServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.req == nil {
+		s.req = m
+	}
+	return err
+}
+
+func (a *Admin) auditStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !audited(info.FullMethod) {
+		return handler(srv, ss)
+	}
+	as := &auditServerStream{ServerStream: ss}
+	err := handler(srv, as)
+	a.auditLogger.Audit(auditRecord(ss.Context(), info.FullMethod, as.req, err))
+	return err
+}
+
+type auditService struct {
+	a *Admin
+}
+
+func (s *auditService) mustEmbedUnimplementedAuditServiceServer() {
+	return
+}
+
+// List implements AuditServiceServer.
+func (s *auditService) List(ctx context.Context, req *ListAuditRecordsRequest) (*ListAuditRecordsResponse, error) {
+	switch req.Result {
+	case "", server.AuditResultSuccess, server.AuditResultFailure:
+	default:
+		return nil, ErrInvalidArgument("result", "")
+	}
+	page, pageSize := GetPage(req.Page, req.PageSize)
+	offset, n := GetOffsetN(page, pageSize)
+	q := server.AuditQuery{
+		Actor:     req.Actor,
+		Operation: req.Operation,
+		Target:    req.Target,
+		Result:    req.Result,
+		Offset:    int(offset),
+		Limit:     int(n),
+	}
+	if req.Since != nil {
+		q.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		q.Until = req.Until.AsTime()
+	}
+	records, total, err := s.a.auditLogger.Query(q)
+	if err == server.ErrAuditQueryNotSupported {
+		return nil, status.Error(codes.Unimplemented, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &ListAuditRecordsResponse{
+		Records:    make([]*AuditRecord, 0, len(records)),
+		TotalCount: uint32(total),
+	}
+	for _, v := range records {
+		resp.Records = append(resp.Records, &AuditRecord{
+			Time:      timestamppb.New(v.Time),
+			Actor:     v.Actor,
+			SourceIp:  v.SourceIP,
+			Operation: v.Operation,
+			Target:    v.Target,
+			Result:    v.Result,
+			Error:     v.Error,
+			Details:   v.Details,
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type testAuditLogger struct {
+	records []*server.AuditRecord
+	query   server.AuditQuery
+	err     error
+}
+
+func (t *testAuditLogger) Audit(record *server.AuditRecord) {
+	t.records = append(t.records, record)
+}
+
+func (t *testAuditLogger) Query(query server.AuditQuery) ([]*server.AuditRecord, int, error) {
+	t.query = query
+	if t.err != nil {
+		return nil, 0, t.err
+	}
+	return t.records, len(t.records), nil
+}
+
+func TestAuditOperation(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("admin.client.delete", auditOperation("/gmqtt.admin.api.ClientService/Delete"))
+	a.Equal("admin.device_rpc.call", auditOperation("/gmqtt.admin.api.DeviceRPCService/Call"))
+	a.Equal("auth.account.update", auditOperation("/gmqtt.auth.api.AccountService/Update"))
+	a.Equal("some.svc.method", auditOperation("/some.Svc/Method"))
+	a.Equal("invalid", auditOperation("invalid"))
+}
+
+func TestAuditTarget(t *testing.T) {
+	a := assert.New(t)
+	target, details := auditTarget(&SubscribeRequest{
+		ClientId:      "cid",
+		Subscriptions: []*Subscription{{TopicName: "a"}, {TopicName: "b/#"}},
+	})
+	a.Equal("cid", target)
+	a.Equal(map[string]string{"topics": "a,b/#"}, details)
+
+	target, details = auditTarget(&TraceRequest{Username: "u", TopicFilter: "a/#"})
+	a.Equal("u", target)
+	a.Equal(map[string]string{"topic_filter": "a/#"}, details)
+
+	target, details = auditTarget(&PublishRequest{TopicName: "a/b"})
+	a.Equal("a/b", target)
+	a.Nil(details)
+
+	target, details = auditTarget(&SetLogLevelRequest{Plugin: "thingspanel", Level: "debug"})
+	a.Equal("thingspanel", target)
+	a.Equal(map[string]string{"level": "debug"}, details)
+}
+
+func TestAdmin_auditUnaryInterceptor(t *testing.T) {
+	a := assert.New(t)
+	l := &testAuditLogger{}
+	ad := &Admin{auditLogger: l}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-forwarded-for", "10.0.0.1"))
+	ctx = server.WithAuditActor(ctx, "ops")
+
+	// read-only methods are not audited
+	_, err := ad.auditUnaryInterceptor(ctx, &GetClientRequest{ClientId: "cid"}, &grpc.UnaryServerInfo{FullMethod: "/gmqtt.admin.api.ClientService/Get"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+	a.NoError(err)
+	a.Len(l.records, 0)
+
+	_, err = ad.auditUnaryInterceptor(ctx, &DeleteClientRequest{ClientId: "cid"}, &grpc.UnaryServerInfo{FullMethod: "/gmqtt.admin.api.ClientService/Delete"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, ErrNotFound
+		})
+	a.Equal(ErrNotFound, err)
+	a.Len(l.records, 1)
+	a.Equal(&server.AuditRecord{
+		Actor:     "ops",
+		SourceIP:  "10.0.0.1",
+		Operation: "admin.client.delete",
+		Target:    "cid",
+		Error:     "not found",
+	}, l.records[0])
+}
+
+func TestAuthenticator_AuditPermissionDenied(t *testing.T) {
+	a := assert.New(t)
+	auth := newTestAuthenticator(t)
+	l := &testAuditLogger{}
+	auth.auditLogger = l
+	viewerSession, _, err := auth.newSession("viewer")
+	a.NoError(err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(cookieMetadataKey, sessionCookieName+"="+viewerSession))
+	_, err = auth.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/gmqtt.admin.api.ClientService/Delete"}, nil)
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	a.Len(l.records, 1)
+	a.Equal("viewer", l.records[0].Actor)
+	a.Equal("admin.client.delete", l.records[0].Operation)
+	a.Equal(map[string]string{"role": "viewer"}, l.records[0].Details)
+
+	// the actor is passed to the handlers
+	_, err = auth.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/gmqtt.admin.api.ClientService/List"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			actor, _ := server.AuditSourceFromContext(ctx)
+			a.Equal("viewer", actor)
+			return nil, nil
+		})
+	a.NoError(err)
+}
+
+func TestAuditService_List(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(100, 0)
+	l := &testAuditLogger{
+		records: []*server.AuditRecord{
+			{Time: now, Actor: "admin", Operation: "admin.client.delete", Target: "cid", Result: server.AuditResultSuccess},
+		},
+	}
+	s := &auditService{a: &Admin{auditLogger: l}}
+	resp, err := s.List(context.Background(), &ListAuditRecordsRequest{
+		Operation: "admin",
+		Result:    server.AuditResultSuccess,
+		Since:     timestamppb.New(now),
+		PageSize:  10,
+		Page:      2,
+	})
+	a.NoError(err)
+	a.EqualValues(1, resp.TotalCount)
+	a.Equal("cid", resp.Records[0].Target)
+	a.Equal(now.Unix(), resp.Records[0].Time.Seconds)
+	a.Equal(server.AuditQuery{
+		Operation: "admin",
+		Result:    server.AuditResultSuccess,
+		Since:     now.UTC(),
+		Offset:    10,
+		Limit:     10,
+	}, l.query)
+
+	_, err = s.List(context.Background(), &ListAuditRecordsRequest{Result: "unknown"})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	l.err = server.ErrAuditQueryNotSupported
+	_, err = s.List(context.Background(), &ListAuditRecordsRequest{})
+	a.Equal(codes.Unimplemented, status.Code(err))
+	l.err = errors.New("io error")
+	_, err = s.List(context.Background(), &ListAuditRecordsRequest{})
+	a.Equal(codes.Internal, status.Code(err))
+}
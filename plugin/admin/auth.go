@@ -0,0 +1,442 @@
+package admin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// Role is the role of the admin users and API tokens.
+type Role string
+
+const (
+	// RoleViewer can only call the read-only APIs.
+	RoleViewer Role = "viewer"
+	// RoleOperator can also publish messages, call devices and manage the clients and subscriptions.
+	RoleOperator Role = "operator"
+	// RoleAdmin can call all APIs.
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+func (r Role) valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// allows returns whether the role is granted the permission of the required role.
+func (r Role) allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// methodRoles is the minimum role required by the gRPC methods.
+// The methods which are not listed require RoleAdmin.
+var methodRoles = map[string]Role{
+	"/gmqtt.admin.api.ClientService/List":              RoleViewer,
+	"/gmqtt.admin.api.ClientService/Get":               RoleViewer,
+	"/gmqtt.admin.api.ClientService/Delete":            RoleOperator,
+	"/gmqtt.admin.api.SubscriptionService/List":        RoleViewer,
+	"/gmqtt.admin.api.SubscriptionService/Filter":      RoleViewer,
+	"/gmqtt.admin.api.SubscriptionService/Subscribe":   RoleOperator,
+	"/gmqtt.admin.api.SubscriptionService/Unsubscribe": RoleOperator,
+	"/gmqtt.admin.api.PublishService/Publish":          RoleOperator,
+	"/gmqtt.admin.api.DeviceRPCService/Call":           RoleOperator,
+	"/gmqtt.admin.api.RetainedService/List":            RoleViewer,
+	"/gmqtt.admin.api.RetainedService/Get":             RoleViewer,
+	"/gmqtt.admin.api.RetainedService/Set":             RoleOperator,
+	"/gmqtt.admin.api.RetainedService/Delete":          RoleOperator,
+	"/gmqtt.admin.api.QueueService/List":               RoleViewer,
+	"/gmqtt.admin.api.QueueService/Drop":               RoleOperator,
+	"/gmqtt.admin.api.StatsService/GetGlobal":          RoleViewer,
+	"/gmqtt.admin.api.StatsService/GetClient":          RoleViewer,
+	"/gmqtt.admin.api.StatsService/TopClients":         RoleViewer,
+	"/gmqtt.admin.api.TraceService/Start":              RoleOperator,
+	"/gmqtt.admin.api.LogService/GetLevel":             RoleViewer,
+	"/gmqtt.auth.api.AccountService/List":              RoleViewer,
+	"/gmqtt.auth.api.AccountService/Get":               RoleViewer,
+	"/gmqtt.federation.api.Membership/ListMembers":     RoleViewer,
+	"/gmqtt.federation.api.Membership/ListPeers":       RoleViewer,
+}
+
+const (
+	csrfHeader    = "X-CSRF-Token"
+	csrfFormField = "csrf_token"
+	// cookieMetadataKey is the metadata key of the Cookie header forwarded by grpc-gateway.
+	cookieMetadataKey = runtime.MetadataPrefix + "cookie"
+)
+
+var (
+	errUnauthenticated  = status.Error(codes.Unauthenticated, "unauthenticated")
+	errPermissionDenied = status.Error(codes.PermissionDenied, "permission denied")
+	errInvalidCSRFToken = status.Error(codes.PermissionDenied, "invalid csrf token")
+	errAuthDisabled     = status.Error(codes.PermissionDenied, "the authentication is disabled, only the read-only APIs are allowed")
+)
+
+// identity is the authenticated admin user or API token.
+type identity struct {
+	// name is the username or the token name.
+	name string
+	role Role
+	// session indicates whether the identity is authenticated by the session cookie.
+	session bool
+}
+
+type identityKey struct{}
+
+// identityFromContext returns the identity of the caller, it returns false if the authentication is disabled.
+func identityFromContext(ctx context.Context) (*identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*identity)
+	return id, ok
+}
+
+// session is the payload of the session cookie.
+type session struct {
+	Username string `json:"u"`
+	Expiry   int64  `json:"e"`
+	Nonce    string `json:"n"`
+}
+
+// authenticator authenticates and authorizes the admin users and API tokens.
+type authenticator struct {
+	config AuthConfig
+	secret []byte
+	users  map[string]*UserConfig
+	// tokens is keyed by the sha256 hash of the token.
+	tokens map[[sha256.Size]byte]*TokenConfig
+	// dummyHash is used to compare the password for the unknown users,
+	// so that the response time does not reveal whether the user exists.
+	dummyHash []byte
+	now       func() time.Time
+	// auditLogger records the login, logout and permission denied events, it is nil in tests.
+	auditLogger server.AuditLogger
+}
+
+func newAuthenticator(config AuthConfig) (*authenticator, error) {
+	a := &authenticator{
+		config: config,
+		users:  make(map[string]*UserConfig),
+		tokens: make(map[[sha256.Size]byte]*TokenConfig),
+		now:    time.Now,
+	}
+	if config.SessionSecret != "" {
+		a.secret = []byte(config.SessionSecret)
+	} else {
+		a.secret = make([]byte, 32)
+		if _, err := rand.Read(a.secret); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range config.Users {
+		a.users[v.Username] = v
+	}
+	for _, v := range config.Tokens {
+		b, err := hex.DecodeString(v.TokenSHA256)
+		if err != nil || len(b) != sha256.Size {
+			return nil, errors.New("invalid token_sha256")
+		}
+		var key [sha256.Size]byte
+		copy(key[:], b)
+		a.tokens[key] = v
+	}
+	var err error
+	a.dummyHash, err = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *authenticator) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// login checks the username and password.
+func (a *authenticator) login(username, password string) (*identity, error) {
+	u, ok := a.users[username]
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+		return nil, errUnauthenticated
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)); err != nil {
+		return nil, errUnauthenticated
+	}
+	return &identity{name: u.Username, role: u.Role, session: true}, nil
+}
+
+// newSession returns the signed session cookie value for the user.
+func (a *authenticator) newSession(username string) (value string, expiry time.Time, err error) {
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return "", time.Time{}, err
+	}
+	expiry = a.now().Add(a.config.SessionExpiry)
+	b, err := json.Marshal(&session{
+		Username: username,
+		Expiry:   expiry.Unix(),
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + a.sign(payload), expiry, nil
+}
+
+// verifySession verifies the session cookie value and returns the identity of the session user.
+// The role is read from the current configuration, so that the removed users are logged out.
+func (a *authenticator) verifySession(value string) (*identity, error) {
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(a.sign(parts[0]))) {
+		return nil, errUnauthenticated
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	s := &session{}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, errUnauthenticated
+	}
+	if a.now().Unix() >= s.Expiry {
+		return nil, errUnauthenticated
+	}
+	u, ok := a.users[s.Username]
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	return &identity{name: u.Username, role: u.Role, session: true}, nil
+}
+
+// csrfToken returns the csrf token bound to the session cookie value.
+func (a *authenticator) csrfToken(sessionValue string) string {
+	return a.sign("csrf:" + sessionValue)
+}
+
+func (a *authenticator) verifyCSRFToken(sessionValue, token string) bool {
+	return token != "" && hmac.Equal([]byte(token), []byte(a.csrfToken(sessionValue)))
+}
+
+// verifyToken verifies the API bearer token.
+func (a *authenticator) verifyToken(token string) (*identity, error) {
+	sum := sha256.Sum256([]byte(token))
+	var found *TokenConfig
+	// compare all tokens in constant time
+	for k, v := range a.tokens {
+		if subtle.ConstantTimeCompare(k[:], sum[:]) == 1 {
+			found = v
+		}
+	}
+	if found == nil {
+		return nil, errUnauthenticated
+	}
+	return &identity{name: found.Name, role: found.Role}, nil
+}
+
+func bearerToken(authorization string) (string, bool) {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(authorization[len(prefix):]), true
+}
+
+func sessionCookie(cookies []string) (string, bool) {
+	r := &http.Request{Header: http.Header{"Cookie": cookies}}
+	c, err := r.Cookie(sessionCookieName)
+	if err != nil || c.Value == "" {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// authenticate authenticates the request by the Authorization header or the session cookie.
+// The session cookie is ignored if the Authorization header is present.
+func (a *authenticator) authenticate(authorization string, cookies []string) (*identity, error) {
+	if authorization != "" {
+		token, ok := bearerToken(authorization)
+		if !ok {
+			return nil, errUnauthenticated
+		}
+		return a.verifyToken(token)
+	}
+	if value, ok := sessionCookie(cookies); ok {
+		return a.verifySession(value)
+	}
+	return nil, errUnauthenticated
+}
+
+func (a *authenticator) authenticateContext(ctx context.Context) (*identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var authorization string
+	if v := md.Get("authorization"); len(v) != 0 {
+		authorization = v[0]
+	}
+	return a.authenticate(authorization, md.Get(cookieMetadataKey))
+}
+
+// authorize checks whether the identity is allowed to call the method.
+func authorize(fullMethod string, id *identity) error {
+	required, ok := methodRoles[fullMethod]
+	if !ok {
+		required = RoleAdmin
+	}
+	if !id.role.allows(required) {
+		return errPermissionDenied
+	}
+	return nil
+}
+
+func (a *authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id, err := a.authenticateContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(info.FullMethod, id); err != nil {
+		a.permissionDenied(ctx, info.FullMethod, id)
+		return nil, err
+	}
+	return handler(withIdentity(ctx, id), req)
+}
+
+// withIdentity returns a copy of ctx which carries the identity for the handlers and the audit records.
+func withIdentity(ctx context.Context, id *identity) context.Context {
+	return server.WithAuditActor(context.WithValue(ctx, identityKey{}, id), id.name)
+}
+
+func (a *authenticator) audit(record *server.AuditRecord) {
+	if a.auditLogger != nil {
+		a.auditLogger.Audit(record)
+	}
+}
+
+func (a *authenticator) permissionDenied(ctx context.Context, fullMethod string, id *identity) {
+	log.Warn("permission denied", zap.String("identity", id.name), zap.String("role", string(id.role)), zap.String("method", fullMethod))
+	_, sourceIP := server.AuditSourceFromContext(ctx)
+	a.audit(&server.AuditRecord{
+		Actor:     id.name,
+		SourceIP:  sourceIP,
+		Operation: auditOperation(fullMethod),
+		Error:     errPermissionDenied.Error(),
+		Details:   map[string]string{"role": string(id.role)},
+	})
+}
+
+type identityServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (a *authenticator) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, err := a.authenticateContext(ss.Context())
+	if err != nil {
+		return err
+	}
+	if err = authorize(info.FullMethod, id); err != nil {
+		a.permissionDenied(ss.Context(), info.FullMethod, id)
+		return err
+	}
+	return handler(srv, &identityServerStream{
+		ServerStream: ss,
+		ctx:          withIdentity(ss.Context(), id),
+	})
+}
+
+// readOnly checks whether the method is allowed when the authentication is disabled.
+// Only the admin methods granted to RoleViewer are allowed, the other admin methods require the authentication.
+// The methods registered by other plugins, such as auth and federation, are not restricted.
+func readOnly(fullMethod string) error {
+	if !strings.HasPrefix(fullMethod, adminAPIPrefix) {
+		return nil
+	}
+	if methodRoles[fullMethod] != RoleViewer {
+		log.Warn("the method is not allowed when the authentication is disabled", zap.String("method", fullMethod))
+		return errAuthDisabled
+	}
+	return nil
+}
+
+func readOnlyUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := readOnly(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func readOnlyStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := readOnly(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// writeHTTPError writes the error in the same format as grpc-gateway.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	s := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(runtime.HTTPStatusFromCode(s.Code()))
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   s.Message(),
+		"code":    s.Code(),
+		"message": s.Message(),
+	})
+}
+
+// httpMiddleware authenticates the API requests and checks the csrf token for the unsafe requests
+// which are authenticated by the session cookie.
+// The authorization is done by the gRPC interceptors.
+func (a *authenticator) httpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authorization := r.Header.Get("Authorization")
+		id, err := a.authenticate(authorization, r.Header["Cookie"])
+		if err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+		if id.session && !isSafeMethod(r.Method) {
+			value, _ := sessionCookie(r.Header["Cookie"])
+			if !a.verifyCSRFToken(value, r.Header.Get(csrfHeader)) {
+				writeHTTPError(w, errInvalidCSRFToken)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
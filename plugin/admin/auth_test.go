@@ -0,0 +1,301 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	log = zap.NewNop()
+}
+
+func hashPassword(t *testing.T, password string) string {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestAuthConfig(t *testing.T) AuthConfig {
+	return AuthConfig{
+		Enable:        true,
+		SessionSecret: "0123456789abcdef",
+		SessionExpiry: time.Hour,
+		Users: []*UserConfig{
+			{Username: "admin", Password: hashPassword(t, "admin_pwd"), Role: RoleAdmin},
+			{Username: "viewer", Password: hashPassword(t, "viewer_pwd"), Role: RoleViewer},
+		},
+		Tokens: []*TokenConfig{
+			{Name: "ops", TokenSHA256: hashToken("ops_token"), Role: RoleOperator},
+		},
+	}
+}
+
+func newTestAuthenticator(t *testing.T) *authenticator {
+	auth, err := newAuthenticator(newTestAuthConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	c := &Config{Auth: newTestAuthConfig(t)}
+	a.NoError(c.Validate())
+	a.NoError((&DefaultConfig).Validate())
+
+	var tt = []func(c *AuthConfig){
+		func(c *AuthConfig) { c.Users = nil; c.Tokens = nil },
+		func(c *AuthConfig) { c.SessionSecret = "short" },
+		func(c *AuthConfig) { c.SessionExpiry = 0 },
+		func(c *AuthConfig) { c.Users[0].Password = "admin_pwd" },
+		func(c *AuthConfig) { c.Users[1].Username = "admin" },
+		func(c *AuthConfig) { c.Users[0].Role = "root" },
+		func(c *AuthConfig) { c.Tokens[0].TokenSHA256 = "abc" },
+		func(c *AuthConfig) { c.Tokens[0].Name = "" },
+		func(c *AuthConfig) { c.Tokens[0].Role = "" },
+	}
+	for _, v := range tt {
+		c := &Config{Auth: newTestAuthConfig(t)}
+		v(&c.Auth)
+		a.Error(c.Validate())
+	}
+}
+
+func TestAuthenticator_Session(t *testing.T) {
+	a := assert.New(t)
+	auth := newTestAuthenticator(t)
+
+	_, err := auth.login("admin", "wrong")
+	a.Equal(errUnauthenticated, err)
+	_, err = auth.login("unknown", "admin_pwd")
+	a.Equal(errUnauthenticated, err)
+	id, err := auth.login("admin", "admin_pwd")
+	a.NoError(err)
+	a.Equal(RoleAdmin, id.role)
+
+	value, expiry, err := auth.newSession(id.name)
+	a.NoError(err)
+	a.WithinDuration(time.Now().Add(time.Hour), expiry, time.Minute)
+	id, err = auth.verifySession(value)
+	a.NoError(err)
+	a.Equal("admin", id.name)
+	a.True(id.session)
+
+	// tampered
+	_, err = auth.verifySession("x" + value)
+	a.Equal(errUnauthenticated, err)
+	_, err = auth.verifySession(strings.Split(value, ".")[0])
+	a.Equal(errUnauthenticated, err)
+
+	csrf := auth.csrfToken(value)
+	a.True(auth.verifyCSRFToken(value, csrf))
+	a.False(auth.verifyCSRFToken(value, ""))
+	value2, _, err := auth.newSession("admin")
+	a.NoError(err)
+	a.False(auth.verifyCSRFToken(value2, csrf))
+
+	// expired
+	auth.now = func() time.Time {
+		return time.Now().Add(2 * time.Hour)
+	}
+	_, err = auth.verifySession(value)
+	a.Equal(errUnauthenticated, err)
+
+	// signed by another secret
+	auth2 := newTestAuthenticator(t)
+	auth2.secret = []byte("another secret key")
+	_, err = auth2.verifySession(value2)
+	a.Equal(errUnauthenticated, err)
+}
+
+func TestAuthenticator_UnaryInterceptor(t *testing.T) {
+	a := assert.New(t)
+	auth := newTestAuthenticator(t)
+	viewerSession, _, err := auth.newSession("viewer")
+	a.NoError(err)
+
+	var tt = []struct {
+		name   string
+		md     metadata.MD
+		method string
+		code   codes.Code
+		id     string
+	}{
+		{name: "no_credential", md: metadata.Pairs(), method: "/gmqtt.admin.api.ClientService/List", code: codes.Unauthenticated},
+		{name: "invalid_token", md: metadata.Pairs("authorization", "Bearer x"), method: "/gmqtt.admin.api.ClientService/List", code: codes.Unauthenticated},
+		{name: "basic_auth", md: metadata.Pairs("authorization", "Basic x"), method: "/gmqtt.admin.api.ClientService/List", code: codes.Unauthenticated},
+		{name: "operator_publish", md: metadata.Pairs("authorization", "Bearer ops_token"), method: "/gmqtt.admin.api.PublishService/Publish", code: codes.OK, id: "ops"},
+		{name: "operator_purge", md: metadata.Pairs("authorization", "bearer ops_token"), method: "/gmqtt.admin.api.QueueService/Purge", code: codes.PermissionDenied},
+		{name: "operator_unknown_method", md: metadata.Pairs("authorization", "Bearer ops_token"), method: "/some.Service/Method", code: codes.PermissionDenied},
+		{name: "viewer_list", md: metadata.Pairs(cookieMetadataKey, sessionCookieName+"="+viewerSession), method: "/gmqtt.admin.api.ClientService/List", code: codes.OK, id: "viewer"},
+		{name: "viewer_delete", md: metadata.Pairs(cookieMetadataKey, sessionCookieName+"="+viewerSession), method: "/gmqtt.admin.api.ClientService/Delete", code: codes.PermissionDenied},
+		{name: "viewer_list_keys", md: metadata.Pairs(cookieMetadataKey, sessionCookieName+"="+viewerSession), method: "/gmqtt.federation.api.Membership/ListKeys", code: codes.PermissionDenied},
+		{name: "operator_list_keys", md: metadata.Pairs("authorization", "Bearer ops_token"), method: "/gmqtt.federation.api.Membership/ListKeys", code: codes.PermissionDenied},
+		// the cookie is ignored if the authorization header is present
+		{name: "token_precedes_cookie", md: metadata.Pairs("authorization", "Bearer x", cookieMetadataKey, sessionCookieName+"="+viewerSession), method: "/gmqtt.admin.api.ClientService/List", code: codes.Unauthenticated},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			a := assert.New(t)
+			ctx := metadata.NewIncomingContext(context.Background(), v.md)
+			_, err := auth.unaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: v.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				id, ok := identityFromContext(ctx)
+				a.True(ok)
+				a.Equal(v.id, id.name)
+				return nil, nil
+			})
+			a.Equal(v.code, status.Code(err))
+		})
+	}
+}
+
+func TestAuthenticator_HTTPMiddleware(t *testing.T) {
+	a := assert.New(t)
+	auth := newTestAuthenticator(t)
+	value, _, err := auth.newSession("admin")
+	a.NoError(err)
+	cookie := &http.Cookie{Name: sessionCookieName, Value: value}
+	h := auth.httpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(r *http.Request) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// the web UI is not protected by the middleware
+	a.Equal(http.StatusOK, serve(httptest.NewRequest(http.MethodGet, "/", nil)))
+	a.Equal(http.StatusUnauthorized, serve(httptest.NewRequest(http.MethodGet, "/v1/clients", nil)))
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/clients", nil)
+	r.AddCookie(cookie)
+	a.Equal(http.StatusOK, serve(r))
+
+	r = httptest.NewRequest(http.MethodPost, "/v1/publish", nil)
+	r.AddCookie(cookie)
+	a.Equal(http.StatusForbidden, serve(r))
+
+	r = httptest.NewRequest(http.MethodPost, "/v1/publish", nil)
+	r.AddCookie(cookie)
+	r.Header.Set(csrfHeader, auth.csrfToken(value))
+	a.Equal(http.StatusOK, serve(r))
+
+	// bearer tokens do not need csrf token
+	r = httptest.NewRequest(http.MethodPost, "/v1/publish", nil)
+	r.Header.Set("Authorization", "Bearer ops_token")
+	a.Equal(http.StatusOK, serve(r))
+}
+
+func TestWebUI_LoginAndLogout(t *testing.T) {
+	a := assert.New(t)
+	ui := &webUI{auth: newTestAuthenticator(t)}
+
+	login := func(username, password string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {username}, "password": {password}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		ui.handleLogin(rec, r, nil)
+		return rec
+	}
+	rec := login("admin", "wrong")
+	a.Equal(http.StatusSeeOther, rec.Code)
+	a.Equal("/?error=credentials", rec.Header().Get("Location"))
+	a.Empty(rec.Result().Cookies())
+
+	rec = login("admin", "admin_pwd")
+	a.Equal("/dashboard", rec.Header().Get("Location"))
+	cookies := rec.Result().Cookies()
+	a.Len(cookies, 1)
+	a.True(cookies[0].HttpOnly)
+
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	r.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	ui.serveDashboardPage(rec, r, nil)
+	a.Equal(http.StatusOK, rec.Code)
+	a.Contains(rec.Body.String(), ui.auth.csrfToken(cookies[0].Value))
+
+	rec = httptest.NewRecorder()
+	ui.serveDashboardPage(rec, httptest.NewRequest(http.MethodGet, "/dashboard", nil), nil)
+	a.Equal(http.StatusSeeOther, rec.Code)
+
+	// logout requires the csrf token
+	r = httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	ui.handleLogout(rec, r, nil)
+	a.Equal(http.StatusForbidden, rec.Code)
+
+	form := url.Values{csrfFormField: {ui.auth.csrfToken(cookies[0].Value)}}
+	r = httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	ui.handleLogout(rec, r, nil)
+	a.Equal(http.StatusSeeOther, rec.Code)
+	a.Equal(-1, rec.Result().Cookies()[0].MaxAge)
+}
+
+func TestReadOnlyInterceptor(t *testing.T) {
+	a := assert.New(t)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+	_, err := readOnlyUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/gmqtt.admin.api.ClientService/List"}, handler)
+	a.NoError(err)
+	for _, v := range []string{
+		"/gmqtt.admin.api.PublishService/Publish",
+		"/gmqtt.admin.api.ClientService/Delete",
+		"/gmqtt.admin.api.QueueService/Purge",
+		"/gmqtt.admin.api.Unknown/Method",
+	} {
+		_, err = readOnlyUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: v}, handler)
+		a.Equal(codes.PermissionDenied, status.Code(err), v)
+	}
+	// the methods of other plugins are not restricted.
+	for _, v := range []string{
+		"/gmqtt.auth.api.AccountService/Add",
+		"/gmqtt.federation.api.Membership/Join",
+		"/some.Service/Method",
+	} {
+		_, err = readOnlyUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: v}, handler)
+		a.NoError(err, v)
+	}
+}
+
+func TestWebUI_AuthDisabled(t *testing.T) {
+	a := assert.New(t)
+	ui := &webUI{}
+	for _, v := range []func(w http.ResponseWriter, r *http.Request, _ map[string]string){
+		ui.serveLoginPage, ui.serveDashboardPage, ui.handleLogin, ui.handleLogout,
+	} {
+		rec := httptest.NewRecorder()
+		v(rec, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+		a.Equal(http.StatusForbidden, rec.Code)
+	}
+}
@@ -2,8 +2,13 @@ package admin
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type clientService struct {
@@ -17,6 +22,9 @@ func (c *clientService) mustEmbedUnimplementedClientServiceServer() {
 // List lists clients information which the session is valid in the broker (both connected and disconnected).
 func (c *clientService) List(ctx context.Context, req *ListClientRequest) (*ListClientResponse, error) {
 	page, pageSize := GetPage(req.Page, req.PageSize)
+	if req.Cluster {
+		return c.clusterList(ctx, page, pageSize)
+	}
 	clients, total, err := c.a.store.GetClients(page, pageSize)
 	if err != nil {
 		return &ListClientResponse{}, err
@@ -32,6 +40,9 @@ func (c *clientService) Get(ctx context.Context, req *GetClientRequest) (*GetCli
 	if req.ClientId == "" {
 		return nil, ErrInvalidArgument("client_id", "")
 	}
+	if req.Cluster {
+		return c.clusterGet(ctx, req)
+	}
 	client := c.a.store.GetClientByID(req.ClientId)
 	if client == nil {
 		return nil, ErrNotFound
@@ -46,6 +57,12 @@ func (c *clientService) Delete(ctx context.Context, req *DeleteClientRequest) (*
 	if req.ClientId == "" {
 		return nil, ErrInvalidArgument("client_id", "")
 	}
+	if req.Cluster {
+		if c.a.cluster == nil {
+			return nil, ErrClusterNotEnabled
+		}
+		return &empty.Empty{}, c.a.clusterCall(ctx, clusterDeleteClient, req)
+	}
 	if req.CleanSession {
 		c.a.clientService.TerminateSession(req.ClientId)
 	} else {
@@ -56,3 +73,50 @@ func (c *clientService) Delete(ctx context.Context, req *DeleteClientRequest) (*
 	}
 	return &empty.Empty{}, nil
 }
+
+func (c *clientService) clusterList(ctx context.Context, page, pageSize uint) (*ListClientResponse, error) {
+	if c.a.cluster == nil {
+		return nil, ErrClusterNotEnabled
+	}
+	rs, total, nodeErrors := c.a.clusterList(ctx, clusterListClients, page, pageSize, func() proto.Message {
+		return &ListClientResponse{}
+	})
+	resp := &ListClientResponse{
+		Clients:    make([]*Client, 0),
+		TotalCount: total,
+		NodeErrors: nodeErrors,
+	}
+	for _, v := range rs {
+		for _, client := range v.resp.(*ListClientResponse).Clients {
+			client.NodeName = v.nodeName
+			resp.Clients = append(resp.Clients, client)
+		}
+	}
+	return resp, nil
+}
+
+// clusterGet finds the client on all nodes, the client on the first node in the order of the node name is returned.
+func (c *clientService) clusterGet(ctx context.Context, req *GetClientRequest) (*GetClientResponse, error) {
+	if c.a.cluster == nil {
+		return nil, ErrClusterNotEnabled
+	}
+	var errs []string
+	for _, v := range c.a.callNodes(ctx, c.a.cluster.Nodes(), clusterGetClient, func(nodeName string) proto.Message {
+		return req
+	}, func() proto.Message {
+		return &GetClientResponse{}
+	}) {
+		if v.err == nil {
+			resp := v.resp.(*GetClientResponse)
+			resp.Client.NodeName = v.nodeName
+			return resp, nil
+		}
+		if status.Code(v.err) != codes.NotFound {
+			errs = append(errs, fmt.Sprintf("%s: %s", v.nodeName, v.err.Error()))
+		}
+	}
+	if len(errs) != 0 {
+		return nil, status.Errorf(codes.Unavailable, "client not found, failed on nodes: %s", strings.Join(errs, "; "))
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,255 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// The methods registered to the cluster, which are called by the cluster-wide operations of other nodes.
+const (
+	clusterListClients       = "admin.ListClients"
+	clusterGetClient         = "admin.GetClient"
+	clusterDeleteClient      = "admin.DeleteClient"
+	clusterListSubscriptions = "admin.ListSubscriptions"
+	clusterUnsubscribe       = "admin.Unsubscribe"
+)
+
+// clusterCallTimeout is the timeout of calling a node in the cluster-wide operations.
+const clusterCallTimeout = 5 * time.Second
+
+// ErrClusterNotEnabled is returned when requesting the cluster-wide operations without the federation plugin.
+var ErrClusterNotEnabled = status.Error(codes.FailedPrecondition, "cluster-wide operation requires the federation plugin")
+
+// listResponse is implemented by ListClientResponse and ListSubscriptionResponse.
+type listResponse interface {
+	proto.Message
+	GetTotalCount() uint32
+}
+
+// nodeResult is the result of calling the method on a node.
+type nodeResult struct {
+	nodeName string
+	resp     proto.Message
+	err      error
+}
+
+// findCluster returns the plugin which implements server.Cluster, it returns nil if not found.
+func findCluster(plugins []server.Plugin) server.Cluster {
+	for _, v := range plugins {
+		if c, ok := v.(server.Cluster); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// clusterHandler adapts fn to server.ClusterHandler, newReq returns the message to unmarshal the request into.
+func clusterHandler(newReq func() proto.Message, fn func(ctx context.Context, req proto.Message) (proto.Message, error)) server.ClusterHandler {
+	return func(ctx context.Context, b []byte) ([]byte, error) {
+		req := newReq()
+		if err := proto.Unmarshal(b, req); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		resp, err := fn(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(resp)
+	}
+}
+
+// registerClusterHandlers registers the handlers of the cluster-wide operations.
+// The cluster field of the requests from other nodes is ignored, so the handlers only operate on the local node.
+func (a *Admin) registerClusterHandlers(c *clientService, s *subscriptionService) {
+	a.cluster.RegisterClusterHandler(clusterListClients, clusterHandler(func() proto.Message {
+		return &ListRange{}
+	}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		r := req.(*ListRange)
+		clients, total, err := a.store.getClientRange(uint(r.Offset), uint(r.Limit))
+		if err != nil {
+			return nil, err
+		}
+		return &ListClientResponse{
+			Clients:    clients,
+			TotalCount: total,
+		}, nil
+	}))
+	a.cluster.RegisterClusterHandler(clusterGetClient, clusterHandler(func() proto.Message {
+		return &GetClientRequest{}
+	}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		r := req.(*GetClientRequest)
+		r.Cluster = false
+		return c.Get(ctx, r)
+	}))
+	a.cluster.RegisterClusterHandler(clusterDeleteClient, clusterHandler(func() proto.Message {
+		return &DeleteClientRequest{}
+	}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		r := req.(*DeleteClientRequest)
+		r.Cluster = false
+		resp, err := c.Delete(ctx, r)
+		a.auditClusterCall(ctx, "/gmqtt.admin.api.ClientService/Delete", r, err)
+		return resp, err
+	}))
+	a.cluster.RegisterClusterHandler(clusterListSubscriptions, clusterHandler(func() proto.Message {
+		return &ListRange{}
+	}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		r := req.(*ListRange)
+		subs, total, err := a.store.getSubscriptionRange(uint(r.Offset), uint(r.Limit))
+		if err != nil {
+			return nil, err
+		}
+		return &ListSubscriptionResponse{
+			Subscriptions: subs,
+			TotalCount:    total,
+		}, nil
+	}))
+	a.cluster.RegisterClusterHandler(clusterUnsubscribe, clusterHandler(func() proto.Message {
+		return &UnsubscribeRequest{}
+	}, func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		r := req.(*UnsubscribeRequest)
+		r.Cluster = false
+		resp, err := s.Unsubscribe(ctx, r)
+		a.auditClusterCall(ctx, "/gmqtt.admin.api.SubscriptionService/Unsubscribe", r, err)
+		return resp, err
+	}))
+}
+
+// auditClusterCall records the operation requested by another node, with the admin identity forwarded by that node.
+// The operations on the local node are recorded by the audit interceptor of the API.
+func (a *Admin) auditClusterCall(ctx context.Context, fullMethod string, req interface{}, err error) {
+	nodeName := server.ClusterCallerFromContext(ctx)
+	if nodeName == "" {
+		return
+	}
+	r := auditRecord(ctx, fullMethod, req, err)
+	if r.Details == nil {
+		r.Details = make(map[string]string)
+	}
+	r.Details["origin_node"] = nodeName
+	a.auditLogger.Audit(r)
+}
+
+// callNodes calls the method on the nodes concurrently and returns the results in the order of the nodes.
+// reqFn returns the request for the node, the node is skipped if it returns nil.
+func (a *Admin) callNodes(ctx context.Context, nodes []string, method string, reqFn func(nodeName string) proto.Message, newResp func() proto.Message) []*nodeResult {
+	rs := make([]*nodeResult, 0, len(nodes))
+	var wg sync.WaitGroup
+	for _, v := range nodes {
+		req := reqFn(v)
+		if req == nil {
+			continue
+		}
+		r := &nodeResult{nodeName: v}
+		rs = append(rs, r)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.err = a.callNode(ctx, r.nodeName, method, req, func() proto.Message {
+				r.resp = newResp()
+				return r.resp
+			})
+		}()
+	}
+	wg.Wait()
+	return rs
+}
+
+func (a *Admin) callNode(ctx context.Context, nodeName string, method string, req proto.Message, newResp func() proto.Message) error {
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, clusterCallTimeout)
+	defer cancel()
+	b, err = a.cluster.CallNode(ctx, nodeName, method, b)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, newResp())
+}
+
+// clusterList returns the page of the cluster-wide list, in which the rows of the nodes are concatenated in the order of the node name.
+// It counts the rows on each node at first, and then only fetches the rows in the page from the nodes which hold them.
+// The returned responses are in the order of the nodes, and the nodes which failed to respond are returned in nodeErrors.
+func (a *Admin) clusterList(ctx context.Context, method string, page, pageSize uint, newResp func() proto.Message) (rs []*nodeResult, total uint32, nodeErrors map[string]string) {
+	nodeErrors = make(map[string]string)
+	counts := make(map[string]uint)
+	var nodes []string
+	for _, v := range a.callNodes(ctx, a.cluster.Nodes(), method, func(nodeName string) proto.Message {
+		return &ListRange{}
+	}, newResp) {
+		if v.err != nil {
+			nodeErrors[v.nodeName] = v.err.Error()
+			continue
+		}
+		nodes = append(nodes, v.nodeName)
+		counts[v.nodeName] = uint(v.resp.(listResponse).GetTotalCount())
+	}
+	offset, n := GetOffsetN(page, pageSize)
+	ranges := make(map[string]*ListRange)
+	var start uint
+	for _, v := range nodes {
+		lo, hi := offset, offset+n
+		if lo < start {
+			lo = start
+		}
+		if end := start + counts[v]; hi > end {
+			hi = end
+		}
+		if lo < hi {
+			ranges[v] = &ListRange{
+				Offset: uint32(lo - start),
+				Limit:  uint32(hi - lo),
+			}
+		}
+		start += counts[v]
+	}
+	for _, v := range a.callNodes(ctx, nodes, method, func(nodeName string) proto.Message {
+		if r, ok := ranges[nodeName]; ok {
+			return r
+		}
+		return nil
+	}, newResp) {
+		if v.err != nil {
+			nodeErrors[v.nodeName] = v.err.Error()
+			continue
+		}
+		rs = append(rs, v)
+	}
+	for _, v := range nodes {
+		if _, ok := nodeErrors[v]; !ok {
+			total += uint32(counts[v])
+		}
+	}
+	return rs, total, nodeErrors
+}
+
+// clusterCall calls the method on all nodes, it returns an error if any node failed.
+func (a *Admin) clusterCall(ctx context.Context, method string, req proto.Message) error {
+	var errs []string
+	for _, v := range a.callNodes(ctx, a.cluster.Nodes(), method, func(nodeName string) proto.Message {
+		return req
+	}, func() proto.Message {
+		return &empty.Empty{}
+	}) {
+		if v.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", v.nodeName, v.err.Error()))
+		}
+	}
+	if len(errs) != 0 {
+		sort.Strings(errs)
+		return status.Errorf(codes.Unavailable, "failed on nodes: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// testCluster routes the calls to the handlers registered by the admin of each node.
+type testCluster struct {
+	nodeName string
+	handlers map[string]map[string]server.ClusterHandler
+	// failed is the nodes which fail to respond.
+	failed map[string]bool
+}
+
+func (c *testCluster) NodeName() string {
+	return c.nodeName
+}
+
+func (c *testCluster) Nodes() []string {
+	var nodes []string
+	for k := range c.handlers {
+		nodes = append(nodes, k)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func (c *testCluster) RegisterClusterHandler(method string, handler server.ClusterHandler) {
+	if c.handlers[c.nodeName] == nil {
+		c.handlers[c.nodeName] = make(map[string]server.ClusterHandler)
+	}
+	c.handlers[c.nodeName][method] = handler
+}
+
+func (c *testCluster) CallNode(ctx context.Context, nodeName string, method string, req []byte) ([]byte, error) {
+	if c.failed[nodeName] {
+		return nil, errors.New("unavailable")
+	}
+	if nodeName != c.nodeName {
+		ctx = server.WithClusterCaller(ctx, c.nodeName)
+	}
+	return c.handlers[nodeName][method](ctx, req)
+}
+
+type testNode struct {
+	admin *Admin
+	cs    *server.MockClientService
+	ss    *server.MockSubscriptionService
+	c     *clientService
+	s     *subscriptionService
+	audit *testAuditLogger
+}
+
+// newTestNodes creates the admin of the nodes which share the handlers.
+func newTestNodes(ctrl *gomock.Controller, nodes ...string) (map[string]*testNode, map[string]bool) {
+	handlers := make(map[string]map[string]server.ClusterHandler)
+	failed := make(map[string]bool)
+	rs := make(map[string]*testNode)
+	for _, v := range nodes {
+		sr := server.NewMockStatsReader(ctrl)
+		sr.EXPECT().GetClientStats(gomock.Any()).AnyTimes()
+		n := &testNode{
+			cs:    server.NewMockClientService(ctrl),
+			ss:    server.NewMockSubscriptionService(ctrl),
+			audit: &testAuditLogger{},
+		}
+		n.admin = &Admin{
+			statsReader:   sr,
+			clientService: n.cs,
+			store:         newStore(sr, mockConfig),
+			auditLogger:   n.audit,
+			cluster: &testCluster{
+				nodeName: v,
+				handlers: handlers,
+				failed:   failed,
+			},
+		}
+		n.admin.store.subscriptionService = n.ss
+		n.c = &clientService{a: n.admin}
+		n.s = &subscriptionService{a: n.admin}
+		n.admin.registerClusterHandlers(n.c, n.s)
+		rs[v] = n
+	}
+	return rs, failed
+}
+
+func TestClientService_Cluster(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes, failed := newTestNodes(ctrl, "node1", "node2", "node3")
+	// node1: 0,1,2 node2: none node3: 3,4
+	for i := 0; i < 5; i++ {
+		n := nodes["node1"]
+		if i > 2 {
+			n = nodes["node3"]
+		}
+		n.admin.store.clientIndexer.Set(strconv.Itoa(i), &Client{ClientId: strconv.Itoa(i)})
+	}
+	c := nodes["node2"].c
+
+	resp, err := c.List(context.Background(), &ListClientRequest{Cluster: true})
+	a.NoError(err)
+	a.EqualValues(5, resp.TotalCount)
+	a.Len(resp.Clients, 5)
+	for k, v := range resp.Clients {
+		a.Equal(strconv.Itoa(k), v.ClientId)
+	}
+	a.Equal("node1", resp.Clients[0].NodeName)
+	a.Equal("node3", resp.Clients[4].NodeName)
+	// the clients in the store are not modified.
+	a.Equal("", nodes["node1"].admin.store.GetClientByID("0").NodeName)
+
+	// the page spans node1 and node3.
+	resp, err = c.List(context.Background(), &ListClientRequest{Cluster: true, Page: 2, PageSize: 2})
+	a.NoError(err)
+	a.Len(resp.Clients, 2)
+	a.Equal("2", resp.Clients[0].ClientId)
+	a.Equal("node1", resp.Clients[0].NodeName)
+	a.Equal("3", resp.Clients[1].ClientId)
+	a.Equal("node3", resp.Clients[1].NodeName)
+
+	resp, err = c.List(context.Background(), &ListClientRequest{Cluster: true, Page: 4, PageSize: 2})
+	a.NoError(err)
+	a.Len(resp.Clients, 0)
+	a.EqualValues(5, resp.TotalCount)
+
+	failed["node1"] = true
+	resp, err = c.List(context.Background(), &ListClientRequest{Cluster: true})
+	a.NoError(err)
+	a.EqualValues(2, resp.TotalCount)
+	a.Len(resp.Clients, 2)
+	a.Contains(resp.NodeErrors, "node1")
+
+	getResp, err := c.Get(context.Background(), &GetClientRequest{ClientId: "3", Cluster: true})
+	a.NoError(err)
+	a.Equal("node3", getResp.Client.NodeName)
+	_, err = c.Get(context.Background(), &GetClientRequest{ClientId: "0", Cluster: true})
+	a.Equal(codes.Unavailable, status.Code(err))
+	delete(failed, "node1")
+	_, err = c.Get(context.Background(), &GetClientRequest{ClientId: "5", Cluster: true})
+	a.Equal(ErrNotFound, err)
+
+	for _, v := range nodes {
+		v.cs.EXPECT().TerminateSession("0")
+	}
+	_, err = c.Delete(server.WithAuditActor(context.Background(), "admin"), &DeleteClientRequest{ClientId: "0", CleanSession: true, Cluster: true})
+	a.NoError(err)
+	// the operations requested by node2 are recorded on the other nodes with the forwarded actor.
+	a.Len(nodes["node2"].audit.records, 0)
+	for _, v := range []string{"node1", "node3"} {
+		a.Equal([]*server.AuditRecord{{
+			Actor:     "admin",
+			Operation: "admin.client.delete",
+			Target:    "0",
+			Details:   map[string]string{"origin_node": "node2"},
+		}}, nodes[v].audit.records)
+	}
+
+	failed["node3"] = true
+	nodes["node1"].cs.EXPECT().GetClient("0").Return(nil)
+	nodes["node2"].cs.EXPECT().GetClient("0").Return(nil)
+	_, err = c.Delete(context.Background(), &DeleteClientRequest{ClientId: "0", Cluster: true})
+	a.Equal(codes.Unavailable, status.Code(err))
+
+	// without the federation plugin
+	admin := &Admin{}
+	_, err = (&clientService{a: admin}).List(context.Background(), &ListClientRequest{Cluster: true})
+	a.Equal(ErrClusterNotEnabled, err)
+	_, err = (&clientService{a: admin}).Delete(context.Background(), &DeleteClientRequest{ClientId: "0", Cluster: true})
+	a.Equal(ErrClusterNotEnabled, err)
+}
+
+func TestSubscriptionService_Cluster(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nodes, _ := newTestNodes(ctrl, "node1", "node2")
+	nodes["node1"].admin.store.addSubscription("c1", &gmqtt.Subscription{TopicFilter: "a"})
+	nodes["node2"].admin.store.addSubscription("c2", &gmqtt.Subscription{TopicFilter: "b"})
+	nodes["node2"].admin.store.addSubscription("c2", &gmqtt.Subscription{TopicFilter: "c"})
+	s := nodes["node1"].s
+
+	resp, err := s.List(context.Background(), &ListSubscriptionRequest{Cluster: true, Page: 1, PageSize: 2})
+	a.NoError(err)
+	a.EqualValues(3, resp.TotalCount)
+	a.Len(resp.Subscriptions, 2)
+	a.Equal("a", resp.Subscriptions[0].TopicName)
+	a.Equal("node1", resp.Subscriptions[0].NodeName)
+	a.Equal("b", resp.Subscriptions[1].TopicName)
+	a.Equal("node2", resp.Subscriptions[1].NodeName)
+
+	for _, v := range nodes {
+		v.ss.EXPECT().Unsubscribe("c2", "b")
+	}
+	_, err = s.Unsubscribe(context.Background(), &UnsubscribeRequest{ClientId: "c2", Topics: []string{"b"}, Cluster: true})
+	a.NoError(err)
+	a.Len(nodes["node1"].audit.records, 0)
+	a.Len(nodes["node2"].audit.records, 1)
+	a.Equal("admin.subscription.unsubscribe", nodes["node2"].audit.records[0].Operation)
+	a.Equal("node1", nodes["node2"].audit.records[0].Details["origin_node"])
+
+	_, err = (&subscriptionService{a: &Admin{}}).Unsubscribe(context.Background(), &UnsubscribeRequest{ClientId: "c2", Topics: []string{"b"}, Cluster: true})
+	a.Equal(ErrClusterNotEnabled, err)
+}
@@ -1,78 +1,127 @@
 package admin
 
 import (
+	"encoding/hex"
 	"errors"
-	"net"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Config is the configuration for the admin plugin.
+// The listen addresses of the gRPC and HTTP servers are configured in the api section.
 type Config struct {
-	HTTP HTTPConfig `yaml:"http"`
-	GRPC GRPCConfig `yaml:"grpc"`
+	Auth AuthConfig `yaml:"auth"`
 }
 
-// HTTPConfig is the configuration for http endpoint.
-type HTTPConfig struct {
-	// Enable indicates whether to expose http endpoint.
+// AuthConfig is the authentication configuration for the admin APIs and web UI.
+type AuthConfig struct {
+	// Enable indicates whether to enable the authentication.
 	Enable bool `yaml:"enable"`
-	// Addr is the address that the http server listen on.
-	Addr string `yaml:"http_addr"`
+	// SessionSecret is the secret to sign the session cookies of the web UI.
+	// If it is empty, a random secret is generated on start, which invalidates all sessions after restart.
+	SessionSecret string `yaml:"session_secret"`
+	// SessionExpiry is the lifetime of the session cookies.
+	SessionExpiry time.Duration `yaml:"session_expiry"`
+	// SecureCookie indicates whether to set the Secure attribute of the session cookies,
+	// it should be enabled if the web UI is served over https.
+	SecureCookie bool `yaml:"secure_cookie"`
+	// Users is the users who can login to the web UI.
+	Users []*UserConfig `yaml:"users"`
+	// Tokens is the bearer tokens for the API clients.
+	Tokens []*TokenConfig `yaml:"tokens"`
+}
+
+// UserConfig is the configuration of an admin user.
+type UserConfig struct {
+	Username string `yaml:"username"`
+	// Password is the bcrypt hash of the password.
+	Password string `yaml:"password"`
+	Role     Role   `yaml:"role"`
 }
 
-// GRPCConfig is the configuration for gRPC endpoint.
-type GRPCConfig struct {
-	// Addr is the address that the gRPC server listen on.
-	Addr string `yaml:"http_addr"`
+// TokenConfig is the configuration of an API bearer token.
+type TokenConfig struct {
+	// Name identifies the token in the logs.
+	Name string `yaml:"name"`
+	// TokenSHA256 is the hex encoded sha256 hash of the token.
+	TokenSHA256 string `yaml:"token_sha256"`
+	Role        Role   `yaml:"role"`
 }
 
 // Validate validates the configuration, and return an error if it is invalid.
 func (c *Config) Validate() error {
-	if c.HTTP.Enable {
-		_, _, err := net.SplitHostPort(c.HTTP.Addr)
-		if err != nil {
-			return errors.New("invalid http_addr")
+	a := c.Auth
+	if !a.Enable {
+		return nil
+	}
+	if len(a.Users) == 0 && len(a.Tokens) == 0 {
+		return errors.New("at least one user or token must be set when auth is enabled")
+	}
+	if a.SessionSecret != "" && len(a.SessionSecret) < 16 {
+		return errors.New("session_secret must be at least 16 characters")
+	}
+	if a.SessionExpiry <= 0 {
+		return errors.New("invalid session_expiry")
+	}
+	users := make(map[string]struct{})
+	for _, v := range a.Users {
+		if v.Username == "" {
+			return errors.New("username must be set")
+		}
+		if _, ok := users[v.Username]; ok {
+			return fmt.Errorf("duplicated username: %s", v.Username)
+		}
+		users[v.Username] = struct{}{}
+		if _, err := bcrypt.Cost([]byte(v.Password)); err != nil {
+			return fmt.Errorf("invalid bcrypt password hash of user %s: %s", v.Username, err)
+		}
+		if !v.Role.valid() {
+			return fmt.Errorf("invalid role of user %s: %s", v.Username, v.Role)
 		}
 	}
-	_, _, err := net.SplitHostPort(c.GRPC.Addr)
-	if err != nil {
-		return errors.New("invalid grpc_addr")
+	tokens := make(map[string]struct{})
+	for _, v := range a.Tokens {
+		if v.Name == "" {
+			return errors.New("token name must be set")
+		}
+		if _, ok := tokens[v.Name]; ok {
+			return fmt.Errorf("duplicated token name: %s", v.Name)
+		}
+		tokens[v.Name] = struct{}{}
+		if b, err := hex.DecodeString(v.TokenSHA256); err != nil || len(b) != 32 {
+			return fmt.Errorf("invalid token_sha256 of token %s", v.Name)
+		}
+		if !v.Role.valid() {
+			return fmt.Errorf("invalid role of token %s: %s", v.Name, v.Role)
+		}
 	}
 	return nil
 }
 
 // DefaultConfig is the default configuration.
 var DefaultConfig = Config{
-	HTTP: HTTPConfig{
-		Enable: true,
-		Addr:   "127.0.0.1:8083",
-	},
-	GRPC: GRPCConfig{
-		Addr: "unix://./gmqttd.sock",
+	Auth: AuthConfig{
+		Enable:        false,
+		SessionExpiry: 12 * time.Hour,
 	},
 }
 
 func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type cfg Config
+	df := cfg(DefaultConfig)
 	var v = &struct {
-		Admin cfg `yaml:"admin"`
+		Admin *cfg `yaml:"admin"`
 	}{
-		Admin: cfg(DefaultConfig),
+		Admin: &df,
 	}
 	if err := unmarshal(v); err != nil {
 		return err
 	}
-	emptyGRPC := GRPCConfig{}
-	if v.Admin.GRPC == emptyGRPC {
-		v.Admin.GRPC = DefaultConfig.GRPC
-	}
-	emptyHTTP := HTTPConfig{}
-	if v.Admin.HTTP == emptyHTTP {
-		v.Admin.HTTP = DefaultConfig.HTTP
-	}
-	empty := cfg(Config{})
-	if v.Admin == empty {
-		v.Admin = cfg(DefaultConfig)
+	if v.Admin == nil {
+		v.Admin = &df
 	}
-	*c = Config(v.Admin)
+	*c = Config(*v.Admin)
 	return nil
 }
@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+const (
+	// commandTopicPrefix is the prefix of the command topic: devices/command/{device_number}/{message_id}
+	commandTopicPrefix = "devices/command/"
+	// responseTopicPrefix is the prefix of the response topic: devices/command/response/{message_id}
+	responseTopicPrefix = "devices/command/response/"
+	defaultCallTimeout  = 10 * time.Second
+	maxCallTimeout      = 300 * time.Second
+)
+
+var (
+	// ErrDeviceOffline is returned when the device is offline and the offline policy is fail fast.
+	ErrDeviceOffline = status.Error(codes.FailedPrecondition, "device is offline")
+	// ErrCallTimeout is returned when the device does not respond in time.
+	ErrCallTimeout = status.Error(codes.DeadlineExceeded, "wait for response timeout")
+)
+
+// deviceRPC implements the DeviceRPCService.
+// The command is published to devices/command/{device_number}/{message_id}
+// with ResponseTopic = devices/command/response/{message_id} and CorrelationData = message_id.
+// The response is correlated by the message_id in the response topic, or by the CorrelationData for v5 devices.
+// Only the response from the client which subscribes the commands of the target device is accepted.
+type deviceRPC struct {
+	a  *Admin
+	mu sync.Mutex
+	// pending is the calls waiting for response, key by message id.
+	pending map[string]*pendingCall
+}
+
+// pendingCall is a call waiting for the response.
+type pendingCall struct {
+	deviceNumber string
+	ch           chan *gmqtt.Message
+}
+
+func newDeviceRPC(a *Admin) *deviceRPC {
+	return &deviceRPC{
+		a:       a,
+		pending: make(map[string]*pendingCall),
+	}
+}
+
+func (d *deviceRPC) mustEmbedUnimplementedDeviceRPCServiceServer() {
+	return
+}
+
+func validTopicLevel(s string) bool {
+	return s != "" && !strings.ContainsAny(s, "/+#")
+}
+
+// isOnline returns whether there is a connected client subscribing the commands of the device.
+func (d *deviceRPC) isOnline(deviceNumber, topic string) (online bool) {
+	prefix := commandTopicPrefix + deviceNumber + "/"
+	d.a.subscriptionService.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		if !strings.HasPrefix(sub.TopicFilter, prefix) {
+			return true
+		}
+		if d.a.clientService.GetClient(clientID) != nil {
+			online = true
+			return false
+		}
+		return true
+	}, subscription.IterationOptions{
+		Type:      subscription.TypeAll,
+		TopicName: topic,
+		MatchType: subscription.MatchFilter,
+	})
+	return online
+}
+
+// isDevice returns whether the client subscribes the commands of the device.
+func (d *deviceRPC) isDevice(clientID, deviceNumber string) (ok bool) {
+	prefix := commandTopicPrefix + deviceNumber + "/"
+	d.a.subscriptionService.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		if strings.HasPrefix(sub.TopicFilter, prefix) {
+			ok = true
+			return false
+		}
+		return true
+	}, subscription.IterationOptions{
+		Type:     subscription.TypeAll,
+		ClientID: clientID,
+	})
+	return ok
+}
+
+func (d *deviceRPC) register(messageID, deviceNumber string) (chan *gmqtt.Message, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.pending[messageID]; ok {
+		return nil, false
+	}
+	ch := make(chan *gmqtt.Message, 1)
+	d.pending[messageID] = &pendingCall{
+		deviceNumber: deviceNumber,
+		ch:           ch,
+	}
+	return ch, true
+}
+
+func (d *deviceRPC) unregister(messageID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, messageID)
+}
+
+// resolve delivers the message published by the client to the waiting call if it is a response.
+// The response is dropped if the client is not the target device of the call.
+func (d *deviceRPC) resolve(clientID string, msg *gmqtt.Message) {
+	d.mu.Lock()
+	if len(d.pending) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	call, ok := d.pending[string(msg.CorrelationData)]
+	if !ok && strings.HasPrefix(msg.Topic, responseTopicPrefix) {
+		call, ok = d.pending[msg.Topic[len(responseTopicPrefix):]]
+	}
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	if !d.isDevice(clientID, call.deviceNumber) {
+		log.Warn("response from the client which is not the target device is dropped",
+			zap.String("client_id", clientID),
+			zap.String("device_number", call.deviceNumber),
+			zap.String("topic", msg.Topic))
+		return
+	}
+	select {
+	case call.ch <- msg:
+	default:
+		// already responded
+	}
+}
+
+// Call publishes a command to the device and waits for the response.
+func (d *deviceRPC) Call(ctx context.Context, req *CallRequest) (*CallResponse, error) {
+	if !validTopicLevel(req.DeviceNumber) {
+		return nil, ErrInvalidArgument("device_number", "")
+	}
+	if req.Qos > uint32(packets.Qos2) {
+		return nil, ErrInvalidArgument("qos", "")
+	}
+	if req.OfflinePolicy == OfflinePolicy_OFFLINE_POLICY_QUEUE && req.Qos == uint32(packets.Qos0) {
+		return nil, ErrInvalidArgument("qos", "qos must be 1 or 2 when the offline policy is queue")
+	}
+	timeout := defaultCallTimeout
+	if req.Timeout != 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
+	}
+	if timeout > maxCallTimeout {
+		return nil, ErrInvalidArgument("timeout", "")
+	}
+	messageID := req.MessageId
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+	if !validTopicLevel(messageID) {
+		return nil, ErrInvalidArgument("message_id", "")
+	}
+	topic := commandTopicPrefix + req.DeviceNumber + "/" + messageID
+	if req.OfflinePolicy != OfflinePolicy_OFFLINE_POLICY_QUEUE && !d.isOnline(req.DeviceNumber, topic) {
+		return nil, ErrDeviceOffline
+	}
+	ch, ok := d.register(messageID, req.DeviceNumber)
+	if !ok {
+		return nil, status.Error(codes.AlreadyExists, "duplicated message_id")
+	}
+	defer d.unregister(messageID)
+
+	d.a.publisher.Publish(&gmqtt.Message{
+		QoS:             byte(req.Qos),
+		Topic:           topic,
+		Payload:         []byte(req.Payload),
+		ResponseTopic:   responseTopicPrefix + messageID,
+		CorrelationData: []byte(messageID),
+	})
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case msg := <-ch:
+		resp := &CallResponse{
+			MessageId:   messageID,
+			Topic:       msg.Topic,
+			Payload:     string(msg.Payload),
+			ContentType: msg.ContentType,
+		}
+		for _, v := range msg.UserProperties {
+			resp.UserProperties = append(resp.UserProperties, &UserProperties{
+				K: v.K,
+				V: v.V,
+			})
+		}
+		return resp, nil
+	case <-t.C:
+		return nil, ErrCallTimeout
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
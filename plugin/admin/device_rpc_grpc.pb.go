@@ -0,0 +1,99 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package admin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// DeviceRPCServiceClient is the client API for DeviceRPCService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DeviceRPCServiceClient interface {
+	// Call publishes a command to the device and waits for the response.
+	Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error)
+}
+
+type deviceRPCServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDeviceRPCServiceClient(cc grpc.ClientConnInterface) DeviceRPCServiceClient {
+	return &deviceRPCServiceClient{cc}
+}
+
+func (c *deviceRPCServiceClient) Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error) {
+	out := new(CallResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.DeviceRPCService/Call", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeviceRPCServiceServer is the server API for DeviceRPCService service.
+// All implementations must embed UnimplementedDeviceRPCServiceServer
+// for forward compatibility
+type DeviceRPCServiceServer interface {
+	// Call publishes a command to the device and waits for the response.
+	Call(context.Context, *CallRequest) (*CallResponse, error)
+	mustEmbedUnimplementedDeviceRPCServiceServer()
+}
+
+// UnimplementedDeviceRPCServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDeviceRPCServiceServer struct {
+}
+
+func (UnimplementedDeviceRPCServiceServer) Call(context.Context, *CallRequest) (*CallResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Call not implemented")
+}
+func (UnimplementedDeviceRPCServiceServer) mustEmbedUnimplementedDeviceRPCServiceServer() {}
+
+// UnsafeDeviceRPCServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DeviceRPCServiceServer will
+// result in compilation errors.
+type UnsafeDeviceRPCServiceServer interface {
+	mustEmbedUnimplementedDeviceRPCServiceServer()
+}
+
+func RegisterDeviceRPCServiceServer(s grpc.ServiceRegistrar, srv DeviceRPCServiceServer) {
+	s.RegisterService(&_DeviceRPCService_serviceDesc, srv)
+}
+
+func _DeviceRPCService_Call_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DeviceRPCServiceServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.DeviceRPCService/Call",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DeviceRPCServiceServer).Call(ctx, req.(*CallRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _DeviceRPCService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.DeviceRPCService",
+	HandlerType: (*DeviceRPCServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    _DeviceRPCService_Call_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "device_rpc.proto",
+}
@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type rpcMocks struct {
+	ctrl *gomock.Controller
+	pub  *server.MockPublisher
+	subs *server.MockSubscriptionService
+	cs   *server.MockClientService
+}
+
+func newTestDeviceRPC(ctrl *gomock.Controller) (*deviceRPC, *rpcMocks) {
+	m := &rpcMocks{
+		ctrl: ctrl,
+		pub:  server.NewMockPublisher(ctrl),
+		subs: server.NewMockSubscriptionService(ctrl),
+		cs:   server.NewMockClientService(ctrl),
+	}
+	a := &Admin{
+		publisher:           m.pub,
+		subscriptionService: m.subs,
+		clientService:       m.cs,
+	}
+	a.deviceRPC = newDeviceRPC(a)
+	return a.deviceRPC, m
+}
+
+// expectSubscriber mocks a subscriber of the device command topic.
+func (m *rpcMocks) expectSubscriber(clientID, topicFilter string, online bool) {
+	m.subs.EXPECT().Iterate(gomock.Any(), gomock.Any()).Do(func(fn subscription.IterateFn, options subscription.IterationOptions) {
+		fn(clientID, &gmqtt.Subscription{TopicFilter: topicFilter})
+	})
+	if online {
+		m.cs.EXPECT().GetClient(clientID).Return(server.NewMockClient(m.ctrl))
+	} else {
+		m.cs.EXPECT().GetClient(clientID).Return(nil).AnyTimes()
+	}
+}
+
+// expectDevice mocks the subscriptions of the client which responds to the command.
+func (m *rpcMocks) expectDevice(clientID, topicFilter string) {
+	m.subs.EXPECT().Iterate(gomock.Any(), subscription.IterationOptions{
+		Type:     subscription.TypeAll,
+		ClientID: clientID,
+	}).Do(func(fn subscription.IterateFn, options subscription.IterationOptions) {
+		fn(clientID, &gmqtt.Subscription{TopicFilter: topicFilter})
+	})
+}
+
+func TestDeviceRPC_Call(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, m := newTestDeviceRPC(ctrl)
+	m.expectSubscriber("dev1", "devices/command/dev1/+", true)
+	m.expectDevice("dev1", "devices/command/dev1/+")
+
+	m.pub.EXPECT().Publish(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		a.Equal("devices/command/dev1/m1", msg.Topic)
+		a.Equal("devices/command/response/m1", msg.ResponseTopic)
+		a.Equal([]byte("m1"), msg.CorrelationData)
+		a.EqualValues(1, msg.QoS)
+		go d.resolve("dev1", &gmqtt.Message{
+			Topic:   "devices/command/response/m1",
+			Payload: []byte("ok"),
+		})
+	})
+	resp, err := d.Call(context.Background(), &CallRequest{
+		DeviceNumber: "dev1",
+		MessageId:    "m1",
+		Payload:      "cmd",
+		Qos:          1,
+	})
+	a.Nil(err)
+	a.Equal(&CallResponse{
+		MessageId: "m1",
+		Topic:     "devices/command/response/m1",
+		Payload:   "ok",
+	}, resp)
+	a.Len(d.pending, 0)
+}
+
+func TestDeviceRPC_Call_CorrelationData(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, m := newTestDeviceRPC(ctrl)
+	m.expectSubscriber("dev1", "devices/command/dev1/+", true)
+	m.expectDevice("dev1", "devices/command/dev1/+")
+
+	var messageID string
+	m.pub.EXPECT().Publish(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		messageID = string(msg.CorrelationData)
+		go d.resolve("dev1", &gmqtt.Message{
+			Topic:           "other/topic",
+			Payload:         []byte("ok"),
+			CorrelationData: msg.CorrelationData,
+			ContentType:     "json",
+			UserProperties: []packets.UserProperty{
+				{K: []byte("k"), V: []byte("v")},
+			},
+		})
+	})
+	resp, err := d.Call(context.Background(), &CallRequest{
+		DeviceNumber: "dev1",
+		Payload:      "cmd",
+	})
+	a.Nil(err)
+	a.NotEmpty(messageID)
+	a.Equal(&CallResponse{
+		MessageId:   messageID,
+		Topic:       "other/topic",
+		Payload:     "ok",
+		ContentType: "json",
+		UserProperties: []*UserProperties{
+			{K: []byte("k"), V: []byte("v")},
+		},
+	}, resp)
+}
+
+func TestDeviceRPC_Call_ForgedResponse(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, m := newTestDeviceRPC(ctrl)
+	m.expectSubscriber("dev1", "devices/command/dev1/+", true)
+	// dev2 responds to the command of dev1.
+	m.expectDevice("dev2", "devices/command/dev2/+")
+
+	m.pub.EXPECT().Publish(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		d.resolve("dev2", &gmqtt.Message{
+			Topic:   "devices/command/response/m1",
+			Payload: []byte("forged"),
+		})
+	})
+	_, err := d.Call(context.Background(), &CallRequest{
+		DeviceNumber: "dev1",
+		MessageId:    "m1",
+		Timeout:      1,
+	})
+	a.Equal(ErrCallTimeout, err)
+}
+
+func TestDeviceRPC_Call_Offline(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, m := newTestDeviceRPC(ctrl)
+
+	// a wildcard subscriber of other client does not make the device online
+	m.expectSubscriber("platform", "devices/command/+/+", false)
+	_, err := d.Call(context.Background(), &CallRequest{
+		DeviceNumber: "dev1",
+	})
+	a.Equal(ErrDeviceOffline, err)
+
+	m.expectSubscriber("dev1", "devices/command/dev1/+", false)
+	_, err = d.Call(context.Background(), &CallRequest{
+		DeviceNumber:  "dev1",
+		OfflinePolicy: OfflinePolicy_OFFLINE_POLICY_FAIL_FAST,
+	})
+	a.Equal(ErrDeviceOffline, err)
+
+	// queue policy requires qos > 0
+	_, err = d.Call(context.Background(), &CallRequest{
+		DeviceNumber:  "dev1",
+		OfflinePolicy: OfflinePolicy_OFFLINE_POLICY_QUEUE,
+	})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	m.pub.EXPECT().Publish(gomock.Any())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = d.Call(ctx, &CallRequest{
+		DeviceNumber:  "dev1",
+		Qos:           1,
+		OfflinePolicy: OfflinePolicy_OFFLINE_POLICY_QUEUE,
+	})
+	a.Equal(codes.DeadlineExceeded, status.Code(err))
+	a.Len(d.pending, 0)
+}
+
+func TestDeviceRPC_Call_InvalidArgument(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, _ := newTestDeviceRPC(ctrl)
+	for _, v := range []*CallRequest{
+		{DeviceNumber: ""},
+		{DeviceNumber: "a/b"},
+		{DeviceNumber: "dev1", MessageId: "+"},
+		{DeviceNumber: "dev1", Qos: 3},
+		{DeviceNumber: "dev1", Timeout: 301},
+	} {
+		_, err := d.Call(context.Background(), v)
+		a.Equal(codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestDeviceRPC_Call_DuplicatedMessageID(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	d, m := newTestDeviceRPC(ctrl)
+	_, ok := d.register("m1", "dev1")
+	a.True(ok)
+	m.expectSubscriber("dev1", "devices/command/dev1/+", true)
+	_, err := d.Call(context.Background(), &CallRequest{
+		DeviceNumber: "dev1",
+		MessageId:    "m1",
+	})
+	a.Equal(codes.AlreadyExists, status.Code(err))
+}
+
+func testPlugins(ctrl *gomock.Controller, names ...string) []server.Plugin {
+	var plugins []server.Plugin
+	for _, v := range names {
+		p := server.NewMockPlugin(ctrl)
+		p.EXPECT().Name().Return(v).AnyTimes()
+		plugins = append(plugins, p)
+	}
+	return plugins
+}
+
+func TestCheckPluginOrder(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the default plugin_order must place admin before thingspanel to receive the device RPC responses.
+	b, err := ioutil.ReadFile("../../cmd/gmqttd/default_config.yml")
+	a.NoError(err)
+	var c struct {
+		PluginOrder []string `yaml:"plugin_order"`
+	}
+	a.NoError(yaml.Unmarshal(b, &c))
+	a.Contains(c.PluginOrder, Name)
+	a.NoError(checkPluginOrder(testPlugins(ctrl, c.PluginOrder...)))
+
+	a.NoError(checkPluginOrder(testPlugins(ctrl, Name)))
+	a.Error(checkPluginOrder(testPlugins(ctrl, "sys", "thingspanel", Name)))
+}
@@ -15,6 +15,7 @@ func (a *Admin) HookWrapper() server.HookWrapper {
 		OnSessionTerminatedWrapper: a.OnSessionTerminatedWrapper,
 		OnSubscribedWrapper:        a.OnSubscribedWrapper,
 		OnUnsubscribedWrapper:      a.OnUnsubscribedWrapper,
+		OnMsgArrivedWrapper:        a.OnMsgArrivedWrapper,
 	}
 }
 
@@ -59,3 +60,16 @@ func (a *Admin) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnUnsubs
 		a.store.removeSubscription(client.ClientOptions().ClientID, topicName)
 	}
 }
+
+func (a *Admin) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		if req.Message != nil {
+			a.deviceRPC.resolve(client.ClientOptions().ClientID, req.Message)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+type logService struct {
+	a *Admin
+}
+
+func (l *logService) mustEmbedUnimplementedLogServiceServer() {
+	return
+}
+
+func (l *logService) levels() (*GetLogLevelResponse, error) {
+	global, plugins, err := l.a.logLevelManager.Levels()
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	resp := &GetLogLevelResponse{
+		Level:   global.String(),
+		Plugins: make(map[string]string),
+	}
+	for k, v := range plugins {
+		resp.Plugins[k] = v.String()
+	}
+	return resp, nil
+}
+
+// GetLevel implements LogServiceServer.
+func (l *logService) GetLevel(ctx context.Context, req *GetLogLevelRequest) (*GetLogLevelResponse, error) {
+	return l.levels()
+}
+
+// SetLevel implements LogServiceServer.
+func (l *logService) SetLevel(ctx context.Context, req *SetLogLevelRequest) (*GetLogLevelResponse, error) {
+	level, err := config.ParseLogLevel(req.Level)
+	if err != nil {
+		return nil, ErrInvalidArgument("level", "")
+	}
+	err = l.a.logLevelManager.SetLevel(req.Plugin, level)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Info("log level changed", zap.String("target_plugin", req.Plugin), zap.String("level", req.Level))
+	return l.levels()
+}
+
+// UnsetLevel implements LogServiceServer.
+func (l *logService) UnsetLevel(ctx context.Context, req *UnsetLogLevelRequest) (*empty.Empty, error) {
+	if req.Plugin == "" {
+		return nil, ErrInvalidArgument("plugin", "")
+	}
+	err := l.a.logLevelManager.UnsetLevel(req.Plugin)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestLogService(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	lm := server.NewMockLogLevelManager(ctrl)
+	l := &logService{a: &Admin{logLevelManager: lm}}
+
+	lm.EXPECT().Levels().Return(zapcore.InfoLevel, map[string]zapcore.Level{"thingspanel": zapcore.DebugLevel}, nil)
+	resp, err := l.GetLevel(context.Background(), &GetLogLevelRequest{})
+	a.Nil(err)
+	a.Equal("info", resp.Level)
+	a.Equal(map[string]string{"thingspanel": "debug"}, resp.Plugins)
+
+	_, err = l.SetLevel(context.Background(), &SetLogLevelRequest{Level: "verbose"})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	lm.EXPECT().SetLevel("", zapcore.WarnLevel).Return(nil)
+	lm.EXPECT().Levels().Return(zapcore.WarnLevel, map[string]zapcore.Level{}, nil)
+	resp, err = l.SetLevel(context.Background(), &SetLogLevelRequest{Level: "warn"})
+	a.Nil(err)
+	a.Equal("warn", resp.Level)
+
+	_, err = l.UnsetLevel(context.Background(), &UnsetLogLevelRequest{})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	lm.EXPECT().UnsetLevel("thingspanel").Return(errors.New("not reloadable"))
+	_, err = l.UnsetLevel(context.Background(), &UnsetLogLevelRequest{Plugin: "thingspanel"})
+	a.Equal(codes.FailedPrecondition, status.Code(err))
+}
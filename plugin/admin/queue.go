@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type queueService struct {
+	a *Admin
+}
+
+func (q *queueService) mustEmbedUnimplementedQueueServiceServer() {
+	return
+}
+
+func queueError(err error) error {
+	if err == server.ErrQueueNotFound {
+		return ErrNotFound
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func elemToQueueMessage(index int, elem *queue.Elem, version packets.Version) *QueueMessage {
+	qm := &QueueMessage{
+		Index:    uint32(index),
+		PacketId: uint32(elem.ID()),
+		QueuedAt: timestamppb.New(elem.At),
+	}
+	if !elem.Expiry.IsZero() {
+		qm.Expiry = timestamppb.New(elem.Expiry)
+	}
+	pub, ok := elem.MessageWithID.(*queue.Publish)
+	if !ok {
+		qm.State = QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBCOMP
+		return qm
+	}
+	switch {
+	case pub.ID() == 0:
+		qm.State = QueueMessageState_QUEUE_MESSAGE_STATE_QUEUED
+	case pub.QoS == packets.Qos1:
+		qm.State = QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBACK
+	default:
+		qm.State = QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBREC
+	}
+	qm.TopicName = pub.Topic
+	qm.Qos = uint32(pub.QoS)
+	qm.Retained = pub.Retained
+	qm.Payload = string(pub.Payload)
+	qm.Size = pub.TotalBytes(version)
+	return qm
+}
+
+// List lists the inflight and queued messages of the client.
+func (q *queueService) List(ctx context.Context, req *ListQueueRequest) (*ListQueueResponse, error) {
+	if req.ClientId == "" {
+		return nil, ErrInvalidArgument("client_id", "")
+	}
+	version := packets.Version5
+	if client := q.a.clientService.GetClient(req.ClientId); client != nil {
+		version = client.Version()
+	}
+	page, pageSize := GetPage(req.Page, req.PageSize)
+	offset, n := GetOffsetN(page, pageSize)
+	resp := &ListQueueResponse{
+		Messages: make([]*QueueMessage, 0),
+	}
+	var i int
+	err := q.a.queueService.Iterate(req.ClientId, func(elem *queue.Elem) bool {
+		if elem.ID() == 0 {
+			resp.QueueLen++
+		} else {
+			resp.InflightLen++
+		}
+		if uint(i) >= offset && uint(i) < offset+n {
+			resp.Messages = append(resp.Messages, elemToQueueMessage(i, elem, version))
+		}
+		i++
+		return true
+	})
+	if err != nil {
+		return nil, queueError(err)
+	}
+	resp.TotalCount = uint32(i)
+	return resp, nil
+}
+
+// Purge drops all queued messages of the client.
+func (q *queueService) Purge(ctx context.Context, req *PurgeQueueRequest) (*PurgeQueueResponse, error) {
+	if req.ClientId == "" {
+		return nil, ErrInvalidArgument("client_id", "")
+	}
+	n, err := q.a.queueService.Drop(req.ClientId, func(index int, elem *queue.Elem) bool {
+		return true
+	})
+	if err != nil {
+		return nil, queueError(err)
+	}
+	return &PurgeQueueResponse{
+		DroppedCount: uint32(n),
+	}, nil
+}
+
+// Drop drops the queued message at the given index.
+func (q *queueService) Drop(ctx context.Context, req *DropQueueMessageRequest) (*empty.Empty, error) {
+	if req.ClientId == "" {
+		return nil, ErrInvalidArgument("client_id", "")
+	}
+	n, err := q.a.queueService.Drop(req.ClientId, func(index int, elem *queue.Elem) bool {
+		if index != int(req.Index) {
+			return false
+		}
+		return req.TopicName == "" || req.TopicName == elem.MessageWithID.(*queue.Publish).Topic
+	})
+	if err != nil {
+		return nil, queueError(err)
+	}
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+	return &empty.Empty{}, nil
+}
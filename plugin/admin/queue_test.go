@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/persistence/queue/mem"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type nopNotifier struct{}
+
+func (nopNotifier) NotifyDropped(elem *queue.Elem, err error) {}
+func (nopNotifier) NotifyInflightAdded(delta int)             {}
+func (nopNotifier) NotifyMsgQueueAdded(delta int)             {}
+
+// testQueueService serves the queue of client "c1".
+type testQueueService struct {
+	q queue.Store
+}
+
+func (t *testQueueService) Iterate(clientID string, fn queue.IterateFn) error {
+	if clientID != "c1" {
+		return server.ErrQueueNotFound
+	}
+	return t.q.Iterate(fn)
+}
+
+func (t *testQueueService) Drop(clientID string, fn queue.DropFn) (int, error) {
+	if clientID != "c1" {
+		return 0, server.ErrQueueNotFound
+	}
+	return t.q.Drop(fn)
+}
+
+func (t *testQueueService) Enqueue(clientID string, msgs ...*gmqtt.Message) error {
+	return nil
+}
+
+func newTestQueueService(t *testing.T, ctrl *gomock.Controller) *queueService {
+	q, err := mem.New(mem.Options{
+		MaxQueuedMsg:    10,
+		ClientID:        "c1",
+		DefaultNotifier: nopNotifier{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(100, 0)
+	for _, v := range []queue.MessageWithID{
+		&queue.Pubrel{PacketID: 1},
+		&queue.Publish{Message: &gmqtt.Message{Topic: "a", QoS: 1, PacketID: 2, Payload: []byte("a")}},
+		&queue.Publish{Message: &gmqtt.Message{Topic: "b", QoS: 2, PacketID: 3, Payload: []byte("b")}},
+		&queue.Publish{Message: &gmqtt.Message{Topic: "c", QoS: 1, Payload: []byte("c")}},
+		&queue.Publish{Message: &gmqtt.Message{Topic: "d", QoS: 0, Payload: []byte("d")}},
+	} {
+		err = q.Add(&queue.Elem{
+			At:            now,
+			Expiry:        now.Add(time.Minute),
+			MessageWithID: v,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	cs := server.NewMockClientService(ctrl)
+	cs.EXPECT().GetClient(gomock.Any()).Return(nil).AnyTimes()
+	return &queueService{
+		a: &Admin{
+			clientService: cs,
+			queueService:  &testQueueService{q: q},
+		},
+	}
+}
+
+func TestQueueService_List(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	q := newTestQueueService(t, ctrl)
+
+	resp, err := q.List(context.Background(), &ListQueueRequest{ClientId: "c1"})
+	a.Nil(err)
+	a.EqualValues(5, resp.TotalCount)
+	a.EqualValues(3, resp.InflightLen)
+	a.EqualValues(2, resp.QueueLen)
+	a.Len(resp.Messages, 5)
+	var states []QueueMessageState
+	for _, v := range resp.Messages {
+		states = append(states, v.State)
+	}
+	a.Equal([]QueueMessageState{
+		QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBCOMP,
+		QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBACK,
+		QueueMessageState_QUEUE_MESSAGE_STATE_WAIT_PUBREC,
+		QueueMessageState_QUEUE_MESSAGE_STATE_QUEUED,
+		QueueMessageState_QUEUE_MESSAGE_STATE_QUEUED,
+	}, states)
+	a.EqualValues(1, resp.Messages[0].PacketId)
+	a.Empty(resp.Messages[0].TopicName)
+
+	m := resp.Messages[1]
+	a.EqualValues(1, m.Index)
+	a.Equal("a", m.TopicName)
+	a.EqualValues(1, m.Qos)
+	a.EqualValues(2, m.PacketId)
+	a.Equal("a", m.Payload)
+	a.NotZero(m.Size)
+	a.EqualValues(100, m.QueuedAt.Seconds)
+	a.EqualValues(160, m.Expiry.Seconds)
+
+	resp, err = q.List(context.Background(), &ListQueueRequest{ClientId: "c1", Page: 2, PageSize: 2})
+	a.Nil(err)
+	a.Len(resp.Messages, 2)
+	a.EqualValues(2, resp.Messages[0].Index)
+	a.Equal("b", resp.Messages[0].TopicName)
+
+	_, err = q.List(context.Background(), &ListQueueRequest{ClientId: "c2"})
+	a.Equal(ErrNotFound, err)
+	_, err = q.List(context.Background(), &ListQueueRequest{})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func TestQueueService_DropAndPurge(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	q := newTestQueueService(t, ctrl)
+
+	// inflight messages can not be dropped
+	_, err := q.Drop(context.Background(), &DropQueueMessageRequest{ClientId: "c1", Index: 1})
+	a.Equal(ErrNotFound, err)
+	// topic name mismatch
+	_, err = q.Drop(context.Background(), &DropQueueMessageRequest{ClientId: "c1", Index: 3, TopicName: "d"})
+	a.Equal(ErrNotFound, err)
+
+	_, err = q.Drop(context.Background(), &DropQueueMessageRequest{ClientId: "c1", Index: 3, TopicName: "c"})
+	a.Nil(err)
+	resp, err := q.List(context.Background(), &ListQueueRequest{ClientId: "c1"})
+	a.Nil(err)
+	a.EqualValues(4, resp.TotalCount)
+	a.Equal("d", resp.Messages[3].TopicName)
+	a.EqualValues(packets.Qos0, resp.Messages[3].Qos)
+
+	purge, err := q.Purge(context.Background(), &PurgeQueueRequest{ClientId: "c1"})
+	a.Nil(err)
+	a.EqualValues(1, purge.DroppedCount)
+	resp, err = q.List(context.Background(), &ListQueueRequest{ClientId: "c1"})
+	a.Nil(err)
+	a.EqualValues(3, resp.TotalCount)
+	a.EqualValues(0, resp.QueueLen)
+
+	_, err = q.Purge(context.Background(), &PurgeQueueRequest{ClientId: "c2"})
+	a.Equal(ErrNotFound, err)
+}
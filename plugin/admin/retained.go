@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"context"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+type retainedService struct {
+	a *Admin
+}
+
+func (r *retainedService) mustEmbedUnimplementedRetainedServiceServer() {
+	return
+}
+
+func messageToRetained(msg *gmqtt.Message) *RetainedMessage {
+	rs := &RetainedMessage{
+		TopicName:       msg.Topic,
+		Payload:         string(msg.Payload),
+		Qos:             uint32(msg.QoS),
+		ContentType:     msg.ContentType,
+		CorrelationData: string(msg.CorrelationData),
+		MessageExpiry:   msg.MessageExpiry,
+		PayloadFormat:   uint32(msg.PayloadFormat),
+		ResponseTopic:   msg.ResponseTopic,
+	}
+	for _, v := range msg.UserProperties {
+		rs.UserProperties = append(rs.UserProperties, &UserProperties{
+			K: v.K,
+			V: v.V,
+		})
+	}
+	return rs
+}
+
+// matched returns the retained messages that match the topic filter, sorted by topic name.
+// All retained messages are returned if the topic filter is empty.
+func (r *retainedService) matched(topicFilter string) []*gmqtt.Message {
+	var msgs []*gmqtt.Message
+	if topicFilter == "" {
+		r.a.retainedService.Iterate(func(message *gmqtt.Message) bool {
+			msgs = append(msgs, message)
+			return true
+		})
+	} else {
+		msgs = r.a.retainedService.GetMatchedMessages(topicFilter)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Topic < msgs[j].Topic
+	})
+	return msgs
+}
+
+// List lists retained messages in the broker.
+func (r *retainedService) List(ctx context.Context, req *ListRetainedRequest) (*ListRetainedResponse, error) {
+	if req.TopicFilter != "" && !packets.ValidTopicFilter(true, []byte(req.TopicFilter)) {
+		return nil, ErrInvalidArgument("topic_filter", "")
+	}
+	page, pageSize := GetPage(req.Page, req.PageSize)
+	offset, n := GetOffsetN(page, pageSize)
+	msgs := r.matched(req.TopicFilter)
+	resp := &ListRetainedResponse{
+		RetainedMessages: make([]*RetainedMessage, 0),
+		TotalCount:       uint32(len(msgs)),
+	}
+	for i := offset; i < offset+n && i < uint(len(msgs)); i++ {
+		resp.RetainedMessages = append(resp.RetainedMessages, messageToRetained(msgs[i]))
+	}
+	return resp, nil
+}
+
+// Get returns the retained message for the given topic name.
+func (r *retainedService) Get(ctx context.Context, req *GetRetainedRequest) (*GetRetainedResponse, error) {
+	if req.TopicName == "" {
+		return nil, ErrInvalidArgument("topic_name", "cannot be empty")
+	}
+	msg := r.a.retainedService.GetRetainedMessage(req.TopicName)
+	if msg == nil {
+		return nil, ErrNotFound
+	}
+	return &GetRetainedResponse{
+		RetainedMessage: messageToRetained(msg),
+	}, nil
+}
+
+// Delete deletes the retained message for the given topic name.
+func (r *retainedService) Delete(ctx context.Context, req *DeleteRetainedRequest) (*empty.Empty, error) {
+	if req.TopicName == "" {
+		return nil, ErrInvalidArgument("topic_name", "cannot be empty")
+	}
+	r.a.retainedService.Remove(req.TopicName)
+	return &empty.Empty{}, nil
+}
+
+// Clear deletes the retained messages that match the topic filter.
+func (r *retainedService) Clear(ctx context.Context, req *ClearRetainedRequest) (*ClearRetainedResponse, error) {
+	if req.TopicFilter == "" {
+		return nil, ErrInvalidArgument("topic_filter", "cannot be empty")
+	}
+	if !packets.ValidTopicFilter(true, []byte(req.TopicFilter)) {
+		return nil, ErrInvalidArgument("topic_filter", "")
+	}
+	msgs := r.a.retainedService.GetMatchedMessages(req.TopicFilter)
+	for _, v := range msgs {
+		r.a.retainedService.Remove(v.Topic)
+	}
+	return &ClearRetainedResponse{
+		DeletedCount: uint32(len(msgs)),
+	}, nil
+}
+
+// Set adds or replaces the retained message.
+// The retained message will not be delivered to the existing subscribers.
+func (r *retainedService) Set(ctx context.Context, req *SetRetainedRequest) (*empty.Empty, error) {
+	m := req.RetainedMessage
+	if m == nil {
+		return nil, ErrInvalidArgument("retained_message", "cannot be empty")
+	}
+	if !packets.ValidTopicName(true, []byte(m.TopicName)) {
+		return nil, ErrInvalidArgument("topic_name", "")
+	}
+	if m.Payload == "" {
+		return nil, ErrInvalidArgument("payload", "cannot be empty, use Delete to remove the retained message")
+	}
+	if m.Qos > uint32(packets.Qos2) {
+		return nil, ErrInvalidArgument("qos", "")
+	}
+	if m.PayloadFormat != 0 && m.PayloadFormat != 1 {
+		return nil, ErrInvalidArgument("payload_format", "")
+	}
+	if m.ResponseTopic != "" && !packets.ValidV5Topic([]byte(m.ResponseTopic)) {
+		return nil, ErrInvalidArgument("response_topic", "")
+	}
+	var userPpt []packets.UserProperty
+	for _, v := range m.UserProperties {
+		userPpt = append(userPpt, packets.UserProperty{
+			K: v.K,
+			V: v.V,
+		})
+	}
+	r.a.retainedService.AddOrReplace(&gmqtt.Message{
+		QoS:             byte(m.Qos),
+		Retained:        true,
+		Topic:           m.TopicName,
+		Payload:         []byte(m.Payload),
+		ContentType:     m.ContentType,
+		CorrelationData: []byte(m.CorrelationData),
+		MessageExpiry:   m.MessageExpiry,
+		PayloadFormat:   packets.PayloadFormat(m.PayloadFormat),
+		ResponseTopic:   m.ResponseTopic,
+		UserProperties:  userPpt,
+	})
+	return &empty.Empty{}, nil
+}
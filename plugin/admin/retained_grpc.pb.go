@@ -0,0 +1,254 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package admin
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// RetainedServiceClient is the client API for RetainedService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RetainedServiceClient interface {
+	// List retained messages.
+	List(ctx context.Context, in *ListRetainedRequest, opts ...grpc.CallOption) (*ListRetainedResponse, error)
+	// Get the retained message for given topic name.
+	// Return NotFound error when the retained message not found.
+	Get(ctx context.Context, in *GetRetainedRequest, opts ...grpc.CallOption) (*GetRetainedResponse, error)
+	// Delete the retained message for given topic name.
+	Delete(ctx context.Context, in *DeleteRetainedRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// Clear the retained messages that match the topic filter.
+	Clear(ctx context.Context, in *ClearRetainedRequest, opts ...grpc.CallOption) (*ClearRetainedResponse, error)
+	// Set adds or replaces the retained message.
+	Set(ctx context.Context, in *SetRetainedRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type retainedServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRetainedServiceClient(cc grpc.ClientConnInterface) RetainedServiceClient {
+	return &retainedServiceClient{cc}
+}
+
+func (c *retainedServiceClient) List(ctx context.Context, in *ListRetainedRequest, opts ...grpc.CallOption) (*ListRetainedResponse, error) {
+	out := new(ListRetainedResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.RetainedService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *retainedServiceClient) Get(ctx context.Context, in *GetRetainedRequest, opts ...grpc.CallOption) (*GetRetainedResponse, error) {
+	out := new(GetRetainedResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.RetainedService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *retainedServiceClient) Delete(ctx context.Context, in *DeleteRetainedRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.RetainedService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *retainedServiceClient) Clear(ctx context.Context, in *ClearRetainedRequest, opts ...grpc.CallOption) (*ClearRetainedResponse, error) {
+	out := new(ClearRetainedResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.RetainedService/Clear", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *retainedServiceClient) Set(ctx context.Context, in *SetRetainedRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.RetainedService/Set", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RetainedServiceServer is the server API for RetainedService service.
+// All implementations must embed UnimplementedRetainedServiceServer
+// for forward compatibility
+type RetainedServiceServer interface {
+	// List retained messages.
+	List(context.Context, *ListRetainedRequest) (*ListRetainedResponse, error)
+	// Get the retained message for given topic name.
+	// Return NotFound error when the retained message not found.
+	Get(context.Context, *GetRetainedRequest) (*GetRetainedResponse, error)
+	// Delete the retained message for given topic name.
+	Delete(context.Context, *DeleteRetainedRequest) (*empty.Empty, error)
+	// Clear the retained messages that match the topic filter.
+	Clear(context.Context, *ClearRetainedRequest) (*ClearRetainedResponse, error)
+	// Set adds or replaces the retained message.
+	Set(context.Context, *SetRetainedRequest) (*empty.Empty, error)
+	mustEmbedUnimplementedRetainedServiceServer()
+}
+
+// UnimplementedRetainedServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRetainedServiceServer struct {
+}
+
+func (UnimplementedRetainedServiceServer) List(context.Context, *ListRetainedRequest) (*ListRetainedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedRetainedServiceServer) Get(context.Context, *GetRetainedRequest) (*GetRetainedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedRetainedServiceServer) Delete(context.Context, *DeleteRetainedRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedRetainedServiceServer) Clear(context.Context, *ClearRetainedRequest) (*ClearRetainedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clear not implemented")
+}
+func (UnimplementedRetainedServiceServer) Set(context.Context, *SetRetainedRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Set not implemented")
+}
+func (UnimplementedRetainedServiceServer) mustEmbedUnimplementedRetainedServiceServer() {}
+
+// UnsafeRetainedServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RetainedServiceServer will
+// result in compilation errors.
+type UnsafeRetainedServiceServer interface {
+	mustEmbedUnimplementedRetainedServiceServer()
+}
+
+func RegisterRetainedServiceServer(s grpc.ServiceRegistrar, srv RetainedServiceServer) {
+	s.RegisterService(&_RetainedService_serviceDesc, srv)
+}
+
+func _RetainedService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRetainedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RetainedServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.RetainedService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RetainedServiceServer).List(ctx, req.(*ListRetainedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RetainedService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRetainedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RetainedServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.RetainedService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RetainedServiceServer).Get(ctx, req.(*GetRetainedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RetainedService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRetainedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RetainedServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.RetainedService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RetainedServiceServer).Delete(ctx, req.(*DeleteRetainedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RetainedService_Clear_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearRetainedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RetainedServiceServer).Clear(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.RetainedService/Clear",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RetainedServiceServer).Clear(ctx, req.(*ClearRetainedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RetainedService_Set_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetRetainedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RetainedServiceServer).Set(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.RetainedService/Set",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RetainedServiceServer).Set(ctx, req.(*SetRetainedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _RetainedService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.RetainedService",
+	HandlerType: (*RetainedServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _RetainedService_List_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _RetainedService_Get_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _RetainedService_Delete_Handler,
+		},
+		{
+			MethodName: "Clear",
+			Handler:    _RetainedService_Clear_Handler,
+		},
+		{
+			MethodName: "Set",
+			Handler:    _RetainedService_Set_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "retained.proto",
+}
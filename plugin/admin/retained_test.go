@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+)
+
+func newTestRetainedService(topics ...string) *retainedService {
+	store := trie.NewStore()
+	for _, v := range topics {
+		store.AddOrReplace(&gmqtt.Message{
+			Topic:    v,
+			Payload:  []byte(v),
+			Retained: true,
+		})
+	}
+	return &retainedService{
+		a: &Admin{
+			retainedService: store,
+		},
+	}
+}
+
+func TestRetainedService_List(t *testing.T) {
+	a := assert.New(t)
+	r := newTestRetainedService("b/1", "a/1", "a/2", "$SYS/a")
+
+	resp, err := r.List(context.Background(), &ListRetainedRequest{})
+	a.Nil(err)
+	a.EqualValues(4, resp.TotalCount)
+	var topics []string
+	for _, v := range resp.RetainedMessages {
+		topics = append(topics, v.TopicName)
+	}
+	a.Equal([]string{"$SYS/a", "a/1", "a/2", "b/1"}, topics)
+
+	resp, err = r.List(context.Background(), &ListRetainedRequest{
+		TopicFilter: "a/#",
+		Page:        2,
+		PageSize:    1,
+	})
+	a.Nil(err)
+	a.EqualValues(2, resp.TotalCount)
+	a.Len(resp.RetainedMessages, 1)
+	a.Equal(&RetainedMessage{
+		TopicName: "a/2",
+		Payload:   "a/2",
+	}, resp.RetainedMessages[0])
+
+	resp, err = r.List(context.Background(), &ListRetainedRequest{
+		TopicFilter: "a/#",
+		Page:        3,
+		PageSize:    1,
+	})
+	a.Nil(err)
+	a.Len(resp.RetainedMessages, 0)
+
+	_, err = r.List(context.Background(), &ListRetainedRequest{
+		TopicFilter: "a/#/b",
+	})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func TestRetainedService_GetSetDelete(t *testing.T) {
+	a := assert.New(t)
+	r := newTestRetainedService()
+
+	_, err := r.Get(context.Background(), &GetRetainedRequest{TopicName: "a"})
+	a.Equal(ErrNotFound, err)
+
+	msg := &RetainedMessage{
+		TopicName:     "a",
+		Payload:       "payload",
+		Qos:           1,
+		ContentType:   "json",
+		PayloadFormat: 1,
+		UserProperties: []*UserProperties{
+			{K: []byte("k"), V: []byte("v")},
+		},
+	}
+	_, err = r.Set(context.Background(), &SetRetainedRequest{RetainedMessage: msg})
+	a.Nil(err)
+
+	resp, err := r.Get(context.Background(), &GetRetainedRequest{TopicName: "a"})
+	a.Nil(err)
+	a.Equal(msg, resp.RetainedMessage)
+
+	_, err = r.Delete(context.Background(), &DeleteRetainedRequest{TopicName: "a"})
+	a.Nil(err)
+	_, err = r.Get(context.Background(), &GetRetainedRequest{TopicName: "a"})
+	a.Equal(ErrNotFound, err)
+
+	for _, v := range []*RetainedMessage{
+		nil,
+		{TopicName: "a/+", Payload: "p"},
+		{TopicName: "a", Payload: ""},
+		{TopicName: "a", Payload: "p", Qos: 3},
+		{TopicName: "a", Payload: "p", PayloadFormat: 2},
+	} {
+		_, err = r.Set(context.Background(), &SetRetainedRequest{RetainedMessage: v})
+		a.Equal(codes.InvalidArgument, status.Code(err))
+	}
+}
+
+func TestRetainedService_Clear(t *testing.T) {
+	a := assert.New(t)
+	r := newTestRetainedService("a/1", "a/2", "b/1", "$SYS/a")
+
+	_, err := r.Clear(context.Background(), &ClearRetainedRequest{})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	resp, err := r.Clear(context.Background(), &ClearRetainedRequest{TopicFilter: "a/+"})
+	a.Nil(err)
+	a.EqualValues(2, resp.DeletedCount)
+
+	// '#' does not match system topics
+	resp, err = r.Clear(context.Background(), &ClearRetainedRequest{TopicFilter: "#"})
+	a.Nil(err)
+	a.EqualValues(1, resp.DeletedCount)
+
+	list, err := r.List(context.Background(), &ListRetainedRequest{})
+	a.Nil(err)
+	a.Len(list.RetainedMessages, 1)
+	a.Equal("$SYS/a", list.RetainedMessages[0].TopicName)
+}
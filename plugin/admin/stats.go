@@ -0,0 +1,304 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const (
+	// statsSampleInterval is the interval to sample the counters for the rates calculation.
+	statsSampleInterval = 10 * time.Second
+	defaultTopLimit     = 10
+	maxTopLimit         = 1000
+)
+
+// rateSample is a sample of the counters used to calculate the rates.
+type rateSample struct {
+	at              time.Time
+	packetsReceived uint64
+	packetsSent     uint64
+	bytesReceived   uint64
+	bytesSent       uint64
+	msgsReceived    uint64
+	msgsSent        uint64
+	msgsDropped     uint64
+}
+
+func newRateSample(at time.Time, ps server.PacketStats, ms server.MessageStats) *rateSample {
+	return &rateSample{
+		at:              at,
+		packetsReceived: ps.ReceivedTotal.Total,
+		packetsSent:     ps.SentTotal.Total,
+		bytesReceived:   ps.BytesReceived.Total,
+		bytesSent:       ps.BytesSent.Total,
+		msgsReceived:    ms.Qos0.ReceivedTotal + ms.Qos1.ReceivedTotal + ms.Qos2.ReceivedTotal,
+		msgsSent:        ms.Qos0.SentTotal + ms.Qos1.SentTotal + ms.Qos2.SentTotal,
+		msgsDropped:     ms.GetDroppedTotal(),
+	}
+}
+
+// rates calculates the per second rates between the two samples.
+func (cur *rateSample) rates(prev *rateSample) *Rates {
+	if prev == nil {
+		return &Rates{}
+	}
+	elapsed := cur.at.Sub(prev.at).Seconds()
+	if elapsed <= 0 {
+		return &Rates{}
+	}
+	rate := func(c, p uint64) float64 {
+		// the counters are reset when the session is terminated
+		if c < p {
+			return 0
+		}
+		return float64(c-p) / elapsed
+	}
+	return &Rates{
+		PacketsReceived:  rate(cur.packetsReceived, prev.packetsReceived),
+		PacketsSent:      rate(cur.packetsSent, prev.packetsSent),
+		BytesReceived:    rate(cur.bytesReceived, prev.bytesReceived),
+		BytesSent:        rate(cur.bytesSent, prev.bytesSent),
+		MessagesReceived: rate(cur.msgsReceived, prev.msgsReceived),
+		MessagesSent:     rate(cur.msgsSent, prev.msgsSent),
+		MessagesDropped:  rate(cur.msgsDropped, prev.msgsDropped),
+	}
+}
+
+type statsService struct {
+	a  *Admin
+	mu sync.RWMutex
+	// the latest samples and the rates calculated from the last two samples.
+	globalSample  *rateSample
+	globalRates   *Rates
+	clientSamples map[string]*rateSample
+	clientRates   map[string]*Rates
+	exit          chan struct{}
+	wg            sync.WaitGroup
+}
+
+func newStatsService(a *Admin) *statsService {
+	return &statsService{
+		a:             a,
+		globalRates:   &Rates{},
+		clientSamples: make(map[string]*rateSample),
+		clientRates:   make(map[string]*Rates),
+		exit:          make(chan struct{}),
+	}
+}
+
+func (s *statsService) mustEmbedUnimplementedStatsServiceServer() {
+	return
+}
+
+// sample samples the counters and updates the rates.
+func (s *statsService) sample(now time.Time) {
+	gs := s.a.statsReader.GetGlobalStats()
+	global := newRateSample(now, gs.PacketStats, gs.MessageStats)
+	clients := make(map[string]*rateSample)
+	for _, id := range s.a.store.clientIDs() {
+		if cs, ok := s.a.statsReader.GetClientStats(id); ok {
+			clients[id] = newRateSample(now, cs.PacketStats, cs.MessageStats)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.globalRates = global.rates(s.globalSample)
+	s.globalSample = global
+	rates := make(map[string]*Rates, len(clients))
+	for id, v := range clients {
+		rates[id] = v.rates(s.clientSamples[id])
+	}
+	s.clientSamples = clients
+	s.clientRates = rates
+}
+
+func (s *statsService) run() {
+	s.sample(time.Now())
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(statsSampleInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.exit:
+				return
+			case now := <-t.C:
+				s.sample(now)
+			}
+		}
+	}()
+}
+
+func (s *statsService) stop() {
+	close(s.exit)
+	s.wg.Wait()
+}
+
+func (s *statsService) rates(clientID string) *Rates {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if r, ok := s.clientRates[clientID]; ok {
+		return r
+	}
+	return &Rates{}
+}
+
+func toPacketStats(ps server.PacketStats) *PacketStats {
+	return &PacketStats{
+		ReceivedTotal:   ps.ReceivedTotal.Total,
+		SentTotal:       ps.SentTotal.Total,
+		BytesReceived:   ps.BytesReceived.Total,
+		BytesSent:       ps.BytesSent.Total,
+		PublishReceived: ps.ReceivedTotal.Publish,
+		PublishSent:     ps.SentTotal.Publish,
+	}
+}
+
+func toDroppedStats(d server.DroppedTotal) *DroppedStats {
+	return &DroppedStats{
+		Total:                d.Internal + d.ExceedsMaxPacketSize + d.QueueFull + d.Expired + d.InflightExpired,
+		Internal:             d.Internal,
+		ExceedsMaxPacketSize: d.ExceedsMaxPacketSize,
+		QueueFull:            d.QueueFull,
+		Expired:              d.Expired,
+		InflightExpired:      d.InflightExpired,
+	}
+}
+
+func toMessageQosStats(m server.MessageQosStats) *MessageQosStats {
+	return &MessageQosStats{
+		ReceivedTotal: m.ReceivedTotal,
+		SentTotal:     m.SentTotal,
+		Dropped:       toDroppedStats(m.DroppedTotal),
+	}
+}
+
+func toMessageStats(ms server.MessageStats) *MessageStats {
+	var dropped server.DroppedTotal
+	for _, v := range []server.MessageQosStats{ms.Qos0, ms.Qos1, ms.Qos2} {
+		dropped.Internal += v.DroppedTotal.Internal
+		dropped.ExceedsMaxPacketSize += v.DroppedTotal.ExceedsMaxPacketSize
+		dropped.QueueFull += v.DroppedTotal.QueueFull
+		dropped.Expired += v.DroppedTotal.Expired
+		dropped.InflightExpired += v.DroppedTotal.InflightExpired
+	}
+	return &MessageStats{
+		ReceivedTotal:   ms.Qos0.ReceivedTotal + ms.Qos1.ReceivedTotal + ms.Qos2.ReceivedTotal,
+		SentTotal:       ms.Qos0.SentTotal + ms.Qos1.SentTotal + ms.Qos2.SentTotal,
+		Dropped:         toDroppedStats(dropped),
+		InflightCurrent: ms.InflightCurrent,
+		QueuedCurrent:   ms.QueuedCurrent,
+		Qos0:            toMessageQosStats(ms.Qos0),
+		Qos1:            toMessageQosStats(ms.Qos1),
+		Qos2:            toMessageQosStats(ms.Qos2),
+	}
+}
+
+func (s *statsService) toClientStats(clientID string, cs server.ClientStats) *ClientStats {
+	return &ClientStats{
+		ClientId:     clientID,
+		PacketStats:  toPacketStats(cs.PacketStats),
+		MessageStats: toMessageStats(cs.MessageStats),
+		SubscriptionStats: &SubscriptionStats{
+			SubscriptionsTotal:   cs.SubscriptionStats.SubscriptionsTotal,
+			SubscriptionsCurrent: cs.SubscriptionStats.SubscriptionsCurrent,
+		},
+		Rates: s.rates(clientID),
+	}
+}
+
+// GetGlobal returns the global statistics.
+func (s *statsService) GetGlobal(ctx context.Context, req *empty.Empty) (*GetGlobalStatsResponse, error) {
+	gs := s.a.statsReader.GetGlobalStats()
+	cs := gs.ConnectionStats
+	s.mu.RLock()
+	rates := s.globalRates
+	s.mu.RUnlock()
+	return &GetGlobalStatsResponse{
+		ConnectionStats: &ConnectionStats{
+			ConnectedTotal:             cs.ConnectedTotal,
+			DisconnectedTotal:          cs.DisconnectedTotal,
+			SessionCreatedTotal:        cs.SessionCreatedTotal,
+			SessionTerminatedTakenOver: cs.SessionTerminated.TakenOver,
+			SessionTerminatedExpired:   cs.SessionTerminated.Expired,
+			SessionTerminatedNormal:    cs.SessionTerminated.Normal,
+			ActiveCurrent:              cs.ActiveCurrent,
+			InactiveCurrent:            cs.InactiveCurrent,
+		},
+		PacketStats:  toPacketStats(gs.PacketStats),
+		MessageStats: toMessageStats(gs.MessageStats),
+		SubscriptionStats: &SubscriptionStats{
+			SubscriptionsTotal:   gs.SubscriptionStats.SubscriptionsTotal,
+			SubscriptionsCurrent: gs.SubscriptionStats.SubscriptionsCurrent,
+		},
+		Rates: rates,
+	}, nil
+}
+
+// GetClient returns the statistics for the given client id.
+func (s *statsService) GetClient(ctx context.Context, req *GetClientStatsRequest) (*GetClientStatsResponse, error) {
+	if req.ClientId == "" {
+		return nil, ErrInvalidArgument("client_id", "")
+	}
+	cs, ok := s.a.statsReader.GetClientStats(req.ClientId)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &GetClientStatsResponse{
+		Stats: s.toClientStats(req.ClientId, cs),
+	}, nil
+}
+
+// TopClients returns the top N clients sorted by the given rate or counter in descending order.
+func (s *statsService) TopClients(ctx context.Context, req *TopClientsRequest) (*TopClientsResponse, error) {
+	if req.Limit > maxTopLimit {
+		return nil, ErrInvalidArgument("limit", fmt.Sprintf("limit too large, must <= %d", maxTopLimit))
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultTopLimit
+	}
+	var key func(c *ClientStats) float64
+	switch req.SortBy {
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_UNSPECIFIED, TopClientsSortBy_TOP_CLIENTS_SORT_BY_MESSAGES_RECEIVED_RATE:
+		key = func(c *ClientStats) float64 { return c.Rates.MessagesReceived }
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_MESSAGES_SENT_RATE:
+		key = func(c *ClientStats) float64 { return c.Rates.MessagesSent }
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_BYTES_RECEIVED_RATE:
+		key = func(c *ClientStats) float64 { return c.Rates.BytesReceived }
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_BYTES_SENT_RATE:
+		key = func(c *ClientStats) float64 { return c.Rates.BytesSent }
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_MESSAGES_DROPPED_TOTAL:
+		key = func(c *ClientStats) float64 { return float64(c.MessageStats.Dropped.Total) }
+	case TopClientsSortBy_TOP_CLIENTS_SORT_BY_QUEUED_CURRENT:
+		key = func(c *ClientStats) float64 { return float64(c.MessageStats.QueuedCurrent) }
+	default:
+		return nil, ErrInvalidArgument("sort_by", "")
+	}
+	var clients []*ClientStats
+	for _, id := range s.a.store.clientIDs() {
+		if cs, ok := s.a.statsReader.GetClientStats(id); ok {
+			clients = append(clients, s.toClientStats(id, cs))
+		}
+	}
+	sort.SliceStable(clients, func(i, j int) bool {
+		ki, kj := key(clients[i]), key(clients[j])
+		if ki != kj {
+			return ki > kj
+		}
+		return clients[i].ClientId < clients[j].ClientId
+	})
+	if len(clients) > int(req.Limit) {
+		clients = clients[:req.Limit]
+	}
+	return &TopClientsResponse{
+		Clients: clients,
+	}, nil
+}
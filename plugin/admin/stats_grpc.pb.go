@@ -0,0 +1,178 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package admin
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// StatsServiceClient is the client API for StatsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StatsServiceClient interface {
+	// Get the global statistics.
+	GetGlobal(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*GetGlobalStatsResponse, error)
+	// Get the statistics for given client id.
+	// Return NotFound error when the client not found.
+	GetClient(ctx context.Context, in *GetClientStatsRequest, opts ...grpc.CallOption) (*GetClientStatsResponse, error)
+	// List the top N clients sorted by the given rate or counter in descending order.
+	TopClients(ctx context.Context, in *TopClientsRequest, opts ...grpc.CallOption) (*TopClientsResponse, error)
+}
+
+type statsServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStatsServiceClient(cc grpc.ClientConnInterface) StatsServiceClient {
+	return &statsServiceClient{cc}
+}
+
+func (c *statsServiceClient) GetGlobal(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*GetGlobalStatsResponse, error) {
+	out := new(GetGlobalStatsResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.StatsService/GetGlobal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) GetClient(ctx context.Context, in *GetClientStatsRequest, opts ...grpc.CallOption) (*GetClientStatsResponse, error) {
+	out := new(GetClientStatsResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.StatsService/GetClient", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsServiceClient) TopClients(ctx context.Context, in *TopClientsRequest, opts ...grpc.CallOption) (*TopClientsResponse, error) {
+	out := new(TopClientsResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.StatsService/TopClients", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StatsServiceServer is the server API for StatsService service.
+// All implementations must embed UnimplementedStatsServiceServer
+// for forward compatibility
+type StatsServiceServer interface {
+	// Get the global statistics.
+	GetGlobal(context.Context, *empty.Empty) (*GetGlobalStatsResponse, error)
+	// Get the statistics for given client id.
+	// Return NotFound error when the client not found.
+	GetClient(context.Context, *GetClientStatsRequest) (*GetClientStatsResponse, error)
+	// List the top N clients sorted by the given rate or counter in descending order.
+	TopClients(context.Context, *TopClientsRequest) (*TopClientsResponse, error)
+	mustEmbedUnimplementedStatsServiceServer()
+}
+
+// UnimplementedStatsServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedStatsServiceServer struct {
+}
+
+func (UnimplementedStatsServiceServer) GetGlobal(context.Context, *empty.Empty) (*GetGlobalStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGlobal not implemented")
+}
+func (UnimplementedStatsServiceServer) GetClient(context.Context, *GetClientStatsRequest) (*GetClientStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClient not implemented")
+}
+func (UnimplementedStatsServiceServer) TopClients(context.Context, *TopClientsRequest) (*TopClientsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TopClients not implemented")
+}
+func (UnimplementedStatsServiceServer) mustEmbedUnimplementedStatsServiceServer() {}
+
+// UnsafeStatsServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StatsServiceServer will
+// result in compilation errors.
+type UnsafeStatsServiceServer interface {
+	mustEmbedUnimplementedStatsServiceServer()
+}
+
+func RegisterStatsServiceServer(s grpc.ServiceRegistrar, srv StatsServiceServer) {
+	s.RegisterService(&_StatsService_serviceDesc, srv)
+}
+
+func _StatsService_GetGlobal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetGlobal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.StatsService/GetGlobal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetGlobal(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_GetClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClientStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).GetClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.StatsService/GetClient",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).GetClient(ctx, req.(*GetClientStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StatsService_TopClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopClientsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsServiceServer).TopClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.StatsService/TopClients",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsServiceServer).TopClients(ctx, req.(*TopClientsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _StatsService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.StatsService",
+	HandlerType: (*StatsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetGlobal",
+			Handler:    _StatsService_GetGlobal_Handler,
+		},
+		{
+			MethodName: "GetClient",
+			Handler:    _StatsService_GetClient_Handler,
+		},
+		{
+			MethodName: "TopClients",
+			Handler:    _StatsService_TopClients_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "stats.proto",
+}
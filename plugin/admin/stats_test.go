@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newTestClientStats(received, bytes, queued, dropped uint64) server.ClientStats {
+	cs := server.ClientStats{}
+	cs.PacketStats.ReceivedTotal.Total = received
+	cs.PacketStats.ReceivedTotal.Publish = received
+	cs.PacketStats.BytesReceived.Total = bytes
+	cs.MessageStats.Qos1.ReceivedTotal = received
+	cs.MessageStats.Qos1.DroppedTotal.QueueFull = dropped
+	cs.MessageStats.QueuedCurrent = queued
+	return cs
+}
+
+func TestStatsService_GetGlobal(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sr := server.NewMockStatsReader(ctrl)
+	ad := &Admin{
+		statsReader: sr,
+		store:       newStore(sr, config.DefaultConfig()),
+	}
+	s := newStatsService(ad)
+
+	gs := server.GlobalStats{}
+	gs.ConnectionStats.ConnectedTotal = 3
+	gs.ConnectionStats.ActiveCurrent = 2
+	gs.ConnectionStats.SessionTerminated.TakenOver = 1
+	gs.PacketStats.SentTotal.Total = 10
+	gs.MessageStats.Qos0.SentTotal = 4
+	gs.MessageStats.Qos2.DroppedTotal.Expired = 2
+	gs.SubscriptionStats.SubscriptionsCurrent = 5
+	sr.EXPECT().GetGlobalStats().Return(gs)
+	now := time.Unix(100, 0)
+	s.sample(now)
+
+	gs.PacketStats.SentTotal.Total = 30
+	gs.MessageStats.Qos0.SentTotal = 14
+	sr.EXPECT().GetGlobalStats().Return(gs).Times(2)
+	s.sample(now.Add(10 * time.Second))
+
+	resp, err := s.GetGlobal(context.Background(), &empty.Empty{})
+	a.Nil(err)
+	a.EqualValues(3, resp.ConnectionStats.ConnectedTotal)
+	a.EqualValues(2, resp.ConnectionStats.ActiveCurrent)
+	a.EqualValues(1, resp.ConnectionStats.SessionTerminatedTakenOver)
+	a.EqualValues(30, resp.PacketStats.SentTotal)
+	a.EqualValues(14, resp.MessageStats.SentTotal)
+	a.EqualValues(14, resp.MessageStats.Qos0.SentTotal)
+	a.EqualValues(2, resp.MessageStats.Dropped.Total)
+	a.EqualValues(2, resp.MessageStats.Qos2.Dropped.Expired)
+	a.EqualValues(5, resp.SubscriptionStats.SubscriptionsCurrent)
+	a.EqualValues(2, resp.Rates.PacketsSent)
+	a.EqualValues(1, resp.Rates.MessagesSent)
+	a.Zero(resp.Rates.MessagesDropped)
+}
+
+func TestStatsService_GetClientAndTopClients(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sr := server.NewMockStatsReader(ctrl)
+	ad := &Admin{
+		statsReader: sr,
+		store:       newStore(sr, config.DefaultConfig()),
+	}
+	for _, id := range []string{"c1", "c2", "c3"} {
+		ad.store.clientIndexer.Set(id, &Client{ClientId: id})
+	}
+	s := newStatsService(ad)
+
+	stats := map[string]server.ClientStats{
+		"c1": newTestClientStats(10, 100, 0, 0),
+		"c2": newTestClientStats(10, 100, 5, 3),
+		"c3": newTestClientStats(10, 100, 1, 0),
+	}
+	sr.EXPECT().GetGlobalStats().Return(server.GlobalStats{}).AnyTimes()
+	sr.EXPECT().GetClientStats(gomock.Any()).DoAndReturn(func(id string) (server.ClientStats, bool) {
+		cs, ok := stats[id]
+		return cs, ok
+	}).AnyTimes()
+	now := time.Unix(100, 0)
+	s.sample(now)
+	stats["c1"] = newTestClientStats(30, 200, 0, 0)
+	stats["c2"] = newTestClientStats(20, 1100, 5, 3)
+	stats["c3"] = newTestClientStats(40, 300, 1, 0)
+	s.sample(now.Add(10 * time.Second))
+
+	resp, err := s.GetClient(context.Background(), &GetClientStatsRequest{ClientId: "c1"})
+	a.Nil(err)
+	a.Equal("c1", resp.Stats.ClientId)
+	a.EqualValues(30, resp.Stats.PacketStats.ReceivedTotal)
+	a.EqualValues(30, resp.Stats.MessageStats.ReceivedTotal)
+	a.EqualValues(2, resp.Stats.Rates.MessagesReceived)
+	a.EqualValues(10, resp.Stats.Rates.BytesReceived)
+
+	_, err = s.GetClient(context.Background(), &GetClientStatsRequest{ClientId: "c4"})
+	a.Equal(ErrNotFound, err)
+	_, err = s.GetClient(context.Background(), &GetClientStatsRequest{})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	var tt = []struct {
+		sortBy   TopClientsSortBy
+		limit    uint32
+		expected []string
+	}{
+		{sortBy: TopClientsSortBy_TOP_CLIENTS_SORT_BY_UNSPECIFIED, expected: []string{"c3", "c1", "c2"}},
+		{sortBy: TopClientsSortBy_TOP_CLIENTS_SORT_BY_BYTES_RECEIVED_RATE, limit: 2, expected: []string{"c2", "c3"}},
+		{sortBy: TopClientsSortBy_TOP_CLIENTS_SORT_BY_MESSAGES_DROPPED_TOTAL, limit: 1, expected: []string{"c2"}},
+		{sortBy: TopClientsSortBy_TOP_CLIENTS_SORT_BY_QUEUED_CURRENT, expected: []string{"c2", "c3", "c1"}},
+	}
+	for _, v := range tt {
+		top, err := s.TopClients(context.Background(), &TopClientsRequest{SortBy: v.sortBy, Limit: v.limit})
+		a.Nil(err)
+		var ids []string
+		for _, c := range top.Clients {
+			ids = append(ids, c.ClientId)
+		}
+		a.Equal(v.expected, ids, v.sortBy.String())
+	}
+
+	_, err = s.TopClients(context.Background(), &TopClientsRequest{Limit: maxTopLimit + 1})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	// the rates of the removed clients are dropped on next sampling
+	ad.store.removeClient("c1")
+	s.sample(now.Add(20 * time.Second))
+	a.Len(s.clientRates, 2)
+}
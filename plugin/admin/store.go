@@ -77,6 +77,17 @@ func (s *store) removeClient(clientID string) {
 	s.clientMu.Unlock()
 }
 
+// clientIDs returns the ids of all clients in the store.
+func (s *store) clientIDs() []string {
+	s.clientMu.RLock()
+	defer s.clientMu.RUnlock()
+	ids := make([]string, 0, s.clientIndexer.Len())
+	s.clientIndexer.Iterate(func(elem *list.Element) {
+		ids = append(ids, elem.Value.(*Client).ClientId)
+	}, 0, uint(s.clientIndexer.Len()))
+	return ids
+}
+
 // GetClientByID returns the client information for the given client id.
 func (s *store) GetClientByID(clientID string) *Client {
 	s.clientMu.RLock()
@@ -132,6 +143,12 @@ func fillClientInfo(c *Client, stsReader server.StatsReader) {
 
 // GetClients
 func (s *store) GetClients(page, pageSize uint) (rs []*Client, total uint32, err error) {
+	offset, n := GetOffsetN(page, pageSize)
+	return s.getClientRange(offset, n)
+}
+
+// getClientRange returns at most n clients begin from offset.
+func (s *store) getClientRange(offset, n uint) (rs []*Client, total uint32, err error) {
 	rs = make([]*Client, 0)
 	fn := func(elem *list.Element) {
 		c := elem.Value.(*Client)
@@ -140,20 +157,24 @@ func (s *store) GetClients(page, pageSize uint) (rs []*Client, total uint32, err
 	}
 	s.clientMu.RLock()
 	defer s.clientMu.RUnlock()
-	offset, n := GetOffsetN(page, pageSize)
 	s.clientIndexer.Iterate(fn, offset, n)
 	return rs, uint32(s.clientIndexer.Len()), nil
 }
 
 // GetSubscriptions
 func (s *store) GetSubscriptions(page, pageSize uint) (rs []*Subscription, total uint32, err error) {
+	offset, n := GetOffsetN(page, pageSize)
+	return s.getSubscriptionRange(offset, n)
+}
+
+// getSubscriptionRange returns at most n subscriptions begin from offset.
+func (s *store) getSubscriptionRange(offset, n uint) (rs []*Subscription, total uint32, err error) {
 	rs = make([]*Subscription, 0)
 	fn := func(elem *list.Element) {
 		rs = append(rs, elem.Value.(*Subscription))
 	}
 	s.subMu.RLock()
 	defer s.subMu.RUnlock()
-	offset, n := GetOffsetN(page, pageSize)
 	s.subIndexer.Iterate(fn, offset, n)
 	return rs, uint32(s.subIndexer.Len()), nil
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/DrmagicE/gmqtt"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
@@ -26,6 +27,9 @@ func (s *subscriptionService) mustEmbedUnimplementedSubscriptionServiceServer()
 // List lists subscriptions in the broker.
 func (s *subscriptionService) List(ctx context.Context, req *ListSubscriptionRequest) (*ListSubscriptionResponse, error) {
 	page, pageSize := GetPage(req.Page, req.PageSize)
+	if req.Cluster {
+		return s.clusterList(ctx, page, pageSize)
+	}
 	subs, total, err := s.a.store.GetSubscriptions(page, pageSize)
 	if err != nil {
 		return &ListSubscriptionResponse{}, err
@@ -36,6 +40,27 @@ func (s *subscriptionService) List(ctx context.Context, req *ListSubscriptionReq
 	}, nil
 }
 
+func (s *subscriptionService) clusterList(ctx context.Context, page, pageSize uint) (*ListSubscriptionResponse, error) {
+	if s.a.cluster == nil {
+		return nil, ErrClusterNotEnabled
+	}
+	rs, total, nodeErrors := s.a.clusterList(ctx, clusterListSubscriptions, page, pageSize, func() proto.Message {
+		return &ListSubscriptionResponse{}
+	})
+	resp := &ListSubscriptionResponse{
+		Subscriptions: make([]*Subscription, 0),
+		TotalCount:    total,
+		NodeErrors:    nodeErrors,
+	}
+	for _, v := range rs {
+		for _, sub := range v.resp.(*ListSubscriptionResponse).Subscriptions {
+			sub.NodeName = v.nodeName
+			resp.Subscriptions = append(resp.Subscriptions, sub)
+		}
+	}
+	return resp, nil
+}
+
 // Filter filters subscriptions with the request params.
 // Paging is not supported, and the results are not sorted in any way.
 // Using huge req.Limit can impact performance.
@@ -174,9 +199,19 @@ func (s *subscriptionService) Unsubscribe(ctx context.Context, req *UnsubscribeR
 			return nil, ErrInvalidArgument(fmt.Sprintf("topics[%d]", k), "")
 		}
 	}
+	if req.Cluster {
+		if s.a.cluster == nil {
+			return nil, ErrClusterNotEnabled
+		}
+		return &empty.Empty{}, s.a.clusterCall(ctx, clusterUnsubscribe, req)
+	}
 	err = s.a.store.subscriptionService.Unsubscribe(req.ClientId, req.Topics...)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unsubscribe: %s", err.Error()))
 	}
+	// the OnUnsubscribed hooks are not called for the subscriptions removed by the API.
+	for _, v := range req.Topics {
+		s.a.store.removeSubscription(req.ClientId, v)
+	}
 	return &empty.Empty{}, nil
 }
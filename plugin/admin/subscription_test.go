@@ -335,6 +335,7 @@ func TestSubscriptionService_Unsubscribe(t *testing.T) {
 	}
 	sub.a.store.subscriptionService = ss
 
+	admin.store.addSubscription("cid", &gmqtt.Subscription{TopicFilter: "a"})
 	topics := []string{
 		"a", "b",
 	}
@@ -344,6 +345,8 @@ func TestSubscriptionService_Unsubscribe(t *testing.T) {
 		Topics:   topics,
 	})
 	a.Nil(err)
+	_, total, _ := admin.store.GetSubscriptions(1, 20)
+	a.EqualValues(0, total)
 
 }
 
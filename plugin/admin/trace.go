@@ -0,0 +1,342 @@
+package admin
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const (
+	defaultTraceDuration     = 60
+	maxTraceDuration         = 600
+	defaultTracePayloadLimit = 256
+	maxTracePayloadLimit     = 65536
+	// traceBufferSize is the size of the event buffer of each trace,
+	// the events are dropped if the buffer is full.
+	traceBufferSize = 1024
+)
+
+type traceService struct {
+	a *Admin
+}
+
+func (t *traceService) mustEmbedUnimplementedTraceServiceServer() {
+	return
+}
+
+// Start implements TraceServiceServer.
+func (t *traceService) Start(req *TraceRequest, stream TraceService_StartServer) error {
+	if req.ClientId == "" && req.Username == "" && req.TopicFilter == "" {
+		return ErrInvalidArgument("client_id", "at least one of client_id, username and topic_filter must be set")
+	}
+	if req.TopicFilter != "" && !packets.ValidTopicFilter(true, []byte(req.TopicFilter)) {
+		return ErrInvalidArgument("topic_filter", "")
+	}
+	if req.Duration == 0 {
+		req.Duration = defaultTraceDuration
+	}
+	if req.Duration > maxTraceDuration {
+		return ErrInvalidArgument("duration", "must <= "+strconv.Itoa(maxTraceDuration))
+	}
+	if req.PayloadLimit == 0 {
+		req.PayloadLimit = defaultTracePayloadLimit
+	}
+	if req.PayloadLimit > maxTracePayloadLimit {
+		return ErrInvalidArgument("payload_limit", "must <= "+strconv.Itoa(maxTracePayloadLimit))
+	}
+	tr := newTracer(req)
+	remove := t.a.traceService.AddTracer(tr)
+	defer remove()
+	var operator string
+	if id, ok := identityFromContext(stream.Context()); ok {
+		operator = id.name
+	}
+	log.Info("trace started",
+		zap.String("client_id", req.ClientId),
+		zap.String("username", req.Username),
+		zap.String("topic_filter", req.TopicFilter),
+		zap.Uint32("duration", req.Duration),
+		zap.String("operator", operator))
+	defer log.Info("trace stopped", zap.String("client_id", req.ClientId),
+		zap.String("username", req.Username),
+		zap.String("topic_filter", req.TopicFilter))
+
+	timer := time.NewTimer(time.Duration(req.Duration) * time.Second)
+	defer timer.Stop()
+	var n uint32
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-timer.C:
+			return nil
+		case ev := <-tr.events:
+			ev.DroppedEvents = atomic.SwapUint32(&tr.dropped, 0)
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+			n++
+			if req.MaxEvents != 0 && n >= req.MaxEvents {
+				return nil
+			}
+		}
+	}
+}
+
+// tracer implements server.Tracer, it filters and converts the events in the client goroutines
+// and buffers the converted events for the stream.
+type tracer struct {
+	clientID     string
+	username     string
+	topicFilter  string
+	payloadLimit uint32
+	events       chan *TraceEvent
+	// dropped is the number of events that are dropped since the last sent event.
+	dropped uint32
+}
+
+func newTracer(req *TraceRequest) *tracer {
+	return &tracer{
+		clientID:     req.ClientId,
+		username:     req.Username,
+		topicFilter:  req.TopicFilter,
+		payloadLimit: req.PayloadLimit,
+		events:       make(chan *TraceEvent, traceBufferSize),
+	}
+}
+
+// Trace implements server.Tracer.
+func (t *tracer) Trace(event *server.TraceEvent) {
+	if t.clientID != "" && t.clientID != event.ClientID {
+		return
+	}
+	if t.username != "" && t.username != event.Username {
+		return
+	}
+	if t.topicFilter != "" && !t.matchTopics(eventTopics(event)) {
+		return
+	}
+	select {
+	case t.events <- t.convert(event):
+	default:
+		atomic.AddUint32(&t.dropped, 1)
+	}
+}
+
+// matchTopics returns whether any of the topic names or topic filters matches the topic filter of the trace.
+func (t *tracer) matchTopics(topics []string) bool {
+	for _, v := range topics {
+		if v == t.topicFilter || packets.TopicMatch([]byte(v), []byte(t.topicFilter)) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventTopics returns the topic names or topic filters of the event.
+func eventTopics(event *server.TraceEvent) []string {
+	switch p := event.Packet.(type) {
+	case *packets.Subscribe:
+		topics := make([]string, 0, len(p.Topics))
+		for _, v := range p.Topics {
+			topics = append(topics, v.Name)
+		}
+		return topics
+	case *packets.Unsubscribe:
+		return p.Topics
+	}
+	if event.Topic != "" {
+		return []string{event.Topic}
+	}
+	return nil
+}
+
+func (t *tracer) convert(event *server.TraceEvent) *TraceEvent {
+	ev := &TraceEvent{
+		ClientId: event.ClientID,
+		Username: event.Username,
+		Topic:    strings.Join(eventTopics(event), ","),
+		Decision: event.Decision,
+		Time:     timestamppb.New(event.Time),
+	}
+	switch event.Type {
+	case server.TracePacketReceived:
+		ev.Type = TraceEventType_TRACE_EVENT_TYPE_PACKET_RECEIVED
+	case server.TracePacketSent:
+		ev.Type = TraceEventType_TRACE_EVENT_TYPE_PACKET_SENT
+	case server.TraceDecision:
+		ev.Type = TraceEventType_TRACE_EVENT_TYPE_DECISION
+	}
+	if event.Err != nil {
+		ev.Reason = event.Err.Error()
+	}
+	if event.Message != nil {
+		t.setMessage(ev, event.Message)
+	}
+	if event.Packet != nil {
+		t.setPacket(ev, event.Packet)
+	}
+	return ev
+}
+
+func (t *tracer) setPayload(ev *TraceEvent, payload []byte) {
+	ev.PayloadSize = uint32(len(payload))
+	if uint32(len(payload)) > t.payloadLimit {
+		payload = payload[:t.payloadLimit]
+	}
+	ev.Payload = string(payload)
+}
+
+func (t *tracer) setMessage(ev *TraceEvent, msg *gmqtt.Message) {
+	ev.Qos = uint32(msg.QoS)
+	ev.Retained = msg.Retained
+	ev.Dup = msg.Dup
+	ev.PacketId = uint32(msg.PacketID)
+	t.setPayload(ev, msg.Payload)
+	ev.Properties, ev.UserProperties = traceProperties(gmqtt.MessageToPublish(msg, packets.Version5).Properties)
+}
+
+func (t *tracer) setPacket(ev *TraceEvent, packet packets.Packet) {
+	var props *packets.Properties
+	switch p := packet.(type) {
+	case *packets.Connect:
+		ev.PacketType = "CONNECT"
+		props = p.Properties
+	case *packets.Connack:
+		ev.PacketType = "CONNACK"
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Publish:
+		ev.PacketType = "PUBLISH"
+		ev.Qos = uint32(p.Qos)
+		ev.Retained = p.Retain
+		ev.Dup = p.Dup
+		ev.PacketId = uint32(p.PacketID)
+		t.setPayload(ev, p.Payload)
+		props = p.Properties
+	case *packets.Puback:
+		ev.PacketType = "PUBACK"
+		ev.PacketId = uint32(p.PacketID)
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Pubrec:
+		ev.PacketType = "PUBREC"
+		ev.PacketId = uint32(p.PacketID)
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Pubrel:
+		ev.PacketType = "PUBREL"
+		ev.PacketId = uint32(p.PacketID)
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Pubcomp:
+		ev.PacketType = "PUBCOMP"
+		ev.PacketId = uint32(p.PacketID)
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Subscribe:
+		ev.PacketType = "SUBSCRIBE"
+		ev.PacketId = uint32(p.PacketID)
+		props = p.Properties
+	case *packets.Suback:
+		ev.PacketType = "SUBACK"
+		ev.PacketId = uint32(p.PacketID)
+		if len(p.Payload) != 0 {
+			ev.ReasonCode = uint32(p.Payload[0])
+		}
+		props = p.Properties
+	case *packets.Unsubscribe:
+		ev.PacketType = "UNSUBSCRIBE"
+		ev.PacketId = uint32(p.PacketID)
+		props = p.Properties
+	case *packets.Unsuback:
+		ev.PacketType = "UNSUBACK"
+		ev.PacketId = uint32(p.PacketID)
+		if len(p.Payload) != 0 {
+			ev.ReasonCode = uint32(p.Payload[0])
+		}
+		props = p.Properties
+	case *packets.Disconnect:
+		ev.PacketType = "DISCONNECT"
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	case *packets.Pingreq:
+		ev.PacketType = "PINGREQ"
+	case *packets.Pingresp:
+		ev.PacketType = "PINGRESP"
+	case *packets.Auth:
+		ev.PacketType = "AUTH"
+		ev.ReasonCode = uint32(p.Code)
+		props = p.Properties
+	}
+	ev.Properties, ev.UserProperties = traceProperties(props)
+}
+
+// traceProperties converts the v5 properties into the properties map and the user properties.
+func traceProperties(p *packets.Properties) (map[string]string, []*TraceUserProperty) {
+	if p == nil {
+		return nil, nil
+	}
+	m := make(map[string]string)
+	setBytes := func(name string, v []byte) {
+		if len(v) != 0 {
+			m[name] = string(v)
+		}
+	}
+	setUint := func(name string, v uint64) {
+		m[name] = strconv.FormatUint(v, 10)
+	}
+	if p.PayloadFormat != nil {
+		setUint("payload_format", uint64(*p.PayloadFormat))
+	}
+	if p.MessageExpiry != nil {
+		setUint("message_expiry", uint64(*p.MessageExpiry))
+	}
+	setBytes("content_type", p.ContentType)
+	setBytes("response_topic", p.ResponseTopic)
+	setBytes("correlation_data", p.CorrelationData)
+	if len(p.SubscriptionIdentifier) != 0 {
+		ids := make([]string, 0, len(p.SubscriptionIdentifier))
+		for _, v := range p.SubscriptionIdentifier {
+			ids = append(ids, strconv.FormatUint(uint64(v), 10))
+		}
+		m["subscription_identifier"] = strings.Join(ids, ",")
+	}
+	if p.SessionExpiryInterval != nil {
+		setUint("session_expiry_interval", uint64(*p.SessionExpiryInterval))
+	}
+	setBytes("assigned_client_id", p.AssignedClientID)
+	if p.ServerKeepAlive != nil {
+		setUint("server_keep_alive", uint64(*p.ServerKeepAlive))
+	}
+	setBytes("auth_method", p.AuthMethod)
+	setBytes("reason_string", p.ReasonString)
+	if p.ReceiveMaximum != nil {
+		setUint("receive_maximum", uint64(*p.ReceiveMaximum))
+	}
+	if p.TopicAliasMaximum != nil {
+		setUint("topic_alias_maximum", uint64(*p.TopicAliasMaximum))
+	}
+	if p.TopicAlias != nil {
+		setUint("topic_alias", uint64(*p.TopicAlias))
+	}
+	if p.MaximumPacketSize != nil {
+		setUint("maximum_packet_size", uint64(*p.MaximumPacketSize))
+	}
+	var users []*TraceUserProperty
+	for _, v := range p.User {
+		users = append(users, &TraceUserProperty{Key: string(v.K), Value: string(v.V)})
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	return m, users
+}
@@ -0,0 +1,128 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package admin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// TraceServiceClient is the client API for TraceService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TraceServiceClient interface {
+	// Start a time-boxed trace, stream the packets and decisions of the matched clients.
+	// The trace stops when the duration elapses, the max_events is reached or the request is canceled.
+	Start(ctx context.Context, in *TraceRequest, opts ...grpc.CallOption) (TraceService_StartClient, error)
+}
+
+type traceServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTraceServiceClient(cc grpc.ClientConnInterface) TraceServiceClient {
+	return &traceServiceClient{cc}
+}
+
+func (c *traceServiceClient) Start(ctx context.Context, in *TraceRequest, opts ...grpc.CallOption) (TraceService_StartClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TraceService_serviceDesc.Streams[0], "/gmqtt.admin.api.TraceService/Start", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traceServiceStartClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TraceService_StartClient interface {
+	Recv() (*TraceEvent, error)
+	grpc.ClientStream
+}
+
+type traceServiceStartClient struct {
+	grpc.ClientStream
+}
+
+func (x *traceServiceStartClient) Recv() (*TraceEvent, error) {
+	m := new(TraceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TraceServiceServer is the server API for TraceService service.
+// All implementations must embed UnimplementedTraceServiceServer
+// for forward compatibility
+type TraceServiceServer interface {
+	// Start a time-boxed trace, stream the packets and decisions of the matched clients.
+	// The trace stops when the duration elapses, the max_events is reached or the request is canceled.
+	Start(*TraceRequest, TraceService_StartServer) error
+	mustEmbedUnimplementedTraceServiceServer()
+}
+
+// UnimplementedTraceServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTraceServiceServer struct {
+}
+
+func (UnimplementedTraceServiceServer) Start(*TraceRequest, TraceService_StartServer) error {
+	return status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedTraceServiceServer) mustEmbedUnimplementedTraceServiceServer() {}
+
+// UnsafeTraceServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TraceServiceServer will
+// result in compilation errors.
+type UnsafeTraceServiceServer interface {
+	mustEmbedUnimplementedTraceServiceServer()
+}
+
+func RegisterTraceServiceServer(s grpc.ServiceRegistrar, srv TraceServiceServer) {
+	s.RegisterService(&_TraceService_serviceDesc, srv)
+}
+
+func _TraceService_Start_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TraceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraceServiceServer).Start(m, &traceServiceStartServer{stream})
+}
+
+type TraceService_StartServer interface {
+	Send(*TraceEvent) error
+	grpc.ServerStream
+}
+
+type traceServiceStartServer struct {
+	grpc.ServerStream
+}
+
+func (x *traceServiceStartServer) Send(m *TraceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _TraceService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.TraceService",
+	HandlerType: (*TraceServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Start",
+			Handler:       _TraceService_Start_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "trace.proto",
+}
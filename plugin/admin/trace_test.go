@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type testTraceService struct {
+	tracer chan server.Tracer
+}
+
+func (t *testTraceService) AddTracer(tracer server.Tracer) (remove func()) {
+	t.tracer <- tracer
+	return func() {}
+}
+
+type testTraceStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *TraceEvent
+}
+
+func (t *testTraceStream) Context() context.Context {
+	return t.ctx
+}
+
+func (t *testTraceStream) Send(ev *TraceEvent) error {
+	t.events <- ev
+	return nil
+}
+
+func TestTracer_Trace(t *testing.T) {
+	a := assert.New(t)
+	tr := newTracer(&TraceRequest{
+		ClientId:     "cid",
+		TopicFilter:  "a/#",
+		PayloadLimit: 3,
+	})
+	var tt = []struct {
+		event   *server.TraceEvent
+		matched bool
+	}{
+		{event: &server.TraceEvent{ClientID: "cid", Packet: &packets.Pingreq{}}, matched: false},
+		{event: &server.TraceEvent{ClientID: "cid2", Topic: "a/b", Packet: &packets.Publish{}}, matched: false},
+		{event: &server.TraceEvent{ClientID: "cid", Topic: "b", Packet: &packets.Publish{}}, matched: false},
+		{event: &server.TraceEvent{ClientID: "cid", Topic: "a/b", Packet: &packets.Publish{}}, matched: true},
+		{event: &server.TraceEvent{ClientID: "cid", Packet: &packets.Subscribe{Topics: []packets.Topic{{Name: "b"}, {Name: "a/+"}}}}, matched: true},
+		{event: &server.TraceEvent{ClientID: "cid", Packet: &packets.Unsubscribe{Topics: []string{"a"}}}, matched: true},
+		{event: &server.TraceEvent{ClientID: "cid", Type: server.TraceDecision, Topic: "a/c"}, matched: true},
+	}
+	for _, v := range tt {
+		tr.Trace(v.event)
+		a.Equal(v.matched, len(tr.events) == 1, v.event.Topic)
+		if len(tr.events) == 1 {
+			<-tr.events
+		}
+	}
+
+	tr.Trace(&server.TraceEvent{
+		Type:     server.TracePacketSent,
+		ClientID: "cid",
+		Username: "user",
+		Topic:    "a/b",
+		Packet: &packets.Publish{
+			Qos:      packets.Qos1,
+			PacketID: 2,
+			Retain:   true,
+			Payload:  []byte("12345"),
+			Properties: &packets.Properties{
+				ContentType: []byte("json"),
+				User:        []packets.UserProperty{{K: []byte("k"), V: []byte("v")}},
+			},
+		},
+	})
+	ev := <-tr.events
+	a.Equal(TraceEventType_TRACE_EVENT_TYPE_PACKET_SENT, ev.Type)
+	a.Equal("PUBLISH", ev.PacketType)
+	a.Equal("user", ev.Username)
+	a.Equal("a/b", ev.Topic)
+	a.EqualValues(1, ev.Qos)
+	a.EqualValues(2, ev.PacketId)
+	a.True(ev.Retained)
+	a.Equal("123", ev.Payload)
+	a.EqualValues(5, ev.PayloadSize)
+	a.Equal(map[string]string{"content_type": "json"}, ev.Properties)
+	a.Equal([]*TraceUserProperty{{Key: "k", Value: "v"}}, ev.UserProperties)
+
+	tr.Trace(&server.TraceEvent{
+		Type:     server.TraceDecision,
+		ClientID: "cid",
+		Topic:    "a/b",
+		Decision: server.DecisionMessageDropped,
+		Message:  &gmqtt.Message{Topic: "a/b", QoS: packets.Qos2, Payload: []byte("1")},
+		Err:      errors.New("queue full"),
+	})
+	ev = <-tr.events
+	a.Equal(TraceEventType_TRACE_EVENT_TYPE_DECISION, ev.Type)
+	a.Empty(ev.PacketType)
+	a.Equal(server.DecisionMessageDropped, ev.Decision)
+	a.Equal("queue full", ev.Reason)
+	a.EqualValues(2, ev.Qos)
+	a.Equal("1", ev.Payload)
+
+	// drop the events if the buffer is full
+	for i := 0; i < traceBufferSize+2; i++ {
+		tr.Trace(&server.TraceEvent{ClientID: "cid", Topic: "a"})
+	}
+	a.EqualValues(2, tr.dropped)
+}
+
+func TestTraceService_Start(t *testing.T) {
+	a := assert.New(t)
+	ts := &testTraceService{tracer: make(chan server.Tracer, 1)}
+	s := &traceService{a: &Admin{traceService: ts}}
+
+	var tt = []*TraceRequest{
+		{},
+		{ClientId: "cid", TopicFilter: "a/#/b"},
+		{ClientId: "cid", Duration: maxTraceDuration + 1},
+		{ClientId: "cid", PayloadLimit: maxTracePayloadLimit + 1},
+	}
+	for _, v := range tt {
+		err := s.Start(v, &testTraceStream{ctx: context.Background()})
+		a.Equal(codes.InvalidArgument, status.Code(err))
+	}
+
+	stream := &testTraceStream{ctx: context.Background(), events: make(chan *TraceEvent, 10)}
+	done := make(chan error)
+	go func() {
+		done <- s.Start(&TraceRequest{ClientId: "cid", MaxEvents: 2}, stream)
+	}()
+	tr := <-ts.tracer
+	tr.Trace(&server.TraceEvent{ClientID: "cid", Packet: &packets.Pingreq{}})
+	tr.Trace(&server.TraceEvent{ClientID: "cid2", Packet: &packets.Pingreq{}})
+	tr.Trace(&server.TraceEvent{ClientID: "cid", Packet: &packets.Pingresp{}})
+	select {
+	case err := <-done:
+		a.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("trace did not stop after max_events")
+	}
+	a.Equal("PINGREQ", (<-stream.events).PacketType)
+	a.Equal("PINGRESP", (<-stream.events).PacketType)
+
+	// stop when the request is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- s.Start(&TraceRequest{ClientId: "cid"}, &testTraceStream{ctx: ctx})
+	}()
+	<-ts.tracer
+	cancel()
+	select {
+	case err := <-done:
+		a.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("trace did not stop after canceled")
+	}
+}
@@ -3,40 +3,48 @@ package admin
 import (
 	"context"
 	"fmt"
+	"html"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/server"
 )
 
 const (
-	loginUsername         = "admin"
-	loginPassword         = "admin"
 	sessionCookieName     = "gmqtt_admin_session"
-	sessionCookieValue    = "authenticated"
 	loginErrorParam       = "error"
 	loginErrorCredentials = "credentials"
 	loginErrorForm        = "form"
 	loginErrorRequired    = "unauthorized"
 )
 
-func registerAdminUI(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
+// webUI serves the admin web UI.
+// If auth is nil, the authentication is disabled and the web UI is not served.
+type webUI struct {
+	auth *authenticator
+}
+
+func (ui *webUI) register(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) error {
 	_ = ctx
 	_ = endpoint
 	_ = opts
 
-	if err := handleStaticPath(mux, "GET", "/", serveLoginPage); err != nil {
+	if err := handleStaticPath(mux, "GET", "/", ui.serveLoginPage); err != nil {
 		return err
 	}
-	if err := handleStaticPath(mux, "GET", "/dashboard", serveDashboardPage); err != nil {
+	if err := handleStaticPath(mux, "GET", "/dashboard", ui.serveDashboardPage); err != nil {
 		return err
 	}
-	if err := handleStaticPath(mux, "POST", "/login", handleLogin); err != nil {
+	if err := handleStaticPath(mux, "POST", "/login", ui.handleLogin); err != nil {
 		return err
 	}
-	if err := handleStaticPath(mux, "POST", "/logout", handleLogout); err != nil {
+	if err := handleStaticPath(mux, "POST", "/logout", ui.handleLogout); err != nil {
 		return err
 	}
 	return nil
@@ -74,8 +82,33 @@ func handleStaticPath(mux *runtime.ServeMux, method, path string, h runtime.Hand
 	return nil
 }
 
-func serveLoginPage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	if isAuthenticated(r) {
+// disabled writes the error response and returns true if the authentication is disabled.
+func (ui *webUI) disabled(w http.ResponseWriter) bool {
+	if ui.auth != nil {
+		return false
+	}
+	http.Error(w, "the web UI requires the authentication, set plugins.admin.auth.enable to true", http.StatusForbidden)
+	return true
+}
+
+// session returns the identity and the session cookie value of the request.
+func (ui *webUI) session(r *http.Request) (*identity, string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, "", false
+	}
+	id, err := ui.auth.verifySession(cookie.Value)
+	if err != nil {
+		return nil, "", false
+	}
+	return id, cookie.Value, true
+}
+
+func (ui *webUI) serveLoginPage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if ui.disabled(w) {
+		return
+	}
+	if _, _, ok := ui.session(r); ok {
 		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 		return
 	}
@@ -83,60 +116,91 @@ func serveLoginPage(w http.ResponseWriter, r *http.Request, _ map[string]string)
 	writeLoginPage(w, errorCode)
 }
 
-func serveDashboardPage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	if !isAuthenticated(r) {
+func (ui *webUI) serveDashboardPage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if ui.disabled(w) {
+		return
+	}
+	id, value, ok := ui.session(r)
+	if !ok {
 		http.Redirect(w, r, "/?"+loginErrorParam+"="+loginErrorRequired, http.StatusSeeOther)
 		return
 	}
-	writeDashboardPage(w)
+	writeDashboardPage(w, id, ui.auth.csrfToken(value))
 }
 
-func handleLogin(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+func (ui *webUI) handleLogin(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if ui.disabled(w) {
+		return
+	}
 	if err := r.ParseForm(); err != nil {
 		http.Redirect(w, r, "/?"+loginErrorParam+"="+loginErrorForm, http.StatusSeeOther)
 		return
 	}
 	username := r.FormValue("username")
-	password := r.FormValue("password")
-	if username == loginUsername && password == loginPassword {
-		setSessionCookie(w)
-		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+	id, err := ui.auth.login(username, r.FormValue("password"))
+	record := &server.AuditRecord{
+		Actor:     username,
+		SourceIP:  remoteIP(r),
+		Operation: "admin.login",
+	}
+	if err != nil {
+		log.Warn("admin login failed", zap.String("username", username), zap.String("remote_addr", r.RemoteAddr))
+		record.Error = "invalid credentials"
+		ui.auth.audit(record)
+		http.Redirect(w, r, "/?"+loginErrorParam+"="+loginErrorCredentials, http.StatusSeeOther)
 		return
 	}
-	http.Redirect(w, r, "/?"+loginErrorParam+"="+loginErrorCredentials, http.StatusSeeOther)
-}
-
-func handleLogout(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-	clearSessionCookie(w)
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	value, expiry, err := ui.auth.newSession(id.name)
+	if err != nil {
+		log.Error("fail to create admin session", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	ui.setSessionCookie(w, value, expiry)
+	ui.auth.audit(record)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
 }
 
-func isAuthenticated(r *http.Request) bool {
-	cookie, err := r.Cookie(sessionCookieName)
-	if err != nil {
-		return false
+func (ui *webUI) handleLogout(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if ui.disabled(w) {
+		return
+	}
+	id, value, ok := ui.session(r)
+	if ok && !ui.auth.verifyCSRFToken(value, r.PostFormValue(csrfFormField)) {
+		writeHTTPError(w, errInvalidCSRFToken)
+		return
 	}
-	return cookie.Value == sessionCookieValue
+	if ok {
+		ui.auth.audit(&server.AuditRecord{
+			Actor:     id.name,
+			SourceIP:  remoteIP(r),
+			Operation: "admin.logout",
+		})
+	}
+	ui.clearSessionCookie(w)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-func setSessionCookie(w http.ResponseWriter) {
+func (ui *webUI) setSessionCookie(w http.ResponseWriter, value string, expiry time.Time) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
-		Value:    sessionCookieValue,
+		Value:    value,
 		Path:     "/",
 		HttpOnly: true,
+		Secure:   ui.auth.config.SecureCookie,
 		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(12 * time.Hour),
+		Expires:  expiry,
 	})
 }
 
-func clearSessionCookie(w http.ResponseWriter) {
+func (ui *webUI) clearSessionCookie(w http.ResponseWriter) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    "",
 		Path:     "/",
 		MaxAge:   -1,
 		HttpOnly: true,
+		Secure:   ui.auth.config.SecureCookie,
 		SameSite: http.SameSiteLaxMode,
 		Expires:  time.Unix(0, 0),
 	})
@@ -194,12 +258,17 @@ func writeLoginPage(w http.ResponseWriter, errorCode string) {
 </html>`))
 }
 
-func writeDashboardPage(w http.ResponseWriter) {
+func writeDashboardPage(w http.ResponseWriter, id *identity, csrfToken string) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var userBadge string
+	if id != nil {
+		userBadge = `<span class="badge">` + html.EscapeString(id.name) + " (" + html.EscapeString(string(id.role)) + ")</span>"
+	}
 	_, _ = w.Write([]byte(`<!DOCTYPE html>
 <html lang="en">
 <head>
 <meta charset="utf-8">
+<meta name="csrf-token" content="` + html.EscapeString(csrfToken) + `">
 <title>GMQTT 管理控制台</title>
 <style>
 :root { color-scheme: dark; }
@@ -207,6 +276,7 @@ func writeDashboardPage(w http.ResponseWriter) {
 body { font-family: Arial, sans-serif; background: #0e1628; color: #f5f6f8; margin: 0; }
 header { display: flex; justify-content: space-between; align-items: center; padding: 20px 32px; background: #101a32; box-shadow: 0 4px 20px rgba(0,0,0,0.35); }
 h1 { margin: 0; font-size: 24px; }
+header form { display: flex; gap: 12px; align-items: center; }
 main { padding: 24px 32px; display: grid; gap: 24px; }
 @media (min-width: 1100px) {
 	main { grid-template-columns: repeat(2, minmax(0, 1fr)); }
@@ -244,6 +314,8 @@ form .actions { display: flex; gap: 12px; align-items: center; }
 <header>
 	<h1>GMQTT 管理控制台</h1>
 	<form method="post" action="/logout">
+		` + userBadge + `
+		<input type="hidden" name="csrf_token" value="` + html.EscapeString(csrfToken) + `">
 		<button class="btn btn-secondary" type="submit">退出登录</button>
 	</form>
 </header>
@@ -380,6 +452,18 @@ form .actions { display: flex; gap: 12px; align-items: center; }
 	</section>
 </main>
 <script>
+const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+
+async function apiFetch(url, options) {
+	options = options || {};
+	options.headers = Object.assign({}, options.headers, { "X-CSRF-Token": csrfToken });
+	const res = await fetch(url, options);
+	if (res.status === 401) {
+		window.location.href = "/?error=unauthorized";
+	}
+	return res;
+}
+
 const clientsRefreshBtn = document.getElementById("clients-refresh");
 const clientsStatus = document.getElementById("clients-status");
 const clientsBody = document.getElementById("clients-body");
@@ -390,7 +474,7 @@ async function loadClients() {
 	clientsStatus.className = "status";
 	clientsStatus.textContent = "正在获取客户端信息...";
 	try {
-		const res = await fetch("/v1/clients");
+		const res = await apiFetch("/v1/clients");
 		if (!res.ok) {
 			const text = await res.text();
 			throw new Error(text || "请求失败");
@@ -476,7 +560,7 @@ subscriptionsForm.addEventListener("submit", async function(event) {
 	}
 	const url = "/v1/filter_subscriptions" + (params.toString() ? "?" + params.toString() : "");
 	try {
-		const res = await fetch(url);
+		const res = await apiFetch(url);
 		if (!res.ok) {
 			const text = await res.text();
 			throw new Error(text || "请求失败");
@@ -533,7 +617,7 @@ publishForm.addEventListener("submit", async function(event) {
 		body.payload_format = Number(payloadFormat);
 	}
 	try {
-		const res = await fetch("/v1/publish", {
+		const res = await apiFetch("/v1/publish", {
 			method: "POST",
 			headers: {
 				"Content-Type": "application/json"
@@ -564,3 +648,12 @@ publishForm.addEventListener("submit", async function(event) {
 </body>
 </html>`))
 }
+
+// remoteIP returns the IP of the remote address of the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
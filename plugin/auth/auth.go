@@ -53,6 +53,8 @@ type Auth struct {
 	indexer *admin.Indexer
 	// saveFile persists the account data to password file.
 	saveFile func() error
+	// auditLogger records the account changes, it is nil in tests.
+	auditLogger server.AuditLogger
 }
 
 // generatePassword generates the hashed password for the plain password.
@@ -118,6 +120,7 @@ func (a *Auth) validate(username, password string) (permitted bool, err error) {
 
 var registerAPI = func(service server.Server, a *Auth) error {
 	apiRegistrar := service.APIRegistrar()
+	a.auditLogger = service.AuditLogger()
 	RegisterAccountServiceServer(apiRegistrar, a)
 	err := apiRegistrar.RegisterHTTPHandler(RegisterAccountServiceHandlerFromEndpoint)
 	return err
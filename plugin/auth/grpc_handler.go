@@ -12,6 +12,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/DrmagicE/gmqtt/plugin/admin"
+	"github.com/DrmagicE/gmqtt/server"
 )
 
 // List lists all accounts
@@ -48,6 +49,24 @@ func (a *Auth) Get(ctx context.Context, req *GetAccountRequest) (resp *GetAccoun
 	return nil, admin.ErrNotFound
 }
 
+// audit records the account change made through the API.
+func (a *Auth) audit(ctx context.Context, operation string, username string, err error) {
+	if a.auditLogger == nil {
+		return
+	}
+	actor, sourceIP := server.AuditSourceFromContext(ctx)
+	r := &server.AuditRecord{
+		Actor:     actor,
+		SourceIP:  sourceIP,
+		Operation: operation,
+		Target:    username,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	a.auditLogger.Audit(r)
+}
+
 // saveFileHandler is the default handler for auth.saveFile, must call after auth.mu is locked
 func (a *Auth) saveFileHandler() error {
 	tmpfile, err := ioutil.TempFile("./", "gmqtt_password")
@@ -106,6 +125,7 @@ func (a *Auth) Update(ctx context.Context, req *UpdateAccountRequest) (resp *emp
 		// should rollback if failed to persist to file.
 		if oact == nil {
 			a.indexer.Remove(req.Username)
+			a.audit(ctx, "auth.account.create", req.Username, err)
 			return &empty.Empty{}, err
 		}
 		a.indexer.Set(req.Username, &Account{
@@ -115,8 +135,10 @@ func (a *Auth) Update(ctx context.Context, req *UpdateAccountRequest) (resp *emp
 	}
 	if oact == nil {
 		log.Info("new account created", zap.String("username", req.Username))
+		a.audit(ctx, "auth.account.create", req.Username, err)
 	} else {
 		log.Info("password updated", zap.String("username", req.Username))
+		a.audit(ctx, "auth.account.update", req.Username, err)
 	}
 
 	return &empty.Empty{}, err
@@ -132,6 +154,7 @@ func (a *Auth) Delete(ctx context.Context, req *DeleteAccountRequest) (resp *emp
 	act := a.indexer.GetByID(req.Username)
 	if act == nil {
 		// fast path
+		a.audit(ctx, "auth.account.delete", req.Username, nil)
 		return &empty.Empty{}, nil
 	}
 	oact := act.Value
@@ -143,8 +166,10 @@ func (a *Auth) Delete(ctx context.Context, req *DeleteAccountRequest) (resp *emp
 			Username: req.Username,
 			Password: oact.(*Account).Password,
 		})
+		a.audit(ctx, "auth.account.delete", req.Username, err)
 		return &empty.Empty{}, err
 	}
 	log.Info("account deleted", zap.String("username", req.Username))
+	a.audit(ctx, "auth.account.delete", req.Username, nil)
 	return &empty.Empty{}, nil
 }
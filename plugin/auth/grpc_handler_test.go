@@ -12,6 +12,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
 )
 
 func TestAuth_List_Get_Delete(t *testing.T) {
@@ -208,3 +209,53 @@ func TestAuth_saveFileHandler(t *testing.T) {
 	a.Equal("p11", rs[0].Password)
 
 }
+
+type testAuditLogger struct {
+	records []*server.AuditRecord
+}
+
+func (t *testAuditLogger) Audit(record *server.AuditRecord) {
+	t.records = append(t.records, record)
+}
+
+func (t *testAuditLogger) Query(query server.AuditQuery) ([]*server.AuditRecord, int, error) {
+	return nil, 0, server.ErrAuditQueryNotSupported
+}
+
+func TestAuth_Audit(t *testing.T) {
+	a := assert.New(t)
+	path := "./testdata/gmqtt_password.yml"
+	cfg := DefaultConfig
+	cfg.PasswordFile = path
+	cfg.Hash = Plain
+	auth, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			"auth": &cfg,
+		},
+	})
+	a.Nil(err)
+	err = auth.Load(nil)
+	a.Nil(err)
+	au := auth.(*Auth)
+	au.saveFile = func() error {
+		return nil
+	}
+	al := &testAuditLogger{}
+	au.auditLogger = al
+	ctx := server.WithAuditActor(context.Background(), "admin")
+
+	_, err = au.Update(ctx, &UpdateAccountRequest{Username: "u1", Password: "p2"})
+	a.Nil(err)
+	_, err = au.Update(ctx, &UpdateAccountRequest{Username: "u3", Password: "p3"})
+	a.Nil(err)
+	au.saveFile = func() error {
+		return errors.New("some error")
+	}
+	_, err = au.Delete(ctx, &DeleteAccountRequest{Username: "u1"})
+	a.NotNil(err)
+
+	a.Len(al.records, 3)
+	a.Equal(&server.AuditRecord{Actor: "admin", Operation: "auth.account.update", Target: "u1"}, al.records[0])
+	a.Equal(&server.AuditRecord{Actor: "admin", Operation: "auth.account.create", Target: "u3"}, al.records[1])
+	a.Equal(&server.AuditRecord{Actor: "admin", Operation: "auth.account.delete", Target: "u1", Error: "some error"}, al.records[2])
+}
@@ -0,0 +1,115 @@
+package federation
+
+import (
+	"context"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// The metadata keys of the audit actor and source IP forwarded by CallNode.
+const (
+	auditActorMetadataKey    = "audit_actor"
+	auditSourceIPMetadataKey = "audit_source_ip"
+)
+
+// NodeName returns the name of the local node.
+func (f *Federation) NodeName() string {
+	return f.nodeName
+}
+
+// Nodes returns the names of the local node and all peers, sorted by name.
+func (f *Federation) Nodes() []string {
+	f.memberMu.Lock()
+	nodes := make([]string, 0, len(f.peers)+1)
+	nodes = append(nodes, f.nodeName)
+	for k := range f.peers {
+		nodes = append(nodes, k)
+	}
+	f.memberMu.Unlock()
+	sort.Strings(nodes)
+	return nodes
+}
+
+// RegisterClusterHandler registers the handler of the method.
+func (f *Federation) RegisterClusterHandler(method string, handler server.ClusterHandler) {
+	f.handlersMu.Lock()
+	defer f.handlersMu.Unlock()
+	f.handlers[method] = handler
+}
+
+func (f *Federation) handle(ctx context.Context, method string, req []byte) ([]byte, error) {
+	f.handlersMu.RLock()
+	h := f.handlers[method]
+	f.handlersMu.RUnlock()
+	if h == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method: %s", method)
+	}
+	return h(ctx, req)
+}
+
+// CallNode calls the handler of the method on the node.
+func (f *Federation) CallNode(ctx context.Context, nodeName string, method string, req []byte) ([]byte, error) {
+	if nodeName == f.nodeName {
+		return f.handle(ctx, method, req)
+	}
+	f.memberMu.Lock()
+	p := f.peers[nodeName]
+	f.memberMu.Unlock()
+	if p == nil {
+		return nil, status.Errorf(codes.NotFound, "node [%s] not found", nodeName)
+	}
+	resp, err := p.call(ctx, &CallRequest{
+		Method:  method,
+		Payload: req,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// Call is the handler of the requests sent by CallNode from other nodes.
+// The caller must be a member of the federation, see authenticatePeer.
+// The audit actor and source IP of the request are forwarded from the calling node,
+// and the handler can get the name of the calling node by server.ClusterCallerFromContext.
+func (f *Federation) Call(ctx context.Context, req *CallRequest) (*CallResponse, error) {
+	nodeName, err := f.authenticatePeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = server.WithClusterCaller(ctx, nodeName)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(auditActorMetadataKey); len(v) != 0 {
+			ctx = server.WithAuditActor(ctx, v[0])
+		}
+		if v := md.Get(auditSourceIPMetadataKey); len(v) != 0 {
+			ctx = server.WithAuditSourceIP(ctx, v[0])
+		}
+	}
+	payload, err := f.handle(ctx, req.Method, req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return &CallResponse{Payload: payload}, nil
+}
+
+// call calls the Call method of the peer.
+func (p *peer) call(ctx context.Context, req *CallRequest) (*CallResponse, error) {
+	p.stateMu.Lock()
+	client := p.client
+	p.stateMu.Unlock()
+	if client == nil {
+		return nil, status.Errorf(codes.Unavailable, "node [%s] is not connected", p.member.Name)
+	}
+	actor, sourceIP := server.AuditSourceFromContext(ctx)
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs(
+		"node_name", p.localName,
+		auditActorMetadataKey, actor,
+		auditSourceIPMetadataKey, sourceIP))
+	return client.Call(ctx, req)
+}
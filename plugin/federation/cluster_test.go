@@ -0,0 +1,82 @@
+package federation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/serf/serf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestFederation_Cluster(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.nodeJoin(serf.MemberEvent{
+		Members: []serf.Member{
+			{Name: "node2"}, {Name: "node1"},
+		},
+	})
+	a.Equal("node0", f.NodeName())
+	a.Equal([]string{"node0", "node1", "node2"}, f.Nodes())
+
+	f.RegisterClusterHandler("echo", func(ctx context.Context, req []byte) ([]byte, error) {
+		return append([]byte("echo:"), req...), nil
+	})
+	f.RegisterClusterHandler("whoami", func(ctx context.Context, req []byte) ([]byte, error) {
+		actor, sourceIP := server.AuditSourceFromContext(ctx)
+		return []byte(server.ClusterCallerFromContext(ctx) + "," + actor + "," + sourceIP), nil
+	})
+	resp, err := f.CallNode(context.Background(), "node0", "echo", []byte("1"))
+	a.NoError(err)
+	a.Equal([]byte("echo:1"), resp)
+	_, err = f.CallNode(context.Background(), "node0", "unknown", nil)
+	a.Equal(codes.Unimplemented, status.Code(err))
+
+	// the requests from other nodes
+	_, err = f.Call(context.Background(), &CallRequest{Method: "echo"})
+	a.Error(err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("node_name", "node1"))
+	callResp, err := f.Call(ctx, &CallRequest{Method: "echo", Payload: []byte("2")})
+	a.NoError(err)
+	a.Equal([]byte("echo:2"), callResp.Payload)
+	// not a member
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("node_name", "node3"))
+	_, err = f.Call(ctx, &CallRequest{Method: "echo"})
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	// the audit identity is forwarded from the calling node
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("node_name", "node1", "audit_actor", "admin", "audit_source_ip", "10.0.0.1"))
+	callResp, err = f.Call(ctx, &CallRequest{Method: "whoami"})
+	a.NoError(err)
+	a.Equal([]byte("node1,admin,10.0.0.1"), callResp.Payload)
+	resp, err = f.CallNode(context.Background(), "node0", "whoami", nil)
+	a.NoError(err)
+	a.Equal([]byte(",anonymous,"), resp)
+
+	_, err = f.CallNode(context.Background(), "node3", "echo", nil)
+	a.Equal(codes.NotFound, status.Code(err))
+	_, err = f.CallNode(context.Background(), "node1", "echo", nil)
+	a.Equal(codes.Unavailable, status.Code(err))
+
+	client := NewMockFederationClient(ctrl)
+	f.peers["node1"].client = client
+	client.EXPECT().Call(gomock.Any(), &CallRequest{Method: "echo", Payload: []byte("3")}).DoAndReturn(
+		func(ctx context.Context, req *CallRequest, opts ...grpc.CallOption) (*CallResponse, error) {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			a.Equal([]string{"node0"}, md.Get("node_name"))
+			a.Equal([]string{"admin"}, md.Get("audit_actor"))
+			return &CallResponse{Payload: []byte("echo:3")}, nil
+		})
+	resp, err = f.CallNode(server.WithAuditActor(context.Background(), "admin"), "node1", "echo", []byte("3"))
+	a.NoError(err)
+	a.Equal([]byte("echo:3"), resp)
+}
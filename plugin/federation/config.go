@@ -1,6 +1,7 @@
 package federation
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -17,6 +18,35 @@ const (
 	DefaultGossipPort    = "8902"
 	DefaultRetryInterval = 5 * time.Second
 	DefaultRetryTimeout  = 1 * time.Minute
+	// DefaultTakeoverTimeout is the default timeout to take over the session from another node.
+	DefaultTakeoverTimeout = 5 * time.Second
+	// DefaultDiscoveryInterval is the default interval to discover the peers.
+	DefaultDiscoveryInterval = 30 * time.Second
+	// DefaultPeerQueueSize is the default max number of the events in the queue of each peer.
+	DefaultPeerQueueSize = 100000
+	// DefaultBlockTimeout is the default timeout to wait for the queue space with the block overflow policy.
+	DefaultBlockTimeout = 5 * time.Second
+	// DefaultBatchSize is the default max number of the events in a batch.
+	DefaultBatchSize = 100
+)
+
+// Overflow policies of the peer queue.
+const (
+	OverflowDropQoS0   = "drop_qos0"
+	OverflowBlock      = "block"
+	OverflowDisconnect = "disconnect"
+)
+
+// Compression algorithms of the event stream.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// DNS record types for the peer discovery.
+const (
+	DNSTypeA   = "a"
+	DNSTypeSRV = "srv"
 )
 
 // stub function for testing
@@ -60,6 +90,231 @@ type Config struct {
 	// the cluster until an explicit join is received. If this is set to
 	// true, we ignore the leave, and rejoin the cluster on start.
 	RejoinAfterLeave bool `yaml:"rejoin_after_leave"`
+	// TLS is the mTLS configuration for the federation gRPC server and client.
+	TLS TLSConfig `yaml:"tls"`
+	// GossipEncryption is the encryption configuration for the gossip.
+	GossipEncryption GossipEncryption `yaml:"gossip_encryption"`
+	// SessionTakeover is the configuration for taking over the session when the client reconnects to another node.
+	SessionTakeover SessionTakeover `yaml:"session_takeover"`
+	// Discovery is the configuration for discovering the peers by DNS or the peers file.
+	Discovery Discovery `yaml:"discovery"`
+	// PeerQueue is the configuration for the event queue of each peer.
+	PeerQueue PeerQueue `yaml:"peer_queue"`
+}
+
+// SessionTakeover is the configuration for the cross-node session takeover.
+// When a client connects to a node while its session is on another node,
+// the other node disconnects the old client and hands over the session state (subscriptions and queued messages)
+// to the node that the client connects to.
+type SessionTakeover struct {
+	// Disable disables the session takeover, the session on the other node will be left there until it expires.
+	Disable bool `yaml:"disable"`
+	// Timeout is the timeout to take over the session from another node.
+	// If timeout expires, the client connects with a new session. Defaults to 5s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (s *SessionTakeover) timeout() time.Duration {
+	if s.Timeout == 0 {
+		return DefaultTakeoverTimeout
+	}
+	return s.Timeout
+}
+
+// Discovery is the configuration for discovering the peers to join, in addition to RetryJoin.
+// The peers are discovered periodically, and the discovered peers which are not alive members of the cluster are joined,
+// so that the node rejoins the cluster automatically as the membership changes.
+type Discovery struct {
+	// DNSName is the DNS name to discover the peers, e.g. the headless service in Kubernetes.
+	DNSName string `yaml:"dns_name"`
+	// DNSType is the type of the DNS records to query: a | srv. Defaults to a.
+	// For "a", the A and AAAA records of DNSName are the addresses of the peers, and DNSPort is the gossip port.
+	// For "srv", DNSName is the full name of the SRV records, e.g. _gossip._tcp.gmqtt.default.svc.cluster.local,
+	// and the targets and ports of the records are the gossip addresses of the peers.
+	DNSType string `yaml:"dns_type"`
+	// DNSPort is the gossip port of the peers discovered by A records. Defaults to 8902.
+	DNSPort int `yaml:"dns_port"`
+	// PeersFile is the path of the file which lists the gossip addresses of the peers, one address per line.
+	// Empty lines and lines beginning with '#' are ignored. If the port is missing, the default gossip port (8902) will be used.
+	// The file is watched, and the peers are joined once the file is changed.
+	PeersFile string `yaml:"peers_file"`
+	// RefreshInterval is the interval to resolve the DNS name and join the discovered peers. Defaults to 30s.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+func (d *Discovery) enabled() bool {
+	return d.DNSName != "" || d.PeersFile != ""
+}
+
+func (d *Discovery) interval() time.Duration {
+	if d.RefreshInterval == 0 {
+		return DefaultDiscoveryInterval
+	}
+	return d.RefreshInterval
+}
+
+func (d *Discovery) dnsType() string {
+	if d.DNSType == "" {
+		return DNSTypeA
+	}
+	return d.DNSType
+}
+
+func (d *Discovery) dnsPort() int {
+	if d.DNSPort == 0 {
+		p, _ := strconv.Atoi(DefaultGossipPort)
+		return p
+	}
+	return d.DNSPort
+}
+
+func (d *Discovery) validate() error {
+	if t := d.dnsType(); t != DNSTypeA && t != DNSTypeSRV {
+		return fmt.Errorf("invalid discovery.dns_type: %s", d.DNSType)
+	}
+	if !isPortNumber(strconv.Itoa(d.dnsPort())) {
+		return fmt.Errorf("invalid discovery.dns_port: %d", d.DNSPort)
+	}
+	if d.RefreshInterval < 0 {
+		return fmt.Errorf("invalid discovery.refresh_interval: %d", d.RefreshInterval)
+	}
+	return nil
+}
+
+// PeerQueue is the configuration for the queue which buffers the events to send to each peer,
+// and for the event stream which sends them.
+type PeerQueue struct {
+	// MaxSize is the max number of the events in the queue, including the events which are sent but not acked.
+	// Defaults to 100000.
+	// The events of the full state sync after the peer reconnects with a clean start are not limited by MaxSize,
+	// so it should be large enough to hold the subscriptions, retained messages and sessions of the node.
+	MaxSize int `yaml:"max_size"`
+	// OverflowPolicy is the action to take when the queue is full: drop_qos0 | block | disconnect. Defaults to drop_qos0.
+	// drop_qos0: drops the new event if it is a QoS 0 message, otherwise drops the oldest QoS 0 message which is not sent.
+	// block: blocks the messages published by the clients in OnMsgArrived until the queue has space, for at most BlockTimeout.
+	// The other events are added under the broker lock, they never block and fall back to disconnect.
+	// disconnect: disconnects the peer and discards the queue, the peer rebuilds the full state on reconnect.
+	// If drop_qos0 has nothing to drop or block times out, it falls back to disconnect,
+	// since dropping other events (e.g. subscriptions) would make the routing state of the peer inconsistent.
+	OverflowPolicy string `yaml:"overflow_policy"`
+	// BlockTimeout is the timeout to wait for the queue space with the block policy. Defaults to 5s.
+	// Note that the hooks of all clients which publish to the cluster are blocked while waiting.
+	BlockTimeout time.Duration `yaml:"block_timeout"`
+	// BatchSize is the max number of the events which are sent in a batch. Defaults to 100.
+	BatchSize int `yaml:"batch_size"`
+	// Compression is the compression algorithm of the batches: none | gzip. Defaults to gzip.
+	// The events are sent one by one without compression to the peers that do not support batching.
+	Compression string `yaml:"compression"`
+}
+
+func (p *PeerQueue) maxSize() int {
+	if p.MaxSize == 0 {
+		return DefaultPeerQueueSize
+	}
+	return p.MaxSize
+}
+
+func (p *PeerQueue) overflowPolicy() string {
+	if p.OverflowPolicy == "" {
+		return OverflowDropQoS0
+	}
+	return p.OverflowPolicy
+}
+
+func (p *PeerQueue) blockTimeout() time.Duration {
+	if p.BlockTimeout == 0 {
+		return DefaultBlockTimeout
+	}
+	return p.BlockTimeout
+}
+
+func (p *PeerQueue) batchSize() int {
+	if p.BatchSize == 0 {
+		return DefaultBatchSize
+	}
+	return p.BatchSize
+}
+
+func (p *PeerQueue) compression() string {
+	if p.Compression == "" {
+		return CompressionGzip
+	}
+	return p.Compression
+}
+
+func (p *PeerQueue) validate() error {
+	if p.MaxSize < 0 {
+		return fmt.Errorf("invalid peer_queue.max_size: %d", p.MaxSize)
+	}
+	switch p.overflowPolicy() {
+	case OverflowDropQoS0, OverflowBlock, OverflowDisconnect:
+	default:
+		return fmt.Errorf("invalid peer_queue.overflow_policy: %s", p.OverflowPolicy)
+	}
+	if p.BlockTimeout < 0 {
+		return fmt.Errorf("invalid peer_queue.block_timeout: %d", p.BlockTimeout)
+	}
+	if p.BatchSize < 0 {
+		return fmt.Errorf("invalid peer_queue.batch_size: %d", p.BatchSize)
+	}
+	if c := p.compression(); c != CompressionNone && c != CompressionGzip {
+		return fmt.Errorf("invalid peer_queue.compression: %s", p.Compression)
+	}
+	return nil
+}
+
+// TLSConfig is the mTLS configuration for the federation gRPC server and client.
+// If enabled, each node presents its certificate to the peers and verifies the certificate of the peers
+// with the CA, so the certificate must be valid for both server and client authentication.
+type TLSConfig struct {
+	// Enable indicates whether to enable mTLS.
+	Enable bool `yaml:"enable"`
+	// CertFile is the path of the PEM encoded certificate of the node.
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the path of the PEM encoded private key of the node.
+	KeyFile string `yaml:"key_file"`
+	// CAFile is the path of the PEM encoded CA certificates which are used to verify the certificates of the peers.
+	CAFile string `yaml:"ca_file"`
+	// AllowedNames is the names that the certificates of the peers must match, either the common name or one of the DNS SANs.
+	// If empty, any certificate signed by the CA is allowed.
+	// The host names of the peers are not verified, since the peers are usually addressed by IP.
+	AllowedNames []string `yaml:"allowed_names"`
+}
+
+// GossipEncryption is the encryption configuration for the gossip.
+// If enabled, the gossip messages are encrypted with the primary key of the keyring,
+// and the nodes that don't have the key can not join the cluster.
+type GossipEncryption struct {
+	// Keys is the base64 encoded keys of the keyring, each key must be 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256.
+	// The first key is the primary key which is used to encrypt messages, the others are only used to decrypt messages.
+	// Empty means the encryption is disabled unless the KeyringFile exists.
+	Keys []string `yaml:"keys"`
+	// KeyringFile is the path of the file which persists the keyring.
+	// The keys changed by the membership API are written into the file,
+	// and if the file exists at startup, the keys in the file take precedence over the Keys.
+	KeyringFile string `yaml:"keyring_file"`
+}
+
+func (t *TLSConfig) validate() error {
+	if !t.Enable {
+		return nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("missing tls cert_file or key_file")
+	}
+	if t.CAFile == "" {
+		return errors.New("missing tls ca_file")
+	}
+	return nil
+}
+
+func (g *GossipEncryption) validate() error {
+	for _, v := range g.Keys {
+		if _, err := decodeGossipKey(v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func isPortNumber(port string) bool {
@@ -149,7 +404,19 @@ func (c *Config) Validate() (err error) {
 	if c.RetryTimeout <= 0 {
 		return fmt.Errorf("invalid retry_timeout: %d", c.RetryTimeout)
 	}
-	return nil
+	if c.SessionTakeover.Timeout < 0 {
+		return fmt.Errorf("invalid session_takeover.timeout: %d", c.SessionTakeover.Timeout)
+	}
+	if err = c.TLS.validate(); err != nil {
+		return err
+	}
+	if err = c.Discovery.validate(); err != nil {
+		return err
+	}
+	if err = c.PeerQueue.validate(); err != nil {
+		return err
+	}
+	return c.GossipEncryption.validate()
 }
 
 // DefaultConfig is the default configuration.
@@ -167,6 +434,20 @@ func init() {
 		RetryJoin:     nil,
 		RetryInterval: DefaultRetryInterval,
 		RetryTimeout:  DefaultRetryTimeout,
+		SessionTakeover: SessionTakeover{
+			Timeout: DefaultTakeoverTimeout,
+		},
+		Discovery: Discovery{
+			DNSType:         DNSTypeA,
+			RefreshInterval: DefaultDiscoveryInterval,
+		},
+		PeerQueue: PeerQueue{
+			MaxSize:        DefaultPeerQueueSize,
+			OverflowPolicy: OverflowDropQoS0,
+			BlockTimeout:   DefaultBlockTimeout,
+			BatchSize:      DefaultBatchSize,
+			Compression:    CompressionGzip,
+		},
 	}
 }
 
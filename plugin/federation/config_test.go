@@ -214,6 +214,60 @@ func TestConfig_Validate(t *testing.T) {
 				RejoinAfterLeave:    false,
 			},
 			valid: true,
+		}, {
+			name: "invalidTLS",
+			cfg: &Config{
+				NodeName:      "name2",
+				FedAddr:       "127.0.0.1:1234",
+				GossipAddr:    "127.0.0.1:1235",
+				RetryInterval: 1,
+				RetryTimeout:  2,
+				TLS: TLSConfig{
+					Enable:   true,
+					CertFile: "node.pem",
+					KeyFile:  "node-key.pem",
+				},
+			},
+			valid: false,
+		}, {
+			name: "invalidGossipKey",
+			cfg: &Config{
+				NodeName:      "name2",
+				FedAddr:       "127.0.0.1:1234",
+				GossipAddr:    "127.0.0.1:1235",
+				RetryInterval: 1,
+				RetryTimeout:  2,
+				GossipEncryption: GossipEncryption{
+					// 8 bytes
+					Keys: []string{"MTIzNDU2Nzg="},
+				},
+			},
+			valid: false,
+		}, {
+			name: "gossipEncryption",
+			cfg: &Config{
+				NodeName:      "name2",
+				FedAddr:       "127.0.0.1:1234",
+				GossipAddr:    "127.0.0.1:1235",
+				RetryInterval: 1,
+				RetryTimeout:  2,
+				GossipEncryption: GossipEncryption{
+					Keys: []string{"MTIzNDU2Nzg5MDEyMzQ1Ng=="},
+				},
+			},
+			expected: &Config{
+				NodeName:            "name2",
+				FedAddr:             "127.0.0.1:1234",
+				AdvertiseFedAddr:    "127.0.0.1:1234",
+				GossipAddr:          "127.0.0.1:1235",
+				AdvertiseGossipAddr: "127.0.0.1:1235",
+				RetryInterval:       1,
+				RetryTimeout:        2,
+				GossipEncryption: GossipEncryption{
+					Keys: []string{"MTIzNDU2Nzg5MDEyMzQ1Ng=="},
+				},
+			},
+			valid: true,
 		},
 	}
 	for _, v := range tt {
@@ -232,3 +286,36 @@ func TestConfig_Validate(t *testing.T) {
 	}
 
 }
+
+func TestDiscovery_validate(t *testing.T) {
+	a := assert.New(t)
+	d := &Discovery{DNSName: "gmqtt"}
+	a.NoError(d.validate())
+	a.Equal(DNSTypeA, d.dnsType())
+	a.Equal(8902, d.dnsPort())
+	a.Equal(DefaultDiscoveryInterval, d.interval())
+	a.True(d.enabled())
+	a.False((&Discovery{}).enabled())
+
+	a.Error((&Discovery{DNSType: "txt"}).validate())
+	a.Error((&Discovery{DNSPort: 70000}).validate())
+	a.Error((&Discovery{RefreshInterval: -1}).validate())
+}
+
+func TestPeerQueue_validate(t *testing.T) {
+	a := assert.New(t)
+	p := &PeerQueue{}
+	a.NoError(p.validate())
+	a.Equal(DefaultPeerQueueSize, p.maxSize())
+	a.Equal(OverflowDropQoS0, p.overflowPolicy())
+	a.Equal(DefaultBlockTimeout, p.blockTimeout())
+	a.Equal(DefaultBatchSize, p.batchSize())
+	a.Equal(CompressionGzip, p.compression())
+	a.NoError((&PeerQueue{OverflowPolicy: OverflowBlock, Compression: CompressionNone}).validate())
+
+	a.Error((&PeerQueue{MaxSize: -1}).validate())
+	a.Error((&PeerQueue{OverflowPolicy: "drop"}).validate())
+	a.Error((&PeerQueue{BlockTimeout: -1}).validate())
+	a.Error((&PeerQueue{BatchSize: -1}).validate())
+	a.Error((&PeerQueue{Compression: "snappy"}).validate())
+}
@@ -0,0 +1,252 @@
+package federation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/serf/serf"
+	"go.uber.org/zap"
+)
+
+// peersFileCheckInterval is the interval to check whether the peers file has been changed.
+var peersFileCheckInterval = time.Second
+
+// discoveryTimeout is the timeout to resolve the peers in each discovery.
+const discoveryTimeout = 10 * time.Second
+
+// Resolver resolves the DNS records for the peer discovery, *net.Resolver implements it.
+// It can be replaced with a fake resolver for testing.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) (addrs []string, err error)
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+}
+
+// discovery discovers the peers by DNS and the peers file, and joins the peers which are not alive members.
+type discovery struct {
+	config   *Discovery
+	serf     iSerf
+	resolver Resolver
+	// fileState is the modification time and size of the peers file when it was read.
+	fileState string
+	// filePeers is the addresses in the peers file.
+	filePeers []string
+	exit      chan struct{}
+	done      chan struct{}
+}
+
+func newDiscovery(config *Discovery, s iSerf) *discovery {
+	return &discovery{
+		config:   config,
+		serf:     s,
+		resolver: net.DefaultResolver,
+		exit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// parsePeersFile parses the addresses of the peers file.
+func parsePeersFile(b []byte) ([]string, error) {
+	var peers []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, err := getAddr(line, DefaultGossipPort, "peer address", false)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, addr)
+	}
+	return peers, scanner.Err()
+}
+
+// loadPeersFile reads the peers file if it has been changed, it returns whether the file has been changed.
+// If the file does not exist, there are no peers in the file.
+func (d *discovery) loadPeersFile() (changed bool, err error) {
+	if d.config.PeersFile == "" {
+		return false, nil
+	}
+	var state string
+	fi, err := os.Stat(d.config.PeersFile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err == nil {
+		state = fmt.Sprintf("%d-%d", fi.ModTime().UnixNano(), fi.Size())
+	}
+	if state == d.fileState {
+		return false, nil
+	}
+	var peers []string
+	if state != "" {
+		b, err := ioutil.ReadFile(d.config.PeersFile)
+		if err != nil {
+			return false, err
+		}
+		peers, err = parsePeersFile(b)
+		if err != nil {
+			// keep the previous peers until the file is fixed.
+			d.fileState = state
+			return false, fmt.Errorf("invalid peers_file: %s", err)
+		}
+	}
+	d.fileState = state
+	d.filePeers = peers
+	return true, nil
+}
+
+// resolve resolves the host of the address into IP addresses.
+func (d *discovery) resolve(ctx context.Context, host string, port string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{net.JoinHostPort(host, port)}, nil
+	}
+	ips, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, v := range ips {
+		addrs = append(addrs, net.JoinHostPort(v, port))
+	}
+	return addrs, nil
+}
+
+// lookupDNS returns the addresses of the peers discovered by DNS.
+// If some SRV targets fail to be resolved, the addresses of the others are returned with the error.
+func (d *discovery) lookupDNS(ctx context.Context) ([]string, error) {
+	if d.config.dnsType() == DNSTypeA {
+		return d.resolve(ctx, d.config.DNSName, strconv.Itoa(d.config.dnsPort()))
+	}
+	_, srvs, err := d.resolver.LookupSRV(ctx, "", "", d.config.DNSName)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	var errs []string
+	for _, v := range srvs {
+		rs, err := d.resolve(ctx, strings.TrimSuffix(v.Target, "."), strconv.Itoa(int(v.Port)))
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		addrs = append(addrs, rs...)
+	}
+	if len(errs) != 0 {
+		return addrs, errors.New(strings.Join(errs, "; "))
+	}
+	return addrs, nil
+}
+
+// peers returns the gossip addresses of the discovered peers, which are resolved into IP addresses.
+// If some peers fail to be resolved, the others are returned with the error.
+func (d *discovery) peers(ctx context.Context) ([]string, error) {
+	var errs []string
+	set := make(map[string]struct{})
+	if d.config.DNSName != "" {
+		addrs, err := d.lookupDNS(ctx)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for _, v := range addrs {
+			set[v] = struct{}{}
+		}
+	}
+	for _, v := range d.filePeers {
+		host, port, _ := net.SplitHostPort(v)
+		addrs, err := d.resolve(ctx, host, port)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for _, v := range addrs {
+			set[v] = struct{}{}
+		}
+	}
+	peers := make([]string, 0, len(set))
+	for k := range set {
+		peers = append(peers, k)
+	}
+	sort.Strings(peers)
+	if len(errs) != 0 {
+		return peers, fmt.Errorf("fail to resolve peers: %s", strings.Join(errs, "; "))
+	}
+	return peers, nil
+}
+
+// join joins the discovered peers which are not alive members.
+func (d *discovery) join() {
+	if _, err := d.loadPeersFile(); err != nil {
+		log.Warn("fail to read the peers file", zap.String("path", d.config.PeersFile), zap.Error(err))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	peers, err := d.peers(ctx)
+	if err != nil {
+		log.Warn("peer discovery failed", zap.Error(err))
+	}
+	alive := make(map[string]struct{})
+	for _, v := range d.serf.Members() {
+		if v.Status == serf.StatusAlive {
+			alive[net.JoinHostPort(v.Addr.String(), strconv.Itoa(int(v.Port)))] = struct{}{}
+		}
+	}
+	var addrs []string
+	for _, v := range peers {
+		if _, ok := alive[v]; !ok {
+			addrs = append(addrs, v)
+		}
+	}
+	if len(addrs) == 0 {
+		return
+	}
+	n, err := d.serf.Join(addrs, true)
+	if err != nil {
+		log.Warn("fail to join the discovered peers", zap.Strings("peers", addrs), zap.Error(err))
+		return
+	}
+	log.Info("joined the discovered peers", zap.Strings("peers", addrs), zap.Int("joined", n))
+}
+
+// run discovers and joins the peers periodically until stop is called.
+func (d *discovery) run() {
+	defer close(d.done)
+	d.join()
+	refresh := time.NewTicker(d.config.interval())
+	defer refresh.Stop()
+	check := time.NewTicker(peersFileCheckInterval)
+	defer check.Stop()
+	for {
+		select {
+		case <-d.exit:
+			return
+		case <-refresh.C:
+			d.join()
+		case <-check.C:
+			changed, err := d.loadPeersFile()
+			if err != nil {
+				log.Warn("fail to read the peers file", zap.String("path", d.config.PeersFile), zap.Error(err))
+				continue
+			}
+			if changed {
+				log.Info("the peers file has been changed", zap.Strings("peers", d.filePeers))
+				d.join()
+			}
+		}
+	}
+}
+
+func (d *discovery) stop() {
+	close(d.exit)
+	<-d.done
+}
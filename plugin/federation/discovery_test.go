@@ -0,0 +1,165 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/serf/serf"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	hosts map[string][]string
+	srvs  map[string][]*net.SRV
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) (addrs []string, err error) {
+	if v, ok := r.hosts[host]; ok {
+		return v, nil
+	}
+	return nil, errors.New("no such host: " + host)
+}
+
+func (r *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error) {
+	if v, ok := r.srvs[name]; ok {
+		return name, v, nil
+	}
+	return "", nil, errors.New("no such host: " + name)
+}
+
+func TestParsePeersFile(t *testing.T) {
+	a := assert.New(t)
+	peers, err := parsePeersFile([]byte("# peers\n127.0.0.1\n\n  node2:1234  \n"))
+	a.NoError(err)
+	a.Equal([]string{"127.0.0.1:" + DefaultGossipPort, "node2:1234"}, peers)
+
+	_, err = parsePeersFile([]byte("127.0.0.1:abc"))
+	a.Error(err)
+}
+
+func TestDiscovery_peers(t *testing.T) {
+	a := assert.New(t)
+	resolver := &fakeResolver{
+		hosts: map[string][]string{
+			"gmqtt.default.svc": {"10.0.0.1", "10.0.0.2"},
+			"gmqtt-0.gmqtt":     {"10.0.0.1"},
+			"gmqtt-1.gmqtt":     {"10.0.0.3"},
+		},
+		srvs: map[string][]*net.SRV{
+			"_gossip._tcp.gmqtt": {
+				{Target: "gmqtt-0.gmqtt.", Port: 7946},
+				{Target: "gmqtt-1.gmqtt.", Port: 7946},
+			},
+		},
+	}
+	d := newDiscovery(&Discovery{DNSName: "gmqtt.default.svc"}, nil)
+	d.resolver = resolver
+	peers, err := d.peers(context.Background())
+	a.NoError(err)
+	a.Equal([]string{"10.0.0.1:" + DefaultGossipPort, "10.0.0.2:" + DefaultGossipPort}, peers)
+
+	d.config = &Discovery{DNSName: "_gossip._tcp.gmqtt", DNSType: DNSTypeSRV}
+	peers, err = d.peers(context.Background())
+	a.NoError(err)
+	a.Equal([]string{"10.0.0.1:7946", "10.0.0.3:7946"}, peers)
+
+	// the SRV targets which are resolved successfully are returned with the error.
+	resolver.srvs["_gossip._tcp.gmqtt"] = append([]*net.SRV{{Target: "gmqtt-2.gmqtt.", Port: 7946}}, resolver.srvs["_gossip._tcp.gmqtt"]...)
+	peers, err = d.peers(context.Background())
+	a.Error(err)
+	a.Contains(err.Error(), "gmqtt-2.gmqtt")
+	a.Equal([]string{"10.0.0.1:7946", "10.0.0.3:7946"}, peers)
+
+	// the peers which are resolved successfully are returned with the error.
+	d.config = &Discovery{DNSName: "unknown", DNSPort: 1234}
+	d.filePeers = []string{"10.0.0.4:1234", "gmqtt-1.gmqtt:1234"}
+	peers, err = d.peers(context.Background())
+	a.Error(err)
+	a.Equal([]string{"10.0.0.3:1234", "10.0.0.4:1234"}, peers)
+}
+
+func TestDiscovery_join(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, err := ioutil.TempDir("", "federation_discovery")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	peersFile := filepath.Join(dir, "peers")
+
+	mockSerf := NewMockiSerf(ctrl)
+	d := newDiscovery(&Discovery{
+		DNSName:   "gmqtt",
+		PeersFile: peersFile,
+	}, mockSerf)
+	resolver := &fakeResolver{
+		hosts: map[string][]string{
+			"gmqtt": {"10.0.0.1", "10.0.0.2"},
+		},
+	}
+	d.resolver = resolver
+	members := []serf.Member{
+		{Addr: net.ParseIP("10.0.0.1"), Port: 8902, Status: serf.StatusAlive},
+		{Addr: net.ParseIP("10.0.0.2"), Port: 8902, Status: serf.StatusFailed},
+		{Addr: net.ParseIP("10.0.0.3"), Port: 8902, Status: serf.StatusLeft},
+	}
+	mockSerf.EXPECT().Members().Return(members).AnyTimes()
+
+	// the failed member is rejoined.
+	mockSerf.EXPECT().Join([]string{"10.0.0.2:8902"}, true).Return(1, nil)
+	d.join()
+
+	// the new peers in the peers file.
+	a.NoError(ioutil.WriteFile(peersFile, []byte("10.0.0.1\n10.0.0.3\n"), 0644))
+	mockSerf.EXPECT().Join([]string{"10.0.0.2:8902", "10.0.0.3:8902"}, true).Return(0, errors.New("error"))
+	d.join()
+	a.Equal([]string{"10.0.0.1:8902", "10.0.0.3:8902"}, d.filePeers)
+
+	// nothing to join
+	members[1].Status = serf.StatusAlive
+	members[2].Status = serf.StatusAlive
+	d.join()
+}
+
+func TestDiscovery_run(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, err := ioutil.TempDir("", "federation_discovery")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	peersFile := filepath.Join(dir, "peers")
+	peersFileCheckInterval = 10 * time.Millisecond
+	defer func() {
+		peersFileCheckInterval = time.Second
+	}()
+
+	mockSerf := NewMockiSerf(ctrl)
+	mockSerf.EXPECT().Members().Return(nil).AnyTimes()
+	d := newDiscovery(&Discovery{
+		PeersFile:       peersFile,
+		RefreshInterval: time.Hour,
+	}, mockSerf)
+	go d.run()
+	defer d.stop()
+
+	joined := make(chan []string, 1)
+	mockSerf.EXPECT().Join(gomock.Any(), true).DoAndReturn(func(existing []string, ignoreOld bool) (int, error) {
+		joined <- existing
+		return len(existing), nil
+	})
+	a.NoError(ioutil.WriteFile(peersFile, []byte("10.0.0.1:1234"), 0644))
+	select {
+	case addrs := <-joined:
+		a.Equal([]string{"10.0.0.1:1234"}, addrs)
+	case <-time.After(time.Second):
+		a.FailNow("the peers file is not watched")
+	}
+}
@@ -3,10 +3,12 @@ package federation
 import (
 	"container/list"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,11 +29,13 @@ import (
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
 	"github.com/DrmagicE/gmqtt/pkg/packets"
+	promplugin "github.com/DrmagicE/gmqtt/plugin/prometheus"
 	"github.com/DrmagicE/gmqtt/retained"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
 var _ server.Plugin = (*Federation)(nil)
+var _ server.Cluster = (*Federation)(nil)
 
 const Name = "federation"
 
@@ -80,12 +84,23 @@ func getSerfConfig(cfg *Config, eventCh chan serf.Event, logOut io.Writer) *serf
 	p, _ = strconv.Atoi(port)
 	serfCfg.MemberlistConfig.AdvertisePort = p
 
+	serfCfg.KeyringFile = cfg.GossipEncryption.KeyringFile
+
 	serfCfg.Tags = map[string]string{"fed_addr": cfg.AdvertiseFedAddr}
 	serfCfg.LogOutput = logOut
 	serfCfg.MemberlistConfig.LogOutput = logOut
 	return serfCfg
 }
 
+// sharedSubscriptionConfig returns the shared subscription config to select the nodes.
+// The nodes are selected in turn as the previous versions did if no strategy is configured.
+func sharedSubscriptionConfig(c config.SharedSubscription) config.SharedSubscription {
+	if c.Strategy == "" {
+		c.Strategy = config.SharedStrategyRoundRobin
+	}
+	return c
+}
+
 func New(config config.Config) (server.Plugin, error) {
 	log = server.LoggerWithField(zap.String("plugin", Name))
 	cfg := config.Plugins[Name].(*Config)
@@ -93,28 +108,50 @@ func New(config config.Config) (server.Plugin, error) {
 		config:        cfg,
 		nodeName:      cfg.NodeName,
 		localSubStore: &localSubStore{},
-		fedSubStore: &fedSubStore{
-			TrieDB:     mem.NewStore(),
-			sharedSent: map[string]uint64{},
-		},
-		serfEventCh: make(chan serf.Event, 10000),
+		serfEventCh:   make(chan serf.Event, 10000),
 		sessionMgr: &sessionMgr{
 			sessions: map[string]*session{},
 		},
-		peers: make(map[string]*peer),
-		exit:  make(chan struct{}),
-		wg:    &sync.WaitGroup{},
+		peers:    make(map[string]*peer),
+		exit:     make(chan struct{}),
+		wg:       &sync.WaitGroup{},
+		registry: newClientRegistry(),
+		pendingSessions: &pendingSessions{
+			sessions: make(map[string]*pendingSession),
+		},
+		handlers: make(map[string]server.ClusterHandler),
+	}
+	var err error
+	f.sharedSelector, err = server.NewSharedSelector(sharedSubscriptionConfig(config.SharedSubscription))
+	if err != nil {
+		return nil, err
+	}
+	f.fedSubStore = &fedSubStore{
+		Store: f.sharedSelector.WrapStore(mem.NewStore()),
 	}
 	logOut, err := getSerfLogger(config.Log.Level)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.TLS.Enable {
+		f.serverTLS, f.clientTLS, err = newTLSConfigs(&cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
 	serfCfg := getSerfConfig(cfg, f.serfEventCh, logOut)
+	serfCfg.MemberlistConfig.Keyring, err = cfg.GossipEncryption.keyring()
+	if err != nil {
+		return nil, err
+	}
 	s, err := serf.Create(serfCfg)
 	if err != nil {
 		return nil, err
 	}
 	f.serf = s
+	if serfCfg.MemberlistConfig.Keyring != nil {
+		f.keyManager = s.KeyManager()
+	}
 	return f, nil
 }
 
@@ -135,22 +172,36 @@ type Federation struct {
 	// fedSubStore store federation subscription tree which take nodeName as the subscriber identifier.
 	// It is used to determine which node the incoming message should be routed to.
 	fedSubStore *fedSubStore
+	// sharedSelector selects which node the shared message should be sent to.
+	sharedSelector *server.SharedSelector
 	// retainedStore store is the retained store of the gmqtt core.
 	// Retained message will be broadcast to other nodes in the federation.
 	retainedStore retained.Store
 	publisher     server.Publisher
-	exit          chan struct{}
-	memberMu      sync.Mutex
-	peers         map[string]*peer
-	wg            *sync.WaitGroup
+	clientService server.ClientService
+	queueService  server.QueueService
+	// registry is the cluster-wide client id registry, which is used to find the node to take over the session from.
+	registry *clientRegistry
+	// pendingSessions stores the session state taken over from other nodes until the session is created.
+	pendingSessions *pendingSessions
+	exit            chan struct{}
+	memberMu        sync.Mutex
+	peers           map[string]*peer
+	wg              *sync.WaitGroup
+	// serverTLS and clientTLS are the tls configs of the federation gRPC server and client, nil if mTLS is disabled.
+	serverTLS *tls.Config
+	clientTLS *tls.Config
+	// keyManager manages the gossip encryption keys, nil if the gossip encryption is disabled.
+	keyManager iKeyManager
+	// discovery discovers and joins the peers, nil if the discovery is disabled.
+	discovery *discovery
+	// handlers are the cluster handlers registered by other plugins, see server.Cluster.
+	handlersMu sync.RWMutex
+	handlers   map[string]server.ClusterHandler
 }
 
 type fedSubStore struct {
-	*mem.TrieDB
-	sharedMu sync.Mutex
-	// sharedSent store the number of shared topic sent.
-	// It is used to select which node the message should be send to with round-robin strategy
-	sharedSent map[string]uint64
+	subscription.Store
 }
 
 type sessionMgr struct {
@@ -220,6 +271,31 @@ func (f *Federation) ListMembers(ctx context.Context, req *empty.Empty) (resp *L
 	return resp, nil
 }
 
+// ListPeers lists the state of the event streams to the peers.
+func (f *Federation) ListPeers(ctx context.Context, req *empty.Empty) (resp *ListPeersResponse, err error) {
+	return &ListPeersResponse{
+		Peers: f.peerInfos(),
+	}, nil
+}
+
+// peerInfos returns the state of the peers sorted by the node name.
+func (f *Federation) peerInfos() []*Peer {
+	f.memberMu.Lock()
+	peers := make([]*peer, 0, len(f.peers))
+	for _, v := range f.peers {
+		peers = append(peers, v)
+	}
+	f.memberMu.Unlock()
+	rs := make([]*Peer, 0, len(peers))
+	for _, v := range peers {
+		rs = append(rs, v.info())
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Name < rs[j].Name
+	})
+	return rs
+}
+
 // Leave triggers a graceful leave for the local node.
 // This is used to ensure other nodes see the node as "left" instead of "failed".
 // Note that a leaved node cannot re-join the cluster unless you restart the leaved node.
@@ -394,24 +470,20 @@ func getNodeNameFromContext(ctx context.Context) (string, error) {
 
 // Hello is the handler for the handshake process before opening the event stream.
 func (f *Federation) Hello(ctx context.Context, req *ClientHello) (resp *ServerHello, err error) {
-	nodeName, err := getNodeNameFromContext(ctx)
+	nodeName, err := f.authenticatePeer(ctx)
 	if err != nil {
 		return nil, err
 	}
-	f.memberMu.Lock()
-	p := f.peers[nodeName]
-	f.memberMu.Unlock()
-	if p == nil {
-		return nil, status.Errorf(codes.Internal, "Hello: the node [%s] has not yet joined", nodeName)
-	}
 
 	cleanStart, nextID := f.sessionMgr.add(nodeName, req.SessionId)
 	if cleanStart {
 		_ = f.fedSubStore.UnsubscribeAll(nodeName)
+		f.registry.removeNode(nodeName)
 	}
 	resp = &ServerHello{
 		CleanStart:  cleanStart,
 		NextEventId: nextID,
+		Batch:       true,
 	}
 	return resp, nil
 }
@@ -444,26 +516,67 @@ func (f *Federation) eventStreamHandler(sess *session, in *Event) (ack *Ack) {
 		_ = f.fedSubStore.Unsubscribe(sess.nodeName, unsub.TopicName)
 		return &Ack{EventId: eventID}
 	}
+	if claim := in.GetSessionClaim(); claim != nil {
+		f.registry.claim(claim.ClientId, sess.nodeName)
+		return &Ack{EventId: eventID}
+	}
+	if release := in.GetSessionRelease(); release != nil {
+		f.registry.release(release.ClientId, sess.nodeName)
+		return &Ack{EventId: eventID}
+	}
 	return nil
 }
 
+// eventStreamServer is the common interface of the server-side streams of EventStream and EventBatchStream.
+type eventStreamServer interface {
+	Context() context.Context
+	Send(*Ack) error
+	recv() ([]*Event, error)
+}
+
+type singleEventStreamServer struct {
+	Federation_EventStreamServer
+}
+
+func (s singleEventStreamServer) recv() ([]*Event, error) {
+	in, err := s.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return []*Event{in}, nil
+}
+
+type batchEventStreamServer struct {
+	Federation_EventBatchStreamServer
+}
+
+func (s batchEventStreamServer) recv() ([]*Event, error) {
+	in, err := s.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return in.Events, nil
+}
+
 func (f *Federation) EventStream(stream Federation_EventStreamServer) (err error) {
+	return f.serveEvents(singleEventStreamServer{stream})
+}
+
+// EventBatchStream is the same as EventStream except that the events are received in batches,
+// and only the last event of each batch is acked.
+func (f *Federation) EventBatchStream(stream Federation_EventBatchStreamServer) (err error) {
+	return f.serveEvents(batchEventStreamServer{stream})
+}
+
+func (f *Federation) serveEvents(stream eventStreamServer) (err error) {
 	defer func() {
 		if err != nil && err != io.EOF {
 			log.Error("EventStream error", zap.Error(err))
 		}
 	}()
-	md, ok := metadata.FromIncomingContext(stream.Context())
-	if !ok {
-		return status.Errorf(codes.DataLoss, "EventStream: failed to get metadata")
-	}
-	s := md.Get("node_name")
-	if len(s) == 0 {
-		return status.Errorf(codes.InvalidArgument, "EventStream: missing node_name metadata")
-	}
-	nodeName := s[0]
-	if nodeName == "" {
-		return status.Errorf(codes.InvalidArgument, "EventStream: missing node_name metadata")
+	nodeName, err := f.authenticatePeer(stream.Context())
+	if err != nil {
+		return err
 	}
 	sess := f.sessionMgr.get(nodeName)
 	if sess == nil {
@@ -479,21 +592,26 @@ func (f *Federation) EventStream(stream Federation_EventStreamServer) (err error
 	}()
 	go func() {
 		for {
-			var in *Event
 			select {
 			case <-done:
 			default:
-				in, err = stream.Recv()
+				events, err := stream.recv()
 				if err != nil {
 					errCh <- err
 					return
 				}
-				if ce := log.Check(zapcore.DebugLevel, "event received"); ce != nil {
-					ce.Write(zap.String("event", in.String()))
+				var ack *Ack
+				for _, in := range events {
+					if ce := log.Check(zapcore.DebugLevel, "event received"); ce != nil {
+						ce.Write(zap.String("event", in.String()))
+					}
+					if a := f.eventStreamHandler(sess, in); a != nil {
+						ack = a
+					}
+				}
+				if ack == nil {
+					continue
 				}
-
-				ack := f.eventStreamHandler(sess, in)
-
 				err = stream.Send(ack)
 				if err != nil {
 					errCh <- err
@@ -532,7 +650,13 @@ func (f *Federation) Load(service server.Server) error {
 	f.localSubStore.init(service.SubscriptionService())
 	f.retainedStore = service.RetainedService()
 	f.publisher = service.Publisher()
-	srv := grpc.NewServer()
+	f.clientService = service.ClientService()
+	f.queueService = service.QueueService()
+	collector.setFederation(f)
+	if err := promplugin.Register(collector); err != nil {
+		return err
+	}
+	srv := grpc.NewServer(f.serverOptions()...)
 	RegisterFederationServer(srv, f)
 	l, err := net.Listen("tcp", f.config.FedAddr)
 	if err != nil {
@@ -559,6 +683,10 @@ func (f *Federation) Load(service server.Server) error {
 			err = f.startSerf(t)
 			if err == nil {
 				log.Info("retry join succeed")
+				if f.config.Discovery.enabled() {
+					f.discovery = newDiscovery(&f.config.Discovery, f.serf)
+					go f.discovery.run()
+				}
 				return nil
 			}
 			log.Info("retry join failed", zap.Error(err))
@@ -567,6 +695,9 @@ func (f *Federation) Load(service server.Server) error {
 }
 
 func (f *Federation) Unload() error {
+	if f.discovery != nil {
+		f.discovery.stop()
+	}
 	err := f.serf.Leave()
 	if err != nil {
 		return err
@@ -1,8 +1,4 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
-// versions:
-// - protoc-gen-go-grpc v1.2.0
-// - protoc             v3.6.1
-// source: federation.proto
 
 package federation
 
@@ -16,7 +12,6 @@ import (
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.32.0 or later.
 const _ = grpc.SupportPackageIsVersion7
 
 // MembershipClient is the client API for Membership service.
@@ -37,6 +32,18 @@ type MembershipClient interface {
 	ForceLeave(ctx context.Context, in *ForceLeaveRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	// ListMembers lists all known members in the Serf cluster.
 	ListMembers(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListMembersResponse, error)
+	// ListPeers lists the state of the event streams to the peers.
+	ListPeers(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListPeersResponse, error)
+	// ListKeys lists the gossip encryption keys installed on the members.
+	ListKeys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyResponse, error)
+	// InstallKey installs a new gossip encryption key on all members.
+	// The key is only used to decrypt messages until UseKey is called.
+	InstallKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	// UseKey changes the primary gossip encryption key of all members, which is used to encrypt messages.
+	// The key must have been installed on all members.
+	UseKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	// RemoveKey removes a gossip encryption key from all members, the primary key can not be removed.
+	RemoveKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error)
 }
 
 type membershipClient struct {
@@ -83,6 +90,51 @@ func (c *membershipClient) ListMembers(ctx context.Context, in *empty.Empty, opt
 	return out, nil
 }
 
+func (c *membershipClient) ListPeers(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ListPeersResponse, error) {
+	out := new(ListPeersResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Membership/ListPeers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membershipClient) ListKeys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Membership/ListKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membershipClient) InstallKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Membership/InstallKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membershipClient) UseKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Membership/UseKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *membershipClient) RemoveKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Membership/RemoveKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MembershipServer is the server API for Membership service.
 // All implementations must embed UnimplementedMembershipServer
 // for forward compatibility
@@ -101,6 +153,18 @@ type MembershipServer interface {
 	ForceLeave(context.Context, *ForceLeaveRequest) (*empty.Empty, error)
 	// ListMembers lists all known members in the Serf cluster.
 	ListMembers(context.Context, *empty.Empty) (*ListMembersResponse, error)
+	// ListPeers lists the state of the event streams to the peers.
+	ListPeers(context.Context, *empty.Empty) (*ListPeersResponse, error)
+	// ListKeys lists the gossip encryption keys installed on the members.
+	ListKeys(context.Context, *empty.Empty) (*KeyResponse, error)
+	// InstallKey installs a new gossip encryption key on all members.
+	// The key is only used to decrypt messages until UseKey is called.
+	InstallKey(context.Context, *KeyRequest) (*KeyResponse, error)
+	// UseKey changes the primary gossip encryption key of all members, which is used to encrypt messages.
+	// The key must have been installed on all members.
+	UseKey(context.Context, *KeyRequest) (*KeyResponse, error)
+	// RemoveKey removes a gossip encryption key from all members, the primary key can not be removed.
+	RemoveKey(context.Context, *KeyRequest) (*KeyResponse, error)
 	mustEmbedUnimplementedMembershipServer()
 }
 
@@ -120,6 +184,21 @@ func (UnimplementedMembershipServer) ForceLeave(context.Context, *ForceLeaveRequ
 func (UnimplementedMembershipServer) ListMembers(context.Context, *empty.Empty) (*ListMembersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListMembers not implemented")
 }
+func (UnimplementedMembershipServer) ListPeers(context.Context, *empty.Empty) (*ListPeersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeers not implemented")
+}
+func (UnimplementedMembershipServer) ListKeys(context.Context, *empty.Empty) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListKeys not implemented")
+}
+func (UnimplementedMembershipServer) InstallKey(context.Context, *KeyRequest) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InstallKey not implemented")
+}
+func (UnimplementedMembershipServer) UseKey(context.Context, *KeyRequest) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UseKey not implemented")
+}
+func (UnimplementedMembershipServer) RemoveKey(context.Context, *KeyRequest) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveKey not implemented")
+}
 func (UnimplementedMembershipServer) mustEmbedUnimplementedMembershipServer() {}
 
 // UnsafeMembershipServer may be embedded to opt out of forward compatibility for this service.
@@ -130,7 +209,7 @@ type UnsafeMembershipServer interface {
 }
 
 func RegisterMembershipServer(s grpc.ServiceRegistrar, srv MembershipServer) {
-	s.RegisterService(&Membership_ServiceDesc, srv)
+	s.RegisterService(&_Membership_serviceDesc, srv)
 }
 
 func _Membership_Join_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
@@ -205,10 +284,97 @@ func _Membership_ListMembers_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
-// Membership_ServiceDesc is the grpc.ServiceDesc for Membership service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Membership_ServiceDesc = grpc.ServiceDesc{
+func _Membership_ListPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembershipServer).ListPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Membership/ListPeers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembershipServer).ListPeers(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Membership_ListKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembershipServer).ListKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Membership/ListKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembershipServer).ListKeys(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Membership_InstallKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembershipServer).InstallKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Membership/InstallKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembershipServer).InstallKey(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Membership_UseKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembershipServer).UseKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Membership/UseKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembershipServer).UseKey(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Membership_RemoveKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MembershipServer).RemoveKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Membership/RemoveKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MembershipServer).RemoveKey(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Membership_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "gmqtt.federation.api.Membership",
 	HandlerType: (*MembershipServer)(nil),
 	Methods: []grpc.MethodDesc{
@@ -228,6 +394,26 @@ var Membership_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListMembers",
 			Handler:    _Membership_ListMembers_Handler,
 		},
+		{
+			MethodName: "ListPeers",
+			Handler:    _Membership_ListPeers_Handler,
+		},
+		{
+			MethodName: "ListKeys",
+			Handler:    _Membership_ListKeys_Handler,
+		},
+		{
+			MethodName: "InstallKey",
+			Handler:    _Membership_InstallKey_Handler,
+		},
+		{
+			MethodName: "UseKey",
+			Handler:    _Membership_UseKey_Handler,
+		},
+		{
+			MethodName: "RemoveKey",
+			Handler:    _Membership_RemoveKey_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "federation.proto",
@@ -239,6 +425,13 @@ var Membership_ServiceDesc = grpc.ServiceDesc{
 type FederationClient interface {
 	Hello(ctx context.Context, in *ClientHello, opts ...grpc.CallOption) (*ServerHello, error)
 	EventStream(ctx context.Context, opts ...grpc.CallOption) (Federation_EventStreamClient, error)
+	// EventBatchStream is the same as EventStream except that the events are sent in batches,
+	// and only the last event of each batch is acked.
+	EventBatchStream(ctx context.Context, opts ...grpc.CallOption) (Federation_EventBatchStreamClient, error)
+	// Takeover disconnects the client and hands over the session to the caller node.
+	Takeover(ctx context.Context, in *TakeoverRequest, opts ...grpc.CallOption) (*TakeoverResponse, error)
+	// Call calls the handler of the method registered by other plugins, e.g. the cluster-wide queries of the admin plugin.
+	Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error)
 }
 
 type federationClient struct {
@@ -259,7 +452,7 @@ func (c *federationClient) Hello(ctx context.Context, in *ClientHello, opts ...g
 }
 
 func (c *federationClient) EventStream(ctx context.Context, opts ...grpc.CallOption) (Federation_EventStreamClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Federation_ServiceDesc.Streams[0], "/gmqtt.federation.api.Federation/EventStream", opts...)
+	stream, err := c.cc.NewStream(ctx, &_Federation_serviceDesc.Streams[0], "/gmqtt.federation.api.Federation/EventStream", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -289,12 +482,68 @@ func (x *federationEventStreamClient) Recv() (*Ack, error) {
 	return m, nil
 }
 
+func (c *federationClient) EventBatchStream(ctx context.Context, opts ...grpc.CallOption) (Federation_EventBatchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Federation_serviceDesc.Streams[1], "/gmqtt.federation.api.Federation/EventBatchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &federationEventBatchStreamClient{stream}
+	return x, nil
+}
+
+type Federation_EventBatchStreamClient interface {
+	Send(*EventBatch) error
+	Recv() (*Ack, error)
+	grpc.ClientStream
+}
+
+type federationEventBatchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *federationEventBatchStreamClient) Send(m *EventBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *federationEventBatchStreamClient) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *federationClient) Takeover(ctx context.Context, in *TakeoverRequest, opts ...grpc.CallOption) (*TakeoverResponse, error) {
+	out := new(TakeoverResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Federation/Takeover", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *federationClient) Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error) {
+	out := new(CallResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.federation.api.Federation/Call", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FederationServer is the server API for Federation service.
 // All implementations must embed UnimplementedFederationServer
 // for forward compatibility
 type FederationServer interface {
 	Hello(context.Context, *ClientHello) (*ServerHello, error)
 	EventStream(Federation_EventStreamServer) error
+	// EventBatchStream is the same as EventStream except that the events are sent in batches,
+	// and only the last event of each batch is acked.
+	EventBatchStream(Federation_EventBatchStreamServer) error
+	// Takeover disconnects the client and hands over the session to the caller node.
+	Takeover(context.Context, *TakeoverRequest) (*TakeoverResponse, error)
+	// Call calls the handler of the method registered by other plugins, e.g. the cluster-wide queries of the admin plugin.
+	Call(context.Context, *CallRequest) (*CallResponse, error)
 	mustEmbedUnimplementedFederationServer()
 }
 
@@ -308,6 +557,15 @@ func (UnimplementedFederationServer) Hello(context.Context, *ClientHello) (*Serv
 func (UnimplementedFederationServer) EventStream(Federation_EventStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method EventStream not implemented")
 }
+func (UnimplementedFederationServer) EventBatchStream(Federation_EventBatchStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method EventBatchStream not implemented")
+}
+func (UnimplementedFederationServer) Takeover(context.Context, *TakeoverRequest) (*TakeoverResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Takeover not implemented")
+}
+func (UnimplementedFederationServer) Call(context.Context, *CallRequest) (*CallResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Call not implemented")
+}
 func (UnimplementedFederationServer) mustEmbedUnimplementedFederationServer() {}
 
 // UnsafeFederationServer may be embedded to opt out of forward compatibility for this service.
@@ -318,7 +576,7 @@ type UnsafeFederationServer interface {
 }
 
 func RegisterFederationServer(s grpc.ServiceRegistrar, srv FederationServer) {
-	s.RegisterService(&Federation_ServiceDesc, srv)
+	s.RegisterService(&_Federation_serviceDesc, srv)
 }
 
 func _Federation_Hello_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
@@ -365,10 +623,69 @@ func (x *federationEventStreamServer) Recv() (*Event, error) {
 	return m, nil
 }
 
-// Federation_ServiceDesc is the grpc.ServiceDesc for Federation service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Federation_ServiceDesc = grpc.ServiceDesc{
+func _Federation_EventBatchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FederationServer).EventBatchStream(&federationEventBatchStreamServer{stream})
+}
+
+type Federation_EventBatchStreamServer interface {
+	Send(*Ack) error
+	Recv() (*EventBatch, error)
+	grpc.ServerStream
+}
+
+type federationEventBatchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *federationEventBatchStreamServer) Send(m *Ack) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *federationEventBatchStreamServer) Recv() (*EventBatch, error) {
+	m := new(EventBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Federation_Takeover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TakeoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).Takeover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Federation/Takeover",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).Takeover(ctx, req.(*TakeoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Federation_Call_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FederationServer).Call(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.federation.api.Federation/Call",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FederationServer).Call(ctx, req.(*CallRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Federation_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "gmqtt.federation.api.Federation",
 	HandlerType: (*FederationServer)(nil),
 	Methods: []grpc.MethodDesc{
@@ -376,6 +693,14 @@ var Federation_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Hello",
 			Handler:    _Federation_Hello_Handler,
 		},
+		{
+			MethodName: "Takeover",
+			Handler:    _Federation_Takeover_Handler,
+		},
+		{
+			MethodName: "Call",
+			Handler:    _Federation_Call_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -384,6 +709,12 @@ var Federation_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "EventBatchStream",
+			Handler:       _Federation_EventBatchStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "federation.proto",
 }
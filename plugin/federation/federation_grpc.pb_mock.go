@@ -35,6 +35,46 @@ func (m *MockFederationClient) EXPECT() *MockFederationClientMockRecorder {
 	return m.recorder
 }
 
+// Call mocks base method
+func (m *MockFederationClient) Call(arg0 context.Context, arg1 *CallRequest, arg2 ...grpc.CallOption) (*CallResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Call", varargs...)
+	ret0, _ := ret[0].(*CallResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Call indicates an expected call of Call
+func (mr *MockFederationClientMockRecorder) Call(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Call", reflect.TypeOf((*MockFederationClient)(nil).Call), varargs...)
+}
+
+// EventBatchStream mocks base method
+func (m *MockFederationClient) EventBatchStream(arg0 context.Context, arg1 ...grpc.CallOption) (Federation_EventBatchStreamClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EventBatchStream", varargs...)
+	ret0, _ := ret[0].(Federation_EventBatchStreamClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EventBatchStream indicates an expected call of EventBatchStream
+func (mr *MockFederationClientMockRecorder) EventBatchStream(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EventBatchStream", reflect.TypeOf((*MockFederationClient)(nil).EventBatchStream), varargs...)
+}
+
 // EventStream mocks base method
 func (m *MockFederationClient) EventStream(arg0 context.Context, arg1 ...grpc.CallOption) (Federation_EventStreamClient, error) {
 	m.ctrl.T.Helper()
@@ -75,6 +115,26 @@ func (mr *MockFederationClientMockRecorder) Hello(arg0, arg1 interface{}, arg2 .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hello", reflect.TypeOf((*MockFederationClient)(nil).Hello), varargs...)
 }
 
+// Takeover mocks base method
+func (m *MockFederationClient) Takeover(arg0 context.Context, arg1 *TakeoverRequest, arg2 ...grpc.CallOption) (*TakeoverResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Takeover", varargs...)
+	ret0, _ := ret[0].(*TakeoverResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Takeover indicates an expected call of Takeover
+func (mr *MockFederationClientMockRecorder) Takeover(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Takeover", reflect.TypeOf((*MockFederationClient)(nil).Takeover), varargs...)
+}
+
 // MockFederation_EventStreamClient is a mock of Federation_EventStreamClient interface
 type MockFederation_EventStreamClient struct {
 	ctrl     *gomock.Controller
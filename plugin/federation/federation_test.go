@@ -2,14 +2,18 @@ package federation
 
 import (
 	"context"
+	"io"
 	"net"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hashicorp/serf/serf"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/DrmagicE/gmqtt"
@@ -453,3 +457,112 @@ func TestFederation_Hello(t *testing.T) {
 
 	a.EqualValues(2, resp.NextEventId)
 }
+
+type testBatchStreamServer struct {
+	grpc.ServerStream
+	ctx context.Context
+	in  chan *EventBatch
+	out chan *Ack
+}
+
+func (s *testBatchStreamServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testBatchStreamServer) Send(ack *Ack) error {
+	s.out <- ack
+	return nil
+}
+
+func (s *testBatchStreamServer) Recv() (*EventBatch, error) {
+	b, ok := <-s.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return b, nil
+}
+
+func TestFederation_EventBatchStream(t *testing.T) {
+	a := assert.New(t)
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.peers["node1"] = &peer{}
+	resp, err := f.Hello(mockMetaContext("node1"), &ClientHello{SessionId: "sid"})
+	a.NoError(err)
+	a.True(resp.Batch)
+
+	srv := &testBatchStreamServer{
+		ctx: mockMetaContext("node1"),
+		in:  make(chan *EventBatch, 2),
+		out: make(chan *Ack, 2),
+	}
+	srv.in <- &EventBatch{Events: []*Event{
+		{Id: 0, Event: &Event_Subscribe{Subscribe: &Subscribe{TopicFilter: "a"}}},
+		{Id: 1, Event: &Event_Subscribe{Subscribe: &Subscribe{TopicFilter: "b"}}},
+	}}
+	close(srv.in)
+	a.NoError(f.EventBatchStream(srv))
+	// only the last event is acked.
+	a.Len(srv.out, 1)
+	a.EqualValues(1, (<-srv.out).EventId)
+	a.EqualValues(2, f.sessionMgr.get("node1").nextEventID)
+	a.EqualValues(2, f.fedSubStore.GetStats().SubscriptionsCurrent)
+}
+
+func TestFederation_ListPeers(t *testing.T) {
+	a := assert.New(t)
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.nodeJoin(serf.MemberEvent{
+		Members: []serf.Member{
+			{Name: "node2", Tags: map[string]string{"fed_addr": "127.0.0.1:8911"}},
+			{Name: "node1", Tags: map[string]string{"fed_addr": "127.0.0.1:8901"}},
+		},
+	})
+	f.peers["node1"].queue.add(&Event{Event: &Event_Subscribe{Subscribe: &Subscribe{TopicFilter: "a"}}})
+	atomic.StoreUint64(&f.peers["node1"].stats.reconnects, 2)
+	atomic.StoreInt64(&f.peers["node1"].stats.rtt, int64(1500*time.Microsecond))
+
+	resp, err := f.ListPeers(context.Background(), nil)
+	a.NoError(err)
+	a.Len(resp.Peers, 2)
+	a.Equal(&Peer{
+		Name:        "node1",
+		Addr:        "127.0.0.1:8901",
+		QueueLength: 1,
+		NextEventId: 1,
+		RttMs:       1.5,
+		Reconnects:  2,
+	}, resp.Peers[0])
+	a.Equal("node2", resp.Peers[1].Name)
+
+	reg := prometheus.NewPedanticRegistry()
+	c := &peerCollector{}
+	c.setFederation(f)
+	a.NoError(reg.Register(c))
+	mfs, err := reg.Gather()
+	a.NoError(err)
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			if m.Label[0].GetValue() != "node1" {
+				continue
+			}
+			if m.Gauge != nil {
+				values[mf.GetName()] = m.Gauge.GetValue()
+			} else {
+				values[mf.GetName()] = m.Counter.GetValue()
+			}
+		}
+	}
+	a.Equal(map[string]float64{
+		"gmqtt_federation_peer_connected":                  0,
+		"gmqtt_federation_peer_queue_length":               1,
+		"gmqtt_federation_peer_next_event_id":              1,
+		"gmqtt_federation_peer_acked_event_id":             0,
+		"gmqtt_federation_peer_rtt_seconds":                0.0015,
+		"gmqtt_federation_peer_reconnects_total":           2,
+		"gmqtt_federation_peer_dropped_events_total":       0,
+		"gmqtt_federation_peer_overflow_disconnects_total": 0,
+	}, values)
+}
@@ -16,6 +16,69 @@ func (f *Federation) HookWrapper() server.HookWrapper {
 		OnMsgArrivedWrapper:        f.OnMsgArrivedWrapper,
 		OnSessionTerminatedWrapper: f.OnSessionTerminatedWrapper,
 		OnWillPublishWrapper:       f.OnWillPublishWrapper,
+		OnBasicAuthWrapper:         f.OnBasicAuthWrapper,
+		OnEnhancedAuthWrapper:      f.OnEnhancedAuthWrapper,
+		OnSessionCreatedWrapper:    f.OnSessionCreatedWrapper,
+		OnSessionResumedWrapper:    f.OnSessionResumedWrapper,
+	}
+}
+
+// OnBasicAuthWrapper takes over the session from other nodes after the client passes the authentication.
+func (f *Federation) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		f.takeover(string(req.Connect.ClientID), req.Connect.CleanStart)
+		return nil
+	}
+}
+
+// OnEnhancedAuthWrapper takes over the session from other nodes after the client passes the authentication.
+// If the authentication needs to continue, the session is taken over after the last AUTH packet is accepted.
+func (f *Federation) OnEnhancedAuthWrapper(pre server.OnEnhancedAuth) server.OnEnhancedAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (*server.EnhancedAuthResponse, error) {
+		resp, err := pre(ctx, client, req)
+		if err != nil || resp == nil {
+			return resp, err
+		}
+		clientID, cleanStart := string(req.Connect.ClientID), req.Connect.CleanStart
+		if !resp.Continue {
+			f.takeover(clientID, cleanStart)
+			return resp, nil
+		}
+		if resp.OnAuth == nil {
+			return resp, nil
+		}
+		r := *resp
+		r.OnAuth = f.onAuth(resp.OnAuth, clientID, cleanStart)
+		return &r, nil
+	}
+}
+
+// onAuth wraps the OnAuth of the continued enhanced authentication to take over the session once the authentication succeeds.
+func (f *Federation) onAuth(pre server.OnAuth, clientID string, cleanStart bool) server.OnAuth {
+	return func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+		resp, err := pre(ctx, client, req)
+		if err == nil && resp != nil && !resp.Continue {
+			f.takeover(clientID, cleanStart)
+		}
+		return resp, err
+	}
+}
+
+func (f *Federation) OnSessionCreatedWrapper(pre server.OnSessionCreated) server.OnSessionCreated {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		f.sessionClaimed(client.ClientOptions().ClientID)
+	}
+}
+
+func (f *Federation) OnSessionResumedWrapper(pre server.OnSessionResumed) server.OnSessionResumed {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		f.sessionClaimed(client.ClientOptions().ClientID)
 	}
 }
 
@@ -23,23 +86,26 @@ func (f *Federation) OnSubscribedWrapper(pre server.OnSubscribed) server.OnSubsc
 	return func(ctx context.Context, client server.Client, subscription *gmqtt.Subscription) {
 		pre(ctx, client, subscription)
 		if subscription != nil {
-			if !f.localSubStore.subscribe(client.ClientOptions().ClientID, subscription.GetFullTopicName()) {
-				return
-			}
-			// only send new subscription
-			f.memberMu.Lock()
-			defer f.memberMu.Unlock()
-			for _, v := range f.peers {
-				sub := &Subscribe{
-					ShareName:   subscription.ShareName,
-					TopicFilter: subscription.TopicFilter,
-				}
-				v.queue.add(&Event{
-					Event: &Event_Subscribe{
-						Subscribe: sub,
-					}})
-			}
+			f.subscribed(client.ClientOptions().ClientID, subscription)
+		}
+	}
+}
+
+// subscribed adds the subscription into localSubStore and sends it to other nodes if it is new.
+func (f *Federation) subscribed(clientID string, subscription *gmqtt.Subscription) {
+	if !f.localSubStore.subscribe(clientID, subscription.GetFullTopicName()) {
+		return
+	}
+	// only send new subscription
+	for _, v := range f.peerList() {
+		sub := &Subscribe{
+			ShareName:   subscription.ShareName,
+			TopicFilter: subscription.TopicFilter,
 		}
+		v.queue.add(&Event{
+			Event: &Event_Subscribe{
+				Subscribe: sub,
+			}})
 	}
 }
 
@@ -50,9 +116,7 @@ func (f *Federation) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnU
 			return
 		}
 		// only unsubscribe topic if there is no local subscriber anymore.
-		f.memberMu.Lock()
-		defer f.memberMu.Unlock()
-		for _, v := range f.peers {
+		for _, v := range f.peerList() {
 			unsub := &Unsubscribe{
 				TopicName: topicName,
 			}
@@ -64,15 +128,72 @@ func (f *Federation) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnU
 	}
 }
 
-func sendSharedMsg(fs *fedSubStore, sharedList map[string][]string, send func(nodeName string, topicName string)) {
-	// shared subscription
-	fs.sharedMu.Lock()
-	defer fs.sharedMu.Unlock()
+// sharedNodes is the node list of a shared subscription.
+type sharedNodes struct {
+	shareName   string
+	topicFilter string
+	nodes       []string
+}
+
+// add appends the node to the list, it creates the list if s is nil.
+func (s *sharedNodes) add(sub *gmqtt.Subscription, nodeName string) *sharedNodes {
+	if s == nil {
+		s = &sharedNodes{
+			shareName:   sub.ShareName,
+			topicFilter: sub.TopicFilter,
+		}
+	}
+	s.nodes = append(s.nodes, nodeName)
+	return s
+}
+
+// peerList returns the snapshot of the peers.
+// The events are added to the peer queues without holding memberMu, since adding an event may block.
+func (f *Federation) peerList() []*peer {
+	f.memberMu.Lock()
+	defer f.memberMu.Unlock()
+	peers := make([]*peer, 0, len(f.peers))
+	for _, v := range f.peers {
+		peers = append(peers, v)
+	}
+	return peers
+}
+
+// peerMap returns the snapshot of the peers keyed by the node name.
+func (f *Federation) peerMap() map[string]*peer {
+	f.memberMu.Lock()
+	defer f.memberMu.Unlock()
+	peers := make(map[string]*peer, len(f.peers))
+	for k, v := range f.peers {
+		peers[k] = v
+	}
+	return peers
+}
+
+// peerQueueDepth returns the function which returns the number of the events in the queue of the peer node.
+// The function returns 0 for the local node, since the local delivery does not go through a peer queue.
+func peerQueueDepth(peers map[string]*peer) func(nodeName string) int {
+	return func(nodeName string) int {
+		if p, ok := peers[nodeName]; ok {
+			return p.queue.status().length
+		}
+		return 0
+	}
+}
+
+// sendSharedMsg selects one node for each shared subscription with the configured shared subscription strategy.
+func (f *Federation) sendSharedMsg(msg *gmqtt.Message, publisherID string, sharedList map[string]*sharedNodes, queueDepth func(nodeName string) int, send func(nodeName string, topicName string)) {
 	for topicName, v := range sharedList {
-		sort.Strings(v)
-		mod := fs.sharedSent[topicName] % (uint64(len(v)))
-		fs.sharedSent[topicName]++
-		send(v[mod], topicName)
+		sort.Strings(v.nodes)
+		i := f.sharedSelector.Select(&server.SharedSelectRequest{
+			PublisherID: publisherID,
+			Message:     msg,
+			ShareName:   v.shareName,
+			TopicFilter: v.topicFilter,
+			Candidates:  v.nodes,
+			QueueDepth:  queueDepth,
+		})
+		send(v.nodes[i], topicName)
 	}
 }
 
@@ -82,27 +203,35 @@ func sendSharedMsg(fs *fedSubStore, sharedList map[string][]string, send func(no
 // For shared subscription, we should either only send the message to local subscriber or only send the message to one node.
 // If drop is true, the local node will drop the message.
 // If options is not nil, the local node will apply the options to topic matching process.
-func (f *Federation) sendMessage(msg *gmqtt.Message) (drop bool, options *subscription.IterationOptions) {
-	f.memberMu.Lock()
-	defer f.memberMu.Unlock()
+// If wait is true, the message waits for the queue space with the block overflow policy,
+// so it must not be true if the caller holds any broker lock.
+func (f *Federation) sendMessage(msg *gmqtt.Message, publisherID string, wait bool) (drop bool, options *subscription.IterationOptions) {
+	peers := f.peerMap()
+	add := func(p *peer) {
+		event := &Event{
+			Event: &Event_Message{
+				Message: messageToEvent(msg),
+			}}
+		if wait {
+			p.queue.addWait(event)
+		} else {
+			p.queue.add(event)
+		}
+	}
 
 	if msg.Retained {
-		eventMsg := messageToEvent(msg)
-		for _, v := range f.peers {
-			v.queue.add(&Event{
-				Event: &Event_Message{
-					Message: eventMsg,
-				}})
+		for _, v := range peers {
+			add(v)
 		}
 		return
 	}
 
 	// shared topic => []nodeName.
-	sharedList := make(map[string][]string)
+	sharedList := make(map[string]*sharedNodes)
 	// append local shared subscription
 	f.localSubStore.localStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
 		fullTopic := sub.GetFullTopicName()
-		sharedList[fullTopic] = append(sharedList[fullTopic], f.nodeName)
+		sharedList[fullTopic] = sharedList[fullTopic].add(sub, f.nodeName)
 		return true
 	}, subscription.IterationOptions{
 		Type:      subscription.TypeShared,
@@ -116,7 +245,7 @@ func (f *Federation) sendMessage(msg *gmqtt.Message) (drop bool, options *subscr
 	f.fedSubStore.Iterate(func(nodeName string, sub *gmqtt.Subscription) bool {
 		if sub.ShareName != "" {
 			fullTopic := sub.GetFullTopicName()
-			sharedList[fullTopic] = append(sharedList[fullTopic], nodeName)
+			sharedList[fullTopic] = sharedList[fullTopic].add(sub, nodeName)
 			return true
 		}
 		nonShared[nodeName] = struct{}{}
@@ -129,7 +258,7 @@ func (f *Federation) sendMessage(msg *gmqtt.Message) (drop bool, options *subscr
 
 	sent := make(map[string]struct{})
 	// shared subscription
-	sendSharedMsg(f.fedSubStore, sharedList, func(nodeName string, topicName string) {
+	f.sendSharedMsg(msg, publisherID, sharedList, peerQueueDepth(peers), func(nodeName string, topicName string) {
 		// Do nothing if it is the local node.
 		if nodeName == f.nodeName {
 			return
@@ -138,12 +267,8 @@ func (f *Federation) sendMessage(msg *gmqtt.Message) (drop bool, options *subscr
 			return
 		}
 		sent[nodeName] = struct{}{}
-		if p, ok := f.peers[nodeName]; ok {
-			eventMsg := messageToEvent(msg)
-			p.queue.add(&Event{
-				Event: &Event_Message{
-					Message: eventMsg,
-				}})
+		if p, ok := peers[nodeName]; ok {
+			add(p)
 			drop = true
 			nonSharedOpts := subscription.IterationOptions{
 				Type:      subscription.TypeAll ^ subscription.TypeShared,
@@ -165,12 +290,8 @@ func (f *Federation) sendMessage(msg *gmqtt.Message) (drop bool, options *subscr
 		if _, ok := sent[nodeName]; ok {
 			continue
 		}
-		if p, ok := f.peers[nodeName]; ok {
-			eventMsg := messageToEvent(msg)
-			p.queue.add(&Event{
-				Event: &Event_Message{
-					Message: eventMsg,
-				}})
+		if p, ok := peers[nodeName]; ok {
+			add(p)
 		}
 	}
 	return
@@ -182,7 +303,8 @@ func (f *Federation) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgAr
 			return err
 		}
 		if req.Message != nil {
-			drop, opts := f.sendMessage(req.Message)
+			// OnMsgArrived is not called under the broker lock, so the message can wait for the queue space.
+			drop, opts := f.sendMessage(req.Message, client.ClientOptions().ClientID, true)
 			if drop {
 				req.Drop()
 			}
@@ -197,10 +319,9 @@ func (f *Federation) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgAr
 func (f *Federation) OnSessionTerminatedWrapper(pre server.OnSessionTerminated) server.OnSessionTerminated {
 	return func(ctx context.Context, clientID string, reason server.SessionTerminatedReason) {
 		pre(ctx, clientID, reason)
+		f.sessionReleased(clientID)
 		if unsubs := f.localSubStore.unsubscribeAll(clientID); len(unsubs) != 0 {
-			f.memberMu.Lock()
-			defer f.memberMu.Unlock()
-			for _, v := range f.peers {
+			for _, v := range f.peerList() {
 				for _, topicName := range unsubs {
 					unsub := &Unsubscribe{
 						TopicName: topicName,
@@ -220,7 +341,8 @@ func (f *Federation) OnWillPublishWrapper(pre server.OnWillPublish) server.OnWil
 	return func(ctx context.Context, clientID string, req *server.WillMsgRequest) {
 		pre(ctx, clientID, req)
 		if req.Message != nil {
-			drop, opts := f.sendMessage(req.Message)
+			// OnWillPublish is called under the broker lock.
+			drop, opts := f.sendMessage(req.Message, clientID, false)
 			if drop {
 				req.Drop()
 			}
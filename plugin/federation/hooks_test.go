@@ -75,7 +75,7 @@ func TestFederation_OnMsgArrivedWrapper(t *testing.T) {
 		Payload:  []byte("payload"),
 		Retained: true,
 	}
-	mockQueue.EXPECT().add(&Event{
+	mockQueue.EXPECT().addWait(&Event{
 		Event: &Event_Message{
 			Message: messageToEvent(retainedMsg),
 		},
@@ -90,7 +90,7 @@ func TestFederation_OnMsgArrivedWrapper(t *testing.T) {
 	}, &gmqtt.Subscription{
 		TopicFilter: "#",
 	})
-	mockQueue.EXPECT().add(&Event{
+	mockQueue.EXPECT().addWait(&Event{
 		Event: &Event_Message{
 			Message: messageToEvent(msg),
 		},
@@ -100,7 +100,7 @@ func TestFederation_OnMsgArrivedWrapper(t *testing.T) {
 	}))
 
 	// send only once if a retained message also has matched topic
-	mockQueue.EXPECT().add(&Event{
+	mockQueue.EXPECT().addWait(&Event{
 		Event: &Event_Message{
 			Message: messageToEvent(retainedMsg),
 		},
@@ -116,6 +116,7 @@ func TestFederation_OnMsgArrivedWrapper_SharedSubscription(t *testing.T) {
 	a := assert.New(t)
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
+	// the nodes are selected in turn if no strategy is configured.
 	p, _ := New(testConfig)
 	f := p.(*Federation)
 	f.localSubStore.localStore = mem.NewStore()
@@ -164,7 +165,7 @@ func TestFederation_OnMsgArrivedWrapper_SharedSubscription(t *testing.T) {
 
 	// round-robin
 	for k := range nodes {
-		mockQueues[k].EXPECT().add(&Event{
+		mockQueues[k].EXPECT().addWait(&Event{
 			Event: &Event_Message{
 				Message: messageToEvent(msg),
 			},
@@ -190,7 +191,7 @@ func TestFederation_OnMsgArrivedWrapper_SharedSubscription(t *testing.T) {
 	msgReq := &server.MsgArrivedRequest{
 		Message: msg,
 	}
-	mockQueues[0].EXPECT().add(&Event{
+	mockQueues[0].EXPECT().addWait(&Event{
 		Event: &Event_Message{
 			Message: messageToEvent(msg),
 		},
@@ -328,8 +329,17 @@ func TestFederation_OnSessionTerminatedWrapper(t *testing.T) {
 		return
 	})
 
+	expectRelease := func(clientID string) {
+		mockQueue.EXPECT().add(&Event{
+			Event: &Event_SessionRelease{
+				SessionRelease: &SessionRelease{ClientId: clientID},
+			},
+		})
+	}
+	expectRelease("client1")
 	onSessionTerminated(context.Background(), "client1", 0)
 
+	expectRelease("client2")
 	mockQueue.EXPECT().add(&Event{
 		Event: &Event_Unsubscribe{
 			Unsubscribe: &Unsubscribe{
@@ -340,6 +350,7 @@ func TestFederation_OnSessionTerminatedWrapper(t *testing.T) {
 	onSessionTerminated(context.Background(), "client2", 0)
 
 	var b, c bool
+	expectRelease("client3")
 	mockQueue.EXPECT().add(gomock.Any()).Do(func(event *Event) {
 		if event.Event.(*Event_Unsubscribe).Unsubscribe.TopicName == "/topicB" {
 			b = true
@@ -353,3 +364,58 @@ func TestFederation_OnSessionTerminatedWrapper(t *testing.T) {
 	a.True(b)
 	a.True(c)
 }
+
+func TestFederation_OnMsgArrivedWrapper_LeastQueueDepth(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := testConfig
+	cfg.SharedSubscription = config.SharedSubscription{
+		Strategy: config.SharedStrategyLeastQueueDepth,
+	}
+	p, _ := New(cfg)
+	f := p.(*Federation)
+	f.localSubStore.localStore = mem.NewStore()
+	onMsgArrived := f.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		return nil
+	})
+	mockCli := server.NewMockClient(ctrl)
+	mockCli.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID: "client1",
+	}).AnyTimes()
+	depth := map[string]int{"node1": 10, "node2": 1}
+	queues := make(map[string]*Mockqueue)
+	for _, v := range []string{"node1", "node2"} {
+		f.nodeJoin(serf.MemberEvent{
+			Members: []serf.Member{
+				{
+					Name: v,
+				},
+			},
+		})
+		f.fedSubStore.Subscribe(v, &gmqtt.Subscription{
+			ShareName:   "abc",
+			TopicFilter: "/topicA",
+		})
+		mq := NewMockqueue(ctrl)
+		mq.EXPECT().status().Return(queueStatus{length: depth[v]}).AnyTimes()
+		queues[v] = mq
+		f.peers[v].queue = mq
+	}
+	msg := &gmqtt.Message{
+		QoS:     1,
+		Topic:   "/topicA",
+		Payload: []byte("payload"),
+	}
+	// the message is sent to the peer with the shorter queue.
+	for i := 0; i < 2; i++ {
+		queues["node2"].EXPECT().addWait(&Event{
+			Event: &Event_Message{
+				Message: messageToEvent(msg),
+			},
+		})
+		a.NoError(onMsgArrived(context.Background(), mockCli, &server.MsgArrivedRequest{
+			Message: msg,
+		}))
+	}
+}
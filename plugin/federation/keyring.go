@@ -0,0 +1,153 @@
+package federation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/hashicorp/memberlist"
+	"github.com/hashicorp/serf/serf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errEncryptionDisabled = status.Error(codes.FailedPrecondition, "gossip encryption is disabled")
+
+func decodeGossipKey(key string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gossip encryption key: %s", err)
+	}
+	if err = memberlist.ValidateKey(b); err != nil {
+		return nil, fmt.Errorf("invalid gossip encryption key: %s", err)
+	}
+	return b, nil
+}
+
+// readKeyringFile returns the keys persisted in the keyring file, it returns nil if the file does not exist.
+func readKeyringFile(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	if err = json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("invalid keyring_file: %s", err)
+	}
+	return keys, nil
+}
+
+// keyring returns the keyring of the gossip, it returns nil if the encryption is disabled.
+func (g *GossipEncryption) keyring() (*memberlist.Keyring, error) {
+	keys := g.Keys
+	if g.KeyringFile != "" {
+		fileKeys, err := readKeyringFile(g.KeyringFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(fileKeys) != 0 {
+			keys = fileKeys
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	var rawKeys [][]byte
+	for _, v := range keys {
+		b, err := decodeGossipKey(v)
+		if err != nil {
+			return nil, err
+		}
+		rawKeys = append(rawKeys, b)
+	}
+	return memberlist.NewKeyring(rawKeys, rawKeys[0])
+}
+
+func keyResponse(resp *serf.KeyResponse) *KeyResponse {
+	rs := &KeyResponse{
+		NumNodes: int32(resp.NumNodes),
+		NumResp:  int32(resp.NumResp),
+		NumErr:   int32(resp.NumErr),
+		Messages: resp.Messages,
+	}
+	rs.Keys = keyCounts(resp.Keys)
+	rs.PrimaryKeys = keyCounts(resp.PrimaryKeys)
+	return rs
+}
+
+// keyCounts converts the key counts, the empty keys reported by the members which don't respond with the keys are ignored.
+func keyCounts(m map[string]int) map[string]int32 {
+	var rs map[string]int32
+	for k, v := range m {
+		if k == "" {
+			continue
+		}
+		if rs == nil {
+			rs = make(map[string]int32)
+		}
+		rs[k] = int32(v)
+	}
+	return rs
+}
+
+// keyOperation performs the keyring operation on all members.
+// If some members failed, the response is returned with the error messages of the members instead of an error.
+func (f *Federation) keyOperation(key string, op func(key string) (*serf.KeyResponse, error)) (*KeyResponse, error) {
+	if f.keyManager == nil {
+		return nil, errEncryptionDisabled
+	}
+	if key != "" {
+		if _, err := decodeGossipKey(key); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	resp, err := op(key)
+	if resp == nil {
+		return nil, err
+	}
+	return keyResponse(resp), nil
+}
+
+// ListKeys lists the gossip encryption keys installed on the members.
+func (f *Federation) ListKeys(ctx context.Context, req *empty.Empty) (*KeyResponse, error) {
+	return f.keyOperation("", func(string) (*serf.KeyResponse, error) {
+		return f.keyManager.ListKeys()
+	})
+}
+
+// InstallKey installs a new gossip encryption key on all members.
+func (f *Federation) InstallKey(ctx context.Context, req *KeyRequest) (*KeyResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty key")
+	}
+	return f.keyOperation(req.Key, func(key string) (*serf.KeyResponse, error) {
+		return f.keyManager.InstallKey(key)
+	})
+}
+
+// UseKey changes the primary gossip encryption key of all members.
+func (f *Federation) UseKey(ctx context.Context, req *KeyRequest) (*KeyResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty key")
+	}
+	return f.keyOperation(req.Key, func(key string) (*serf.KeyResponse, error) {
+		return f.keyManager.UseKey(key)
+	})
+}
+
+// RemoveKey removes a gossip encryption key from all members.
+func (f *Federation) RemoveKey(ctx context.Context, req *KeyRequest) (*KeyResponse, error) {
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty key")
+	}
+	return f.keyOperation(req.Key, func(key string) (*serf.KeyResponse, error) {
+		return f.keyManager.RemoveKey(key)
+	})
+}
@@ -0,0 +1,99 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/hashicorp/serf/serf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testGossipKey1 = "MTIzNDU2Nzg5MDEyMzQ1Ng=="
+	testGossipKey2 = "YWJjZGVmZ2hpamtsbW5vcA=="
+)
+
+func TestGossipEncryption_keyring(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "federation_keyring")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	g := &GossipEncryption{}
+	kr, err := g.keyring()
+	a.NoError(err)
+	a.Nil(kr)
+
+	g = &GossipEncryption{
+		Keys:        []string{testGossipKey1, testGossipKey2},
+		KeyringFile: filepath.Join(dir, "keyring"),
+	}
+	kr, err = g.keyring()
+	a.NoError(err)
+	a.Len(kr.GetKeys(), 2)
+	a.Equal("1234567890123456", string(kr.GetPrimaryKey()))
+
+	// the keys in the keyring file take precedence.
+	a.NoError(ioutil.WriteFile(g.KeyringFile, []byte(`["`+testGossipKey2+`"]`), 0600))
+	kr, err = g.keyring()
+	a.NoError(err)
+	a.Len(kr.GetKeys(), 1)
+	a.Equal("abcdefghijklmnop", string(kr.GetPrimaryKey()))
+
+	a.NoError(ioutil.WriteFile(g.KeyringFile, []byte(`invalid`), 0600))
+	_, err = g.keyring()
+	a.Error(err)
+}
+
+func TestFederation_KeyAPI(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	f := &Federation{}
+	_, err := f.ListKeys(context.Background(), &empty.Empty{})
+	a.Equal(codes.FailedPrecondition, status.Code(err))
+
+	km := NewMockiKeyManager(ctrl)
+	f.keyManager = km
+
+	_, err = f.InstallKey(context.Background(), &KeyRequest{Key: "invalid"})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+	_, err = f.UseKey(context.Background(), &KeyRequest{})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	km.EXPECT().InstallKey(testGossipKey2).Return(&serf.KeyResponse{
+		NumNodes: 2,
+		NumResp:  2,
+		NumErr:   1,
+		Messages: map[string]string{"node2": "failed"},
+	}, errors.New("1/2 nodes reported failure"))
+	resp, err := f.InstallKey(context.Background(), &KeyRequest{Key: testGossipKey2})
+	a.NoError(err)
+	a.EqualValues(1, resp.NumErr)
+	a.Equal("failed", resp.Messages["node2"])
+
+	km.EXPECT().ListKeys().Return(&serf.KeyResponse{
+		NumNodes:    2,
+		NumResp:     2,
+		Keys:        map[string]int{testGossipKey1: 2, testGossipKey2: 1},
+		PrimaryKeys: map[string]int{testGossipKey1: 2},
+	}, nil)
+	resp, err = f.ListKeys(context.Background(), &empty.Empty{})
+	a.NoError(err)
+	a.Equal(map[string]int32{testGossipKey1: 2, testGossipKey2: 1}, resp.Keys)
+	a.Equal(map[string]int32{testGossipKey1: 2}, resp.PrimaryKeys)
+
+	e := errors.New("error")
+	km.EXPECT().RemoveKey(testGossipKey1).Return(nil, e)
+	_, err = f.RemoveKey(context.Background(), &KeyRequest{Key: testGossipKey1})
+	a.Equal(e, err)
+}
@@ -18,6 +18,15 @@ type iSerf interface {
 	Shutdown() error
 }
 
+// iKeyManager is the interface for *serf.KeyManager.
+// It is used for test.
+type iKeyManager interface {
+	InstallKey(key string) (*serf.KeyResponse, error)
+	UseKey(key string) (*serf.KeyResponse, error)
+	RemoveKey(key string) (*serf.KeyResponse, error)
+	ListKeys() (*serf.KeyResponse, error)
+}
+
 var servePeerEventStream = func(p *peer) {
 	p.serveEventStream()
 }
@@ -73,9 +82,10 @@ func (f *Federation) nodeJoin(member serf.MemberEvent) {
 				member:    v,
 				exit:      make(chan struct{}),
 				sessionID: uuid.New().String(),
-				queue:     newEventQueue(),
+				stats:     &peerStats{},
 				localName: f.nodeName,
 			}
+			p.queue = newEventQueue(&f.config.PeerQueue, p.stats, p.overflow)
 			f.peers[v.Name] = p
 			go servePeerEventStream(p)
 		}
@@ -94,6 +104,7 @@ func (f *Federation) nodeFail(member serf.MemberEvent) {
 			p.stop()
 			delete(f.peers, v.Name)
 			_ = f.fedSubStore.UnsubscribeAll(v.Name)
+			f.registry.removeNode(v.Name)
 			f.sessionMgr.del(v.Name)
 		}
 	}
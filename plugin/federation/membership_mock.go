@@ -103,3 +103,86 @@ func (mr *MockiSerfMockRecorder) Shutdown() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockiSerf)(nil).Shutdown))
 }
+
+// MockiKeyManager is a mock of iKeyManager interface
+type MockiKeyManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockiKeyManagerMockRecorder
+}
+
+// MockiKeyManagerMockRecorder is the mock recorder for MockiKeyManager
+type MockiKeyManagerMockRecorder struct {
+	mock *MockiKeyManager
+}
+
+// NewMockiKeyManager creates a new mock instance
+func NewMockiKeyManager(ctrl *gomock.Controller) *MockiKeyManager {
+	mock := &MockiKeyManager{ctrl: ctrl}
+	mock.recorder = &MockiKeyManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockiKeyManager) EXPECT() *MockiKeyManagerMockRecorder {
+	return m.recorder
+}
+
+// InstallKey mocks base method
+func (m *MockiKeyManager) InstallKey(key string) (*serf.KeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InstallKey", key)
+	ret0, _ := ret[0].(*serf.KeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InstallKey indicates an expected call of InstallKey
+func (mr *MockiKeyManagerMockRecorder) InstallKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstallKey", reflect.TypeOf((*MockiKeyManager)(nil).InstallKey), key)
+}
+
+// UseKey mocks base method
+func (m *MockiKeyManager) UseKey(key string) (*serf.KeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseKey", key)
+	ret0, _ := ret[0].(*serf.KeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseKey indicates an expected call of UseKey
+func (mr *MockiKeyManagerMockRecorder) UseKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseKey", reflect.TypeOf((*MockiKeyManager)(nil).UseKey), key)
+}
+
+// RemoveKey mocks base method
+func (m *MockiKeyManager) RemoveKey(key string) (*serf.KeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveKey", key)
+	ret0, _ := ret[0].(*serf.KeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveKey indicates an expected call of RemoveKey
+func (mr *MockiKeyManagerMockRecorder) RemoveKey(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveKey", reflect.TypeOf((*MockiKeyManager)(nil).RemoveKey), key)
+}
+
+// ListKeys mocks base method
+func (m *MockiKeyManager) ListKeys() (*serf.KeyResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListKeys")
+	ret0, _ := ret[0].(*serf.KeyResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListKeys indicates an expected call of ListKeys
+func (mr *MockiKeyManagerMockRecorder) ListKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListKeys", reflect.TypeOf((*MockiKeyManager)(nil).ListKeys))
+}
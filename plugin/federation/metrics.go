@@ -0,0 +1,77 @@
+package federation
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricPrefix = "gmqtt_federation_peer_"
+
+var (
+	peerConnectedDesc = prometheus.NewDesc(metricPrefix+"connected",
+		"Whether the event stream to the peer is connected (1) or not (0).", []string{"peer"}, nil)
+	peerQueueLengthDesc = prometheus.NewDesc(metricPrefix+"queue_length",
+		"The number of the events in the queue of the peer, including the events which are sent but not acked.", []string{"peer"}, nil)
+	peerNextEventIDDesc = prometheus.NewDesc(metricPrefix+"next_event_id",
+		"The id of the next event to be added into the queue of the peer.", []string{"peer"}, nil)
+	peerAckedEventIDDesc = prometheus.NewDesc(metricPrefix+"acked_event_id",
+		"The id of the last event acked by the peer.", []string{"peer"}, nil)
+	peerRTTDesc = prometheus.NewDesc(metricPrefix+"rtt_seconds",
+		"The round trip time from sending the last acked batch to receiving the ack.", []string{"peer"}, nil)
+	peerReconnectsDesc = prometheus.NewDesc(metricPrefix+"reconnects_total",
+		"The number of times the event stream to the peer reconnected.", []string{"peer"}, nil)
+	peerDroppedDesc = prometheus.NewDesc(metricPrefix+"dropped_events_total",
+		"The number of the events dropped by the overflow policy.", []string{"peer"}, nil)
+	peerOverflowsDesc = prometheus.NewDesc(metricPrefix+"overflow_disconnects_total",
+		"The number of times the peer was disconnected because the queue was full.", []string{"peer"}, nil)
+)
+
+// collector collects the metrics of the peers.
+// It is a package level variable to be registered once, since the prometheus registry rejects another collector with the same metrics.
+var collector = &peerCollector{}
+
+type peerCollector struct {
+	mu  sync.Mutex
+	fed *Federation
+}
+
+func (c *peerCollector) setFederation(f *Federation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fed = f
+}
+
+func (c *peerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerConnectedDesc
+	ch <- peerQueueLengthDesc
+	ch <- peerNextEventIDDesc
+	ch <- peerAckedEventIDDesc
+	ch <- peerRTTDesc
+	ch <- peerReconnectsDesc
+	ch <- peerDroppedDesc
+	ch <- peerOverflowsDesc
+}
+
+func (c *peerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	f := c.fed
+	c.mu.Unlock()
+	if f == nil {
+		return
+	}
+	for _, v := range f.peerInfos() {
+		var connected float64
+		if v.Connected {
+			connected = 1
+		}
+		ch <- prometheus.MustNewConstMetric(peerConnectedDesc, prometheus.GaugeValue, connected, v.Name)
+		ch <- prometheus.MustNewConstMetric(peerQueueLengthDesc, prometheus.GaugeValue, float64(v.QueueLength), v.Name)
+		ch <- prometheus.MustNewConstMetric(peerNextEventIDDesc, prometheus.GaugeValue, float64(v.NextEventId), v.Name)
+		ch <- prometheus.MustNewConstMetric(peerAckedEventIDDesc, prometheus.GaugeValue, float64(v.AckedEventId), v.Name)
+		ch <- prometheus.MustNewConstMetric(peerRTTDesc, prometheus.GaugeValue, v.RttMs/1000, v.Name)
+		ch <- prometheus.MustNewConstMetric(peerReconnectsDesc, prometheus.CounterValue, float64(v.Reconnects), v.Name)
+		ch <- prometheus.MustNewConstMetric(peerDroppedDesc, prometheus.CounterValue, float64(v.DroppedEvents), v.Name)
+		ch <- prometheus.MustNewConstMetric(peerOverflowsDesc, prometheus.CounterValue, float64(v.OverflowDisconnects), v.Name)
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hashicorp/serf/serf"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/DrmagicE/gmqtt"
@@ -26,6 +29,9 @@ const (
 	peerStateStreaming
 )
 
+// errQueueOverflow is returned by fetchEvents to disconnect the stream after the queue is discarded due to overflow.
+var errQueueOverflow = errors.New("the queue of the peer is full")
+
 // peer represents a remote node which act as the event stream server.
 type peer struct {
 	fed       *Federation
@@ -35,21 +41,84 @@ type peer struct {
 	// local session id
 	sessionID string
 	queue     queue
+	stats     *peerStats
 	// stateMu guards the following fields
 	stateMu sync.Mutex
 	state   peerState
 	// client-side stream
 	stream *stream
+	// client is the gRPC client of the peer, nil if the stream has not been initialized.
+	client FederationClient
+	// renewSession is set to 1 after overflow to renew the session on reconnect, it is accessed atomically.
+	renewSession int32
+}
+
+// peerStats is the state and statistics of the peer, the fields are accessed atomically,
+// so that they can be read without waiting for the stream which may be blocked in the handshake.
+type peerStats struct {
+	// ackedID is the id of the last acked event.
+	ackedID uint64
+	// rtt is the round trip time of the last acked batch in nanoseconds.
+	rtt        int64
+	reconnects uint64
+	// dropped is the number of the events dropped by the overflow policy.
+	dropped uint64
+	// overflows is the number of times the queue was discarded due to overflow.
+	overflows uint64
+	// connected is 1 if the stream is connected.
+	connected int32
+	// batch is 1 if the events are sent in batches by the stream.
+	batch int32
 }
 
 type stream struct {
 	queue   queue
+	stats   *peerStats
+	batch   bool
 	conn    *grpc.ClientConn
-	client  Federation_EventStreamClient
+	client  eventStreamClient
 	close   chan struct{}
 	errOnce sync.Once
 	err     error
 	wg      sync.WaitGroup
+	// inflightMu guards inflight
+	inflightMu sync.Mutex
+	// inflight is the batches which are sent but not acked, in the order of sending.
+	inflight []inflightBatch
+}
+
+// inflightBatch is used to measure the round trip time.
+type inflightBatch struct {
+	lastID uint64
+	sentAt time.Time
+}
+
+// eventStreamClient is the common interface of the client-side streams of EventStream and EventBatchStream.
+type eventStreamClient interface {
+	send(events []*Event) error
+	Recv() (*Ack, error)
+}
+
+// singleEventStream sends the events one by one, it is used if the peer does not support EventBatchStream.
+type singleEventStream struct {
+	Federation_EventStreamClient
+}
+
+func (s singleEventStream) send(events []*Event) error {
+	for _, v := range events {
+		if err := s.Send(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type batchEventStream struct {
+	Federation_EventBatchStreamClient
+}
+
+func (s batchEventStream) send(events []*Event) error {
+	return s.Send(&EventBatch{Events: events})
 }
 
 // interface for testing
@@ -58,51 +127,89 @@ type queue interface {
 	close()
 	open()
 	setReadPosition(id uint64)
+	// add adds the event without blocking, so it can be called under the broker and federation locks.
+	// With the block overflow policy, the queue is discarded if it is full, like the disconnect policy.
 	add(event *Event)
-	fetchEvents() []*Event
+	// addWait adds the event, it waits for the space up to PeerQueue.BlockTimeout with the block overflow policy.
+	// It must not be called under any broker or federation lock.
+	addWait(event *Event)
+	// fill adds the events of the full state sync, which are not limited by the max size.
+	fill(events []*Event)
+	fetchEvents() ([]*Event, error)
 	ack(id uint64)
+	status() queueStatus
+}
+
+// queueStatus is the status of the queue.
+type queueStatus struct {
+	// length is the number of the events in the queue, including the events which are sent but not acked.
+	length int
+	// nextID is the id of the next event to be added.
+	nextID uint64
 }
 
 // eventQueue store the events that are ready to send.
-// TODO add max buffer size
+// The number of the events is limited by PeerQueue.MaxSize, and the events which exceed the limit
+// are handled by PeerQueue.OverflowPolicy.
 type eventQueue struct {
 	cond     *sync.Cond
 	nextID   uint64
 	l        *list.List
 	nextRead *list.Element
 	closed   bool
+	config   *PeerQueue
+	stats    *peerStats
+	// unsentQoS0 is the number of the QoS 0 messages which are not sent, they are dropped first by the drop_qos0 policy.
+	unsentQoS0 int
+	// overflowed indicates that the queue has been discarded due to overflow and the stream should be disconnected.
+	overflowed bool
+	// onOverflow is called after the queue is discarded due to overflow.
+	onOverflow func()
 }
 
-func newEventQueue() *eventQueue {
+func newEventQueue(config *PeerQueue, stats *peerStats, onOverflow func()) *eventQueue {
 	return &eventQueue{
-		cond:   sync.NewCond(&sync.Mutex{}),
-		nextID: 0,
-		l:      list.New(),
-		closed: false,
+		cond:       sync.NewCond(&sync.Mutex{}),
+		nextID:     0,
+		l:          list.New(),
+		closed:     false,
+		config:     config,
+		stats:      stats,
+		onOverflow: onOverflow,
 	}
 }
 
+func isQoS0(event *Event) bool {
+	msg := event.GetMessage()
+	return msg != nil && msg.Qos == 0
+}
+
 func (e *eventQueue) clear() {
 	e.cond.L.Lock()
-	defer e.cond.L.Unlock()
+	defer func() {
+		e.cond.L.Unlock()
+		e.cond.Broadcast()
+	}()
 	e.nextID = 0
 	e.l = list.New()
 	e.nextRead = nil
 	e.closed = false
+	e.unsentQoS0 = 0
+	e.overflowed = false
 }
 
 func (e *eventQueue) close() {
 	e.cond.L.Lock()
 	defer e.cond.L.Unlock()
 	e.closed = true
-	e.cond.Signal()
+	e.cond.Broadcast()
 }
 
 func (e *eventQueue) open() {
 	e.cond.L.Lock()
 	defer e.cond.L.Unlock()
 	e.closed = false
-	e.cond.Signal()
+	e.cond.Broadcast()
 }
 
 func (e *eventQueue) setReadPosition(id uint64) {
@@ -112,54 +219,165 @@ func (e *eventQueue) setReadPosition(id uint64) {
 		ev := elem.Value.(*Event)
 		if ev.Id == id {
 			e.nextRead = elem
-			return
+			break
+		}
+	}
+	e.unsentQoS0 = 0
+	for elem := e.nextRead; elem != nil; elem = elem.Next() {
+		if isQoS0(elem.Value.(*Event)) {
+			e.unsentQoS0++
 		}
 	}
 }
 
-func (e *eventQueue) add(event *Event) {
-	e.cond.L.Lock()
-	defer func() {
-		e.cond.L.Unlock()
-		e.cond.Signal()
-	}()
+func (e *eventQueue) pushLocked(event *Event) {
 	event.Id = e.nextID
 	e.nextID++
 	elem := e.l.PushBack(event)
 	if e.nextRead == nil {
 		e.nextRead = elem
 	}
+	if isQoS0(event) {
+		e.unsentQoS0++
+	}
+}
+
+func (e *eventQueue) add(event *Event) {
+	e.addEvent(event, false)
+}
+
+func (e *eventQueue) addWait(event *Event) {
+	e.addEvent(event, true)
+}
+
+func (e *eventQueue) addEvent(event *Event, wait bool) {
+	e.cond.L.Lock()
+	ok, overflow := e.makeRoomLocked(event, wait)
+	if ok {
+		e.pushLocked(event)
+	}
+	e.cond.L.Unlock()
+	e.cond.Broadcast()
+	if overflow && e.onOverflow != nil {
+		e.onOverflow()
+	}
+}
+
+func (e *eventQueue) fill(events []*Event) {
+	e.cond.L.Lock()
+	defer func() {
+		e.cond.L.Unlock()
+		e.cond.Broadcast()
+	}()
+	for _, v := range events {
+		e.pushLocked(v)
+	}
+}
+
+// makeRoomLocked makes room for the event according to the overflow policy if the queue is full.
+// The block policy only waits if wait is true, the waiting releases the lock of the queue.
+// It returns whether the event can be added, and whether the queue has been discarded due to overflow.
+func (e *eventQueue) makeRoomLocked(event *Event, wait bool) (ok bool, overflow bool) {
+	if e.l.Len() < e.config.maxSize() {
+		return true, false
+	}
+	switch e.config.overflowPolicy() {
+	case OverflowDropQoS0:
+		if isQoS0(event) {
+			atomic.AddUint64(&e.stats.dropped, 1)
+			return false, false
+		}
+		if e.unsentQoS0 > 0 {
+			e.dropUnsentQoS0Locked()
+			atomic.AddUint64(&e.stats.dropped, 1)
+			return true, false
+		}
+	case OverflowBlock:
+		if wait && e.waitLocked(e.config.blockTimeout()) {
+			return true, false
+		}
+	}
+	// Discard the queue, the peer will rebuild the full state on reconnect.
+	atomic.AddUint64(&e.stats.dropped, uint64(e.l.Len())+1)
+	atomic.AddUint64(&e.stats.overflows, 1)
+	e.l = list.New()
+	e.nextRead = nil
+	e.unsentQoS0 = 0
+	e.overflowed = true
+	return false, true
 }
 
-func (e *eventQueue) fetchEvents() []*Event {
+// dropUnsentQoS0Locked drops the oldest QoS 0 message which is not sent.
+func (e *eventQueue) dropUnsentQoS0Locked() {
+	for elem := e.nextRead; elem != nil; elem = elem.Next() {
+		if isQoS0(elem.Value.(*Event)) {
+			if elem == e.nextRead {
+				e.nextRead = elem.Next()
+			}
+			e.l.Remove(elem)
+			e.unsentQoS0--
+			return
+		}
+	}
+}
+
+// waitLocked waits until the queue has space, it returns false if timeout expires.
+func (e *eventQueue) waitLocked(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	t := time.AfterFunc(timeout, func() {
+		e.cond.L.Lock()
+		defer e.cond.L.Unlock()
+		e.cond.Broadcast()
+	})
+	defer t.Stop()
+	for e.l.Len() >= e.config.maxSize() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		e.cond.Wait()
+	}
+	return true
+}
+
+// fetchEvents returns the next batch of the events to send, it returns nil if the queue has been closed,
+// or errQueueOverflow if the queue has been discarded due to overflow.
+func (e *eventQueue) fetchEvents() ([]*Event, error) {
 	e.cond.L.Lock()
 	defer e.cond.L.Unlock()
 
-	for (e.l.Len() == 0 || e.nextRead == nil) && !e.closed {
+	for (e.l.Len() == 0 || e.nextRead == nil) && !e.closed && !e.overflowed {
 		e.cond.Wait()
 	}
+	if e.overflowed {
+		e.overflowed = false
+		return nil, errQueueOverflow
+	}
 	if e.closed {
-		return nil
+		return nil, nil
 	}
 	ev := make([]*Event, 0)
 	var elem *list.Element
 	elem = e.nextRead
-	for i := 0; i < 100; i++ {
-		ev = append(ev, elem.Value.(*Event))
+	for i := 0; i < e.config.batchSize(); i++ {
+		event := elem.Value.(*Event)
+		ev = append(ev, event)
+		if isQoS0(event) {
+			e.unsentQoS0--
+		}
 		elem = elem.Next()
 		if elem == nil {
 			break
 		}
 	}
 	e.nextRead = elem
-	return ev
+	return ev, nil
 }
 
 func (e *eventQueue) ack(id uint64) {
 	e.cond.L.Lock()
 	defer func() {
 		e.cond.L.Unlock()
-		e.cond.Signal()
+		e.cond.Broadcast()
 	}()
 	var next *list.Element
 	for elem := e.l.Front(); elem != nil; elem = next {
@@ -174,6 +392,42 @@ func (e *eventQueue) ack(id uint64) {
 	}
 }
 
+func (e *eventQueue) status() queueStatus {
+	e.cond.L.Lock()
+	defer e.cond.L.Unlock()
+	return queueStatus{
+		length: e.l.Len(),
+		nextID: e.nextID,
+	}
+}
+
+// info returns the state of the peer.
+func (p *peer) info() *Peer {
+	st := p.queue.status()
+	return &Peer{
+		Name:                p.member.Name,
+		Addr:                p.member.Tags["fed_addr"],
+		Connected:           atomic.LoadInt32(&p.stats.connected) == 1,
+		Batch:               atomic.LoadInt32(&p.stats.batch) == 1,
+		QueueLength:         uint64(st.length),
+		NextEventId:         st.nextID,
+		AckedEventId:        atomic.LoadUint64(&p.stats.ackedID),
+		RttMs:               float64(atomic.LoadInt64(&p.stats.rtt)) / float64(time.Millisecond),
+		Reconnects:          atomic.LoadUint64(&p.stats.reconnects),
+		DroppedEvents:       atomic.LoadUint64(&p.stats.dropped),
+		OverflowDisconnects: atomic.LoadUint64(&p.stats.overflows),
+	}
+}
+
+// overflow is called after the queue is discarded due to overflow, the stream is disconnected by fetchEvents.
+// It renews the session on reconnect, so that the peer rebuilds the full state.
+// It must not wait for stateMu, since it is called in the hooks.
+func (p *peer) overflow() {
+	atomic.StoreInt32(&p.renewSession, 1)
+	log.Warn("the queue of the peer is full, disconnect the peer", zap.String("remote_node", p.member.Name),
+		zap.String("overflow_policy", p.fed.config.PeerQueue.overflowPolicy()))
+}
+
 func (p *peer) stop() {
 	select {
 	case <-p.exit:
@@ -210,6 +464,7 @@ func (p *peer) serveEventStream() {
 				log.Error("stream broken, reconnecting", zap.Error(err),
 					zap.Int("reconnect_count", reconnectCount))
 				reconnectCount++
+				atomic.AddUint64(&p.stats.reconnects, 1)
 				continue
 			}
 			return
@@ -228,6 +483,9 @@ func (p *peer) initStream(client FederationClient, conn *grpc.ClientConn) (s *st
 	if p.state == peerStateStopped {
 		return nil, errors.New("peer has been stopped")
 	}
+	if atomic.CompareAndSwapInt32(&p.renewSession, 1, 0) {
+		p.sessionID = uuid.New().String()
+	}
 	helloMD := metadata.Pairs("node_name", p.localName)
 	helloCtx := metadata.NewOutgoingContext(context.Background(), helloMD)
 	sh, err := client.Hello(helloCtx, &ClientHello{
@@ -240,10 +498,11 @@ func (p *peer) initStream(client FederationClient, conn *grpc.ClientConn) (s *st
 	if sh.CleanStart {
 		p.queue.clear()
 		// sync full state
+		var events []*Event
 		p.fed.localSubStore.Lock()
 		for k := range p.fed.localSubStore.topics {
 			shareName, topicFilter := subscription.SplitTopic(k)
-			p.queue.add(&Event{
+			events = append(events, &Event{
 				Event: &Event_Subscribe{Subscribe: &Subscribe{
 					ShareName:   shareName,
 					TopicFilter: topicFilter,
@@ -254,29 +513,59 @@ func (p *peer) initStream(client FederationClient, conn *grpc.ClientConn) (s *st
 
 		p.fed.retainedStore.Iterate(func(message *gmqtt.Message) bool {
 			// TODO add timestamp to retained message and use Last Write Wins (LWW) to resolve write conflicts.
-			p.queue.add(&Event{
+			events = append(events, &Event{
 				Event: &Event_Message{
 					Message: messageToEvent(message.Copy()),
 				},
 			})
 			return true
 		})
+
+		err = p.fed.clientService.IterateSession(func(session *gmqtt.Session) bool {
+			events = append(events, &Event{
+				Event: &Event_SessionClaim{
+					SessionClaim: &SessionClaim{ClientId: session.ClientID},
+				},
+			})
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.queue.fill(events)
 	}
 	p.queue.setReadPosition(sh.NextEventId)
 	md := metadata.Pairs("node_name", p.localName)
 	ctx := metadata.NewOutgoingContext(context.Background(), md)
-	c, err := client.EventStream(ctx)
-	if err != nil {
-		return nil, err
+	var c eventStreamClient
+	if sh.Batch {
+		var opts []grpc.CallOption
+		if p.fed.config.PeerQueue.compression() == CompressionGzip {
+			opts = append(opts, grpc.UseCompressor(gzip.Name))
+		}
+		bc, err := client.EventBatchStream(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		c = batchEventStream{bc}
+	} else {
+		sc, err := client.EventStream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c = singleEventStream{sc}
 	}
 	p.queue.open()
 	s = &stream{
 		queue:  p.queue,
+		stats:  p.stats,
+		batch:  sh.Batch,
 		conn:   conn,
 		client: c,
 		close:  make(chan struct{}),
 	}
 	p.stream = s
+	p.client = client
 	return s, nil
 }
 
@@ -294,7 +583,7 @@ func (p *peer) serveStream(reconnectCount int, backoff *time.Timer) (err error)
 		}
 	}()
 	addr := p.member.Tags["fed_addr"]
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	conn, err := grpc.Dial(addr, p.fed.dialOption())
 	if err != nil {
 		return err
 	}
@@ -303,6 +592,13 @@ func (p *peer) serveStream(reconnectCount int, backoff *time.Timer) (err error)
 	if err != nil {
 		return err
 	}
+	var batch int32
+	if s.batch {
+		batch = 1
+	}
+	atomic.StoreInt32(&p.stats.batch, batch)
+	atomic.StoreInt32(&p.stats.connected, 1)
+	defer atomic.StoreInt32(&p.stats.connected, 0)
 	return s.serve()
 }
 
@@ -346,6 +642,7 @@ func (s *stream) readLoop() {
 				return
 			}
 			s.queue.ack(resp.EventId)
+			s.acked(resp.EventId)
 			if ce := log.Check(zapcore.DebugLevel, "event acked"); ce != nil {
 				ce.Write(zap.Uint64("id", resp.EventId))
 			}
@@ -363,19 +660,46 @@ func (s *stream) sendEvents() {
 		s.wg.Done()
 	}()
 	for {
-		events := s.queue.fetchEvents()
-		// stream has been closed
+		var events []*Event
+		events, err = s.queue.fetchEvents()
+		// stream has been closed or the queue has overflowed
 		if events == nil {
 			return
 		}
+		s.sent(events[len(events)-1].Id)
+		err = s.client.send(events)
+		if err != nil {
+			return
+		}
 		for _, v := range events {
-			err := s.client.Send(v)
-			if err != nil {
-				return
-			}
 			if ce := log.Check(zapcore.DebugLevel, "event sent"); ce != nil {
 				ce.Write(zap.String("event", v.String()))
 			}
 		}
 	}
 }
+
+// sent records the send time of the batch whose last event is lastID.
+func (s *stream) sent(lastID uint64) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	s.inflight = append(s.inflight, inflightBatch{
+		lastID: lastID,
+		sentAt: time.Now(),
+	})
+}
+
+// acked updates the acked event id and the round trip time of the acked batches.
+func (s *stream) acked(id uint64) {
+	atomic.StoreUint64(&s.stats.ackedID, id)
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	n := 0
+	for n < len(s.inflight) && s.inflight[n].lastID <= id {
+		n++
+	}
+	if n != 0 {
+		atomic.StoreInt64(&s.stats.rtt, int64(time.Since(s.inflight[n-1].sentAt)))
+		s.inflight = s.inflight[n:]
+	}
+}
@@ -9,6 +9,58 @@ import (
 	reflect "reflect"
 )
 
+// MockeventStreamClient is a mock of eventStreamClient interface
+type MockeventStreamClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockeventStreamClientMockRecorder
+}
+
+// MockeventStreamClientMockRecorder is the mock recorder for MockeventStreamClient
+type MockeventStreamClientMockRecorder struct {
+	mock *MockeventStreamClient
+}
+
+// NewMockeventStreamClient creates a new mock instance
+func NewMockeventStreamClient(ctrl *gomock.Controller) *MockeventStreamClient {
+	mock := &MockeventStreamClient{ctrl: ctrl}
+	mock.recorder = &MockeventStreamClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockeventStreamClient) EXPECT() *MockeventStreamClientMockRecorder {
+	return m.recorder
+}
+
+// send mocks base method
+func (m *MockeventStreamClient) send(events []*Event) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "send", events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// send indicates an expected call of send
+func (mr *MockeventStreamClientMockRecorder) send(events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "send", reflect.TypeOf((*MockeventStreamClient)(nil).send), events)
+}
+
+// Recv mocks base method
+func (m *MockeventStreamClient) Recv() (*Ack, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Recv")
+	ret0, _ := ret[0].(*Ack)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Recv indicates an expected call of Recv
+func (mr *MockeventStreamClientMockRecorder) Recv() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Recv", reflect.TypeOf((*MockeventStreamClient)(nil).Recv))
+}
+
 // Mockqueue is a mock of queue interface
 type Mockqueue struct {
 	ctrl     *gomock.Controller
@@ -92,12 +144,37 @@ func (mr *MockqueueMockRecorder) add(event interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "add", reflect.TypeOf((*Mockqueue)(nil).add), event)
 }
 
+// addWait mocks base method
+func (m *Mockqueue) addWait(event *Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "addWait", event)
+}
+
+// addWait indicates an expected call of addWait
+func (mr *MockqueueMockRecorder) addWait(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "addWait", reflect.TypeOf((*Mockqueue)(nil).addWait), event)
+}
+
+// fill mocks base method
+func (m *Mockqueue) fill(events []*Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "fill", events)
+}
+
+// fill indicates an expected call of fill
+func (mr *MockqueueMockRecorder) fill(events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fill", reflect.TypeOf((*Mockqueue)(nil).fill), events)
+}
+
 // fetchEvents mocks base method
-func (m *Mockqueue) fetchEvents() []*Event {
+func (m *Mockqueue) fetchEvents() ([]*Event, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "fetchEvents")
 	ret0, _ := ret[0].([]*Event)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // fetchEvents indicates an expected call of fetchEvents
@@ -117,3 +194,17 @@ func (mr *MockqueueMockRecorder) ack(id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ack", reflect.TypeOf((*Mockqueue)(nil).ack), id)
 }
+
+// status mocks base method
+func (m *Mockqueue) status() queueStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "status")
+	ret0, _ := ret[0].(queueStatus)
+	return ret0
+}
+
+// status indicates an expected call of status
+func (mr *MockqueueMockRecorder) status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "status", reflect.TypeOf((*Mockqueue)(nil).status))
+}
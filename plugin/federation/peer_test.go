@@ -1,17 +1,22 @@
 package federation
 
 import (
+	"context"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hashicorp/serf/serf"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
 
 	"github.com/DrmagicE/gmqtt"
 	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
 	"github.com/DrmagicE/gmqtt/retained"
 	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
 )
 
 func TestPeer_initStream_CleanStart(t *testing.T) {
@@ -25,10 +30,12 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 	ls.init(mem.NewStore())
 
 	retained := trie.NewStore()
+	cs := server.NewMockClientService(ctrl)
 	p := &peer{
 		fed: &Federation{
 			localSubStore: ls,
 			retainedStore: retained,
+			clientService: cs,
 		},
 		localName: "",
 		member: serf.Member{
@@ -50,6 +57,10 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 	}
 	retained.AddOrReplace(m1)
 	retained.AddOrReplace(m2)
+	cs.EXPECT().IterateSession(gomock.Any()).DoAndReturn(func(fn func(*gmqtt.Session) bool) error {
+		fn(&gmqtt.Session{ClientID: "c1"})
+		return nil
+	})
 
 	client := NewMockFederationClient(ctrl)
 
@@ -60,8 +71,10 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 		NextEventId: 0,
 	}, nil)
 
+	fillCall := mockQueue.EXPECT().fill(gomock.Any())
 	gomock.InOrder(
 		mockQueue.EXPECT().clear(),
+		fillCall,
 		mockQueue.EXPECT().setReadPosition(uint64(0)),
 		mockQueue.EXPECT().open(),
 	)
@@ -70,6 +83,7 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 	// So we had to collect them into map.
 	subEvents := make(map[string]string)
 	msgEvents := make(map[string]string)
+	var claimEvents []string
 
 	expectedSubEvents := map[string]*Event{
 		"topicA": {
@@ -99,18 +113,23 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 			},
 		},
 	}
-	mockQueue.EXPECT().add(gomock.Any()).Do(func(event *Event) {
-		switch event.Event.(type) {
-		case *Event_Subscribe:
-			sub := event.Event.(*Event_Subscribe)
-			subEvents[sub.Subscribe.TopicFilter] = event.String()
-		case *Event_Message:
-			msg := event.Event.(*Event_Message)
-			msgEvents[msg.Message.TopicName] = event.String()
-		default:
-			a.FailNow("unexpected event type: %s", reflect.TypeOf(event.Event))
+	fillCall.Do(func(events []*Event) {
+		a.Len(events, 5)
+		for _, event := range events {
+			switch event.Event.(type) {
+			case *Event_Subscribe:
+				sub := event.Event.(*Event_Subscribe)
+				subEvents[sub.Subscribe.TopicFilter] = event.String()
+			case *Event_Message:
+				msg := event.Event.(*Event_Message)
+				msgEvents[msg.Message.TopicName] = event.String()
+			case *Event_SessionClaim:
+				claimEvents = append(claimEvents, event.GetSessionClaim().ClientId)
+			default:
+				a.FailNow("unexpected event type: %s", reflect.TypeOf(event.Event))
+			}
 		}
-	}).Times(4)
+	})
 
 	client.EXPECT().EventStream(gomock.Any())
 	_, err := p.initStream(client, nil)
@@ -122,6 +141,7 @@ func TestPeer_initStream_CleanStart(t *testing.T) {
 	for k, v := range subEvents {
 		a.Equal(expectedSubEvents[k].String(), v)
 	}
+	a.Equal([]string{"c1"}, claimEvents)
 
 }
 
@@ -170,3 +190,183 @@ func TestPeer_initStream_CleanStartFalse(t *testing.T) {
 	a.NoError(err)
 
 }
+
+func TestPeer_initStream_Batch(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockQueue := NewMockqueue(ctrl)
+	p := &peer{
+		fed: &Federation{
+			config: &Config{},
+		},
+		member: serf.Member{
+			Name: "node2",
+		},
+		sessionID: "sessionID",
+		queue:     mockQueue,
+	}
+	client := NewMockFederationClient(ctrl)
+	client.EXPECT().Hello(gomock.Any(), gomock.Any()).Return(&ServerHello{
+		NextEventId: 10,
+		Batch:       true,
+	}, nil)
+	mockQueue.EXPECT().setReadPosition(uint64(10))
+	mockQueue.EXPECT().open()
+	// with the gzip compressor
+	client.EXPECT().EventBatchStream(gomock.Any(), gomock.Any())
+
+	s, err := p.initStream(client, nil)
+	a.NoError(err)
+	a.IsType(batchEventStream{}, s.client)
+	a.True(s.batch)
+}
+
+func TestEventQueue_overflow(t *testing.T) {
+	a := assert.New(t)
+	qos0 := func() *Event {
+		return &Event{Event: &Event_Message{Message: &Message{Qos: 0}}}
+	}
+	qos1 := func() *Event {
+		return &Event{Event: &Event_Message{Message: &Message{Qos: 1}}}
+	}
+	var q *eventQueue
+	fetch := func() (rs []uint64) {
+		events, err := q.fetchEvents()
+		a.NoError(err)
+		for _, v := range events {
+			rs = append(rs, v.Id)
+		}
+		return rs
+	}
+
+	// drop_qos0
+	stats := &peerStats{}
+	var overflows int
+	q = newEventQueue(&PeerQueue{MaxSize: 3, BatchSize: 1}, stats, func() {
+		overflows++
+	})
+	q.add(qos0())
+	q.add(qos1())
+	q.add(qos0())
+	a.Equal([]uint64{0}, fetch())
+	// the new QoS 0 message is dropped.
+	q.add(qos0())
+	a.EqualValues(1, stats.dropped)
+	// the oldest QoS 0 message which is not sent is dropped.
+	q.add(qos1())
+	a.EqualValues(2, stats.dropped)
+	a.Equal(3, q.status().length)
+	a.Equal([]uint64{1}, fetch())
+	a.Equal([]uint64{3}, fetch())
+	// nothing to drop, the queue is discarded.
+	q.add(qos1())
+	a.Equal(1, overflows)
+	a.EqualValues(6, stats.dropped)
+	a.EqualValues(1, stats.overflows)
+	a.Equal(queueStatus{length: 0, nextID: 4}, q.status())
+	// the stream is disconnected.
+	_, err := q.fetchEvents()
+	a.Equal(errQueueOverflow, err)
+
+	// block
+	stats = &peerStats{}
+	q = newEventQueue(&PeerQueue{
+		MaxSize:        1,
+		OverflowPolicy: OverflowBlock,
+		BlockTimeout:   time.Second,
+	}, stats, nil)
+	q.add(qos0())
+	added := make(chan struct{})
+	go func() {
+		q.addWait(qos0())
+		close(added)
+	}()
+	select {
+	case <-added:
+		a.FailNow("add should be blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	fetch()
+	q.ack(0)
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		a.FailNow("add should be unblocked by ack")
+	}
+	a.Equal(1, q.status().length)
+	// timeout
+	q.config.BlockTimeout = 10 * time.Millisecond
+	q.addWait(qos0())
+	a.EqualValues(1, stats.overflows)
+	a.Equal(0, q.status().length)
+	// add never blocks, the full queue is discarded.
+	q.config.BlockTimeout = time.Minute
+	q.add(qos0())
+	q.add(qos0())
+	a.EqualValues(2, stats.overflows)
+	a.Equal(0, q.status().length)
+
+	// disconnect
+	stats = &peerStats{}
+	q = newEventQueue(&PeerQueue{MaxSize: 1, OverflowPolicy: OverflowDisconnect}, stats, nil)
+	q.add(qos0())
+	q.add(qos0())
+	a.EqualValues(1, stats.overflows)
+	a.EqualValues(2, stats.dropped)
+
+	// the full state sync is not limited.
+	q.fill([]*Event{qos1(), qos1()})
+	a.Equal(2, q.status().length)
+}
+
+func TestPeer_overflow(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	p := &peer{
+		fed: &Federation{
+			config: &Config{},
+		},
+		sessionID: "sessionID",
+		stats:     &peerStats{},
+	}
+	p.queue = newEventQueue(&PeerQueue{MaxSize: 1, OverflowPolicy: OverflowDisconnect}, p.stats, p.overflow)
+	p.queue.add(&Event{})
+	p.queue.add(&Event{})
+	_, err := p.queue.fetchEvents()
+	a.Equal(errQueueOverflow, err)
+
+	// the session is renewed on reconnect.
+	client := NewMockFederationClient(ctrl)
+	client.EXPECT().Hello(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, req *ClientHello, opts ...grpc.CallOption) (*ServerHello, error) {
+		a.NotEqual("sessionID", req.SessionId)
+		return &ServerHello{}, nil
+	})
+	client.EXPECT().EventStream(gomock.Any())
+	_, err = p.initStream(client, nil)
+	a.NoError(err)
+	a.NotEqual("sessionID", p.sessionID)
+	a.EqualValues(0, p.renewSession)
+}
+
+func TestStream_acked(t *testing.T) {
+	a := assert.New(t)
+	s := &stream{
+		stats: &peerStats{},
+	}
+	s.sent(1)
+	s.sent(3)
+	s.acked(0)
+	a.Len(s.inflight, 2)
+	a.Zero(atomic.LoadInt64(&s.stats.rtt))
+	time.Sleep(time.Millisecond)
+	s.acked(1)
+	a.Len(s.inflight, 1)
+	a.EqualValues(1, s.stats.ackedID)
+	a.True(atomic.LoadInt64(&s.stats.rtt) >= int64(time.Millisecond))
+	s.acked(3)
+	a.Len(s.inflight, 0)
+}
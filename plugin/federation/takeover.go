@@ -0,0 +1,305 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	queuestore "github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// pendingSessionTTL is the max time to wait for the session to be created after it is taken over.
+// The pending session will be discarded if the connection fails before creating the session.
+const pendingSessionTTL = time.Minute
+
+// clientRegistry is the cluster-wide client id registry, which stores the node that holds the session of the client.
+// It only stores the sessions on the other nodes, and is maintained by the SessionClaim and SessionRelease events.
+type clientRegistry struct {
+	mu sync.RWMutex
+	// [clientID]nodeName
+	nodes map[string]string
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{
+		nodes: make(map[string]string),
+	}
+}
+
+// claim records that the session of the client is on the node.
+func (c *clientRegistry) claim(clientID string, nodeName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[clientID] = nodeName
+}
+
+// release removes the client if the session of the client is on the node.
+// The client may have been claimed by another node, in which case, the release event is outdated.
+func (c *clientRegistry) release(clientID string, nodeName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes[clientID] == nodeName {
+		delete(c.nodes, clientID)
+	}
+}
+
+// delete removes the client, it is called when the session of the client is on the local node.
+func (c *clientRegistry) delete(clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.nodes, clientID)
+}
+
+// removeNode removes all clients of the node.
+func (c *clientRegistry) removeNode(nodeName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.nodes {
+		if v == nodeName {
+			delete(c.nodes, k)
+		}
+	}
+}
+
+// node returns the node that holds the session of the client, it returns empty string if not found.
+func (c *clientRegistry) node(clientID string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.nodes[clientID]
+}
+
+type pendingSession struct {
+	state *TakeoverResponse
+	at    time.Time
+}
+
+// pendingSessions stores the session state taken over from other nodes,
+// which will be restored after the session is created on the local node.
+type pendingSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*pendingSession
+}
+
+func (p *pendingSessions) add(clientID string, state *TakeoverResponse) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for k, v := range p.sessions {
+		if now.Sub(v.at) > pendingSessionTTL {
+			delete(p.sessions, k)
+		}
+	}
+	p.sessions[clientID] = &pendingSession{
+		state: state,
+		at:    now,
+	}
+}
+
+func (p *pendingSessions) pop(clientID string) *TakeoverResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.sessions[clientID]
+	delete(p.sessions, clientID)
+	if s == nil || time.Since(s.at) > pendingSessionTTL {
+		return nil
+	}
+	return s.state
+}
+
+func subscriptionToProto(sub *gmqtt.Subscription) *ClientSubscription {
+	return &ClientSubscription{
+		ShareName:         sub.ShareName,
+		TopicFilter:       sub.TopicFilter,
+		Qos:               uint32(sub.QoS),
+		NoLocal:           sub.NoLocal,
+		RetainAsPublished: sub.RetainAsPublished,
+		RetainHandling:    uint32(sub.RetainHandling),
+		Id:                sub.ID,
+	}
+}
+
+func protoToSubscription(sub *ClientSubscription) *gmqtt.Subscription {
+	return &gmqtt.Subscription{
+		ShareName:         sub.ShareName,
+		TopicFilter:       sub.TopicFilter,
+		ID:                sub.Id,
+		QoS:               packets.QoS(sub.Qos),
+		NoLocal:           sub.NoLocal,
+		RetainAsPublished: sub.RetainAsPublished,
+		RetainHandling:    byte(sub.RetainHandling),
+	}
+}
+
+// sessionState returns the subscriptions and the unexpired messages in the queue of the local client.
+func (f *Federation) sessionState(clientID string) (subs []*ClientSubscription, msgs []*Message) {
+	f.localSubStore.localStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		subs = append(subs, subscriptionToProto(sub))
+		return true
+	}, subscription.IterationOptions{
+		Type:     subscription.TypeAll,
+		ClientID: clientID,
+	})
+	now := time.Now()
+	err := f.queueService.Iterate(clientID, func(elem *queuestore.Elem) bool {
+		pub, ok := elem.MessageWithID.(*queuestore.Publish)
+		// The pubrel can not be handed over, since the packet id is only valid in the session.
+		if !ok {
+			return true
+		}
+		msg := pub.Message.Copy()
+		if !elem.Expiry.IsZero() {
+			if !elem.Expiry.After(now) {
+				return true
+			}
+			msg.MessageExpiry = uint32(math.Ceil(elem.Expiry.Sub(now).Seconds()))
+		}
+		msgs = append(msgs, messageToEvent(msg))
+		return true
+	})
+	if err != nil && err != server.ErrQueueNotFound {
+		log.Error("fail to read the queue", zap.String("client_id", clientID), zap.Error(err))
+	}
+	return subs, msgs
+}
+
+// Takeover disconnects the client and hands over the session to the caller node.
+func (f *Federation) Takeover(ctx context.Context, req *TakeoverRequest) (*TakeoverResponse, error) {
+	nodeName, err := f.authenticatePeer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.ClientId == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty client_id")
+	}
+	resp := &TakeoverResponse{}
+	resp.Found = f.clientService.TakeoverSession(req.ClientId, !req.CleanStart, func(sess *gmqtt.Session) {
+		if !req.CleanStart {
+			resp.Subscriptions, resp.Messages = f.sessionState(req.ClientId)
+		}
+	})
+	f.registry.claim(req.ClientId, nodeName)
+	log.Info("session taken over",
+		zap.String("client_id", req.ClientId),
+		zap.String("node_name", nodeName),
+		zap.Bool("found", resp.Found),
+		zap.Int("subscriptions", len(resp.Subscriptions)),
+		zap.Int("messages", len(resp.Messages)))
+	return resp, nil
+}
+
+// takeover takes over the session of the client from the node that holds it.
+// It is called before the session is created on the local node,
+// and the session state will be restored after the session is created.
+func (f *Federation) takeover(clientID string, cleanStart bool) {
+	if f.config.SessionTakeover.Disable || clientID == "" {
+		return
+	}
+	nodeName := f.registry.node(clientID)
+	if nodeName == "" {
+		return
+	}
+	f.memberMu.Lock()
+	p := f.peers[nodeName]
+	f.memberMu.Unlock()
+	if p == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.config.SessionTakeover.timeout())
+	defer cancel()
+	resp, err := p.takeover(ctx, &TakeoverRequest{
+		ClientId:   clientID,
+		CleanStart: cleanStart,
+	})
+	if err != nil {
+		log.Warn("fail to take over the session",
+			zap.String("client_id", clientID),
+			zap.String("node_name", nodeName),
+			zap.Error(err))
+		return
+	}
+	f.registry.release(clientID, nodeName)
+	if len(resp.Subscriptions) != 0 || len(resp.Messages) != 0 {
+		f.pendingSessions.add(clientID, resp)
+	}
+}
+
+// restoreSession restores the session state taken over from other nodes.
+// It is called in OnSessionCreated or OnSessionResumed hooks.
+func (f *Federation) restoreSession(clientID string, state *TakeoverResponse) {
+	for _, v := range state.Subscriptions {
+		sub := protoToSubscription(v)
+		_, err := f.localSubStore.localStore.Subscribe(clientID, sub)
+		if err != nil {
+			log.Error("fail to restore the subscription", zap.String("client_id", clientID), zap.Error(err))
+			continue
+		}
+		f.subscribed(clientID, sub)
+	}
+	if len(state.Messages) == 0 {
+		return
+	}
+	msgs := make([]*gmqtt.Message, len(state.Messages))
+	for k, v := range state.Messages {
+		msgs[k] = eventToMessage(v)
+	}
+	// The hooks are called with the server lock held,
+	// enqueue the messages in another goroutine which will wait for the session being registered.
+	go func() {
+		if err := f.queueService.Enqueue(clientID, msgs...); err != nil {
+			log.Error("fail to restore the queued messages", zap.String("client_id", clientID), zap.Error(err))
+		}
+	}()
+}
+
+// sessionClaimed is called after the session of the client is created or resumed on the local node.
+func (f *Federation) sessionClaimed(clientID string) {
+	f.registry.delete(clientID)
+	if state := f.pendingSessions.pop(clientID); state != nil {
+		f.restoreSession(clientID, state)
+	}
+	f.broadcast(&Event{
+		Event: &Event_SessionClaim{
+			SessionClaim: &SessionClaim{ClientId: clientID},
+		},
+	})
+}
+
+// sessionReleased is called after the session of the client is terminated on the local node.
+func (f *Federation) sessionReleased(clientID string) {
+	f.broadcast(&Event{
+		Event: &Event_SessionRelease{
+			SessionRelease: &SessionRelease{ClientId: clientID},
+		},
+	})
+}
+
+// broadcast sends the event to all peers.
+func (f *Federation) broadcast(event *Event) {
+	for _, v := range f.peerList() {
+		// each queue sets the event id.
+		v.queue.add(&Event{Event: event.Event})
+	}
+}
+
+// takeover calls the Takeover method of the peer.
+func (p *peer) takeover(ctx context.Context, req *TakeoverRequest) (*TakeoverResponse, error) {
+	p.stateMu.Lock()
+	client := p.client
+	p.stateMu.Unlock()
+	if client == nil {
+		return nil, errors.New("the peer is not connected")
+	}
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs("node_name", p.localName))
+	return client.Takeover(ctx, req)
+}
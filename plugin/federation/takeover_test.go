@@ -0,0 +1,279 @@
+package federation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/serf/serf"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	queuestore "github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestClientRegistry(t *testing.T) {
+	a := assert.New(t)
+	r := newClientRegistry()
+	r.claim("c1", "node1")
+	r.claim("c2", "node1")
+	r.claim("c3", "node2")
+	a.Equal("node1", r.node("c1"))
+
+	// c1 is claimed by node2, the release from node1 is outdated.
+	r.claim("c1", "node2")
+	r.release("c1", "node1")
+	a.Equal("node2", r.node("c1"))
+	r.release("c1", "node2")
+	a.Equal("", r.node("c1"))
+
+	r.removeNode("node1")
+	a.Equal("", r.node("c2"))
+	a.Equal("node2", r.node("c3"))
+	r.delete("c3")
+	a.Equal("", r.node("c3"))
+}
+
+func TestPendingSessions(t *testing.T) {
+	a := assert.New(t)
+	p := &pendingSessions{
+		sessions: make(map[string]*pendingSession),
+	}
+	state := &TakeoverResponse{Found: true}
+	p.add("c1", state)
+	a.Equal(state, p.pop("c1"))
+	a.Nil(p.pop("c1"))
+
+	p.add("c1", state)
+	p.sessions["c1"].at = time.Now().Add(-2 * pendingSessionTTL)
+	a.Nil(p.pop("c1"))
+}
+
+func TestFederation_Takeover(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.localSubStore.init(mem.NewStore())
+	cs := server.NewMockClientService(ctrl)
+	qs := server.NewMockQueueService(ctrl)
+	f.clientService = cs
+	f.queueService = qs
+
+	sub := &gmqtt.Subscription{
+		ShareName:   "share",
+		TopicFilter: "a/b",
+		ID:          1,
+		QoS:         1,
+		NoLocal:     true,
+	}
+	_, _ = f.localSubStore.localStore.Subscribe("c1", sub)
+	_, _ = f.localSubStore.localStore.Subscribe("c2", &gmqtt.Subscription{TopicFilter: "c"})
+
+	_, err := f.Takeover(context.Background(), &TakeoverRequest{ClientId: "c1"})
+	a.Error(err)
+
+	// the caller must be a member
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("node_name", "node2"))
+	_, err = f.Takeover(ctx, &TakeoverRequest{ClientId: "c1"})
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	f.peers["node2"] = &peer{}
+
+	now := time.Now()
+	cs.EXPECT().TakeoverSession("c1", true, gomock.Any()).DoAndReturn(func(clientID string, resume bool, fn func(sess *gmqtt.Session)) bool {
+		fn(&gmqtt.Session{ClientID: clientID})
+		return true
+	})
+	qs.EXPECT().Iterate("c1", gomock.Any()).DoAndReturn(func(clientID string, fn queuestore.IterateFn) error {
+		for _, v := range []*queuestore.Elem{
+			{
+				MessageWithID: &queuestore.Pubrel{PacketID: 1},
+			}, {
+				At:            now,
+				MessageWithID: &queuestore.Publish{Message: &gmqtt.Message{Topic: "a/b", QoS: 1, PacketID: 2, Payload: []byte("1")}},
+			}, {
+				At:            now,
+				Expiry:        now.Add(-time.Second),
+				MessageWithID: &queuestore.Publish{Message: &gmqtt.Message{Topic: "a/b", QoS: 1, Payload: []byte("expired")}},
+			}, {
+				At:            now,
+				Expiry:        now.Add(10 * time.Second),
+				MessageWithID: &queuestore.Publish{Message: &gmqtt.Message{Topic: "a/b", Payload: []byte("2")}},
+			},
+		} {
+			fn(v)
+		}
+		return nil
+	})
+	resp, err := f.Takeover(ctx, &TakeoverRequest{ClientId: "c1"})
+	a.NoError(err)
+	a.True(resp.Found)
+	a.Len(resp.Subscriptions, 1)
+	a.Equal(sub, protoToSubscription(resp.Subscriptions[0]))
+	a.Len(resp.Messages, 2)
+	a.Equal([]byte("1"), resp.Messages[0].Payload)
+	a.EqualValues(0, resp.Messages[0].MessageExpiry)
+	a.Equal([]byte("2"), resp.Messages[1].Payload)
+	a.EqualValues(10, resp.Messages[1].MessageExpiry)
+	a.Equal("node2", f.registry.node("c1"))
+
+	// clean start, the session is discarded.
+	cs.EXPECT().TakeoverSession("c2", false, gomock.Any()).DoAndReturn(func(clientID string, resume bool, fn func(sess *gmqtt.Session)) bool {
+		fn(&gmqtt.Session{ClientID: clientID})
+		return true
+	})
+	resp, err = f.Takeover(ctx, &TakeoverRequest{ClientId: "c2", CleanStart: true})
+	a.NoError(err)
+	a.True(resp.Found)
+	a.Empty(resp.Subscriptions)
+	a.Empty(resp.Messages)
+}
+
+func TestFederation_takeover(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.localSubStore.init(mem.NewStore())
+	qs := server.NewMockQueueService(ctrl)
+	f.queueService = qs
+	f.nodeJoin(serf.MemberEvent{
+		Members: []serf.Member{
+			{
+				Name: "node2",
+			},
+		},
+	})
+	mockQueue := NewMockqueue(ctrl)
+	client := NewMockFederationClient(ctrl)
+	f.peers["node2"].queue = mockQueue
+	f.peers["node2"].client = client
+
+	// not in the registry
+	f.takeover("c1", false)
+
+	f.eventStreamHandler(&session{nodeName: "node2", seenEvents: newLRUCache(10)}, &Event{
+		Id:    0,
+		Event: &Event_SessionClaim{SessionClaim: &SessionClaim{ClientId: "c1"}},
+	})
+	a.Equal("node2", f.registry.node("c1"))
+
+	sub := &gmqtt.Subscription{TopicFilter: "a/b", QoS: 1}
+	client.EXPECT().Takeover(gomock.Any(), &TakeoverRequest{ClientId: "c1"}).DoAndReturn(
+		func(ctx context.Context, req *TakeoverRequest, opts ...grpc.CallOption) (*TakeoverResponse, error) {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			a.Equal([]string{"node0"}, md.Get("node_name"))
+			return &TakeoverResponse{
+				Found:         true,
+				Subscriptions: []*ClientSubscription{subscriptionToProto(sub)},
+				Messages:      []*Message{{TopicName: "a/b", Payload: []byte("1"), Qos: 1}},
+			}, nil
+		})
+	f.takeover("c1", false)
+	a.Equal("", f.registry.node("c1"))
+
+	// the session state is restored after the session is created.
+	enqueued := make(chan []*gmqtt.Message, 1)
+	qs.EXPECT().Enqueue("c1", gomock.Any()).DoAndReturn(func(clientID string, msgs ...*gmqtt.Message) error {
+		enqueued <- msgs
+		return nil
+	})
+	mockQueue.EXPECT().add(&Event{
+		Event: &Event_Subscribe{Subscribe: &Subscribe{TopicFilter: "a/b"}},
+	})
+	mockQueue.EXPECT().add(&Event{
+		Event: &Event_SessionClaim{SessionClaim: &SessionClaim{ClientId: "c1"}},
+	})
+	mockClient := server.NewMockClient(ctrl)
+	mockClient.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "c1"})
+	f.OnSessionCreatedWrapper(func(ctx context.Context, client server.Client) {})(context.Background(), mockClient)
+
+	var rs []*gmqtt.Subscription
+	f.localSubStore.localStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		rs = append(rs, sub)
+		return true
+	}, subscription.IterationOptions{
+		Type:     subscription.TypeAll,
+		ClientID: "c1",
+	})
+	a.Equal([]*gmqtt.Subscription{sub}, rs)
+	select {
+	case msgs := <-enqueued:
+		a.Len(msgs, 1)
+		a.Equal([]byte("1"), msgs[0].Payload)
+	case <-time.After(time.Second):
+		a.FailNow("messages not enqueued")
+	}
+
+	// the release from the node which does not hold the session is ignored.
+	f.registry.claim("c2", "node3")
+	f.eventStreamHandler(&session{nodeName: "node2", seenEvents: newLRUCache(10)}, &Event{
+		Id:    1,
+		Event: &Event_SessionRelease{SessionRelease: &SessionRelease{ClientId: "c2"}},
+	})
+	a.Equal("node3", f.registry.node("c2"))
+
+	// disabled
+	cfg := *f.config
+	cfg.SessionTakeover.Disable = true
+	f.config = &cfg
+	f.registry.claim("c1", "node2")
+	f.takeover("c1", false)
+}
+
+func TestFederation_OnEnhancedAuthWrapper_Continue(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.nodeJoin(serf.MemberEvent{
+		Members: []serf.Member{
+			{
+				Name: "node2",
+			},
+		},
+	})
+	client := NewMockFederationClient(ctrl)
+	f.peers["node2"].client = client
+	f.registry.claim("c1", "node2")
+
+	rounds := 0
+	onEnhancedAuth := f.OnEnhancedAuthWrapper(func(ctx context.Context, client server.Client, req *server.ConnectRequest) (*server.EnhancedAuthResponse, error) {
+		return &server.EnhancedAuthResponse{
+			Continue: true,
+			OnAuth: func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+				rounds++
+				return &server.AuthResponse{Continue: rounds < 2}, nil
+			},
+		}, nil
+	})
+	resp, err := onEnhancedAuth(context.Background(), nil, &server.ConnectRequest{
+		Connect: &packets.Connect{ClientID: []byte("c1"), CleanStart: true},
+	})
+	a.NoError(err)
+	a.True(resp.Continue)
+
+	// the session is not taken over until the authentication succeeds.
+	authResp, err := resp.OnAuth(context.Background(), nil, &server.AuthRequest{})
+	a.NoError(err)
+	a.True(authResp.Continue)
+	a.Equal("node2", f.registry.node("c1"))
+
+	client.EXPECT().Takeover(gomock.Any(), &TakeoverRequest{ClientId: "c1", CleanStart: true}).Return(&TakeoverResponse{}, nil)
+	authResp, err = resp.OnAuth(context.Background(), nil, &server.AuthRequest{})
+	a.NoError(err)
+	a.False(authResp.Continue)
+	a.Equal("", f.registry.node("c1"))
+}
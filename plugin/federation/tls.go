@@ -0,0 +1,160 @@
+package federation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newTLSConfigs returns the tls configs of the federation gRPC server and client.
+func newTLSConfigs(c *TLSConfig) (server *tls.Config, client *tls.Config, err error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load tls key pair: %s", err)
+	}
+	ca, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read tls ca_file: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, nil, errors.New("no valid certificate in tls ca_file")
+	}
+	server = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPeerName(verifiedChains[0][0], c.AllowedNames)
+		},
+	}
+	client = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// The host name verification is skipped, the certificate is verified in VerifyPeerCertificate instead.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			leaf, err := verifyServerCert(rawCerts, pool)
+			if err != nil {
+				return err
+			}
+			return verifyPeerName(leaf, c.AllowedNames)
+		},
+	}
+	return server, client, nil
+}
+
+// verifyServerCert verifies the certificate chain sent by the server with the CA.
+func verifyServerCert(rawCerts [][]byte, roots *x509.CertPool) (*x509.Certificate, error) {
+	if len(rawCerts) == 0 {
+		return nil, errors.New("no certificate from the peer")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for k, v := range rawCerts {
+		cert, err := x509.ParseCertificate(v)
+		if err != nil {
+			return nil, err
+		}
+		certs[k] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, v := range certs[1:] {
+		intermediates.AddCert(v)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// verifyPeerName returns an error if neither the common name nor the DNS SANs of the certificate is in the allowed names.
+func verifyPeerName(cert *x509.Certificate, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, v := range allowed {
+		if certHasName(cert, v) {
+			return nil
+		}
+	}
+	log.Warn("reject the peer whose certificate does not match the allowed names",
+		zap.String("common_name", cert.Subject.CommonName),
+		zap.Strings("dns_names", cert.DNSNames))
+	return fmt.Errorf("the certificate of %s is not allowed", cert.Subject.CommonName)
+}
+
+// certHasName returns whether the common name or a DNS SAN of the certificate is the name.
+func certHasName(cert *x509.Certificate, name string) bool {
+	if cert.Subject.CommonName == name {
+		return true
+	}
+	for _, v := range cert.DNSNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatePeer returns the node name of the calling node, which must be a member of the federation.
+// If mTLS is enabled, the node name must also be the common name or a DNS SAN of the client certificate,
+// so that a node can not act as another one.
+func (f *Federation) authenticatePeer(ctx context.Context) (string, error) {
+	nodeName, err := getNodeNameFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	f.memberMu.Lock()
+	_, ok := f.peers[nodeName]
+	f.memberMu.Unlock()
+	if !ok {
+		log.Warn("reject the request from the node which is not a member", zap.String("node_name", nodeName))
+		return "", status.Errorf(codes.PermissionDenied, "the node [%s] is not a member", nodeName)
+	}
+	if f.serverTLS == nil {
+		return nodeName, nil
+	}
+	var cert *x509.Certificate
+	if p, ok := grpcpeer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) != 0 {
+			cert = info.State.PeerCertificates[0]
+		}
+	}
+	if cert == nil || !certHasName(cert, nodeName) {
+		log.Warn("reject the request whose certificate does not match the node name", zap.String("node_name", nodeName))
+		return "", status.Errorf(codes.PermissionDenied, "the certificate does not match the node [%s]", nodeName)
+	}
+	return nodeName, nil
+}
+
+// serverOptions returns the options of the federation gRPC server.
+func (f *Federation) serverOptions() []grpc.ServerOption {
+	if f.serverTLS == nil {
+		return nil
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(f.serverTLS))}
+}
+
+// dialOption returns the transport option to dial the federation gRPC server of the peers.
+func (f *Federation) dialOption() grpc.DialOption {
+	if f.clientTLS == nil {
+		return grpc.WithInsecure()
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(f.clientTLS))
+}
@@ -0,0 +1,210 @@
+package federation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// writeNodeTLSConfig issues a node certificate and writes the files into dir.
+func (ca *testCA) writeNodeTLSConfig(t *testing.T, dir string, name string, allowed []string) *TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name + ".cluster.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &TLSConfig{
+		Enable:       true,
+		CertFile:     filepath.Join(dir, name+".pem"),
+		KeyFile:      filepath.Join(dir, name+"-key.pem"),
+		CAFile:       filepath.Join(dir, name+"-ca.pem"),
+		AllowedNames: allowed,
+	}
+	files := map[string][]byte{
+		c.CertFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		c.KeyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		c.CAFile:   ca.pem,
+	}
+	for k, v := range files {
+		if err := ioutil.WriteFile(k, v, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+// handshake performs the tls handshake between the server node and the client node,
+// and returns the errors of both sides.
+func handshake(t *testing.T, srvCfg, cliCfg *TLSConfig) (srvErr, cliErr error) {
+	serverTLS, _, err := newTLSConfigs(srvCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, clientTLS, err := newTLSConfigs(cliCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	done := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer c.Close()
+		done <- tls.Server(c, serverTLS).Handshake()
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	cliErr = tls.Client(c, clientTLS).Handshake()
+	if cliErr != nil {
+		c.Close()
+	}
+	return <-done, cliErr
+}
+
+func TestTLS_handshake(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "federation_tls")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+
+	node1 := ca.writeNodeTLSConfig(t, dir, "node1", []string{"node1", "node2.cluster.local"})
+	node2 := ca.writeNodeTLSConfig(t, dir, "node2", []string{"node1", "node2"})
+	node3 := ca.writeNodeTLSConfig(t, dir, "node3", nil)
+
+	srvErr, cliErr := handshake(t, node1, node2)
+	a.NoError(srvErr)
+	a.NoError(cliErr)
+
+	// node3 is not allowed by node1.
+	srvErr, _ = handshake(t, node1, node3)
+	a.Error(srvErr)
+	// node2 rejects node3 as the server.
+	_, cliErr = handshake(t, node3, node2)
+	a.Error(cliErr)
+	// node3 allows any peer signed by the CA, but node1 rejects node3 as the server.
+	_, cliErr = handshake(t, node3, node1)
+	a.Error(cliErr)
+	node5 := ca.writeNodeTLSConfig(t, dir, "node5", nil)
+	srvErr, cliErr = handshake(t, node3, node5)
+	a.NoError(srvErr)
+	a.NoError(cliErr)
+
+	// the certificate signed by another CA is rejected.
+	other := newTestCA(t).writeNodeTLSConfig(t, dir, "node4", nil)
+	srvErr, _ = handshake(t, node3, other)
+	a.Error(srvErr)
+	_, cliErr = handshake(t, other, node3)
+	a.Error(cliErr)
+}
+
+func TestFederation_authenticatePeer(t *testing.T) {
+	a := assert.New(t)
+	p, _ := New(testConfig)
+	f := p.(*Federation)
+	f.peers["node1"] = &peer{}
+
+	_, err := f.authenticatePeer(context.Background())
+	a.Error(err)
+	_, err = f.authenticatePeer(mockMetaContext("node2"))
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	nodeName, err := f.authenticatePeer(mockMetaContext("node1"))
+	a.NoError(err)
+	a.Equal("node1", nodeName)
+
+	// with mTLS, the node name must match the client certificate.
+	f.serverTLS = &tls.Config{}
+	_, err = f.authenticatePeer(mockMetaContext("node1"))
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	withCert := func(ctx context.Context, cert *x509.Certificate) context.Context {
+		return grpcpeer.NewContext(ctx, &grpcpeer.Peer{
+			Addr:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+		})
+	}
+	_, err = f.authenticatePeer(withCert(mockMetaContext("node1"), &x509.Certificate{Subject: pkix.Name{CommonName: "node2"}}))
+	a.Equal(codes.PermissionDenied, status.Code(err))
+	_, err = f.authenticatePeer(withCert(mockMetaContext("node1"), &x509.Certificate{Subject: pkix.Name{CommonName: "node1"}}))
+	a.NoError(err)
+	_, err = f.authenticatePeer(withCert(mockMetaContext("node1"), &x509.Certificate{DNSNames: []string{"node1"}}))
+	a.NoError(err)
+}
@@ -1,9 +1,18 @@
 package prometheus
 
 import (
+	"time"
+
 	"github.com/DrmagicE/gmqtt/server"
 )
 
+var _ server.HookObserver = (*Prometheus)(nil)
+
 func (p *Prometheus) HookWrapper() server.HookWrapper {
 	return server.HookWrapper{}
 }
+
+// ObserveHook implements server.HookObserver.
+func (p *Prometheus) ObserveHook(hook string, d time.Duration) {
+	p.hookDuration.WithLabelValues(hook).Observe(d.Seconds())
+}
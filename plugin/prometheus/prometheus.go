@@ -35,9 +35,17 @@ func New(config config.Config) (server.Plugin, error) {
 	return &Prometheus{
 		httpServer: httpServer,
 		path:       cfg.Path,
+		hookDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricPrefix + "hook_duration_seconds",
+			Help:    "The time spent in the hooks of all plugins.",
+			Buckets: hookDurationBuckets,
+		}, []string{"hook"}),
 	}, nil
 }
 
+// hookDurationBuckets ranges from 100µs to 5s, the hooks which access the database or the network may take dozens of milliseconds.
+var hookDurationBuckets = []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 5}
+
 var log *zap.Logger
 
 const dashboardPageTemplate = `<!DOCTYPE html>
@@ -303,13 +311,14 @@ type Prometheus struct {
 	statsManager server.StatsReader
 	httpServer   *http.Server
 	path         string
+	// hookDuration is the histogram of the time spent in the hooks, labeled by the hook name.
+	hookDuration *prometheus.HistogramVec
 }
 
 func (p *Prometheus) Load(service server.Server) error {
 	log = server.LoggerWithField(zap.String("plugin", Name))
 	p.statsManager = service.StatsManager()
-	r := prometheus.DefaultRegisterer
-	r.MustRegister(p)
+	registerer.MustRegister(p, p.hookDuration)
 	mu := http.NewServeMux()
 	mu.Handle(p.path, promhttp.Handler())
 	mu.Handle("/", http.HandlerFunc(p.dashboardHandler))
@@ -0,0 +1,23 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerer is the registerer of the metrics exposed by the exporter.
+var registerer = prometheus.DefaultRegisterer
+
+// Register registers the collectors of other plugins, the metrics are exposed by the exporter along with the gmqtt metrics.
+// It can be called before or after the prometheus plugin is loaded, typically in the Load method of the plugin.
+// Registering a collector which has already been registered is a no-op, so that Register can be called on every Load.
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registerer.Register(c); err != nil {
+			if are, ok := err.(prometheus.AlreadyRegisteredError); ok && are.ExistingCollector == c {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
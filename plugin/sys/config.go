@@ -0,0 +1,136 @@
+package sys
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sections of the periodic statistics.
+const (
+	SectionBroker        = "broker"
+	SectionConnections   = "connections"
+	SectionSessions      = "sessions"
+	SectionMessages      = "messages"
+	SectionPackets       = "packets"
+	SectionSubscriptions = "subscriptions"
+)
+
+// Client lifecycle events.
+const (
+	EventConnected    = "connected"
+	EventDisconnected = "disconnected"
+	EventSubscribed   = "subscribed"
+	EventUnsubscribed = "unsubscribed"
+)
+
+// Config is the configuration for the sys plugin.
+type Config struct {
+	// NodeName is used in the topic prefix: $SYS/brokers/{node_name}/. Defaults to hostname.
+	NodeName string `yaml:"node_name"`
+	// Interval is the default publishing interval of the statistics.
+	Interval time.Duration `yaml:"interval"`
+	// Sections is the enabled statistic sections and their publishing intervals.
+	// Zero interval means using the default Interval.
+	Sections map[string]time.Duration `yaml:"sections"`
+	// Events is the enabled client lifecycle events.
+	Events []string `yaml:"events"`
+	// PrivilegedClientIDs is the client id list which is allowed to subscribe $SYS topics.
+	PrivilegedClientIDs []string `yaml:"privileged_client_ids"`
+	// PrivilegedUsernames is the username list which is allowed to subscribe $SYS topics.
+	PrivilegedUsernames []string `yaml:"privileged_usernames"`
+}
+
+var allSections = []string{SectionBroker, SectionConnections, SectionSessions, SectionMessages, SectionPackets, SectionSubscriptions}
+
+var allEvents = []string{EventConnected, EventDisconnected, EventSubscribed, EventUnsubscribed}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if c.NodeName == "" {
+		hostName, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		c.NodeName = hostName
+	}
+	if strings.ContainsAny(c.NodeName, "/+#") {
+		return fmt.Errorf("invalid node_name: %s", c.NodeName)
+	}
+	if c.Interval <= 0 {
+		return errors.New("invalid interval")
+	}
+	for k, v := range c.Sections {
+		if !contains(allSections, k) {
+			return fmt.Errorf("invalid section: %s", k)
+		}
+		if v < 0 {
+			return fmt.Errorf("invalid interval of section: %s", k)
+		}
+	}
+	for _, v := range c.Events {
+		if !contains(allEvents, v) {
+			return fmt.Errorf("invalid event: %s", v)
+		}
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	Interval: 10 * time.Second,
+	Sections: map[string]time.Duration{
+		SectionBroker:        0,
+		SectionConnections:   0,
+		SectionSessions:      0,
+		SectionMessages:      0,
+		SectionPackets:       0,
+		SectionSubscriptions: 0,
+	},
+	Events: allEvents,
+}
+
+func init() {
+	hostName, err := os.Hostname()
+	if err != nil {
+		panic(err)
+	}
+	DefaultConfig.NodeName = hostName
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	df := cfg(DefaultConfig)
+	// yaml merges the mapping into the existing map, reset it to make the sections replaceable.
+	df.Sections = nil
+	var v = &struct {
+		Sys *cfg `yaml:"sys"`
+	}{
+		Sys: &df,
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	if v.Sys == nil {
+		v.Sys = &df
+	}
+	if v.Sys.Sections == nil {
+		v.Sys.Sections = make(map[string]time.Duration)
+		for k, d := range DefaultConfig.Sections {
+			v.Sys.Sections[k] = d
+		}
+	}
+	*c = Config(*v.Sys)
+	return nil
+}
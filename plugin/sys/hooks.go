@@ -0,0 +1,187 @@
+package sys
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// sysTopicPrefix is the prefix of the topics which are only allowed to be subscribed by the privileged clients.
+const sysTopicPrefix = "$SYS"
+
+func (s *Sys) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnConnectedWrapper:    s.OnConnectedWrapper,
+		OnClosedWrapper:       s.OnClosedWrapper,
+		OnSubscribeWrapper:    s.OnSubscribeWrapper,
+		OnSubscribedWrapper:   s.OnSubscribedWrapper,
+		OnUnsubscribedWrapper: s.OnUnsubscribedWrapper,
+		OnMsgArrivedWrapper:   s.OnMsgArrivedWrapper,
+	}
+}
+
+// clientEvent is the JSON payload of the client lifecycle events.
+type clientEvent struct {
+	ClientID    string `json:"client_id"`
+	Username    string `json:"username"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	ProtoVer    byte   `json:"proto_ver,omitempty"`
+	KeepAlive   uint16 `json:"keepalive,omitempty"`
+	ConnectedAt int64  `json:"connected_at,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Topic       string `json:"topic,omitempty"`
+	QoS         *byte  `json:"qos,omitempty"`
+	Timestamp   int64  `json:"ts"`
+}
+
+// topicLevelReplacer replaces the characters that are not allowed in a topic level.
+var topicLevelReplacer = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+func (s *Sys) isPrivileged(client server.Client) bool {
+	opts := client.ClientOptions()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.privileged[opts.ClientID]; ok {
+		return true
+	}
+	_, ok := s.privUser[opts.Username]
+	return ok
+}
+
+func (s *Sys) newEvent(client server.Client) *clientEvent {
+	opts := client.ClientOptions()
+	return &clientEvent{
+		ClientID:  opts.ClientID,
+		Username:  opts.Username,
+		Timestamp: s.now().UnixNano() / 1e6,
+	}
+}
+
+// emit publishes the event to $SYS/brokers/{node_name}/clients/{client_id}/{event}.
+func (s *Sys) emit(event string, e *clientEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Error("fail to marshal event", zap.String("event", event), zap.Error(err))
+		return
+	}
+	s.mu.RLock()
+	prefix := s.prefix
+	s.mu.RUnlock()
+	msg := &gmqtt.Message{
+		Topic:   prefix + "clients/" + topicLevelReplacer.Replace(e.ClientID) + "/" + event,
+		Payload: b,
+	}
+	select {
+	case s.eventCh <- msg:
+	default:
+		log.Warn("event queue is full, dropping event", zap.String("event", event), zap.String("client_id", e.ClientID))
+	}
+}
+
+func (s *Sys) eventEnabled(event string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.events[event]
+	return ok
+}
+
+func (s *Sys) OnConnectedWrapper(pre server.OnConnected) server.OnConnected {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		if !s.eventEnabled(EventConnected) {
+			return
+		}
+		e := s.newEvent(client)
+		if conn := client.Connection(); conn != nil {
+			e.IPAddress = conn.RemoteAddr().String()
+		}
+		e.ProtoVer = byte(client.Version())
+		e.KeepAlive = client.ClientOptions().KeepAlive
+		e.ConnectedAt = client.ConnectedAt().UnixNano() / 1e6
+		s.emit(EventConnected, e)
+	}
+}
+
+func (s *Sys) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		if !s.eventEnabled(EventDisconnected) {
+			return
+		}
+		e := s.newEvent(client)
+		e.Reason = "normal"
+		if err != nil {
+			e.Reason = err.Error()
+		}
+		s.emit(EventDisconnected, e)
+	}
+}
+
+func (s *Sys) OnSubscribeWrapper(pre server.OnSubscribe) server.OnSubscribe {
+	return func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		if s.isPrivileged(client) {
+			return nil
+		}
+		for k, v := range req.Subscriptions {
+			if strings.HasPrefix(v.Sub.TopicFilter, sysTopicPrefix) {
+				req.Reject(k, &codes.Error{
+					Code: codes.NotAuthorized,
+				})
+			}
+		}
+		return nil
+	}
+}
+
+func (s *Sys) OnSubscribedWrapper(pre server.OnSubscribed) server.OnSubscribed {
+	return func(ctx context.Context, client server.Client, subscription *gmqtt.Subscription) {
+		pre(ctx, client, subscription)
+		if subscription == nil || !s.eventEnabled(EventSubscribed) {
+			return
+		}
+		e := s.newEvent(client)
+		e.Topic = subscription.GetFullTopicName()
+		qos := subscription.QoS
+		e.QoS = &qos
+		s.emit(EventSubscribed, e)
+	}
+}
+
+func (s *Sys) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnUnsubscribed {
+	return func(ctx context.Context, client server.Client, topicName string) {
+		pre(ctx, client, topicName)
+		if !s.eventEnabled(EventUnsubscribed) {
+			return
+		}
+		e := s.newEvent(client)
+		e.Topic = topicName
+		s.emit(EventUnsubscribed, e)
+	}
+}
+
+// OnMsgArrivedWrapper rejects the messages published to the $SYS topics by clients,
+// the $SYS topic tree is maintained by the broker only.
+func (s *Sys) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		if req.Message != nil && strings.HasPrefix(req.Message.Topic, sysTopicPrefix+"/") {
+			return &codes.Error{
+				Code: codes.NotAuthorized,
+			}
+		}
+		return nil
+	}
+}
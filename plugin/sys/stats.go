@@ -0,0 +1,109 @@
+package sys
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// collector converts the GlobalStats of a section into topic => payload pairs.
+// The topics are relative to $SYS/brokers/{node_name}/.
+type collector struct {
+	section string
+	// prev and prevTime are the last collected stats, used to calculate the rates.
+	prev     *server.GlobalStats
+	prevTime time.Time
+}
+
+func newCollector(section string) *collector {
+	return &collector{section: section}
+}
+
+func uintString(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+// rate returns the per second rate of the counter.
+// It returns 0 for the first collection.
+func rate(cur, prev uint64, elapsed time.Duration) string {
+	if elapsed <= 0 || cur < prev {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(cur-prev)/elapsed.Seconds(), 'f', 2, 64)
+}
+
+func receivedTotal(m server.MessageStats) uint64 {
+	return m.Qos0.ReceivedTotal + m.Qos1.ReceivedTotal + m.Qos2.ReceivedTotal
+}
+
+func sentTotal(m server.MessageStats) uint64 {
+	return m.Qos0.SentTotal + m.Qos1.SentTotal + m.Qos2.SentTotal
+}
+
+func (c *collector) collect(st server.GlobalStats, now time.Time, startedAt time.Time) map[string]string {
+	prev := c.prev
+	var elapsed time.Duration
+	if prev == nil {
+		prev = &st
+	} else {
+		elapsed = now.Sub(c.prevTime)
+	}
+	c.prev = &st
+	c.prevTime = now
+
+	rs := make(map[string]string)
+	switch c.section {
+	case SectionBroker:
+		rs["uptime"] = strconv.FormatInt(int64(now.Sub(startedAt).Seconds()), 10)
+		rs["datetime"] = now.Format(time.RFC3339)
+	case SectionConnections:
+		cs := st.ConnectionStats
+		rs["stats/connections/count"] = uintString(cs.ActiveCurrent)
+		rs["stats/connections/connected_total"] = uintString(cs.ConnectedTotal)
+		rs["stats/connections/disconnected_total"] = uintString(cs.DisconnectedTotal)
+	case SectionSessions:
+		cs := st.ConnectionStats
+		rs["stats/sessions/active"] = uintString(cs.ActiveCurrent)
+		rs["stats/sessions/inactive"] = uintString(cs.InactiveCurrent)
+		rs["stats/sessions/created_total"] = uintString(cs.SessionCreatedTotal)
+		rs["stats/sessions/terminated/taken_over"] = uintString(cs.SessionTerminated.TakenOver)
+		rs["stats/sessions/terminated/expired"] = uintString(cs.SessionTerminated.Expired)
+		rs["stats/sessions/terminated/normal"] = uintString(cs.SessionTerminated.Normal)
+	case SectionMessages:
+		ms := st.MessageStats
+		received, sent := receivedTotal(ms), sentTotal(ms)
+		rs["stats/messages/received_total"] = uintString(received)
+		rs["stats/messages/sent_total"] = uintString(sent)
+		rs["stats/messages/received_rate"] = rate(received, receivedTotal(prev.MessageStats), elapsed)
+		rs["stats/messages/sent_rate"] = rate(sent, sentTotal(prev.MessageStats), elapsed)
+		rs["stats/messages/queued"] = uintString(ms.QueuedCurrent)
+		rs["stats/messages/inflight"] = uintString(ms.InflightCurrent)
+		rs["stats/messages/dropped_total"] = uintString(ms.GetDroppedTotal())
+		var dropped server.DroppedTotal
+		for _, v := range []server.MessageQosStats{ms.Qos0, ms.Qos1, ms.Qos2} {
+			dropped.Internal += v.DroppedTotal.Internal
+			dropped.ExceedsMaxPacketSize += v.DroppedTotal.ExceedsMaxPacketSize
+			dropped.QueueFull += v.DroppedTotal.QueueFull
+			dropped.Expired += v.DroppedTotal.Expired
+			dropped.InflightExpired += v.DroppedTotal.InflightExpired
+		}
+		rs["stats/messages/dropped/internal"] = uintString(dropped.Internal)
+		rs["stats/messages/dropped/exceeds_max_packet_size"] = uintString(dropped.ExceedsMaxPacketSize)
+		rs["stats/messages/dropped/queue_full"] = uintString(dropped.QueueFull)
+		rs["stats/messages/dropped/expired"] = uintString(dropped.Expired)
+		rs["stats/messages/dropped/inflight_expired"] = uintString(dropped.InflightExpired)
+	case SectionPackets:
+		ps, pp := st.PacketStats, prev.PacketStats
+		rs["stats/packets/received_total"] = uintString(ps.ReceivedTotal.Total)
+		rs["stats/packets/sent_total"] = uintString(ps.SentTotal.Total)
+		rs["stats/packets/bytes_received"] = uintString(ps.BytesReceived.Total)
+		rs["stats/packets/bytes_sent"] = uintString(ps.BytesSent.Total)
+		rs["stats/packets/bytes_received_rate"] = rate(ps.BytesReceived.Total, pp.BytesReceived.Total, elapsed)
+		rs["stats/packets/bytes_sent_rate"] = rate(ps.BytesSent.Total, pp.BytesSent.Total, elapsed)
+	case SectionSubscriptions:
+		rs["stats/subscriptions/count"] = uintString(st.SubscriptionStats.SubscriptionsCurrent)
+		rs["stats/subscriptions/total"] = uintString(st.SubscriptionStats.SubscriptionsTotal)
+	}
+	return rs
+}
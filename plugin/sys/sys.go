@@ -0,0 +1,201 @@
+package sys
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var (
+	_ server.Plugin     = (*Sys)(nil)
+	_ server.Reloadable = (*Sys)(nil)
+)
+
+const Name = "sys"
+
+// eventQueueSize is the buffer size of the pending event messages.
+// Events are dropped if the buffer is full.
+const eventQueueSize = 1024
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	s := &Sys{
+		eventCh: make(chan *gmqtt.Message, eventQueueSize),
+		exit:    make(chan struct{}),
+		wg:      &sync.WaitGroup{},
+		statsWg: &sync.WaitGroup{},
+		now:     time.Now,
+	}
+	s.apply(cfg)
+	return s, nil
+}
+
+var log *zap.Logger
+
+// Sys publishes the broker statistics and client lifecycle events under the $SYS/brokers/{node_name}/ topic tree.
+type Sys struct {
+	// mu guards the fields which are replaced on reload: config, prefix, events, privileged, privUser and statsExit.
+	mu     sync.RWMutex
+	config *Config
+	// prefix is the topic prefix: $SYS/brokers/{node_name}/
+	prefix string
+	events map[string]struct{}
+	// privileged is the set of client ids which are allowed to subscribe $SYS topics.
+	privileged map[string]struct{}
+	// privUser is the set of usernames which are allowed to subscribe $SYS topics.
+	privUser  map[string]struct{}
+	publisher server.Publisher
+	retained  server.RetainedService
+	stats     server.StatsReader
+	// eventCh buffers the event messages.
+	// Events are published asynchronously because the hooks may be called with the server lock held.
+	eventCh   chan *gmqtt.Message
+	startedAt time.Time
+	now       func() time.Time
+	exit      chan struct{}
+	wg        *sync.WaitGroup
+	// statsExit stops the statistics goroutines, which are restarted on reload.
+	statsExit chan struct{}
+	statsWg   *sync.WaitGroup
+}
+
+// apply applies the configuration, the caller must hold the lock or be the only user.
+func (s *Sys) apply(cfg *Config) {
+	s.config = cfg
+	s.prefix = "$SYS/brokers/" + cfg.NodeName + "/"
+	s.events = make(map[string]struct{})
+	s.privileged = make(map[string]struct{})
+	s.privUser = make(map[string]struct{})
+	for _, v := range cfg.Events {
+		s.events[v] = struct{}{}
+	}
+	for _, v := range cfg.PrivilegedClientIDs {
+		s.privileged[v] = struct{}{}
+	}
+	for _, v := range cfg.PrivilegedUsernames {
+		s.privUser[v] = struct{}{}
+	}
+}
+
+// startStats starts a publishing goroutine for each enabled section, the caller must hold the lock.
+func (s *Sys) startStats() {
+	s.statsExit = make(chan struct{})
+	for section, interval := range s.config.Sections {
+		if interval == 0 {
+			interval = s.config.Interval
+		}
+		s.statsWg.Add(1)
+		go s.publishStats(section, interval, s.prefix, s.statsExit)
+	}
+}
+
+// stopStats stops the publishing goroutines, the caller must hold the lock.
+func (s *Sys) stopStats() {
+	if s.statsExit == nil {
+		return
+	}
+	close(s.statsExit)
+	s.statsWg.Wait()
+	s.statsExit = nil
+}
+
+func (s *Sys) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	if err := checkPluginOrder(service.Plugins()); err != nil {
+		return err
+	}
+	s.publisher = service.Publisher()
+	s.retained = service.RetainedService()
+	s.stats = service.StatsManager()
+	s.startedAt = s.now()
+
+	s.wg.Add(1)
+	go s.publishEvents()
+	s.mu.Lock()
+	s.startStats()
+	s.mu.Unlock()
+	return nil
+}
+
+// checkPluginOrder checks that sys is placed before thingspanel,
+// since thingspanel does not pass the client hooks which enforce the $SYS access control to the plugins after it.
+func checkPluginOrder(plugins []server.Plugin) error {
+	return server.CheckPluginBefore(plugins, Name, "thingspanel")
+}
+
+// Reload applies the new sys configuration: the node name, sections, intervals, events and privileged clients.
+// The statistics goroutines are restarted with the new sections and intervals.
+func (s *Sys) Reload(config config.Config) error {
+	cfg := config.Plugins[Name].(*Config)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	loaded := s.statsExit != nil
+	s.stopStats()
+	s.apply(cfg)
+	if loaded {
+		s.startStats()
+	}
+	return nil
+}
+
+func (s *Sys) Unload() error {
+	close(s.exit)
+	s.mu.Lock()
+	s.stopStats()
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Sys) Name() string {
+	return Name
+}
+
+func (s *Sys) publishEvents() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.exit:
+			return
+		case msg := <-s.eventCh:
+			s.publisher.Publish(msg)
+		}
+	}
+}
+
+func (s *Sys) publishStats(section string, interval time.Duration, prefix string, exit chan struct{}) {
+	defer s.statsWg.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	c := newCollector(section)
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			now := s.now()
+			for topic, value := range c.collect(s.stats.GetGlobalStats(), now, s.startedAt) {
+				msg := &gmqtt.Message{
+					Topic:   prefix + topic,
+					Payload: []byte(value),
+				}
+				// The retained store keeps the last values so that new subscribers can get them immediately,
+				// the current subscribers receive the values as normal messages.
+				retained := msg.Copy()
+				retained.Retained = true
+				s.retained.AddOrReplace(retained)
+				s.publisher.Publish(msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package sys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	log = zap.NewNop()
+}
+
+func newTestSys(t *testing.T, cfg Config) *Sys {
+	p, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &cfg,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.(*Sys)
+	s.now = func() time.Time {
+		return time.Unix(100, 0)
+	}
+	return s
+}
+
+func TestConfig_UnmarshalYAML(t *testing.T) {
+	a := assert.New(t)
+	var c Config
+	a.Nil(yaml.Unmarshal([]byte(`
+sys:
+  node_name: node1
+  interval: 5s
+  sections:
+    messages: 1s
+    broker: 0
+  events:
+    - connected
+  privileged_client_ids:
+    - admin
+`), &c))
+	a.Nil(c.Validate())
+	a.Equal("node1", c.NodeName)
+	a.Equal(5*time.Second, c.Interval)
+	a.Equal(map[string]time.Duration{
+		SectionMessages: time.Second,
+		SectionBroker:   0,
+	}, c.Sections)
+	a.Equal([]string{EventConnected}, c.Events)
+	a.Equal([]string{"admin"}, c.PrivilegedClientIDs)
+	// default sections are not modified
+	a.Len(DefaultConfig.Sections, len(allSections))
+
+	c = Config{}
+	a.Nil(yaml.Unmarshal([]byte(`sys:`), &c))
+	a.Equal(DefaultConfig.Sections, c.Sections)
+	a.Equal(DefaultConfig.Interval, c.Interval)
+
+	c.Sections = map[string]time.Duration{"unknown": 0}
+	a.NotNil(c.Validate())
+	c.Sections = nil
+	c.Events = []string{"unknown"}
+	a.NotNil(c.Validate())
+}
+
+func TestSys_OnSubscribeWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := DefaultConfig
+	cfg.PrivilegedClientIDs = []string{"admin"}
+	cfg.PrivilegedUsernames = []string{"root"}
+	s := newTestSys(t, cfg)
+	onSubscribe := s.OnSubscribeWrapper(func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		return nil
+	})
+
+	newReq := func() *server.SubscribeRequest {
+		req := &server.SubscribeRequest{
+			Subscriptions: make(map[string]*struct {
+				Sub   *gmqtt.Subscription
+				Error error
+			}),
+		}
+		for _, v := range []string{"$SYS/brokers/#", "$share/g/$SYS/brokers/#", "a/b"} {
+			shareName, filter := "", v
+			if v == "$share/g/$SYS/brokers/#" {
+				shareName, filter = "g", "$SYS/brokers/#"
+			}
+			req.Subscriptions[v] = &struct {
+				Sub   *gmqtt.Subscription
+				Error error
+			}{Sub: &gmqtt.Subscription{ShareName: shareName, TopicFilter: filter}}
+		}
+		return req
+	}
+	for _, v := range []*server.ClientOptions{
+		{ClientID: "admin"},
+		{ClientID: "c1", Username: "root"},
+	} {
+		client := server.NewMockClient(ctrl)
+		client.EXPECT().ClientOptions().Return(v).AnyTimes()
+		req := newReq()
+		a.Nil(onSubscribe(context.Background(), client, req))
+		for _, sub := range req.Subscriptions {
+			a.Nil(sub.Error)
+		}
+	}
+
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "c1", Username: "user"}).AnyTimes()
+	req := newReq()
+	a.Nil(onSubscribe(context.Background(), client, req))
+	a.Equal(&codes.Error{Code: codes.NotAuthorized}, req.Subscriptions["$SYS/brokers/#"].Error)
+	a.Equal(&codes.Error{Code: codes.NotAuthorized}, req.Subscriptions["$share/g/$SYS/brokers/#"].Error)
+	a.Nil(req.Subscriptions["a/b"].Error)
+}
+
+func TestSys_OnMsgArrivedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	s := newTestSys(t, DefaultConfig)
+	onMsgArrived := s.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		return nil
+	})
+	client := server.NewMockClient(ctrl)
+	a.NotNil(onMsgArrived(context.Background(), client, &server.MsgArrivedRequest{
+		Message: &gmqtt.Message{Topic: "$SYS/brokers/node/uptime"},
+	}))
+	a.Nil(onMsgArrived(context.Background(), client, &server.MsgArrivedRequest{
+		Message: &gmqtt.Message{Topic: "a/b"},
+	}))
+}
+
+func TestSys_Events(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := DefaultConfig
+	cfg.NodeName = "node1"
+	cfg.Events = []string{EventConnected, EventDisconnected, EventSubscribed}
+	s := newTestSys(t, cfg)
+
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID:  "dev/1",
+		Username:  "user",
+		KeepAlive: 60,
+	}).AnyTimes()
+	client.EXPECT().Connection().Return(nil).AnyTimes()
+	client.EXPECT().Version().Return(packets.Version5).AnyTimes()
+	client.EXPECT().ConnectedAt().Return(time.Unix(99, 0)).AnyTimes()
+
+	s.OnConnectedWrapper(func(ctx context.Context, client server.Client) {})(context.Background(), client)
+	s.OnSubscribedWrapper(func(ctx context.Context, client server.Client, subscription *gmqtt.Subscription) {})(context.Background(), client, &gmqtt.Subscription{
+		TopicFilter: "a/b",
+		QoS:         1,
+	})
+	s.OnClosedWrapper(func(ctx context.Context, client server.Client, err error) {})(context.Background(), client, errors.New("eof"))
+	// disabled event
+	s.OnUnsubscribedWrapper(func(ctx context.Context, client server.Client, topicName string) {})(context.Background(), client, "a/b")
+
+	a.Len(s.eventCh, 3)
+	expected := []struct {
+		topic string
+		event map[string]interface{}
+	}{
+		{
+			topic: "$SYS/brokers/node1/clients/dev_1/connected",
+			event: map[string]interface{}{
+				"client_id":    "dev/1",
+				"username":     "user",
+				"proto_ver":    float64(packets.Version5),
+				"keepalive":    float64(60),
+				"connected_at": float64(99000),
+				"ts":           float64(100000),
+			},
+		},
+		{
+			topic: "$SYS/brokers/node1/clients/dev_1/subscribed",
+			event: map[string]interface{}{
+				"client_id": "dev/1",
+				"username":  "user",
+				"topic":     "a/b",
+				"qos":       float64(1),
+				"ts":        float64(100000),
+			},
+		},
+		{
+			topic: "$SYS/brokers/node1/clients/dev_1/disconnected",
+			event: map[string]interface{}{
+				"client_id": "dev/1",
+				"username":  "user",
+				"reason":    "eof",
+				"ts":        float64(100000),
+			},
+		},
+	}
+	for _, v := range expected {
+		msg := <-s.eventCh
+		a.Equal(v.topic, msg.Topic)
+		var event map[string]interface{}
+		a.Nil(json.Unmarshal(msg.Payload, &event))
+		a.Equal(v.event, event)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	a := assert.New(t)
+	startedAt := time.Unix(0, 0)
+	c := newCollector(SectionMessages)
+	st := server.GlobalStats{}
+	st.MessageStats.Qos1.ReceivedTotal = 10
+	st.MessageStats.Qos2.DroppedTotal.QueueFull = 2
+	rs := c.collect(st, time.Unix(10, 0), startedAt)
+	a.Equal("10", rs["stats/messages/received_total"])
+	a.Equal("0", rs["stats/messages/received_rate"])
+	a.Equal("2", rs["stats/messages/dropped_total"])
+	a.Equal("2", rs["stats/messages/dropped/queue_full"])
+
+	st.MessageStats.Qos0.ReceivedTotal = 20
+	rs = c.collect(st, time.Unix(20, 0), startedAt)
+	a.Equal("30", rs["stats/messages/received_total"])
+	a.Equal("2.00", rs["stats/messages/received_rate"])
+
+	c = newCollector(SectionBroker)
+	rs = c.collect(st, time.Unix(20, 0), startedAt)
+	a.Equal("20", rs["uptime"])
+}
+
+func TestSys_Load(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := DefaultConfig
+	cfg.NodeName = "node1"
+	cfg.Interval = 10 * time.Millisecond
+	cfg.Sections = map[string]time.Duration{
+		SectionSubscriptions: 0,
+	}
+	s := newTestSys(t, cfg)
+
+	srv := server.NewMockServer(ctrl)
+	srv.EXPECT().Plugins().Return(nil)
+	pub := server.NewMockPublisher(ctrl)
+	rt := server.NewMockRetainedService(ctrl)
+	stats := server.NewMockStatsReader(ctrl)
+	srv.EXPECT().Publisher().Return(pub)
+	srv.EXPECT().RetainedService().Return(rt)
+	srv.EXPECT().StatsManager().Return(stats)
+
+	st := server.GlobalStats{}
+	st.SubscriptionStats.SubscriptionsCurrent = 3
+	stats.EXPECT().GetGlobalStats().Return(st).MinTimes(1)
+	published := make(chan *gmqtt.Message, 10)
+	rt.EXPECT().AddOrReplace(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		a.True(msg.Retained)
+	}).MinTimes(1)
+	pub.EXPECT().Publish(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		select {
+		case published <- msg:
+		default:
+		}
+	}).MinTimes(1)
+
+	a.Nil(s.Load(srv))
+	topics := make(map[string]string)
+	for len(topics) < 2 {
+		msg := <-published
+		topics[msg.Topic] = string(msg.Payload)
+		a.False(msg.Retained)
+	}
+	a.Nil(s.Unload())
+	a.Equal(map[string]string{
+		"$SYS/brokers/node1/stats/subscriptions/count": "3",
+		"$SYS/brokers/node1/stats/subscriptions/total": "0",
+	}, topics)
+}
+
+func TestSys_LoadPluginOrder(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var plugins []server.Plugin
+	for _, v := range []string{"thingspanel", Name} {
+		p := server.NewMockPlugin(ctrl)
+		p.EXPECT().Name().Return(v).AnyTimes()
+		plugins = append(plugins, p)
+	}
+	srv := server.NewMockServer(ctrl)
+	srv.EXPECT().Plugins().Return(plugins)
+	a.Error(newTestSys(t, DefaultConfig).Load(srv))
+}
+
+func TestSys_Reload(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := DefaultConfig
+	cfg.NodeName = "node1"
+	cfg.Interval = time.Hour
+	cfg.Sections = map[string]time.Duration{
+		SectionSubscriptions: 0,
+	}
+	s := newTestSys(t, cfg)
+
+	srv := server.NewMockServer(ctrl)
+	srv.EXPECT().Plugins().Return(nil)
+	pub := server.NewMockPublisher(ctrl)
+	rt := server.NewMockRetainedService(ctrl)
+	stats := server.NewMockStatsReader(ctrl)
+	srv.EXPECT().Publisher().Return(pub)
+	srv.EXPECT().RetainedService().Return(rt)
+	srv.EXPECT().StatsManager().Return(stats)
+	stats.EXPECT().GetGlobalStats().Return(server.GlobalStats{}).MinTimes(1)
+	rt.EXPECT().AddOrReplace(gomock.Any()).MinTimes(1)
+	published := make(chan *gmqtt.Message, 10)
+	pub.EXPECT().Publish(gomock.Any()).Do(func(msg *gmqtt.Message) {
+		select {
+		case published <- msg:
+		default:
+		}
+	}).MinTimes(1)
+	a.Nil(s.Load(srv))
+
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "admin"}).AnyTimes()
+	a.False(s.isPrivileged(client))
+
+	newCfg := cfg
+	newCfg.NodeName = "node2"
+	newCfg.Interval = 10 * time.Millisecond
+	newCfg.Events = nil
+	newCfg.PrivilegedClientIDs = []string{"admin"}
+	a.Nil(s.Reload(config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &newCfg,
+		},
+	}))
+	a.True(s.isPrivileged(client))
+	a.False(s.eventEnabled(EventConnected))
+
+	msg := <-published
+	a.Contains(msg.Topic, "$SYS/brokers/node2/stats/subscriptions/")
+	a.Nil(s.Unload())
+}
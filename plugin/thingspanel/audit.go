@@ -0,0 +1,72 @@
+package thingspanel
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// Operations of the privileged users (root and plugin), which bypass the device authentication and authorization.
+const (
+	auditPrivilegedConnect = "thingspanel.privileged.connect"
+	auditPrivilegedPublish = "thingspanel.privileged.publish"
+)
+
+// auditedTopicPrefixes are the prefixes of the downlink control topics besides the command topics.
+// The privileged users also forward the telemetry, which is data-plane traffic and not audited.
+var auditedTopicPrefixes = []string{
+	"devices/telemetry/control/",
+	"devices/attributes/set/",
+	"ota/devices/inform/",
+	"gateway/telemetry/control/",
+	"gateway/attributes/set/",
+	"gateway/command/",
+}
+
+// auditedTopic returns whether the message published to the topic controls the devices.
+func auditedTopic(topic string) bool {
+	if _, _, ok := parseCommandTopic(topic); ok {
+		return true
+	}
+	for _, v := range auditedTopicPrefixes {
+		if strings.HasPrefix(topic, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditPrivileged records the operation of the privileged user.
+func (t *Thingspanel) auditPrivileged(client server.Client, username, operation, target string, details map[string]string, err error) {
+	if t.auditLogger == nil {
+		return
+	}
+	r := &server.AuditRecord{
+		Actor:     username,
+		Operation: operation,
+		Target:    target,
+		Details:   details,
+	}
+	if conn := client.Connection(); conn != nil {
+		r.SourceIP = server.AuditSourceIP(conn.RemoteAddr())
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	t.auditLogger.Audit(r)
+}
+
+// auditPrivilegedPublish records the message published by the privileged user if it controls the devices.
+func (t *Thingspanel) auditPrivilegedPublish(client server.Client, msg *gmqtt.Message) {
+	if t.auditLogger == nil || !auditedTopic(msg.Topic) {
+		return
+	}
+	t.auditPrivileged(client, client.ClientOptions().Username, auditPrivilegedPublish, msg.Topic, map[string]string{
+		"client_id":    client.ClientOptions().ClientID,
+		"qos":          strconv.Itoa(int(msg.QoS)),
+		"retained":     strconv.FormatBool(msg.Retained),
+		"payload_size": strconv.Itoa(len(msg.Payload)),
+	}, nil)
+}
@@ -0,0 +1,60 @@
+package thingspanel
+
+import (
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type testAuditLogger struct {
+	records []*server.AuditRecord
+}
+
+func (l *testAuditLogger) Audit(record *server.AuditRecord) {
+	l.records = append(l.records, record)
+}
+
+func (l *testAuditLogger) Query(query server.AuditQuery) ([]*server.AuditRecord, int, error) {
+	return nil, 0, server.ErrAuditQueryNotSupported
+}
+
+type testConn struct {
+	net.Conn
+}
+
+func (c testConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("10.0.0.8"), Port: 50000}
+}
+
+func TestThingspanel_auditPrivilegedPublish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().Connection().Return(testConn{}).AnyTimes()
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "platform", Username: "root"}).AnyTimes()
+
+	// no-op without audit logger
+	tp := &Thingspanel{}
+	tp.auditPrivilegedPublish(client, &gmqtt.Message{Topic: "devices/telemetry/control/d1"})
+
+	l := &testAuditLogger{}
+	tp.auditLogger = l
+	// the forwarded telemetry is not audited
+	tp.auditPrivilegedPublish(client, &gmqtt.Message{Topic: "devices/telemetry", Payload: []byte("{}")})
+	tp.auditPrivilegedPublish(client, &gmqtt.Message{Topic: "devices/command/state", Payload: []byte("{}")})
+	tp.auditPrivilegedPublish(client, &gmqtt.Message{Topic: "devices/telemetry/control/d1", QoS: 1, Payload: []byte("{}")})
+	if len(l.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(l.records))
+	}
+	r := l.records[0]
+	if r.Actor != "root" || r.Operation != auditPrivilegedPublish || r.Target != "devices/telemetry/control/d1" || r.SourceIP != "10.0.0.8" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+	if r.Details["client_id"] != "platform" || r.Details["qos"] != "1" || r.Details["payload_size"] != "2" {
+		t.Fatalf("unexpected details: %v", r.Details)
+	}
+}
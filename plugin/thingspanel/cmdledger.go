@@ -0,0 +1,560 @@
+package thingspanel
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gopkg.in/redis.v5"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+)
+
+// CommandState is the lifecycle state of a downlink command.
+type CommandState string
+
+const (
+	// CommandPending means the command has been accepted but not delivered to the device yet.
+	CommandPending CommandState = "pending"
+	// CommandDelivered means the command has been delivered to the device.
+	CommandDelivered CommandState = "delivered"
+	// CommandAcknowledged means the device has responded to the command.
+	CommandAcknowledged CommandState = "acknowledged"
+	// CommandExpired means the command was not acknowledged before its deadline.
+	CommandExpired CommandState = "expired"
+	// CommandFailed means the command was dropped by the broker or the device responded with an error.
+	CommandFailed CommandState = "failed"
+)
+
+// final returns whether the state is a terminal state.
+func (s CommandState) final() bool {
+	return s == CommandAcknowledged || s == CommandExpired || s == CommandFailed
+}
+
+const (
+	cmdLedgerKeyPrefix     = "tp:cmd:ledger:"
+	cmdLedgerPendingPrefix = "tp:cmd:pending:"
+	cmdLedgerDeadlineKey   = "tp:cmd:deadline"
+
+	// commandTopicPrefix is the prefix of the command topic: devices/command/{device_number}/{message_id}
+	commandTopicPrefix = "devices/command/"
+	// commandResponseTopicPrefix is the prefix of the response topic: devices/command/response/{message_id}
+	commandResponseTopicPrefix = "devices/command/response/"
+
+	// cmdLedgerUpdateQueueSize is the capacity of the asynchronous state update queue.
+	cmdLedgerUpdateQueueSize = 10000
+)
+
+var cmdLedgerNow = time.Now
+
+// commandDeviceNumber returns the device number of the given client.
+var commandDeviceNumber = func(clientID string) (string, error) {
+	deviceID, err := GetStr("mqtt_clinet_id_" + clientID)
+	if err != nil {
+		return "", err
+	}
+	dev, err := GetDeviceById(deviceID)
+	if err != nil {
+		return "", err
+	}
+	return dev.DeviceNumber, nil
+}
+
+// CommandLedgerConfig is the configuration of the command ledger, read from the command_ledger section of thingspanel.yml.
+type CommandLedgerConfig struct {
+	Enabled bool
+	// TTL is the default lifetime of a command, a MQTT5 message expiry interval overrides it.
+	TTL time.Duration
+	// Retention is how long the records in final states are kept in redis.
+	Retention time.Duration
+	// ReplayDelay is the delay before replaying the pending commands after the device connected,
+	// which gives the persistent session a chance to deliver the queued commands first.
+	ReplayDelay time.Duration
+	// SweepInterval is the interval to check for expired commands.
+	SweepInterval time.Duration
+	// StateTopic is the topic which the state changes are published to.
+	StateTopic string
+}
+
+func commandLedgerConfigFromViper() CommandLedgerConfig {
+	viper.SetDefault("command_ledger.enabled", false)
+	viper.SetDefault("command_ledger.ttl", time.Hour)
+	viper.SetDefault("command_ledger.retention", 24*time.Hour)
+	viper.SetDefault("command_ledger.replay_delay", time.Second)
+	viper.SetDefault("command_ledger.sweep_interval", 10*time.Second)
+	viper.SetDefault("command_ledger.state_topic", "devices/command/state")
+	return CommandLedgerConfig{
+		Enabled:       viper.GetBool("command_ledger.enabled"),
+		TTL:           viper.GetDuration("command_ledger.ttl"),
+		Retention:     viper.GetDuration("command_ledger.retention"),
+		ReplayDelay:   viper.GetDuration("command_ledger.replay_delay"),
+		SweepInterval: viper.GetDuration("command_ledger.sweep_interval"),
+		StateTopic:    viper.GetString("command_ledger.state_topic"),
+	}
+}
+
+// CommandRecord is a downlink command in the ledger.
+type CommandRecord struct {
+	MessageID    string       `json:"message_id"`
+	DeviceNumber string       `json:"device_number"`
+	Topic        string       `json:"topic"`
+	Payload      string       `json:"payload"`
+	QoS          byte         `json:"qos"`
+	State        CommandState `json:"state"`
+	Error        string       `json:"error,omitempty"`
+	// Replays is the number of times the command was replayed.
+	Replays   int   `json:"replays,omitempty"`
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+	ExpireAt  int64 `json:"expire_at"`
+}
+
+// CommandStateEvent is published to the state topic when the state of a command changes.
+type CommandStateEvent struct {
+	MessageID    string       `json:"message_id"`
+	DeviceNumber string       `json:"device_number"`
+	State        CommandState `json:"state"`
+	Error        string       `json:"error,omitempty"`
+	Ts           int64        `json:"ts"`
+}
+
+// ledgerUpdate is a state transition of a command.
+type ledgerUpdate struct {
+	messageID string
+	to        CommandState
+	errMsg    string
+	from      []CommandState
+}
+
+// commandLedger tracks the lifecycle of the downlink commands published by the platform
+// and replays the pending commands when the device reconnects.
+// The records are stored in redis:
+//
+//	tp:cmd:ledger:{message_id}       the JSON encoded CommandRecord
+//	tp:cmd:pending:{device_number}   sorted set of the pending message ids, scored by the expire time
+//	tp:cmd:deadline                  sorted set of the unfinished message ids, scored by the expire time
+type commandLedger struct {
+	cfg CommandLedgerConfig
+	// mu serializes the read-modify-write of the records.
+	mu sync.Mutex
+	// replay republishes the command to the device.
+	replay func(msg *gmqtt.Message)
+	// notify publishes the state change to the platform.
+	notify func(topic string, payload []byte) error
+	// updates is the queue of the state updates from the broker hooks,
+	// which are applied by the update worker to keep redis round-trips out of the hooks.
+	updates chan ledgerUpdate
+	exit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newCommandLedger(cfg CommandLedgerConfig, replay func(msg *gmqtt.Message), notify func(topic string, payload []byte) error) *commandLedger {
+	return &commandLedger{
+		cfg:     cfg,
+		replay:  replay,
+		notify:  notify,
+		updates: make(chan ledgerUpdate, cmdLedgerUpdateQueueSize),
+		exit:    make(chan struct{}),
+	}
+}
+
+// parseCommandTopic returns the device number and message id of the command topic.
+func parseCommandTopic(topic string) (deviceNumber, messageID string, ok bool) {
+	if !strings.HasPrefix(topic, commandTopicPrefix) {
+		return "", "", false
+	}
+	levels := strings.Split(topic[len(commandTopicPrefix):], "/")
+	if len(levels) != 2 || levels[0] == "" || levels[1] == "" || levels[0] == "response" {
+		return "", "", false
+	}
+	return levels[0], levels[1], true
+}
+
+func cmdLedgerKey(messageID string) string {
+	return cmdLedgerKeyPrefix + messageID
+}
+
+func cmdLedgerPendingKey(deviceNumber string) string {
+	return cmdLedgerPendingPrefix + deviceNumber
+}
+
+func (l *commandLedger) get(messageID string) (*CommandRecord, error) {
+	rec := &CommandRecord{}
+	if err := GetRedisForJsondata(cmdLedgerKey(messageID), rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (l *commandLedger) save(rec *CommandRecord) error {
+	exp := time.Unix(0, rec.ExpireAt*int64(time.Millisecond)).Sub(cmdLedgerNow()) + l.cfg.Retention
+	if exp <= 0 {
+		exp = l.cfg.Retention
+	}
+	return SetRedisForJsondata(cmdLedgerKey(rec.MessageID), rec, exp)
+}
+
+func (l *commandLedger) publishState(rec *CommandRecord) {
+	if l.notify == nil || l.cfg.StateTopic == "" {
+		return
+	}
+	b, _ := json.Marshal(&CommandStateEvent{
+		MessageID:    rec.MessageID,
+		DeviceNumber: rec.DeviceNumber,
+		State:        rec.State,
+		Error:        rec.Error,
+		Ts:           rec.UpdatedAt,
+	})
+	if err := l.notify(l.cfg.StateTopic, b); err != nil {
+		Log.Warn("【命令台账】状态上报失败", zap.String("message_id", rec.MessageID), zap.Error(err))
+	}
+}
+
+// record adds the command published by the platform into the ledger with the pending state.
+func (l *commandLedger) record(msg *gmqtt.Message) error {
+	deviceNumber, messageID, ok := parseCommandTopic(msg.Topic)
+	if !ok {
+		return nil
+	}
+	now := cmdLedgerNow()
+	ttl := l.cfg.TTL
+	if msg.MessageExpiry != 0 {
+		ttl = time.Duration(msg.MessageExpiry) * time.Second
+	}
+	rec := &CommandRecord{
+		MessageID:    messageID,
+		DeviceNumber: deviceNumber,
+		Topic:        msg.Topic,
+		Payload:      string(msg.Payload),
+		QoS:          msg.QoS,
+		State:        CommandPending,
+		CreatedAt:    now.UnixNano() / 1e6,
+		UpdatedAt:    now.UnixNano() / 1e6,
+		ExpireAt:     now.Add(ttl).UnixNano() / 1e6,
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if old, err := l.get(messageID); err == nil && !old.State.final() {
+		// the platform retries the same command, keep the original record.
+		return nil
+	}
+	if err := l.save(rec); err != nil {
+		return err
+	}
+	z := redis.Z{Score: float64(rec.ExpireAt), Member: messageID}
+	if err := redisCache.ZAdd(cmdLedgerPendingKey(deviceNumber), z).Err(); err != nil {
+		return err
+	}
+	if err := redisCache.ZAdd(cmdLedgerDeadlineKey, z).Err(); err != nil {
+		return err
+	}
+	l.publishState(rec)
+	return nil
+}
+
+// transit changes the state of the command if its current state is one of the from states.
+func (l *commandLedger) transit(messageID string, to CommandState, errMsg string, from ...CommandState) error {
+	return l.apply(ledgerUpdate{messageID: messageID, to: to, errMsg: errMsg, from: from})
+}
+
+// apply applies the state update to the command.
+func (l *commandLedger) apply(u ledgerUpdate) error {
+	messageID, to, errMsg := u.messageID, u.to, u.errMsg
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec, err := l.get(messageID)
+	if err != nil {
+		if err == redis.Nil {
+			// not a command tracked by the ledger
+			return nil
+		}
+		return err
+	}
+	var allowed bool
+	for _, v := range u.from {
+		if rec.State == v {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil
+	}
+	rec.State = to
+	rec.Error = errMsg
+	rec.UpdatedAt = cmdLedgerNow().UnixNano() / 1e6
+	if err := l.save(rec); err != nil {
+		return err
+	}
+	if to != CommandPending {
+		if err := redisCache.ZRem(cmdLedgerPendingKey(rec.DeviceNumber), messageID).Err(); err != nil {
+			return err
+		}
+	}
+	if to.final() {
+		if err := redisCache.ZRem(cmdLedgerDeadlineKey, messageID).Err(); err != nil {
+			return err
+		}
+	}
+	l.publishState(rec)
+	return nil
+}
+
+// deliveredUpdate returns the update which marks the command as delivered.
+func deliveredUpdate(msg *gmqtt.Message) (ledgerUpdate, bool) {
+	_, messageID, ok := parseCommandTopic(msg.Topic)
+	if !ok {
+		return ledgerUpdate{}, false
+	}
+	return ledgerUpdate{messageID: messageID, to: CommandDelivered, from: []CommandState{CommandPending}}, true
+}
+
+// droppedUpdate returns the update which marks the command as failed, or expired if it is dropped due to expiry.
+func droppedUpdate(msg *gmqtt.Message, err error) (ledgerUpdate, bool) {
+	_, messageID, ok := parseCommandTopic(msg.Topic)
+	if !ok {
+		return ledgerUpdate{}, false
+	}
+	to := CommandFailed
+	if errors.Is(err, queue.ErrDropExpired) || errors.Is(err, queue.ErrDropExpiredInflight) {
+		to = CommandExpired
+	}
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	return ledgerUpdate{messageID: messageID, to: to, errMsg: errMsg, from: []CommandState{CommandPending, CommandDelivered}}, true
+}
+
+// respondedUpdate returns the update which marks the command as acknowledged when the device responds on
+// devices/command/response/{message_id}. A response with a non-zero "result" field marks the command as failed.
+func respondedUpdate(topic string, payload []byte) (ledgerUpdate, bool) {
+	if !strings.HasPrefix(topic, commandResponseTopicPrefix) {
+		return ledgerUpdate{}, false
+	}
+	messageID := topic[len(commandResponseTopicPrefix):]
+	if messageID == "" || strings.Contains(messageID, "/") {
+		return ledgerUpdate{}, false
+	}
+	var resp struct {
+		Result  *int   `json:"result"`
+		Message string `json:"message"`
+	}
+	to, errMsg := CommandAcknowledged, ""
+	if json.Unmarshal(payload, &resp) == nil && resp.Result != nil && *resp.Result != 0 {
+		to = CommandFailed
+		errMsg = resp.Message
+		if errMsg == "" {
+			errMsg = "result: " + strconv.Itoa(*resp.Result)
+		}
+	}
+	return ledgerUpdate{messageID: messageID, to: to, errMsg: errMsg, from: []CommandState{CommandPending, CommandDelivered}}, true
+}
+
+// delivered marks the command as delivered.
+func (l *commandLedger) delivered(msg *gmqtt.Message) error {
+	if u, ok := deliveredUpdate(msg); ok {
+		return l.apply(u)
+	}
+	return nil
+}
+
+// dropped marks the command as failed, or expired if it is dropped due to expiry.
+func (l *commandLedger) dropped(msg *gmqtt.Message, err error) error {
+	if u, ok := droppedUpdate(msg, err); ok {
+		return l.apply(u)
+	}
+	return nil
+}
+
+// responded marks the command as acknowledged or failed according to the response of the device.
+func (l *commandLedger) responded(topic string, payload []byte) error {
+	if u, ok := respondedUpdate(topic, payload); ok {
+		return l.apply(u)
+	}
+	return nil
+}
+
+// enqueue adds the update to the update queue without blocking, the update is dropped if the queue is full.
+func (l *commandLedger) enqueue(u ledgerUpdate) {
+	select {
+	case l.updates <- u:
+	default:
+		Log.Warn("【命令台账】状态更新队列已满，丢弃更新", zap.String("message_id", u.messageID), zap.String("state", string(u.to)))
+	}
+}
+
+// onDelivered is the asynchronous version of delivered, it is called in the OnDelivered hook.
+func (l *commandLedger) onDelivered(msg *gmqtt.Message) {
+	if u, ok := deliveredUpdate(msg); ok {
+		l.enqueue(u)
+	}
+}
+
+// onDropped is the asynchronous version of dropped, it is called in the OnMsgDropped hook.
+func (l *commandLedger) onDropped(msg *gmqtt.Message, err error) {
+	if u, ok := droppedUpdate(msg, err); ok {
+		l.enqueue(u)
+	}
+}
+
+// onResponded is the asynchronous version of responded, it is called in the OnMsgArrived hook.
+func (l *commandLedger) onResponded(topic string, payload []byte) {
+	if u, ok := respondedUpdate(topic, payload); ok {
+		l.enqueue(u)
+	}
+}
+
+// pendingIDs returns the message ids of the pending commands of the device which are not expired.
+func (l *commandLedger) pendingIDs(deviceNumber string) ([]string, error) {
+	now := cmdLedgerNow().UnixNano() / 1e6
+	return redisCache.ZRangeByScore(cmdLedgerPendingKey(deviceNumber), redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now, 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// markReplayed increases the replay counter of the command if it is still pending.
+// It returns nil if the command is not pending anymore.
+func (l *commandLedger) markReplayed(messageID string) (*CommandRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec, err := l.get(messageID)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.State != CommandPending {
+		return nil, nil
+	}
+	rec.Replays++
+	if err := l.save(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// replayPending republishes the pending commands to the device.
+func (l *commandLedger) replayPending(deviceNumber string) error {
+	ids, err := l.pendingIDs(deviceNumber)
+	if err != nil {
+		return err
+	}
+	now := cmdLedgerNow().UnixNano() / 1e6
+	for _, id := range ids {
+		rec, err := l.markReplayed(id)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			continue
+		}
+		Log.Info("【命令台账】重放离线命令", zap.String("device_number", deviceNumber), zap.String("message_id", rec.MessageID))
+		l.replay(&gmqtt.Message{
+			QoS:           rec.QoS,
+			Topic:         rec.Topic,
+			Payload:       []byte(rec.Payload),
+			MessageExpiry: uint32((rec.ExpireAt - now + 999) / 1000),
+		})
+	}
+	return nil
+}
+
+// sweep expires the unfinished commands which are past the deadline.
+func (l *commandLedger) sweep() error {
+	now := cmdLedgerNow().UnixNano() / 1e6
+	ids, err := redisCache.ZRangeByScore(cmdLedgerDeadlineKey, redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now, 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := l.transit(id, CommandExpired, "ttl exceeded", CommandPending, CommandDelivered); err != nil {
+			return err
+		}
+		// the record may be evicted or already finished, remove it from the index anyway.
+		if err := redisCache.ZRem(cmdLedgerDeadlineKey, id).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *commandLedger) run() {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			select {
+			case <-l.exit:
+				// apply the queued updates before exiting.
+				for {
+					select {
+					case u := <-l.updates:
+						if err := l.apply(u); err != nil {
+							Log.Warn("【命令台账】更新命令状态失败", zap.String("message_id", u.messageID), zap.Error(err))
+						}
+					default:
+						return
+					}
+				}
+			case u := <-l.updates:
+				if err := l.apply(u); err != nil {
+					Log.Warn("【命令台账】更新命令状态失败", zap.String("message_id", u.messageID), zap.Error(err))
+				}
+			}
+		}
+	}()
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		t := time.NewTicker(l.cfg.SweepInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-l.exit:
+				return
+			case <-t.C:
+				if err := l.sweep(); err != nil {
+					Log.Warn("【命令台账】过期检查失败", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// onConnected replays the pending commands of the device after the replay delay.
+func (l *commandLedger) onConnected(clientID string) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		select {
+		case <-l.exit:
+			return
+		case <-time.After(l.cfg.ReplayDelay):
+		}
+		deviceNumber, err := commandDeviceNumber(clientID)
+		if err != nil || deviceNumber == "" {
+			Log.Warn("【命令台账】获取设备编号失败", zap.String("client_id", clientID), zap.Error(err))
+			return
+		}
+		if err := l.replayPending(deviceNumber); err != nil {
+			Log.Warn("【命令台账】重放离线命令失败", zap.String("device_number", deviceNumber), zap.Error(err))
+		}
+	}()
+}
+
+func (l *commandLedger) stop() {
+	close(l.exit)
+	l.wg.Wait()
+}
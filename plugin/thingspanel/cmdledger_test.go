@@ -0,0 +1,267 @@
+package thingspanel
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+	"gopkg.in/redis.v5"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+)
+
+type testLedger struct {
+	*commandLedger
+	replayed []*gmqtt.Message
+	states   []CommandStateEvent
+	onReplay func(msg *gmqtt.Message)
+}
+
+func newTestLedger(t *testing.T) *testLedger {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	redisCache = redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = redisCache.Close() })
+
+	Log = zap.NewNop()
+	cmdLedgerNow = func() time.Time { return time.Unix(1000, 0) }
+	t.Cleanup(func() { cmdLedgerNow = time.Now })
+
+	tl := &testLedger{}
+	tl.commandLedger = newCommandLedger(CommandLedgerConfig{
+		Enabled:    true,
+		TTL:        time.Minute,
+		Retention:  time.Hour,
+		StateTopic: "devices/command/state",
+	}, func(msg *gmqtt.Message) {
+		tl.replayed = append(tl.replayed, msg)
+		if tl.onReplay != nil {
+			tl.onReplay(msg)
+		}
+	}, func(topic string, payload []byte) error {
+		var e CommandStateEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		tl.states = append(tl.states, e)
+		return nil
+	})
+	return tl
+}
+
+func (tl *testLedger) mustState(t *testing.T, messageID string, state CommandState) {
+	t.Helper()
+	rec, err := tl.get(messageID)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if rec.State != state {
+		t.Fatalf("expected state %s, got %s", state, rec.State)
+	}
+}
+
+func TestParseCommandTopic(t *testing.T) {
+	for _, v := range []struct {
+		topic        string
+		deviceNumber string
+		messageID    string
+		ok           bool
+	}{
+		{topic: "devices/command/dev1/m1", deviceNumber: "dev1", messageID: "m1", ok: true},
+		{topic: "devices/command/response/m1"},
+		{topic: "devices/command/dev1"},
+		{topic: "devices/command/dev1/m1/x"},
+		{topic: "devices/telemetry/dev1/m1"},
+	} {
+		dn, mid, ok := parseCommandTopic(v.topic)
+		if dn != v.deviceNumber || mid != v.messageID || ok != v.ok {
+			t.Fatalf("%s: unexpected result %s %s %v", v.topic, dn, mid, ok)
+		}
+	}
+}
+
+func TestCommandLedger_Lifecycle(t *testing.T) {
+	tl := newTestLedger(t)
+	msg := &gmqtt.Message{Topic: "devices/command/dev1/m1", Payload: []byte(`{"method":"reboot"}`), QoS: 1}
+	if err := tl.record(msg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandPending)
+
+	// non-command messages are ignored
+	if err := tl.delivered(&gmqtt.Message{Topic: "devices/telemetry"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := tl.delivered(msg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandDelivered)
+
+	if err := tl.responded("devices/command/response/m1", []byte(`{"result":0}`)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandAcknowledged)
+
+	// final state can not be changed
+	if err := tl.dropped(msg, queue.ErrDropQueueFull); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandAcknowledged)
+
+	expected := []CommandState{CommandPending, CommandDelivered, CommandAcknowledged}
+	if len(tl.states) != len(expected) {
+		t.Fatalf("expected %d state events, got %d", len(expected), len(tl.states))
+	}
+	for k, v := range expected {
+		if tl.states[k].State != v || tl.states[k].MessageID != "m1" || tl.states[k].DeviceNumber != "dev1" {
+			t.Fatalf("unexpected state event: %+v", tl.states[k])
+		}
+	}
+}
+
+func TestCommandLedger_Failed(t *testing.T) {
+	tl := newTestLedger(t)
+	for _, v := range []string{"m1", "m2", "m3"} {
+		if err := tl.record(&gmqtt.Message{Topic: "devices/command/dev1/" + v}); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	if err := tl.responded("devices/command/response/m1", []byte(`{"result":1,"message":"busy"}`)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandFailed)
+	rec, _ := tl.get("m1")
+	if rec.Error != "busy" {
+		t.Fatalf("unexpected error: %s", rec.Error)
+	}
+
+	if err := tl.dropped(&gmqtt.Message{Topic: "devices/command/dev1/m2"}, queue.ErrDropQueueFull); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m2", CommandFailed)
+
+	if err := tl.dropped(&gmqtt.Message{Topic: "devices/command/dev1/m3"}, queue.ErrDropExpired); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m3", CommandExpired)
+}
+
+func TestCommandLedger_ReplayAndExpire(t *testing.T) {
+	tl := newTestLedger(t)
+	if err := tl.record(&gmqtt.Message{Topic: "devices/command/dev1/m1", QoS: 1}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	// per-command ttl
+	if err := tl.record(&gmqtt.Message{Topic: "devices/command/dev1/m2", MessageExpiry: 10}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := tl.record(&gmqtt.Message{Topic: "devices/command/dev1/m3"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := tl.delivered(&gmqtt.Message{Topic: "devices/command/dev1/m3"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if err := tl.replayPending("dev1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tl.replayed) != 2 {
+		t.Fatalf("expected 2 replayed commands, got %d", len(tl.replayed))
+	}
+	for _, v := range tl.replayed {
+		switch v.Topic {
+		case "devices/command/dev1/m1":
+			if v.QoS != 1 || v.MessageExpiry != 60 {
+				t.Fatalf("unexpected message: %+v", v)
+			}
+		case "devices/command/dev1/m2":
+			if v.MessageExpiry != 10 {
+				t.Fatalf("unexpected message: %+v", v)
+			}
+		default:
+			t.Fatalf("unexpected topic: %s", v.Topic)
+		}
+	}
+
+	cmdLedgerNow = func() time.Time { return time.Unix(1030, 0) }
+	if err := tl.sweep(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandPending)
+	tl.mustState(t, "m2", CommandExpired)
+	tl.mustState(t, "m3", CommandDelivered)
+
+	tl.replayed = nil
+	if err := tl.replayPending("dev1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tl.replayed) != 1 || tl.replayed[0].Topic != "devices/command/dev1/m1" {
+		t.Fatalf("unexpected replayed commands: %+v", tl.replayed)
+	}
+	rec, _ := tl.get("m1")
+	if rec.Replays != 2 {
+		t.Fatalf("expected 2 replays, got %d", rec.Replays)
+	}
+
+	cmdLedgerNow = func() time.Time { return time.Unix(1061, 0) }
+	if err := tl.sweep(); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.mustState(t, "m1", CommandExpired)
+	tl.mustState(t, "m3", CommandExpired)
+	tl.replayed = nil
+	if err := tl.replayPending("dev1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tl.replayed) != 0 {
+		t.Fatalf("expected no replayed commands, got %d", len(tl.replayed))
+	}
+}
+
+func TestCommandLedger_ReplaySkipsDelivered(t *testing.T) {
+	tl := newTestLedger(t)
+	for _, v := range []string{"m1", "m2"} {
+		if err := tl.record(&gmqtt.Message{Topic: "devices/command/dev1/" + v}); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	// m2 is delivered by the persistent session while m1 is being replayed.
+	tl.onReplay = func(msg *gmqtt.Message) {
+		if err := tl.delivered(&gmqtt.Message{Topic: "devices/command/dev1/m2"}); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	if err := tl.replayPending("dev1"); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(tl.replayed) != 1 || tl.replayed[0].Topic != "devices/command/dev1/m1" {
+		t.Fatalf("unexpected replayed commands: %+v", tl.replayed)
+	}
+	tl.mustState(t, "m2", CommandDelivered)
+}
+
+func TestCommandLedger_AsyncUpdates(t *testing.T) {
+	tl := newTestLedger(t)
+	tl.cfg.SweepInterval = time.Hour
+	msg := &gmqtt.Message{Topic: "devices/command/dev1/m1"}
+	if err := tl.record(msg); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	tl.onDelivered(&gmqtt.Message{Topic: "devices/telemetry"})
+	tl.onDelivered(msg)
+	tl.onResponded("devices/command/response/m1", []byte(`{"result":0}`))
+	if len(tl.updates) != 2 {
+		t.Fatalf("expected 2 queued updates, got %d", len(tl.updates))
+	}
+	// the queued updates are applied before the worker exits.
+	tl.run()
+	tl.stop()
+	tl.mustState(t, "m1", CommandAcknowledged)
+}
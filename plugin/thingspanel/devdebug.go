@@ -76,6 +76,12 @@ func GetDeviceDebugConfig(deviceID string) (DeviceDebugConfig, bool, error) {
 // WriteDeviceDebugLog appends a log entry if device debug is enabled.
 // It is safe to call frequently; missing/expired config results in a no-op.
 func WriteDeviceDebugLog(deviceID string, entry DeviceDebugLogEntry) (bool, error) {
+	written, err := writeDeviceDebugLog(deviceID, entry)
+	observeDebugLogWrite(written, err)
+	return written, err
+}
+
+func writeDeviceDebugLog(deviceID string, entry DeviceDebugLogEntry) (bool, error) {
 	cfg, enabled, err := GetDeviceDebugConfig(deviceID)
 	if err != nil || !enabled {
 		return false, err
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/DrmagicE/gmqtt"
 	"github.com/DrmagicE/gmqtt/plugin/thingspanel/util"
 	"github.com/DrmagicE/gmqtt/server"
 	"github.com/spf13/viper"
@@ -19,6 +20,8 @@ func (t *Thingspanel) HookWrapper() server.HookWrapper {
 		OnMsgArrivedWrapper: t.OnMsgArrivedWrapper,
 		OnConnectedWrapper:  t.OnConnectedWrapper,
 		OnClosedWrapper:     t.OnClosedWrapper,
+		OnDeliveredWrapper:  t.OnDeliveredWrapper,
+		OnMsgDroppedWrapper: t.OnMsgDroppedWrapper,
 	}
 }
 
@@ -33,20 +36,28 @@ func (t *Thingspanel) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicA
 		if string(req.Connect.Username) == "root" {
 			password := viper.GetString("mqtt.password")
 			if string(req.Connect.Password) == password {
+				t.auditPrivileged(client, "root", auditPrivilegedConnect, string(req.Connect.ClientID), nil, nil)
+				observeAuth(authResultOK, authReasonRoot)
 				return nil
 			} else {
 				err := errors.New("password error;")
 				Log.Warn(err.Error())
+				t.auditPrivileged(client, "root", auditPrivilegedConnect, string(req.Connect.ClientID), nil, err)
+				observeAuth(authResultDeny, authReasonRootPassword)
 				return err
 			}
 		}
 		if string(req.Connect.Username) == "plugin" {
 			password := viper.GetString("mqtt.plugin_password")
 			if string(req.Connect.Password) == password {
+				t.auditPrivileged(client, "plugin", auditPrivilegedConnect, string(req.Connect.ClientID), nil, nil)
+				observeAuth(authResultOK, authReasonPlugin)
 				return nil
 			} else {
 				err := errors.New("password error;")
 				Log.Warn(err.Error())
+				t.auditPrivileged(client, "plugin", auditPrivilegedConnect, string(req.Connect.ClientID), nil, err)
+				observeAuth(authResultDeny, authReasonPluginPassword)
 				return err
 			}
 		}
@@ -68,6 +79,7 @@ func (t *Thingspanel) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicA
 			Log.Warn("【鉴权】失败",
 				zap.String("client_id", string(req.Connect.ClientID)),
 				zap.Error(err))
+			observeAuth(authResultDeny, authReasonVoucher)
 			// å¤±è´¥æ—¶å°½åŠ›å®šä½è®¾å¤‡IDï¼ˆä¸è®°å½•æ˜Žæ–‡å¯†ç ï¼‰ï¼Œä»¥ä¾¿å…¥åº“è°ƒè¯•æ—¥å¿—
 			if string(req.Connect.Username) != "root" && string(req.Connect.Username) != "plugin" && string(req.Connect.Password) != "" {
 				fallbackVoucher := fmt.Sprintf(`{"username":"%s"}`, string(req.Connect.Username))
@@ -105,8 +117,10 @@ func (t *Thingspanel) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicA
 		err = SetStr("mqtt_clinet_id_"+string(req.Connect.ClientID), device.ID, 0)
 		if err != nil {
 			Log.Error(err.Error())
+			observeAuth(authResultDeny, authReasonCache)
 			return err
 		}
+		observeAuth(authResultOK, authReasonDevice)
 		return nil
 	}
 }
@@ -132,7 +146,33 @@ func (t *Thingspanel) OnConnectedWrapper(pre server.OnConnected) server.OnConnec
 			if err := DefaultMqttClient.SendData("devices/status/"+deviceId, []byte("1")); err != nil {
 				Log.Warn("【设备上线】上报状态失败", zap.String("device_id", deviceId), zap.Error(err))
 			}
+			// 重放离线期间的待下发命令
+			if t.ledger != nil {
+				t.ledger.onConnected(client.ClientOptions().ClientID)
+			}
+		}
+	}
+}
+
+// 命令送达钩子函数
+func (t *Thingspanel) OnDeliveredWrapper(pre server.OnDelivered) server.OnDelivered {
+	return func(ctx context.Context, client server.Client, msg *gmqtt.Message) {
+		pre(ctx, client, msg)
+		if t.ledger == nil {
+			return
+		}
+		t.ledger.onDelivered(msg)
+	}
+}
+
+// 消息丢弃钩子函数
+func (t *Thingspanel) OnMsgDroppedWrapper(pre server.OnMsgDropped) server.OnMsgDropped {
+	return func(ctx context.Context, clientID string, msg *gmqtt.Message, err error) {
+		pre(ctx, clientID, msg, err)
+		if t.ledger == nil {
+			return
 		}
+		t.ledger.onDropped(msg, err)
 	}
 }
 func (t *Thingspanel) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
@@ -239,6 +279,64 @@ func (t *Thingspanel) OnSubscribeWrapper(pre server.OnSubscribe) server.OnSubscr
 	}
 }
 
+// forwardMappedDown forwards the downlink message published by the platform to the device's original topic
+// if the topic is a normalized downlink topic and the device config has a matching topic mapping.
+func (t *Thingspanel) forwardMappedDown(ctx context.Context, clientID, username, topic string, payload []byte) {
+	if deviceNumber, ok := TryExtractDeviceNumberFromNormalized(topic); ok && deviceNumber != "" {
+		_, span := startSpan(ctx, "thingspanel.db.get_device_by_number")
+		dev, derr := GetDeviceByNumber(deviceNumber)
+		endSpan(span, derr)
+		if derr == nil && dev != nil && dev.DeviceConfigID != nil {
+			svc := NewTopicMapService()
+			rctx, span := startSpan(ctx, "thingspanel.topicmap.resolve_down")
+			src, outPayload, matched := svc.ResolveDownSource(rctx, *dev.DeviceConfigID, topic, deviceNumber, payload)
+			span.End()
+			if matched && src != "" {
+				forwardSucceeded := true
+				if err := DefaultMqttClient.SendDataContext(ctx, src, outPayload); err != nil {
+					forwardSucceeded = false
+					Log.Warn("【下行自定义主题额外转发】失败", zap.String("topic", topic), zap.String("client_id", clientID), zap.Error(err))
+					_, _ = WriteDeviceDebugLog(dev.ID, DeviceDebugLogEntry{
+						Protocol:  "mqtt",
+						Action:    "forward",
+						Direction: "down",
+						Outcome:   "error",
+						Error:     err.Error(),
+						Payload:   string(payload),
+						Meta: map[string]interface{}{
+							"client_id":    clientID,
+							"username":     username,
+							"topic":        topic,
+							"mapped":       true,
+							"target_topic": topic,
+							"source_topic": src,
+						},
+					})
+				} else {
+					Log.Info("【下行自定义主题额外转发】成功", zap.String("topic", topic), zap.String("client_id", clientID), zap.String("target", src))
+				}
+				if forwardSucceeded {
+					_, _ = WriteDeviceDebugLog(dev.ID, DeviceDebugLogEntry{
+						Protocol:  "mqtt",
+						Action:    "forward",
+						Direction: "down",
+						Outcome:   "ok",
+						Payload:   string(payload),
+						Meta: map[string]interface{}{
+							"client_id":    clientID,
+							"username":     username,
+							"topic":        topic,
+							"mapped":       true,
+							"target_topic": topic,
+							"source_topic": src,
+						},
+					})
+				}
+			}
+		}
+	}
+}
+
 func (t *Thingspanel) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
 	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) (err error) {
 		username := client.ClientOptions().Username
@@ -249,70 +347,40 @@ func (t *Thingspanel) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgA
 			zap.String("payload", string(req.Message.Payload)))
 		// root用户和插件用户直接转发
 		if username == "root" || username == "plugin" {
+			t.auditPrivilegedPublish(client, req.Message)
 			// RootMessageForwardWrapper(req.Message.Topic, req.Message.Payload, false)
-			// root平台下发：若主题属于规范“下行主题”，提取设备号并按映射额外转发到设备原始主题
-			topic := req.Message.Topic
-			if deviceNumber, ok := TryExtractDeviceNumberFromNormalized(topic); ok && deviceNumber != "" {
-				if dev, derr := GetDeviceByNumber(deviceNumber); derr == nil && dev != nil && dev.DeviceConfigID != nil {
-					svc := NewTopicMapService()
-					if src, outPayload, matched := svc.ResolveDownSource(ctx, *dev.DeviceConfigID, topic, deviceNumber, req.Message.Payload); matched && src != "" {
-						forwardSucceeded := true
-						if err := DefaultMqttClient.SendData(src, outPayload); err != nil {
-							forwardSucceeded = false
-							Log.Warn("【下行自定义主题额外转发】失败", zap.String("topic", topic), zap.String("client_id", client.ClientOptions().ClientID), zap.Error(err))
-							_, _ = WriteDeviceDebugLog(dev.ID, DeviceDebugLogEntry{
-								Protocol:  "mqtt",
-								Action:    "forward",
-								Direction: "down",
-								Outcome:   "error",
-								Error:     err.Error(),
-								Payload:   string(req.Message.Payload),
-								Meta: map[string]interface{}{
-									"client_id":    client.ClientOptions().ClientID,
-									"username":     username,
-									"topic":        topic,
-									"mapped":       true,
-									"target_topic": topic,
-									"source_topic": src,
-								},
-							})
-						} else {
-							Log.Info("【下行自定义主题额外转发】成功", zap.String("topic", topic), zap.String("client_id", client.ClientOptions().ClientID), zap.String("target", src))
-						}
-						if forwardSucceeded {
-							_, _ = WriteDeviceDebugLog(dev.ID, DeviceDebugLogEntry{
-								Protocol:  "mqtt",
-								Action:    "forward",
-								Direction: "down",
-								Outcome:   "ok",
-								Payload:   string(req.Message.Payload),
-								Meta: map[string]interface{}{
-									"client_id":    client.ClientOptions().ClientID,
-									"username":     username,
-									"topic":        topic,
-									"mapped":       true,
-									"target_topic": topic,
-									"source_topic": src,
-								},
-							})
-						}
-					}
+			// 记录平台下发的命令
+			if t.ledger != nil {
+				if err := t.ledger.record(req.Message); err != nil {
+					Log.Warn("【命令台账】记录命令失败", zap.String("topic", req.Message.Topic), zap.Error(err))
 				}
 			}
+			// root平台下发：若主题属于规范“下行主题”，按映射额外转发到设备原始主题
+			t.forwardMappedDown(ctx, client.ClientOptions().ClientID, username, req.Message.Topic, req.Message.Payload)
 			return nil
 		}
 
 		the_pub := string(req.Publish.TopicName)
 		originalPayload := string(req.Message.Payload)
 
+		// 设备命令响应
+		if t.ledger != nil {
+			t.ledger.onResponded(the_pub, req.Message.Payload)
+		}
+
 		// 获取设备与配置ID（用于自定义映射）
+		_, span := startSpan(ctx, "thingspanel.redis.get_device_id")
 		deviceId, err := GetStr("mqtt_clinet_id_" + client.ClientOptions().ClientID)
+		endSpan(span, err)
 		if err != nil {
 			return err
 		}
 		var deviceConfigID string
 		if deviceId != "" {
-			if dev, derr := GetDeviceById(deviceId); derr == nil && dev != nil && dev.DeviceConfigID != nil {
+			_, span := startSpan(ctx, "thingspanel.db.get_device")
+			dev, derr := GetDeviceById(deviceId)
+			endSpan(span, derr)
+			if derr == nil && dev != nil && dev.DeviceConfigID != nil {
 				deviceConfigID = *dev.DeviceConfigID
 			}
 		}
@@ -320,12 +388,15 @@ func (t *Thingspanel) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgA
 		// 优先尝试上行自定义映射
 		if deviceConfigID != "" {
 			svc := NewTopicMapService()
-			if target, ok := svc.ResolveUpTarget(ctx, deviceConfigID, the_pub); ok && target != "" {
+			rctx, span := startSpan(ctx, "thingspanel.topicmap.resolve_up")
+			target, ok := svc.ResolveUpTarget(rctx, deviceConfigID, the_pub)
+			span.End()
+			if ok && target != "" {
 				newMsgMap := make(map[string]interface{})
 				newMsgMap["device_id"] = deviceId
 				newMsgMap["values"] = req.Message.Payload
 				newMsgJson, _ := json.Marshal(newMsgMap)
-				if err := DefaultMqttClient.SendData(target, newMsgJson); err != nil {
+				if err := DefaultMqttClient.SendDataContext(ctx, target, newMsgJson); err != nil {
 					if deviceId != "" {
 						_, _ = WriteDeviceDebugLog(deviceId, DeviceDebugLogEntry{
 							Protocol:  "mqtt",
@@ -0,0 +1,84 @@
+package thingspanel
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricPrefix = "gmqtt_thingspanel_"
+
+// The label values of authTotal.
+const (
+	authResultOK   = "ok"
+	authResultDeny = "deny"
+
+	authReasonRoot           = "root"
+	authReasonPlugin         = "plugin"
+	authReasonDevice         = "device"
+	authReasonRootPassword   = "root_password"
+	authReasonPluginPassword = "plugin_password"
+	authReasonVoucher        = "voucher"
+	authReasonCache          = "cache"
+)
+
+var (
+	authTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricPrefix + "auth_total",
+		Help: "The number of the authentications, labeled by the result (ok|deny) and the reason.",
+	}, []string{"result", "reason"})
+
+	topicMapTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricPrefix + "topic_mapping_total",
+		Help: "The number of the custom topic mapping lookups, labeled by the direction (up|down) and the result (hit|miss).",
+	}, []string{"direction", "result"})
+
+	forwardFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricPrefix + "forward_failures_total",
+		Help: "The number of the messages which the internal publisher failed to forward, labeled by the reason (timeout|error).",
+	}, []string{"reason"})
+
+	publisherQueueDepth = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: metricPrefix + "publisher_queue_depth",
+		Help: "The number of the messages waiting in the queue of the internal publisher.",
+	}, func() float64 {
+		return float64(DefaultMqttClient.queueLen())
+	})
+
+	debugLogWritesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricPrefix + "debug_log_writes_total",
+		Help: "The number of the device debug log writes, labeled by the result (ok|error).",
+	}, []string{"result"})
+)
+
+// collectors returns the collectors to be registered with the prometheus plugin.
+func collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		authTotal,
+		topicMapTotal,
+		forwardFailuresTotal,
+		publisherQueueDepth,
+		debugLogWritesTotal,
+	}
+}
+
+func observeAuth(result, reason string) {
+	authTotal.WithLabelValues(result, reason).Inc()
+}
+
+func observeTopicMap(direction Direction, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	topicMapTotal.WithLabelValues(string(direction), result).Inc()
+}
+
+// observeDebugLogWrite counts the result of WriteDeviceDebugLog, the calls for the devices without debug enabled are ignored.
+func observeDebugLogWrite(written bool, err error) {
+	if err != nil {
+		debugLogWritesTotal.WithLabelValues("error").Inc()
+		return
+	}
+	if written {
+		debugLogWritesTotal.WithLabelValues("ok").Inc()
+	}
+}
@@ -0,0 +1,84 @@
+package thingspanel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gopkg.in/redis.v5"
+)
+
+func TestTopicMapMetrics(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	defer s.Close()
+
+	redisCache = redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = redisCache.Close() })
+
+	mappings := []DeviceTopicMapping{{SourceTopic: "dev/+/up", TargetTopic: "devices/telemetry"}}
+	if err := SetRedisForJsondata(cacheKeyUp("cfg1"), mappings, 0); err != nil {
+		t.Fatalf("set mappings: %v", err)
+	}
+	hit := topicMapTotal.WithLabelValues("up", "hit")
+	miss := topicMapTotal.WithLabelValues("up", "miss")
+	hitBefore, missBefore := testutil.ToFloat64(hit), testutil.ToFloat64(miss)
+
+	svc := NewTopicMapService()
+	if target, ok := svc.ResolveUpTarget(context.Background(), "cfg1", "dev/1/up"); !ok || target != "devices/telemetry" {
+		t.Fatalf("expected matched, got %q %v", target, ok)
+	}
+	if _, ok := svc.ResolveUpTarget(context.Background(), "cfg1", "dev/1/down"); ok {
+		t.Fatalf("expected not matched")
+	}
+	if v := testutil.ToFloat64(hit) - hitBefore; v != 1 {
+		t.Fatalf("expected 1 hit, got %v", v)
+	}
+	if v := testutil.ToFloat64(miss) - missBefore; v != 1 {
+		t.Fatalf("expected 1 miss, got %v", v)
+	}
+}
+
+func TestDebugLogWriteMetrics(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	defer s.Close()
+
+	redisCache = redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = redisCache.Close() })
+
+	devDebugNow = func() time.Time { return time.Unix(1000, 0) }
+	t.Cleanup(func() { devDebugNow = time.Now })
+
+	cfg := DeviceDebugConfig{Enabled: true, MaxItems: 10}
+	if err := SetRedisForJsondata(devDebugCfgKey("dev1"), cfg, 0); err != nil {
+		t.Fatalf("set cfg: %v", err)
+	}
+	ok := debugLogWritesTotal.WithLabelValues("ok")
+	failed := debugLogWritesTotal.WithLabelValues("error")
+	okBefore, failedBefore := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+
+	entry := DeviceDebugLogEntry{Action: "publish", Direction: "up", Outcome: "ok"}
+	if _, err := WriteDeviceDebugLog("dev1", entry); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	// debug is not enabled for dev2, it is not counted.
+	if _, err := WriteDeviceDebugLog("dev2", entry); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+	if _, err := WriteDeviceDebugLog("", entry); err == nil {
+		t.Fatalf("expected error for empty device id")
+	}
+	if v := testutil.ToFloat64(ok) - okBefore; v != 1 {
+		t.Fatalf("expected 1 ok, got %v", v)
+	}
+	if v := testutil.ToFloat64(failed) - failedBefore; v != 1 {
+		t.Fatalf("expected 1 error, got %v", v)
+	}
+}
@@ -1,11 +1,14 @@
 package thingspanel
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -59,9 +62,19 @@ func (c *MqttClient) MqttInit() error {
 }
 
 func (c *MqttClient) SendData(topic string, data []byte) error {
+	return c.SendDataContext(context.Background(), topic, data)
+}
+
+// SendDataContext 发布消息，并在 ctx 中的链路下创建 thingspanel.forward 子 span，覆盖排队与发布确认。
+// 注意：客户端使用 MQTT 3.1.1，无法携带 traceparent 用户属性，
+// 转发后的消息在 broker 中会开始一条新的链路，只能通过 thingspanel.forward span 的 mqtt.topic 属性关联。
+func (c *MqttClient) SendDataContext(ctx context.Context, topic string, data []byte) error {
+	_, span := startSpan(ctx, "thingspanel.forward")
+	span.SetAttributes(attribute.String("mqtt.topic", topic))
 	defer func() {
 		if err := recover(); err != nil {
 			fmt.Println("【SendData】异常捕捉：", err)
+			endSpan(span, fmt.Errorf("%v", err))
 		}
 	}()
 	if !c.IsFlag {
@@ -80,6 +93,7 @@ func (c *MqttClient) SendData(topic string, data []byte) error {
 		token := c.Client.Publish(topic, 1, false, string(data))
 		go func() {
 			token.WaitTimeout(15 * time.Second)
+			endSpan(span, token.Error())
 		}()
 		return nil
 	}
@@ -87,16 +101,26 @@ func (c *MqttClient) SendData(topic string, data []byte) error {
 	c.sendCh <- func() {
 		token := c.Client.Publish(topic, 1, false, string(data))
 		if !token.WaitTimeout(15 * time.Second) {
+			forwardFailuresTotal.WithLabelValues("timeout").Inc()
 			Log.Warn("【消息发布超时】", zap.String("topic", topic), zap.String("data", string(data)))
+			endSpan(span, errors.New("publish timeout"))
 			return
 		}
-		if err := token.Error(); err != nil {
+		err := token.Error()
+		if err != nil {
+			forwardFailuresTotal.WithLabelValues("error").Inc()
 			Log.Warn("【消息发布失败】", zap.String("topic", topic), zap.String("data", string(data)), zap.Error(err))
 		}
+		endSpan(span, err)
 	}
 	return nil
 }
 
+// queueLen 返回串行化通道中等待发送的消息数
+func (c *MqttClient) queueLen() int {
+	return len(c.sendCh)
+}
+
 // sendWorker 后台串行发送协程：按顺序执行所有发布任务
 func (c *MqttClient) sendWorker() {
 	for task := range c.sendCh {
@@ -0,0 +1,58 @@
+package thingspanel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+type testMqttClient struct {
+	mqtt.Client
+	published map[string]interface{}
+}
+
+func (c *testMqttClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.published[topic] = payload
+	return testToken{}
+}
+
+type testToken struct {
+	mqtt.Token
+}
+
+func (testToken) WaitTimeout(time.Duration) bool { return true }
+
+func (testToken) Error() error { return nil }
+
+func TestMqttClient_SendDataContext(t *testing.T) {
+	Log = zap.NewNop()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	ctx, hook := tp.Tracer("test").Start(context.Background(), "hook.OnMsgArrived")
+
+	client := &testMqttClient{published: make(map[string]interface{})}
+	c := &MqttClient{Client: client, IsFlag: true, sendCh: make(chan func(), 1)}
+	if err := c.SendDataContext(ctx, "devices/telemetry", []byte("{}")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	(<-c.sendCh)()
+	hook.End()
+
+	// The MQTT 3.1.1 client can not carry the traceparent user property,
+	// the forwarded message is published as is and the broker starts a new trace for it.
+	if v := client.published["devices/telemetry"]; v != "{}" {
+		t.Fatalf("unexpected payload: %v", v)
+	}
+	spans := sr.Ended()
+	if len(spans) != 2 || spans[0].Name() != "thingspanel.forward" {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+	if spans[0].Parent().SpanID() != hook.SpanContext().SpanID() {
+		t.Fatalf("the forward span is not a child of the hook span")
+	}
+}
@@ -1,6 +1,7 @@
 package thingspanel
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -8,7 +9,9 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/DrmagicE/gmqtt"
 	"github.com/DrmagicE/gmqtt/config"
+	promplugin "github.com/DrmagicE/gmqtt/plugin/prometheus"
 	"github.com/DrmagicE/gmqtt/server"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -50,17 +53,44 @@ func New(config config.Config) (server.Plugin, error) {
 	return &Thingspanel{}, nil
 }
 
-type Thingspanel struct{}
+type Thingspanel struct {
+	// ledger is the downlink command ledger, nil if disabled.
+	ledger *commandLedger
+	// auditLogger records the operations of the privileged users.
+	auditLogger server.AuditLogger
+}
 
 func (t *Thingspanel) Load(service server.Server) error {
 	Log = server.LoggerWithField(zap.String("plugin", Name))
 	runtimeInitOnce.Do(func() {
 		runtimeInitErr = runtimeInit()
 	})
-	return runtimeInitErr
+	if runtimeInitErr != nil {
+		return runtimeInitErr
+	}
+	if err := promplugin.Register(collectors()...); err != nil {
+		return err
+	}
+	t.auditLogger = service.AuditLogger()
+	if cfg := commandLedgerConfigFromViper(); cfg.Enabled {
+		publisher := service.Publisher()
+		// replay the commands through the same path as the platform publishes them:
+		// deliver to the normalized topic and forward to the mapped device topic.
+		t.ledger = newCommandLedger(cfg, func(msg *gmqtt.Message) {
+			publisher.Publish(msg)
+			t.forwardMappedDown(context.Background(), "", "", msg.Topic, msg.Payload)
+		}, DefaultMqttClient.SendData)
+		t.ledger.run()
+	}
+	return nil
 }
 
-func (t *Thingspanel) Unload() error { return nil }
+func (t *Thingspanel) Unload() error {
+	if t.ledger != nil {
+		t.ledger.stop()
+	}
+	return nil
+}
 
 func (t *Thingspanel) Name() string { return Name }
 
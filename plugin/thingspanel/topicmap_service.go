@@ -16,7 +16,8 @@ func NewTopicMapService() *TopicMapService {
 
 // ResolveUpTarget tries to resolve an up-direction target topic for a given device_config_id and incoming source topic.
 // Returns target topic and true if matched; otherwise returns empty string and false.
-func (s *TopicMapService) ResolveUpTarget(ctx context.Context, deviceConfigID string, incomingSource string) (string, bool) {
+func (s *TopicMapService) ResolveUpTarget(ctx context.Context, deviceConfigID string, incomingSource string) (target string, ok bool) {
+	defer func() { observeTopicMap(DirectionUp, ok) }()
 	mappings, err := GetMappingsWithCache(ctx, deviceConfigID, DirectionUp)
 	if err != nil || len(mappings) == 0 {
 		return "", false
@@ -56,7 +57,8 @@ func (s *TopicMapService) AllowDownSubscribe(ctx context.Context, deviceConfigID
 // when platform publishes to a normalized down target topic. 平台发布到规范化下行目标主题时，解析下行原始主题
 // variables currently support: device_number 目前支持的变量：device_number
 // payload will be trimmed to params when data_identifier matched; otherwise kept as-is.
-func (s *TopicMapService) ResolveDownSource(ctx context.Context, deviceConfigID string, normalizedTarget string, deviceNumber string, payload []byte) (string, []byte, bool) {
+func (s *TopicMapService) ResolveDownSource(ctx context.Context, deviceConfigID string, normalizedTarget string, deviceNumber string, payload []byte) (source string, out []byte, ok bool) {
+	defer func() { observeTopicMap(DirectionDown, ok) }()
 	// 获取设备配置ID对应的下行自定义主题映射
 	mappings, err := GetMappingsWithCache(ctx, deviceConfigID, DirectionDown)
 	if err != nil || len(mappings) == 0 {
@@ -0,0 +1,26 @@
+package thingspanel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by the thingspanel plugin.
+const tracerName = "github.com/DrmagicE/gmqtt/plugin/thingspanel"
+
+// startSpan starts a child span of the hook span in ctx.
+// It uses the tracer provider of the hook span, so the spans are created only if tracing is enabled in the broker.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, name)
+}
+
+// endSpan records the error if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,234 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/pkg/aof"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const (
+	retainedBucket = "retained"
+)
+
+var _ retained.Store = (*Store)(nil)
+
+// Store implements the retained.Store. It persists retained messages in aof.DB
+// and uses an in-memory trie as the match index, the index is rebuilt from the db in Init.
+type Store struct {
+	mu    sync.Mutex
+	db    *aof.DB
+	index retained.Store
+	// expiry stores the expiry time of the retained messages which have the message expiry interval, key by topic name.
+	expiry map[string]time.Time
+	log    *zap.Logger
+}
+
+func New(db *aof.DB) *Store {
+	return &Store{
+		db:     db,
+		index:  trie.NewStore(),
+		expiry: make(map[string]time.Time),
+		log:    server.LoggerWithField(zap.String("retained", "file")),
+	}
+}
+
+// encode encodes the retained message into bytes.
+// Format: 8 byte expiry timestamp (0 means never expire) | message
+func encode(msg *gmqtt.Message, expiry time.Time) []byte {
+	b := bytes.NewBuffer(make([]byte, 0, 100))
+	ts := make([]byte, 8)
+	if !expiry.IsZero() {
+		binary.BigEndian.PutUint64(ts, uint64(expiry.Unix()))
+	}
+	b.Write(ts)
+	encoding.EncodeMessage(msg, b)
+	return b.Bytes()
+}
+
+func decode(b []byte) (msg *gmqtt.Message, expiry time.Time, err error) {
+	if len(b) < 8 {
+		return nil, expiry, errors.New("invalid input length")
+	}
+	if ts := binary.BigEndian.Uint64(b[0:8]); ts != 0 {
+		expiry = time.Unix(int64(ts), 0)
+	}
+	msg, err = encoding.DecodeMessage(bytes.NewBuffer(b[8:]))
+	return
+}
+
+// Init loads all retained messages from the db and rebuilds the match index.
+// Expired messages will be removed.
+func (s *Store) Init() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.index.ClearAll()
+	s.expiry = make(map[string]time.Time)
+	var total int
+	var expired []string
+	var err error
+	s.db.ForEach(retainedBucket, func(key string, value []byte) bool {
+		var msg *gmqtt.Message
+		var expiry time.Time
+		msg, expiry, err = decode(value)
+		if err != nil {
+			return false
+		}
+		if !expiry.IsZero() {
+			if !now.Before(expiry) {
+				expired = append(expired, key)
+				return true
+			}
+			s.expiry[msg.Topic] = expiry
+		}
+		s.index.AddOrReplace(msg)
+		total++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if len(expired) != 0 {
+		if err = s.db.Delete(retainedBucket, expired...); err != nil {
+			return err
+		}
+	}
+	s.log.Info("init retained store succeeded", zap.Int("retained_total", total))
+	return nil
+}
+
+// checkExpiryLocked returns the message that can be delivered to clients.
+// It returns nil if the message is expired,
+// otherwise the message expiry interval of the returned message is set to the remaining lifetime.
+func (s *Store) checkExpiryLocked(now time.Time, msg *gmqtt.Message) *gmqtt.Message {
+	expiry, ok := s.expiry[msg.Topic]
+	if !ok {
+		return msg
+	}
+	if !now.Before(expiry) {
+		return nil
+	}
+	msg = msg.Copy()
+	msg.MessageExpiry = uint32(expiry.Sub(now).Seconds())
+	if msg.MessageExpiry == 0 {
+		msg.MessageExpiry = 1
+	}
+	return msg
+}
+
+// removeExpiredLocked removes the given expired messages from the index and the db.
+func (s *Store) removeExpiredLocked(topics []string) {
+	if len(topics) == 0 {
+		return
+	}
+	for _, v := range topics {
+		s.index.Remove(v)
+		delete(s.expiry, v)
+	}
+	if err := s.db.Delete(retainedBucket, topics...); err != nil {
+		s.log.Error("fail to remove expired retained messages", zap.Error(err))
+	}
+}
+
+// GetRetainedMessage returns the retain message of the given topic name.
+// Returns nil if the topic name not exists or the message is expired.
+func (s *Store) GetRetainedMessage(topicName string) *gmqtt.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg := s.index.GetRetainedMessage(topicName)
+	if msg == nil {
+		return nil
+	}
+	rs := s.checkExpiryLocked(time.Now(), msg)
+	if rs == nil {
+		s.removeExpiredLocked([]string{topicName})
+	}
+	return rs
+}
+
+// ClearAll clears all retained messages.
+func (s *Store) ClearAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.DeleteBucket(retainedBucket); err != nil {
+		s.log.Error("fail to clear retained messages", zap.Error(err))
+	}
+	s.index.ClearAll()
+	s.expiry = make(map[string]time.Time)
+}
+
+// AddOrReplace adds or replaces a retained message.
+func (s *Store) AddOrReplace(message *gmqtt.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expiry time.Time
+	if message.MessageExpiry != 0 {
+		expiry = time.Now().Add(time.Duration(message.MessageExpiry) * time.Second)
+	}
+	if err := s.db.Put(retainedBucket, message.Topic, encode(message, expiry)); err != nil {
+		s.log.Error("fail to add retained message", zap.String("topic", message.Topic), zap.Error(err))
+	}
+	if expiry.IsZero() {
+		delete(s.expiry, message.Topic)
+	} else {
+		s.expiry[message.Topic] = expiry
+	}
+	s.index.AddOrReplace(message)
+}
+
+// Remove removes the retained message of the topic name.
+func (s *Store) Remove(topicName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.Delete(retainedBucket, topicName); err != nil {
+		s.log.Error("fail to remove retained message", zap.String("topic", topicName), zap.Error(err))
+	}
+	delete(s.expiry, topicName)
+	s.index.Remove(topicName)
+}
+
+// GetMatchedMessages returns all messages that match the topic filter.
+func (s *Store) GetMatchedMessages(topicFilter string) []*gmqtt.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var rs []*gmqtt.Message
+	var expired []string
+	for _, v := range s.index.GetMatchedMessages(topicFilter) {
+		if msg := s.checkExpiryLocked(now, v); msg != nil {
+			rs = append(rs, msg)
+		} else {
+			expired = append(expired, v.Topic)
+		}
+	}
+	s.removeExpiredLocked(expired)
+	return rs
+}
+
+// Iterate iterates all retained messages. Expired messages will be skipped.
+func (s *Store) Iterate(fn retained.IterateFn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var expired []string
+	s.index.Iterate(func(message *gmqtt.Message) bool {
+		msg := s.checkExpiryLocked(now, message)
+		if msg == nil {
+			expired = append(expired, message.Topic)
+			return true
+		}
+		return fn(msg)
+	})
+	s.removeExpiredLocked(expired)
+}
@@ -0,0 +1,315 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+const (
+	retainedPrefix = "retained:"
+)
+
+var _ retained.Store = (*Store)(nil)
+
+// Store implements the retained.Store. It persists retained messages in redis
+// and uses an in-memory trie as the match index, the index is rebuilt from redis in Init.
+type Store struct {
+	// writeMu serializes the writes, so that the redis keys and the index are updated in the same order.
+	// The redis I/O is done under writeMu only, so that the lookups are not blocked by the network.
+	writeMu sync.Mutex
+	// mu guards index and expiry.
+	mu    sync.Mutex
+	pool  *redigo.Pool
+	index retained.Store
+	// expiry stores the expiry time of the retained messages which have the message expiry interval, key by topic name.
+	expiry map[string]time.Time
+	// keyPrefix is prepended to all keys.
+	keyPrefix string
+	// hashTag wraps the topic name in the key with braces (e.g: retained:{topicName}),
+	// so that the slot of the key in redis cluster only depends on the topic name.
+	hashTag bool
+	log     *zap.Logger
+}
+
+func New(pool *redigo.Pool, keyPrefix string, hashTag bool) *Store {
+	return &Store{
+		keyPrefix: keyPrefix,
+		hashTag:   hashTag,
+		pool:      pool,
+		index:     trie.NewStore(),
+		expiry:    make(map[string]time.Time),
+		log:       server.LoggerWithField(zap.String("retained", "redis")),
+	}
+}
+
+func (s *Store) getKey(topicName string) string {
+	if s.hashTag {
+		return s.keyPrefix + retainedPrefix + "{" + topicName + "}"
+	}
+	return s.keyPrefix + retainedPrefix + topicName
+}
+
+// encode encodes the retained message into bytes.
+// Format: 8 byte expiry timestamp (0 means never expire) | message
+func encode(msg *gmqtt.Message, expiry time.Time) []byte {
+	b := bytes.NewBuffer(make([]byte, 0, 100))
+	ts := make([]byte, 8)
+	if !expiry.IsZero() {
+		binary.BigEndian.PutUint64(ts, uint64(expiry.Unix()))
+	}
+	b.Write(ts)
+	encoding.EncodeMessage(msg, b)
+	return b.Bytes()
+}
+
+func decode(b []byte) (msg *gmqtt.Message, expiry time.Time, err error) {
+	if len(b) < 8 {
+		return nil, expiry, errors.New("invalid input length")
+	}
+	if ts := binary.BigEndian.Uint64(b[0:8]); ts != 0 {
+		expiry = time.Unix(int64(ts), 0)
+	}
+	msg, err = encoding.DecodeMessage(bytes.NewBuffer(b[8:]))
+	return
+}
+
+// Init loads all retained messages from redis and rebuilds the match index.
+// Expired messages will be skipped.
+func (s *Store) Init() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	now := time.Now()
+	index := trie.NewStore()
+	expiryMap := make(map[string]time.Time)
+	var total int
+	err := s.scanKeys(c, func(keys []string) error {
+		args := make([]interface{}, len(keys))
+		for k, v := range keys {
+			args[k] = v
+		}
+		values, err := redigo.ByteSlices(c.Do("mget", args...))
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			// the key has been expired or removed after scanning.
+			if v == nil {
+				continue
+			}
+			msg, expiry, err := decode(v)
+			if err != nil {
+				return err
+			}
+			if !expiry.IsZero() {
+				if !now.Before(expiry) {
+					continue
+				}
+				expiryMap[msg.Topic] = expiry
+			}
+			index.AddOrReplace(msg)
+			total++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.index = index
+	s.expiry = expiryMap
+	s.mu.Unlock()
+	s.log.Info("init retained store succeeded", zap.Int("retained_total", total))
+	return nil
+}
+
+// scanKeys walks through all retained keys and calls fn for each batch.
+func (s *Store) scanKeys(c redigo.Conn, fn func(keys []string) error) error {
+	iter := 0
+	for {
+		arr, err := redigo.Values(c.Do("SCAN", iter, "MATCH", s.keyPrefix+retainedPrefix+"*"))
+		if err != nil {
+			return err
+		}
+		keys, err := redigo.Strings(arr[1], nil)
+		if err != nil {
+			return err
+		}
+		if len(keys) != 0 {
+			err = fn(keys)
+			if err != nil {
+				return err
+			}
+		}
+		iter, _ = redigo.Int(arr[0], nil)
+		if iter == 0 {
+			return nil
+		}
+	}
+}
+
+// checkExpiryLocked returns the message that can be delivered to clients.
+// It returns nil if the message is expired,
+// otherwise the message expiry interval of the returned message is set to the remaining lifetime.
+func (s *Store) checkExpiryLocked(now time.Time, msg *gmqtt.Message) *gmqtt.Message {
+	expiry, ok := s.expiry[msg.Topic]
+	if !ok {
+		return msg
+	}
+	if !now.Before(expiry) {
+		return nil
+	}
+	msg = msg.Copy()
+	msg.MessageExpiry = uint32(expiry.Sub(now).Seconds())
+	if msg.MessageExpiry == 0 {
+		msg.MessageExpiry = 1
+	}
+	return msg
+}
+
+// removeExpiredLocked removes the given expired messages from the index.
+// The redis keys will be removed by redis itself.
+func (s *Store) removeExpiredLocked(topics []string) {
+	for _, v := range topics {
+		s.index.Remove(v)
+		delete(s.expiry, v)
+	}
+}
+
+// GetRetainedMessage returns the retain message of the given topic name.
+// Returns nil if the topic name not exists or the message is expired.
+func (s *Store) GetRetainedMessage(topicName string) *gmqtt.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg := s.index.GetRetainedMessage(topicName)
+	if msg == nil {
+		return nil
+	}
+	rs := s.checkExpiryLocked(time.Now(), msg)
+	if rs == nil {
+		s.removeExpiredLocked([]string{topicName})
+	}
+	return rs
+}
+
+// ClearAll clears all retained messages.
+func (s *Store) ClearAll() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	err := s.scanKeys(c, func(keys []string) error {
+		args := make([]interface{}, len(keys))
+		for k, v := range keys {
+			args[k] = v
+		}
+		_, err := c.Do("del", args...)
+		return err
+	})
+	if err != nil {
+		s.log.Error("fail to clear retained messages", zap.Error(err))
+	}
+	s.mu.Lock()
+	s.index.ClearAll()
+	s.expiry = make(map[string]time.Time)
+	s.mu.Unlock()
+}
+
+// AddOrReplace adds or replaces a retained message.
+func (s *Store) AddOrReplace(message *gmqtt.Message) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	var expiry time.Time
+	var err error
+	if message.MessageExpiry != 0 {
+		expiry = time.Now().Add(time.Duration(message.MessageExpiry) * time.Second)
+		_, err = c.Do("set", s.getKey(message.Topic), encode(message, expiry), "ex", message.MessageExpiry)
+	} else {
+		_, err = c.Do("set", s.getKey(message.Topic), encode(message, expiry))
+	}
+	if err != nil {
+		s.log.Error("fail to add retained message", zap.String("topic", message.Topic), zap.Error(err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expiry.IsZero() {
+		delete(s.expiry, message.Topic)
+	} else {
+		s.expiry[message.Topic] = expiry
+	}
+	s.index.AddOrReplace(message)
+}
+
+// Remove removes the retained message of the topic name.
+func (s *Store) Remove(topicName string) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	_, err := c.Do("del", s.getKey(topicName))
+	if err != nil {
+		s.log.Error("fail to remove retained message", zap.String("topic", topicName), zap.Error(err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.expiry, topicName)
+	s.index.Remove(topicName)
+}
+
+// GetMatchedMessages returns all messages that match the topic filter.
+func (s *Store) GetMatchedMessages(topicFilter string) []*gmqtt.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var rs []*gmqtt.Message
+	var expired []string
+	for _, v := range s.index.GetMatchedMessages(topicFilter) {
+		if msg := s.checkExpiryLocked(now, v); msg != nil {
+			rs = append(rs, msg)
+		} else {
+			expired = append(expired, v.Topic)
+		}
+	}
+	s.removeExpiredLocked(expired)
+	return rs
+}
+
+// Iterate iterates all retained messages. Expired messages will be skipped.
+// fn is called without holding the lock, so it is safe to call the store in fn.
+func (s *Store) Iterate(fn retained.IterateFn) {
+	s.mu.Lock()
+	now := time.Now()
+	var expired []string
+	var msgs []*gmqtt.Message
+	s.index.Iterate(func(message *gmqtt.Message) bool {
+		msg := s.checkExpiryLocked(now, message)
+		if msg == nil {
+			expired = append(expired, message.Topic)
+			return true
+		}
+		msgs = append(msgs, msg)
+		return true
+	})
+	s.removeExpiredLocked(expired)
+	s.mu.Unlock()
+	for _, v := range msgs {
+		if !fn(v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
+)
+
+func newPool(t *testing.T) (*miniredis.Miniredis, *redigo.Pool) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis: %v", err)
+	}
+	t.Cleanup(s.Close)
+	pool := &redigo.Pool{
+		Dial: func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", s.Addr())
+		},
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	return s, pool
+}
+
+func TestStore(t *testing.T) {
+	s, pool := newPool(t)
+	retained_test.TestSuite(t, func() retained.Store {
+		s.FlushAll()
+		st := New(pool, "", false)
+		if err := st.Init(); err != nil {
+			t.Fatal(err)
+		}
+		return st
+	})
+}
+
+func TestStore_Init(t *testing.T) {
+	a := assert.New(t)
+	_, pool := newPool(t)
+	msgs := []*gmqtt.Message{
+		{
+			QoS:      1,
+			Retained: true,
+			Topic:    "a/b",
+			Payload:  []byte{1, 2, 3},
+		},
+		{
+			QoS:             2,
+			Retained:        true,
+			Topic:           "$SYS/a",
+			Payload:         []byte{1},
+			ContentType:     "json",
+			CorrelationData: []byte{1, 2},
+			ResponseTopic:   "resp",
+			PayloadFormat:   packets.PayloadFormatString,
+			UserProperties: []packets.UserProperty{
+				{K: []byte("k"), V: []byte("v")},
+			},
+		},
+	}
+	st := New(pool, "", false)
+	a.Nil(st.Init())
+	for _, v := range msgs {
+		st.AddOrReplace(v)
+	}
+	st.AddOrReplace(&gmqtt.Message{Topic: "a/c", Payload: []byte{1}})
+	st.Remove("a/c")
+
+	// rebuild the index from redis
+	st = New(pool, "", false)
+	a.Nil(st.Init())
+	for _, v := range msgs {
+		a.Equal(v, st.GetRetainedMessage(v.Topic))
+	}
+	a.Nil(st.GetRetainedMessage("a/c"))
+	a.Len(st.GetMatchedMessages("a/+"), 1)
+	a.Len(st.GetMatchedMessages("$SYS/#"), 1)
+}
+
+func TestStore_Expiry(t *testing.T) {
+	a := assert.New(t)
+	s, pool := newPool(t)
+	st := New(pool, "", false)
+	a.Nil(st.Init())
+	st.AddOrReplace(&gmqtt.Message{
+		Topic:         "a/b",
+		Payload:       []byte{1},
+		MessageExpiry: 100,
+	})
+	a.True(s.TTL(st.getKey("a/b")) > 0)
+	msg := st.GetRetainedMessage("a/b")
+	a.NotNil(msg)
+	a.True(msg.MessageExpiry > 0 && msg.MessageExpiry <= 100)
+
+	// expired messages are skipped when rebuilding the index
+	a.Nil(s.Set(st.getKey("a/c"), string(encode(&gmqtt.Message{
+		Topic:   "a/c",
+		Payload: []byte{1},
+	}, time.Now().Add(-time.Second)))))
+	st = New(pool, "", false)
+	a.Nil(st.Init())
+	a.NotNil(st.GetRetainedMessage("a/b"))
+	a.Nil(st.GetRetainedMessage("a/c"))
+
+	// expired messages are removed from the index
+	st.expiry["a/b"] = time.Now().Add(-time.Second)
+	a.Len(st.GetMatchedMessages("#"), 0)
+	a.Nil(st.GetRetainedMessage("a/b"))
+}
+
+func TestStore_IterateReentrant(t *testing.T) {
+	a := assert.New(t)
+	_, pool := newPool(t)
+	st := New(pool, "", false)
+	a.Nil(st.Init())
+	for _, v := range []string{"a", "b", "c"} {
+		st.AddOrReplace(&gmqtt.Message{Topic: v, Payload: []byte{1}})
+	}
+	// the callback is able to call the store.
+	st.Iterate(func(message *gmqtt.Message) bool {
+		a.NotNil(st.GetRetainedMessage(message.Topic))
+		st.Remove(message.Topic)
+		return true
+	})
+	a.Len(st.GetMatchedMessages("#"), 0)
+}
+
+func TestStore_HashTag(t *testing.T) {
+	a := assert.New(t)
+	_, pool := newPool(t)
+	a.Equal("p:retained:a/b", New(pool, "p:", false).getKey("a/b"))
+	a.Equal("p:retained:{a/b}", New(pool, "p:", true).getKey("a/b"))
+}
@@ -0,0 +1,300 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/retained"
+)
+
+// NewStoreFn returns a new empty retained.Store for each test case.
+type NewStoreFn func() retained.Store
+
+// TestSuite runs the shared retained.Store test cases against the store returned by newFn.
+func TestSuite(t *testing.T, newFn NewStoreFn) {
+	t.Run("ClearAll", func(t *testing.T) {
+		testClearAll(t, newFn)
+	})
+	t.Run("GetRetainedMessage", func(t *testing.T) {
+		testGetRetainedMessage(t, newFn)
+	})
+	t.Run("GetMatchedMessages", func(t *testing.T) {
+		testGetMatchedMessages(t, newFn)
+	})
+	t.Run("Remove", func(t *testing.T) {
+		testRemove(t, newFn)
+	})
+	t.Run("Iterate", func(t *testing.T) {
+		testIterate(t, newFn)
+	})
+	t.Run("Iterate_Cancel", func(t *testing.T) {
+		testIterateCancel(t, newFn)
+	})
+}
+
+func testClearAll(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	s.AddOrReplace(&gmqtt.Message{
+		Topic: "a/b/c",
+	})
+	s.AddOrReplace(&gmqtt.Message{
+		Topic:   "a/b/c/d",
+		Payload: []byte{1, 2, 3},
+	})
+	s.ClearAll()
+	a.Nil(s.GetRetainedMessage("a/b/c"))
+	a.Nil(s.GetRetainedMessage("a/b/c/d"))
+}
+
+func testGetRetainedMessage(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	tt := []*gmqtt.Message{
+		{
+			Topic:   "a/b/c/d",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b/c/",
+			Payload: []byte{1, 2, 3, 4},
+		},
+		{
+			Topic:   "a/",
+			Payload: []byte{1, 2, 3},
+		},
+	}
+	for _, v := range tt {
+		s.AddOrReplace(v)
+	}
+	for _, v := range tt {
+		rs := s.GetRetainedMessage(v.Topic)
+		a.Equal(v.Topic, rs.Topic)
+		a.Equal(v.Payload, rs.Payload)
+	}
+	a.Nil(s.GetRetainedMessage("a/b"))
+}
+
+func testGetMatchedMessages(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	msgs := []*gmqtt.Message{
+		{
+			Topic:   "a/b/c/d",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b/c/",
+			Payload: []byte{1, 2, 3, 4},
+		},
+		{
+			Topic:   "a/",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "b/a",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a",
+			Payload: []byte{1, 2, 3},
+		},
+	}
+	var tt = []struct {
+		TopicFilter string
+		expected    map[string]*gmqtt.Message
+	}{
+		{
+			TopicFilter: "a/+/c/",
+			expected: map[string]*gmqtt.Message{
+				"a/b/c/": {
+					Payload: []byte{1, 2, 3, 4},
+				},
+			},
+		},
+		{
+			TopicFilter: "a/+",
+			expected: map[string]*gmqtt.Message{
+				"a/": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a/b": {
+					Payload: []byte{1, 2, 3},
+				},
+			},
+		},
+		{
+			TopicFilter: "#",
+			expected: map[string]*gmqtt.Message{
+				"a/b/c/d": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a/b/c/": {
+					Payload: []byte{1, 2, 3, 4},
+				},
+				"a/": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a/b": {
+					Payload: []byte{1, 2, 3},
+				},
+				"b/a": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a": {
+					Payload: []byte{1, 2, 3},
+				},
+			},
+		},
+		{
+			TopicFilter: "a/#",
+			expected: map[string]*gmqtt.Message{
+				"a/b/c/d": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a/b/c/": {
+					Payload: []byte{1, 2, 3, 4},
+				},
+				"a/": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a/b": {
+					Payload: []byte{1, 2, 3},
+				},
+				"a": {
+					Payload: []byte{1, 2, 3},
+				},
+			},
+		},
+		{
+			TopicFilter: "a/b/c/d",
+			expected: map[string]*gmqtt.Message{
+				"a/b/c/d": {
+					Payload: []byte{1, 2, 3},
+				},
+			},
+		},
+	}
+	for _, v := range msgs {
+		s.AddOrReplace(v)
+	}
+	for _, v := range tt {
+		t.Run(v.TopicFilter, func(t *testing.T) {
+			rs := s.GetMatchedMessages(v.TopicFilter)
+			a.Equal(len(v.expected), len(rs))
+			got := make(map[string]*gmqtt.Message)
+			for _, v := range rs {
+				got[v.Topic] = v
+			}
+			for k, v := range v.expected {
+				a.Equal(v.Payload, got[k].Payload)
+			}
+		})
+
+	}
+}
+
+func testRemove(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	s.AddOrReplace(&gmqtt.Message{
+		Topic: "a/b/c",
+	})
+	s.AddOrReplace(&gmqtt.Message{
+		Topic:   "a/b/c/d",
+		Payload: []byte{1, 2, 3},
+	})
+	a.NotNil(s.GetRetainedMessage("a/b/c"))
+	s.Remove("a/b/c")
+	a.Nil(s.GetRetainedMessage("a/b/c"))
+}
+
+func testIterate(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	msgs := []*gmqtt.Message{
+		{
+			Topic:   "a/b/c/d",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b/c/",
+			Payload: []byte{1, 2, 3, 4},
+		},
+		{
+			Topic:   "a/",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "$SYS/a/b",
+			Payload: []byte{1, 2, 3},
+		},
+	}
+
+	for _, v := range msgs {
+		s.AddOrReplace(v)
+	}
+	var rs []*gmqtt.Message
+	s.Iterate(func(message *gmqtt.Message) bool {
+		rs = append(rs, message)
+		return true
+	})
+	a.ElementsMatch(msgs, rs)
+}
+
+func testIterateCancel(t *testing.T, newFn NewStoreFn) {
+	a := assert.New(t)
+	s := newFn()
+	msgs := []*gmqtt.Message{
+		{
+			Topic:   "a/b/c/d",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b/c/",
+			Payload: []byte{1, 2, 3, 4},
+		},
+		{
+			Topic:   "a/",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a/b",
+			Payload: []byte{1, 2, 3},
+		},
+		{
+			Topic:   "a",
+			Payload: []byte{1, 2, 3},
+		},
+	}
+
+	for _, v := range msgs {
+		s.AddOrReplace(v)
+	}
+	var i int
+	var rs []*gmqtt.Message
+	s.Iterate(func(message *gmqtt.Message) bool {
+		if i == 2 {
+			return false
+		}
+		rs = append(rs, message)
+		i++
+		return true
+	})
+	a.Len(rs, 2)
+
+}
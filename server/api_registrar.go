@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
@@ -28,6 +29,17 @@ type APIRegistrar interface {
 	RegisterHTTPHandler(fn HTTPHandler) error
 	// RegisterService registers a service and its implementation to all gRPC servers.
 	RegisterService(desc *grpc.ServiceDesc, impl interface{})
+	// RegisterUnaryInterceptor registers the unary interceptor to all gRPC servers.
+	// The interceptors are chained in registration order and must be registered before the servers start,
+	// i.e. in the Load function of the plugin.
+	RegisterUnaryInterceptor(i grpc.UnaryServerInterceptor)
+	// RegisterStreamInterceptor registers the stream interceptor to all gRPC servers.
+	// The interceptors are chained in registration order and must be registered before the servers start.
+	RegisterStreamInterceptor(i grpc.StreamServerInterceptor)
+	// RegisterHTTPMiddleware registers the middleware to all http servers.
+	// The first registered middleware is the outermost one.
+	// The middlewares must be registered before the servers start.
+	RegisterHTTPMiddleware(m HTTPMiddleware)
 }
 
 type apiRegistrar struct {
@@ -42,13 +54,39 @@ func (a *apiRegistrar) RegisterService(desc *grpc.ServiceDesc, impl interface{})
 	}
 }
 
+// RegisterUnaryInterceptor implements APIRegistrar interface
+func (a *apiRegistrar) RegisterUnaryInterceptor(i grpc.UnaryServerInterceptor) {
+	for _, v := range a.gRPCServers {
+		v.unaryInterceptors = append(v.unaryInterceptors, i)
+	}
+}
+
+// RegisterStreamInterceptor implements APIRegistrar interface
+func (a *apiRegistrar) RegisterStreamInterceptor(i grpc.StreamServerInterceptor) {
+	for _, v := range a.gRPCServers {
+		v.streamInterceptors = append(v.streamInterceptors, i)
+	}
+}
+
+// RegisterHTTPMiddleware implements APIRegistrar interface
+func (a *apiRegistrar) RegisterHTTPMiddleware(m HTTPMiddleware) {
+	for _, v := range a.httpServers {
+		v.middlewares = append(v.middlewares, m)
+	}
+}
+
 // RegisterHTTPHandler implements APIRegistrar interface
 func (a *apiRegistrar) RegisterHTTPHandler(fn HTTPHandler) error {
 	var err error
 	for _, v := range a.httpServers {
 		schema, addr := splitEndpoint(v.gRPCEndpoint)
 		if schema == "unix" {
-			err = fn(context.Background(), v.mux, v.gRPCEndpoint, []grpc.DialOption{grpc.WithInsecure()})
+			endpoint := v.gRPCEndpoint
+			// gRPC does not accept the relative path in the unix:// form, e.g. unix://./gmqttd.sock, use unix:./gmqttd.sock instead.
+			if !strings.HasPrefix(addr, "/") {
+				endpoint = "unix:" + addr
+			}
+			err = fn(context.Background(), v.mux, endpoint, []grpc.DialOption{grpc.WithInsecure()})
 			if err != nil {
 				return err
 			}
@@ -63,24 +101,55 @@ func (a *apiRegistrar) RegisterHTTPHandler(fn HTTPHandler) error {
 }
 
 type gRPCServer struct {
-	server   *grpc.Server
-	serve    func(errChan chan error) error
-	shutdown func()
-	endpoint string
+	server             *grpc.Server
+	serve              func(errChan chan error) error
+	shutdown           func()
+	endpoint           string
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+}
+
+// unaryInterceptor calls the registered unary interceptors.
+func (g *gRPCServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if len(g.unaryInterceptors) == 0 {
+		return handler(ctx, req)
+	}
+	return grpc_middleware.ChainUnaryServer(g.unaryInterceptors...)(ctx, req, info, handler)
+}
+
+// streamInterceptor calls the registered stream interceptors.
+func (g *gRPCServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if len(g.streamInterceptors) == 0 {
+		return handler(srv, ss)
+	}
+	return grpc_middleware.ChainStreamServer(g.streamInterceptors...)(srv, ss, info, handler)
 }
 
 type httpServer struct {
 	gRPCEndpoint string
 	endpoint     string
 	mux          *runtime.ServeMux
+	middlewares  []HTTPMiddleware
 	tlsCfg       *tls.Config
 	serve        func(errChan chan error) error
 	shutdown     func()
 }
 
+// handler returns the mux wrapped by the registered middlewares.
+func (h *httpServer) handler() http.Handler {
+	var handler http.Handler = h.mux
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+	return handler
+}
+
 // HTTPHandler is the http handler defined by gRPC-gateway.
 type HTTPHandler = func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error)
 
+// HTTPMiddleware wraps the http handler of the http server.
+type HTTPMiddleware = func(next http.Handler) http.Handler
+
 func splitEndpoint(endpoint string) (schema string, addr string) {
 	epParts := strings.SplitN(endpoint, "://", 2)
 	if len(epParts) == 1 && epParts[0] != "" {
@@ -123,6 +192,9 @@ func buildGRPCServer(endpoint *config.Endpoint) (*gRPCServer, error) {
 		}
 		cred = credentials.NewTLS(tlsCfg)
 	}
+	g := &gRPCServer{
+		endpoint: endpoint.Address,
+	}
 	server := grpc.NewServer(
 		grpc.Creds(cred),
 		grpc.ChainUnaryInterceptor(
@@ -132,7 +204,11 @@ func buildGRPCServer(endpoint *config.Endpoint) (*gRPCServer, error) {
 				}
 				return grpc_zap.DefaultClientCodeToLevel(code)
 			})),
-			grpc_prometheus.UnaryServerInterceptor),
+			grpc_prometheus.UnaryServerInterceptor,
+			g.unaryInterceptor),
+		grpc.ChainStreamInterceptor(
+			grpc_prometheus.StreamServerInterceptor,
+			g.streamInterceptor),
 	)
 	grpc_prometheus.Register(server)
 	shutdown := func() {
@@ -153,12 +229,10 @@ func buildGRPCServer(endpoint *config.Endpoint) (*gRPCServer, error) {
 		return nil
 	}
 
-	return &gRPCServer{
-		server:   server,
-		serve:    serve,
-		shutdown: shutdown,
-		endpoint: endpoint.Address,
-	}, nil
+	g.server = server
+	g.serve = serve
+	g.shutdown = shutdown
+	return g, nil
 }
 
 func buildHTTPServer(endpoint *config.Endpoint) (*httpServer, error) {
@@ -171,9 +245,12 @@ func buildHTTPServer(endpoint *config.Endpoint) (*httpServer, error) {
 		}
 	}
 	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}))
-	server := &http.Server{
-		Handler: mux,
+	h := &httpServer{
+		gRPCEndpoint: endpoint.Map,
+		mux:          mux,
+		endpoint:     endpoint.Address,
 	}
+	server := &http.Server{}
 	shutdown := func() {
 		server.Shutdown(context.Background())
 	}
@@ -186,6 +263,7 @@ func buildHTTPServer(endpoint *config.Endpoint) (*httpServer, error) {
 		if tlsCfg != nil {
 			l = tls.NewListener(l, tlsCfg)
 		}
+		server.Handler = h.handler()
 		go func() {
 			select {
 			case errChan <- server.Serve(l):
@@ -195,13 +273,9 @@ func buildHTTPServer(endpoint *config.Endpoint) (*httpServer, error) {
 		return nil
 	}
 
-	return &httpServer{
-		gRPCEndpoint: endpoint.Map,
-		mux:          mux,
-		serve:        serve,
-		shutdown:     shutdown,
-		endpoint:     endpoint.Address,
-	}, nil
+	h.serve = serve
+	h.shutdown = shutdown
+	return h, nil
 }
 
 func (srv *server) exit() {
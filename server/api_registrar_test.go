@@ -7,6 +7,8 @@ import (
 	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -196,6 +198,13 @@ func TestApiRegistrar_RegisterHTTPHandler(t *testing.T) {
 		return nil
 	}))
 
+	// test unix socket with relative path
+	reg.httpServers[0].gRPCEndpoint = "unix://./gmqttd.sock"
+	a.NoError(reg.RegisterHTTPHandler(func(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
+		a.Equal("unix:./gmqttd.sock", endpoint)
+		return nil
+	}))
+
 	// test tcp socket
 	reg = &apiRegistrar{
 		httpServers: []*httpServer{
@@ -218,6 +227,61 @@ func TestApiRegistrar_RegisterHTTPHandler(t *testing.T) {
 	}))
 }
 
+func TestApiRegistrar_RegisterInterceptorsAndMiddlewares(t *testing.T) {
+	a := assert.New(t)
+	g := &gRPCServer{}
+	h := &httpServer{
+		mux: runtime.NewServeMux(),
+	}
+	reg := &apiRegistrar{
+		gRPCServers: []*gRPCServer{g},
+		httpServers: []*httpServer{h},
+	}
+	var calls []string
+	unary := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	reg.RegisterUnaryInterceptor(unary("1"))
+	reg.RegisterUnaryInterceptor(unary("2"))
+	resp, err := g.unaryInterceptor(context.Background(), "req", &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls = append(calls, "handler")
+		return req, nil
+	})
+	a.NoError(err)
+	a.Equal("req", resp)
+	a.Equal([]string{"1", "2", "handler"}, calls)
+
+	calls = nil
+	reg.RegisterStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		calls = append(calls, "stream")
+		return handler(srv, ss)
+	})
+	a.NoError(g.streamInterceptor(nil, nil, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		calls = append(calls, "handler")
+		return nil
+	}))
+	a.Equal([]string{"stream", "handler"}, calls)
+
+	calls = nil
+	middleware := func(name string) HTTPMiddleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	reg.RegisterHTTPMiddleware(middleware("1"))
+	reg.RegisterHTTPMiddleware(middleware("2"))
+	rec := httptest.NewRecorder()
+	h.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	a.Equal([]string{"1", "2"}, calls)
+	a.Equal(http.StatusNotFound, rec.Code)
+}
+
 func TestBuildTLSConfig(t *testing.T) {
 
 	t.Run("verify_false", func(t *testing.T) {
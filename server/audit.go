@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+var (
+	auditSinkFactories = make(map[string]NewAuditSink)
+)
+
+// RegisterAuditSinkFactory registers the audit sink factory, the sink is selected by the audit.sink configuration.
+func RegisterAuditSinkFactory(name string, new NewAuditSink) {
+	if _, ok := auditSinkFactories[name]; ok {
+		panic("duplicated audit sink factory: " + name)
+	}
+	auditSinkFactories[name] = new
+}
+
+// Results of the audit records.
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditActorAnonymous is the actor of the API requests when the API authentication is disabled.
+const AuditActorAnonymous = "anonymous"
+
+// auditBufferSize is the size of the buffer between the callers and the sink.
+const auditBufferSize = 4096
+
+// ErrAuditQueryNotSupported is returned by AuditLogger.Query if the sink does not implement AuditQuerier.
+var ErrAuditQueryNotSupported = errors.New("the audit sink does not support query")
+
+// AuditRecord is the record of an administrative or privileged operation.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Actor is who performs the operation, e.g. the admin user, the API token name or the MQTT username.
+	Actor string `json:"actor"`
+	// SourceIP is the IP address where the operation comes from.
+	SourceIP string `json:"source_ip,omitempty"`
+	// Operation is the name of the operation, e.g. admin.client.delete, auth.account.update.
+	Operation string `json:"operation"`
+	// Target is the object of the operation, e.g. the client id, the username or the topic name.
+	Target string `json:"target,omitempty"`
+	// Result is either AuditResultSuccess or AuditResultFailure.
+	Result string `json:"result"`
+	// Error is the error message if the operation fails.
+	Error string `json:"error,omitempty"`
+	// Details are the additional information of the operation.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// AuditQuery is the query conditions of the audit records, the empty conditions are ignored.
+type AuditQuery struct {
+	Actor     string
+	Operation string
+	Target    string
+	Result    string
+	// Since and Until limit the time range of the records, both are inclusive.
+	Since time.Time
+	Until time.Time
+	// Offset and Limit paginate the matched records which are sorted by time in descending order.
+	Offset int
+	Limit  int
+}
+
+// Match returns whether the record matches the query conditions.
+// The operation condition matches the operation itself and the operations prefixed by it, e.g. "admin" matches "admin.client.delete".
+func (q *AuditQuery) Match(r *AuditRecord) bool {
+	if q.Actor != "" && q.Actor != r.Actor {
+		return false
+	}
+	if q.Operation != "" && q.Operation != r.Operation && !strings.HasPrefix(r.Operation, q.Operation+".") {
+		return false
+	}
+	if q.Target != "" && q.Target != r.Target {
+		return false
+	}
+	if q.Result != "" && q.Result != r.Result {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && r.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// NewAuditSink is the factory of the AuditSink.
+type NewAuditSink func(config config.Config) (AuditSink, error)
+
+// AuditSink persists the audit records.
+// Write is called in a single goroutine.
+type AuditSink interface {
+	Write(record *AuditRecord) error
+	Close() error
+}
+
+// AuditQuerier is an optional interface of the AuditSink to support querying the records.
+type AuditQuerier interface {
+	// Query returns the records in the page and the total number of the matched records.
+	Query(query AuditQuery) (records []*AuditRecord, total int, err error)
+}
+
+// AuditLogger records the administrative and privileged operations.
+type AuditLogger interface {
+	// Audit records the operation asynchronously, it never blocks.
+	// Time and Result are filled if they are empty.
+	Audit(record *AuditRecord)
+	// Query queries the records that have been written to the sink.
+	// Return ErrAuditQueryNotSupported if the sink does not support query.
+	Query(query AuditQuery) (records []*AuditRecord, total int, err error)
+}
+
+type auditManager struct {
+	sink    AuditSink
+	records chan *AuditRecord
+	wg      sync.WaitGroup
+	// mu guards closed to avoid sending on the closed records channel.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAuditManager(sink AuditSink) *auditManager {
+	a := &auditManager{
+		sink:    sink,
+		records: make(chan *AuditRecord, auditBufferSize),
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+func (a *auditManager) run() {
+	defer a.wg.Done()
+	for r := range a.records {
+		if err := a.sink.Write(r); err != nil {
+			zaplog.Error("failed to write audit record", append(auditRecordFields(r), zap.Error(err))...)
+		}
+	}
+}
+
+// Audit implements AuditLogger interface.
+func (a *auditManager) Audit(record *AuditRecord) {
+	if a == nil {
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.Result == "" {
+		if record.Error == "" {
+			record.Result = AuditResultSuccess
+		} else {
+			record.Result = AuditResultFailure
+		}
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		zaplog.Warn("audit logger is closed, the record is dropped", auditRecordFields(record)...)
+		return
+	}
+	select {
+	case a.records <- record:
+	default:
+		zaplog.Error("audit buffer is full, the record is dropped", auditRecordFields(record)...)
+	}
+}
+
+// Query implements AuditLogger interface.
+func (a *auditManager) Query(query AuditQuery) (records []*AuditRecord, total int, err error) {
+	if a == nil {
+		return nil, 0, ErrAuditQueryNotSupported
+	}
+	if q, ok := a.sink.(AuditQuerier); ok {
+		return q.Query(query)
+	}
+	return nil, 0, ErrAuditQueryNotSupported
+}
+
+// close flushes the buffered records and closes the sink.
+// The records passed to Audit after close are dropped.
+func (a *auditManager) close() error {
+	a.mu.Lock()
+	a.closed = true
+	close(a.records)
+	a.mu.Unlock()
+	a.wg.Wait()
+	return a.sink.Close()
+}
+
+func auditRecordFields(r *AuditRecord) []zap.Field {
+	return []zap.Field{
+		zap.String("actor", r.Actor),
+		zap.String("source_ip", r.SourceIP),
+		zap.String("operation", r.Operation),
+		zap.String("target", r.Target),
+		zap.String("result", r.Result),
+		zap.String("error", r.Error),
+	}
+}
+
+type auditActorKey struct{}
+
+type auditSourceIPKey struct{}
+
+// WithAuditActor returns a copy of ctx which carries the actor of the API request.
+// The API authenticators (e.g. the admin plugin) use it to pass the authenticated identity to the API handlers.
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// WithAuditSourceIP returns a copy of ctx which carries the source IP of the API request,
+// e.g. the IP observed by the node which forwards the request to this node.
+func WithAuditSourceIP(ctx context.Context, sourceIP string) context.Context {
+	return context.WithValue(ctx, auditSourceIPKey{}, sourceIP)
+}
+
+// AuditSourceFromContext returns the actor and the source IP of the gRPC API request.
+// The actor is AuditActorAnonymous if it is not set by WithAuditActor.
+// The source IP set by WithAuditSourceIP takes precedence,
+// for the requests forwarded by the HTTP gateway, the source IP is the remote address observed by the gateway.
+func AuditSourceFromContext(ctx context.Context) (actor, sourceIP string) {
+	actor, _ = ctx.Value(auditActorKey{}).(string)
+	if actor == "" {
+		actor = AuditActorAnonymous
+	}
+	if ip, ok := ctx.Value(auditSourceIPKey{}).(string); ok {
+		return actor, ip
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if fwd := md.Get("x-forwarded-for"); len(fwd) != 0 {
+			ips := strings.Split(fwd[len(fwd)-1], ",")
+			return actor, strings.TrimSpace(ips[len(ips)-1])
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return actor, AuditSourceIP(p.Addr)
+	}
+	return actor, ""
+}
+
+// AuditSourceIP returns the IP of the address, or the address itself if it has no port (e.g. unix sockets).
+func AuditSourceIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type testAuditSink struct {
+	mu      sync.Mutex
+	records []*AuditRecord
+	closed  bool
+}
+
+func (t *testAuditSink) Write(record *AuditRecord) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = append(t.records, record)
+	return nil
+}
+
+func (t *testAuditSink) Close() error {
+	t.closed = true
+	return nil
+}
+
+func TestAuditManager(t *testing.T) {
+	a := assert.New(t)
+	sink := &testAuditSink{}
+	am := newAuditManager(sink)
+	am.Audit(&AuditRecord{Actor: "admin", Operation: "admin.client.delete", Target: "cid"})
+	am.Audit(&AuditRecord{Actor: "admin", Operation: "admin.client.delete", Target: "cid2", Error: "not found"})
+	_, _, err := am.Query(AuditQuery{})
+	a.Equal(ErrAuditQueryNotSupported, err)
+	a.NoError(am.close())
+	a.True(sink.closed)
+
+	a.Len(sink.records, 2)
+	a.False(sink.records[0].Time.IsZero())
+	a.Equal(AuditResultSuccess, sink.records[0].Result)
+	a.Equal(AuditResultFailure, sink.records[1].Result)
+
+	// dropped after closed
+	am.Audit(&AuditRecord{Actor: "admin"})
+	a.Len(sink.records, 2)
+
+	// nil manager is a no-op
+	var nilManager *auditManager
+	nilManager.Audit(&AuditRecord{})
+	_, _, err = nilManager.Query(AuditQuery{})
+	a.Error(err)
+}
+
+func TestAuditSourceFromContext(t *testing.T) {
+	a := assert.New(t)
+	actor, ip := AuditSourceFromContext(context.Background())
+	a.Equal(AuditActorAnonymous, actor)
+	a.Empty(ip)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})
+	ctx = WithAuditActor(ctx, "admin")
+	actor, ip = AuditSourceFromContext(ctx)
+	a.Equal("admin", actor)
+	a.Equal("10.0.0.1", ip)
+
+	// forwarded by the gateway
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "1.1.1.1, 192.168.1.2"))
+	_, ip = AuditSourceFromContext(ctx)
+	a.Equal("192.168.1.2", ip)
+
+	a.Equal("/tmp/gmqttd.sock", AuditSourceIP(&net.UnixAddr{Name: "/tmp/gmqttd.sock", Net: "unix"}))
+}
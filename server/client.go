@@ -18,6 +18,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
@@ -311,8 +313,21 @@ func (client *client) writeLoop() {
 		case <-client.close:
 			return
 		case packet := <-client.out:
+			// topic is the topic name before replacing it with the topic alias, it is only set when tracing is enabled.
+			var topic string
+			ctx := context.Background()
+			var span trace.Span
+			if tp, ok := packet.(*tracedPublish); ok {
+				packet = tp.Publish
+				ctx, span = srv.otel.start(tp.ctx, spanSend,
+					trace.WithSpanKind(trace.SpanKindProducer),
+					trace.WithAttributes(attrClientID.String(client.opts.ClientID), attrTopic.String(string(tp.TopicName)), attrQoS.Int(int(tp.Qos))))
+			}
 			switch p := packet.(type) {
 			case *packets.Publish:
+				if srv.traceManager.enabled() {
+					topic = string(p.TopicName)
+				}
 				if client.version == packets.Version5 {
 					if client.opts.ClientTopicAliasMax > 0 {
 						// use alias if exist
@@ -329,7 +344,7 @@ func (client *client) writeLoop() {
 				}
 				// OnDelivered hook
 				if srv.hooks.OnDelivered != nil {
-					srv.hooks.OnDelivered(context.Background(), client, gmqtt.MessageFromPublish(p))
+					srv.hooks.OnDelivered(ctx, client, gmqtt.MessageFromPublish(p))
 				}
 				srv.statsManager.messageSent(p.Qos, client.opts.ClientID)
 			case *packets.Puback, *packets.Pubcomp:
@@ -342,9 +357,15 @@ func (client *client) writeLoop() {
 				}
 			}
 			err = client.writePacket(packet)
+			if span != nil {
+				endSpan(span, err)
+			}
 			if err != nil {
 				return
 			}
+			if srv.traceManager.enabled() {
+				client.tracePacket(TracePacketSent, packet, topic)
+			}
 			srv.statsManager.packetSent(packet, client.opts.ClientID)
 			if _, ok := packet.(*packets.Disconnect); ok {
 				_ = client.rwc.Close()
@@ -355,7 +376,7 @@ func (client *client) writeLoop() {
 }
 
 func (client *client) writePacket(packet packets.Packet) error {
-	if client.server.config.Log.DumpPacket {
+	if client.server.isDumpPacket() {
 		if ce := zaplog.Check(zapcore.DebugLevel, "sending packet"); ce != nil {
 			ce.Write(
 				zap.String("packet", packet.String()),
@@ -421,10 +442,18 @@ func (client *client) readLoop() {
 				}
 			}
 		}
+		// trace the CONNECT packet before the connection is established, so that the rejected connections are traced too.
+		_, isConnect := packet.(*packets.Connect)
+		if isConnect && srv.traceManager.enabled() {
+			client.tracePacket(TracePacketReceived, packet, "")
+		}
 		client.in <- packet
 		<-client.connected
+		if !isConnect && srv.traceManager.enabled() {
+			client.tracePacket(TracePacketReceived, packet, "")
+		}
 		srv.statsManager.packetReceived(packet, client.opts.ClientID)
-		if client.server.config.Log.DumpPacket {
+		if client.server.isDumpPacket() {
 			if ce := zaplog.Check(zapcore.DebugLevel, "received packet"); ce != nil {
 				ce.Write(
 					zap.String("packet", packet.String()),
@@ -537,6 +566,14 @@ func (client *client) connectWithTimeOut() (ok bool) {
 	var authOpts *AuthOptions
 	// for enhanced auth
 	var onAuth OnAuth
+	// ctx is the context of the connect span, which starts on receiving the CONNECT packet.
+	ctx := context.Background()
+	var span trace.Span
+	defer func() {
+		if span != nil {
+			endSpan(span, err)
+		}
+	}()
 
 	for {
 		select {
@@ -553,8 +590,16 @@ func (client *client) connectWithTimeOut() (ok bool) {
 					break
 				}
 				conn = p.(*packets.Connect)
+				if srv := client.server; srv.otel.enabled() {
+					if conn.Version == packets.Version5 {
+						ctx = srv.otel.extract(ctx, conn.Properties.User)
+					}
+					ctx, span = srv.otel.start(ctx, spanConnect,
+						trace.WithSpanKind(trace.SpanKindServer),
+						trace.WithAttributes(attrClientID.String(string(conn.ClientID)), attrUsername.String(string(conn.Username))))
+				}
 				var resp *EnhancedAuthResponse
-				authOpts, resp, err = client.connectHandler(conn)
+				authOpts, resp, err = client.connectHandler(ctx, conn)
 				if err != nil {
 					break
 				}
@@ -581,7 +626,7 @@ func (client *client) connectWithTimeOut() (ok bool) {
 				}
 
 				var authResp *AuthResponse
-				authResp, err = client.authHandler(au, authOpts, onAuth)
+				authResp, err = client.authHandler(ctx, au, authOpts, onAuth)
 				if err != nil {
 					break
 				}
@@ -599,6 +644,9 @@ func (client *client) connectWithTimeOut() (ok bool) {
 			}
 			// authentication fail
 			if err != nil {
+				if client.server.traceManager.enabled() {
+					client.traceConnectRejected(conn, err)
+				}
 				sendErrConnack(client, err)
 				return
 			}
@@ -679,8 +727,13 @@ func (client *client) connectWithTimeOut() (ok bool) {
 			client.newPacketIDLimiter(client.opts.MaxInflight)
 
 			var sessionResume bool
+			_, regSpan := client.server.otel.start(ctx, spanSessionRegister)
 			sessionResume, err = client.register(conn, client)
+			endSpan(regSpan, err)
 			if err != nil {
+				if client.server.traceManager.enabled() {
+					client.traceConnectRejected(conn, err)
+				}
 				sendErrConnack(client, err)
 				return
 			}
@@ -697,10 +750,10 @@ func (client *client) connectWithTimeOut() (ok bool) {
 	}
 }
 
-func (client *client) basicAuth(conn *packets.Connect, authOpts *AuthOptions) (err error) {
+func (client *client) basicAuth(ctx context.Context, conn *packets.Connect, authOpts *AuthOptions) (err error) {
 	srv := client.server
 	if srv.hooks.OnBasicAuth != nil {
-		err = srv.hooks.OnBasicAuth(context.Background(), client, &ConnectRequest{
+		err = srv.hooks.OnBasicAuth(ctx, client, &ConnectRequest{
 			Connect: conn,
 			Options: authOpts,
 		})
@@ -709,13 +762,13 @@ func (client *client) basicAuth(conn *packets.Connect, authOpts *AuthOptions) (e
 	return err
 }
 
-func (client *client) enhancedAuth(conn *packets.Connect, authOpts *AuthOptions) (resp *EnhancedAuthResponse, err error) {
+func (client *client) enhancedAuth(ctx context.Context, conn *packets.Connect, authOpts *AuthOptions) (resp *EnhancedAuthResponse, err error) {
 	srv := client.server
 	if srv.hooks.OnEnhancedAuth == nil {
 		return nil, errors.New("OnEnhancedAuth hook is nil")
 	}
 
-	resp, err = srv.hooks.OnEnhancedAuth(context.Background(), client, &ConnectRequest{
+	resp, err = srv.hooks.OnEnhancedAuth(ctx, client, &ConnectRequest{
 		Connect: conn,
 		Options: authOpts,
 	})
@@ -725,7 +778,7 @@ func (client *client) enhancedAuth(conn *packets.Connect, authOpts *AuthOptions)
 	return resp, err
 }
 
-func (client *client) connectHandler(conn *packets.Connect) (authOpts *AuthOptions, enhancedResp *EnhancedAuthResponse, err error) {
+func (client *client) connectHandler(ctx context.Context, conn *packets.Connect) (authOpts *AuthOptions, enhancedResp *EnhancedAuthResponse, err error) {
 	if !client.config.MQTT.AllowZeroLenClientID && len(conn.ClientID) == 0 {
 		err = &codes.Error{
 			Code: codes.ClientIdentifierNotValid,
@@ -737,17 +790,17 @@ func (client *client) connectHandler(conn *packets.Connect) (authOpts *AuthOptio
 	authOpts = client.defaultAuthOptions(conn)
 
 	if packets.IsVersion3X(client.version) || (packets.IsVersion5(client.version) && conn.Properties.AuthMethod == nil) {
-		err = client.basicAuth(conn, authOpts)
+		err = client.basicAuth(ctx, conn, authOpts)
 	}
 	if client.version == packets.Version5 && conn.Properties.AuthMethod != nil {
-		enhancedResp, err = client.enhancedAuth(conn, authOpts)
+		enhancedResp, err = client.enhancedAuth(ctx, conn, authOpts)
 	}
 
 	return
 }
 
-func (client *client) authHandler(auth *packets.Auth, authOpts *AuthOptions, onAuth OnAuth) (resp *AuthResponse, err error) {
-	authResp, err := onAuth(context.Background(), client, &AuthRequest{
+func (client *client) authHandler(ctx context.Context, auth *packets.Auth, authOpts *AuthOptions, onAuth OnAuth) (resp *AuthResponse, err error) {
+	authResp, err := onAuth(ctx, client, &AuthRequest{
 		Auth:    auth,
 		Options: authOpts,
 	})
@@ -865,6 +918,11 @@ func (client *client) subscribeHandler(sub *packets.Subscribe) *codes.Error {
 	if srv.hooks.OnSubscribe != nil {
 		err := srv.hooks.OnSubscribe(context.Background(), client, subReq)
 		if ce := converError(err); ce != nil {
+			if srv.traceManager.enabled() {
+				for _, v := range sub.Topics {
+					client.traceDecision(DecisionSubscribeRejected, v.Name, nil, err)
+				}
+			}
 			suback.Properties = getErrorProperties(client, &ce.ErrorDetails)
 			for k := range suback.Payload {
 				if packets.IsVersion3X(client.version) {
@@ -905,6 +963,9 @@ func (client *client) subscribeHandler(sub *packets.Subscribe) *codes.Error {
 		var subRs subscription.SubscribeResult
 		var err error
 		if subErr != nil {
+			if srv.traceManager.enabled() {
+				client.traceDecision(DecisionSubscribeRejected, v.Name, nil, subErr)
+			}
 			code = subErr.Code
 			if packets.IsVersion3X(client.version) {
 				code = packets.SubscribeFailure
@@ -1019,8 +1080,19 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 
 	}
 
+	ctx := context.Background()
+	if srv.otel.enabled() {
+		var span trace.Span
+		ctx, span = srv.otel.start(srv.otel.extract(ctx, msg.UserProperties), spanPublish,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attrClientID.String(client.opts.ClientID), attrTopic.String(msg.Topic), attrQoS.Int(int(pub.Qos))))
+		defer span.End()
+	}
+
 	if pub.Qos == packets.Qos2 {
+		_, span := srv.otel.start(ctx, spanUnackSet)
 		exist, err := client.unackStore.Set(pub.PacketID)
+		endSpan(span, err)
 		if err != nil {
 			return converError(err)
 		}
@@ -1030,11 +1102,13 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 	}
 
 	if pub.Retain {
+		_, span := srv.otel.start(ctx, spanRetainedStore)
 		if len(pub.Payload) == 0 {
 			srv.retainedDB.Remove(string(pub.TopicName))
 		} else {
 			srv.retainedDB.AddOrReplace(msg.Copy())
 		}
+		span.End()
 	}
 
 	var err error
@@ -1047,14 +1121,30 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 				Message:          msg,
 				IterationOptions: opts,
 			}
-			err = srv.hooks.OnMsgArrived(context.Background(), client, req)
+			err = srv.hooks.OnMsgArrived(ctx, client, req)
+			if srv.traceManager.enabled() {
+				if err != nil {
+					client.traceDecision(DecisionPublishRejected, msg.Topic, msg, err)
+				} else if req.Message == nil {
+					client.traceDecision(DecisionPublishDiscarded, msg.Topic, msg, nil)
+				}
+			}
 			msg = req.Message
 			opts = req.IterationOptions
 		}
 		if msg != nil && err == nil {
+			// propagate the trace context to the subscribers.
+			srv.otel.inject(ctx, &msg.UserProperties)
 			topicMatched = client.deliverMessage(client.opts.ClientID, msg, opts)
 		}
 	}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(attrMatched.Bool(topicMatched))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+	}
 
 	var ack packets.Packet
 	// ack properties
@@ -1189,6 +1279,11 @@ func (client *client) unsubscribeHandler(unSub *packets.Unsubscribe) {
 	if srv.hooks.OnUnsubscribe != nil {
 		err := srv.hooks.OnUnsubscribe(context.Background(), client, req)
 		if ce := converError(err); ce != nil {
+			if srv.traceManager.enabled() {
+				for _, v := range unSub.Topics {
+					client.traceDecision(DecisionUnsubscribeRejected, v, nil, err)
+				}
+			}
 			unSuback.Properties = getErrorProperties(client, &ce.ErrorDetails)
 			for k := range cs {
 				cs[k] = ce.Code
@@ -1201,6 +1296,9 @@ func (client *client) unsubscribeHandler(unSub *packets.Unsubscribe) {
 		topicName := req.Unsubs[v].TopicName
 		ce := converError(req.Unsubs[v].Error)
 		if ce != nil {
+			if srv.traceManager.enabled() {
+				client.traceDecision(DecisionUnsubscribeRejected, v, nil, ce)
+			}
 			code = ce.Code
 		}
 		if code == codes.Success {
@@ -1378,7 +1476,7 @@ func (client *client) pollInflights() (cont bool, err error) {
 			// The Server need not use the same set of Subscription Identifiers in the retransmitted PUBLISH packet.
 			m.SubscriptionIdentifier = nil
 			client.pl.markUsedLocked(id)
-			client.write(gmqtt.MessageToPublish(m.Message, client.version))
+			client.writePublish(m.Message)
 		case *queue.Pubrel:
 			client.write(&packets.Pubrel{PacketID: id})
 		}
@@ -1387,6 +1485,20 @@ func (client *client) pollInflights() (cont bool, err error) {
 	return true, nil
 }
 
+// writePublish writes the message to the client,
+// the trace context of the message is passed along with the PUBLISH packet if OpenTelemetry tracing is enabled.
+func (client *client) writePublish(msg *gmqtt.Message) {
+	pub := gmqtt.MessageToPublish(msg, client.version)
+	if srv := client.server; srv.otel.enabled() {
+		client.write(&tracedPublish{
+			Publish: pub,
+			ctx:     srv.otel.extract(context.Background(), msg.UserProperties),
+		})
+		return
+	}
+	client.write(pub)
+}
+
 func (client *client) pollNewMessages(ids []packets.PacketID) (unused []packets.PacketID, err error) {
 	now := time.Now()
 	var elems []*queue.Elem
@@ -1404,7 +1516,7 @@ func (client *client) pollNewMessages(ids []packets.PacketID) (unused []packets.
 				d := uint32(now.Sub(v.At).Seconds())
 				m.Message.MessageExpiry = d
 			}
-			client.write(gmqtt.MessageToPublish(m.Message, client.version))
+			client.writePublish(m.Message)
 		case *queue.Pubrel:
 		}
 	}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// The hook names passed to HookObserver.
+const (
+	HookOnAccept            = "OnAccept"
+	HookOnStop              = "OnStop"
+	HookOnSubscribe         = "OnSubscribe"
+	HookOnSubscribed        = "OnSubscribed"
+	HookOnUnsubscribe       = "OnUnsubscribe"
+	HookOnUnsubscribed      = "OnUnsubscribed"
+	HookOnMsgArrived        = "OnMsgArrived"
+	HookOnBasicAuth         = "OnBasicAuth"
+	HookOnEnhancedAuth      = "OnEnhancedAuth"
+	HookOnReAuth            = "OnReAuth"
+	HookOnConnected         = "OnConnected"
+	HookOnSessionCreated    = "OnSessionCreated"
+	HookOnSessionResumed    = "OnSessionResumed"
+	HookOnSessionTerminated = "OnSessionTerminated"
+	HookOnDelivered         = "OnDelivered"
+	HookOnClosed            = "OnClosed"
+	HookOnMsgDropped        = "OnMsgDropped"
+	HookOnWillPublish       = "OnWillPublish"
+	HookOnWillPublished     = "OnWillPublished"
+)
+
+type hookObservers []HookObserver
+
+func (o hookObservers) observe(hook string, start time.Time) {
+	d := time.Since(start)
+	for _, v := range o {
+		v.ObserveHook(hook, d)
+	}
+}
+
+// observe wraps all hooks which are set, to report the time spent in the hooks to the observers.
+func (h *Hooks) observe(observers []HookObserver) {
+	o := hookObservers(observers)
+	if fn := h.OnAccept; fn != nil {
+		h.OnAccept = func(ctx context.Context, conn net.Conn) bool {
+			defer o.observe(HookOnAccept, time.Now())
+			return fn(ctx, conn)
+		}
+	}
+	if fn := h.OnStop; fn != nil {
+		h.OnStop = func(ctx context.Context) {
+			defer o.observe(HookOnStop, time.Now())
+			fn(ctx)
+		}
+	}
+	if fn := h.OnSubscribe; fn != nil {
+		h.OnSubscribe = func(ctx context.Context, client Client, req *SubscribeRequest) error {
+			defer o.observe(HookOnSubscribe, time.Now())
+			return fn(ctx, client, req)
+		}
+	}
+	if fn := h.OnSubscribed; fn != nil {
+		h.OnSubscribed = func(ctx context.Context, client Client, subscription *gmqtt.Subscription) {
+			defer o.observe(HookOnSubscribed, time.Now())
+			fn(ctx, client, subscription)
+		}
+	}
+	if fn := h.OnUnsubscribe; fn != nil {
+		h.OnUnsubscribe = func(ctx context.Context, client Client, req *UnsubscribeRequest) error {
+			defer o.observe(HookOnUnsubscribe, time.Now())
+			return fn(ctx, client, req)
+		}
+	}
+	if fn := h.OnUnsubscribed; fn != nil {
+		h.OnUnsubscribed = func(ctx context.Context, client Client, topicName string) {
+			defer o.observe(HookOnUnsubscribed, time.Now())
+			fn(ctx, client, topicName)
+		}
+	}
+	if fn := h.OnMsgArrived; fn != nil {
+		h.OnMsgArrived = func(ctx context.Context, client Client, req *MsgArrivedRequest) error {
+			defer o.observe(HookOnMsgArrived, time.Now())
+			return fn(ctx, client, req)
+		}
+	}
+	if fn := h.OnBasicAuth; fn != nil {
+		h.OnBasicAuth = func(ctx context.Context, client Client, req *ConnectRequest) error {
+			defer o.observe(HookOnBasicAuth, time.Now())
+			return fn(ctx, client, req)
+		}
+	}
+	if fn := h.OnEnhancedAuth; fn != nil {
+		h.OnEnhancedAuth = func(ctx context.Context, client Client, req *ConnectRequest) (*EnhancedAuthResponse, error) {
+			defer o.observe(HookOnEnhancedAuth, time.Now())
+			return fn(ctx, client, req)
+		}
+	}
+	if fn := h.OnReAuth; fn != nil {
+		h.OnReAuth = func(ctx context.Context, client Client, auth *packets.Auth) (*AuthResponse, error) {
+			defer o.observe(HookOnReAuth, time.Now())
+			return fn(ctx, client, auth)
+		}
+	}
+	if fn := h.OnConnected; fn != nil {
+		h.OnConnected = func(ctx context.Context, client Client) {
+			defer o.observe(HookOnConnected, time.Now())
+			fn(ctx, client)
+		}
+	}
+	if fn := h.OnSessionCreated; fn != nil {
+		h.OnSessionCreated = func(ctx context.Context, client Client) {
+			defer o.observe(HookOnSessionCreated, time.Now())
+			fn(ctx, client)
+		}
+	}
+	if fn := h.OnSessionResumed; fn != nil {
+		h.OnSessionResumed = func(ctx context.Context, client Client) {
+			defer o.observe(HookOnSessionResumed, time.Now())
+			fn(ctx, client)
+		}
+	}
+	if fn := h.OnSessionTerminated; fn != nil {
+		h.OnSessionTerminated = func(ctx context.Context, clientID string, reason SessionTerminatedReason) {
+			defer o.observe(HookOnSessionTerminated, time.Now())
+			fn(ctx, clientID, reason)
+		}
+	}
+	if fn := h.OnDelivered; fn != nil {
+		h.OnDelivered = func(ctx context.Context, client Client, msg *gmqtt.Message) {
+			defer o.observe(HookOnDelivered, time.Now())
+			fn(ctx, client, msg)
+		}
+	}
+	if fn := h.OnClosed; fn != nil {
+		h.OnClosed = func(ctx context.Context, client Client, err error) {
+			defer o.observe(HookOnClosed, time.Now())
+			fn(ctx, client, err)
+		}
+	}
+	if fn := h.OnMsgDropped; fn != nil {
+		h.OnMsgDropped = func(ctx context.Context, clientID string, msg *gmqtt.Message, err error) {
+			defer o.observe(HookOnMsgDropped, time.Now())
+			fn(ctx, clientID, msg, err)
+		}
+	}
+	if fn := h.OnWillPublish; fn != nil {
+		h.OnWillPublish = func(ctx context.Context, clientID string, req *WillMsgRequest) {
+			defer o.observe(HookOnWillPublish, time.Now())
+			fn(ctx, clientID, req)
+		}
+	}
+	if fn := h.OnWillPublished; fn != nil {
+		h.OnWillPublished = func(ctx context.Context, clientID string, msg *gmqtt.Message) {
+			defer o.observe(HookOnWillPublished, time.Now())
+			fn(ctx, clientID, msg)
+		}
+	}
+}
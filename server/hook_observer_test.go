@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHookObserver struct {
+	observed []string
+}
+
+func (t *testHookObserver) ObserveHook(hook string, d time.Duration) {
+	t.observed = append(t.observed, hook)
+}
+
+func TestHooks_observe(t *testing.T) {
+	a := assert.New(t)
+	e := errors.New("deny")
+	h := &Hooks{
+		OnBasicAuth: func(ctx context.Context, client Client, req *ConnectRequest) error {
+			return e
+		},
+		OnMsgArrived: func(ctx context.Context, client Client, req *MsgArrivedRequest) error {
+			return nil
+		},
+	}
+	o1, o2 := &testHookObserver{}, &testHookObserver{}
+	h.observe([]HookObserver{o1, o2})
+	// the hooks which are not set remain nil.
+	a.Nil(h.OnSubscribe)
+	a.Nil(h.OnDelivered)
+
+	a.Equal(e, h.OnBasicAuth(context.Background(), nil, nil))
+	a.Nil(h.OnMsgArrived(context.Background(), nil, nil))
+	a.Nil(h.OnMsgArrived(context.Background(), nil, nil))
+	a.Equal([]string{HookOnBasicAuth, HookOnMsgArrived, HookOnMsgArrived}, o1.observed)
+	a.Equal(o1.observed, o2.observed)
+}
@@ -0,0 +1,239 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// Listener is a TCP, websocket or MQTT-SN listener created from the listener configuration.
+// Unlike the listeners set by WithTCPListener and WithWebsocketServer,
+// the listeners set by WithListeners are added and removed when the listeners configuration is reloaded.
+type Listener struct {
+	config config.ListenerConfig
+	ln     net.Listener
+	ws     *WsServer
+	closed int32
+}
+
+// NewListener binds the address of the listener configuration.
+func NewListener(c config.ListenerConfig) (*Listener, error) {
+	if c.MQTTSN != nil {
+		gw, err := newMQTTSNGateway(c.Address, *c.MQTTSN)
+		if err != nil {
+			return nil, err
+		}
+		return &Listener{
+			config: c,
+			ln:     gw,
+		}, nil
+	}
+	tlsConfig, err := listenerTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return listen(c, tlsConfig)
+}
+
+// listenerTLSConfig loads the certificate of the listener configuration, it returns nil if tls is not enabled.
+func listenerTLSConfig(c config.ListenerConfig) (*tls.Config, error) {
+	if c.TLSOptions == nil || c.MQTTSN != nil {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}, nil
+}
+
+// listen binds the address of the TCP or websocket listener configuration.
+func listen(c config.ListenerConfig, tlsConfig *tls.Config) (*Listener, error) {
+	ln, err := net.Listen("tcp", c.Address)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	l := &Listener{
+		config: c,
+		ln:     ln,
+	}
+	if c.Websocket != nil {
+		l.ws = &WsServer{
+			Server: &http.Server{Addr: c.Address},
+			Path:   c.Websocket.Path,
+		}
+	}
+	return l, nil
+}
+
+// Addr returns the listening address.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Websocket returns whether it is a websocket listener.
+func (l *Listener) Websocket() bool {
+	return l.ws != nil
+}
+
+// MQTTSN returns whether it is a MQTT-SN gateway listener.
+func (l *Listener) MQTTSN() bool {
+	return l.config.MQTTSN != nil
+}
+
+// Close stops accepting new connections, the established connections are not affected,
+// except for the MQTT-SN listener, which closes the connections of its clients.
+func (l *Listener) Close() error {
+	atomic.StoreInt32(&l.closed, 1)
+	if l.ws != nil {
+		// close the http server first, so that Serve returns http.ErrServerClosed.
+		_ = l.ws.Server.Close()
+	}
+	return l.ln.Close()
+}
+
+func (l *Listener) isClosed() bool {
+	return atomic.LoadInt32(&l.closed) == 1
+}
+
+func (srv *server) serveListener(l *Listener) {
+	if l.ws == nil {
+		srv.serveTCP(l.ln)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(l.ws.Path, srv.wsHandler())
+	l.ws.Server.Handler = mux
+	err := l.ws.Server.Serve(l.ln)
+	if err != nil && err != http.ErrServerClosed && !l.isClosed() {
+		srv.setError(fmt.Errorf("serveWebSocket error: %s", err.Error()))
+	}
+}
+
+// startListeners starts serving the listeners set by WithListeners.
+func (srv *server) startListeners() (tcps []string, ws []string, mqttsn []string) {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	srv.listenerServing = true
+	for _, l := range srv.listeners {
+		if l.Websocket() {
+			ws = append(ws, l.Addr().String())
+		} else if l.MQTTSN() {
+			mqttsn = append(mqttsn, l.Addr().String())
+		} else {
+			tcps = append(tcps, l.Addr().String())
+		}
+		go srv.serveListener(l)
+	}
+	return
+}
+
+// closeListeners closes the listeners set by WithListeners, the closed listeners are never reopened.
+func (srv *server) closeListeners() {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	srv.listenerServing = false
+	srv.listenerClosed = true
+	for _, l := range srv.listeners {
+		l.Close()
+	}
+}
+
+// reloadListeners adds and removes the listeners according to the new configuration.
+// A changed listener is replaced by replaceListener. It returns the listeners configuration that takes effect.
+func (srv *server) reloadListeners(old, new []*config.ListenerConfig) ([]*config.ListenerConfig, error) {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	if srv.listeners == nil {
+		return old, fmt.Errorf("the listeners are not created from the configuration, %s", ErrRestartRequired)
+	}
+	if srv.listenerClosed {
+		return old, ErrServerStopped
+	}
+	want := make(map[string]*config.ListenerConfig)
+	for _, v := range new {
+		want[v.Address] = v
+	}
+	for addr, l := range srv.listeners {
+		if _, ok := want[addr]; ok {
+			continue
+		}
+		l.Close()
+		delete(srv.listeners, addr)
+		zaplog.Info("listener removed", zap.String("address", addr))
+	}
+	var errs []error
+	var applied []*config.ListenerConfig
+	for _, c := range new {
+		old := srv.listeners[c.Address]
+		if old != nil && reflect.DeepEqual(*c, old.config) {
+			applied = append(applied, c)
+			continue
+		}
+		l, err := replaceListener(old, *c)
+		if l == nil {
+			delete(srv.listeners, c.Address)
+		} else if l != old {
+			srv.listeners[c.Address] = l
+			if srv.listenerServing {
+				go srv.serveListener(l)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", c.Address, err))
+			if l != nil {
+				applied = append(applied, &l.config)
+			}
+			continue
+		}
+		applied = append(applied, c)
+		zaplog.Info("listener added", zap.String("address", c.Address), zap.Bool("websocket", l.Websocket()), zap.Bool("mqttsn", l.MQTTSN()))
+	}
+	if len(errs) != 0 {
+		return applied, fmt.Errorf("failed to add listeners: %v", errs)
+	}
+	return applied, nil
+}
+
+// replaceListener binds the listener configuration and replaces the old listener on the same address, old can be nil.
+// The certificate is loaded before closing the old listener, so an invalid certificate keeps the old listener serving.
+// If the new listener fails to bind, the old configuration is bound again.
+// It returns the listener that takes effect on the address, which is nil if none is bound.
+func replaceListener(old *Listener, c config.ListenerConfig) (*Listener, error) {
+	if old == nil {
+		return NewListener(c)
+	}
+	tlsConfig, err := listenerTLSConfig(c)
+	if err != nil {
+		return old, err
+	}
+	old.Close()
+	var l *Listener
+	if c.MQTTSN != nil {
+		l, err = NewListener(c)
+	} else {
+		l, err = listen(c, tlsConfig)
+	}
+	if err == nil {
+		return l, nil
+	}
+	restored, rerr := NewListener(old.config)
+	if rerr != nil {
+		zaplog.Error("failed to restore the listener", zap.String("address", c.Address), zap.Error(rerr))
+		return nil, err
+	}
+	zaplog.Warn("failed to bind the new listener, the old one is restored", zap.String("address", c.Address), zap.Error(err))
+	return restored, err
+}
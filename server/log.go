@@ -0,0 +1,250 @@
+package server
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// pluginLogKey is the field key of the plugin name in the plugin loggers, see LoggerWithField.
+const pluginLogKey = "plugin"
+
+var errLoggerNotReloadable = errors.New("the logger is not set by WithLogger, " + ErrRestartRequired.Error())
+
+// LogLevelManager changes the log levels at runtime.
+// The changes are overwritten if the log config is changed on reload.
+type LogLevelManager interface {
+	// Levels returns the global level and the level overrides of the plugins.
+	Levels() (global zapcore.Level, plugins map[string]zapcore.Level, err error)
+	// SetLevel sets the global level if the plugin is empty, otherwise sets the level override of the plugin.
+	SetLevel(plugin string, level zapcore.Level) error
+	// UnsetLevel removes the level override of the plugin, the plugin uses the global level after that.
+	UnsetLevel(plugin string) error
+}
+
+// logLevelManager implements LogLevelManager.
+type logLevelManager struct {
+	srv *server
+}
+
+func (l *logLevelManager) levels() (*logLevels, error) {
+	if l.srv.logCore == nil {
+		return nil, errLoggerNotReloadable
+	}
+	return l.srv.logCore.levels(), nil
+}
+
+func (l *logLevelManager) Levels() (global zapcore.Level, plugins map[string]zapcore.Level, err error) {
+	lv, err := l.levels()
+	if err != nil {
+		return
+	}
+	global = lv.global.Level()
+	plugins = make(map[string]zapcore.Level)
+	for k, v := range lv.pluginLevels() {
+		plugins[k] = v.Level()
+	}
+	return
+}
+
+func (l *logLevelManager) SetLevel(plugin string, level zapcore.Level) error {
+	lv, err := l.levels()
+	if err != nil {
+		return err
+	}
+	lv.set(plugin, level)
+	return nil
+}
+
+func (l *logLevelManager) UnsetLevel(plugin string) error {
+	lv, err := l.levels()
+	if err != nil {
+		return err
+	}
+	lv.unset(plugin)
+	return nil
+}
+
+// logLevels is the global level and the level overrides of the plugins.
+type logLevels struct {
+	global zap.AtomicLevel
+	// mu serializes the updates of plugins.
+	mu sync.Mutex
+	// plugins stores the map[string]zap.AtomicLevel, which is copied on write.
+	plugins atomic.Value
+}
+
+func newLogLevels() *logLevels {
+	l := &logLevels{
+		global: zap.NewAtomicLevelAt(zapcore.InfoLevel),
+	}
+	l.plugins.Store(make(map[string]zap.AtomicLevel))
+	return l
+}
+
+func (l *logLevels) pluginLevels() map[string]zap.AtomicLevel {
+	return l.plugins.Load().(map[string]zap.AtomicLevel)
+}
+
+// enabler returns the level of the plugin, or the global level if the plugin has no override.
+func (l *logLevels) enabler(plugin string) zapcore.LevelEnabler {
+	if plugin != "" {
+		if lv, ok := l.pluginLevels()[plugin]; ok {
+			return lv
+		}
+	}
+	return l.global
+}
+
+func (l *logLevels) set(plugin string, level zapcore.Level) {
+	if plugin == "" {
+		l.global.SetLevel(level)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.pluginLevels()
+	if lv, ok := old[plugin]; ok {
+		lv.SetLevel(level)
+		return
+	}
+	m := make(map[string]zap.AtomicLevel, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[plugin] = zap.NewAtomicLevelAt(level)
+	l.plugins.Store(m)
+}
+
+func (l *logLevels) unset(plugin string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.pluginLevels()
+	m := make(map[string]zap.AtomicLevel, len(old))
+	for k, v := range old {
+		if k != plugin {
+			m[k] = v
+		}
+	}
+	l.plugins.Store(m)
+}
+
+// apply applies the levels of the log config, the overrides which are not in the config are removed.
+func (l *logLevels) apply(c config.LogConfig) error {
+	global, err := config.ParseLogLevel(c.Level)
+	if err != nil {
+		return err
+	}
+	m := make(map[string]zap.AtomicLevel)
+	for k, v := range c.Plugins {
+		lv, err := config.ParseLogLevel(v)
+		if err != nil {
+			return err
+		}
+		m[k] = zap.NewAtomicLevelAt(lv)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.global.SetLevel(global)
+	l.plugins.Store(m)
+	return nil
+}
+
+// logCore is a zapcore.Core which delegates to a replaceable core and filters the logs by the runtime levels.
+// The loggers derived by With() are also affected when the core or the levels are changed,
+// so the loggers held by the plugins follow the changes. The replaceable core is expected to enable all levels.
+type logCore struct {
+	root   *logRoot
+	fields []zapcore.Field
+	// plugin is the value of the plugin field, which selects the level override.
+	plugin string
+	// cache caches the derived core of the current root core.
+	cache atomic.Value
+}
+
+type logRoot struct {
+	mu      sync.RWMutex
+	core    zapcore.Core
+	version int64
+	levels  *logLevels
+}
+
+type cachedCore struct {
+	version int64
+	core    zapcore.Core
+}
+
+func newLogCore(core zapcore.Core) *logCore {
+	return &logCore{
+		root: &logRoot{core: core, levels: newLogLevels()},
+	}
+}
+
+func (c *logCore) levels() *logLevels {
+	return c.root.levels
+}
+
+// swap replaces the root core.
+func (c *logCore) swap(core zapcore.Core) {
+	c.root.mu.Lock()
+	old := c.root.core
+	c.root.core = core
+	c.root.version++
+	c.root.mu.Unlock()
+	_ = old.Sync()
+}
+
+func (c *logCore) current() zapcore.Core {
+	c.root.mu.RLock()
+	core, version := c.root.core, c.root.version
+	c.root.mu.RUnlock()
+	if cc, ok := c.cache.Load().(*cachedCore); ok && cc.version == version {
+		return cc.core
+	}
+	if len(c.fields) != 0 {
+		core = core.With(c.fields)
+	}
+	c.cache.Store(&cachedCore{version: version, core: core})
+	return core
+}
+
+func (c *logCore) Enabled(lvl zapcore.Level) bool {
+	return c.root.levels.enabler(c.plugin).Enabled(lvl)
+}
+
+func (c *logCore) With(fields []zapcore.Field) zapcore.Core {
+	fs := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	fs = append(fs, c.fields...)
+	fs = append(fs, fields...)
+	plugin := c.plugin
+	for _, f := range fields {
+		if f.Key == pluginLogKey && f.Type == zapcore.StringType {
+			plugin = f.String
+		}
+	}
+	return &logCore{
+		root:   c.root,
+		fields: fs,
+		plugin: plugin,
+	}
+}
+
+func (c *logCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.current().Check(ent, ce)
+}
+
+func (c *logCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.current().Write(ent, fields)
+}
+
+func (c *logCore) Sync() error {
+	return c.current().Sync()
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: server/log.go
+
+// Package server is a generated GoMock package.
+package server
+
+import (
+	gomock "github.com/golang/mock/gomock"
+	zapcore "go.uber.org/zap/zapcore"
+	reflect "reflect"
+)
+
+// MockLogLevelManager is a mock of LogLevelManager interface
+type MockLogLevelManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockLogLevelManagerMockRecorder
+}
+
+// MockLogLevelManagerMockRecorder is the mock recorder for MockLogLevelManager
+type MockLogLevelManagerMockRecorder struct {
+	mock *MockLogLevelManager
+}
+
+// NewMockLogLevelManager creates a new mock instance
+func NewMockLogLevelManager(ctrl *gomock.Controller) *MockLogLevelManager {
+	mock := &MockLogLevelManager{ctrl: ctrl}
+	mock.recorder = &MockLogLevelManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockLogLevelManager) EXPECT() *MockLogLevelManagerMockRecorder {
+	return m.recorder
+}
+
+// Levels mocks base method
+func (m *MockLogLevelManager) Levels() (zapcore.Level, map[string]zapcore.Level, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Levels")
+	ret0, _ := ret[0].(zapcore.Level)
+	ret1, _ := ret[1].(map[string]zapcore.Level)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Levels indicates an expected call of Levels
+func (mr *MockLogLevelManagerMockRecorder) Levels() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Levels", reflect.TypeOf((*MockLogLevelManager)(nil).Levels))
+}
+
+// SetLevel mocks base method
+func (m *MockLogLevelManager) SetLevel(plugin string, level zapcore.Level) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLevel", plugin, level)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLevel indicates an expected call of SetLevel
+func (mr *MockLogLevelManagerMockRecorder) SetLevel(plugin, level interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLevel", reflect.TypeOf((*MockLogLevelManager)(nil).SetLevel), plugin, level)
+}
+
+// UnsetLevel mocks base method
+func (m *MockLogLevelManager) UnsetLevel(plugin string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnsetLevel", plugin)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnsetLevel indicates an expected call of UnsetLevel
+func (mr *MockLogLevelManagerMockRecorder) UnsetLevel(plugin interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnsetLevel", reflect.TypeOf((*MockLogLevelManager)(nil).UnsetLevel), plugin)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestLogCore(t *testing.T) {
+	a := assert.New(t)
+	core1, logs1 := observer.New(zapcore.DebugLevel)
+	core := newLogCore(core1)
+	l := zap.New(core)
+	child := l.With(zap.String("plugin", "test"))
+
+	child.Debug("debug1")
+	child.Info("info1")
+	a.Equal(1, logs1.Len())
+	a.Equal("test", logs1.All()[0].ContextMap()["plugin"])
+
+	core2, logs2 := observer.New(zapcore.DebugLevel)
+	core.swap(core2)
+	core.levels().set("test", zapcore.DebugLevel)
+	child.Debug("debug2")
+	l.Debug("debug3")
+	l.Info("info2")
+	a.Equal(1, logs1.Len())
+	a.Equal(2, logs2.Len())
+	a.Equal("debug2", logs2.All()[0].Message)
+	a.Equal("test", logs2.All()[0].ContextMap()["plugin"])
+	a.Empty(logs2.All()[1].ContextMap())
+
+	core.levels().unset("test")
+	core.levels().set("", zapcore.ErrorLevel)
+	child.Debug("debug4")
+	child.Warn("warn1")
+	child.Error("error1")
+	a.Equal(3, logs2.Len())
+	a.Equal("error1", logs2.All()[2].Message)
+}
+
+func TestLogLevelManager(t *testing.T) {
+	a := assert.New(t)
+	defer func(l *zap.Logger) {
+		zaplog = l
+	}(zaplog)
+	c := config.DefaultConfig()
+	c.Log.Level = "warn"
+	c.Log.Plugins = map[string]string{"p1": "debug"}
+	core, logs := observer.New(zapcore.DebugLevel)
+	srv := New(WithConfig(c), WithLogger(zap.New(core)))
+	p1 := LoggerWithField(zap.String("plugin", "p1"))
+	p2 := LoggerWithField(zap.String("plugin", "p2"))
+
+	m := srv.LogLevelManager()
+	global, plugins, err := m.Levels()
+	a.Nil(err)
+	a.Equal(zapcore.WarnLevel, global)
+	a.Equal(map[string]zapcore.Level{"p1": zapcore.DebugLevel}, plugins)
+	p1.Debug("p1")
+	p2.Info("p2")
+	a.Equal(1, logs.Len())
+
+	a.Nil(m.SetLevel("p2", zapcore.InfoLevel))
+	a.Nil(m.UnsetLevel("p1"))
+	a.Nil(m.SetLevel("", zapcore.ErrorLevel))
+	p1.Warn("p1")
+	p2.Info("p2")
+	a.Equal(2, logs.Len())
+	a.Equal("p2", logs.All()[1].Message)
+	global, plugins, err = m.Levels()
+	a.Nil(err)
+	a.Equal(zapcore.ErrorLevel, global)
+	a.Equal(map[string]zapcore.Level{"p2": zapcore.InfoLevel}, plugins)
+
+	// the levels are overwritten by the log config on reload.
+	nc := c
+	nc.Log.Level = "info"
+	nc.Log.Plugins = nil
+	rs, err := srv.ReloadConfig(nc)
+	a.Nil(err)
+	a.Nil(resultsBySection(rs)[ReloadSectionLog].Err)
+	global, plugins, err = m.Levels()
+	a.Nil(err)
+	a.Equal(zapcore.InfoLevel, global)
+	a.Empty(plugins)
+
+	_, _, err = New().LogLevelManager().Levels()
+	a.Equal(errLoggerNotReloadable, err)
+}
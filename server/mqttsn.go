@@ -0,0 +1,297 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/mqttsn"
+)
+
+var errMQTTSNGatewayClosed = errors.New("mqttsn gateway closed")
+
+// mqttsnGateway is a MQTT-SN v1.2 gateway listening on UDP.
+// It implements net.Listener. Each MQTT-SN client is translated into a MQTT 3.1.1 connection over an in-memory pipe,
+// and the server side of the pipe is returned by Accept, so that the client is served like any other TCP client
+// and the hooks (authentication, ACL, etc.) apply unchanged.
+type mqttsnGateway struct {
+	config config.MQTTSNOptions
+	conn   *net.UDPConn
+	accept chan net.Conn
+	// predefined is the predefined topic ids, key by the topic name.
+	predefined map[string]uint16
+	// qosMinusOneNetworks is the networks which are allowed to publish the QoS -1 messages.
+	qosMinusOneNetworks []*net.IPNet
+
+	closeOnce sync.Once
+	close     chan struct{}
+	wg        sync.WaitGroup
+
+	mu sync.Mutex
+	// sessions is the sessions key by the client address.
+	sessions map[string]*snSession
+	// qosMinusOne is the session which publishes the QoS -1 messages on behalf of the clients.
+	qosMinusOne *snSession
+}
+
+func newMQTTSNGateway(address string, c config.MQTTSNOptions) (*mqttsnGateway, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	qosMinusOneNetworks, err := c.GetQoSMinusOneAllowedNetworks()
+	if err != nil {
+		return nil, err
+	}
+	var advertiseAddr *net.UDPAddr
+	if c.AdvertiseAddress != "" {
+		advertiseAddr, err = net.ResolveUDPAddr("udp", c.AdvertiseAddress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	g := &mqttsnGateway{
+		config:              c,
+		conn:                conn,
+		accept:              make(chan net.Conn),
+		predefined:          make(map[string]uint16),
+		qosMinusOneNetworks: qosMinusOneNetworks,
+		close:               make(chan struct{}),
+		sessions:            make(map[string]*snSession),
+	}
+	for id, name := range c.PredefinedTopics {
+		g.predefined[name] = id
+	}
+	g.wg.Add(1)
+	go g.readLoop()
+	if advertiseAddr != nil {
+		g.wg.Add(1)
+		go g.advertiseLoop(advertiseAddr)
+	}
+	return g, nil
+}
+
+// Accept returns the server side connection of the next connecting MQTT-SN client.
+func (g *mqttsnGateway) Accept() (net.Conn, error) {
+	select {
+	case c := <-g.accept:
+		return c, nil
+	case <-g.close:
+		return nil, errMQTTSNGatewayClosed
+	}
+}
+
+// Close closes the UDP socket and all the MQTT-SN sessions.
+func (g *mqttsnGateway) Close() error {
+	g.closeOnce.Do(func() {
+		close(g.close)
+		g.conn.Close()
+		g.mu.Lock()
+		for _, s := range g.sessions {
+			s.stop()
+		}
+		if g.qosMinusOne != nil {
+			g.qosMinusOne.stop()
+		}
+		g.mu.Unlock()
+		g.wg.Wait()
+	})
+	return nil
+}
+
+// Addr returns the UDP address.
+func (g *mqttsnGateway) Addr() net.Addr {
+	return g.conn.LocalAddr()
+}
+
+func (g *mqttsnGateway) isClosed() bool {
+	select {
+	case <-g.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *mqttsnGateway) writeTo(msg mqttsn.Message, addr *net.UDPAddr) {
+	if addr == nil {
+		return
+	}
+	if ce := zaplog.Check(zap.DebugLevel, "mqttsn message sent"); ce != nil {
+		ce.Write(zap.String("remote", addr.String()), zap.String("message", msg.String()))
+	}
+	_, err := g.conn.WriteToUDP(msg.Pack(), addr)
+	if err != nil && !g.isClosed() {
+		zaplog.Warn("failed to send mqttsn message", zap.String("remote", addr.String()), zap.Error(err))
+	}
+}
+
+func (g *mqttsnGateway) readLoop() {
+	defer g.wg.Done()
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := g.conn.ReadFromUDP(buf)
+		if err != nil {
+			if g.isClosed() {
+				return
+			}
+			zaplog.Warn("mqttsn read error", zap.Error(err))
+			continue
+		}
+		msg, err := mqttsn.ReadMessage(buf[:n])
+		if err != nil {
+			zaplog.Debug("invalid mqttsn message", zap.String("remote", addr.String()), zap.Error(err))
+			continue
+		}
+		if ce := zaplog.Check(zap.DebugLevel, "mqttsn message received"); ce != nil {
+			ce.Write(zap.String("remote", addr.String()), zap.String("message", msg.String()))
+		}
+		g.dispatch(addr, msg)
+	}
+}
+
+func (g *mqttsnGateway) advertiseLoop(addr *net.UDPAddr) {
+	defer g.wg.Done()
+	interval := g.config.GetAdvertiseInterval()
+	adv := &mqttsn.Advertise{
+		GatewayID: g.config.GetGatewayID(),
+		Duration:  uint16(interval / time.Second),
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		g.writeTo(adv, addr)
+		select {
+		case <-ticker.C:
+		case <-g.close:
+			return
+		}
+	}
+}
+
+// dispatch delivers the message to the session of the client.
+func (g *mqttsnGateway) dispatch(addr *net.UDPAddr, msg mqttsn.Message) {
+	switch m := msg.(type) {
+	case *mqttsn.SearchGW:
+		g.writeTo(&mqttsn.GWInfo{GatewayID: g.config.GetGatewayID()}, addr)
+		return
+	case *mqttsn.Advertise, *mqttsn.GWInfo:
+		// sent by other gateways.
+		return
+	case *mqttsn.Publish:
+		if m.QoS == mqttsn.QoSMinusOne {
+			if !g.allowQoSMinusOne(addr) {
+				zaplog.Debug("mqttsn QoS -1 message is not allowed, dropped", zap.String("remote", addr.String()))
+				return
+			}
+			g.publishQoSMinusOne(m)
+			return
+		}
+	}
+	key := addr.String()
+	g.mu.Lock()
+	s := g.sessions[key]
+	switch m := msg.(type) {
+	case *mqttsn.Connect:
+		if s != nil && (s.clientID != m.ClientID || s.isStopped()) {
+			// another client connects from the same address.
+			g.removeSessionLocked(s)
+			s.stop()
+			s = nil
+		}
+		// A client which connects from a new address always gets a new session with a new MQTT connection,
+		// so that it is authenticated by the hooks before the broker takes over the session of the previous address.
+		if s == nil {
+			if m.ClientID == "" {
+				g.mu.Unlock()
+				g.writeTo(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported}, addr)
+				return
+			}
+			s = newSNSession(g, m.ClientID, key)
+			g.sessions[key] = s
+			g.wg.Add(1)
+			go func() {
+				defer g.wg.Done()
+				s.run()
+			}()
+		}
+	}
+	g.mu.Unlock()
+	if s == nil || s.isStopped() {
+		if _, ok := msg.(*mqttsn.Disconnect); !ok {
+			// the client is unknown, informs it to connect again.
+			g.writeTo(&mqttsn.Disconnect{}, addr)
+		}
+		return
+	}
+	s.deliver(snPacket{addr: addr, msg: msg})
+}
+
+// allowQoSMinusOne returns whether the address is allowed to publish the QoS -1 messages.
+func (g *mqttsnGateway) allowQoSMinusOne(addr *net.UDPAddr) bool {
+	for _, v := range g.qosMinusOneNetworks {
+		if v.Contains(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *mqttsnGateway) publishQoSMinusOne(m *mqttsn.Publish) {
+	g.mu.Lock()
+	s := g.qosMinusOne
+	if s == nil || s.isStopped() {
+		s = newSNSession(g, g.config.GetQoSMinusOneClientID(), "")
+		g.qosMinusOne = s
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			s.run()
+		}()
+		s.deliver(snPacket{msg: &mqttsn.Connect{
+			Flags:      mqttsn.Flags{CleanSession: true},
+			ProtocolID: mqttsn.ProtocolID,
+			ClientID:   s.clientID,
+		}})
+	}
+	g.mu.Unlock()
+	s.deliver(snPacket{msg: m})
+}
+
+func (g *mqttsnGateway) removeSessionLocked(s *snSession) {
+	if s.key != "" && g.sessions[s.key] == s {
+		delete(g.sessions, s.key)
+	}
+	if g.qosMinusOne == s {
+		g.qosMinusOne = nil
+	}
+}
+
+func (g *mqttsnGateway) removeSession(s *snSession) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.removeSessionLocked(s)
+}
+
+// snConn is the server side connection of a MQTT-SN client, which reports the UDP address of the client.
+type snConn struct {
+	net.Conn
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *snConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *snConn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,874 @@
+package server
+
+import (
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/mqttsn"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// snMaxPingInterval is the maximum interval of the PINGREQ sent on behalf of the sleeping client,
+// in case the broker shortens the keep alive, see config.MQTT.MaxKeepAlive.
+const snMaxPingInterval = 30 * time.Second
+
+// snMaxQueuedPackets is the maximum number of the packets received from the broker which are not handled by the session.
+// The session handles the packets without waiting for the client, so the queue only grows if the session is stuck.
+const snMaxQueuedPackets = 10000
+
+type snState byte
+
+const (
+	// snConnecting means the gateway is waiting for the will topic, the will message or the CONNACK of the broker.
+	snConnecting snState = iota
+	snActive
+	snAsleep
+)
+
+// snPacket is a MQTT-SN message received from the address.
+type snPacket struct {
+	addr *net.UDPAddr
+	msg  mqttsn.Message
+}
+
+type snInflightKey struct {
+	typ   byte
+	msgID uint16
+}
+
+// snInflight is a message sent to the client which is waiting for the acknowledgement.
+type snInflight struct {
+	msg     mqttsn.Message
+	sentAt  time.Time
+	retries int
+}
+
+// snPacketQueue is the queue of the packets received from the broker.
+// The broker never blocks on writing to the pipe, otherwise the broker and the session may block on writing to each other.
+// Instead, the connection is closed if the queue exceeds snMaxQueuedPackets.
+type snPacketQueue struct {
+	mu      sync.Mutex
+	packets []packets.Packet
+	notify  chan struct{}
+	closed  chan struct{}
+}
+
+func newSNPacketQueue() *snPacketQueue {
+	return &snPacketQueue{
+		notify: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+// push adds the packet to the queue, it returns false if the queue is full.
+func (q *snPacketQueue) push(p packets.Packet) bool {
+	q.mu.Lock()
+	if len(q.packets) >= snMaxQueuedPackets {
+		q.mu.Unlock()
+		return false
+	}
+	q.packets = append(q.packets, p)
+	q.mu.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (q *snPacketQueue) pop() []packets.Packet {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p := q.packets
+	q.packets = nil
+	return p
+}
+
+// snSession translates a MQTT-SN client into a MQTT 3.1.1 connection.
+// The session is bound to the address of the client, all fields are only accessed by the run goroutine.
+type snSession struct {
+	gw       *mqttsnGateway
+	clientID string
+	// key is the address of the client, it is empty for the QoS -1 session.
+	key  string
+	addr *net.UDPAddr
+	in   chan snPacket
+
+	stopOnce sync.Once
+	stopped  chan struct{}
+	connMu   sync.Mutex
+	conn     net.Conn
+	w        *packets.Writer
+	queue    *snPacketQueue
+
+	state   snState
+	connect *mqttsn.Connect
+	will    *mqttsn.WillTopic
+	// keepAlive is the keep alive of the MQTT connection.
+	keepAlive     time.Duration
+	sleepDuration time.Duration
+	lastSeen      time.Time
+	lastPing      time.Time
+	// pingresp indicates whether to send PINGRESP to the awake client once the buffered messages are sent.
+	pingresp bool
+
+	// topics is the registered topic names, key by the topic id.
+	topics      map[uint16]string
+	topicIDs    map[string]uint16
+	nextTopicID uint16
+	nextMsgID   uint16
+	// registering is the messages waiting for the REGACK, key by the topic id.
+	registering map[uint16][]*packets.Publish
+	// pubTopics is the topic ids of the QoS 1 and QoS 2 messages from the client, key by the msg id.
+	pubTopics map[uint16]uint16
+	// subTopics is the topic ids of the SUBACK, key by the msg id.
+	subTopics map[uint16]uint16
+	inflight  map[snInflightKey]*snInflight
+	// buffered is the messages buffered for the sleeping client.
+	buffered []*packets.Publish
+	// dropped is the QoS 2 messages dropped by the gateway, the PUBREL of which is completed by the gateway.
+	dropped map[uint16]struct{}
+	// pending is the messages published before the MQTT connection is established.
+	pending []*mqttsn.Publish
+}
+
+func newSNSession(gw *mqttsnGateway, clientID string, key string) *snSession {
+	return &snSession{
+		gw:          gw,
+		clientID:    clientID,
+		key:         key,
+		in:          make(chan snPacket, 64),
+		stopped:     make(chan struct{}),
+		lastSeen:    time.Now(),
+		topics:      make(map[uint16]string),
+		topicIDs:    make(map[string]uint16),
+		registering: make(map[uint16][]*packets.Publish),
+		pubTopics:   make(map[uint16]uint16),
+		subTopics:   make(map[uint16]uint16),
+		inflight:    make(map[snInflightKey]*snInflight),
+		dropped:     make(map[uint16]struct{}),
+	}
+}
+
+// deliver delivers the message to the session, the message is dropped if the session is busy.
+func (s *snSession) deliver(p snPacket) {
+	select {
+	case s.in <- p:
+	default:
+		zaplog.Warn("mqttsn session is busy, message dropped", zap.String("client_id", s.clientID))
+	}
+}
+
+// stop stops the session without sending DISCONNECT to the broker, so that the will message is published.
+func (s *snSession) stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopped)
+	})
+	s.connMu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.connMu.Unlock()
+}
+
+func (s *snSession) isStopped() bool {
+	select {
+	case <-s.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *snSession) run() {
+	defer func() {
+		s.stop()
+		s.gw.removeSession(s)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		var notify, closed chan struct{}
+		if s.queue != nil {
+			notify, closed = s.queue.notify, s.queue.closed
+		}
+		select {
+		case p := <-s.in:
+			if p.addr != nil {
+				s.addr = p.addr
+			}
+			s.lastSeen = time.Now()
+			s.handleSN(p.msg)
+		case <-notify:
+			s.handleMQTTPackets()
+		case <-closed:
+			s.handleMQTTPackets()
+			if s.isStopped() {
+				return
+			}
+			zaplog.Debug("mqttsn session closed by the broker", zap.String("client_id", s.clientID))
+			if s.state == snConnecting {
+				// the broker may close the connection without sending the CONNACK.
+				s.send(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported})
+			} else {
+				s.send(&mqttsn.Disconnect{})
+			}
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		case <-s.gw.close:
+			return
+		case <-s.stopped:
+			return
+		}
+		if s.isStopped() {
+			return
+		}
+	}
+}
+
+func (s *snSession) send(msg mqttsn.Message) {
+	s.gw.writeTo(msg, s.addr)
+}
+
+// sendWithRetry sends the message and retransmits it until it is acknowledged.
+func (s *snSession) sendWithRetry(key snInflightKey, msg mqttsn.Message) {
+	s.inflight[key] = &snInflight{msg: msg, sentAt: time.Now()}
+	s.send(msg)
+}
+
+func (s *snSession) writeMQTT(p packets.Packet) {
+	if s.w == nil {
+		return
+	}
+	err := s.w.WriteAndFlush(p)
+	if err != nil {
+		zaplog.Debug("mqttsn session write error", zap.String("client_id", s.clientID), zap.Error(err))
+		s.stop()
+	}
+}
+
+func (s *snSession) tick(now time.Time) {
+	switch s.state {
+	case snConnecting:
+		if now.Sub(s.lastSeen) > s.gw.config.GetRetryInterval()*time.Duration(s.gw.config.GetMaxRetries()) {
+			zaplog.Info("mqttsn client connect timeout", zap.String("client_id", s.clientID))
+			s.stop()
+		}
+	case snAsleep:
+		if now.Sub(s.lastSeen) > s.sleepDuration*3/2 {
+			zaplog.Info("mqttsn sleeping client lost", zap.String("client_id", s.clientID))
+			s.stop()
+			return
+		}
+		// keeps the MQTT connection alive on behalf of the sleeping client.
+		interval := s.keepAlive / 2
+		if interval > snMaxPingInterval {
+			interval = snMaxPingInterval
+		}
+		if s.keepAlive > 0 && now.Sub(s.lastPing) >= interval {
+			s.lastPing = now
+			s.writeMQTT(&packets.Pingreq{})
+		}
+	case snActive:
+		interval := s.gw.config.GetRetryInterval()
+		for _, v := range s.inflight {
+			if now.Sub(v.sentAt) < interval {
+				continue
+			}
+			if v.retries >= s.gw.config.GetMaxRetries() {
+				zaplog.Info("mqttsn client lost, max retries exceeded", zap.String("client_id", s.clientID))
+				s.stop()
+				return
+			}
+			v.retries++
+			v.sentAt = now
+			if p, ok := v.msg.(*mqttsn.Publish); ok {
+				p.DUP = true
+			}
+			s.send(v.msg)
+		}
+	}
+}
+
+func (s *snSession) handleSN(msg mqttsn.Message) {
+	switch m := msg.(type) {
+	case *mqttsn.Connect:
+		s.handleConnect(m)
+	case *mqttsn.WillTopic:
+		if s.state != snConnecting || s.connect == nil || s.w != nil {
+			return
+		}
+		if m.WillTopic == "" {
+			s.connectMQTT()
+			return
+		}
+		s.will = m
+		s.send(&mqttsn.WillMsgReq{})
+	case *mqttsn.WillMsg:
+		if s.state != snConnecting || s.will == nil || s.w != nil {
+			return
+		}
+		s.connectMQTT(m.WillMsg)
+	case *mqttsn.WillTopicUpd:
+		s.send(&mqttsn.WillTopicResp{ReturnCode: mqttsn.RejectedNotSupported})
+	case *mqttsn.WillMsgUpd:
+		s.send(&mqttsn.WillMsgResp{ReturnCode: mqttsn.RejectedNotSupported})
+	case *mqttsn.Register:
+		s.handleRegister(m)
+	case *mqttsn.Regack:
+		s.handleRegack(m)
+	case *mqttsn.Publish:
+		s.handlePublish(m)
+	case *mqttsn.Puback:
+		if _, ok := s.inflight[snInflightKey{mqttsn.PUBLISH, m.MsgID}]; !ok {
+			return
+		}
+		delete(s.inflight, snInflightKey{mqttsn.PUBLISH, m.MsgID})
+		if m.ReturnCode == mqttsn.RejectedInvalidTopicID {
+			// the client lost the registration, registers the topic again for the next messages.
+			if name, ok := s.topics[m.TopicID]; ok {
+				delete(s.topics, m.TopicID)
+				delete(s.topicIDs, name)
+			}
+		}
+		s.writeMQTT(&packets.Puback{Version: packets.Version311, PacketID: m.MsgID})
+	case *mqttsn.Pubrec:
+		if _, ok := s.inflight[snInflightKey{mqttsn.PUBLISH, m.MsgID}]; ok {
+			delete(s.inflight, snInflightKey{mqttsn.PUBLISH, m.MsgID})
+			s.writeMQTT(&packets.Pubrec{Version: packets.Version311, PacketID: m.MsgID})
+		}
+	case *mqttsn.Pubcomp:
+		if _, ok := s.inflight[snInflightKey{mqttsn.PUBREL, m.MsgID}]; ok {
+			delete(s.inflight, snInflightKey{mqttsn.PUBREL, m.MsgID})
+			s.writeMQTT(&packets.Pubcomp{Version: packets.Version311, PacketID: m.MsgID})
+		}
+	case *mqttsn.Pubrel:
+		// packets.Pubrel has no version, it is encoded as a MQTT 3.1.1 PUBREL since it has neither a reason code nor properties.
+		s.writeMQTT(&packets.Pubrel{PacketID: m.MsgID})
+	case *mqttsn.Subscribe:
+		s.handleSubscribe(m)
+	case *mqttsn.Unsubscribe:
+		s.handleUnsubscribe(m)
+	case *mqttsn.Pingreq:
+		s.handlePingreq()
+	case *mqttsn.Disconnect:
+		s.handleDisconnect(m)
+	}
+}
+
+func (s *snSession) handleConnect(m *mqttsn.Connect) {
+	if s.state != snConnecting {
+		if !m.CleanSession {
+			// the client returns to the active state.
+			s.state = snActive
+			s.send(&mqttsn.Connack{ReturnCode: mqttsn.Accepted})
+			s.flush()
+			return
+		}
+		s.closeMQTT()
+	} else if s.w != nil {
+		// waiting for the CONNACK of the broker, the CONNECT is retransmitted.
+		return
+	}
+	s.state = snConnecting
+	s.connect = m
+	s.will = nil
+	if m.Will {
+		s.send(&mqttsn.WillTopicReq{})
+		return
+	}
+	s.connectMQTT()
+}
+
+// connectMQTT establishes the MQTT connection to the broker.
+func (s *snSession) connectMQTT(willMsg ...[]byte) {
+	username, password, ok := s.gw.config.GetCredential(s.clientID)
+	if !ok {
+		zaplog.Info("mqttsn client rejected, no credential", zap.String("client_id", s.clientID))
+		s.stop()
+		s.send(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported})
+		return
+	}
+	local, remote := net.Pipe()
+	conn := &snConn{Conn: local, local: s.gw.Addr(), remote: s.gw.Addr()}
+	if s.addr != nil {
+		conn.remote = s.addr
+	}
+	select {
+	case s.gw.accept <- conn:
+	case <-s.gw.close:
+		return
+	case <-s.stopped:
+		return
+	}
+	s.connMu.Lock()
+	s.conn = remote
+	s.connMu.Unlock()
+	s.w = packets.NewWriter(remote)
+	s.queue = newSNPacketQueue()
+	go s.readMQTT(remote, s.queue)
+
+	s.keepAlive = time.Duration(s.connect.Duration) * time.Second
+	connect := &packets.Connect{
+		Version:       packets.Version311,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version311,
+		CleanStart:    s.connect.CleanSession,
+		KeepAlive:     s.connect.Duration,
+		ClientID:      []byte(s.clientID),
+		UsernameFlag:  true,
+		Username:      []byte(username),
+	}
+	if password != "" {
+		connect.PasswordFlag = true
+		connect.Password = []byte(password)
+	}
+	if s.will != nil && len(willMsg) != 0 {
+		connect.WillFlag = true
+		connect.WillTopic = []byte(s.will.WillTopic)
+		connect.WillQos = s.will.QoS
+		if connect.WillQos > packets.Qos2 {
+			connect.WillQos = packets.Qos0
+		}
+		connect.WillRetain = s.will.Retain
+		connect.WillMsg = willMsg[0]
+	}
+	s.writeMQTT(connect)
+}
+
+func (s *snSession) readMQTT(conn net.Conn, q *snPacketQueue) {
+	defer close(q.closed)
+	r := packets.NewReader(conn)
+	for {
+		p, err := r.ReadPacket()
+		if err != nil {
+			return
+		}
+		if !q.push(p) {
+			zaplog.Warn("mqttsn session queue is full, the connection is closed", zap.String("client_id", s.clientID))
+			conn.Close()
+			return
+		}
+	}
+}
+
+// closeMQTT gracefully closes the MQTT connection, the will message is not published.
+func (s *snSession) closeMQTT() {
+	if s.w != nil {
+		_ = s.w.WriteAndFlush(&packets.Disconnect{Version: packets.Version311})
+	}
+	s.connMu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.connMu.Unlock()
+	s.w = nil
+	s.queue = nil
+	s.inflight = make(map[snInflightKey]*snInflight)
+	s.registering = make(map[uint16][]*packets.Publish)
+	s.pubTopics = make(map[uint16]uint16)
+	s.subTopics = make(map[uint16]uint16)
+	s.dropped = make(map[uint16]struct{})
+	s.buffered = nil
+	s.pending = nil
+}
+
+func (s *snSession) handleDisconnect(m *mqttsn.Disconnect) {
+	if m.Duration == 0 || s.state == snConnecting {
+		s.closeMQTT()
+		s.send(&mqttsn.Disconnect{})
+		s.stop()
+		return
+	}
+	s.state = snAsleep
+	s.sleepDuration = time.Duration(m.Duration) * time.Second
+	s.lastPing = time.Now()
+	s.send(&mqttsn.Disconnect{})
+}
+
+func (s *snSession) handlePingreq() {
+	switch s.state {
+	case snConnecting:
+		return
+	case snActive:
+		// the PINGRESP of the broker is forwarded to the client.
+		s.writeMQTT(&packets.Pingreq{})
+		return
+	}
+	// the sleeping client is awake, sends the buffered messages and then the PINGRESP.
+	s.pingresp = true
+	s.flush()
+	s.sendPingresp()
+}
+
+// sendPingresp sends the PINGRESP to the awake client once all buffered messages are sent.
+func (s *snSession) sendPingresp() {
+	if !s.pingresp || len(s.registering) != 0 {
+		return
+	}
+	s.pingresp = false
+	s.send(&mqttsn.Pingresp{})
+}
+
+// flush sends the buffered messages and retransmits the inflight messages.
+func (s *snSession) flush() {
+	now := time.Now()
+	for _, v := range s.inflight {
+		v.sentAt = now
+		if p, ok := v.msg.(*mqttsn.Publish); ok {
+			p.DUP = true
+		}
+		s.send(v.msg)
+	}
+	buffered := s.buffered
+	s.buffered = nil
+	for _, v := range buffered {
+		s.sendPublish(v)
+	}
+}
+
+// topicName returns the topic name of the topic id, returns false if the topic id is invalid.
+func (s *snSession) topicName(idType uint8, id uint16) (string, bool) {
+	switch idType {
+	case mqttsn.TopicIDTypeNormal:
+		name, ok := s.topics[id]
+		return name, ok
+	case mqttsn.TopicIDTypePredefined:
+		name, ok := s.gw.config.PredefinedTopics[id]
+		return name, ok
+	case mqttsn.TopicIDTypeShortName:
+		return mqttsn.ShortTopicName(id), true
+	}
+	return "", false
+}
+
+// register returns the registered topic id of the topic name, a new id is allocated if the topic is not registered.
+func (s *snSession) register(name string) (id uint16, ok bool) {
+	if id, ok := s.topicIDs[name]; ok {
+		return id, true
+	}
+	for i := 0; i < 0xFFFE; i++ {
+		s.nextTopicID++
+		if s.nextTopicID == 0 || s.nextTopicID == 0xFFFF {
+			s.nextTopicID = 1
+		}
+		if _, ok := s.topics[s.nextTopicID]; !ok {
+			s.topics[s.nextTopicID] = name
+			s.topicIDs[name] = s.nextTopicID
+			return s.nextTopicID, true
+		}
+	}
+	return 0, false
+}
+
+// newMsgID returns the msg id of the REGISTER sent to the client.
+// The msg ids share the same space with the packet ids of the messages from the broker (MQTT-SN 5.3.5),
+// so the ids of the messages which are in flight or waiting to be sent to the client are skipped.
+func (s *snSession) newMsgID() uint16 {
+	used := make(map[uint16]struct{}, len(s.inflight)+len(s.buffered))
+	for k := range s.inflight {
+		used[k.msgID] = struct{}{}
+	}
+	for _, msgs := range s.registering {
+		for _, p := range msgs {
+			used[p.PacketID] = struct{}{}
+		}
+	}
+	for _, p := range s.buffered {
+		used[p.PacketID] = struct{}{}
+	}
+	for i := 0; i < math.MaxUint16; i++ {
+		s.nextMsgID++
+		if s.nextMsgID == 0 {
+			s.nextMsgID = 1
+		}
+		if _, ok := used[s.nextMsgID]; !ok {
+			break
+		}
+	}
+	return s.nextMsgID
+}
+
+func (s *snSession) handleRegister(m *mqttsn.Register) {
+	if m.TopicName == "" || strings.ContainsAny(m.TopicName, "+#") {
+		s.send(&mqttsn.Regack{MsgID: m.MsgID, ReturnCode: mqttsn.RejectedInvalidTopicID})
+		return
+	}
+	id, ok := s.register(m.TopicName)
+	if !ok {
+		s.send(&mqttsn.Regack{MsgID: m.MsgID, ReturnCode: mqttsn.RejectedCongestion})
+		return
+	}
+	s.send(&mqttsn.Regack{TopicID: id, MsgID: m.MsgID, ReturnCode: mqttsn.Accepted})
+}
+
+func (s *snSession) handleRegack(m *mqttsn.Regack) {
+	key := snInflightKey{mqttsn.REGISTER, m.MsgID}
+	v, ok := s.inflight[key]
+	if !ok {
+		return
+	}
+	delete(s.inflight, key)
+	id := v.msg.(*mqttsn.Register).TopicID
+	msgs := s.registering[id]
+	delete(s.registering, id)
+	if m.ReturnCode != mqttsn.Accepted {
+		if name, ok := s.topics[id]; ok {
+			delete(s.topics, id)
+			delete(s.topicIDs, name)
+		}
+		for _, p := range msgs {
+			s.drop(p)
+		}
+	} else {
+		for _, p := range msgs {
+			s.sendPublish(p)
+		}
+	}
+	s.sendPingresp()
+}
+
+func (s *snSession) handlePublish(m *mqttsn.Publish) {
+	if s.w == nil {
+		return
+	}
+	if s.state == snConnecting {
+		if len(s.pending) >= s.gw.config.GetMaxBufferedMessages() {
+			zaplog.Warn("mqttsn client publishes too many messages before connected, message dropped", zap.String("client_id", s.clientID))
+			if m.QoS == packets.Qos1 || m.QoS == packets.Qos2 {
+				s.send(&mqttsn.Puback{TopicID: m.TopicID, MsgID: m.MsgID, ReturnCode: mqttsn.RejectedCongestion})
+			}
+			return
+		}
+		s.pending = append(s.pending, m)
+		return
+	}
+	name, ok := s.topicName(m.TopicIDType, m.TopicID)
+	if !ok {
+		s.send(&mqttsn.Puback{TopicID: m.TopicID, MsgID: m.MsgID, ReturnCode: mqttsn.RejectedInvalidTopicID})
+		return
+	}
+	qos := m.QoS
+	if qos == mqttsn.QoSMinusOne {
+		qos = packets.Qos0
+	}
+	if qos > packets.Qos0 {
+		if m.MsgID == 0 {
+			s.send(&mqttsn.Puback{TopicID: m.TopicID, MsgID: m.MsgID, ReturnCode: mqttsn.RejectedNotSupported})
+			return
+		}
+		s.pubTopics[m.MsgID] = m.TopicID
+	}
+	s.writeMQTT(&packets.Publish{
+		Version:   packets.Version311,
+		Dup:       m.DUP && qos > packets.Qos0,
+		Qos:       qos,
+		Retain:    m.Retain,
+		TopicName: []byte(name),
+		PacketID:  m.MsgID,
+		Payload:   m.Data,
+	})
+}
+
+func (s *snSession) handleSubscribe(m *mqttsn.Subscribe) {
+	var name string
+	var id uint16
+	switch m.TopicIDType {
+	case mqttsn.TopicIDTypeNormal:
+		name = m.TopicName
+		if !strings.ContainsAny(name, "+#") {
+			id, _ = s.register(name)
+		}
+	case mqttsn.TopicIDTypePredefined:
+		var ok bool
+		name, ok = s.gw.config.PredefinedTopics[m.TopicID]
+		if !ok {
+			s.send(&mqttsn.Suback{TopicID: m.TopicID, MsgID: m.MsgID, ReturnCode: mqttsn.RejectedInvalidTopicID})
+			return
+		}
+		id = m.TopicID
+	case mqttsn.TopicIDTypeShortName:
+		name = mqttsn.ShortTopicName(m.TopicID)
+	default:
+		s.send(&mqttsn.Suback{MsgID: m.MsgID, ReturnCode: mqttsn.RejectedInvalidTopicID})
+		return
+	}
+	qos := m.QoS
+	if qos > packets.Qos2 {
+		qos = packets.Qos0
+	}
+	s.subTopics[m.MsgID] = id
+	s.writeMQTT(&packets.Subscribe{
+		Version:  packets.Version311,
+		PacketID: m.MsgID,
+		Topics: []packets.Topic{
+			{Name: name, SubOptions: packets.SubOptions{Qos: qos}},
+		},
+	})
+}
+
+func (s *snSession) handleUnsubscribe(m *mqttsn.Unsubscribe) {
+	var name string
+	switch m.TopicIDType {
+	case mqttsn.TopicIDTypeNormal:
+		name = m.TopicName
+	case mqttsn.TopicIDTypePredefined, mqttsn.TopicIDTypeShortName:
+		var ok bool
+		name, ok = s.topicName(m.TopicIDType, m.TopicID)
+		if !ok {
+			s.send(&mqttsn.Unsuback{MsgID: m.MsgID})
+			return
+		}
+	default:
+		s.send(&mqttsn.Unsuback{MsgID: m.MsgID})
+		return
+	}
+	s.writeMQTT(&packets.Unsubscribe{
+		Version:  packets.Version311,
+		PacketID: m.MsgID,
+		Topics:   []string{name},
+	})
+}
+
+func (s *snSession) handleMQTTPackets() {
+	if s.queue == nil {
+		return
+	}
+	for _, p := range s.queue.pop() {
+		s.handleMQTT(p)
+	}
+}
+
+func (s *snSession) handleMQTT(p packets.Packet) {
+	switch p := p.(type) {
+	case *packets.Connack:
+		if p.Code != codes.V3Accepted {
+			zaplog.Info("mqttsn client rejected by the broker", zap.String("client_id", s.clientID), zap.Uint8("code", p.Code))
+			code := uint8(mqttsn.RejectedNotSupported)
+			if p.Code == codes.V3ServerUnavaliable {
+				code = mqttsn.RejectedCongestion
+			}
+			s.stop()
+			s.send(&mqttsn.Connack{ReturnCode: code})
+			return
+		}
+		s.state = snActive
+		s.send(&mqttsn.Connack{ReturnCode: mqttsn.Accepted})
+		pending := s.pending
+		s.pending = nil
+		for _, v := range pending {
+			s.handlePublish(v)
+		}
+	case *packets.Publish:
+		if s.state == snAsleep && !s.pingresp {
+			s.buffer(p)
+			return
+		}
+		s.sendPublish(p)
+	case *packets.Puback:
+		id := s.pubTopics[p.PacketID]
+		delete(s.pubTopics, p.PacketID)
+		s.send(&mqttsn.Puback{TopicID: id, MsgID: p.PacketID, ReturnCode: mqttsn.Accepted})
+	case *packets.Pubrec:
+		s.send(&mqttsn.Pubrec{MsgID: p.PacketID})
+	case *packets.Pubcomp:
+		delete(s.pubTopics, p.PacketID)
+		s.send(&mqttsn.Pubcomp{MsgID: p.PacketID})
+	case *packets.Pubrel:
+		if _, ok := s.dropped[p.PacketID]; ok {
+			delete(s.dropped, p.PacketID)
+			s.writeMQTT(&packets.Pubcomp{Version: packets.Version311, PacketID: p.PacketID})
+			return
+		}
+		s.sendWithRetry(snInflightKey{mqttsn.PUBREL, p.PacketID}, &mqttsn.Pubrel{MsgID: p.PacketID})
+	case *packets.Suback:
+		id := s.subTopics[p.PacketID]
+		delete(s.subTopics, p.PacketID)
+		suback := &mqttsn.Suback{TopicID: id, MsgID: p.PacketID, ReturnCode: mqttsn.Accepted}
+		if len(p.Payload) == 0 || p.Payload[0] >= packets.SubscribeFailure {
+			suback.TopicID = 0
+			suback.ReturnCode = mqttsn.RejectedNotSupported
+		} else {
+			suback.QoS = p.Payload[0]
+		}
+		s.send(suback)
+	case *packets.Unsuback:
+		s.send(&mqttsn.Unsuback{MsgID: p.PacketID})
+	case *packets.Pingresp:
+		if s.state == snActive {
+			s.send(&mqttsn.Pingresp{})
+		}
+	}
+}
+
+// buffer buffers the message for the sleeping client, the oldest message is dropped if the buffer is full.
+func (s *snSession) buffer(p *packets.Publish) {
+	if len(s.buffered) >= s.gw.config.GetMaxBufferedMessages() {
+		zaplog.Warn("mqttsn sleeping client buffer is full, the oldest message is dropped", zap.String("client_id", s.clientID))
+		s.drop(s.buffered[0])
+		s.buffered = s.buffered[1:]
+	}
+	s.buffered = append(s.buffered, p)
+}
+
+// drop acknowledges the message which is not delivered to the client, so that the broker removes it from the inflight queue.
+func (s *snSession) drop(p *packets.Publish) {
+	switch p.Qos {
+	case packets.Qos1:
+		s.writeMQTT(&packets.Puback{Version: packets.Version311, PacketID: p.PacketID})
+	case packets.Qos2:
+		s.dropped[p.PacketID] = struct{}{}
+		s.writeMQTT(&packets.Pubrec{Version: packets.Version311, PacketID: p.PacketID})
+	}
+}
+
+// sendPublish sends the message from the broker to the client,
+// the topic is registered first if it is neither a predefined topic nor a short topic name.
+func (s *snSession) sendPublish(p *packets.Publish) {
+	name := string(p.TopicName)
+	pub := &mqttsn.Publish{
+		Flags: mqttsn.Flags{
+			QoS:    p.Qos,
+			Retain: p.Retain,
+		},
+		MsgID: p.PacketID,
+		Data:  p.Payload,
+	}
+	if id, ok := s.gw.predefined[name]; ok {
+		pub.TopicIDType = mqttsn.TopicIDTypePredefined
+		pub.TopicID = id
+	} else if len(name) == 2 {
+		pub.TopicIDType = mqttsn.TopicIDTypeShortName
+		pub.TopicID = mqttsn.ShortTopicID(name)
+	} else {
+		id, registered := s.topicIDs[name]
+		if !registered {
+			id, registered = s.register(name)
+			if !registered {
+				s.drop(p)
+				return
+			}
+			s.registering[id] = nil
+			msgID := s.newMsgID()
+			s.sendWithRetry(snInflightKey{mqttsn.REGISTER, msgID}, &mqttsn.Register{TopicID: id, MsgID: msgID, TopicName: name})
+		}
+		if _, ok := s.registering[id]; ok {
+			s.registering[id] = append(s.registering[id], p)
+			return
+		}
+		pub.TopicID = id
+	}
+	if p.Qos == packets.Qos0 {
+		s.send(pub)
+		return
+	}
+	s.sendWithRetry(snInflightKey{mqttsn.PUBLISH, p.PacketID}, pub)
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/mqttsn"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+type snTestClient struct {
+	t    *testing.T
+	conn *net.UDPConn
+}
+
+func newSNTestClient(t *testing.T, l *Listener) *snTestClient {
+	conn, err := net.DialUDP("udp", nil, l.Addr().(*net.UDPAddr))
+	assert.Nil(t, err)
+	return &snTestClient{t: t, conn: conn}
+}
+
+func (c *snTestClient) send(msg mqttsn.Message) {
+	_, err := c.conn.Write(msg.Pack())
+	assert.Nil(c.t, err)
+}
+
+func (c *snTestClient) recv() mqttsn.Message {
+	buf := make([]byte, 65535)
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := c.conn.Read(buf)
+	if !assert.Nil(c.t, err) {
+		c.t.FailNow()
+	}
+	msg, err := mqttsn.ReadMessage(buf[:n])
+	assert.Nil(c.t, err)
+	return msg
+}
+
+// noMessage asserts that the client does not receive any message in a short time.
+func (c *snTestClient) noMessage() {
+	buf := make([]byte, 65535)
+	c.conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, err := c.conn.Read(buf)
+	assert.Error(c.t, err)
+}
+
+// snTestBroker acts as the broker side of the connection accepted from the gateway.
+type snTestBroker struct {
+	t    *testing.T
+	conn net.Conn
+	r    *packets.Reader
+	w    *packets.Writer
+}
+
+func acceptSNTestBroker(t *testing.T, l *Listener) *snTestBroker {
+	ch := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.ln.Accept()
+		if err == nil {
+			ch <- conn
+		}
+	}()
+	select {
+	case conn := <-ch:
+		return &snTestBroker{t: t, conn: conn, r: packets.NewReader(conn), w: packets.NewWriter(conn)}
+	case <-time.After(2 * time.Second):
+		t.Fatal("accept timeout")
+	}
+	return nil
+}
+
+func (b *snTestBroker) read() packets.Packet {
+	b.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	p, err := b.r.ReadPacket()
+	if !assert.Nil(b.t, err) {
+		b.t.FailNow()
+	}
+	return p
+}
+
+func (b *snTestBroker) write(p packets.Packet) {
+	assert.Nil(b.t, b.w.WriteAndFlush(p))
+}
+
+func newTestMQTTSNListener(t *testing.T) *Listener {
+	l, err := NewListener(config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		MQTTSN: &config.MQTTSNOptions{
+			PredefinedTopics: map[uint16]string{
+				1: "predefined/1",
+			},
+			Password: "pwd",
+		},
+	})
+	assert.Nil(t, err)
+	assert.True(t, l.MQTTSN())
+	return l
+}
+
+// connectSNTestClient connects the client with the will message and returns the broker side of the connection.
+func connectSNTestClient(t *testing.T, l *Listener, c *snTestClient, clientID string) *snTestBroker {
+	a := assert.New(t)
+	c.send(&mqttsn.Connect{
+		Flags:      mqttsn.Flags{Will: true, CleanSession: true},
+		ProtocolID: mqttsn.ProtocolID,
+		Duration:   30,
+		ClientID:   clientID,
+	})
+	a.Equal(&mqttsn.WillTopicReq{}, c.recv())
+	c.send(&mqttsn.WillTopic{Flags: mqttsn.Flags{QoS: 1}, WillTopic: "will"})
+	a.Equal(&mqttsn.WillMsgReq{}, c.recv())
+	c.send(&mqttsn.WillMsg{WillMsg: []byte("offline")})
+
+	b := acceptSNTestBroker(t, l)
+	a.Equal(c.conn.LocalAddr().String(), b.conn.RemoteAddr().String())
+	connect := b.read().(*packets.Connect)
+	a.Equal(clientID, string(connect.ClientID))
+	a.Equal(clientID, string(connect.Username))
+	a.Equal("pwd", string(connect.Password))
+	a.True(connect.CleanStart)
+	a.EqualValues(30, connect.KeepAlive)
+	a.True(connect.WillFlag)
+	a.Equal("will", string(connect.WillTopic))
+	a.Equal("offline", string(connect.WillMsg))
+	a.EqualValues(1, connect.WillQos)
+
+	b.write(&packets.Connack{Version: packets.Version311, Code: codes.V3Accepted})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.Accepted}, c.recv())
+	return b
+}
+
+func TestMQTTSNGateway_SearchGW(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	c.send(&mqttsn.SearchGW{})
+	a.Equal(&mqttsn.GWInfo{GatewayID: 1, GatewayAddr: []byte{}}, c.recv())
+	// unknown clients are asked to connect.
+	c.send(&mqttsn.Pingreq{})
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+}
+
+func TestMQTTSNGateway_Publish(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	b := connectSNTestClient(t, l, c, "cid")
+
+	c.send(&mqttsn.Register{MsgID: 1, TopicName: "a/b"})
+	regack := c.recv().(*mqttsn.Regack)
+	a.EqualValues(mqttsn.Accepted, regack.ReturnCode)
+	a.NotZero(regack.TopicID)
+
+	c.send(&mqttsn.Publish{Flags: mqttsn.Flags{QoS: 1}, TopicID: regack.TopicID, MsgID: 2, Data: []byte("registered")})
+	pub := b.read().(*packets.Publish)
+	a.Equal("a/b", string(pub.TopicName))
+	a.EqualValues(1, pub.Qos)
+	a.EqualValues(2, pub.PacketID)
+	a.Equal("registered", string(pub.Payload))
+	b.write(&packets.Puback{Version: packets.Version311, PacketID: 2})
+	a.Equal(&mqttsn.Puback{TopicID: regack.TopicID, MsgID: 2, ReturnCode: mqttsn.Accepted}, c.recv())
+
+	c.send(&mqttsn.Publish{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypePredefined}, TopicID: 1, Data: []byte("predefined")})
+	pub = b.read().(*packets.Publish)
+	a.Equal("predefined/1", string(pub.TopicName))
+	a.EqualValues(0, pub.Qos)
+
+	c.send(&mqttsn.Publish{Flags: mqttsn.Flags{QoS: 2, TopicIDType: mqttsn.TopicIDTypeShortName}, TopicID: mqttsn.ShortTopicID("ab"), MsgID: 3})
+	pub = b.read().(*packets.Publish)
+	a.Equal("ab", string(pub.TopicName))
+	a.EqualValues(2, pub.Qos)
+	b.write(&packets.Pubrec{Version: packets.Version311, PacketID: 3})
+	a.Equal(&mqttsn.Pubrec{MsgID: 3}, c.recv())
+	c.send(&mqttsn.Pubrel{MsgID: 3})
+	a.EqualValues(3, b.read().(*packets.Pubrel).PacketID)
+	b.write(&packets.Pubcomp{Version: packets.Version311, PacketID: 3})
+	a.Equal(&mqttsn.Pubcomp{MsgID: 3}, c.recv())
+
+	// unknown topic id
+	c.send(&mqttsn.Publish{Flags: mqttsn.Flags{QoS: 1}, TopicID: 100, MsgID: 4})
+	a.Equal(&mqttsn.Puback{TopicID: 100, MsgID: 4, ReturnCode: mqttsn.RejectedInvalidTopicID}, c.recv())
+
+	c.send(&mqttsn.Disconnect{})
+	a.IsType(&packets.Disconnect{}, b.read())
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+}
+
+func TestMQTTSNGateway_Subscribe(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	b := connectSNTestClient(t, l, c, "cid")
+
+	c.send(&mqttsn.Subscribe{Flags: mqttsn.Flags{QoS: 1}, MsgID: 1, TopicName: "x/+"})
+	sub := b.read().(*packets.Subscribe)
+	a.Equal("x/+", sub.Topics[0].Name)
+	a.EqualValues(1, sub.Topics[0].Qos)
+	b.write(&packets.Suback{Version: packets.Version311, PacketID: 1, Payload: []codes.Code{1}})
+	a.Equal(&mqttsn.Suback{Flags: mqttsn.Flags{QoS: 1}, MsgID: 1, ReturnCode: mqttsn.Accepted}, c.recv())
+
+	c.send(&mqttsn.Subscribe{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypePredefined}, MsgID: 2, TopicID: 2})
+	a.Equal(&mqttsn.Suback{TopicID: 2, MsgID: 2, ReturnCode: mqttsn.RejectedInvalidTopicID}, c.recv())
+
+	// the topic is registered before the message is published to the client.
+	b.write(&packets.Publish{Version: packets.Version311, Qos: 1, PacketID: 10, TopicName: []byte("x/y"), Payload: []byte("1")})
+	reg := c.recv().(*mqttsn.Register)
+	a.Equal("x/y", reg.TopicName)
+	c.send(&mqttsn.Regack{TopicID: reg.TopicID, MsgID: reg.MsgID, ReturnCode: mqttsn.Accepted})
+	pub := c.recv().(*mqttsn.Publish)
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{QoS: 1}, TopicID: reg.TopicID, MsgID: 10, Data: []byte("1")}, pub)
+	c.send(&mqttsn.Puback{TopicID: reg.TopicID, MsgID: 10, ReturnCode: mqttsn.Accepted})
+	a.EqualValues(10, b.read().(*packets.Puback).PacketID)
+
+	// the registered topic is not registered again.
+	b.write(&packets.Publish{Version: packets.Version311, TopicName: []byte("x/y"), Payload: []byte("2")})
+	a.Equal(&mqttsn.Publish{TopicID: reg.TopicID, Data: []byte("2")}, c.recv())
+	b.write(&packets.Publish{Version: packets.Version311, TopicName: []byte("predefined/1"), Payload: []byte("3")})
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypePredefined}, TopicID: 1, Data: []byte("3")}, c.recv())
+
+	c.send(&mqttsn.Unsubscribe{MsgID: 3, TopicName: "x/+"})
+	unsub := b.read().(*packets.Unsubscribe)
+	a.Equal([]string{"x/+"}, unsub.Topics)
+	b.write(&packets.Unsuback{Version: packets.Version311, PacketID: 3})
+	a.Equal(&mqttsn.Unsuback{MsgID: 3}, c.recv())
+}
+
+func TestMQTTSNGateway_Sleep(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	b := connectSNTestClient(t, l, c, "cid")
+
+	c.send(&mqttsn.Disconnect{Duration: 60})
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+
+	b.write(&packets.Publish{Version: packets.Version311, TopicName: []byte("ab"), Payload: []byte("1")})
+	b.write(&packets.Publish{Version: packets.Version311, Qos: 1, PacketID: 1, TopicName: []byte("c/d"), Payload: []byte("2")})
+	c.noMessage()
+
+	// wakes up.
+	c.send(&mqttsn.Pingreq{ClientID: "cid"})
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypeShortName}, TopicID: mqttsn.ShortTopicID("ab"), Data: []byte("1")}, c.recv())
+	reg := c.recv().(*mqttsn.Register)
+	a.Equal("c/d", reg.TopicName)
+	c.send(&mqttsn.Regack{TopicID: reg.TopicID, MsgID: reg.MsgID, ReturnCode: mqttsn.Accepted})
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{QoS: 1}, TopicID: reg.TopicID, MsgID: 1, Data: []byte("2")}, c.recv())
+	a.Equal(&mqttsn.Pingresp{}, c.recv())
+	c.send(&mqttsn.Puback{TopicID: reg.TopicID, MsgID: 1, ReturnCode: mqttsn.Accepted})
+	a.EqualValues(1, b.read().(*packets.Puback).PacketID)
+
+	// returns to the active state.
+	c.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.Accepted}, c.recv())
+	b.write(&packets.Publish{Version: packets.Version311, TopicName: []byte("ab"), Payload: []byte("3")})
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypeShortName}, TopicID: mqttsn.ShortTopicID("ab"), Data: []byte("3")}, c.recv())
+
+	// the client is disconnected when the broker closes the connection.
+	b.conn.Close()
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+}
+
+func TestMQTTSNGateway_NewAddress(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	b := connectSNTestClient(t, l, c, "cid")
+	c.send(&mqttsn.Disconnect{Duration: 60})
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+
+	// the session is never moved by PINGREQ.
+	c2 := newSNTestClient(t, l)
+	c2.send(&mqttsn.Pingreq{ClientID: "cid"})
+	a.Equal(&mqttsn.Disconnect{}, c2.recv())
+
+	// CONNECT from the new address is sent to the broker as a new MQTT connection even if it is not clean session.
+	c2.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	b2 := acceptSNTestBroker(t, l)
+	a.Equal(c2.conn.LocalAddr().String(), b2.conn.RemoteAddr().String())
+	connect := b2.read().(*packets.Connect)
+	a.Equal("cid", string(connect.ClientID))
+	a.False(connect.CleanStart)
+	b2.write(&packets.Connack{Version: packets.Version311, Code: codes.V3Accepted})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.Accepted}, c2.recv())
+
+	// the broker takes over the session and closes the previous connection.
+	b.conn.Close()
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+	b2.write(&packets.Publish{Version: packets.Version311, TopicName: []byte("ab"), Payload: []byte("1")})
+	a.Equal(&mqttsn.Publish{Flags: mqttsn.Flags{TopicIDType: mqttsn.TopicIDTypeShortName}, TopicID: mqttsn.ShortTopicID("ab"), Data: []byte("1")}, c2.recv())
+}
+
+func TestMQTTSNGateway_QoSMinusOne(t *testing.T) {
+	a := assert.New(t)
+	pub := &mqttsn.Publish{
+		Flags:   mqttsn.Flags{QoS: mqttsn.QoSMinusOne, TopicIDType: mqttsn.TopicIDTypePredefined},
+		TopicID: 1,
+		Data:    []byte("data"),
+	}
+	// QoS -1 is disabled by default.
+	l := newTestMQTTSNListener(t)
+	c := newSNTestClient(t, l)
+	c.send(pub)
+	time.Sleep(100 * time.Millisecond)
+	gw := l.ln.(*mqttsnGateway)
+	gw.mu.Lock()
+	a.Nil(gw.qosMinusOne)
+	gw.mu.Unlock()
+	l.Close()
+
+	l, err := NewListener(config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		MQTTSN: &config.MQTTSNOptions{
+			PredefinedTopics:           map[uint16]string{1: "predefined/1"},
+			QoSMinusOneAllowedNetworks: []string{"127.0.0.0/8"},
+		},
+	})
+	a.Nil(err)
+	defer l.Close()
+	c = newSNTestClient(t, l)
+	c.send(pub)
+	b := acceptSNTestBroker(t, l)
+	connect := b.read().(*packets.Connect)
+	a.Equal("mqttsn-gateway-1", string(connect.ClientID))
+	b.write(&packets.Connack{Version: packets.Version311, Code: codes.V3Accepted})
+	p := b.read().(*packets.Publish)
+	a.Equal("predefined/1", string(p.TopicName))
+	a.EqualValues(0, p.Qos)
+	a.Equal("data", string(p.Payload))
+	c.noMessage()
+}
+
+func TestMQTTSNGateway_PendingLimit(t *testing.T) {
+	a := assert.New(t)
+	l, err := NewListener(config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		MQTTSN: &config.MQTTSNOptions{
+			MaxBufferedMessages: 1,
+		},
+	})
+	a.Nil(err)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	c.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	b := acceptSNTestBroker(t, l)
+	a.IsType(&packets.Connect{}, b.read())
+
+	// the messages published before the CONNACK are limited by max_buffered_messages.
+	pub := func(msgID uint16) *mqttsn.Publish {
+		return &mqttsn.Publish{
+			Flags:   mqttsn.Flags{QoS: 1, TopicIDType: mqttsn.TopicIDTypeShortName},
+			TopicID: mqttsn.ShortTopicID("ab"),
+			MsgID:   msgID,
+			Data:    []byte("data"),
+		}
+	}
+	c.send(pub(1))
+	c.send(pub(2))
+	a.Equal(&mqttsn.Puback{TopicID: mqttsn.ShortTopicID("ab"), MsgID: 2, ReturnCode: mqttsn.RejectedCongestion}, c.recv())
+
+	b.write(&packets.Connack{Version: packets.Version311, Code: codes.V3Accepted})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.Accepted}, c.recv())
+	a.EqualValues(1, b.read().(*packets.Publish).PacketID)
+}
+
+func TestMQTTSNGateway_ConnectRejected(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	c.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	b := acceptSNTestBroker(t, l)
+	a.IsType(&packets.Connect{}, b.read())
+	b.write(&packets.Connack{Version: packets.Version311, Code: codes.V3BadUsernameorPassword})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported}, c.recv())
+
+	c.send(&mqttsn.Pingreq{})
+	a.Equal(&mqttsn.Disconnect{}, c.recv())
+}
+
+func TestMQTTSNGateway_ConnectClosed(t *testing.T) {
+	a := assert.New(t)
+	l := newTestMQTTSNListener(t)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	c.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	b := acceptSNTestBroker(t, l)
+	a.IsType(&packets.Connect{}, b.read())
+	b.conn.Close()
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported}, c.recv())
+}
+
+func TestMQTTSNGateway_Credentials(t *testing.T) {
+	a := assert.New(t)
+	l, err := NewListener(config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		MQTTSN: &config.MQTTSNOptions{
+			Password: "shared",
+			Credentials: map[string]config.MQTTSNCredential{
+				"cid": {Username: "user1", Password: "pwd1"},
+			},
+		},
+	})
+	a.Nil(err)
+	defer l.Close()
+	c := newSNTestClient(t, l)
+	c.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "cid"})
+	b := acceptSNTestBroker(t, l)
+	connect := b.read().(*packets.Connect)
+	a.Equal("user1", string(connect.Username))
+	a.Equal("pwd1", string(connect.Password))
+
+	// the clients which are not in the credentials are rejected without connecting to the broker.
+	c2 := newSNTestClient(t, l)
+	c2.send(&mqttsn.Connect{ProtocolID: mqttsn.ProtocolID, Duration: 30, ClientID: "unknown"})
+	a.Equal(&mqttsn.Connack{ReturnCode: mqttsn.RejectedNotSupported}, c2.recv())
+}
+
+func TestSNSession_NewMsgID(t *testing.T) {
+	a := assert.New(t)
+	s := newSNSession(nil, "client1", "key")
+	// the ids of the messages in flight or waiting to be sent to the client are skipped.
+	s.inflight[snInflightKey{mqttsn.PUBLISH, 1}] = &snInflight{}
+	s.inflight[snInflightKey{mqttsn.PUBREL, 2}] = &snInflight{}
+	s.inflight[snInflightKey{mqttsn.REGISTER, 3}] = &snInflight{}
+	s.registering[1] = []*packets.Publish{{PacketID: 4}}
+	s.buffered = []*packets.Publish{{PacketID: 5}}
+	a.EqualValues(6, s.newMsgID())
+	a.EqualValues(7, s.newMsgID())
+
+	s.nextMsgID = 65535
+	a.EqualValues(6, s.newMsgID())
+}
@@ -5,7 +5,9 @@ import (
 
 	"github.com/DrmagicE/gmqtt/config"
 	"github.com/DrmagicE/gmqtt/retained"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type Options func(srv *server)
@@ -31,6 +33,19 @@ func WithWebsocketServer(ws ...*WsServer) Options {
 	}
 }
 
+// WithListeners set the listeners created by NewListener.
+// These listeners are added and removed when the listeners configuration is reloaded, see ReloadConfig.
+func WithListeners(lns ...*Listener) Options {
+	return func(srv *server) {
+		if srv.listeners == nil {
+			srv.listeners = make(map[string]*Listener)
+		}
+		for _, v := range lns {
+			srv.listeners[v.config.Address] = v
+		}
+	}
+}
+
 // WithPlugin set plugin(s) of the server.
 func WithPlugin(plugin ...Plugin) Options {
 	return func(srv *server) {
@@ -45,13 +60,29 @@ func WithHook(hooks Hooks) Options {
 	}
 }
 
+// WithLogger set the logger of the server.
+// The logs are filtered by the levels of the log config, which can be changed at runtime by LogLevelManager,
+// so the logger should enable all levels, see config.NewLogger.
+// The log config changes are applied to the logger and the loggers derived from it on reload, see ReloadConfig.
 func WithLogger(logger *zap.Logger) Options {
 	return func(srv *server) {
-		zaplog = logger
+		srv.logCore = newLogCore(logger.Core())
+		zaplog = logger.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return srv.logCore
+		}))
+	}
+}
+
+// WithTracerProvider set the OpenTelemetry tracer provider of the server, which enables OpenTelemetry tracing.
+// Notice: WithTracerProvider will overwrite the tracing config, and the caller is responsible for shutting down the provider.
+func WithTracerProvider(tp trace.TracerProvider) Options {
+	return func(srv *server) {
+		srv.otel = newOTelTracingWithProvider(tp)
 	}
 }
 
-// WithRetainedStore set retained db of the server. Notice: WithRetainedStore(s) will overwrite retainedDB.
+// WithRetainedStore set retained db of the server.
+// Notice: WithRetainedStore(s) will overwrite the retained store provided by the persistence.
 func WithRetainedStore(store retained.Store) Options {
 	return func(srv *server) {
 		srv.retainedDB = store
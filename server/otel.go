@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// otelTracerName is the instrumentation name of the spans created by the broker.
+const otelTracerName = "github.com/DrmagicE/gmqtt/server"
+
+// The span names.
+const (
+	spanConnect         = "mqtt.connect"
+	spanPublish         = "mqtt.publish"
+	spanDeliver         = "mqtt.deliver"
+	spanSend            = "mqtt.send"
+	spanSessionRegister = "persistence.session.register"
+	spanUnackSet        = "persistence.unack.set"
+	spanRetainedStore   = "persistence.retained.store"
+	spanQueueAdd        = "persistence.queue.add"
+)
+
+// The span attributes.
+const (
+	attrClientID = attribute.Key("mqtt.client_id")
+	attrUsername = attribute.Key("mqtt.username")
+	attrTopic    = attribute.Key("mqtt.topic")
+	attrQoS      = attribute.Key("mqtt.qos")
+	attrMatched  = attribute.Key("mqtt.matched")
+	attrPlugin   = attribute.Key("gmqtt.plugin")
+)
+
+// otelTracing creates the OpenTelemetry spans of the connect, publish and delivery paths,
+// and propagates the W3C trace context through the user properties of the messages.
+// A nil *otelTracing is valid and does nothing, which means OpenTelemetry tracing is disabled.
+type otelTracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	// shutdown flushes the pending spans and releases the exporter,
+	// it is nil if the tracer provider is set by WithTracerProvider.
+	shutdown func(ctx context.Context) error
+}
+
+func newOTelTracingWithProvider(tp trace.TracerProvider) *otelTracing {
+	return &otelTracing{
+		tracer:     tp.Tracer(otelTracerName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// newOTelTracing creates the tracer provider and the exporter from the tracing config.
+func newOTelTracing(c config.Config) (*otelTracing, error) {
+	tc := c.Tracing
+	var exp sdktrace.SpanExporter
+	var closer io.Closer
+	var err error
+	switch tc.Exporter {
+	case config.TracingExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(tc.OTLP.Endpoint)}
+		if tc.OTLP.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(tc.OTLP.Headers) != 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(tc.OTLP.Headers))
+		}
+		if tc.OTLP.Timeout > 0 {
+			opts = append(opts, otlptracegrpc.WithTimeout(tc.OTLP.Timeout))
+		}
+		exp, err = otlptracegrpc.New(context.Background(), opts...)
+	case config.TracingExporterFile:
+		p := tc.File.Path
+		if !filepath.IsAbs(p) && c.ConfigDir != "" {
+			p = filepath.Join(c.ConfigDir, p)
+		}
+		var f *os.File
+		f, err = os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		closer = f
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+	if err != nil {
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(tc.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tc.SampleRatio))),
+	)
+	t := newOTelTracingWithProvider(tp)
+	t.shutdown = func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+	return t, nil
+}
+
+func (t *otelTracing) enabled() bool {
+	return t != nil
+}
+
+// start starts a span, it returns a non-recording span if t is nil.
+func (t *otelTracing) start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if t == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+// extract returns the context with the remote span context in the user properties.
+func (t *otelTracing) extract(ctx context.Context, props []packets.UserProperty) context.Context {
+	if t == nil || len(props) == 0 {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, userPropertiesCarrier{props: &props})
+}
+
+// inject sets the span context of ctx into the user properties, the existing trace context is replaced.
+func (t *otelTracing) inject(ctx context.Context, props *[]packets.UserProperty) {
+	if t == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	t.propagator.Inject(ctx, userPropertiesCarrier{props: props})
+}
+
+// endSpan records the error if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// userPropertiesCarrier adapts the MQTT 5 user properties to propagation.TextMapCarrier.
+type userPropertiesCarrier struct {
+	props *[]packets.UserProperty
+}
+
+func (u userPropertiesCarrier) Get(key string) string {
+	for _, v := range *u.props {
+		if string(v.K) == key {
+			return string(v.V)
+		}
+	}
+	return ""
+}
+
+func (u userPropertiesCarrier) Set(key string, value string) {
+	p := packets.UserProperty{K: []byte(key), V: []byte(value)}
+	for k, v := range *u.props {
+		if string(v.K) == key {
+			(*u.props)[k] = p
+			return
+		}
+	}
+	*u.props = append(*u.props, p)
+}
+
+func (u userPropertiesCarrier) Keys() []string {
+	keys := make([]string, 0, len(*u.props))
+	for _, v := range *u.props {
+		keys = append(keys, string(v.K))
+	}
+	return keys
+}
+
+// tracedPublish is the PUBLISH packet sent to the client with the context of the message.
+// The context is extracted from the message instead of the packet,
+// because the user properties are not encoded into the packets of MQTT 3.1.1 clients.
+type tracedPublish struct {
+	*packets.Publish
+	ctx context.Context
+}
+
+// hookSpanOptions returns the options of the hook spans.
+func hookSpanOptions(plugin string) trace.SpanStartOption {
+	return trace.WithAttributes(attrPlugin.String(plugin))
+}
+
+// The hook wrappers below create a span for each plugin on the traced paths.
+// The span of the plugin includes the spans of the plugins which are called through the pre hook.
+
+func (t *otelTracing) wrapOnBasicAuth(plugin string, w OnBasicAuthWrapper) OnBasicAuthWrapper {
+	return func(pre OnBasicAuth) OnBasicAuth {
+		fn := w(pre)
+		return func(ctx context.Context, client Client, req *ConnectRequest) (err error) {
+			ctx, span := t.start(ctx, "hook.OnBasicAuth", hookSpanOptions(plugin))
+			defer func() { endSpan(span, err) }()
+			return fn(ctx, client, req)
+		}
+	}
+}
+
+func (t *otelTracing) wrapOnEnhancedAuth(plugin string, w OnEnhancedAuthWrapper) OnEnhancedAuthWrapper {
+	return func(pre OnEnhancedAuth) OnEnhancedAuth {
+		fn := w(pre)
+		return func(ctx context.Context, client Client, req *ConnectRequest) (resp *EnhancedAuthResponse, err error) {
+			ctx, span := t.start(ctx, "hook.OnEnhancedAuth", hookSpanOptions(plugin))
+			defer func() { endSpan(span, err) }()
+			return fn(ctx, client, req)
+		}
+	}
+}
+
+func (t *otelTracing) wrapOnMsgArrived(plugin string, w OnMsgArrivedWrapper) OnMsgArrivedWrapper {
+	return func(pre OnMsgArrived) OnMsgArrived {
+		fn := w(pre)
+		return func(ctx context.Context, client Client, req *MsgArrivedRequest) (err error) {
+			ctx, span := t.start(ctx, "hook.OnMsgArrived", hookSpanOptions(plugin))
+			defer func() { endSpan(span, err) }()
+			return fn(ctx, client, req)
+		}
+	}
+}
+
+func (t *otelTracing) wrapOnDelivered(plugin string, w OnDeliveredWrapper) OnDeliveredWrapper {
+	return func(pre OnDelivered) OnDelivered {
+		fn := w(pre)
+		return func(ctx context.Context, client Client, msg *gmqtt.Message) {
+			ctx, span := t.start(ctx, "hook.OnDelivered", hookSpanOptions(plugin))
+			defer span.End()
+			fn(ctx, client, msg)
+		}
+	}
+}
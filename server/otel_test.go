@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+const testTraceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+func TestUserPropertiesCarrier(t *testing.T) {
+	a := assert.New(t)
+	props := []packets.UserProperty{{K: []byte("k"), V: []byte("v")}}
+	c := userPropertiesCarrier{props: &props}
+	a.Equal("v", c.Get("k"))
+	a.Equal("", c.Get("traceparent"))
+
+	c.Set("traceparent", "a")
+	a.Equal("a", c.Get("traceparent"))
+	// replace the existing value.
+	c.Set("traceparent", "b")
+	a.Equal("b", c.Get("traceparent"))
+	a.Equal([]string{"k", "traceparent"}, c.Keys())
+	a.Len(props, 2)
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	m := make(map[string]sdktrace.ReadOnlySpan)
+	for _, v := range spans {
+		m[v.Name()] = v
+	}
+	return m
+}
+
+func TestClient_publishHandler_otel(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	sub := mem.NewStore()
+	srv := &server{
+		subscriptionsDB: sub,
+		queueStore:      make(map[string]queue.Store),
+		config:          config.DefaultConfig(),
+		statsManager:    newStatsManager(sub),
+	}
+	WithTracerProvider(tp)(srv)
+	q := queue.NewMockStore(ctrl)
+	srv.queueStore["subscriber"] = q
+	sub.Subscribe("subscriber", &gmqtt.Subscription{TopicFilter: "/topic/A", QoS: 1})
+
+	var hookCtx context.Context
+	srv.hooks.OnMsgArrived = srv.otel.wrapOnMsgArrived("test", func(pre OnMsgArrived) OnMsgArrived {
+		return func(ctx context.Context, client Client, req *MsgArrivedRequest) error {
+			hookCtx = ctx
+			return pre(ctx, client, req)
+		}
+	})(func(ctx context.Context, client Client, req *MsgArrivedRequest) error {
+		return nil
+	})
+	var queued *gmqtt.Message
+	q.EXPECT().Add(gomock.Any()).Do(func(elem *queue.Elem) {
+		queued = elem.MessageWithID.(*queue.Publish).Message
+	})
+
+	c, err := srv.newClient(noopConn{})
+	a.NoError(err)
+	c.opts.ClientID = "publisher"
+	c.version = packets.Version5
+	_ = c.publishHandler(&packets.Publish{
+		Version:   packets.Version5,
+		Qos:       packets.Qos1,
+		TopicName: []byte("/topic/A"),
+		PacketID:  1,
+		Payload:   []byte("b"),
+		Properties: &packets.Properties{
+			User: []packets.UserProperty{{K: []byte("traceparent"), V: []byte(testTraceParent)}},
+		},
+	})
+
+	spans := spansByName(sr.Ended())
+	a.Len(spans, 4)
+	publish := spans[spanPublish]
+	a.Equal("0af7651916cd43dd8448eb211c80319c", publish.SpanContext().TraceID().String())
+	a.Equal("b7ad6b7169203331", publish.Parent().SpanID().String())
+	a.Equal(trace.SpanKindConsumer, publish.SpanKind())
+
+	hook := spans["hook.OnMsgArrived"]
+	a.Equal(publish.SpanContext().SpanID(), hook.Parent().SpanID())
+	a.Equal(hook.SpanContext(), trace.SpanContextFromContext(hookCtx))
+
+	// the queued message carries the publish span as the parent.
+	a.NotNil(queued)
+	deliver := spans[spanDeliver]
+	a.Equal(publish.SpanContext().SpanID(), deliver.Parent().SpanID())
+	a.Equal(deliver.SpanContext().SpanID(), spans[spanQueueAdd].Parent().SpanID())
+	parent := trace.SpanContextFromContext(srv.otel.extract(context.Background(), queued.UserProperties))
+	a.Equal(publish.SpanContext().SpanID(), parent.SpanID())
+	a.Equal(publish.SpanContext().TraceID(), parent.TraceID())
+
+	// the subscriber is a MQTT 3.1.1 client, the trace context is passed along with the packet.
+	sr2 := tracetest.NewSpanRecorder()
+	srv.otel = newOTelTracingWithProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr2)))
+	subscriber, err := srv.newClient(noopConn{})
+	a.NoError(err)
+	subscriber.opts.ClientID = "subscriber"
+	subscriber.version = packets.Version311
+	go subscriber.writeLoop()
+	defer subscriber.Close()
+	subscriber.writePublish(queued)
+	var send []sdktrace.ReadOnlySpan
+	for i := 0; i < 100 && len(send) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		send = sr2.Ended()
+	}
+	if a.Len(send, 1) {
+		a.Equal(spanSend, send[0].Name())
+		a.Equal(publish.SpanContext().SpanID(), send[0].Parent().SpanID())
+		a.Equal(trace.SpanKindProducer, send[0].SpanKind())
+	}
+}
+
+func TestServer_otelDisabled(t *testing.T) {
+	a := assert.New(t)
+	var o *otelTracing
+	a.False(o.enabled())
+	ctx, span := o.start(context.Background(), spanPublish)
+	a.False(span.IsRecording())
+	var props []packets.UserProperty
+	o.inject(ctx, &props)
+	a.Empty(props)
+	a.Equal(ctx, o.extract(ctx, []packets.UserProperty{{K: []byte("traceparent"), V: []byte(testTraceParent)}}))
+}
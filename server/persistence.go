@@ -6,6 +6,7 @@ import (
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
+	"github.com/DrmagicE/gmqtt/retained"
 )
 
 type NewPersistence func(config config.Config) (Persistence, error)
@@ -16,5 +17,6 @@ type Persistence interface {
 	NewSubscriptionStore(config config.Config) (subscription.Store, error)
 	NewSessionStore(config config.Config) (session.Store, error)
 	NewUnackStore(config config.Config, clientID string) (unack.Store, error)
+	NewRetainedStore(config config.Config) (retained.Store, error)
 	Close() error
 }
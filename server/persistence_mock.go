@@ -10,6 +10,7 @@ import (
 	session "github.com/DrmagicE/gmqtt/persistence/session"
 	subscription "github.com/DrmagicE/gmqtt/persistence/subscription"
 	unack "github.com/DrmagicE/gmqtt/persistence/unack"
+	retained "github.com/DrmagicE/gmqtt/retained"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
 )
@@ -111,6 +112,21 @@ func (mr *MockPersistenceMockRecorder) NewUnackStore(config, clientID interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewUnackStore", reflect.TypeOf((*MockPersistence)(nil).NewUnackStore), config, clientID)
 }
 
+// NewRetainedStore mocks base method
+func (m *MockPersistence) NewRetainedStore(config config.Config) (retained.Store, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRetainedStore", config)
+	ret0, _ := ret[0].(retained.Store)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewRetainedStore indicates an expected call of NewRetainedStore
+func (mr *MockPersistenceMockRecorder) NewRetainedStore(config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRetainedStore", reflect.TypeOf((*MockPersistence)(nil).NewRetainedStore), config)
+}
+
 // Close mocks base method
 func (m *MockPersistence) Close() error {
 	m.ctrl.T.Helper()
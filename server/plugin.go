@@ -1,6 +1,10 @@
 package server
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/DrmagicE/gmqtt/config"
 )
 
@@ -42,3 +46,69 @@ type Plugin interface {
 	// Name return the plugin name
 	Name() string
 }
+
+// Reloadable is an optional interface for plugins which can apply the configuration changes without restarting.
+// If a plugin does not implement it, the changes of its configuration take effect after restarting the broker.
+type Reloadable interface {
+	// Reload will be called in ReloadConfig if the plugin configuration has been changed.
+	// The given config is the new configuration. If return error, the old plugin configuration remains.
+	Reload(config config.Config) error
+}
+
+// HookObserver is an optional interface for plugins which observe the time spent in the hooks, e.g. for metrics.
+// ObserveHook will be called after each call of a hook with the hook name (e.g. "OnMsgArrived") and the duration,
+// which is the time spent in the wrappers of all plugins of the hook.
+type HookObserver interface {
+	ObserveHook(hook string, d time.Duration)
+}
+
+// ClusterHandler handles the request sent by CallNode from other nodes, and returns the response.
+// The request and response are opaque to the cluster, the callers and the handlers agree on the encoding.
+type ClusterHandler func(ctx context.Context, req []byte) (resp []byte, err error)
+
+type clusterCallerKey struct{}
+
+// WithClusterCaller returns a copy of ctx which carries the name of the node that sends the request by CallNode.
+// The Cluster implementations use it to tell the ClusterHandler where the request comes from.
+func WithClusterCaller(ctx context.Context, nodeName string) context.Context {
+	return context.WithValue(ctx, clusterCallerKey{}, nodeName)
+}
+
+// ClusterCallerFromContext returns the name of the node that sends the request to the ClusterHandler,
+// it returns an empty string if the handler is called by the local node.
+func ClusterCallerFromContext(ctx context.Context) string {
+	nodeName, _ := ctx.Value(clusterCallerKey{}).(string)
+	return nodeName
+}
+
+// Cluster is an optional interface for plugins which connect the broker with the other nodes, e.g. the federation plugin.
+// It enables other plugins to fan the requests out to all nodes, such as querying the clients in the whole cluster.
+// Plugins can find the implementation in Server.Plugins().
+type Cluster interface {
+	// NodeName returns the name of the local node.
+	NodeName() string
+	// Nodes returns the names of all reachable nodes in the cluster including the local node, sorted by name.
+	Nodes() []string
+	// RegisterClusterHandler registers the handler of the method, it should be called in the Load method of the plugin.
+	RegisterClusterHandler(method string, handler ClusterHandler)
+	// CallNode calls the handler of the method on the node, the handler of the local node is called directly.
+	// The error returned by the remote handler is returned as a gRPC status error.
+	CallNode(ctx context.Context, nodeName string, method string, req []byte) ([]byte, error)
+}
+
+// CheckPluginBefore returns an error if any plugin named in after is placed before the plugin name in plugins,
+// which are in the order of plugin_order. Plugins call it in Load to require their position in plugin_order,
+// e.g. before the plugins which do not pass some hooks on to the plugins after them.
+func CheckPluginBefore(plugins []Plugin, name string, after ...string) error {
+	for _, v := range plugins {
+		if v.Name() == name {
+			return nil
+		}
+		for _, a := range after {
+			if v.Name() == a {
+				return fmt.Errorf("plugin %s must be placed before %s in plugin_order", name, a)
+			}
+		}
+	}
+	return nil
+}
@@ -5,8 +5,11 @@
 package server
 
 import (
+	context "context"
+	config "github.com/DrmagicE/gmqtt/config"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockPlugin is a mock of Plugin interface
@@ -87,3 +90,153 @@ func (mr *MockPluginMockRecorder) Name() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockPlugin)(nil).Name))
 }
+
+// MockReloadable is a mock of Reloadable interface
+type MockReloadable struct {
+	ctrl     *gomock.Controller
+	recorder *MockReloadableMockRecorder
+}
+
+// MockReloadableMockRecorder is the mock recorder for MockReloadable
+type MockReloadableMockRecorder struct {
+	mock *MockReloadable
+}
+
+// NewMockReloadable creates a new mock instance
+func NewMockReloadable(ctrl *gomock.Controller) *MockReloadable {
+	mock := &MockReloadable{ctrl: ctrl}
+	mock.recorder = &MockReloadableMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockReloadable) EXPECT() *MockReloadableMockRecorder {
+	return m.recorder
+}
+
+// Reload mocks base method
+func (m *MockReloadable) Reload(config config.Config) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reload", config)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reload indicates an expected call of Reload
+func (mr *MockReloadableMockRecorder) Reload(config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockReloadable)(nil).Reload), config)
+}
+
+// MockHookObserver is a mock of HookObserver interface
+type MockHookObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockHookObserverMockRecorder
+}
+
+// MockHookObserverMockRecorder is the mock recorder for MockHookObserver
+type MockHookObserverMockRecorder struct {
+	mock *MockHookObserver
+}
+
+// NewMockHookObserver creates a new mock instance
+func NewMockHookObserver(ctrl *gomock.Controller) *MockHookObserver {
+	mock := &MockHookObserver{ctrl: ctrl}
+	mock.recorder = &MockHookObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHookObserver) EXPECT() *MockHookObserverMockRecorder {
+	return m.recorder
+}
+
+// ObserveHook mocks base method
+func (m *MockHookObserver) ObserveHook(hook string, d time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveHook", hook, d)
+}
+
+// ObserveHook indicates an expected call of ObserveHook
+func (mr *MockHookObserverMockRecorder) ObserveHook(hook, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveHook", reflect.TypeOf((*MockHookObserver)(nil).ObserveHook), hook, d)
+}
+
+// MockCluster is a mock of Cluster interface
+type MockCluster struct {
+	ctrl     *gomock.Controller
+	recorder *MockClusterMockRecorder
+}
+
+// MockClusterMockRecorder is the mock recorder for MockCluster
+type MockClusterMockRecorder struct {
+	mock *MockCluster
+}
+
+// NewMockCluster creates a new mock instance
+func NewMockCluster(ctrl *gomock.Controller) *MockCluster {
+	mock := &MockCluster{ctrl: ctrl}
+	mock.recorder = &MockClusterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCluster) EXPECT() *MockClusterMockRecorder {
+	return m.recorder
+}
+
+// NodeName mocks base method
+func (m *MockCluster) NodeName() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NodeName")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NodeName indicates an expected call of NodeName
+func (mr *MockClusterMockRecorder) NodeName() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NodeName", reflect.TypeOf((*MockCluster)(nil).NodeName))
+}
+
+// Nodes mocks base method
+func (m *MockCluster) Nodes() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Nodes")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Nodes indicates an expected call of Nodes
+func (mr *MockClusterMockRecorder) Nodes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Nodes", reflect.TypeOf((*MockCluster)(nil).Nodes))
+}
+
+// RegisterClusterHandler mocks base method
+func (m *MockCluster) RegisterClusterHandler(method string, handler ClusterHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RegisterClusterHandler", method, handler)
+}
+
+// RegisterClusterHandler indicates an expected call of RegisterClusterHandler
+func (mr *MockClusterMockRecorder) RegisterClusterHandler(method, handler interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterClusterHandler", reflect.TypeOf((*MockCluster)(nil).RegisterClusterHandler), method, handler)
+}
+
+// CallNode mocks base method
+func (m *MockCluster) CallNode(ctx context.Context, nodeName, method string, req []byte) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CallNode", ctx, nodeName, method, req)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CallNode indicates an expected call of CallNode
+func (mr *MockClusterMockRecorder) CallNode(ctx, nodeName, method, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CallNode", reflect.TypeOf((*MockCluster)(nil).CallNode), ctx, nodeName, method, req)
+}
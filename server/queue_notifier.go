@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -13,15 +14,17 @@ import (
 type queueNotifier struct {
 	dropHook OnMsgDropped
 	sts      *statsManager
+	tracer   *traceManager
 	cli      *client
 }
 
 // defaultNotifier is used to init the notifier when using a persistent session store (e.g redis) which can load session data
 // while bootstrapping.
-func defaultNotifier(dropHook OnMsgDropped, sts *statsManager, clientID string) *queueNotifier {
+func defaultNotifier(dropHook OnMsgDropped, sts *statsManager, tracer *traceManager, clientID string) *queueNotifier {
 	return &queueNotifier{
 		dropHook: dropHook,
 		sts:      sts,
+		tracer:   tracer,
 		cli:      &client{opts: &ClientOptions{ClientID: clientID}, status: Connected + 1},
 	}
 }
@@ -30,6 +33,18 @@ func (q *queueNotifier) notifyDropped(msg *gmqtt.Message, err error) {
 	cid := q.cli.opts.ClientID
 	zaplog.Warn("message dropped", zap.String("client_id", cid), zap.Error(err))
 	q.sts.messageDropped(msg.QoS, q.cli.opts.ClientID, err)
+	if q.tracer.enabled() {
+		q.tracer.trace(&TraceEvent{
+			Type:     TraceDecision,
+			Time:     time.Now(),
+			ClientID: cid,
+			Username: q.cli.opts.Username,
+			Topic:    msg.Topic,
+			Decision: DecisionMessageDropped,
+			Message:  msg,
+			Err:      err,
+		})
+	}
 	if q.dropHook != nil {
 		q.dropHook(context.Background(), cid, msg, err)
 	}
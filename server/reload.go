@@ -0,0 +1,197 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// The configuration sections reported by ReloadConfig.
+// The plugin sections are reported as "plugins.<plugin name>".
+const (
+	ReloadSectionMQTT      = "mqtt"
+	ReloadSectionLog       = "log"
+	ReloadSectionListeners = "listeners"
+)
+
+var (
+	// ErrRestartRequired is reported for the changed sections which can not be applied without restarting the broker.
+	ErrRestartRequired = errors.New("restart required")
+	// ErrServerStopped is returned when reloading a stopped server.
+	ErrServerStopped = errors.New("server stopped")
+)
+
+// ReloadResult is the reload result of a configuration section.
+type ReloadResult struct {
+	Section string
+	// Changed indicates whether the section is different from the running configuration.
+	Changed bool
+	// Err is the error occurred when applying the change.
+	// The old setting of the section remains if Err is not nil, except the listeners section,
+	// in which the listeners that can be applied are applied.
+	Err error
+}
+
+// ReloadConfig validates the given config and applies the changes to the running server.
+// If the config is invalid, it returns the error and nothing is applied.
+// Otherwise, it applies the changes section by section and returns the result of each section:
+//   - mqtt: takes effect for the new connections and the new messages.
+//   - log: applies the levels and rebuilds the logger if the logger is set by WithLogger.
+//     The levels changed by LogLevelManager are overwritten.
+//   - listeners: adds and removes the listeners set by WithListeners,
+//     the established connections of the removed listeners are not closed.
+//   - plugins.<name>: calls Reload of the enabled plugin if it implements Reloadable.
+//   - the other sections: report ErrRestartRequired if changed.
+func (srv *server) ReloadConfig(c config.Config) ([]*ReloadResult, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	srv.reloadMu.Lock()
+	defer srv.reloadMu.Unlock()
+	old := srv.GetConfig()
+	// applied is the config that takes effect, the unapplied sections keep the old values.
+	applied := c
+	applied.Plugins = make(map[string]config.Configuration)
+	for k, v := range c.Plugins {
+		applied.Plugins[k] = v
+	}
+	var rs []*ReloadResult
+
+	rs = append(rs, &ReloadResult{
+		Section: ReloadSectionMQTT,
+		Changed: !reflect.DeepEqual(old.MQTT, c.MQTT),
+	})
+
+	r := &ReloadResult{
+		Section: ReloadSectionLog,
+		Changed: !reflect.DeepEqual(old.Log, c.Log),
+	}
+	if r.Changed {
+		r.Err = srv.reloadLogger(old.Log, c.Log)
+		if r.Err != nil {
+			applied.Log = old.Log
+		}
+	}
+	rs = append(rs, r)
+
+	r = &ReloadResult{
+		Section: ReloadSectionListeners,
+		Changed: !reflect.DeepEqual(old.Listeners, c.Listeners),
+	}
+	if r.Changed {
+		applied.Listeners, r.Err = srv.reloadListeners(old.Listeners, c.Listeners)
+	}
+	rs = append(rs, r)
+
+	for _, p := range srv.Plugins() {
+		name := p.Name()
+		r := &ReloadResult{
+			Section: "plugins." + name,
+			Changed: !reflect.DeepEqual(old.Plugins[name], c.Plugins[name]),
+		}
+		rs = append(rs, r)
+		if !r.Changed {
+			continue
+		}
+		if rp, ok := p.(Reloadable); ok {
+			r.Err = rp.Reload(c)
+		} else {
+			r.Err = ErrRestartRequired
+		}
+		if r.Err != nil {
+			applied.Plugins[name] = old.Plugins[name]
+		}
+	}
+
+	// The sections below are used on start only.
+	static := []struct {
+		section  string
+		old, new interface{}
+		restore  func()
+	}{
+		{"api", old.API, c.API, func() { applied.API = old.API }},
+		{"gRPC", old.GRPC, c.GRPC, func() { applied.GRPC = old.GRPC }},
+		{"pid_file", old.PidFile, c.PidFile, func() { applied.PidFile = old.PidFile }},
+		{"plugin_order", old.PluginOrder, c.PluginOrder, func() { applied.PluginOrder = old.PluginOrder }},
+		{"persistence", old.Persistence, c.Persistence, func() { applied.Persistence = old.Persistence }},
+		{"topic_alias_manager", old.TopicAliasManager, c.TopicAliasManager, func() { applied.TopicAliasManager = old.TopicAliasManager }},
+		{"shared_subscription", old.SharedSubscription, c.SharedSubscription, func() { applied.SharedSubscription = old.SharedSubscription }},
+		{"audit", old.Audit, c.Audit, func() { applied.Audit = old.Audit }},
+		{"tracing", old.Tracing, c.Tracing, func() { applied.Tracing = old.Tracing }},
+	}
+	for _, v := range static {
+		r := &ReloadResult{
+			Section: v.section,
+			Changed: !reflect.DeepEqual(v.old, v.new),
+		}
+		if r.Changed {
+			r.Err = ErrRestartRequired
+			v.restore()
+		}
+		rs = append(rs, r)
+	}
+
+	// srv.mu guards the config reads in the message delivery.
+	srv.mu.Lock()
+	srv.configMu.Lock()
+	srv.config = applied
+	srv.configMu.Unlock()
+	srv.mu.Unlock()
+	srv.setDumpPacket(applied.Log.DumpPacket)
+	return rs, nil
+}
+
+// ApplyConfig reloads the config and logs the results, see ReloadConfig.
+func (srv *server) ApplyConfig(c config.Config) {
+	rs, err := srv.ReloadConfig(c)
+	if err != nil {
+		zaplog.Error("reload config error", zap.Error(err))
+		return
+	}
+	for _, r := range rs {
+		if r.Err != nil {
+			zaplog.Error("reload config section error", zap.String("section", r.Section), zap.Error(r.Err))
+		}
+	}
+}
+
+func (srv *server) setDumpPacket(b bool) {
+	var v int32
+	if b {
+		v = 1
+	}
+	atomic.StoreInt32(&srv.dumpPacket, v)
+}
+
+func (srv *server) isDumpPacket() bool {
+	return atomic.LoadInt32(&srv.dumpPacket) == 1
+}
+
+// reloadLogger rebuilds the logger if the output, format or sampling is changed, and applies the levels.
+func (srv *server) reloadLogger(old, new config.LogConfig) error {
+	if srv.logCore == nil {
+		return errLoggerNotReloadable
+	}
+	if !reflect.DeepEqual(logOutputConfig(old), logOutputConfig(new)) {
+		l, err := config.NewLogger(new, zapcore.DebugLevel)
+		if err != nil {
+			return err
+		}
+		srv.logCore.swap(l.Core())
+	}
+	return srv.logCore.levels().apply(new)
+}
+
+// logOutputConfig returns the log config without the settings that can be changed without rebuilding the logger.
+func logOutputConfig(c config.LogConfig) config.LogConfig {
+	c.Level = ""
+	c.Plugins = nil
+	c.DumpPacket = false
+	return c
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+type reloadTestConfig struct {
+	Value string
+}
+
+func (r *reloadTestConfig) Validate() error {
+	if r.Value == "invalid" {
+		return errors.New("invalid value")
+	}
+	return nil
+}
+
+func (r *reloadTestConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return nil
+}
+
+type reloadTestPlugin struct {
+	name     string
+	reloaded []string
+	err      error
+}
+
+func (r *reloadTestPlugin) Load(service Server) error {
+	return nil
+}
+
+func (r *reloadTestPlugin) Unload() error {
+	return nil
+}
+
+func (r *reloadTestPlugin) HookWrapper() HookWrapper {
+	return HookWrapper{}
+}
+
+func (r *reloadTestPlugin) Name() string {
+	return r.name
+}
+
+type reloadableTestPlugin struct {
+	reloadTestPlugin
+}
+
+func (r *reloadableTestPlugin) Reload(config config.Config) error {
+	r.reloaded = append(r.reloaded, config.Plugins[r.name].(*reloadTestConfig).Value)
+	return r.err
+}
+
+func reloadTestConfigWith(values map[string]string) config.Config {
+	c := config.DefaultConfig()
+	c.Listeners = nil
+	for k, v := range values {
+		c.Plugins[k] = &reloadTestConfig{Value: v}
+	}
+	return c
+}
+
+func resultsBySection(rs []*ReloadResult) map[string]*ReloadResult {
+	m := make(map[string]*ReloadResult)
+	for _, v := range rs {
+		m[v.Section] = v
+	}
+	return m
+}
+
+func TestServer_ReloadConfig(t *testing.T) {
+	a := assert.New(t)
+	reloadable := &reloadableTestPlugin{reloadTestPlugin{name: "reloadable"}}
+	failed := &reloadableTestPlugin{reloadTestPlugin{name: "failed", err: errors.New("reload error")}}
+	static := &reloadTestPlugin{name: "static"}
+	old := reloadTestConfigWith(map[string]string{"reloadable": "v1", "failed": "v1", "static": "v1"})
+	srv := New(WithConfig(old), WithPlugin(reloadable, failed, static))
+
+	// invalid config, nothing is applied.
+	c := reloadTestConfigWith(map[string]string{"reloadable": "invalid", "failed": "v1", "static": "v1"})
+	c.MQTT.MaxInflight = 1
+	rs, err := srv.ReloadConfig(c)
+	a.Error(err)
+	a.Nil(rs)
+	a.Equal(old.MQTT, srv.GetConfig().MQTT)
+	a.Empty(reloadable.reloaded)
+
+	c = reloadTestConfigWith(map[string]string{"reloadable": "v2", "failed": "v2", "static": "v2"})
+	c.MQTT.MaxInflight = 1
+	c.Log.DumpPacket = true
+	c.Persistence.Type = config.PersistenceTypeRedis
+	rs, err = srv.ReloadConfig(c)
+	a.Nil(err)
+	m := resultsBySection(rs)
+
+	a.True(m[ReloadSectionMQTT].Changed)
+	a.Nil(m[ReloadSectionMQTT].Err)
+	a.EqualValues(1, srv.GetConfig().MQTT.MaxInflight)
+
+	// the logger is not set by WithLogger.
+	a.True(m[ReloadSectionLog].Changed)
+	a.Error(m[ReloadSectionLog].Err)
+	a.False(srv.GetConfig().Log.DumpPacket)
+	a.False(srv.isDumpPacket())
+
+	// the listeners are not changed.
+	a.False(m[ReloadSectionListeners].Changed)
+
+	a.True(m["plugins.reloadable"].Changed)
+	a.Nil(m["plugins.reloadable"].Err)
+	a.Equal([]string{"v2"}, reloadable.reloaded)
+	a.Equal("v2", srv.GetConfig().Plugins["reloadable"].(*reloadTestConfig).Value)
+
+	a.True(m["plugins.failed"].Changed)
+	a.Error(m["plugins.failed"].Err)
+	a.Equal("v1", srv.GetConfig().Plugins["failed"].(*reloadTestConfig).Value)
+
+	a.True(m["plugins.static"].Changed)
+	a.Equal(ErrRestartRequired, m["plugins.static"].Err)
+	a.Equal("v1", srv.GetConfig().Plugins["static"].(*reloadTestConfig).Value)
+
+	a.True(m["persistence"].Changed)
+	a.Equal(ErrRestartRequired, m["persistence"].Err)
+	a.Equal(config.PersistenceTypeMemory, srv.GetConfig().Persistence.Type)
+	a.False(m["audit"].Changed)
+
+	// reload the same config again, the plugins which failed to reload are retried.
+	rs, err = srv.ReloadConfig(c)
+	a.Nil(err)
+	m = resultsBySection(rs)
+	a.False(m[ReloadSectionMQTT].Changed)
+	a.False(m["plugins.reloadable"].Changed)
+	a.True(m["plugins.failed"].Changed)
+	a.Equal([]string{"v2"}, reloadable.reloaded)
+	a.Equal([]string{"v2", "v2"}, failed.reloaded)
+}
+
+func TestServer_ReloadConfig_Logger(t *testing.T) {
+	a := assert.New(t)
+	defer func(l *zap.Logger) {
+		zaplog = l
+	}(zaplog)
+	c := reloadTestConfigWith(nil)
+	c.Log.Level = "info"
+	l, err := config.NewLogger(c.Log, zapcore.DebugLevel)
+	a.Nil(err)
+	srv := New(WithConfig(c), WithLogger(l))
+	a.False(zaplog.Core().Enabled(zapcore.DebugLevel))
+
+	c.Log.Level = "debug"
+	c.Log.DumpPacket = true
+	rs, err := srv.ReloadConfig(c)
+	a.Nil(err)
+	m := resultsBySection(rs)
+	a.True(m[ReloadSectionLog].Changed)
+	a.Nil(m[ReloadSectionLog].Err)
+	a.True(zaplog.Core().Enabled(zapcore.DebugLevel))
+	a.True(srv.isDumpPacket())
+}
+
+func TestServer_ReloadConfig_Listeners(t *testing.T) {
+	a := assert.New(t)
+	c := reloadTestConfigWith(nil)
+	c.Listeners = []*config.ListenerConfig{
+		{Address: "127.0.0.1:0"},
+		{Address: "localhost:0", Websocket: &config.WebsocketOptions{Path: "/"}},
+	}
+	var lns []*Listener
+	for _, v := range c.Listeners {
+		ln, err := NewListener(*v)
+		a.Nil(err)
+		lns = append(lns, ln)
+	}
+	srv := New(WithConfig(c), WithListeners(lns...))
+	srv.startListeners()
+	defer srv.closeListeners()
+
+	dial := func(l *Listener) error {
+		conn, err := net.DialTimeout("tcp", l.Addr().String(), time.Second)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	a.Nil(dial(lns[0]))
+	a.Nil(dial(lns[1]))
+
+	// remove the websocket listener, and change the path of the tcp listener to websocket.
+	nc := reloadTestConfigWith(nil)
+	nc.Listeners = []*config.ListenerConfig{
+		{Address: "127.0.0.1:0", Websocket: &config.WebsocketOptions{Path: "/mqtt"}},
+		{Address: "127.0.0.1:-1"},
+	}
+	rs, err := srv.ReloadConfig(nc)
+	a.Nil(err)
+	m := resultsBySection(rs)
+	a.True(m[ReloadSectionListeners].Changed)
+	a.Error(m[ReloadSectionListeners].Err)
+	a.Equal(nc.Listeners[:1], srv.GetConfig().Listeners)
+
+	a.Error(dial(lns[0]))
+	a.Error(dial(lns[1]))
+	srv.listenerMu.Lock()
+	a.Len(srv.listeners, 1)
+	l := srv.listeners["127.0.0.1:0"]
+	srv.listenerMu.Unlock()
+	a.True(l.Websocket())
+	a.Nil(dial(l))
+
+	// the old listener keeps serving if the certificate of the new configuration is invalid.
+	tc := reloadTestConfigWith(nil)
+	tc.Listeners = []*config.ListenerConfig{
+		{Address: "127.0.0.1:0", TLSOptions: &config.TLSOptions{Cert: "not_exist.crt", Key: "not_exist.key"}},
+	}
+	rs, err = srv.ReloadConfig(tc)
+	a.Nil(err)
+	m = resultsBySection(rs)
+	a.Error(m[ReloadSectionListeners].Err)
+	a.Equal(nc.Listeners[:1], srv.GetConfig().Listeners)
+	srv.listenerMu.Lock()
+	a.Equal(l, srv.listeners["127.0.0.1:0"])
+	srv.listenerMu.Unlock()
+	a.Nil(dial(l))
+
+	// the listeners that are not created from the config can not be reloaded.
+	srv = New(WithConfig(c))
+	rs, err = srv.ReloadConfig(nc)
+	a.Nil(err)
+	m = resultsBySection(rs)
+	a.Error(m[ReloadSectionListeners].Err)
+	a.Equal(c.Listeners, srv.GetConfig().Listeners)
+}
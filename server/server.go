@@ -4,15 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/DrmagicE/gmqtt"
@@ -21,7 +22,6 @@ import (
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
 	"github.com/DrmagicE/gmqtt/pkg/codes"
-	retained_trie "github.com/DrmagicE/gmqtt/retained/trie"
 
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/pkg/packets"
@@ -94,14 +94,25 @@ type Server interface {
 	StatsManager() StatsReader
 	// Stop stop the server gracefully
 	Stop(ctx context.Context) error
-	// ApplyConfig will replace the config of the server
+	// ApplyConfig reloads the config and logs the results, see ReloadConfig.
 	ApplyConfig(config config.Config)
+	// ReloadConfig validates the config and applies the changes to the running server,
+	// returns the reload result of each section.
+	ReloadConfig(config config.Config) ([]*ReloadResult, error)
 
 	ClientService() ClientService
 
 	SubscriptionService() SubscriptionService
 
 	RetainedService() RetainedService
+
+	QueueService() QueueService
+
+	TraceService() TraceService
+	// AuditLogger returns the AuditLogger which records the administrative and privileged operations.
+	AuditLogger() AuditLogger
+	// LogLevelManager returns the LogLevelManager which changes the log levels at runtime.
+	LogLevelManager() LogLevelManager
 	// Plugins returns all enabled plugins
 	Plugins() []Plugin
 	APIRegistrar() APIRegistrar
@@ -158,6 +169,99 @@ func (c *clientService) TerminateSession(clientID string) {
 
 }
 
+func (c *clientService) TakeoverSession(clientID string, resume bool, fn func(sess *gmqtt.Session)) bool {
+	srv := c.srv
+	srv.mu.Lock()
+	cli := srv.clients[clientID]
+	srv.mu.Unlock()
+	if cli != nil {
+		zaplog.Info("session taken over by another broker", zap.String("client_id", clientID))
+		cli.setError(codes.NewError(codes.SessionTakenOver))
+		cli.Close()
+		<-cli.closed
+	}
+	sess, err := srv.sessionStore.Get(clientID)
+	if err != nil {
+		zaplog.Error("fail to get session", zap.String("client_id", clientID), zap.Error(err))
+		return false
+	}
+	if sess == nil {
+		return false
+	}
+	if fn != nil {
+		fn(sess)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if w, ok := srv.willMessage[clientID]; ok {
+		w.signal(!resume)
+	}
+	// the client may reconnect to this broker during fn.
+	if _, ok := srv.offlineClients[clientID]; ok {
+		err = srv.sessionTerminatedLocked(clientID, TakenOverTermination)
+		if err != nil {
+			zaplog.Error("session terminated fail", zap.Error(err))
+		}
+	}
+	return true
+}
+
+type queueService struct {
+	srv *server
+}
+
+func (q *queueService) getQueue(clientID string) (queue.Store, error) {
+	q.srv.mu.RLock()
+	defer q.srv.mu.RUnlock()
+	if qs, ok := q.srv.queueStore[clientID]; ok {
+		return qs, nil
+	}
+	return nil, ErrQueueNotFound
+}
+
+func (q *queueService) Iterate(clientID string, fn queue.IterateFn) error {
+	qs, err := q.getQueue(clientID)
+	if err != nil {
+		return err
+	}
+	return qs.Iterate(fn)
+}
+
+func (q *queueService) Drop(clientID string, fn queue.DropFn) (int, error) {
+	qs, err := q.getQueue(clientID)
+	if err != nil {
+		return 0, err
+	}
+	return qs.Drop(fn)
+}
+
+func (q *queueService) Enqueue(clientID string, msgs ...*gmqtt.Message) error {
+	srv := q.srv
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	qs, ok := srv.queueStore[clientID]
+	if !ok {
+		return ErrQueueNotFound
+	}
+	now := time.Now()
+	for _, msg := range msgs {
+		msg = msg.Copy()
+		msg.PacketID = 0
+		msg.Dup = false
+		err := qs.Add(&queue.Elem{
+			At:     now,
+			Expiry: srv.messageExpiry(now, msg),
+			MessageWithID: &queue.Publish{
+				Message: msg,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // server represents a mqtt server instance.
 // Create a server by using New()
 type server struct {
@@ -174,6 +278,12 @@ type server struct {
 	willMessage     map[string]*willMsg
 	tcpListener     []net.Listener //tcp listeners
 	websocketServer []*WsServer    //websocket serverStop
+	// listenerMu guards listeners, listenerServing and listenerClosed.
+	listenerMu sync.Mutex
+	// listeners is the listeners set by WithListeners, key by address.
+	listeners       map[string]*Listener
+	listenerServing bool
+	listenerClosed  bool
 	errOnce         sync.Once
 	err             error
 	exitChan        chan struct{}
@@ -188,16 +298,27 @@ type server struct {
 	sessionStore session.Store
 
 	// guards config
-	configMu             sync.RWMutex
-	config               config.Config
+	configMu sync.RWMutex
+	config   config.Config
+	// reloadMu serializes the reloads.
+	reloadMu sync.Mutex
+	// logCore is the replaceable log core, which is set by WithLogger.
+	logCore *logCore
+	// dumpPacket is 1 if Log.DumpPacket is enabled.
+	dumpPacket           int32
 	hooks                Hooks
 	plugins              []Plugin
 	statsManager         *statsManager
+	traceManager         *traceManager
+	auditManager         *auditManager
 	publishService       Publisher
 	newTopicAliasManager NewTopicAliasManager
+	sharedSelector       *SharedSelector
 
 	clientService *clientService
 	apiRegistrar  *apiRegistrar
+	// otel is the OpenTelemetry tracing, it is nil if disabled.
+	otel *otelTracing
 }
 
 func (srv *server) APIRegistrar() APIRegistrar {
@@ -217,15 +338,24 @@ func (srv *server) RetainedService() RetainedService {
 	return srv.retainedDB
 }
 
-func (srv *server) ClientService() ClientService {
-	return srv.clientService
+func (srv *server) QueueService() QueueService {
+	return &queueService{srv: srv}
 }
 
-func (srv *server) ApplyConfig(config config.Config) {
-	srv.configMu.Lock()
-	defer srv.configMu.Unlock()
-	srv.config = config
+func (srv *server) TraceService() TraceService {
+	return srv.traceManager
+}
+
+func (srv *server) AuditLogger() AuditLogger {
+	return srv.auditManager
+}
+
+func (srv *server) LogLevelManager() LogLevelManager {
+	return &logLevelManager{srv: srv}
+}
 
+func (srv *server) ClientService() ClientService {
+	return srv.clientService
 }
 
 func (srv *server) SubscriptionService() SubscriptionService {
@@ -603,7 +733,22 @@ func (srv *server) unregisterClient(client *client) {
 	_ = srv.sessionTerminatedLocked(client.opts.ClientID, NormalTermination)
 }
 
-func (srv *server) addMsgToQueueLocked(now time.Time, clientID string, msg *gmqtt.Message, sub *gmqtt.Subscription, ids []uint32, q queue.Store) {
+// messageExpiry returns the expiry time of the message in the queue, zero means never expire.
+func (srv *server) messageExpiry(now time.Time, msg *gmqtt.Message) (expiry time.Time) {
+	mqttCfg := srv.config.MQTT
+	if mqttCfg.MessageExpiry != 0 {
+		if msg.MessageExpiry != 0 && int(msg.MessageExpiry) <= int(mqttCfg.MessageExpiry) {
+			expiry = now.Add(time.Duration(msg.MessageExpiry) * time.Second)
+		} else {
+			expiry = now.Add(mqttCfg.MessageExpiry)
+		}
+	} else if msg.MessageExpiry != 0 {
+		expiry = now.Add(time.Duration(msg.MessageExpiry) * time.Second)
+	}
+	return expiry
+}
+
+func (srv *server) addMsgToQueueLocked(ctx context.Context, now time.Time, clientID string, msg *gmqtt.Message, sub *gmqtt.Subscription, ids []uint32, q queue.Store) {
 	mqttCfg := srv.config.MQTT
 	if !mqttCfg.QueueQos0Msg {
 		// If the client with the clientID is not connected, skip qos0 messages.
@@ -623,15 +768,10 @@ func (srv *server) addMsgToQueueLocked(now time.Time, clientID string, msg *gmqt
 	if !sub.RetainAsPublished {
 		msg.Retained = false
 	}
-	var expiry time.Time
-	if mqttCfg.MessageExpiry != 0 {
-		if msg.MessageExpiry != 0 && int(msg.MessageExpiry) <= int(mqttCfg.MessageExpiry) {
-			expiry = now.Add(time.Duration(msg.MessageExpiry) * time.Second)
-		} else {
-			expiry = now.Add(mqttCfg.MessageExpiry)
-		}
-	} else if msg.MessageExpiry != 0 {
-		expiry = now.Add(time.Duration(msg.MessageExpiry) * time.Second)
+	expiry := srv.messageExpiry(now, msg)
+	var span trace.Span
+	if srv.otel.enabled() {
+		_, span = srv.otel.start(ctx, spanQueueAdd, trace.WithAttributes(attrClientID.String(clientID)))
 	}
 	err := q.Add(&queue.Elem{
 		At:     now,
@@ -640,6 +780,9 @@ func (srv *server) addMsgToQueueLocked(now time.Time, clientID string, msg *gmqt
 			Message: msg,
 		},
 	})
+	if span != nil {
+		endSpan(span, err)
+	}
 	if err != nil {
 		srv.clients[clientID].queueNotifier.notifyDropped(msg, &queue.InternalError{Err: err})
 		return
@@ -660,22 +803,27 @@ type maxQos map[string]*struct {
 
 // deliverHandler controllers the delivery behaviors according to the DeliveryMode config. (overlap or onlyonce)
 type deliverHandler struct {
-	fn      subscription.IterateFn
-	sl      sharedList
-	mq      maxQos
-	matched bool
-	now     time.Time
-	msg     *gmqtt.Message
-	srv     *server
-}
-
-func newDeliverHandler(mode string, srcClientID string, msg *gmqtt.Message, now time.Time, srv *server) *deliverHandler {
+	// ctx is the context of the deliver span.
+	ctx         context.Context
+	fn          subscription.IterateFn
+	sl          sharedList
+	mq          maxQos
+	matched     bool
+	now         time.Time
+	msg         *gmqtt.Message
+	srv         *server
+	srcClientID string
+}
+
+func newDeliverHandler(ctx context.Context, mode string, srcClientID string, msg *gmqtt.Message, now time.Time, srv *server) *deliverHandler {
 	d := &deliverHandler{
-		sl:  make(sharedList),
-		mq:  make(maxQos),
-		msg: msg,
-		srv: srv,
-		now: now,
+		ctx:         ctx,
+		sl:          make(sharedList),
+		mq:          make(maxQos),
+		msg:         msg,
+		srv:         srv,
+		now:         now,
+		srcClientID: srcClientID,
 	}
 	var iterateFn subscription.IterateFn
 	d.fn = func(clientID string, sub *gmqtt.Subscription) bool {
@@ -696,7 +844,7 @@ func newDeliverHandler(mode string, srcClientID string, msg *gmqtt.Message, now
 	if mode == Overlap {
 		iterateFn = func(clientID string, sub *gmqtt.Subscription) bool {
 			if qs := srv.queueStore[clientID]; qs != nil {
-				srv.addMsgToQueueLocked(now, clientID, msg.Copy(), sub, []uint32{sub.ID}, qs)
+				srv.addMsgToQueueLocked(ctx, now, clientID, msg.Copy(), sub, []uint32{sub.ID}, qs)
 			}
 			return true
 		}
@@ -722,22 +870,33 @@ func newDeliverHandler(mode string, srcClientID string, msg *gmqtt.Message, now
 
 func (d *deliverHandler) flush() {
 	// shared subscription
-	// TODO enable customize balance strategy of shared subscription
 	for _, v := range d.sl {
-		var rs struct {
-			clientID string
-			sub      *gmqtt.Subscription
+		sort.Slice(v, func(i, j int) bool {
+			return v[i].clientID < v[j].clientID
+		})
+		candidates := make([]string, len(v))
+		for i := range v {
+			candidates[i] = v[i].clientID
+		}
+		req := &SharedSelectRequest{
+			PublisherID: d.srcClientID,
+			Message:     d.msg,
+			ShareName:   v[0].sub.ShareName,
+			TopicFilter: v[0].sub.TopicFilter,
+			Candidates:  candidates,
+		}
+		if d.srv.statsManager != nil {
+			req.QueueDepth = d.srv.statsManager.queueLen
 		}
-		// random
-		rs = v[rand.Intn(len(v))]
+		rs := v[d.srv.sharedSelector.Select(req)]
 		if c, ok := d.srv.queueStore[rs.clientID]; ok {
-			d.srv.addMsgToQueueLocked(d.now, rs.clientID, d.msg.Copy(), rs.sub, []uint32{rs.sub.ID}, c)
+			d.srv.addMsgToQueueLocked(d.ctx, d.now, rs.clientID, d.msg.Copy(), rs.sub, []uint32{rs.sub.ID}, c)
 		}
 	}
 	// For onlyonce mode, send the non-shared messages.
 	for clientID, v := range d.mq {
 		if qs := d.srv.queueStore[clientID]; qs != nil {
-			d.srv.addMsgToQueueLocked(d.now, clientID, d.msg.Copy(), v.sub, v.subIDs, qs)
+			d.srv.addMsgToQueueLocked(d.ctx, d.now, clientID, d.msg.Copy(), v.sub, v.subIDs, qs)
 		}
 	}
 }
@@ -745,9 +904,20 @@ func (d *deliverHandler) flush() {
 // deliverMessage send msg to matched client, must call under srv.mu.Lock
 func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message, options subscription.IterationOptions) (matched bool) {
 	now := time.Now()
-	d := newDeliverHandler(srv.config.MQTT.DeliveryMode, srcClientID, msg, now, srv)
+	ctx := context.Background()
+	var span trace.Span
+	if srv.otel.enabled() {
+		// the trace context is carried by the message, see publishHandler.
+		ctx, span = srv.otel.start(srv.otel.extract(ctx, msg.UserProperties), spanDeliver,
+			trace.WithAttributes(attrTopic.String(msg.Topic)))
+		defer span.End()
+	}
+	d := newDeliverHandler(ctx, srv.config.MQTT.DeliveryMode, srcClientID, msg, now, srv)
 	srv.subscriptionsDB.Iterate(d.fn, options)
 	d.flush()
+	if span != nil {
+		span.SetAttributes(attrMatched.Bool(d.matched))
+	}
 	return d.matched
 }
 
@@ -839,11 +1009,12 @@ func defaultServer() *server {
 		clients:        make(map[string]*client),
 		offlineClients: make(map[string]time.Time),
 		willMessage:    make(map[string]*willMsg),
-		retainedDB:     retained_trie.NewStore(),
 		config:         config.DefaultConfig(),
 		queueStore:     make(map[string]queue.Store),
 		unackStore:     make(map[string]unack.Store),
+		traceManager:   &traceManager{},
 	}
+	srv.sharedSelector, _ = NewSharedSelector(srv.config.SharedSubscription)
 	srv.publishService = &publishService{server: srv}
 	return srv
 }
@@ -854,6 +1025,12 @@ func New(opts ...Options) *server {
 	for _, fn := range opts {
 		fn(srv)
 	}
+	srv.setDumpPacket(srv.config.Log.DumpPacket)
+	if srv.logCore != nil {
+		if err := srv.logCore.levels().apply(srv.config.Log); err != nil {
+			zaplog.Error("invalid log levels", zap.Error(err))
+		}
+	}
 	return srv
 }
 
@@ -861,6 +1038,10 @@ func (srv *server) init(opts ...Options) (err error) {
 	for _, fn := range opts {
 		fn(srv)
 	}
+	err = srv.initOTelTracing()
+	if err != nil {
+		return err
+	}
 	err = srv.initPluginHooks()
 	if err != nil {
 		return err
@@ -886,11 +1067,23 @@ func (srv *server) init(opts ...Options) (err error) {
 	if err != nil {
 		return err
 	}
+	err = srv.initAuditManager()
+	if err != nil {
+		return err
+	}
 	st, err := srv.persistence.NewSessionStore(srv.config)
 	if err != nil {
 		return err
 	}
 	srv.sessionStore = st
+	// use the retained store from persistence unless it is set by WithRetainedStore.
+	if srv.retainedDB == nil {
+		srv.retainedDB, err = srv.persistence.NewRetainedStore(srv.config)
+		if err != nil {
+			return err
+		}
+		zaplog.Info("init retained store succeeded", zap.String("type", peType))
+	}
 	var sts []*gmqtt.Session
 	var cids []string
 
@@ -912,7 +1105,7 @@ func (srv *server) init(opts ...Options) (err error) {
 
 	// init queue store & unack store from persistence
 	for _, v := range sts {
-		q, err := srv.persistence.NewQueueStore(srv.config, defaultNotifier(srv.hooks.OnMsgDropped, srv.statsManager, v.ClientID), v.ClientID)
+		q, err := srv.persistence.NewQueueStore(srv.config, defaultNotifier(srv.hooks.OnMsgDropped, srv.statsManager, srv.traceManager, v.ClientID), v.ClientID)
 		if err != nil {
 			return err
 		}
@@ -938,6 +1131,11 @@ func (srv *server) init(opts ...Options) (err error) {
 	} else {
 		return fmt.Errorf("topic alias manager : %s not found", srv.config.TopicAliasManager.Type)
 	}
+	srv.sharedSelector, err = NewSharedSelector(srv.config.SharedSubscription)
+	if err != nil {
+		return err
+	}
+	srv.subscriptionsDB = srv.sharedSelector.WrapStore(srv.subscriptionsDB)
 	err = srv.initAPIRegistrar()
 	if err != nil {
 		return err
@@ -945,6 +1143,35 @@ func (srv *server) init(opts ...Options) (err error) {
 	return srv.loadPlugins()
 }
 
+func (srv *server) initOTelTracing() error {
+	// use the tracer provider set by WithTracerProvider if any.
+	if srv.otel != nil || !srv.config.Tracing.Enable {
+		return nil
+	}
+	t, err := newOTelTracing(srv.config)
+	if err != nil {
+		return err
+	}
+	srv.otel = t
+	zaplog.Info("init opentelemetry tracing succeeded", zap.String("exporter", srv.config.Tracing.Exporter))
+	return nil
+}
+
+func (srv *server) initAuditManager() error {
+	sinkType := srv.config.Audit.Sink
+	newFn := auditSinkFactories[sinkType]
+	if newFn == nil {
+		return fmt.Errorf("audit sink factory: %s not found", sinkType)
+	}
+	sink, err := newFn(srv.config)
+	if err != nil {
+		return err
+	}
+	srv.auditManager = newAuditManager(sink)
+	zaplog.Info("init audit sink succeeded", zap.String("sink", sinkType))
+	return nil
+}
+
 func (srv *server) initAPIRegistrar() error {
 	registrar := &apiRegistrar{}
 	for _, v := range srv.config.API.HTTP {
@@ -1123,6 +1350,7 @@ func (srv *server) newClient(c net.Conn) (*client, error) {
 	client.queueNotifier = &queueNotifier{
 		dropHook: srv.hooks.OnMsgDropped,
 		sts:      srv.statsManager,
+		tracer:   srv.traceManager,
 		cli:      client,
 	}
 	client.setConnecting()
@@ -1168,10 +1396,18 @@ func (srv *server) initPluginHooks() error {
 			onAcceptWrappers = append(onAcceptWrappers, hooks.OnAcceptWrapper)
 		}
 		if hooks.OnBasicAuthWrapper != nil {
-			onBasicAuthWrappers = append(onBasicAuthWrappers, hooks.OnBasicAuthWrapper)
+			w := hooks.OnBasicAuthWrapper
+			if srv.otel.enabled() {
+				w = srv.otel.wrapOnBasicAuth(p.Name(), w)
+			}
+			onBasicAuthWrappers = append(onBasicAuthWrappers, w)
 		}
 		if hooks.OnEnhancedAuthWrapper != nil {
-			onEnhancedAuthWrappers = append(onEnhancedAuthWrappers, hooks.OnEnhancedAuthWrapper)
+			w := hooks.OnEnhancedAuthWrapper
+			if srv.otel.enabled() {
+				w = srv.otel.wrapOnEnhancedAuth(p.Name(), w)
+			}
+			onEnhancedAuthWrappers = append(onEnhancedAuthWrappers, w)
 		}
 		if hooks.OnReAuthWrapper != nil {
 			onReAuthWrappers = append(onReAuthWrappers, hooks.OnReAuthWrapper)
@@ -1201,13 +1437,21 @@ func (srv *server) initPluginHooks() error {
 			onUnsubscribedWrappers = append(onUnsubscribedWrappers, hooks.OnUnsubscribedWrapper)
 		}
 		if hooks.OnMsgArrivedWrapper != nil {
-			onMsgArrivedWrappers = append(onMsgArrivedWrappers, hooks.OnMsgArrivedWrapper)
+			w := hooks.OnMsgArrivedWrapper
+			if srv.otel.enabled() {
+				w = srv.otel.wrapOnMsgArrived(p.Name(), w)
+			}
+			onMsgArrivedWrappers = append(onMsgArrivedWrappers, w)
 		}
 		if hooks.OnMsgDroppedWrapper != nil {
 			onMsgDroppedWrappers = append(onMsgDroppedWrappers, hooks.OnMsgDroppedWrapper)
 		}
 		if hooks.OnDeliveredWrapper != nil {
-			OnDeliveredWrappers = append(OnDeliveredWrappers, hooks.OnDeliveredWrapper)
+			w := hooks.OnDeliveredWrapper
+			if srv.otel.enabled() {
+				w = srv.otel.wrapOnDelivered(p.Name(), w)
+			}
+			OnDeliveredWrappers = append(OnDeliveredWrappers, w)
 		}
 		if hooks.OnClosedWrapper != nil {
 			OnClosedWrappers = append(OnClosedWrappers, hooks.OnClosedWrapper)
@@ -1363,6 +1607,15 @@ func (srv *server) initPluginHooks() error {
 		}
 		srv.hooks.OnWillPublished = onWillPublished
 	}
+	var observers []HookObserver
+	for _, p := range srv.plugins {
+		if o, ok := p.(HookObserver); ok {
+			observers = append(observers, o)
+		}
+	}
+	if observers != nil {
+		srv.hooks.observe(observers)
+	}
 	return nil
 }
 
@@ -1416,9 +1669,13 @@ func (srv *server) Run() (err error) {
 	for _, v := range srv.websocketServer {
 		ws = append(ws, v.Server.Addr)
 	}
-	zaplog.Info("gmqtt server started", zap.Strings("tcp server listen on", tcps), zap.Strings("websocket server listen on", ws))
-
 	srv.status = serverStatusStarted
+	lnTCPs, lnWs, lnMQTTSN := srv.startListeners()
+	tcps = append(tcps, lnTCPs...)
+	ws = append(ws, lnWs...)
+	zaplog.Info("gmqtt server started", zap.Strings("tcp server listen on", tcps), zap.Strings("websocket server listen on", ws),
+		zap.Strings("mqtt-sn gateway listen on", lnMQTTSN))
+
 	srv.wg.Add(2)
 	go srv.eventLoop()
 	go srv.serveAPIServer()
@@ -1457,6 +1714,7 @@ func (srv *server) Stop(ctx context.Context) error {
 		for _, ws := range srv.websocketServer {
 			ws.Server.Shutdown(ctx)
 		}
+		srv.closeListeners()
 		// close all idle clients
 		srv.mu.Lock()
 		chs := make([]chan struct{}, len(srv.clients))
@@ -1496,6 +1754,24 @@ func (srv *server) Stop(ctx context.Context) error {
 			if srv.hooks.OnStop != nil {
 				srv.hooks.OnStop(context.Background())
 			}
+			if srv.otel != nil && srv.otel.shutdown != nil {
+				err := srv.otel.shutdown(context.Background())
+				if err != nil {
+					zaplog.Warn("opentelemetry tracing shutdown error", zap.String("error", err.Error()))
+				}
+			}
+			if srv.auditManager != nil {
+				err := srv.auditManager.close()
+				if err != nil {
+					zaplog.Warn("audit sink close error", zap.String("error", err.Error()))
+				}
+			}
+			if srv.persistence != nil {
+				err := srv.persistence.Close()
+				if err != nil {
+					zaplog.Warn("persistence close error", zap.String("error", err.Error()))
+				}
+			}
 		}
 	})
 	return err
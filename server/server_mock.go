@@ -102,6 +102,21 @@ func (mr *MockServerMockRecorder) ApplyConfig(config interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyConfig", reflect.TypeOf((*MockServer)(nil).ApplyConfig), config)
 }
 
+// ReloadConfig mocks base method
+func (m *MockServer) ReloadConfig(config config.Config) ([]*ReloadResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReloadConfig", config)
+	ret0, _ := ret[0].([]*ReloadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReloadConfig indicates an expected call of ReloadConfig
+func (mr *MockServerMockRecorder) ReloadConfig(config interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadConfig", reflect.TypeOf((*MockServer)(nil).ReloadConfig), config)
+}
+
 // ClientService mocks base method
 func (m *MockServer) ClientService() ClientService {
 	m.ctrl.T.Helper()
@@ -144,6 +159,62 @@ func (mr *MockServerMockRecorder) RetainedService() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetainedService", reflect.TypeOf((*MockServer)(nil).RetainedService))
 }
 
+// QueueService mocks base method
+func (m *MockServer) QueueService() QueueService {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueueService")
+	ret0, _ := ret[0].(QueueService)
+	return ret0
+}
+
+// QueueService indicates an expected call of QueueService
+func (mr *MockServerMockRecorder) QueueService() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueueService", reflect.TypeOf((*MockServer)(nil).QueueService))
+}
+
+// TraceService mocks base method
+func (m *MockServer) TraceService() TraceService {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TraceService")
+	ret0, _ := ret[0].(TraceService)
+	return ret0
+}
+
+// TraceService indicates an expected call of TraceService
+func (mr *MockServerMockRecorder) TraceService() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TraceService", reflect.TypeOf((*MockServer)(nil).TraceService))
+}
+
+// AuditLogger mocks base method
+func (m *MockServer) AuditLogger() AuditLogger {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLogger")
+	ret0, _ := ret[0].(AuditLogger)
+	return ret0
+}
+
+// AuditLogger indicates an expected call of AuditLogger
+func (mr *MockServerMockRecorder) AuditLogger() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLogger", reflect.TypeOf((*MockServer)(nil).AuditLogger))
+}
+
+// LogLevelManager mocks base method
+func (m *MockServer) LogLevelManager() LogLevelManager {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogLevelManager")
+	ret0, _ := ret[0].(LogLevelManager)
+	return ret0
+}
+
+// LogLevelManager indicates an expected call of LogLevelManager
+func (mr *MockServerMockRecorder) LogLevelManager() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogLevelManager", reflect.TypeOf((*MockServer)(nil).LogLevelManager))
+}
+
 // Plugins mocks base method
 func (m *MockServer) Plugins() []Plugin {
 	m.ctrl.T.Helper()
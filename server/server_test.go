@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -9,6 +11,7 @@ import (
 	"github.com/DrmagicE/gmqtt"
 	"github.com/DrmagicE/gmqtt/config"
 	"github.com/DrmagicE/gmqtt/persistence/queue"
+	sessionmem "github.com/DrmagicE/gmqtt/persistence/session/mem"
 	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
 	"github.com/DrmagicE/gmqtt/pkg/packets"
 )
@@ -152,3 +155,71 @@ func TestServer_deliverMessage_sharedSubscription(t *testing.T) {
 	a.Equal(2, qos[packets.Qos2])
 
 }
+
+func TestQueueService_Enqueue(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ts := newTestDeliverMsg(ctrl, "c1")
+	srv := ts.srv
+	qs := &queueService{srv: srv}
+
+	a.Equal(ErrQueueNotFound, qs.Enqueue("c2", &gmqtt.Message{}))
+
+	msg := &gmqtt.Message{
+		Topic:         "a",
+		QoS:           1,
+		PacketID:      1,
+		Dup:           true,
+		MessageExpiry: 10,
+	}
+	mockQueue := srv.queueStore["c1"].(*queue.MockStore)
+	mockQueue.EXPECT().Add(gomock.Any()).Do(func(elem *queue.Elem) {
+		pub := elem.MessageWithID.(*queue.Publish)
+		a.EqualValues(0, pub.PacketID)
+		a.False(pub.Dup)
+		a.Equal("a", pub.Topic)
+		a.Equal(10*time.Second, elem.Expiry.Sub(elem.At))
+	})
+	a.NoError(qs.Enqueue("c1", msg))
+	// the message is copied
+	a.EqualValues(1, msg.PacketID)
+}
+
+func TestClientService_TakeoverSession(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ts := newTestDeliverMsg(ctrl, "c1")
+	srv := ts.srv
+	srv.sessionStore = sessionmem.New()
+	srv.offlineClients = map[string]time.Time{"c1": time.Now().Add(time.Hour)}
+	srv.clients = make(map[string]*client)
+	w := &willMsg{send: make(chan bool, 1)}
+	srv.willMessage = map[string]*willMsg{"c1": w}
+	var terminated []SessionTerminatedReason
+	srv.hooks.OnSessionTerminated = func(ctx context.Context, clientID string, reason SessionTerminatedReason) {
+		terminated = append(terminated, reason)
+	}
+	cs := &clientService{srv: srv, sessionStore: srv.sessionStore}
+
+	a.False(cs.TakeoverSession("c1", true, nil))
+	a.NoError(srv.sessionStore.Set(&gmqtt.Session{ClientID: "c1"}))
+
+	mockQueue := srv.queueStore["c1"].(*queue.MockStore)
+	var called bool
+	mockQueue.EXPECT().Clean().Do(func() {
+		a.True(called)
+	})
+	a.True(cs.TakeoverSession("c1", true, func(sess *gmqtt.Session) {
+		called = true
+		a.Equal("c1", sess.ClientID)
+	}))
+	a.Equal([]SessionTerminatedReason{TakenOverTermination}, terminated)
+	// the delayed will message is discarded.
+	a.False(<-w.send)
+	sess, err := srv.sessionStore.Get("c1")
+	a.NoError(err)
+	a.Nil(sess)
+	a.NotContains(srv.offlineClients, "c1")
+}
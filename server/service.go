@@ -1,7 +1,10 @@
 package server
 
 import (
+	"errors"
+
 	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/retained"
@@ -25,6 +28,14 @@ type ClientService interface {
 	GetClient(clientID string) Client
 	IterateClient(fn ClientIterateFn)
 	TerminateSession(clientID string)
+	// TakeoverSession takes over the session of the client by another broker, e.g. another node in the cluster.
+	// It disconnects the client with the SessionTakenOver reason code and waits for the connection to be closed,
+	// then calls fn with the session and terminates the session with TakenOverTermination.
+	// The fn can be used to read the state of the session, such as the subscriptions and the queued messages.
+	// If resume is true, the session is resumed by the other broker and the delayed will message will be discarded,
+	// otherwise the delayed will message is sent immediately.
+	// It returns false if the client does not have a session.
+	TakeoverSession(clientID string, resume bool, fn func(sess *gmqtt.Session)) bool
 }
 
 // SubscriptionService providers the ability to query and add/delete subscriptions.
@@ -52,3 +63,22 @@ type SubscriptionService interface {
 type RetainedService interface {
 	retained.Store
 }
+
+// ErrQueueNotFound is returned by QueueService when the client does not have a session.
+var ErrQueueNotFound = errors.New("queue not found")
+
+// QueueService provides the ability to inspect and drop the messages in the queues of the clients.
+type QueueService interface {
+	// Iterate iterates the inflight and queued messages of the client in order without removing them.
+	// It returns ErrQueueNotFound if the client does not have a session.
+	Iterate(clientID string, fn queue.IterateFn) error
+	// Drop drops the queued (non-inflight) messages of the client for which fn returns true,
+	// and returns the number of dropped messages.
+	// The OnMsgDropped hook will be called with queue.ErrDropManually for each dropped message.
+	// It returns ErrQueueNotFound if the client does not have a session.
+	Drop(clientID string, fn queue.DropFn) (int, error)
+	// Enqueue adds the messages to the queue of the client as if they were delivered to the client.
+	// It is used to restore the queued messages of a session, e.g. the session taken over from another broker.
+	// It returns ErrQueueNotFound if the client does not have a session.
+	Enqueue(clientID string, msgs ...*gmqtt.Message) error
+}
@@ -6,6 +6,7 @@ package server
 
 import (
 	gmqtt "github.com/DrmagicE/gmqtt"
+	queue "github.com/DrmagicE/gmqtt/persistence/queue"
 	session "github.com/DrmagicE/gmqtt/persistence/session"
 	subscription "github.com/DrmagicE/gmqtt/persistence/subscription"
 	retained "github.com/DrmagicE/gmqtt/retained"
@@ -138,6 +139,20 @@ func (mr *MockClientServiceMockRecorder) TerminateSession(clientID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateSession", reflect.TypeOf((*MockClientService)(nil).TerminateSession), clientID)
 }
 
+// TakeoverSession mocks base method
+func (m *MockClientService) TakeoverSession(clientID string, resume bool, fn func(*gmqtt.Session)) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeoverSession", clientID, resume, fn)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// TakeoverSession indicates an expected call of TakeoverSession
+func (mr *MockClientServiceMockRecorder) TakeoverSession(clientID, resume, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeoverSession", reflect.TypeOf((*MockClientService)(nil).TakeoverSession), clientID, resume, fn)
+}
+
 // MockSubscriptionService is a mock of SubscriptionService interface
 type MockSubscriptionService struct {
 	ctrl     *gomock.Controller
@@ -353,3 +368,74 @@ func (mr *MockRetainedServiceMockRecorder) Iterate(fn interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockRetainedService)(nil).Iterate), fn)
 }
+
+// MockQueueService is a mock of QueueService interface
+type MockQueueService struct {
+	ctrl     *gomock.Controller
+	recorder *MockQueueServiceMockRecorder
+}
+
+// MockQueueServiceMockRecorder is the mock recorder for MockQueueService
+type MockQueueServiceMockRecorder struct {
+	mock *MockQueueService
+}
+
+// NewMockQueueService creates a new mock instance
+func NewMockQueueService(ctrl *gomock.Controller) *MockQueueService {
+	mock := &MockQueueService{ctrl: ctrl}
+	mock.recorder = &MockQueueServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockQueueService) EXPECT() *MockQueueServiceMockRecorder {
+	return m.recorder
+}
+
+// Iterate mocks base method
+func (m *MockQueueService) Iterate(clientID string, fn queue.IterateFn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Iterate", clientID, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Iterate indicates an expected call of Iterate
+func (mr *MockQueueServiceMockRecorder) Iterate(clientID, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockQueueService)(nil).Iterate), clientID, fn)
+}
+
+// Drop mocks base method
+func (m *MockQueueService) Drop(clientID string, fn queue.DropFn) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drop", clientID, fn)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Drop indicates an expected call of Drop
+func (mr *MockQueueServiceMockRecorder) Drop(clientID, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drop", reflect.TypeOf((*MockQueueService)(nil).Drop), clientID, fn)
+}
+
+// Enqueue mocks base method
+func (m *MockQueueService) Enqueue(clientID string, msgs ...*gmqtt.Message) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{clientID}
+	for _, a := range msgs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Enqueue", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enqueue indicates an expected call of Enqueue
+func (mr *MockQueueServiceMockRecorder) Enqueue(clientID interface{}, msgs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{clientID}, msgs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockQueueService)(nil).Enqueue), varargs...)
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+)
+
+var sharedStrategyFactories = make(map[string]NewSharedStrategy)
+
+func init() {
+	RegisterSharedStrategyFactory(config.SharedStrategyRandom, func() SharedStrategy {
+		return randomStrategy{}
+	})
+	RegisterSharedStrategyFactory(config.SharedStrategyRoundRobin, func() SharedStrategy {
+		return &roundRobinStrategy{}
+	})
+	RegisterSharedStrategyFactory(config.SharedStrategyStickyPublisher, func() SharedStrategy {
+		return stickyPublisherStrategy{}
+	})
+	RegisterSharedStrategyFactory(config.SharedStrategyHashTopic, func() SharedStrategy {
+		return hashTopicStrategy{}
+	})
+	RegisterSharedStrategyFactory(config.SharedStrategyLeastQueueDepth, func() SharedStrategy {
+		return &leastQueueDepthStrategy{}
+	})
+}
+
+// RegisterSharedStrategyFactory registers a shared subscription load balancing strategy.
+// The name can be used in the shared_subscription section of the config file.
+func RegisterSharedStrategyFactory(name string, new NewSharedStrategy) {
+	if _, ok := sharedStrategyFactories[name]; ok {
+		panic("duplicated shared strategy factory: " + name)
+	}
+	sharedStrategyFactories[name] = new
+}
+
+// NewSharedStrategy creates a SharedStrategy instance.
+// Each share group gets its own instance, so the implementation can keep per-group state.
+type NewSharedStrategy func() SharedStrategy
+
+// SharedSelectRequest is the input of SharedStrategy.
+type SharedSelectRequest struct {
+	// PublisherID is the client id of the publisher, empty if the message is not published by a client.
+	PublisherID string
+	// Message is the message to be delivered.
+	Message *gmqtt.Message
+	// ShareName is the name of the share group.
+	ShareName string
+	// TopicFilter is the topic filter of the shared subscription, without the $share/{ShareName}/ prefix.
+	TopicFilter string
+	// Candidates is the subscriber list, sorted in ascending order.
+	// It is the client id list for local subscriptions, and the node name list for federation.
+	Candidates []string
+	// QueueDepth returns the current queue length of the candidate.
+	// For local subscriptions, it is the length of the message queue of the client.
+	// For federation, it is the length of the event queue of the peer node, and the local node is always 0.
+	// It is nil if the queue length is not available.
+	QueueDepth func(candidate string) int
+}
+
+// SharedStrategy selects one subscriber from the share group to deliver the message.
+type SharedStrategy interface {
+	// Select returns the index of the selected candidate in req.Candidates.
+	// It is called with at least one candidate.
+	Select(req *SharedSelectRequest) int
+}
+
+// SharedSelector dispatches the selection to the strategy of each share group.
+type SharedSelector struct {
+	config config.SharedSubscription
+	mu     sync.Mutex
+	// strategies is the strategy instance of each share group (key by share name + topic filter).
+	strategies map[string]SharedStrategy
+}
+
+// NewSharedSelector returns a SharedSelector for the given config.
+// It returns error if any of the configured strategies is not registered.
+func NewSharedSelector(cfg config.SharedSubscription) (*SharedSelector, error) {
+	names := []string{cfg.StrategyOf("")}
+	for _, v := range cfg.Groups {
+		names = append(names, v)
+	}
+	for _, v := range names {
+		if _, ok := sharedStrategyFactories[v]; !ok {
+			return nil, fmt.Errorf("shared subscription strategy: %s not found", v)
+		}
+	}
+	return &SharedSelector{
+		config:     cfg,
+		strategies: make(map[string]SharedStrategy),
+	}, nil
+}
+
+// Select returns the index of the selected candidate in req.Candidates.
+func (s *SharedSelector) Select(req *SharedSelectRequest) int {
+	if len(req.Candidates) == 1 {
+		return 0
+	}
+	key := req.ShareName + "/" + req.TopicFilter
+	s.mu.Lock()
+	st := s.strategies[key]
+	if st == nil {
+		st = sharedStrategyFactories[s.config.StrategyOf(req.ShareName)]()
+		s.strategies[key] = st
+	}
+	s.mu.Unlock()
+	i := st.Select(req)
+	if i < 0 || i >= len(req.Candidates) {
+		return 0
+	}
+	return i
+}
+
+// release removes the strategy instances of the given topics if there is no subscriber left in the store.
+func (s *SharedSelector) release(store subscription.Store, topics []string) {
+	for _, v := range topics {
+		shareName, topicFilter := subscription.SplitTopic(v)
+		if shareName == "" {
+			continue
+		}
+		var exist bool
+		store.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+			exist = true
+			return false
+		}, subscription.IterationOptions{
+			Type:      subscription.TypeShared,
+			TopicName: v,
+			MatchType: subscription.MatchName,
+		})
+		if !exist {
+			s.mu.Lock()
+			delete(s.strategies, shareName+"/"+topicFilter)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// WrapStore returns the subscription store which releases the strategy instance of the share group
+// once the last subscriber of the group unsubscribes, so that the instances do not pile up when groups come and go.
+func (s *SharedSelector) WrapStore(store subscription.Store) subscription.Store {
+	return &sharedReleaseStore{Store: store, selector: s}
+}
+
+type sharedReleaseStore struct {
+	subscription.Store
+	selector *SharedSelector
+}
+
+func (s *sharedReleaseStore) Unsubscribe(clientID string, topics ...string) error {
+	err := s.Store.Unsubscribe(clientID, topics...)
+	s.selector.release(s.Store, topics)
+	return err
+}
+
+func (s *sharedReleaseStore) UnsubscribeAll(clientID string) error {
+	var topics []string
+	s.Store.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		topics = append(topics, sub.GetFullTopicName())
+		return true
+	}, subscription.IterationOptions{
+		Type:     subscription.TypeShared,
+		ClientID: clientID,
+	})
+	err := s.Store.UnsubscribeAll(clientID)
+	s.selector.release(s.Store, topics)
+	return err
+}
+
+type randomStrategy struct{}
+
+func (randomStrategy) Select(req *SharedSelectRequest) int {
+	return rand.Intn(len(req.Candidates))
+}
+
+type roundRobinStrategy struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+func (r *roundRobinStrategy) Select(req *SharedSelectRequest) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := int(r.next % uint64(len(req.Candidates)))
+	r.next++
+	return i
+}
+
+// rendezvous returns the candidate with the highest hash weight of the given key.
+// Compared to modulo hashing, only the keys of the removed candidate are remapped when the candidates change.
+func rendezvous(key string, candidates []string) int {
+	var max uint64
+	var idx int
+	for i, v := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if w := h.Sum64(); i == 0 || w > max {
+			max = w
+			idx = i
+		}
+	}
+	return idx
+}
+
+type stickyPublisherStrategy struct{}
+
+func (stickyPublisherStrategy) Select(req *SharedSelectRequest) int {
+	if req.PublisherID == "" {
+		return rendezvous(req.Message.Topic, req.Candidates)
+	}
+	return rendezvous(req.PublisherID, req.Candidates)
+}
+
+type hashTopicStrategy struct{}
+
+func (hashTopicStrategy) Select(req *SharedSelectRequest) int {
+	return rendezvous(req.Message.Topic, req.Candidates)
+}
+
+// leastQueueDepthStrategy selects the candidate with the least queued messages.
+// The ties are broken in turn. It falls back to round robin if the queue depth is not available.
+type leastQueueDepthStrategy struct {
+	roundRobinStrategy
+}
+
+func (l *leastQueueDepthStrategy) Select(req *SharedSelectRequest) int {
+	if req.QueueDepth == nil {
+		return l.roundRobinStrategy.Select(req)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(req.Candidates)
+	start := int(l.next % uint64(n))
+	l.next++
+	idx, min := start, req.QueueDepth(req.Candidates[start])
+	for j := 1; j < n; j++ {
+		i := (start + j) % n
+		if d := req.QueueDepth(req.Candidates[i]); d < min {
+			idx, min = i, d
+		}
+	}
+	return idx
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+)
+
+func newSelectRequest(publisherID, topic string, candidates ...string) *SharedSelectRequest {
+	return &SharedSelectRequest{
+		PublisherID: publisherID,
+		Message: &gmqtt.Message{
+			Topic: topic,
+		},
+		ShareName:   "group",
+		TopicFilter: "a/#",
+		Candidates:  candidates,
+	}
+}
+
+func TestNewSharedSelector(t *testing.T) {
+	a := assert.New(t)
+	_, err := NewSharedSelector(config.SharedSubscription{})
+	a.Nil(err)
+	_, err = NewSharedSelector(config.SharedSubscription{
+		Strategy: "unknown",
+	})
+	a.NotNil(err)
+	_, err = NewSharedSelector(config.SharedSubscription{
+		Groups: map[string]string{
+			"group": "unknown",
+		},
+	})
+	a.NotNil(err)
+}
+
+func TestSharedSelector_RoundRobin(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyRoundRobin,
+	})
+	a.Nil(err)
+	for i := 0; i < 6; i++ {
+		a.Equal(i%3, s.Select(newSelectRequest("", "a/b", "c1", "c2", "c3")))
+	}
+	// each share group has its own counter
+	req := newSelectRequest("", "a/b", "c1", "c2")
+	req.ShareName = "another"
+	a.Equal(0, s.Select(req))
+}
+
+func TestSharedSelector_Random(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyRandom,
+	})
+	a.Nil(err)
+	for i := 0; i < 10; i++ {
+		i := s.Select(newSelectRequest("", "a/b", "c1", "c2", "c3"))
+		a.True(i >= 0 && i < 3)
+	}
+}
+
+func TestSharedSelector_StickyPublisher(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyStickyPublisher,
+	})
+	a.Nil(err)
+	candidates := []string{"c1", "c2", "c3", "c4"}
+	selected := make(map[string]string)
+	for _, pub := range []string{"p1", "p2", "p3", "p4", "p5", "p6"} {
+		i := s.Select(newSelectRequest(pub, "a/"+pub, candidates...))
+		selected[pub] = candidates[i]
+		// the topic name does not matter
+		a.Equal(i, s.Select(newSelectRequest(pub, "a/b", candidates...)))
+	}
+	// only the publishers of the removed candidate are remapped
+	removed := selected["p1"]
+	var left []string
+	for _, v := range candidates {
+		if v != removed {
+			left = append(left, v)
+		}
+	}
+	for pub, c := range selected {
+		i := s.Select(newSelectRequest(pub, "a/b", left...))
+		if c != removed {
+			a.Equal(c, left[i])
+		}
+	}
+}
+
+func TestSharedSelector_HashTopic(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyHashTopic,
+	})
+	a.Nil(err)
+	candidates := []string{"c1", "c2", "c3"}
+	for _, topic := range []string{"a/1", "a/2", "a/3", "a/4"} {
+		i := s.Select(newSelectRequest("p1", topic, candidates...))
+		// the publisher does not matter
+		a.Equal(i, s.Select(newSelectRequest("p2", topic, candidates...)))
+	}
+}
+
+func TestSharedSelector_LeastQueueDepth(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyLeastQueueDepth,
+	})
+	a.Nil(err)
+	depth := map[string]int{
+		"c1": 3,
+		"c2": 1,
+		"c3": 1,
+	}
+	req := newSelectRequest("", "a/b", "c1", "c2", "c3")
+	req.QueueDepth = func(candidate string) int {
+		return depth[candidate]
+	}
+	var got []int
+	for i := 0; i < 4; i++ {
+		got = append(got, s.Select(req))
+	}
+	// ties are broken in turn
+	a.NotContains(got, 0)
+	a.Contains(got, 1)
+	a.Contains(got, 2)
+
+	// fallback to round robin
+	req.QueueDepth = nil
+	req.ShareName = "another"
+	for i := 0; i < 3; i++ {
+		a.Equal(i, s.Select(req))
+	}
+}
+
+func TestSharedSelector_Groups(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyRoundRobin,
+		Groups: map[string]string{
+			"sticky": config.SharedStrategyStickyPublisher,
+		},
+	})
+	a.Nil(err)
+	req := newSelectRequest("p1", "a/b", "c1", "c2", "c3")
+	req.ShareName = "sticky"
+	i := s.Select(req)
+	for j := 0; j < 3; j++ {
+		a.Equal(i, s.Select(req))
+	}
+	req.ShareName = "other"
+	for j := 0; j < 3; j++ {
+		a.Equal(j, s.Select(req))
+	}
+}
+
+func TestSharedSelector_WrapStore(t *testing.T) {
+	a := assert.New(t)
+	s, err := NewSharedSelector(config.SharedSubscription{
+		Strategy: config.SharedStrategyRoundRobin,
+	})
+	a.Nil(err)
+	store := s.WrapStore(mem.NewStore())
+	sub := &gmqtt.Subscription{ShareName: "group", TopicFilter: "a/#"}
+	_, err = store.Subscribe("c1", sub)
+	a.Nil(err)
+	_, err = store.Subscribe("c2", sub)
+	a.Nil(err)
+	s.Select(newSelectRequest("", "a/b", "c1", "c2"))
+	a.Len(s.strategies, 1)
+
+	// the strategy is kept until the last subscriber leaves.
+	a.Nil(store.Unsubscribe("c1", "$share/group/a/#"))
+	a.Len(s.strategies, 1)
+	a.Nil(store.UnsubscribeAll("c2"))
+	a.Len(s.strategies, 0)
+}
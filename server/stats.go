@@ -402,6 +402,16 @@ func (s *statsManager) decQueueLen(clientID string, delta uint64) {
 	atomic.AddUint64(&s.totalStats.MessageStats.QueuedCurrent, ^uint64(delta-1))
 }
 
+// queueLen returns the current queue length of the client.
+func (s *statsManager) queueLen(clientID string) int {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	if sts := s.clientStats[clientID]; sts != nil {
+		return int(atomic.LoadUint64(&sts.MessageStats.QueuedCurrent))
+	}
+	return 0
+}
+
 func (m *MessageStats) copy() *MessageStats {
 	return &MessageStats{
 		Qos0: MessageQosStats{
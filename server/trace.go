@@ -0,0 +1,176 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// TraceEventType is the type of the trace event.
+type TraceEventType byte
+
+const (
+	// TracePacketReceived means the packet is received from the client.
+	TracePacketReceived TraceEventType = iota + 1
+	// TracePacketSent means the packet is sent to the client.
+	TracePacketSent
+	// TraceDecision means a decision is made by the hooks or the broker, e.g. the message is dropped.
+	TraceDecision
+)
+
+// Decisions of the TraceDecision events.
+const (
+	// DecisionConnectRejected means the connection is rejected by the auth hooks or the session registration.
+	DecisionConnectRejected = "connect_rejected"
+	// DecisionSubscribeRejected means the subscription is rejected by the OnSubscribe hook.
+	DecisionSubscribeRejected = "subscribe_rejected"
+	// DecisionUnsubscribeRejected means the unsubscription is rejected by the OnUnsubscribe hook.
+	DecisionUnsubscribeRejected = "unsubscribe_rejected"
+	// DecisionPublishRejected means the message is rejected by the OnMsgArrived hook.
+	DecisionPublishRejected = "publish_rejected"
+	// DecisionPublishDiscarded means the message is discarded by the OnMsgArrived hook without error.
+	DecisionPublishDiscarded = "publish_discarded"
+	// DecisionMessageDropped means the message is dropped from the session queue.
+	DecisionMessageDropped = "message_dropped"
+)
+
+// TraceEvent is the event passed to the Tracer.
+type TraceEvent struct {
+	Type     TraceEventType
+	Time     time.Time
+	ClientID string
+	// Username is empty if the client is offline when the event occurs.
+	Username string
+	// Packet is the received or sent packet, it is nil for TraceDecision events.
+	// Tracers must not modify it or hold it after Trace returns.
+	Packet packets.Packet
+	// Topic is the topic name of the PUBLISH packet or the message, or the topic filter of the rejected (un)subscription.
+	// It is set even if the topic alias is used in the PUBLISH packet.
+	Topic string
+	// Decision is the decision of the TraceDecision events.
+	Decision string
+	// Message is the message related to the decision.
+	Message *gmqtt.Message
+	// Err is the reason of the decision.
+	Err error
+}
+
+// Tracer receives the trace events of the clients.
+// Trace is called synchronously in the client goroutines, so it must be fast and must not block.
+type Tracer interface {
+	Trace(event *TraceEvent)
+}
+
+// TraceService provides the ability to trace the packets and decisions of all clients.
+// There is no overhead except an atomic load when there is no tracer.
+type TraceService interface {
+	// AddTracer adds the tracer, the returned function removes it.
+	AddTracer(tracer Tracer) (remove func())
+}
+
+// tracerEntry wraps the tracer to make it comparable.
+type tracerEntry struct {
+	Tracer
+}
+
+type traceManager struct {
+	mu sync.Mutex
+	// n is the number of tracers, it is used to check whether tracing is enabled without lock.
+	n       int32
+	tracers atomic.Value // []*tracerEntry
+}
+
+// enabled returns whether there is any tracer.
+func (t *traceManager) enabled() bool {
+	return t != nil && atomic.LoadInt32(&t.n) != 0
+}
+
+func (t *traceManager) trace(event *TraceEvent) {
+	tracers, _ := t.tracers.Load().([]*tracerEntry)
+	for _, v := range tracers {
+		v.Trace(event)
+	}
+}
+
+// AddTracer implements TraceService interface.
+func (t *traceManager) AddTracer(tracer Tracer) (remove func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := &tracerEntry{Tracer: tracer}
+	tracers, _ := t.tracers.Load().([]*tracerEntry)
+	t.tracers.Store(append(append([]*tracerEntry{}, tracers...), entry))
+	atomic.AddInt32(&t.n, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			tracers, _ := t.tracers.Load().([]*tracerEntry)
+			rs := make([]*tracerEntry, 0, len(tracers))
+			for _, v := range tracers {
+				if v != entry {
+					rs = append(rs, v)
+				}
+			}
+			t.tracers.Store(rs)
+			atomic.AddInt32(&t.n, -1)
+		})
+	}
+}
+
+// tracePacket traces the received or sent packet of the client.
+// The caller must check whether tracing is enabled.
+func (client *client) tracePacket(typ TraceEventType, packet packets.Packet, topic string) {
+	e := &TraceEvent{
+		Type:   typ,
+		Time:   time.Now(),
+		Packet: packet,
+		Topic:  topic,
+	}
+	if conn, ok := packet.(*packets.Connect); ok {
+		// the client options are not set before the connection is established.
+		e.ClientID = string(conn.ClientID)
+		e.Username = string(conn.Username)
+	} else {
+		e.ClientID = client.opts.ClientID
+		e.Username = client.opts.Username
+	}
+	if pub, ok := packet.(*packets.Publish); ok && topic == "" {
+		e.Topic = string(pub.TopicName)
+	}
+	client.server.traceManager.trace(e)
+}
+
+// traceConnectRejected traces the rejected connection.
+// The client options may not be set when the connection is rejected, so the client id and username are read from the CONNECT packet.
+func (client *client) traceConnectRejected(conn *packets.Connect, err error) {
+	e := &TraceEvent{
+		Type:     TraceDecision,
+		Time:     time.Now(),
+		Decision: DecisionConnectRejected,
+		Err:      err,
+	}
+	if conn != nil {
+		e.ClientID = string(conn.ClientID)
+		e.Username = string(conn.Username)
+	}
+	client.server.traceManager.trace(e)
+}
+
+// traceDecision traces the decision of the client.
+// The caller must check whether tracing is enabled.
+func (client *client) traceDecision(decision string, topic string, msg *gmqtt.Message, err error) {
+	client.server.traceManager.trace(&TraceEvent{
+		Type:     TraceDecision,
+		Time:     time.Now(),
+		ClientID: client.opts.ClientID,
+		Username: client.opts.Username,
+		Topic:    topic,
+		Decision: decision,
+		Message:  msg,
+		Err:      err,
+	})
+}
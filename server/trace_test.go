@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+type testTracer struct {
+	events []*TraceEvent
+}
+
+func (t *testTracer) Trace(event *TraceEvent) {
+	t.events = append(t.events, event)
+}
+
+func TestTraceManager_AddTracer(t *testing.T) {
+	a := assert.New(t)
+	var nilManager *traceManager
+	a.False(nilManager.enabled())
+
+	tm := &traceManager{}
+	a.False(tm.enabled())
+	t1, t2 := &testTracer{}, &testTracer{}
+	remove1 := tm.AddTracer(t1)
+	remove2 := tm.AddTracer(t2)
+	a.True(tm.enabled())
+
+	tm.trace(&TraceEvent{ClientID: "cid"})
+	a.Len(t1.events, 1)
+	a.Len(t2.events, 1)
+
+	remove1()
+	// remove twice is a no-op
+	remove1()
+	a.True(tm.enabled())
+	tm.trace(&TraceEvent{ClientID: "cid"})
+	a.Len(t1.events, 1)
+	a.Len(t2.events, 2)
+
+	remove2()
+	a.False(tm.enabled())
+}
+
+func TestClient_publishHandler_traceRejected(t *testing.T) {
+	a := assert.New(t)
+	tr := &testTracer{}
+	srv := &server{
+		config:       config.DefaultConfig(),
+		traceManager: &traceManager{},
+	}
+	srv.traceManager.AddTracer(tr)
+	rejected := &codes.Error{Code: codes.NotAuthorized}
+	srv.hooks.OnMsgArrived = func(ctx context.Context, client Client, req *MsgArrivedRequest) error {
+		return rejected
+	}
+	c, err := srv.newClient(noopConn{})
+	a.NoError(err)
+	c.opts.ClientID = "cid"
+	c.opts.Username = "user"
+	c.version = packets.Version5
+
+	_ = c.publishHandler(&packets.Publish{
+		Version:    packets.Version5,
+		Qos:        packets.Qos1,
+		TopicName:  []byte("/topic/A"),
+		PacketID:   1,
+		Payload:    []byte("b"),
+		Properties: &packets.Properties{},
+	})
+	a.Len(tr.events, 1)
+	e := tr.events[0]
+	a.Equal(TraceDecision, e.Type)
+	a.Equal(DecisionPublishRejected, e.Decision)
+	a.Equal("cid", e.ClientID)
+	a.Equal("user", e.Username)
+	a.Equal("/topic/A", e.Topic)
+	a.Equal([]byte("b"), e.Message.Payload)
+	a.Equal(rejected, e.Err)
+}